@@ -0,0 +1,296 @@
+package buffer
+
+import "unicode"
+
+// BidiDirection is the direction a line of text runs in, which bidirectional text is laid out from
+type BidiDirection uint8
+
+const (
+	BidiAuto BidiDirection = iota // from the first character with a strong direction, left to right if there's none
+	BidiLTR
+	BidiRTL
+)
+
+// bidiClass is the bidirectional character type from UAX #9, leaving out the explicit embeddings and isolates
+type bidiClass uint8
+
+const (
+	bidiL   bidiClass = iota // left to right
+	bidiR                    // right to left, e.g. Hebrew
+	bidiAL                   // Arabic letter
+	bidiEN                   // European number
+	bidiAN                   // Arabic number
+	bidiES                   // European number separator
+	bidiET                   // European number terminator
+	bidiCS                   // common number separator
+	bidiNSM                  // non-spacing mark
+	bidiWS                   // whitespace
+	bidiON                   // other neutral
+)
+
+func classifyBidi(r rune) bidiClass {
+	switch {
+	case r == 0 || r == ' ' || r == '\t':
+		return bidiWS
+	case r >= '0' && r <= '9', r >= 0x06f0 && r <= 0x06f9:
+		return bidiEN
+	case r >= 0x0660 && r <= 0x0669, r == 0x066b || r == 0x066c:
+		return bidiAN
+	case r == '+' || r == '-':
+		return bidiES
+	case r == '#' || r == '%' || r == 0xb0 || unicode.Is(unicode.Sc, r):
+		return bidiET
+	case r == ',' || r == '.' || r == '/' || r == ':' || r == 0xa0:
+		return bidiCS
+	case r >= 0x0590 && r <= 0x05ff, r >= 0x07c0 && r <= 0x085f, r >= 0xfb1d && r <= 0xfb4f, r >= 0x10800 && r <= 0x10fff:
+		if unicode.Is(unicode.Mn, r) {
+			return bidiNSM
+		}
+		return bidiR
+	case r >= 0x0600 && r <= 0x07bf, r >= 0x0860 && r <= 0x08ff, r >= 0xfb50 && r <= 0xfdff, r >= 0xfe70 && r <= 0xfeff:
+		if unicode.Is(unicode.Mn, r) {
+			return bidiNSM
+		}
+		return bidiAL
+	case unicode.Is(unicode.Mn, r):
+		return bidiNSM
+	case unicode.IsSpace(r):
+		return bidiWS
+	case unicode.IsPunct(r) || unicode.IsSymbol(r):
+		return bidiON
+	}
+	return bidiL
+}
+
+// mirroredRunes are the characters shown facing the other way in right to left text
+var mirroredRunes = map[rune]rune{
+	'(': ')', ')': '(',
+	'<': '>', '>': '<',
+	'[': ']', ']': '[',
+	'{': '}', '}': '{',
+	'«': '»', '»': '«',
+	'‹': '›', '›': '‹',
+	'≤': '≥', '≥': '≤',
+}
+
+// bidiLevels returns the embedding level of each character, following the weak, neutral and implicit rules of
+// UAX #9 over a single paragraph with no explicit embeddings
+func bidiLevels(classes []bidiClass, direction BidiDirection) []int {
+	base := 0
+	switch direction {
+	case BidiRTL:
+		base = 1
+	case BidiAuto:
+		for _, c := range classes {
+			if c == bidiL {
+				break
+			}
+			if c == bidiR || c == bidiAL {
+				base = 1
+				break
+			}
+		}
+	}
+	sos := bidiL
+	if base == 1 {
+		sos = bidiR
+	}
+
+	types := make([]bidiClass, len(classes))
+	copy(types, classes)
+
+	// W1: marks take the type of what they're on
+	prev := sos
+	for i, t := range types {
+		if t == bidiNSM {
+			types[i] = prev
+		}
+		prev = types[i]
+	}
+	// W2: European numbers after Arabic letters are Arabic numbers, then W3: Arabic letters are right to left
+	strong := sos
+	for i, t := range types {
+		switch t {
+		case bidiL, bidiR, bidiAL:
+			strong = t
+		case bidiEN:
+			if strong == bidiAL {
+				types[i] = bidiAN
+			}
+		}
+	}
+	for i, t := range types {
+		if t == bidiAL {
+			types[i] = bidiR
+		}
+	}
+	// W4: a single separator between two numbers of the same kind joins them
+	for i := 1; i+1 < len(types); i++ {
+		before, after := types[i-1], types[i+1]
+		if before != after {
+			continue
+		}
+		if (types[i] == bidiES && before == bidiEN) || (types[i] == bidiCS && (before == bidiEN || before == bidiAN)) {
+			types[i] = before
+		}
+	}
+	// W5: terminators next to European numbers are part of them
+	for i := 0; i < len(types); i++ {
+		if types[i] != bidiET {
+			continue
+		}
+		end := i
+		for end < len(types) && types[end] == bidiET {
+			end++
+		}
+		if (i > 0 && types[i-1] == bidiEN) || (end < len(types) && types[end] == bidiEN) {
+			for j := i; j < end; j++ {
+				types[j] = bidiEN
+			}
+		}
+		i = end
+	}
+	// W6: the remaining separators and terminators are neutral, then W7: European numbers in left to right
+	// text are left to right
+	strong = sos
+	for i, t := range types {
+		switch t {
+		case bidiES, bidiET, bidiCS:
+			types[i] = bidiON
+		case bidiL, bidiR:
+			strong = t
+		case bidiEN:
+			if strong == bidiL {
+				types[i] = bidiL
+			}
+		}
+	}
+	// N1 and N2: neutrals between text of the same direction take it, otherwise the paragraph's
+	strongOf := func(t bidiClass) bidiClass {
+		if t == bidiL {
+			return bidiL
+		}
+		return bidiR
+	}
+	for i := 0; i < len(types); i++ {
+		if types[i] != bidiWS && types[i] != bidiON {
+			continue
+		}
+		end := i
+		for end < len(types) && (types[end] == bidiWS || types[end] == bidiON) {
+			end++
+		}
+		before, after := sos, sos
+		if i > 0 {
+			before = strongOf(types[i-1])
+		}
+		if end < len(types) {
+			after = strongOf(types[end])
+		}
+		resolved := sos
+		if before == after {
+			resolved = before
+		}
+		for j := i; j < end; j++ {
+			types[j] = resolved
+		}
+		i = end
+	}
+
+	// I1 and I2: the implicit levels
+	levels := make([]int, len(types))
+	for i, t := range types {
+		levels[i] = base
+		switch {
+		case base == 0 && t == bidiR:
+			levels[i] = 1
+		case base == 0 && (t == bidiAN || t == bidiEN):
+			levels[i] = 2
+		case base == 1 && t != bidiR:
+			levels[i] = 2
+		}
+	}
+	// L1: whitespace at the end of the line goes back to the paragraph's level
+	for i := len(classes) - 1; i >= 0 && classes[i] == bidiWS; i-- {
+		levels[i] = base
+	}
+	return levels
+}
+
+// VisualCells returns the cells of the line in the order they're shown from left to right, with the text in
+// them laid out by the Unicode bidirectional algorithm, and from, the column each displayed cell is from. Lines
+// with right to left text are padded to width with blank, so they can be laid out from the right. Both are nil
+// if there's nothing to reorder.
+func (line *Line) VisualCells(width int, blank Cell, direction BidiDirection) (cells []Cell, from []int) {
+	classes := make([]bidiClass, 0, width)
+	rtl := direction == BidiRTL
+	for i := range line.cells {
+		c := line.cells[i]
+		if c.continuation && i > 0 {
+			// the right half of a wide character goes wherever the left half does
+			classes = append(classes, classes[i-1])
+			continue
+		}
+		class := classifyBidi(c.r)
+		if class == bidiR || class == bidiAL || class == bidiAN {
+			rtl = true
+		}
+		classes = append(classes, class)
+	}
+	if !rtl {
+		return nil, nil
+	}
+
+	cells = append(make([]Cell, 0, width), line.cells...)
+	for len(cells) < width {
+		cells = append(cells, blank)
+		classes = append(classes, bidiWS)
+	}
+
+	levels := bidiLevels(classes, direction)
+	from = make([]int, len(cells))
+	highest, lowestOdd := 0, -1
+	for i, level := range levels {
+		from[i] = i
+		if level > highest {
+			highest = level
+		}
+		if level%2 == 1 && (lowestOdd < 0 || level < lowestOdd) {
+			lowestOdd = level
+		}
+	}
+
+	// L2: reverse every run at each level from the highest down to the lowest odd one
+	for level := highest; lowestOdd >= 0 && level >= lowestOdd; level-- {
+		for i := 0; i < len(from); i++ {
+			if levels[from[i]] < level {
+				continue
+			}
+			end := i
+			for end < len(from) && levels[from[end]] >= level {
+				end++
+			}
+			for l, r := i, end-1; l < r; l, r = l+1, r-1 {
+				from[l], from[r] = from[r], from[l]
+			}
+			i = end
+		}
+	}
+	// a reversed wide character has its halves the wrong way round
+	for i := 0; i+1 < len(from); i++ {
+		if cells[from[i]].continuation && from[i+1] == from[i]-1 {
+			from[i], from[i+1] = from[i+1], from[i]
+			i++
+		}
+	}
+
+	visual := make([]Cell, len(cells))
+	for i, j := range from {
+		visual[i] = cells[j]
+		// L4: brackets and the like face the other way in right to left text
+		if mirrored, ok := mirroredRunes[visual[i].r]; ok && levels[j]%2 == 1 {
+			visual[i].r = mirrored
+		}
+	}
+	return visual, from
+}
@@ -0,0 +1,78 @@
+package buffer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func lineOf(text string) *Line {
+	line := &Line{}
+	for _, r := range text {
+		var cell Cell
+		cell.setRune(r)
+		line.cells = append(line.cells, cell)
+		if RuneWidth(r) == 2 {
+			line.cells[len(line.cells)-1].wide = true
+			line.cells = append(line.cells, Cell{continuation: true})
+		}
+	}
+	return line
+}
+
+func visualText(cells []Cell) string {
+	var text strings.Builder
+	for _, cell := range cells {
+		if !cell.continuation {
+			text.WriteString(cell.Text())
+		}
+	}
+	return text.String()
+}
+
+func TestLeftToRightTextIsntReordered(t *testing.T) {
+	cells, from := lineOf("hello (world) 123").VisualCells(20, Cell{}, BidiAuto)
+	assert.Nil(t, cells)
+	assert.Nil(t, from)
+}
+
+func TestHebrewInLeftToRightText(t *testing.T) {
+	cells, from := lineOf("hi שלום!").VisualCells(8, Cell{}, BidiAuto)
+	assert.Equal(t, "hi םולש!", visualText(cells))
+	assert.Equal(t, []int{0, 1, 2, 6, 5, 4, 3, 7}, from)
+}
+
+func TestRightToLeftLinesAreLaidOutFromTheRight(t *testing.T) {
+	cells, from := lineOf("שלום 123").VisualCells(10, Cell{}, BidiAuto)
+	assert.Equal(t, "  123 םולש", visualText(cells), "numbers stay left to right")
+	assert.Equal(t, 0, from[9], "the first letter is at the right")
+	assert.Equal(t, 9, from[0], "the padding is at the left")
+}
+
+func TestBracketsAreMirroredInRightToLeftText(t *testing.T) {
+	cells, _ := lineOf("(שלום)").VisualCells(6, Cell{}, BidiAuto)
+	assert.Equal(t, "(םולש)", visualText(cells))
+}
+
+func TestForcedDirection(t *testing.T) {
+	cells, _ := lineOf("abc").VisualCells(5, Cell{}, BidiRTL)
+	assert.Equal(t, "  abc", visualText(cells))
+
+	cells, _ = lineOf("שלום abc").VisualCells(8, Cell{}, BidiLTR)
+	assert.Equal(t, "םולש abc", visualText(cells))
+}
+
+func TestArabicNumbers(t *testing.T) {
+	cells, _ := lineOf("عدد ١٢").VisualCells(6, Cell{}, BidiAuto)
+	assert.Equal(t, "١٢ ددع", visualText(cells))
+}
+
+func TestWideCharactersKeepTheirHalvesTogether(t *testing.T) {
+	cells, from := lineOf("א😀ב").VisualCells(4, Cell{}, BidiAuto)
+	require.Len(t, cells, 4)
+	assert.Equal(t, []int{3, 1, 2, 0}, from)
+	assert.True(t, cells[1].Wide())
+	assert.True(t, cells[2].Continuation())
+}
@@ -0,0 +1,76 @@
+package buffer
+
+import "sort"
+
+// Bookmark is a named position in the scrollback
+type Bookmark struct {
+	Name string
+	line uint64 // absolute line number, counting lines already discarded from the scrollback
+}
+
+// TopVisibleLine returns the raw line currently displayed at the top of the view
+func (buffer *Buffer) TopVisibleLine() int {
+	top := buffer.Height() - int(buffer.ViewHeight()) - int(buffer.terminalState.scrollLinesFromBottom)
+	if top < 0 {
+		top = 0
+	}
+	return top
+}
+
+// AddBookmark drops a named bookmark at the given raw line
+func (buffer *Buffer) AddBookmark(name string, rawLine int) {
+	buffer.bookmarks = append(buffer.bookmarks, Bookmark{
+		Name: name,
+		line: buffer.discardedLines + uint64(rawLine),
+	})
+	sort.SliceStable(buffer.bookmarks, func(i, j int) bool {
+		return buffer.bookmarks[i].line < buffer.bookmarks[j].line
+	})
+}
+
+// Bookmarks returns all bookmarks which still point into the scrollback, ordered from oldest to newest
+func (buffer *Buffer) Bookmarks() []Bookmark {
+	buffer.pruneBookmarks()
+	return buffer.bookmarks
+}
+
+// RemoveBookmark deletes the bookmark at the given index of Bookmarks()
+func (buffer *Buffer) RemoveBookmark(index int) {
+	if index < 0 || index >= len(buffer.bookmarks) {
+		return
+	}
+	buffer.bookmarks = append(buffer.bookmarks[:index], buffer.bookmarks[index+1:]...)
+}
+
+// BookmarkLine returns the raw line a bookmark currently points at
+func (buffer *Buffer) BookmarkLine(bookmark Bookmark) int {
+	return int(bookmark.line - buffer.discardedLines)
+}
+
+// NextBookmark returns the first bookmark after the given raw line
+func (buffer *Buffer) NextBookmark(rawLine int) *Bookmark {
+	for _, bookmark := range buffer.Bookmarks() {
+		if buffer.BookmarkLine(bookmark) > rawLine {
+			return &bookmark
+		}
+	}
+	return nil
+}
+
+// PreviousBookmark returns the last bookmark before the given raw line
+func (buffer *Buffer) PreviousBookmark(rawLine int) *Bookmark {
+	bookmarks := buffer.Bookmarks()
+	for i := len(bookmarks) - 1; i >= 0; i-- {
+		if buffer.BookmarkLine(bookmarks[i]) < rawLine {
+			return &bookmarks[i]
+		}
+	}
+	return nil
+}
+
+// drop bookmarks for lines which have fallen out of the scrollback
+func (buffer *Buffer) pruneBookmarks() {
+	for len(buffer.bookmarks) > 0 && buffer.bookmarks[0].line < buffer.discardedLines {
+		buffer.bookmarks = buffer.bookmarks[1:]
+	}
+}
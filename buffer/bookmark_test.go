@@ -0,0 +1,41 @@
+package buffer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBookmarksSurviveScrollbackTrimming(t *testing.T) {
+	b := NewBuffer(NewTerminalState(80, 2, CellAttributes{}, 4))
+	b.terminalState.LineFeedMode = false
+
+	b.Write([]rune("one")...)
+	b.NewLine()
+	b.Write([]rune("two")...)
+	b.NewLine()
+	b.Write([]rune("three")...)
+	b.AddBookmark("two", 1)
+	b.AddBookmark("one", 0)
+
+	bookmarks := b.Bookmarks()
+	require.Equal(t, 2, len(bookmarks))
+	assert.Equal(t, "one", bookmarks[0].Name)
+
+	b.NewLine()
+	b.Write([]rune("four")...)
+	b.NewLine()
+	b.Write([]rune("five")...)
+
+	bookmarks = b.Bookmarks()
+	require.Equal(t, 1, len(bookmarks))
+	assert.Equal(t, "two", bookmarks[0].Name)
+	assert.Equal(t, "two", b.lines[b.BookmarkLine(bookmarks[0])].String())
+
+	next := b.NextBookmark(-1)
+	require.NotNil(t, next)
+	assert.Equal(t, "two", next.Name)
+	assert.Nil(t, b.NextBookmark(b.BookmarkLine(bookmarks[0])))
+	assert.Nil(t, b.PreviousBookmark(0))
+}
@@ -7,30 +7,49 @@ import (
 	"net/url"
 	"os"
 	"strings"
+	"unicode"
 )
 
 type SelectionMode int
 
 const (
-	SelectionChar SelectionMode = iota // char-by-char selection
-	SelectionWord SelectionMode = iota // by word selection
-	SelectionLine SelectionMode = iota // whole line selection
+	SelectionChar   SelectionMode = iota // char-by-char selection
+	SelectionWord   SelectionMode = iota // by word selection
+	SelectionLine   SelectionMode = iota // whole line selection
+	SelectionBlock  SelectionMode = iota // rectangular selection, e.g. for copying a column of a table
+	SelectionOutput SelectionMode = iota // the whole output of a command, as marked by shell integration
 )
 
+// DefaultWordChars are the characters selected as part of a word along with letters and numbers, unless the
+// buffer is given others
+const DefaultWordChars = "!#$%&*+-./<=>?@\\^_`|~"
+
 type Buffer struct {
 	lines                 []Line
 	displayChangeHandlers []chan bool
 	savedX                uint16
 	savedY                uint16
+	savedWrapPending      bool
 	savedCursorAttr       *CellAttributes
 	dirty                 bool
+	damage                Damage // rows of the view which have changed since they were last drawn
 	selectionStart        *Position
 	selectionEnd          *Position
 	selectionMode         SelectionMode
+	wordChars             string // characters other than letters and numbers which are part of a word
 	isSelectionComplete   bool
 	terminalState         *TerminalState
 	savedCharsets         []*map[rune]rune
 	savedCurrentCharset   int
+	discardedLines        uint64 // number of lines dropped off the top of the scrollback so far
+	bookmarks             []Bookmark
+	prompts               []promptMark // commands marked by the shell, oldest first
+	previousOutput        *outputBaseline
+	snapshots             []Snapshot
+	spill                 *Spill // lines dropped off the top are written here, if set
+	spillErr              error
+	noScrollback          bool   // lines scrolled off the top are discarded, as on the alternate screen
+	rewrites              uint64 // counts changes to lines in the scrollback, which otherwise stay as they are
 }
 
 type Position struct {
@@ -58,10 +77,29 @@ func NewBuffer(terminalState *TerminalState) *Buffer {
 		selectionMode:       SelectionChar,
 		isSelectionComplete: true,
 		terminalState:       terminalState,
+		wordChars:           DefaultWordChars,
 	}
 	return b
 }
 
+// NewAltBuffer creates a buffer for the alternate screen, which never keeps lines scrolled off the top
+func NewAltBuffer(terminalState *TerminalState) *Buffer {
+	b := NewBuffer(terminalState)
+	b.noScrollback = true
+	return b
+}
+
+// discardScrollback drops any lines above the view from a buffer without scrollback
+func (buffer *Buffer) discardScrollback() {
+	if !buffer.noScrollback {
+		return
+	}
+	if extra := len(buffer.lines) - int(buffer.ViewHeight()); extra > 0 {
+		releaseLines(buffer.lines[:extra])
+		buffer.lines = buffer.lines[:copy(buffer.lines, buffer.lines[extra:])]
+	}
+}
+
 func (buffer *Buffer) GetURLAtPosition(col uint16, viewRow uint16) string {
 	row := buffer.convertViewLineToRawLine((viewRow)) - uint64(buffer.terminalState.scrollLinesFromBottom)
 
@@ -117,7 +155,11 @@ func (buffer *Buffer) findEndOfWord(col int, row int) int {
 		if cell == nil {
 			break
 		}
-		if isRuneWordSelectionMarker(cell.Rune()) {
+		if cell.Continuation() {
+			end = i
+			continue
+		}
+		if !buffer.isWordRune(cell.Rune()) {
 			break
 		}
 		end = i
@@ -132,7 +174,10 @@ func (buffer *Buffer) findBeginningOfWord(col int, row int) int {
 		if cell == nil {
 			break
 		}
-		if isRuneWordSelectionMarker(cell.Rune()) {
+		if cell.Continuation() {
+			continue
+		}
+		if !buffer.isWordRune(cell.Rune()) {
 			break
 		}
 		start = i
@@ -140,8 +185,18 @@ func (buffer *Buffer) findBeginningOfWord(col int, row int) int {
 	return start
 }
 
-// bounds for word selection
-func isRuneWordSelectionMarker(r rune) bool {
+// SetWordChars sets the characters which are selected as part of a word along with letters and numbers
+func (buffer *Buffer) SetWordChars(chars string) {
+	buffer.wordChars = chars
+}
+
+// isWordRune returns true if r is part of a word for word selection
+func (buffer *Buffer) isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune(buffer.wordChars, r)
+}
+
+// bounds for words in hints
+func isRuneHintMarker(r rune) bool {
 	switch r {
 	case ',', ' ', ':', ';', 0, '\'', '"', '[', ']', '(', ')', '{', '}':
 		return true
@@ -159,12 +214,23 @@ func isRuneURLSelectionMarker(r rune) bool {
 	return false
 }
 
+// GetSelectedText returns the selected text, with lines the terminal wrapped joined back together
 func (buffer *Buffer) GetSelectedText() string {
+	return buffer.GetSelectedTextEx(true)
+}
+
+// GetSelectedTextEx returns the selected text without the blanks at the end of each line. Lines the terminal
+// wrapped are joined back together, or split where they were wrapped if joinWrapped is false.
+func (buffer *Buffer) GetSelectedTextEx(joinWrapped bool) string {
 	start, end := buffer.getActualSelection()
 	if start == nil || end == nil {
 		return ""
 	}
 
+	if buffer.selectionMode == SelectionBlock {
+		return buffer.getBlockText(start, end)
+	}
+
 	var builder strings.Builder
 	builder.Grow(int(buffer.terminalState.viewWidth) * (end.Line - start.Line + 1)) // reserve space to minimize allocations
 
@@ -173,34 +239,60 @@ func (buffer *Buffer) GetSelectedText() string {
 			break
 		}
 
-		line := buffer.lines[row]
+		line := &buffer.lines[row]
 
 		minX := 0
 		maxX := int(buffer.terminalState.viewWidth) - 1
 		if row == start.Line {
 			minX = start.Col
-		} else if !line.wrapped {
+		} else if !line.wrapped || !joinWrapped {
 			builder.WriteString("\n")
 		}
 		if row == end.Line {
 			maxX = end.Col
 		}
+		if maxX >= len(line.cells) {
+			maxX = len(line.cells) - 1
+		}
 
-		for col := minX; col <= maxX; col++ {
-			if col >= len(line.cells) {
-				break
+		// blanks before a line break are just the empty part of the screen, but where the line carries on
+		// into the next they're part of the text
+		joined := joinWrapped && row < end.Line && row+1 < len(buffer.lines) && buffer.lines[row+1].wrapped
+		if !joined {
+			for maxX >= minX && line.cells[maxX].blank() {
+				maxX--
 			}
-			r := line.cells[col].Rune()
-			if r == 0x00 {
-				r = ' '
+		}
+
+		for col := minX; col <= maxX; col++ {
+			if line.cells[col].Continuation() {
+				continue
 			}
-			builder.WriteRune(r)
+			builder.WriteString(line.cells[col].Text())
 		}
 	}
 
 	return builder.String()
 }
 
+// getBlockText returns the text in the rectangle with corners start and end, one line per row
+func (buffer *Buffer) getBlockText(start *Position, end *Position) string {
+	rows := []string{}
+	for row := start.Line; row <= end.Line && row < len(buffer.lines); row++ {
+		var text strings.Builder
+		cells := buffer.lines[row].cells
+		for col := start.Col; col <= end.Col && col < len(cells); col++ {
+			if cells[col].Continuation() {
+				continue
+			}
+			text.WriteString(cells[col].Text())
+		}
+		// the column edge usually falls in the whitespace between columns
+		rows = append(rows, strings.TrimRight(text.String(), " "))
+	}
+	return strings.Join(rows, "\n")
+}
+
 func (buffer *Buffer) StartSelection(col uint16, viewRow uint16, mode SelectionMode) {
 	row := buffer.convertViewLineToRawLine(viewRow) - uint64(buffer.terminalState.scrollLinesFromBottom)
 	buffer.selectionMode = mode
@@ -210,7 +302,7 @@ func (buffer *Buffer) StartSelection(col uint16, viewRow uint16, mode SelectionM
 		Line: int(row),
 	}
 
-	if mode == SelectionChar {
+	if mode == SelectionChar || mode == SelectionBlock {
 		buffer.selectionEnd = nil
 	} else {
 		buffer.selectionEnd = &Position{
@@ -248,6 +340,74 @@ func (buffer *Buffer) ExtendSelection(col uint16, viewRow uint16, complete bool)
 	}
 }
 
+// ExtendSelectionTo moves the nearer end of a finished selection to (col, viewRow), keeping its mode, as when shift
+// clicking. The selection is left unfinished, so it can be dragged further until ExtendSelection finishes it. It
+// returns false if there's no selection to extend.
+func (buffer *Buffer) ExtendSelectionTo(col uint16, viewRow uint16) bool {
+	if !buffer.isSelectionComplete || buffer.selectionStart == nil || buffer.selectionEnd == nil {
+		return false
+	}
+
+	defer buffer.emitDisplayChange()
+
+	row := buffer.convertViewLineToRawLine(viewRow) - uint64(buffer.terminalState.scrollLinesFromBottom)
+	pos := Position{Col: int(col), Line: int(row)}
+
+	// the end further away stays where it is, as the anchor
+	width := int(buffer.ViewWidth())
+	offset := func(p *Position) int {
+		distance := (p.Line-pos.Line)*width + p.Col - pos.Col
+		if distance < 0 {
+			return -distance
+		}
+		return distance
+	}
+	if offset(buffer.selectionStart) < offset(buffer.selectionEnd) {
+		buffer.selectionStart, buffer.selectionEnd = buffer.selectionEnd, buffer.selectionStart
+	}
+	buffer.selectionEnd = &pos
+	buffer.isSelectionComplete = false
+	return true
+}
+
+// MoveSelectionEnd moves the end of a finished selection which was last extended by the given number of columns
+// and rows, keeping it inside the buffer. Moving past either edge of the view carries on from the other edge of the
+// line above or below. It returns the new end, or false if there's no selection to move.
+func (buffer *Buffer) MoveSelectionEnd(cols int, rows int) (Position, bool) {
+	if !buffer.isSelectionComplete || buffer.selectionStart == nil || buffer.selectionEnd == nil {
+		return Position{}, false
+	}
+
+	defer buffer.emitDisplayChange()
+
+	width := int(buffer.ViewWidth())
+	end := *buffer.selectionEnd
+	end.Line += rows
+	end.Col += cols
+	for end.Col < 0 && end.Line > 0 {
+		end.Col += width
+		end.Line--
+	}
+	for end.Col >= width && end.Line < len(buffer.lines)-1 {
+		end.Col -= width
+		end.Line++
+	}
+
+	if end.Line < 0 {
+		end.Line = 0
+	} else if end.Line >= len(buffer.lines) {
+		end.Line = len(buffer.lines) - 1
+	}
+	if end.Col < 0 {
+		end.Col = 0
+	} else if end.Col >= width {
+		end.Col = width - 1
+	}
+
+	buffer.selectionEnd = &end
+	return end, true
+}
+
 func (buffer *Buffer) ClearSelection() {
 	buffer.selectionStart = nil
 	buffer.selectionEnd = nil
@@ -264,6 +424,19 @@ func (buffer *Buffer) getActualSelection() (*Position, *Position) {
 	start := &Position{}
 	end := &Position{}
 
+	if buffer.selectionMode == SelectionBlock {
+		// the corners can be dragged out in any direction, so take the top left and bottom right
+		start.Line, end.Line = buffer.selectionStart.Line, buffer.selectionEnd.Line
+		if start.Line > end.Line {
+			start.Line, end.Line = end.Line, start.Line
+		}
+		start.Col, end.Col = buffer.selectionStart.Col, buffer.selectionEnd.Col
+		if start.Col > end.Col {
+			start.Col, end.Col = end.Col, start.Col
+		}
+		return start, end
+	}
+
 	if comparePositions(buffer.selectionStart, buffer.selectionEnd) >= 0 {
 		start.Col = buffer.selectionStart.Col
 		start.Line = buffer.selectionStart.Line
@@ -287,8 +460,17 @@ func (buffer *Buffer) getActualSelection() (*Position, *Position) {
 		end.Col = buffer.findEndOfWord(end.Col, end.Line)
 
 	case SelectionLine:
-		start.Col = 0
-		end.Col = int(buffer.ViewWidth() - 1)
+		buffer.expandToLines(start, end)
+
+	case SelectionOutput:
+		// clicking outside of any output selects the line instead
+		if from, _, ok := buffer.outputAt(start.Line); ok {
+			start.Line = from
+		}
+		if _, to, ok := buffer.outputAt(end.Line); ok {
+			end.Line = to - 1
+		}
+		buffer.expandToLines(start, end)
 	}
 
 	if start.Line >= len(buffer.lines) {
@@ -304,6 +486,19 @@ func (buffer *Buffer) getActualSelection() (*Position, *Position) {
 	return start, end
 }
 
+// expandToLines moves the ends of a selection out to take in the whole of the lines they're in, as they were
+// written before the terminal wrapped them
+func (buffer *Buffer) expandToLines(start *Position, end *Position) {
+	for start.Line > 0 && start.Line < len(buffer.lines) && buffer.lines[start.Line].wrapped {
+		start.Line--
+	}
+	for end.Line+1 < len(buffer.lines) && buffer.lines[end.Line+1].wrapped {
+		end.Line++
+	}
+	start.Col = 0
+	end.Col = int(buffer.ViewWidth() - 1)
+}
+
 func (buffer *Buffer) InSelection(col uint16, row uint16) bool {
 	start, end := buffer.getActualSelection()
 	if start == nil || end == nil {
@@ -312,6 +507,10 @@ func (buffer *Buffer) InSelection(col uint16, row uint16) bool {
 
 	rawY := int(buffer.convertViewLineToRawLine(row) - uint64(buffer.terminalState.scrollLinesFromBottom))
 
+	if buffer.selectionMode == SelectionBlock {
+		return rawY >= start.Line && rawY <= end.Line && int(col) >= start.Col && int(col) <= end.Col
+	}
+
 	return (rawY > start.Line || (rawY == start.Line && int(col) >= start.Col)) &&
 		(rawY < end.Line || (rawY == end.Line && int(col) <= end.Col))
 }
@@ -345,9 +544,24 @@ func (buffer *Buffer) getAreaScrollRange() (top uint64, bottom uint64) {
 func (buffer *Buffer) AreaScrollDown(lines uint16) {
 	defer buffer.emitDisplayChange()
 
+	// the whole area scrolls, even the lines on screen which haven't been written to yet
+	buffer.getViewLine(uint16(buffer.terminalState.bottomMargin))
+
 	// NOTE: bottom is exclusive
 	top, bottom := buffer.getAreaScrollRange()
 
+	if buffer.hasHorizontalMargins() {
+		buffer.scrollColumns(top, bottom, -int(lines))
+		return
+	}
+
+	// the lines pushed off the bottom of the area are gone
+	if bottom > top+uint64(lines) {
+		releaseLines(buffer.lines[bottom-uint64(lines) : bottom])
+	} else if top < bottom {
+		releaseLines(buffer.lines[top:bottom])
+	}
+
 	for i := bottom; i > top; {
 		i--
 		if i >= top+uint64(lines) {
@@ -361,9 +575,24 @@ func (buffer *Buffer) AreaScrollDown(lines uint16) {
 func (buffer *Buffer) AreaScrollUp(lines uint16) {
 	defer buffer.emitDisplayChange()
 
+	// the whole area scrolls, even the lines on screen which haven't been written to yet
+	buffer.getViewLine(uint16(buffer.terminalState.bottomMargin))
+
 	// NOTE: bottom is exclusive
 	top, bottom := buffer.getAreaScrollRange()
 
+	if buffer.hasHorizontalMargins() {
+		buffer.scrollColumns(top, bottom, int(lines))
+		return
+	}
+
+	// the lines pushed off the top of the area are gone
+	if bottom > top+uint64(lines) {
+		releaseLines(buffer.lines[top : top+uint64(lines)])
+	} else if top < bottom {
+		releaseLines(buffer.lines[top:bottom])
+	}
+
 	for i := top; i < bottom; i++ {
 		from := i + uint64(lines)
 		if from < bottom {
@@ -379,6 +608,7 @@ func (buffer *Buffer) SaveCursor() {
 	buffer.savedCursorAttr = &copiedAttr
 	buffer.savedX = buffer.terminalState.cursorX
 	buffer.savedY = buffer.terminalState.cursorY
+	buffer.savedWrapPending = buffer.terminalState.wrapPending
 	buffer.savedCharsets = make([]*map[rune]rune, len(buffer.terminalState.Charsets))
 	copy(buffer.savedCharsets, buffer.terminalState.Charsets)
 	buffer.savedCurrentCharset = buffer.terminalState.CurrentCharset
@@ -391,6 +621,7 @@ func (buffer *Buffer) RestoreCursor() {
 	}
 	buffer.terminalState.cursorX = buffer.savedX
 	buffer.terminalState.cursorY = buffer.savedY
+	buffer.terminalState.wrapPending = buffer.savedWrapPending
 	if buffer.savedCharsets != nil {
 		buffer.terminalState.Charsets = make([]*map[rune]rune, len(buffer.savedCharsets))
 		copy(buffer.terminalState.Charsets, buffer.savedCharsets)
@@ -418,13 +649,25 @@ func (buffer *Buffer) GetRawCell(viewCol uint16, rawLine uint64) *Cell {
 	return &line.cells[viewCol]
 }
 
+// emitDisplayChange marks the whole view as changed
 func (buffer *Buffer) emitDisplayChange() {
+	buffer.damage.All = true
 	buffer.dirty = true
 }
 
-// Column returns cursor column
+// CursorColumn returns the absolute cursor column (ignoring Origin Mode)
 func (buffer *Buffer) CursorColumn() uint16 {
-	// @todo originMode and left margin
+	return buffer.terminalState.cursorX
+}
+
+// CursorColumnRelative returns the cursor column, which in Origin Mode is relative to the left margin
+func (buffer *Buffer) CursorColumnRelative() uint16 {
+	if buffer.terminalState.OriginMode {
+		left, _ := buffer.terminalState.horizontalMargins()
+		if buffer.terminalState.cursorX >= left {
+			return buffer.terminalState.cursorX - left
+		}
+	}
 	return buffer.terminalState.cursorX
 }
 
@@ -458,6 +701,19 @@ func (buffer *Buffer) RawLine() uint64 {
 	return buffer.convertViewLineToRawLine(buffer.terminalState.cursorY)
 }
 
+// CursorLineText returns the text of the line the cursor is on, joined back together with the lines it wrapped from
+func (buffer *Buffer) CursorLineText() string {
+	end := int(buffer.RawLine()) + 1
+	if end > len(buffer.lines) {
+		return ""
+	}
+	start := end - 1
+	for start > 0 && buffer.lines[start].wrapped {
+		start--
+	}
+	return buffer.textFrom(start, 0, end)
+}
+
 func (buffer *Buffer) convertViewLineToRawLine(viewLine uint16) uint64 {
 	rawHeight := buffer.Height()
 	if int(buffer.terminalState.viewHeight) > rawHeight {
@@ -493,6 +749,10 @@ func (buffer *Buffer) ViewHeight() uint16 {
 
 func (buffer *Buffer) deleteLine() {
 	index := int(buffer.RawLine())
+	if index >= len(buffer.lines) {
+		return
+	}
+	releaseCells(buffer.lines[index].cells)
 	buffer.lines = buffer.lines[:index+copy(buffer.lines[index:], buffer.lines[index+1:])]
 }
 
@@ -500,6 +760,7 @@ func (buffer *Buffer) insertLine() {
 	defer buffer.emitDisplayChange()
 
 	if !buffer.InScrollableRegion() {
+		buffer.getCurrentLine() // make sure the lines up to the cursor exist
 		pos := buffer.RawLine()
 		maxLines := buffer.getMaxLines()
 		newLineCount := uint64(len(buffer.lines) + 1)
@@ -507,6 +768,10 @@ func (buffer *Buffer) insertLine() {
 			newLineCount = maxLines
 		}
 
+		buffer.spillLines(buffer.lines[:uint64(len(buffer.lines))+1-newLineCount])
+		releaseLines(buffer.lines[:uint64(len(buffer.lines))+1-newLineCount])
+		buffer.discardedLines += uint64(len(buffer.lines)) + 1 - newLineCount
+
 		out := make([]Line, newLineCount)
 		copy(
 			out[:pos-(uint64(len(buffer.lines))+1-newLineCount)],
@@ -515,6 +780,7 @@ func (buffer *Buffer) insertLine() {
 		copy(out[pos+1:], buffer.lines[pos:])
 		buffer.lines = out
 	} else {
+		buffer.getViewLine(uint16(buffer.terminalState.bottomMargin))
 		topIndex := buffer.convertViewLineToRawLine(uint16(buffer.terminalState.topMargin))
 		bottomIndex := buffer.convertViewLineToRawLine(uint16(buffer.terminalState.bottomMargin))
 		before := buffer.lines[:topIndex]
@@ -533,16 +799,56 @@ func (buffer *Buffer) insertLine() {
 
 		copy(out[bottomIndex+1:], after)
 
+		// the line at the bottom of the region is pushed out
+		releaseCells(buffer.lines[bottomIndex].cells)
 		out[pos] = newLine()
 		buffer.lines = out
 	}
 }
 
 func (buffer *Buffer) InsertBlankCharacters(count int) {
-	index := int(buffer.RawLine())
-	for i := 0; i < count; i++ {
-		cells := buffer.lines[index].cells
-		buffer.lines[index].cells = append(cells[:buffer.terminalState.cursorX], append([]Cell{buffer.terminalState.DefaultCell(true)}, cells[buffer.terminalState.cursorX:]...)...)
+	defer buffer.damageCursorRow()
+
+	line := buffer.getCurrentLine()
+	x := int(buffer.terminalState.cursorX)
+	if buffer.hasHorizontalMargins() {
+		// only the characters up to the right margin move, and only if the cursor is inside the margins
+		if !buffer.inHorizontalMargins() || count <= 0 {
+			return
+		}
+		_, right := buffer.terminalState.horizontalMargins()
+		end := int(right) + 1
+		if count > end-x {
+			count = end - x
+		}
+		buffer.padLine(line, end)
+		copy(line.cells[x+count:end], line.cells[x:end-count])
+		for i := x; i < x+count; i++ {
+			line.cells[i] = buffer.terminalState.DefaultCell(true)
+		}
+		return
+	}
+	width := int(buffer.ViewWidth())
+	if x >= width || count <= 0 {
+		return
+	}
+	if count > width-x {
+		count = width - x
+	}
+
+	for len(line.cells) < x {
+		line.Append(buffer.terminalState.DefaultCell(false))
+	}
+
+	blanks := make([]Cell, count)
+	for i := range blanks {
+		blanks[i] = buffer.terminalState.DefaultCell(true)
+	}
+	line.cells = append(line.cells[:x], append(blanks, line.cells[x:]...)...)
+
+	// characters pushed past the edge of the screen are lost
+	if len(line.cells) > width {
+		line.cells = line.cells[:width]
 	}
 }
 
@@ -552,6 +858,15 @@ func (buffer *Buffer) InsertLines(count int) {
 		return
 	}
 
+	if count > int(buffer.ViewHeight()) {
+		count = int(buffer.ViewHeight())
+	}
+
+	if buffer.hasHorizontalMargins() {
+		buffer.scrollColumnsFromCursor(-count)
+		return
+	}
+
 	buffer.terminalState.cursorX = 0
 
 	for i := 0; i < count; i++ {
@@ -565,6 +880,15 @@ func (buffer *Buffer) DeleteLines(count int) {
 		return
 	}
 
+	if count > int(buffer.ViewHeight()) {
+		count = int(buffer.ViewHeight())
+	}
+
+	if buffer.hasHorizontalMargins() {
+		buffer.scrollColumnsFromCursor(count)
+		return
+	}
+
 	buffer.terminalState.cursorX = 0
 
 	for i := 0; i < count; i++ {
@@ -576,7 +900,19 @@ func (buffer *Buffer) Index() {
 	// This sequence causes the active position to move downward one line without changing the column position.
 	// If the active position is at the bottom margin, a scroll up is performed."
 
-	defer buffer.emitDisplayChange()
+	buffer.terminalState.wrapPending = false
+
+	if buffer.hasHorizontalMargins() {
+		// only the columns between the margins scroll, so nothing goes into the scrollback
+		if uint(buffer.terminalState.cursorY) == buffer.terminalState.bottomMargin {
+			if buffer.inHorizontalMargins() {
+				buffer.AreaScrollUp(1)
+			}
+		} else if buffer.terminalState.cursorY < buffer.ViewHeight()-1 {
+			buffer.terminalState.cursorY++
+		}
+		return
+	}
 
 	if buffer.InScrollableRegion() {
 
@@ -590,9 +926,19 @@ func (buffer *Buffer) Index() {
 	}
 
 	if buffer.terminalState.cursorY >= buffer.ViewHeight()-1 {
+		if buffer.Height() >= int(buffer.ViewHeight()) && buffer.terminalState.scrollLinesFromBottom == 0 {
+			// the screen moves up a row, and only the new one at the bottom needs drawing
+			buffer.damage.scroll(1, int(buffer.ViewHeight()))
+			buffer.damageRow(buffer.ViewHeight() - 1)
+		} else {
+			buffer.emitDisplayChange()
+		}
 		buffer.lines = append(buffer.lines, newLine())
 		maxLines := buffer.getMaxLines()
 		if uint64(len(buffer.lines)) > maxLines {
+			buffer.spillLines(buffer.lines[:uint64(len(buffer.lines))-maxLines])
+			releaseLines(buffer.lines[:uint64(len(buffer.lines))-maxLines])
+			buffer.discardedLines += uint64(len(buffer.lines)) - maxLines
 			copy(buffer.lines, buffer.lines[uint64(len(buffer.lines))-maxLines:])
 			buffer.lines = buffer.lines[:maxLines]
 		}
@@ -602,10 +948,12 @@ func (buffer *Buffer) Index() {
 }
 
 func (buffer *Buffer) ReverseIndex() {
-	defer buffer.emitDisplayChange()
+	buffer.terminalState.wrapPending = false
 
 	if uint(buffer.terminalState.cursorY) == buffer.terminalState.topMargin {
-		buffer.AreaScrollDown(1)
+		if !buffer.hasHorizontalMargins() || buffer.inHorizontalMargins() {
+			buffer.AreaScrollDown(1)
+		}
 	} else if buffer.terminalState.cursorY > 0 {
 		buffer.terminalState.cursorY--
 	}
@@ -613,114 +961,146 @@ func (buffer *Buffer) ReverseIndex() {
 
 // Write will write a rune to the terminal at the position of the cursor, and increment the cursor position
 func (buffer *Buffer) Write(runes ...rune) {
-	defer buffer.emitDisplayChange()
 	// scroll to bottom on input
+	if buffer.terminalState.scrollLinesFromBottom > 0 {
+		buffer.emitDisplayChange()
+	}
 	buffer.terminalState.scrollLinesFromBottom = 0
 
 	for _, r := range runes {
 
-		line := buffer.getCurrentLine()
-
-		if buffer.terminalState.ReplaceMode {
-
-			if buffer.CursorColumn() >= buffer.Width() {
-				// @todo replace rune at position 0 on next line down
-				return
-			}
-
-			for int(buffer.CursorColumn()) >= len(line.cells) {
-				line.Append(buffer.terminalState.DefaultCell(int(buffer.CursorColumn()) == len(line.cells)))
-			}
-			line.cells[buffer.terminalState.cursorX].attr = buffer.terminalState.CursorAttr
-			line.cells[buffer.terminalState.cursorX].setRune(r)
-			buffer.incrementCursorPosition()
+		width := RuneWidth(r)
+		if width == 0 {
+			buffer.writeCombining(r)
+			buffer.damageCursorRow()
 			continue
 		}
 
-		if buffer.CursorColumn() >= buffer.Width() { // if we're after the line, move to next
+		if buffer.terminalState.wrapPending {
+			// the last column was written by the previous character, so this one goes on the next line
+			buffer.terminalState.wrapPending = false
+			if buffer.terminalState.AutoWrap {
+				buffer.wrapToNextLine()
+			}
+		}
 
+		if edge := buffer.rightEdge(); width == 2 && buffer.CursorColumn() >= edge {
+			if edge <= buffer.leftEdge() {
+				continue
+			}
+			// a wide character doesn't fit in the last column, so it goes at the start of the next line
+			// instead, or over the last two columns if the line can't wrap
 			if buffer.terminalState.AutoWrap {
+				buffer.writeCell(' ', false)
+				buffer.wrapToNextLine()
+			} else {
+				buffer.terminalState.cursorX = edge - 1
+			}
+		}
 
-				buffer.NewLineEx(true)
+		if buffer.terminalState.InsertMode {
+			// the rest of the line moves right to make room, rather than being written over
+			buffer.InsertBlankCharacters(width)
+		}
+		buffer.writeCell(r, width == 2)
+		if width == 2 {
+			buffer.terminalState.cursorX++
+			buffer.writeCell(0, false).continuation = true
+		}
+		buffer.damageCursorRow()
 
-				newLine := buffer.getCurrentLine()
-				if len(newLine.cells) == 0 {
-					newLine.Append(buffer.terminalState.DefaultCell(true))
-				}
-				cell := &newLine.cells[0]
-				cell.setRune(r)
-				cell.attr = buffer.terminalState.CursorAttr
+		buffer.incrementCursorPosition()
+	}
+}
 
-			} else {
-				// no more room on line and wrapping is disabled
-				return
-			}
+// writeCell puts r in the cell under the cursor, without moving the cursor, and returns the cell. Any wide
+// character it overwrites half of is erased.
+func (buffer *Buffer) writeCell(r rune, wide bool) *Cell {
+	line := buffer.getCurrentLine()
+	col := int(buffer.CursorColumn())
 
-			// @todo if next line is wrapped then prepend to it and shuffle characters along line, wrapping to next if necessary
-		} else {
+	for col >= len(line.cells) {
+		line.Append(buffer.terminalState.DefaultCell(col == len(line.cells)))
+	}
 
-			for int(buffer.CursorColumn()) >= len(line.cells) {
-				line.Append(buffer.terminalState.DefaultCell(int(buffer.CursorColumn()) == len(line.cells)))
-			}
+	cell := &line.cells[col]
+	if cell.continuation && col > 0 {
+		line.cells[col-1].setRune(0)
+	}
+	if cell.wide && col+1 < len(line.cells) {
+		line.cells[col+1].setRune(0)
+	}
+	cell.setRune(r)
+	cell.wide = wide
+	cell.attr = buffer.terminalState.CursorAttr
+	return cell
+}
 
-			cell := &line.cells[buffer.CursorColumn()]
-			cell.setRune(r)
-			cell.attr = buffer.terminalState.CursorAttr
-		}
+// writeCombining attaches a zero width character to the one written before it. It's dropped if there's
+// nothing before it on the line.
+func (buffer *Buffer) writeCombining(r rune) {
+	line := buffer.getCurrentLine()
+	col := int(buffer.CursorColumn())
+	if !buffer.terminalState.wrapPending {
+		// the cursor is after the previous character, unless it was written in the last column
+		col--
+	}
+	if col >= 0 && col < len(line.cells) && line.cells[col].continuation {
+		col--
+	}
+	if col < 0 || col >= len(line.cells) || line.cells[col].r == 0 {
+		return
+	}
+	line.cells[col].combining = append(line.cells[col].combining, r)
+}
 
-		buffer.incrementCursorPosition()
+// wrapToNextLine moves the cursor to the start of the next line, marking it as a continuation of the current one.
+// Text between left and right margins isn't a continuation of whole lines, so it's wrapped without marking them.
+func (buffer *Buffer) wrapToNextLine() {
+	buffer.terminalState.cursorX = buffer.leftEdge()
+	buffer.Index()
+	if !buffer.hasHorizontalMargins() {
+		buffer.getCurrentLine().setWrapped(true)
 	}
 }
 
 func (buffer *Buffer) incrementCursorPosition() {
-	// we can increment one column past the end of the line.
-	// this is effectively the beginning of the next line, except when we \r etc.
-	if buffer.CursorColumn() < buffer.Width() {
+	// like xterm, the cursor stays on the last column after writing to it, and the wrap happens
+	// when the next character arrives. Anything which moves the cursor in the meantime cancels it.
+	if buffer.CursorColumn() < buffer.rightEdge() {
 		buffer.terminalState.cursorX++
+	} else if buffer.terminalState.AutoWrap {
+		buffer.terminalState.wrapPending = true
 	}
 }
 
 func (buffer *Buffer) inDoWrap() bool {
 	// xterm uses 'do_wrap' flag for this special terminal state
-	// we use the cursor position right after the boundary
-	// let's see how it works out
-	return buffer.terminalState.cursorX == buffer.terminalState.viewWidth // @todo rightMargin
+	return buffer.terminalState.wrapPending
 }
 
 func (buffer *Buffer) Backspace() {
-	if buffer.terminalState.cursorX == 0 {
+	if buffer.inDoWrap() {
+		// the pending wrap is cancelled, and the cursor moves back from the last column
+		buffer.terminalState.wrapPending = false
+		buffer.MovePosition(-1, 0)
+	} else if buffer.terminalState.cursorX == buffer.leftEdge() {
 		line := buffer.getCurrentLine()
-		if line.wrapped {
-			buffer.MovePosition(int16(buffer.Width()-1), -1)
-		} else {
-			//@todo ring bell or whatever - actually i think the pty will trigger this
+		if buffer.terminalState.ReverseWrap && line.wrapped && buffer.terminalState.cursorY > 0 {
+			buffer.SetPosition(buffer.Width()-1, buffer.CursorLine()-1)
 		}
-	} else if buffer.inDoWrap() {
-		// the "do_wrap" implementation
-		buffer.MovePosition(-2, 0)
 	} else {
 		buffer.MovePosition(-1, 0)
 	}
 }
 
 func (buffer *Buffer) CarriageReturn() {
-	for {
-		line := buffer.getCurrentLine()
-		if line == nil {
-			break
-		}
-		if line.wrapped && buffer.terminalState.cursorY > 0 {
-			buffer.terminalState.cursorY--
-		} else {
-			break
-		}
-	}
-
-	buffer.terminalState.cursorX = 0
+	buffer.terminalState.cursorX = buffer.leftEdge()
+	buffer.terminalState.wrapPending = false
 }
 
 func (buffer *Buffer) Tab() {
-	for buffer.terminalState.cursorX < buffer.terminalState.viewWidth-1 { // @todo rightMargin
+	for buffer.terminalState.cursorX < buffer.rightEdge() {
 		buffer.Write(' ')
 		if buffer.terminalState.IsTabSetAtCursor() {
 			break
@@ -734,17 +1114,10 @@ func (buffer *Buffer) NewLine() {
 
 func (buffer *Buffer) NewLineEx(forceCursorToMargin bool) {
 	if buffer.terminalState.IsNewLineMode() || forceCursorToMargin {
-		buffer.terminalState.cursorX = 0
+		buffer.terminalState.cursorX = buffer.leftEdge()
 	}
 	buffer.Index()
-
-	for {
-		line := buffer.getCurrentLine()
-		if !line.wrapped {
-			break
-		}
-		buffer.Index()
-	}
+	buffer.getCurrentLine() // creates the line if necessary
 }
 
 func (buffer *Buffer) IsNewLineMode() bool {
@@ -752,14 +1125,21 @@ func (buffer *Buffer) IsNewLineMode() bool {
 }
 
 func (buffer *Buffer) MovePosition(x int16, y int16) {
-	var toX uint16
 	var toY uint16
 
-	if int16(buffer.CursorColumn())+x < 0 {
-		toX = 0
-	} else {
-		toX = uint16(int16(buffer.CursorColumn()) + x)
+	// the cursor stops at the margins, unless it's already outside them
+	left, right := buffer.leftEdge(), buffer.rightEdge()
+	col := int(buffer.CursorColumn()) + int(x)
+	if col < int(left) {
+		col = int(left)
+	}
+	if col > int(right) {
+		col = int(right)
 	}
+	if buffer.terminalState.OriginMode {
+		col -= int(left)
+	}
+	toX := uint16(col)
 
 	// should either use CursorLine() and SetPosition() or use absolutes, mind Origin Mode (DECOM)
 	if int16(buffer.CursorLine())+y < 0 {
@@ -772,28 +1152,30 @@ func (buffer *Buffer) MovePosition(x int16, y int16) {
 }
 
 func (buffer *Buffer) SetPosition(col uint16, line uint16) {
-	defer buffer.emitDisplayChange()
-
 	useCol := col
 	useLine := line
 	maxLine := buffer.ViewHeight() - 1
 
+	maxCol := buffer.ViewWidth() - 1
+
 	if buffer.terminalState.OriginMode {
+		left, right := buffer.terminalState.horizontalMargins()
 		useLine += uint16(buffer.terminalState.topMargin)
 		maxLine = uint16(buffer.terminalState.bottomMargin)
-		// @todo left and right margins
+		useCol += left
+		maxCol = right
 	}
 	if useLine > maxLine {
 		useLine = maxLine
 	}
 
-	if useCol >= buffer.ViewWidth() {
-		useCol = buffer.ViewWidth() - 1
-		// logrus.Errorf("Cannot set cursor position: column %d is outside of the current view width (%d columns)", col, buffer.ViewWidth())
+	if useCol > maxCol {
+		useCol = maxCol
 	}
 
 	buffer.terminalState.cursorX = useCol
 	buffer.terminalState.cursorY = useLine
+	buffer.terminalState.wrapPending = false
 }
 
 func (buffer *Buffer) GetVisibleLines() []Line {
@@ -815,9 +1197,44 @@ func (buffer *Buffer) Clear() {
 	for i := 0; i < int(buffer.ViewHeight()); i++ {
 		buffer.lines = append(buffer.lines, newLine())
 	}
+	buffer.discardScrollback()
 	buffer.SetPosition(0, 0) // do we need to set position?
 }
 
+// ClearAll discards the scrollback and everything on screen except the cursor's line, which moves to the top
+// of the screen, so the shell prompt the cursor is usually on stays put
+func (buffer *Buffer) ClearAll() {
+	defer buffer.emitDisplayChange()
+	buffer.ClearScrollback()
+	buffer.ClearSelection()
+
+	// with the scrollback gone, the first line is the top of the screen
+	current := int(buffer.convertViewLineToRawLine(buffer.terminalState.cursorY))
+	kept := current < len(buffer.lines)
+	if kept {
+		buffer.lines[0], buffer.lines[current] = buffer.lines[current], buffer.lines[0]
+		buffer.lines[0].setWrapped(false)
+	}
+	for i := range buffer.lines {
+		if i > 0 || !kept {
+			buffer.lines[i].clear()
+		}
+	}
+	buffer.terminalState.cursorY = 0
+}
+
+// Reset clears the screen and forgets the saved cursor, for a full reset of the terminal (RIS)
+func (buffer *Buffer) Reset() {
+	buffer.Clear()
+	buffer.ClearSelection()
+	buffer.savedX = 0
+	buffer.savedY = 0
+	buffer.savedWrapPending = false
+	buffer.savedCursorAttr = nil
+	buffer.savedCharsets = nil
+	buffer.savedCurrentCharset = 0
+}
+
 // creates if necessary
 func (buffer *Buffer) getCurrentLine() *Line {
 	return buffer.getViewLine(buffer.terminalState.cursorY)
@@ -843,23 +1260,22 @@ func (buffer *Buffer) getViewLine(index uint16) *Line {
 }
 
 func (buffer *Buffer) EraseLine() {
-	defer buffer.emitDisplayChange()
-	line := buffer.getCurrentLine()
-	line.cells = []Cell{}
+	defer buffer.damageCursorRow()
+	buffer.getCurrentLine().clear()
 }
 
 func (buffer *Buffer) EraseLineToCursor() {
-	defer buffer.emitDisplayChange()
+	defer buffer.damageCursorRow()
 	line := buffer.getCurrentLine()
 	for i := 0; i <= int(buffer.terminalState.cursorX); i++ {
 		if i < len(line.cells) {
-			line.cells[i].erase(buffer.terminalState.CursorAttr.BgColour)
+			line.cells[i].erase(buffer.terminalState.CursorAttr)
 		}
 	}
 }
 
 func (buffer *Buffer) EraseLineFromCursor() {
-	defer buffer.emitDisplayChange()
+	defer buffer.damageCursorRow()
 	line := buffer.getCurrentLine()
 
 	if len(line.cells) > 0 {
@@ -880,20 +1296,37 @@ func (buffer *Buffer) EraseDisplay() {
 	for i := uint16(0); i < (buffer.ViewHeight()); i++ {
 		rawLine := buffer.convertViewLineToRawLine(i)
 		if int(rawLine) < len(buffer.lines) {
-			buffer.lines[int(rawLine)].cells = []Cell{}
+			buffer.lines[int(rawLine)].clear()
 		}
 	}
 }
 
 func (buffer *Buffer) DeleteChars(n int) {
-	defer buffer.emitDisplayChange()
+	defer buffer.damageCursorRow()
 
 	line := buffer.getCurrentLine()
-	if int(buffer.terminalState.cursorX) >= len(line.cells) {
+	if buffer.hasHorizontalMargins() {
+		// only the characters up to the right margin move, and only if the cursor is inside the margins
+		if !buffer.inHorizontalMargins() || n <= 0 {
+			return
+		}
+		_, right := buffer.terminalState.horizontalMargins()
+		x, end := int(buffer.terminalState.cursorX), int(right)+1
+		if n > end-x {
+			n = end - x
+		}
+		buffer.padLine(line, end)
+		copy(line.cells[x:end-n], line.cells[x+n:end])
+		for i := end - n; i < end; i++ {
+			line.cells[i].erase(buffer.terminalState.CursorAttr)
+		}
+		return
+	}
+	if int(buffer.terminalState.cursorX) >= len(line.cells) || n <= 0 {
 		return
 	}
 	before := line.cells[:buffer.terminalState.cursorX]
-	if int(buffer.terminalState.cursorX)+n >= len(line.cells) {
+	if n > len(line.cells)-int(buffer.terminalState.cursorX) {
 		n = len(line.cells) - int(buffer.terminalState.cursorX)
 	}
 	after := line.cells[int(buffer.terminalState.cursorX)+n:]
@@ -901,17 +1334,18 @@ func (buffer *Buffer) DeleteChars(n int) {
 }
 
 func (buffer *Buffer) EraseCharacters(n int) {
-	defer buffer.emitDisplayChange()
+	defer buffer.damageCursorRow()
 
 	line := buffer.getCurrentLine()
 
-	max := int(buffer.terminalState.cursorX) + n
-	if max > len(line.cells) {
-		max = len(line.cells)
+	// n is limited before adding, so a huge count can't wrap around
+	max := len(line.cells)
+	if n < max-int(buffer.terminalState.cursorX) {
+		max = int(buffer.terminalState.cursorX) + n
 	}
 
 	for i := int(buffer.terminalState.cursorX); i < max; i++ {
-		line.cells[i].erase(buffer.terminalState.CursorAttr.BgColour)
+		line.cells[i].erase(buffer.terminalState.CursorAttr)
 	}
 }
 
@@ -927,7 +1361,7 @@ func (buffer *Buffer) EraseDisplayFromCursor() {
 	line.cells = line.cells[:max]
 
 	for rawLine := buffer.convertViewLineToRawLine(buffer.terminalState.cursorY) + 1; int(rawLine) < len(buffer.lines); rawLine++ {
-		buffer.lines[int(rawLine)].cells = []Cell{}
+		buffer.lines[int(rawLine)].clear()
 	}
 }
 
@@ -939,12 +1373,12 @@ func (buffer *Buffer) EraseDisplayToCursor() {
 		if i >= len(line.cells) {
 			break
 		}
-		line.cells[i].erase(buffer.terminalState.CursorAttr.BgColour)
+		line.cells[i].erase(buffer.terminalState.CursorAttr)
 	}
 	for i := uint16(0); i < buffer.terminalState.cursorY; i++ {
 		rawLine := buffer.convertViewLineToRawLine(i)
 		if int(rawLine) < len(buffer.lines) {
-			buffer.lines[int(rawLine)].cells = []Cell{}
+			buffer.lines[int(rawLine)].clear()
 		}
 	}
 }
@@ -952,6 +1386,14 @@ func (buffer *Buffer) EraseDisplayToCursor() {
 func (buffer *Buffer) ResizeView(width uint16, height uint16) {
 	defer buffer.emitDisplayChange()
 
+	// a line can't be wrapped to no width, and the cursor needs somewhere to be
+	if width == 0 {
+		width = 1
+	}
+	if height == 0 {
+		height = 1
+	}
+
 	if buffer.terminalState.viewHeight == 0 {
 		buffer.terminalState.viewWidth = width
 		buffer.terminalState.viewHeight = height
@@ -960,6 +1402,8 @@ func (buffer *Buffer) ResizeView(width uint16, height uint16) {
 
 	// @todo scroll to bottom on resize
 	line := buffer.getCurrentLine()
+	selection := buffer.logicalSelection()
+	widthChanged := width != buffer.terminalState.viewWidth
 	cXFromEndOfLine := len(line.cells) - int(buffer.terminalState.cursorX+1)
 
 	cursorYMovement := 0
@@ -970,7 +1414,8 @@ func (buffer *Buffer) ResizeView(width uint16, height uint16) {
 			//line.Cleanse()
 			if len(line.cells) > int(width) { // only try wrapping a line if it's too long
 				sillyCells := line.cells[width:] // grab the cells we need to wrap
-				line.cells = line.cells[:width]
+				// the capacity is cut too, so the line can't grow into the cells now on the next line
+				line.cells = line.cells[:width:width]
 
 				// we need to move cut cells to the next line
 				// if the next line is wrapped anyway, we can push them onto the beginning of that line
@@ -1037,6 +1482,8 @@ func (buffer *Buffer) ResizeView(width uint16, height uint16) {
 
 	buffer.terminalState.viewWidth = width
 	buffer.terminalState.viewHeight = height
+	buffer.discardScrollback() // wrapping may have pushed lines off the top
+	buffer.restoreSelection(selection, widthChanged)
 
 	cY := uint16(len(buffer.lines) - 1)
 	if cY >= buffer.terminalState.viewHeight {
@@ -1047,11 +1494,16 @@ func (buffer *Buffer) ResizeView(width uint16, height uint16) {
 	// position cursorX
 	line = buffer.getCurrentLine()
 	buffer.terminalState.cursorX = uint16((len(line.cells) - cXFromEndOfLine) - 1)
+	buffer.terminalState.wrapPending = false
 
 	buffer.terminalState.ResetVerticalMargins()
+	buffer.terminalState.ResetHorizontalMargins()
 }
 
 func (buffer *Buffer) getMaxLines() uint64 {
+	if buffer.noScrollback {
+		return uint64(buffer.terminalState.viewHeight)
+	}
 	result := buffer.terminalState.maxLines
 	if result < uint64(buffer.terminalState.viewHeight) {
 		result = uint64(buffer.terminalState.viewHeight)
@@ -1089,7 +1541,23 @@ func (buffer *Buffer) CompareViewLines(path string) bool {
 func (buffer *Buffer) ReverseVideo() {
 	defer buffer.emitDisplayChange()
 
+	buffer.rewrites++
 	for _, line := range buffer.lines {
 		line.ReverseVideo()
 	}
 }
+
+// Resolve looks up the colours of every cell and the saved cursor again, after the default colours or the palette change
+func (buffer *Buffer) Resolve(resolve ColourResolver) {
+	defer buffer.emitDisplayChange()
+
+	buffer.rewrites++
+	for _, line := range buffer.lines {
+		for i := range line.cells {
+			line.cells[i].attr.Resolve(resolve)
+		}
+	}
+	if buffer.savedCursorAttr != nil {
+		buffer.savedCursorAttr.Resolve(resolve)
+	}
+}
@@ -1,6 +1,8 @@
 package buffer
 
 import (
+	"fmt"
+	"math"
 	"strings"
 	"testing"
 
@@ -97,8 +99,9 @@ func TestBufferWriteIncrementsCursorCorrectly(t *testing.T) {
 	require.Equal(t, uint16(0), b.CursorLine())
 
 	b.Write('x')
-	require.Equal(t, uint16(5), b.CursorColumn())
+	require.Equal(t, uint16(4), b.CursorColumn())
 	require.Equal(t, uint16(0), b.CursorLine())
+	require.True(t, b.inDoWrap())
 
 	b.Write('x')
 	require.Equal(t, uint16(1), b.CursorColumn())
@@ -119,14 +122,15 @@ func TestWritingNewLineAsFirstRuneOnWrappedLine(t *testing.T) {
 	b.terminalState.LineFeedMode = false
 
 	b.Write('a', 'b', 'c')
-	assert.Equal(t, uint16(3), b.terminalState.cursorX)
+	assert.Equal(t, uint16(2), b.terminalState.cursorX)
 	assert.Equal(t, uint16(0), b.terminalState.cursorY)
+	assert.True(t, b.inDoWrap())
 	b.NewLine()
 	assert.Equal(t, uint16(0), b.terminalState.cursorX)
 	assert.Equal(t, uint16(1), b.terminalState.cursorY)
 
 	b.Write('d', 'e', 'f')
-	assert.Equal(t, uint16(3), b.terminalState.cursorX)
+	assert.Equal(t, uint16(2), b.terminalState.cursorX)
 	assert.Equal(t, uint16(1), b.terminalState.cursorY)
 	b.NewLine()
 
@@ -535,6 +539,91 @@ func TestBackspace(t *testing.T) {
 	assert.Equal(t, "helpo", lines[0].String())
 }
 
+func TestPendingWrapOnLastColumn(t *testing.T) {
+	b := NewBuffer(NewTerminalState(5, 5, CellAttributes{}, 1000))
+	b.Write([]rune("abcde")...)
+	assert.Equal(t, uint16(4), b.CursorColumn())
+	assert.Equal(t, uint16(0), b.CursorLine())
+	assert.True(t, b.inDoWrap())
+
+	b.Write('f')
+	assert.Equal(t, uint16(1), b.CursorColumn())
+	assert.Equal(t, uint16(1), b.CursorLine())
+	assert.False(t, b.inDoWrap())
+
+	lines := b.GetVisibleLines()
+	assert.False(t, lines[0].Wrapped())
+	assert.True(t, lines[1].Wrapped())
+
+	b.StartSelection(0, 0, SelectionChar)
+	b.ExtendSelection(0, 1, true)
+	assert.Equal(t, "abcdef", b.GetSelectedText())
+}
+
+func TestCarriageReturnCancelsPendingWrap(t *testing.T) {
+	// a prompt exactly filling the line, then redrawn from the start of it
+	b := NewBuffer(NewTerminalState(5, 5, CellAttributes{}, 1000))
+	b.Write([]rune("$ abc")...)
+	b.CarriageReturn()
+	b.Write([]rune("$ xyz")...)
+
+	lines := b.GetVisibleLines()
+	require.Equal(t, 1, len(lines))
+	assert.Equal(t, "$ xyz", lines[0].String())
+	assert.Equal(t, uint16(0), b.CursorLine())
+}
+
+func TestNewLineCancelsPendingWrap(t *testing.T) {
+	b := NewBuffer(NewTerminalState(5, 5, CellAttributes{}, 1000))
+	b.Write([]rune("abcde")...)
+	b.CarriageReturn()
+	b.NewLine()
+	b.Write('f')
+
+	lines := b.GetVisibleLines()
+	require.Equal(t, 2, len(lines))
+	assert.Equal(t, "f", lines[1].String())
+	assert.False(t, lines[1].Wrapped())
+}
+
+func TestBackspaceCancelsPendingWrap(t *testing.T) {
+	b := NewBuffer(NewTerminalState(5, 5, CellAttributes{}, 1000))
+	b.Write([]rune("abcde")...)
+	b.Backspace()
+	assert.Equal(t, uint16(3), b.CursorColumn())
+	b.Write('x')
+
+	lines := b.GetVisibleLines()
+	require.Equal(t, 1, len(lines))
+	assert.Equal(t, "abcxe", lines[0].String())
+}
+
+func TestBackspaceOnWrappedLine(t *testing.T) {
+	b := NewBuffer(NewTerminalState(5, 5, CellAttributes{}, 1000))
+	b.Write([]rune("abcdef")...)
+	b.CarriageReturn()
+	b.Backspace()
+	assert.Equal(t, uint16(0), b.CursorColumn())
+	assert.Equal(t, uint16(1), b.CursorLine())
+
+	b.terminalState.ReverseWrap = true
+	b.Backspace()
+	assert.Equal(t, uint16(4), b.CursorColumn())
+	assert.Equal(t, uint16(0), b.CursorLine())
+}
+
+func TestWriteWithoutAutoWrap(t *testing.T) {
+	b := NewBuffer(NewTerminalState(5, 5, CellAttributes{}, 1000))
+	b.terminalState.AutoWrap = false
+	b.Write([]rune("abcdefg")...)
+	assert.Equal(t, uint16(4), b.CursorColumn())
+	assert.False(t, b.inDoWrap())
+
+	lines := b.GetVisibleLines()
+	require.Equal(t, 1, len(lines))
+	assert.Equal(t, "abcdg", lines[0].String())
+}
+
 func TestHorizontalResizeView(t *testing.T) {
 	b := NewBuffer(NewTerminalState(80, 10, CellAttributes{}, 1000))
 
@@ -692,6 +781,46 @@ func TestSelectingLineUp(t *testing.T) {
 	assert.Equal(t, "fox jumps over\nthe lazy dog", b.GetSelectedText())
 }
 
+func TestSelectingWrappedLine(t *testing.T) {
+	b := NewBuffer(NewTerminalState(10, 5, CellAttributes{}, 1000))
+	b.Write([]rune("first")...)
+	b.CarriageReturn()
+	b.NewLine()
+	b.Write([]rune("a line which wraps twice")...)
+
+	b.StartSelection(3, 2, SelectionLine)
+
+	assert.Equal(t, "a line which wraps twice", b.GetSelectedText())
+}
+
+func TestSelectingWordWithWordChars(t *testing.T) {
+	b := NewBuffer(NewTerminalState(80, 5, CellAttributes{}, 1000))
+	b.Write([]rune("see ~/src/main.go:12, then")...)
+
+	b.StartSelection(6, 0, SelectionWord)
+	assert.Equal(t, "~/src/main.go", b.GetSelectedText())
+
+	b.SetWordChars("_")
+	b.StartSelection(6, 0, SelectionWord)
+	assert.Equal(t, "src", b.GetSelectedText())
+}
+
+func TestSelectingPathsWithWordChars(t *testing.T) {
+	b := NewBuffer(NewTerminalState(80, 5, CellAttributes{}, 1000))
+	b.Write([]rune("rm /tmp/my-file.tar.gz")...)
+
+	b.StartSelection(12, 0, SelectionWord)
+	assert.Equal(t, "/tmp/my-file.tar.gz", b.GetSelectedText())
+
+	b.SetWordChars("-")
+	b.StartSelection(12, 0, SelectionWord)
+	assert.Equal(t, "my-file", b.GetSelectedText())
+
+	b.SetWordChars("")
+	b.StartSelection(12, 0, SelectionWord)
+	assert.Equal(t, "file", b.GetSelectedText())
+}
+
 func TestSelectingAfterText(t *testing.T) {
 	b := makeBufferForTestingSelection()
 
@@ -705,3 +834,130 @@ func TestSelectingAfterText(t *testing.T) {
 	assert.Equal(t, end.Col, 79)
 	assert.Equal(t, end.Line, 3)
 }
+
+func TestSelectingBlock(t *testing.T) {
+	b := makeBufferForTestingSelection()
+
+	b.StartSelection(8, 2, SelectionBlock)
+	b.ExtendSelection(4, 0, true)
+
+	assert.Equal(t, "quick\njumps\nlazy", b.GetSelectedText())
+	assert.True(t, b.InSelection(4, 1))
+	assert.True(t, b.InSelection(8, 2))
+	assert.False(t, b.InSelection(9, 1))
+	assert.False(t, b.InSelection(3, 0))
+}
+
+func TestSelectedTextTrimsTrailingBlanks(t *testing.T) {
+	b := NewBuffer(NewTerminalState(20, 5, CellAttributes{}, 1000))
+	b.Write([]rune("one   ")...)
+	b.EraseLineFromCursor()
+	b.CarriageReturn()
+	b.NewLine()
+	b.Write([]rune("two")...)
+
+	b.StartSelection(0, 0, SelectionChar)
+	b.ExtendSelection(19, 1, true)
+
+	assert.Equal(t, "one\ntwo", b.GetSelectedText())
+}
+
+func TestSelectedTextKeepsBlanksWhereLinesWrap(t *testing.T) {
+	b := NewBuffer(NewTerminalState(6, 5, CellAttributes{}, 1000))
+	b.Write([]rune("hello world")...)
+
+	b.StartSelection(0, 0, SelectionChar)
+	b.ExtendSelection(5, 1, true)
+
+	assert.Equal(t, "hello world", b.GetSelectedText())
+	assert.Equal(t, "hello\nworld", b.GetSelectedTextEx(false))
+}
+
+func TestExtendSelectionToMovesTheNearerEnd(t *testing.T) {
+	b := makeBufferForTestingSelection()
+
+	b.StartSelection(4, 0, SelectionChar)
+	b.ExtendSelection(8, 0, true)
+	require.Equal(t, "quick", b.GetSelectedText())
+
+	require.True(t, b.ExtendSelectionTo(2, 0))
+	assert.False(t, b.IsSelectionComplete(), "dragging carries on from a shift click")
+	b.ExtendSelection(2, 0, true)
+	assert.Equal(t, "e quick", b.GetSelectedText())
+
+	require.True(t, b.ExtendSelectionTo(2, 1))
+	b.ExtendSelection(2, 1, true)
+	assert.Equal(t, "e quick brown\nfox", b.GetSelectedText())
+}
+
+func TestExtendSelectionToNeedsASelection(t *testing.T) {
+	b := makeBufferForTestingSelection()
+	assert.False(t, b.ExtendSelectionTo(2, 0))
+	_, ok := b.MoveSelectionEnd(1, 0)
+	assert.False(t, ok)
+}
+
+func TestMoveSelectionEnd(t *testing.T) {
+	b := makeBufferForTestingSelection()
+
+	b.StartSelection(4, 0, SelectionChar)
+	b.ExtendSelection(8, 0, true)
+
+	end, ok := b.MoveSelectionEnd(2, 0)
+	require.True(t, ok)
+	assert.Equal(t, Position{Line: 0, Col: 10}, end)
+	assert.Equal(t, "quick b", b.GetSelectedText())
+
+	end, _ = b.MoveSelectionEnd(0, 1)
+	assert.Equal(t, Position{Line: 1, Col: 10}, end)
+
+	// moving off the left edge carries on at the end of the line above
+	b.MoveSelectionEnd(-10, 0)
+	end, _ = b.MoveSelectionEnd(-1, 0)
+	assert.Equal(t, Position{Line: 0, Col: 79}, end)
+
+	end, _ = b.MoveSelectionEnd(0, -5)
+	assert.Equal(t, Position{Line: 0, Col: 79}, end, "the end stays inside the buffer")
+}
+
+func TestAltBufferHasNoScrollback(t *testing.T) {
+	b := NewAltBuffer(NewTerminalState(80, 5, CellAttributes{}, 1000))
+	b.terminalState.LineFeedMode = false
+	for i := 0; i < 20; i++ {
+		b.Write([]rune(fmt.Sprintf("line %d", i))...)
+		b.NewLine()
+	}
+	assert.Equal(t, 5, len(b.lines))
+	assert.Equal(t, "line 16", b.GetVisibleLines()[0].String())
+
+	b.Clear()
+	assert.Equal(t, 5, len(b.lines))
+	for _, line := range b.GetVisibleLines() {
+		assert.Equal(t, "", line.String())
+	}
+}
+
+func TestDeleteAndEraseCharsWithOutOfRangeCounts(t *testing.T) {
+	b := NewBuffer(NewTerminalState(10, 3, CellAttributes{}, 1000))
+	b.Write([]rune("abcdef")...)
+	b.SetPosition(2, 0)
+
+	b.DeleteChars(-1)
+	b.EraseCharacters(-1)
+	assert.Equal(t, "abcdef", b.lines[0].String())
+
+	b.EraseCharacters(math.MaxInt64)
+	assert.Equal(t, "ab", strings.TrimRight(b.lines[0].String(), "\x00 "))
+
+	b.DeleteChars(math.MaxInt64)
+	assert.Equal(t, "ab", b.lines[0].String())
+}
+
+func TestResizeViewToNothing(t *testing.T) {
+	b := NewBuffer(NewTerminalState(10, 3, CellAttributes{}, 1000))
+	b.Write([]rune("abcdef")...)
+
+	b.ResizeView(0, 0)
+	assert.Equal(t, uint16(1), b.ViewWidth())
+	assert.Equal(t, uint16(1), b.ViewHeight())
+}
@@ -5,20 +5,71 @@ import (
 )
 
 type Cell struct {
-	r     rune
-	attr  CellAttributes
-	image *image.RGBA
+	r            rune
+	combining    []rune // zero width characters written after r, like accents and joiners
+	wide         bool   // r takes up this cell and the continuation cell after it
+	continuation bool   // the right half of the wide character in the cell before
+	attr         CellAttributes
+	image        *image.RGBA
 }
 
+// ColourRef says where a colour came from, so that it can follow changes to the default colours and the palette
+type ColourRef uint16
+
+const (
+	ColourRGB       ColourRef = iota // a true colour, which stays as it was given
+	ColourDefaultFg                  // the default foreground colour
+	ColourDefaultBg                  // the default background colour
+	colourPalette                    // the first of the 256 palette entries
+)
+
+// PaletteRef returns the reference to the palette entry at index
+func PaletteRef(index uint8) ColourRef {
+	return colourPalette + ColourRef(index)
+}
+
+// PaletteIndex returns the palette entry the reference is to, and false if it isn't to the palette
+func (ref ColourRef) PaletteIndex() (uint8, bool) {
+	if ref < colourPalette {
+		return 0, false
+	}
+	return uint8(ref - colourPalette), true
+}
+
+// UnderlineStyle is how an underline is drawn, chosen with SGR 4:x
+type UnderlineStyle uint8
+
+const (
+	UnderlineSingle UnderlineStyle = iota
+	UnderlineDouble
+	UnderlineCurly
+	UnderlineDotted
+	UnderlineDashed
+)
+
+// CellAttributes hold the colours a cell is drawn in, along with where they came from, and its text style
 type CellAttributes struct {
 	FgColour  [3]float32
 	BgColour  [3]float32
+	FgRef     ColourRef
+	BgRef     ColourRef
 	Bold      bool
+	Italic    bool
 	Dim       bool
 	Underline bool
-	Blink     bool
-	Inverse   bool
-	Hidden    bool
+	// UnderlineStyle is how the underline is drawn, and UnderlineColour its colour if UnderlineColoured is
+	// set, otherwise it's drawn in the text colour
+	UnderlineStyle    UnderlineStyle
+	UnderlineColoured bool
+	UnderlineColour   [3]float32
+	UnderlineRef      ColourRef
+	Strikethrough     bool
+	Overline          bool
+	Blink             bool
+	Inverse           bool
+	Hidden            bool
+	// Protected cells are left alone by the selective erase sequences, DECSED and DECSEL. It's set with DECSCA.
+	Protected bool
 }
 
 func (cell *Cell) Image() *image.RGBA {
@@ -37,6 +88,33 @@ func (cell *Cell) Rune() rune {
 	return cell.r
 }
 
+// Combining returns the zero width characters which are drawn over the cell's rune
+func (cell *Cell) Combining() []rune {
+	return cell.combining
+}
+
+// Wide returns true if the cell holds a character two cells wide, which carries on into the next cell
+func (cell *Cell) Wide() bool {
+	return cell.wide
+}
+
+// Continuation returns true if the cell is the right half of a wide character, and has nothing of its own to draw
+func (cell *Cell) Continuation() bool {
+	return cell.continuation
+}
+
+// Text returns the cell's rune followed by any combining characters, with empty cells as a space
+func (cell *Cell) Text() string {
+	r := cell.r
+	if r == 0 {
+		r = ' '
+	}
+	if len(cell.combining) == 0 {
+		return string(r)
+	}
+	return string(append([]rune{r}, cell.combining...))
+}
+
 func (cell *Cell) Fg() [3]float32 {
 	if cell.Attr().Inverse {
 		return cell.attr.BgColour
@@ -44,6 +122,14 @@ func (cell *Cell) Fg() [3]float32 {
 	return cell.attr.FgColour
 }
 
+// UnderlineColour returns the colour to draw the cell's underline in
+func (cell *Cell) UnderlineColour() [3]float32 {
+	if cell.attr.UnderlineColoured {
+		return cell.attr.UnderlineColour
+	}
+	return cell.Fg()
+}
+
 func (cell *Cell) Bg() [3]float32 {
 	if cell.Attr().Inverse {
 		return cell.attr.FgColour
@@ -51,13 +137,24 @@ func (cell *Cell) Bg() [3]float32 {
 	return cell.attr.BgColour
 }
 
-func (cell *Cell) erase(bgColour [3]float32) {
+// blank returns true if there's nothing to see in the cell but its background
+func (cell *Cell) blank() bool {
+	return (cell.r == 0 || cell.r == ' ') && len(cell.combining) == 0 && !cell.continuation && cell.image == nil
+}
+
+// erase clears the cell, leaving it unprotected with the background of attr
+func (cell *Cell) erase(attr CellAttributes) {
 	cell.setRune(0)
-	cell.attr.BgColour = bgColour
+	cell.attr.BgColour = attr.BgColour
+	cell.attr.BgRef = attr.BgRef
+	cell.attr.Protected = false
 }
 
 func (cell *Cell) setRune(r rune) {
 	cell.r = r
+	cell.combining = nil
+	cell.wide = false
+	cell.continuation = false
 }
 
 func NewBackgroundCell(colour [3]float32) Cell {
@@ -69,7 +166,24 @@ func NewBackgroundCell(colour [3]float32) Cell {
 }
 
 func (cellAttr *CellAttributes) ReverseVideo() {
-	oldFgColour := cellAttr.FgColour
-	cellAttr.FgColour = cellAttr.BgColour
-	cellAttr.BgColour = oldFgColour
+	cellAttr.FgColour, cellAttr.BgColour = cellAttr.BgColour, cellAttr.FgColour
+	cellAttr.FgRef, cellAttr.BgRef = cellAttr.BgRef, cellAttr.FgRef
+}
+
+// ColourResolver gives the colour a reference stands for. bold is set when looking up the foreground of bold
+// text, which may be shown in the bright version of the colour.
+type ColourResolver func(ref ColourRef, bold bool) [3]float32
+
+// Resolve looks up the colours which come from the default colours or the palette again, so that they follow
+// changes to them. True colours are left as they are.
+func (cellAttr *CellAttributes) Resolve(resolve ColourResolver) {
+	if cellAttr.FgRef != ColourRGB {
+		cellAttr.FgColour = resolve(cellAttr.FgRef, cellAttr.Bold)
+	}
+	if cellAttr.BgRef != ColourRGB {
+		cellAttr.BgColour = resolve(cellAttr.BgRef, false)
+	}
+	if cellAttr.UnderlineColoured && cellAttr.UnderlineRef != ColourRGB {
+		cellAttr.UnderlineColour = resolve(cellAttr.UnderlineRef, false)
+	}
 }
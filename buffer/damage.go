@@ -0,0 +1,88 @@
+package buffer
+
+// Damage is the part of the view which has changed since it was last drawn. The view first moves up by
+// Scrolled rows, as when output scrolls the screen, and then the rows marked as changed are drawn again.
+type Damage struct {
+	All      bool // everything has changed, so there's no need to look at the rest
+	Scrolled int
+	rows     []uint64 // bitset of changed rows, counted from the top of the view
+}
+
+// Row returns true if the given row of the view has changed
+func (d *Damage) Row(row int) bool {
+	if d.All {
+		return true
+	}
+	word := row / 64
+	return row >= 0 && word < len(d.rows) && d.rows[word]&(1<<uint(row%64)) != 0
+}
+
+// Empty returns true if nothing has changed
+func (d *Damage) Empty() bool {
+	if d.All || d.Scrolled > 0 {
+		return false
+	}
+	for _, word := range d.rows {
+		if word != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Add marks a row as changed, ignoring rows outside a view of the given height
+func (d *Damage) Add(row int, height int) {
+	if d.All || row < 0 || row >= height {
+		return
+	}
+	word := row / 64
+	for len(d.rows) <= word {
+		d.rows = append(d.rows, 0)
+	}
+	d.rows[word] |= 1 << uint(row%64)
+}
+
+// scroll moves the view of the given height up by n rows, taking the changes already marked with it
+func (d *Damage) scroll(n int, height int) {
+	if d.All || n <= 0 {
+		return
+	}
+	d.Scrolled += n
+	if d.Scrolled >= height {
+		// nothing that was on screen is left to move
+		d.All = true
+		return
+	}
+	for row := 0; row < height; row++ {
+		changed := row+n < height && d.Row(row+n)
+		if row/64 < len(d.rows) {
+			if changed {
+				d.rows[row/64] |= 1 << uint(row%64)
+			} else {
+				d.rows[row/64] &^= 1 << uint(row%64)
+			}
+		} else if changed {
+			d.Add(row, height)
+		}
+	}
+}
+
+// damageRow marks a row of the live screen as changed, wherever it is in the view
+func (buffer *Buffer) damageRow(viewRow uint16) {
+	row := int(viewRow) + int(buffer.terminalState.scrollLinesFromBottom)
+	buffer.damage.Add(row, int(buffer.ViewHeight()))
+	buffer.dirty = true
+}
+
+// damageCursorRow marks the row the cursor is on as changed
+func (buffer *Buffer) damageCursorRow() {
+	buffer.damageRow(buffer.terminalState.cursorY)
+}
+
+// TakeDamage returns the changes to the view since they were last taken, and forgets them
+func (buffer *Buffer) TakeDamage() Damage {
+	damage := buffer.damage
+	buffer.damage = Damage{}
+	buffer.dirty = false
+	return damage
+}
@@ -0,0 +1,85 @@
+package buffer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func damagedRows(d Damage, height int) []int {
+	rows := []int{}
+	for row := 0; row < height; row++ {
+		if d.Row(row) {
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+func TestWriteDamagesCursorRow(t *testing.T) {
+	b := NewBuffer(NewTerminalState(20, 5, CellAttributes{}, 1000))
+	writeLines(b, "one", "two")
+	b.TakeDamage()
+
+	b.Write([]rune("three")...)
+	d := b.TakeDamage()
+	assert.False(t, d.All)
+	assert.Equal(t, 0, d.Scrolled)
+	assert.Equal(t, []int{2}, damagedRows(d, 5))
+
+	// nothing has changed since
+	d = b.TakeDamage()
+	assert.True(t, d.Empty())
+
+	b.SetPosition(0, 0)
+	b.EraseLineFromCursor()
+	assert.Equal(t, []int{0}, damagedRows(b.TakeDamage(), 5))
+}
+
+func TestScrollingDamage(t *testing.T) {
+	b := NewBuffer(NewTerminalState(20, 5, CellAttributes{}, 1000))
+	writeLines(b, "1", "2", "3", "4")
+	b.Write([]rune("5")...)
+	b.TakeDamage()
+
+	b.SetPosition(0, 1)
+	b.Write([]rune("x")...)
+	b.SetPosition(0, 4)
+	b.CarriageReturn()
+	b.NewLine()
+	b.Write([]rune("6")...)
+
+	// the row written before the scroll moves up with it
+	d := b.TakeDamage()
+	assert.False(t, d.All)
+	assert.Equal(t, 1, d.Scrolled)
+	assert.Equal(t, []int{0, 4}, damagedRows(d, 5))
+
+	// scrolling the whole screen away leaves nothing to move
+	writeLines(b, "a", "b", "c", "d", "e")
+	assert.True(t, b.TakeDamage().All)
+}
+
+func TestScrolledBackWriteDamagesAll(t *testing.T) {
+	b := NewBuffer(NewTerminalState(20, 5, CellAttributes{}, 1000))
+	writeLines(b, "1", "2", "3", "4", "5", "6", "7")
+	b.terminalState.SetScrollOffset(2)
+	b.TakeDamage()
+
+	// the view jumps back down to the cursor
+	b.Write([]rune("x")...)
+	assert.True(t, b.TakeDamage().All)
+}
+
+func TestDamageScroll(t *testing.T) {
+	var d Damage
+	d.Add(0, 100)
+	d.Add(70, 100)
+	d.Add(99, 100)
+	d.scroll(10, 100)
+	assert.Equal(t, []int{60, 89}, damagedRows(d, 100))
+	assert.Equal(t, 10, d.Scrolled)
+
+	d.scroll(90, 100)
+	assert.True(t, d.All)
+}
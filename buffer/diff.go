@@ -0,0 +1,95 @@
+package buffer
+
+// Baseline is a copy of some lines of the buffer, for comparing later output against
+type Baseline struct {
+	lines  []Line
+	screen bool // compare against view rows rather than raw lines
+	offset int  // the row/line the first line of the baseline lines up with
+	end    int  // rows/lines from here on aren't compared, or -1 for no limit
+}
+
+func newBaseline(lines []Line, screen bool, offset int, end int) *Baseline {
+	baseline := &Baseline{
+		lines:  make([]Line, len(lines)),
+		screen: screen,
+		offset: offset,
+		end:    end,
+	}
+	for i, line := range lines {
+		baseline.lines[i] = Line{
+			wrapped: line.wrapped,
+			cells:   append([]Cell{}, line.cells...),
+		}
+	}
+	return baseline
+}
+
+// ScreenBaseline copies the visible lines, so the view can later be compared against them as they are now
+func (buffer *Buffer) ScreenBaseline() *Baseline {
+	return newBaseline(buffer.GetVisibleLines(), true, 0, -1)
+}
+
+// outputBaseline is the copy of a command's output kept by PreviousOutputBaseline, along with what it was copied
+// from, so it's only copied again once that changes
+type outputBaseline struct {
+	lines    []Line
+	start    uint64 // the absolute lines the output was copied from
+	end      uint64
+	width    uint16 // of the buffer when the output was copied
+	rewrites uint64 // of the buffer when the output was copied
+}
+
+// PreviousOutputBaseline returns the output of the command before the most recent one, lined up with the
+// output of the most recent one. This needs the shell to mark its prompts, and returns nil if there aren't
+// two commands with output to compare. The output is only copied when the prompts or the lines under them
+// change, so this is cheap enough to call on every redraw.
+func (buffer *Buffer) PreviousOutputBaseline() *Baseline {
+	outputs := buffer.commandOutputs()
+	if len(outputs) < 2 {
+		buffer.previousOutput = nil
+		return nil
+	}
+	previous, current := outputs[len(outputs)-2], outputs[len(outputs)-1]
+	start, end := uint64(previous[0])+buffer.discardedLines, uint64(previous[1])+buffer.discardedLines
+	cached := buffer.previousOutput
+	if cached == nil || cached.start != start || cached.end != end || cached.width != buffer.Width() || cached.rewrites != buffer.rewrites {
+		cached = &outputBaseline{
+			lines:    newBaseline(buffer.lines[previous[0]:previous[1]], false, 0, -1).lines,
+			start:    start,
+			end:      end,
+			width:    buffer.Width(),
+			rewrites: buffer.rewrites,
+		}
+		buffer.previousOutput = cached
+	}
+	return &Baseline{lines: cached.lines, offset: current[0], end: current[1]}
+}
+
+// ChangedSince returns true if the cell at the given position differs from the same position in the baseline
+func (buffer *Buffer) ChangedSince(baseline *Baseline, col uint16, viewRow uint16) bool {
+	rawLine := buffer.TopVisibleLine() + int(viewRow)
+	row := rawLine
+	if baseline.screen {
+		row = int(viewRow)
+	}
+	if row < baseline.offset || (baseline.end >= 0 && row >= baseline.end) {
+		return false
+	}
+
+	var old, current Cell
+	if index := row - baseline.offset; index < len(baseline.lines) && int(col) < len(baseline.lines[index].cells) {
+		old = baseline.lines[index].cells[col]
+	}
+	if rawLine < len(buffer.lines) && int(col) < len(buffer.lines[rawLine].cells) {
+		current = buffer.lines[rawLine].cells[col]
+	}
+
+	if isBlank(old) && isBlank(current) {
+		return false
+	}
+	return old.r != current.r || string(old.combining) != string(current.combining) || old.attr != current.attr
+}
+
+func isBlank(cell Cell) bool {
+	return cell.r == 0 || cell.r == ' '
+}
@@ -0,0 +1,99 @@
+package buffer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeLines(b *Buffer, lines ...string) {
+	for _, line := range lines {
+		b.Write([]rune(line)...)
+		b.CarriageReturn()
+		b.NewLine()
+	}
+}
+
+func TestPreviousOutputBaseline(t *testing.T) {
+	b := NewBuffer(NewTerminalState(20, 20, CellAttributes{}, 1000))
+
+	b.MarkPrompt()
+	writeLines(b, "$ make", "ok   pkg/a", "FAIL pkg/b")
+	b.MarkPrompt()
+	writeLines(b, "$ make", "ok   pkg/a", "ok   pkg/b", "ok   pkg/c")
+	b.MarkPrompt()
+	b.Write([]rune("$ ")...)
+
+	assert.Equal(t, []int{0, 3, 7}, b.Prompts())
+
+	baseline := b.PreviousOutputBaseline()
+	require.NotNil(t, baseline)
+
+	// the first line of output is the same
+	for x := uint16(0); x < 10; x++ {
+		assert.False(t, b.ChangedSince(baseline, x, 4))
+	}
+	assert.True(t, b.ChangedSince(baseline, 0, 5))
+	assert.True(t, b.ChangedSince(baseline, 1, 5))
+	assert.False(t, b.ChangedSince(baseline, 4, 5))
+	assert.True(t, b.ChangedSince(baseline, 0, 6))
+	// prompt lines aren't compared
+	assert.False(t, b.ChangedSince(baseline, 2, 3))
+	assert.False(t, b.ChangedSince(baseline, 0, 7))
+}
+
+func TestPreviousOutputBaselineWithoutPrompts(t *testing.T) {
+	b := NewBuffer(NewTerminalState(20, 20, CellAttributes{}, 1000))
+	writeLines(b, "hello")
+	assert.Nil(t, b.PreviousOutputBaseline())
+}
+
+func TestScreenBaseline(t *testing.T) {
+	b := NewBuffer(NewTerminalState(20, 3, CellAttributes{}, 1000))
+	b.Write([]rune("Every 2s: date")...)
+	b.SetPosition(0, 1)
+	b.Write([]rune("12:00:01")...)
+
+	baseline := b.ScreenBaseline()
+
+	b.SetPosition(0, 1)
+	b.Write([]rune("12:00:03")...)
+
+	assert.False(t, b.ChangedSince(baseline, 0, 0))
+	assert.False(t, b.ChangedSince(baseline, 6, 1))
+	assert.True(t, b.ChangedSince(baseline, 7, 1))
+	assert.False(t, b.ChangedSince(baseline, 10, 1))
+}
+
+func TestMarkPromptReplacesRedrawnPrompt(t *testing.T) {
+	b := NewBuffer(NewTerminalState(20, 20, CellAttributes{}, 1000))
+	writeLines(b, "a", "b")
+	b.MarkPrompt()
+	b.MarkPrompt()
+	assert.Equal(t, []int{2}, b.Prompts())
+}
+
+func TestPreviousOutputBaselineIsOnlyCopiedWhenThePromptsChange(t *testing.T) {
+	b := NewBuffer(NewTerminalState(20, 20, CellAttributes{}, 1000))
+
+	b.MarkPrompt()
+	writeLines(b, "$ ls", "a")
+	b.MarkPrompt()
+	writeLines(b, "$ ls", "a")
+
+	first := b.PreviousOutputBaseline()
+	require.NotNil(t, first)
+	writeLines(b, "b")
+	second := b.PreviousOutputBaseline()
+	assert.Same(t, &first.lines[0], &second.lines[0])
+	assert.False(t, b.ChangedSince(second, 0, 3))
+	assert.True(t, b.ChangedSince(second, 0, 4))
+
+	b.MarkPrompt()
+	writeLines(b, "$ ls", "a", "c")
+	third := b.PreviousOutputBaseline()
+	assert.NotSame(t, &first.lines[0], &third.lines[0])
+	assert.False(t, b.ChangedSince(third, 0, 6))
+	assert.True(t, b.ChangedSince(third, 0, 7))
+}
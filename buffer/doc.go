@@ -0,0 +1,5 @@
+// Package buffer holds the state of a terminal screen: lines of cells with their attributes, the
+// cursor, scrollback, selection and the various lookups built on top of them (urls, hints, search).
+//
+// It has no dependencies on the terminal parser or GUI and its exported API follows semantic versioning.
+package buffer
@@ -0,0 +1,235 @@
+package buffer
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+)
+
+// ExportOptions control how the HTML and rich text exports style the text
+type ExportOptions struct {
+	Title      string
+	FontFamily string
+	Foreground [3]float32 // text in the default colours is left to the page's colours
+	Background [3]float32
+	Scrollback bool // every line kept, from the oldest, rather than just those on screen
+}
+
+// exportLines returns the lines on screen, or every line kept in memory if scrollback is set, leaving out blank
+// lines at the bottom. Lines spilled to disk aren't included.
+func (buffer *Buffer) exportLines(scrollback bool) []Line {
+	lines := buffer.GetVisibleLines()
+	if scrollback {
+		lines = buffer.lines
+	}
+	for len(lines) > 0 && len(exportCells(lines, len(lines)-1)) == 0 {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// exportCells returns the cells of lines[i] worth exporting. Trailing blanks are dropped unless they show
+// something, or the next line wraps on from this one, when they're spaces in the middle of the text.
+func exportCells(lines []Line, i int) []Cell {
+	cells := lines[i].cells
+	if i+1 < len(lines) && lines[i+1].wrapped {
+		return cells
+	}
+	for len(cells) > 0 && cells[len(cells)-1].blank() && !cells[len(cells)-1].attr.visibleWhenBlank() {
+		cells = cells[:len(cells)-1]
+	}
+	return cells
+}
+
+// exportText returns what to write for a cell, which for images is a space
+func exportText(cell *Cell) string {
+	if cell.image != nil {
+		return " "
+	}
+	return cell.Text()
+}
+
+// WriteANSI writes the lines as text with the SGR sequences which colour and style it, for cat or less -R to
+// show. Lines wrapped onto from the line above are joined to it, and the pen is reset at the end of every line,
+// so each can be read on its own.
+func (buffer *Buffer) WriteANSI(w io.Writer, scrollback bool) error {
+	out := bufio.NewWriter(w)
+	reset := CellAttributes{FgRef: ColourDefaultFg, BgRef: ColourDefaultBg}.SGR()
+	pen := reset
+
+	lines := buffer.exportLines(scrollback)
+	for i := range lines {
+		if i > 0 && !lines[i].wrapped {
+			if pen != reset {
+				out.WriteString(reset)
+				pen = reset
+			}
+			out.WriteString("\n")
+		}
+		cells := exportCells(lines, i)
+		for j := range cells {
+			cell := &cells[j]
+			if cell.continuation {
+				continue
+			}
+			if sgr := cell.attr.SGR(); sgr != pen {
+				out.WriteString(sgr)
+				pen = sgr
+			}
+			out.WriteString(exportText(cell))
+		}
+	}
+	if pen != reset {
+		out.WriteString(reset)
+	}
+	out.WriteString("\n")
+	return out.Flush()
+}
+
+// WriteHTML writes the lines as a standalone HTML page, with the colours and styles of the text. Lines wrapped
+// onto from the line above are joined to it, and wrapped again by the browser.
+func (buffer *Buffer) WriteHTML(w io.Writer, options ExportOptions) error {
+	out := bufio.NewWriter(w)
+
+	family := "monospace"
+	if options.FontFamily != "" {
+		family = options.FontFamily
+	}
+	fmt.Fprintf(out, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n")
+	fmt.Fprintf(out, "<title>%s</title>\n", html.EscapeString(options.Title))
+	fmt.Fprintf(out, "<style>\n")
+	fmt.Fprintf(out, "body { margin: 0; background: %s; }\n", svgColour(options.Background))
+	fmt.Fprintf(out, "pre { margin: 0; padding: 8px; color: %s; font-family: %s; white-space: pre-wrap; }\n", svgColour(options.Foreground), html.EscapeString(family))
+	fmt.Fprintf(out, "</style>\n</head>\n<body><pre>")
+
+	writeHTMLLines(out, buffer.exportLines(options.Scrollback), options)
+	fmt.Fprintf(out, "</pre></body>\n</html>\n")
+	return out.Flush()
+}
+
+// SelectionHTML returns the selected text as a fragment of HTML, in the default colours and font, for the
+// clipboard
+func (buffer *Buffer) SelectionHTML(options ExportOptions) string {
+	var out strings.Builder
+	family := "monospace"
+	if options.FontFamily != "" {
+		family = options.FontFamily
+	}
+	fmt.Fprintf(&out, `<pre style="color: %s; background: %s; font-family: %s;">`, svgColour(options.Foreground), svgColour(options.Background), html.EscapeString(family))
+	writeHTMLLines(&out, buffer.selectedLines(), options)
+	out.WriteString("</pre>")
+	return out.String()
+}
+
+// writeHTMLLines writes the text of the lines in spans styled like the cells
+func writeHTMLLines(out io.Writer, lines []Line, options ExportOptions) {
+	// text is written in runs of the same style, so the file stays small
+	style := ""
+	setStyle := func(s string) {
+		if s == style {
+			return
+		}
+		if style != "" {
+			io.WriteString(out, "</span>")
+		}
+		if s != "" {
+			fmt.Fprintf(out, `<span style="%s">`, s)
+		}
+		style = s
+	}
+
+	for i := range lines {
+		if i > 0 && !lines[i].wrapped {
+			setStyle("")
+			io.WriteString(out, "\n")
+		}
+		cells := exportCells(lines, i)
+		for j := range cells {
+			cell := &cells[j]
+			if cell.continuation {
+				continue
+			}
+			setStyle(htmlStyle(cell, options))
+			io.WriteString(out, html.EscapeString(exportText(cell)))
+		}
+	}
+	setStyle("")
+}
+
+// selectedLines returns the selected part of each selected line, which wraps on from the one before as it did
+// on screen, except in a block selection
+func (buffer *Buffer) selectedLines() []Line {
+	start, end := buffer.getActualSelection()
+	if start == nil || end == nil {
+		return nil
+	}
+	block := buffer.selectionMode == SelectionBlock
+
+	lines := []Line{}
+	for row := start.Line; row <= end.Line && row < len(buffer.lines); row++ {
+		cells := buffer.lines[row].cells
+		from, to := 0, len(cells)
+		if row == start.Line || block {
+			from = start.Col
+		}
+		if (row == end.Line || block) && end.Col+1 < to {
+			to = end.Col + 1
+		}
+		if from > to {
+			from = to
+		}
+		lines = append(lines, Line{
+			cells:   cells[from:to],
+			wrapped: row > start.Line && !block && buffer.lines[row].wrapped,
+		})
+	}
+	return lines
+}
+
+// htmlStyle returns the CSS for a cell's text, leaving out what's the same as the page's
+func htmlStyle(cell *Cell, options ExportOptions) string {
+	styles := []string{}
+	if fg := cell.Fg(); fg != options.Foreground {
+		styles = append(styles, "color: "+svgColour(fg))
+	}
+	if bg := cell.Bg(); bg != options.Background {
+		styles = append(styles, "background: "+svgColour(bg))
+	}
+	if cell.attr.Bold {
+		styles = append(styles, "font-weight: bold")
+	}
+	if cell.attr.Italic {
+		styles = append(styles, "font-style: italic")
+	}
+	decoration := []string{}
+	if cell.attr.Underline {
+		decoration = append(decoration, "underline")
+	}
+	if cell.attr.Strikethrough {
+		decoration = append(decoration, "line-through")
+	}
+	if cell.attr.Overline {
+		decoration = append(decoration, "overline")
+	}
+	if len(decoration) > 0 {
+		styles = append(styles, "text-decoration: "+strings.Join(decoration, " "))
+	}
+	if cell.attr.Underline {
+		// as in SVG, there's one style and colour for all the lines, so they're taken from the underline
+		if style := svgUnderlineStyles[cell.attr.UnderlineStyle]; style != "" {
+			styles = append(styles, "text-decoration-style: "+style)
+		}
+		if cell.attr.UnderlineColoured {
+			styles = append(styles, "text-decoration-color: "+svgColour(cell.attr.UnderlineColour))
+		}
+	}
+	if cell.attr.Dim {
+		styles = append(styles, "opacity: 0.5")
+	}
+	if cell.attr.Hidden {
+		styles = append(styles, "visibility: hidden")
+	}
+	return strings.Join(styles, "; ")
+}
@@ -0,0 +1,76 @@
+package buffer
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func makeBufferForTestingExport(rows uint16) *Buffer {
+	pen := CellAttributes{FgRef: ColourDefaultFg, BgRef: ColourDefaultBg, FgColour: [3]float32{1, 1, 1}}
+	b := NewBuffer(NewTerminalState(5, rows, pen, 1000))
+	b.Write([]rune("a<b")...)
+	b.CursorAttr().Bold = true
+	b.CursorAttr().FgRef = PaletteRef(1)
+	b.CursorAttr().FgColour = [3]float32{1, 0, 0}
+	b.Write([]rune("ok")...)
+	b.Write('!') // wraps onto the next line
+	*b.CursorAttr() = pen
+	b.CarriageReturn()
+	b.NewLine()
+	b.Write([]rune("end  ")...)
+	return b
+}
+
+func TestWriteANSI(t *testing.T) {
+	b := makeBufferForTestingExport(2)
+
+	var out bytes.Buffer
+	require.Nil(t, b.WriteANSI(&out, true))
+	assert.Equal(t, "a<b\x1b[0;1;38;5;1mok!\x1b[0m\nend\n", out.String())
+
+	out.Reset()
+	require.Nil(t, b.WriteANSI(&out, false))
+	assert.Equal(t, "\x1b[0;1;38;5;1m!\x1b[0m\nend\n", out.String())
+}
+
+func TestWriteHTML(t *testing.T) {
+	b := makeBufferForTestingExport(2)
+
+	var out bytes.Buffer
+	require.Nil(t, b.WriteHTML(&out, ExportOptions{
+		Title:      "a & b",
+		Foreground: [3]float32{1, 1, 1},
+		Scrollback: true,
+	}))
+	page := out.String()
+
+	assert.Contains(t, page, "<title>a &amp; b</title>")
+	assert.Contains(t, page, "color: #ffffff; font-family: monospace;")
+	assert.Contains(t, page, `<pre>a&lt;b<span style="color: #ff0000; font-weight: bold">ok!</span>`+"\nend</pre>")
+}
+
+func TestSelectionHTML(t *testing.T) {
+	b := makeBufferForTestingExport(4)
+	b.StartSelection(1, 0, SelectionChar)
+	b.ExtendSelection(1, 2, true)
+
+	assert.Equal(t, `<pre style="color: #ffffff; background: #000000; font-family: monospace;">&lt;b<span style="color: #ff0000; font-weight: bold">ok!</span>`+"\nen</pre>",
+		b.SelectionHTML(ExportOptions{Foreground: [3]float32{1, 1, 1}}))
+}
+
+func TestSelectionRTF(t *testing.T) {
+	b := makeBufferForTestingExport(4)
+	b.CarriageReturn()
+	b.NewLine()
+	b.Write([]rune("{é}")...)
+	b.StartSelection(3, 0, SelectionChar)
+	b.ExtendSelection(2, 3, true)
+
+	assert.Equal(t,
+		`{\rtf1\ansi\deff0{\fonttbl{\f0\fmodern Hack;}}{\colortbl;\red255\green255\blue255;\red0\green0\blue0;\red255\green0\blue0;}`+
+			`\f0\fs20 {\cf1\chcbpat2 {\cf3\chcbpat2\b ok}{\cf3\chcbpat2\b !}\line {\cf1\chcbpat2 end}\line {\cf1\chcbpat2 \{\u233?\}}}}`,
+		b.SelectionRTF(ExportOptions{FontFamily: "Hack, monospace", Foreground: [3]float32{1, 1, 1}}))
+}
@@ -0,0 +1,84 @@
+package buffer
+
+import (
+	"strings"
+	"unicode"
+)
+
+// FilteredLine is a single line of scrollback shown in a filtered view of the buffer
+type FilteredLine struct {
+	RawLine int  // index of the line in the full buffer
+	Match   bool // false when the line is only included as context around a match
+	Line    Line
+}
+
+// FilterLines returns every line in the buffer containing pattern, along with up to context lines either side of each match.
+// Matching is case insensitive unless the pattern contains an upper case character.
+func (buffer *Buffer) FilterLines(pattern string, context int) []FilteredLine {
+	if pattern == "" {
+		return nil
+	}
+
+	if context < 0 {
+		context = 0
+	}
+
+	caseSensitive := false
+	for _, r := range pattern {
+		if unicode.IsUpper(r) {
+			caseSensitive = true
+			break
+		}
+	}
+	if !caseSensitive {
+		pattern = strings.ToLower(pattern)
+	}
+
+	results := []FilteredLine{}
+	included := -1 // last raw line added to results
+
+	for i := range buffer.lines {
+		text := buffer.lines[i].String()
+		if !caseSensitive {
+			text = strings.ToLower(text)
+		}
+		if !strings.Contains(text, pattern) {
+			continue
+		}
+
+		start := i - context
+		if start <= included {
+			start = included + 1
+		}
+		end := i + context
+		if end >= len(buffer.lines) {
+			end = len(buffer.lines) - 1
+		}
+
+		for j := start; j <= end; j++ {
+			if j == i {
+				results = append(results, FilteredLine{RawLine: j, Match: true, Line: buffer.lines[j].clone()})
+			} else if j > i && buffer.lineContains(j, pattern, caseSensitive) {
+				// later matches inside the context window are handled by their own iteration
+				end = j - 1
+				break
+			} else {
+				results = append(results, FilteredLine{RawLine: j, Line: buffer.lines[j].clone()})
+			}
+		}
+		included = end
+		if included < i {
+			included = i
+		}
+	}
+
+	return results
+}
+
+func (buffer *Buffer) lineContains(rawLine int, pattern string, caseSensitive bool) bool {
+	text := buffer.lines[rawLine].String()
+	if !caseSensitive {
+		text = strings.ToLower(text)
+	}
+	return strings.Contains(text, pattern)
+}
@@ -0,0 +1,47 @@
+package buffer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func makeBufferForTestingFilter() *Buffer {
+	b := NewBuffer(NewTerminalState(80, 5, CellAttributes{}, 100))
+	b.terminalState.LineFeedMode = false
+
+	for _, line := range []string{"compiling a", "compiling b", "Error: bad b", "compiling c", "compiling d", "error: bad d"} {
+		b.Write([]rune(line)...)
+		b.NewLine()
+	}
+	return b
+}
+
+func TestFilterLines(t *testing.T) {
+	b := makeBufferForTestingFilter()
+
+	results := b.FilterLines("error", 0)
+	require.Equal(t, 2, len(results))
+	assert.Equal(t, 2, results[0].RawLine)
+	assert.Equal(t, 5, results[1].RawLine)
+	assert.True(t, results[0].Match)
+
+	results = b.FilterLines("Error", 0)
+	require.Equal(t, 1, len(results))
+	assert.Equal(t, "Error: bad b", results[0].Line.String())
+}
+
+func TestFilterLinesWithContext(t *testing.T) {
+	b := makeBufferForTestingFilter()
+
+	results := b.FilterLines("error", 1)
+	lines := []int{}
+	for _, r := range results {
+		lines = append(lines, r.RawLine)
+	}
+	assert.Equal(t, []int{1, 2, 3, 4, 5, 6}, lines)
+	assert.False(t, results[0].Match)
+	assert.True(t, results[1].Match)
+	assert.True(t, results[4].Match)
+}
@@ -0,0 +1,46 @@
+package buffer
+
+// HighlightCursorLine colours the text of the line the cursor is on from start up to end, counted in runes of
+// CursorLineText, in fg on bg, so it stands out from the rest of the line
+func (buffer *Buffer) HighlightCursorLine(start int, end int, fg [3]float32, bg [3]float32) {
+	last := int(buffer.RawLine())
+	if last >= len(buffer.lines) {
+		return
+	}
+	first := last
+	for first > 0 && buffer.lines[first].wrapped {
+		first--
+	}
+
+	offset := 0
+	for row := first; row <= last; row++ {
+		highlighted := false
+		cells := buffer.lines[row].cells
+		for i := range cells {
+			if cells[i].continuation {
+				continue
+			}
+			if offset >= start && offset < end {
+				highlight(&cells[i], fg, bg)
+				if cells[i].wide && i+1 < len(cells) {
+					highlight(&cells[i+1], fg, bg)
+				}
+				highlighted = true
+			}
+			offset += 1 + len(cells[i].combining)
+		}
+		viewRow := row
+		if len(buffer.lines) > int(buffer.ViewHeight()) {
+			viewRow -= len(buffer.lines) - int(buffer.ViewHeight())
+		}
+		if highlighted && viewRow >= 0 {
+			buffer.damageRow(uint16(viewRow))
+		}
+	}
+}
+
+func highlight(cell *Cell, fg [3]float32, bg [3]float32) {
+	cell.attr.Inverse = false
+	cell.attr.FgColour, cell.attr.FgRef = fg, ColourRGB
+	cell.attr.BgColour, cell.attr.BgRef = bg, ColourRGB
+}
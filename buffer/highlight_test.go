@@ -0,0 +1,31 @@
+package buffer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHighlightCursorLine(t *testing.T) {
+	b := NewBuffer(NewTerminalState(5, 5, CellAttributes{}, 100))
+	b.Write([]rune("ok")...)
+	b.CarriageReturn()
+	b.NewLine()
+	b.Write([]rune("error here")...)
+	assert.Equal(t, "error here", b.CursorLineText(), "the line is joined back together from where it wrapped")
+
+	fg, bg := [3]float32{0, 0, 0}, [3]float32{1, 0, 0}
+	b.HighlightCursorLine(3, 7, fg, bg)
+
+	lines := b.GetVisibleLines()
+	highlighted := func(row int, col int) bool {
+		return lines[row].cells[col].Bg() == bg
+	}
+	assert.False(t, highlighted(1, 2))
+	assert.True(t, highlighted(1, 3))
+	assert.True(t, highlighted(1, 4))
+	assert.True(t, highlighted(2, 0), "the highlight carries on to the line the text wrapped onto")
+	assert.True(t, highlighted(2, 1))
+	assert.False(t, highlighted(2, 2))
+	assert.False(t, highlighted(0, 0))
+}
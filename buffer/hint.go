@@ -22,7 +22,7 @@ func (buffer *Buffer) GetHintAtPosition(col uint16, viewRow uint16) *hints.Hint
 		if cell == nil {
 			break
 		}
-		if isRuneWordSelectionMarker(cell.Rune()) {
+		if isRuneHintMarker(cell.Rune()) {
 			break
 		}
 		candidate = fmt.Sprintf("%c%s", cell.Rune(), candidate)
@@ -36,7 +36,7 @@ func (buffer *Buffer) GetHintAtPosition(col uint16, viewRow uint16) *hints.Hint
 		if cell == nil {
 			break
 		}
-		if isRuneWordSelectionMarker(cell.Rune()) {
+		if isRuneHintMarker(cell.Rune()) {
 			break
 		}
 
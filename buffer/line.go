@@ -12,7 +12,25 @@ type Line struct {
 func newLine() Line {
 	return Line{
 		wrapped: false,
-		cells:   []Cell{},
+		cells:   newCells(),
+	}
+}
+
+// clear empties the line, keeping its cells to be written over again
+func (line *Line) clear() {
+	cells := line.cells[:cap(line.cells)]
+	for i := range cells {
+		cells[i] = Cell{}
+	}
+	line.cells = line.cells[:0]
+	line.wrapped = false
+}
+
+// clone returns a copy of the line which doesn't share its cells, for keeping after the line may have been reused
+func (line *Line) clone() Line {
+	return Line{
+		wrapped: line.wrapped,
+		cells:   append([]Cell{}, line.cells...),
 	}
 }
 
@@ -30,7 +48,7 @@ func (line *Line) ReverseVideo() {
 func (line *Line) Cleanse() {
 	cut := 0
 	for i := len(line.cells) - 1; i >= 0; i-- {
-		if line.cells[i].r != 0 {
+		if line.cells[i].r != 0 || line.cells[i].continuation {
 			break
 		}
 		cut++
@@ -41,6 +59,11 @@ func (line *Line) Cleanse() {
 	line.cells = line.cells[:len(line.cells)-cut]
 }
 
+// Wrapped returns true if the line is a continuation of the previous one, rather than starting after a new line
+func (line *Line) Wrapped() bool {
+	return line.wrapped
+}
+
 func (line *Line) setWrapped(wrapped bool) {
 	line.wrapped = wrapped
 }
@@ -48,7 +71,11 @@ func (line *Line) setWrapped(wrapped bool) {
 func (line *Line) String() string {
 	runes := []rune{}
 	for _, cell := range line.cells {
+		if cell.continuation {
+			continue
+		}
 		runes = append(runes, cell.r)
+		runes = append(runes, cell.combining...)
 	}
 	return strings.TrimRight(string(runes), "\x00 ")
 }
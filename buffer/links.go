@@ -0,0 +1,73 @@
+package buffer
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// LinkRule turns text matching a pattern into a clickable link, built by expanding URL with the match ($0, $1, ${name} etc.).
+// An empty URL gives the whole match.
+type LinkRule struct {
+	pattern *regexp.Regexp
+	url     string
+}
+
+func NewLinkRule(pattern string, url string) (LinkRule, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return LinkRule{}, fmt.Errorf("Invalid link pattern '%s': %s", pattern, err)
+	}
+	if url == "" {
+		url = "$0"
+	}
+	return LinkRule{pattern: re, url: url}, nil
+}
+
+// Link is a span of a line which matched a link rule
+type Link struct {
+	URL      string
+	Rule     int // index of the rule which matched
+	StartCol int
+	EndCol   int // inclusive
+}
+
+// GetLinkAtPosition returns the first rule-generated link covering the given view position, or nil if there isn't one
+func (buffer *Buffer) GetLinkAtPosition(col uint16, viewRow uint16, rules []LinkRule) *Link {
+	if len(rules) == 0 {
+		return nil
+	}
+
+	row := buffer.convertViewLineToRawLine(viewRow) - uint64(buffer.terminalState.scrollLinesFromBottom)
+	if row >= uint64(len(buffer.lines)) {
+		return nil
+	}
+
+	// columns come from the cells the matched bytes were in, as wide and combining characters mean they
+	// don't line up with the runes
+	text, positions := buffer.logicalLine(int(row), int(row)+1)
+	cells := buffer.lines[row].cells
+
+	for i, rule := range rules {
+		for _, match := range rule.pattern.FindAllStringSubmatchIndex(text, -1) {
+			if match[0] == match[1] {
+				continue
+			}
+			start := positions[match[0]].Col
+			end := positions[match[1]-1].Col
+			for end+1 < len(cells) && cells[end+1].continuation {
+				end++
+			}
+			if int(col) < start || int(col) > end {
+				continue
+			}
+			return &Link{
+				URL:      string(rule.pattern.ExpandString(nil, rule.url, text, match)),
+				Rule:     i,
+				StartCol: start,
+				EndCol:   end,
+			}
+		}
+	}
+
+	return nil
+}
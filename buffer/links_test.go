@@ -0,0 +1,53 @@
+package buffer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLinkRules(t *testing.T) {
+	b := NewBuffer(NewTerminalState(80, 5, CellAttributes{}, 100))
+	b.Write([]rune("fixes JIRA-123 and JIRA-456")...)
+
+	jira, err := NewLinkRule(`JIRA-(\d+)`, "https://jira/browse/$0?id=$1")
+	require.Nil(t, err)
+
+	assert.Nil(t, b.GetLinkAtPosition(2, 0, []LinkRule{jira}))
+
+	link := b.GetLinkAtPosition(8, 0, []LinkRule{jira})
+	require.NotNil(t, link)
+	assert.Equal(t, "https://jira/browse/JIRA-123?id=123", link.URL)
+	assert.Equal(t, 6, link.StartCol)
+	assert.Equal(t, 13, link.EndCol)
+
+	link = b.GetLinkAtPosition(26, 0, []LinkRule{jira})
+	require.NotNil(t, link)
+	assert.Equal(t, "https://jira/browse/JIRA-456?id=456", link.URL)
+
+	sha, err := NewLinkRule(`\b[0-9a-f]{7}\b`, "")
+	require.Nil(t, err)
+	b.Write([]rune(" in 1a2b3c4")...)
+	link = b.GetLinkAtPosition(31, 0, []LinkRule{jira, sha})
+	require.NotNil(t, link)
+	assert.Equal(t, "1a2b3c4", link.URL, "an empty url gives the whole match")
+	assert.Equal(t, 1, link.Rule)
+
+	_, err = NewLinkRule(`JIRA-(`, "")
+	assert.NotNil(t, err)
+}
+
+func TestLinkColumnsAfterWideAndCombiningCharacters(t *testing.T) {
+	b := NewBuffer(NewTerminalState(80, 5, CellAttributes{}, 100))
+	b.Write([]rune("日本 é JIRA-1")...)
+
+	jira, err := NewLinkRule(`JIRA-\d+`, "")
+	require.Nil(t, err)
+
+	link := b.GetLinkAtPosition(9, 0, []LinkRule{jira})
+	require.NotNil(t, link)
+	assert.Equal(t, 7, link.StartCol)
+	assert.Equal(t, 12, link.EndCol)
+	assert.Nil(t, b.GetLinkAtPosition(6, 0, []LinkRule{jira}))
+}
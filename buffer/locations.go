@@ -0,0 +1,50 @@
+package buffer
+
+import (
+	"regexp"
+	"strconv"
+)
+
+// FileLocation is a file path followed by a line number and maybe a column, as compilers and linters print them
+type FileLocation struct {
+	Path     string
+	Line     int
+	Column   int // 0 if not given
+	StartCol int
+	EndCol   int // inclusive
+}
+
+// locationPattern matches path:line and path:line:column, where the path either has a directory or an extension,
+// so times and host:port pairs without a dot aren't taken for locations
+var (
+	locationPattern     = regexp.MustCompile(`(?:^|[\s'"(\[])((?:[~.]{0,2}/)?(?:[\w.+@-]+/)*[\w+@-]+(?:\.[\w+@-]+)*):(\d+)(?::(\d+))?`)
+	locationPathPattern = regexp.MustCompile(`/|\.[a-zA-Z]`)
+)
+
+// GetFileLocationAtPosition returns the file location covering the given view position, or nil if there isn't one
+func (buffer *Buffer) GetFileLocationAtPosition(col uint16, viewRow uint16) *FileLocation {
+	row := buffer.convertViewLineToRawLine(viewRow) - uint64(buffer.terminalState.scrollLinesFromBottom)
+	if row >= uint64(len(buffer.lines)) {
+		return nil
+	}
+	cells := buffer.lines[row].cells
+	text, cols := targetText(cells)
+
+	for _, match := range locationPattern.FindAllStringSubmatchIndex(text, -1) {
+		path := text[match[2]:match[3]]
+		if !locationPathPattern.MatchString(path) {
+			continue
+		}
+		start, end := int(cols[match[2]]), int(lastColumn(cells, cols[match[1]-1]))
+		if int(col) < start || int(col) > end {
+			continue
+		}
+		location := &FileLocation{Path: path, StartCol: start, EndCol: end}
+		location.Line, _ = strconv.Atoi(text[match[4]:match[5]])
+		if match[6] >= 0 {
+			location.Column, _ = strconv.Atoi(text[match[6]:match[7]])
+		}
+		return location
+	}
+	return nil
+}
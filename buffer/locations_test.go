@@ -0,0 +1,25 @@
+package buffer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetFileLocationAtPosition(t *testing.T) {
+	b := NewBuffer(NewTerminalState(80, 5, CellAttributes{}, 100))
+	b.Write([]rune("src/main.go:42:7: undefined: x (main_test.go:9) at 12:30 on localhost:8080")...)
+
+	location := b.GetFileLocationAtPosition(3, 0)
+	require.NotNil(t, location)
+	assert.Equal(t, FileLocation{Path: "src/main.go", Line: 42, Column: 7, StartCol: 0, EndCol: 15}, *location)
+
+	location = b.GetFileLocationAtPosition(40, 0)
+	require.NotNil(t, location)
+	assert.Equal(t, FileLocation{Path: "main_test.go", Line: 9, StartCol: 32, EndCol: 45}, *location)
+
+	assert.Nil(t, b.GetFileLocationAtPosition(20, 0))
+	assert.Nil(t, b.GetFileLocationAtPosition(52, 0), "times aren't locations")
+	assert.Nil(t, b.GetFileLocationAtPosition(65, 0), "nor are hosts and ports")
+}
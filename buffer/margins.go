@@ -0,0 +1,158 @@
+package buffer
+
+// hasHorizontalMargins returns true if left and right margins narrower than the view are in use (DECSLRM)
+func (buffer *Buffer) hasHorizontalMargins() bool {
+	left, right := buffer.terminalState.horizontalMargins()
+	return left > 0 || right < buffer.ViewWidth()-1
+}
+
+// inHorizontalMargins returns true if the cursor is between the left and right margins
+func (buffer *Buffer) inHorizontalMargins() bool {
+	left, right := buffer.terminalState.horizontalMargins()
+	return buffer.terminalState.cursorX >= left && buffer.terminalState.cursorX <= right
+}
+
+// inVerticalMargins returns true if the cursor is between the top and bottom margins
+func (buffer *Buffer) inVerticalMargins() bool {
+	y := uint(buffer.terminalState.cursorY)
+	return y >= buffer.terminalState.topMargin && y <= buffer.terminalState.bottomMargin
+}
+
+// leftEdge returns the column the cursor returns to on a carriage return: the left margin, unless the cursor is
+// to the left of it, where it can't move back past the edge of the view
+func (buffer *Buffer) leftEdge() uint16 {
+	left, _ := buffer.terminalState.horizontalMargins()
+	if buffer.terminalState.OriginMode || buffer.terminalState.cursorX >= left {
+		return left
+	}
+	return 0
+}
+
+// rightEdge returns the last column written before the text wraps: the right margin, unless the cursor is to the
+// right of it, where text runs on to the edge of the view
+func (buffer *Buffer) rightEdge() uint16 {
+	_, right := buffer.terminalState.horizontalMargins()
+	if buffer.terminalState.OriginMode || buffer.terminalState.cursorX <= right {
+		return right
+	}
+	return buffer.ViewWidth() - 1
+}
+
+// padLine adds blank cells to the end of a line until it's at least width cells long
+func (buffer *Buffer) padLine(line *Line, width int) {
+	for len(line.cells) < width {
+		line.Append(buffer.terminalState.DefaultCell(false))
+	}
+}
+
+// scrollColumns moves the cells between the left and right margins of lines [top, bottom) up by n lines, or down
+// if n is negative, leaving the cells outside the margins where they are. Blank cells fill the rows left behind.
+func (buffer *Buffer) scrollColumns(top uint64, bottom uint64, n int) {
+	defer buffer.emitDisplayChange()
+
+	left, right := buffer.terminalState.horizontalMargins()
+	end := int(right) + 1
+	for i := top; i < bottom; i++ {
+		buffer.padLine(&buffer.lines[i], end)
+	}
+
+	move := func(to uint64, from uint64) {
+		copy(buffer.lines[to].cells[left:end], buffer.lines[from].cells[left:end])
+	}
+	blank := func(row uint64) {
+		cells := buffer.lines[row].cells[left:end]
+		for i := range cells {
+			cells[i].erase(buffer.terminalState.CursorAttr)
+		}
+	}
+
+	if n >= 0 {
+		for i := top; i < bottom; i++ {
+			if from := i + uint64(n); from < bottom {
+				move(i, from)
+			} else {
+				blank(i)
+			}
+		}
+		return
+	}
+	for i := bottom; i > top; {
+		i--
+		if i >= top+uint64(-n) {
+			move(i, i-uint64(-n))
+		} else {
+			blank(i)
+		}
+	}
+}
+
+// scrollColumnsFromCursor inserts (n < 0) or deletes (n > 0) lines at the cursor between the left and right
+// margins, as IL and DL do, moving what's below it down to the bottom margin. It does nothing if the cursor is
+// outside the margins.
+func (buffer *Buffer) scrollColumnsFromCursor(n int) {
+	if !buffer.inHorizontalMargins() {
+		return
+	}
+	buffer.getViewLine(uint16(buffer.terminalState.bottomMargin))
+	_, bottom := buffer.getAreaScrollRange()
+	buffer.scrollColumns(buffer.RawLine(), bottom, n)
+	buffer.terminalState.cursorX, _ = buffer.terminalState.horizontalMargins()
+}
+
+// shiftColumns moves the cells between the left and right margins of every line in the scrolling region right
+// by n columns, or left if n is negative. Cells pushed past a margin are lost, and blank cells fill the columns
+// left behind.
+func (buffer *Buffer) shiftColumns(n int) {
+	defer buffer.emitDisplayChange()
+
+	buffer.getViewLine(uint16(buffer.terminalState.bottomMargin))
+	top, bottom := buffer.getAreaScrollRange()
+	left, right := buffer.terminalState.horizontalMargins()
+	end := int(right) + 1
+
+	for i := top; i < bottom; i++ {
+		buffer.padLine(&buffer.lines[i], end)
+		cells := buffer.lines[i].cells[left:end]
+		blank := cells
+		if n > 0 && n < len(cells) {
+			copy(cells[n:], cells)
+			blank = cells[:n]
+		} else if n < 0 && -n < len(cells) {
+			copy(cells, cells[-n:])
+			blank = cells[len(cells)+n:]
+		}
+		for j := range blank {
+			blank[j].erase(buffer.terminalState.CursorAttr)
+		}
+	}
+}
+
+// BackIndex moves the cursor left a column, unless it's at the left margin, where the columns between the
+// margins scroll right instead (DECBI)
+func (buffer *Buffer) BackIndex() {
+	buffer.terminalState.wrapPending = false
+
+	left, _ := buffer.terminalState.horizontalMargins()
+	if buffer.terminalState.cursorX == left {
+		if buffer.inVerticalMargins() {
+			buffer.shiftColumns(1)
+		}
+	} else if buffer.terminalState.cursorX > 0 {
+		buffer.terminalState.cursorX--
+	}
+}
+
+// ForwardIndex moves the cursor right a column, unless it's at the right margin, where the columns between the
+// margins scroll left instead (DECFI)
+func (buffer *Buffer) ForwardIndex() {
+	buffer.terminalState.wrapPending = false
+
+	_, right := buffer.terminalState.horizontalMargins()
+	if buffer.terminalState.cursorX == right {
+		if buffer.inVerticalMargins() {
+			buffer.shiftColumns(-1)
+		}
+	} else if buffer.terminalState.cursorX < buffer.ViewWidth()-1 {
+		buffer.terminalState.cursorX++
+	}
+}
@@ -0,0 +1,85 @@
+package buffer
+
+import (
+	"fmt"
+	"io"
+	"unsafe"
+)
+
+// MemoryUsage estimates the memory held by the lines of the buffer in bytes, including any images in them
+func (buffer *Buffer) MemoryUsage() uint64 {
+	total := uint64(cap(buffer.lines)) * uint64(unsafe.Sizeof(Line{}))
+	cellSize := uint64(unsafe.Sizeof(Cell{}))
+	for i := range buffer.lines {
+		cells := buffer.lines[i].cells
+		total += uint64(cap(cells)) * cellSize
+		for j := range cells {
+			if cells[j].image != nil {
+				total += uint64(len(cells[j].image.Pix))
+			}
+		}
+	}
+	return total
+}
+
+// TrimScrollback discards all but the most recent keep lines of scrollback above the view, or moves them to disk
+// if the buffer spills
+func (buffer *Buffer) TrimScrollback(keep int) {
+	buffer.trimScrollback(keep, true)
+}
+
+// ClearScrollback discards all the lines above the view, including any spilled to disk
+func (buffer *Buffer) ClearScrollback() {
+	if buffer.spill != nil {
+		if err := buffer.spill.truncate(); err != nil {
+			buffer.spillErr = err
+			buffer.spill = nil
+		}
+	}
+	buffer.trimScrollback(0, false)
+}
+
+// trimScrollback drops all but keep lines above the view, spilling them to disk first if spill is set
+func (buffer *Buffer) trimScrollback(keep int, spill bool) {
+	if keep < 0 {
+		keep = 0
+	}
+	total := keep + int(buffer.ViewHeight())
+	if len(buffer.lines) <= total {
+		return
+	}
+
+	defer buffer.emitDisplayChange()
+
+	drop := len(buffer.lines) - total
+	if spill {
+		buffer.spillLines(buffer.lines[:drop])
+	}
+	releaseLines(buffer.lines[:drop])
+	// copy into a new slice so the memory held by the old one can be released
+	lines := make([]Line, total)
+	copy(lines, buffer.lines[drop:])
+	buffer.lines = lines
+
+	buffer.discardedLines += uint64(drop)
+	buffer.pruneBookmarks()
+	buffer.ClearSelection()
+
+	if buffer.terminalState.scrollLinesFromBottom > uint(keep) {
+		buffer.terminalState.scrollLinesFromBottom = uint(keep)
+	}
+}
+
+// WriteTrimmed writes the text of the lines TrimScrollback(keep) would discard to w, one per line
+func (buffer *Buffer) WriteTrimmed(w io.Writer, keep int) error {
+	if keep < 0 {
+		keep = 0
+	}
+	drop := len(buffer.lines) - keep - int(buffer.ViewHeight())
+	for i := 0; i < drop; i++ {
+		if _, err := fmt.Fprintln(w, buffer.lines[i].String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
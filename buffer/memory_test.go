@@ -0,0 +1,109 @@
+package buffer
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryUsageGrowsWithScrollback(t *testing.T) {
+	b := NewBuffer(NewTerminalState(80, 5, CellAttributes{}, 1000))
+	b.Write([]rune("hello")...)
+	small := b.MemoryUsage()
+	assert.NotZero(t, small)
+
+	for i := 0; i < 100; i++ {
+		b.Write([]rune(fmt.Sprintf("line %d", i))...)
+		b.CarriageReturn()
+		b.NewLine()
+	}
+	assert.True(t, b.MemoryUsage() > small)
+}
+
+func TestTrimScrollback(t *testing.T) {
+	b := NewBuffer(NewTerminalState(80, 5, CellAttributes{}, 1000))
+	for i := 0; i < 20; i++ {
+		b.Write([]rune(fmt.Sprintf("line %d", i))...)
+		b.CarriageReturn()
+		b.NewLine()
+	}
+	b.AddBookmark("early", 1)
+	b.AddBookmark("late", 18)
+	before := b.MemoryUsage()
+
+	b.TrimScrollback(3)
+
+	require.Equal(t, 8, b.Height())
+	assert.Equal(t, "line 13", b.lines[0].String())
+	assert.Equal(t, "line 19", b.lines[6].String())
+	assert.True(t, b.MemoryUsage() < before)
+
+	bookmarks := b.Bookmarks()
+	require.Equal(t, 1, len(bookmarks))
+	assert.Equal(t, 5, b.BookmarkLine(bookmarks[0]))
+}
+
+func TestWriteTrimmed(t *testing.T) {
+	b := NewBuffer(NewTerminalState(80, 5, CellAttributes{}, 1000))
+	for i := 0; i < 20; i++ {
+		b.Write([]rune(fmt.Sprintf("line %d", i))...)
+		b.CarriageReturn()
+		b.NewLine()
+	}
+
+	var out strings.Builder
+	require.Nil(t, b.WriteTrimmed(&out, 3))
+	assert.Equal(t, 13, strings.Count(out.String(), "\n"))
+	assert.True(t, strings.HasPrefix(out.String(), "line 0\nline 1\n"))
+	assert.True(t, strings.HasSuffix(out.String(), "line 12\n"))
+
+	b.TrimScrollback(3)
+	assert.Equal(t, "line 13", b.lines[0].String())
+}
+
+func TestTrimScrollbackKeepsView(t *testing.T) {
+	b := NewBuffer(NewTerminalState(80, 5, CellAttributes{}, 1000))
+	b.Write([]rune("hello")...)
+	b.TrimScrollback(0)
+	require.Equal(t, 1, b.Height())
+	assert.Equal(t, "hello", b.lines[0].String())
+}
+
+func TestClearScrollback(t *testing.T) {
+	spill, err := NewSpill("")
+	require.Nil(t, err)
+	defer spill.Close()
+
+	b := NewBuffer(NewTerminalState(80, 5, CellAttributes{}, 10))
+	b.SpillTo(spill)
+	for i := 0; i < 20; i++ {
+		writeLines(b, fmt.Sprintf("line %d", i))
+	}
+	require.NotZero(t, b.SpilledLines())
+
+	b.ClearScrollback()
+
+	assert.Equal(t, 5, b.Height())
+	assert.Equal(t, "line 16", b.lines[0].String())
+	assert.Equal(t, 0, b.SpilledLines())
+	assert.Equal(t, 0, b.RestoreSpilled(10), "nothing comes back from the disk")
+}
+
+func TestClearAllKeepsCursorLine(t *testing.T) {
+	b := NewBuffer(NewTerminalState(80, 5, CellAttributes{}, 1000))
+	writeLines(b, "one", "two", "three", "four", "five", "six")
+	b.Write([]rune("~ $ ls")...)
+
+	b.ClearAll()
+
+	require.Equal(t, 5, b.Height())
+	assert.Equal(t, "~ $ ls", b.lines[0].String())
+	for i := 1; i < 5; i++ {
+		assert.Equal(t, "", b.lines[i].String())
+	}
+	assert.Equal(t, uint16(0), b.CursorLine())
+	assert.Equal(t, uint16(6), b.CursorColumn())
+}
@@ -0,0 +1,104 @@
+package buffer
+
+// MinimapPixel is one pixel of the picture of a line in a minimap
+type MinimapPixel struct {
+	Colour [3]float32
+	Ink    bool // there's text under the pixel, rather than only blank space
+}
+
+// Minimap is a low resolution picture of every line of a buffer, for drawing an overview of the scrollback. Each
+// line is a row of pixels, each coloured by the first character in the cells it covers. It's kept up to date by
+// Buffer.UpdateMinimap, which only draws the lines which can have changed since it was last updated.
+type Minimap struct {
+	columns  int
+	rows     [][]MinimapPixel // one for each line of the buffer, oldest first
+	first    uint64           // the absolute line rows starts at
+	width    uint16           // of the buffer when the rows were drawn
+	buffer   *Buffer
+	rewrites uint64 // of the buffer when the rows were drawn
+}
+
+// NewMinimap creates a minimap drawing each line as the given number of pixels across
+func NewMinimap(columns int) *Minimap {
+	if columns < 1 {
+		columns = 1
+	}
+	return &Minimap{columns: columns}
+}
+
+// Columns returns how many pixels across each line is drawn as
+func (m *Minimap) Columns() int {
+	return m.columns
+}
+
+// Len returns the number of lines drawn, which is the height of the buffer as of the last update
+func (m *Minimap) Len() int {
+	return len(m.rows)
+}
+
+// Row returns the picture of the given raw line, as of the last update
+func (m *Minimap) Row(rawLine int) []MinimapPixel {
+	if rawLine < 0 || rawLine >= len(m.rows) {
+		return nil
+	}
+	return m.rows[rawLine]
+}
+
+// Reset forgets every line drawn, so they're all drawn again by the next update
+func (m *Minimap) Reset() {
+	m.rows = m.rows[:0]
+	m.buffer = nil
+}
+
+// UpdateMinimap brings the minimap up to date with the buffer. Lines in the scrollback don't change, so only the
+// lines on screen and those added since the last update are drawn, unless the whole buffer has been rewritten,
+// e.g. by being resized.
+func (buffer *Buffer) UpdateMinimap(m *Minimap) {
+	if m.buffer != buffer || m.width != buffer.Width() || m.rewrites != buffer.rewrites || m.first > buffer.discardedLines {
+		m.rows = m.rows[:0]
+		m.first = buffer.discardedLines
+		m.width = buffer.Width()
+		m.buffer = buffer
+		m.rewrites = buffer.rewrites
+	}
+	if drop := buffer.discardedLines - m.first; drop > 0 {
+		if drop > uint64(len(m.rows)) {
+			drop = uint64(len(m.rows))
+		}
+		m.rows = m.rows[drop:]
+		m.first = buffer.discardedLines
+	}
+
+	from := len(buffer.lines) - int(buffer.ViewHeight())
+	if from > len(m.rows) {
+		from = len(m.rows)
+	}
+	if from < 0 {
+		from = 0
+	}
+	m.rows = m.rows[:from]
+	for i := from; i < len(buffer.lines); i++ {
+		m.rows = append(m.rows, m.draw(&buffer.lines[i]))
+	}
+}
+
+// draw makes the picture of a line, each pixel taking the colour of the first character in the cells it covers
+func (m *Minimap) draw(line *Line) []MinimapPixel {
+	pixels := make([]MinimapPixel, m.columns)
+	perPixel := (int(m.width) + m.columns - 1) / m.columns
+	if perPixel < 1 {
+		perPixel = 1
+	}
+	for i := range line.cells {
+		cell := &line.cells[i]
+		x := i / perPixel
+		if x >= m.columns {
+			break
+		}
+		if pixels[x].Ink || cell.continuation || cell.r == 0 || cell.r == ' ' || cell.attr.Hidden {
+			continue
+		}
+		pixels[x] = MinimapPixel{Colour: cell.Fg(), Ink: true}
+	}
+	return pixels
+}
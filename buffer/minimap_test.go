@@ -0,0 +1,66 @@
+package buffer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// inked returns which pixels of a minimap row have text under them, as a string of # and spaces
+func inked(pixels []MinimapPixel) string {
+	row := make([]rune, len(pixels))
+	for i, pixel := range pixels {
+		row[i] = ' '
+		if pixel.Ink {
+			row[i] = '#'
+		}
+	}
+	return string(row)
+}
+
+func TestMinimapDrawsEachLine(t *testing.T) {
+	b := NewBuffer(NewTerminalState(8, 3, CellAttributes{}, 1000))
+	writeLines(b, "ab  cd", "", "    efgh")
+	m := NewMinimap(4)
+	b.UpdateMinimap(m)
+
+	require.Equal(t, b.Height(), m.Len())
+	assert.Equal(t, "# # ", inked(m.Row(0)), "each pixel covers two cells")
+	assert.Equal(t, "    ", inked(m.Row(1)))
+	assert.Equal(t, "  ##", inked(m.Row(2)))
+	assert.Nil(t, m.Row(m.Len()))
+}
+
+func TestMinimapOnlyRedrawsTheScreen(t *testing.T) {
+	b := NewBuffer(NewTerminalState(4, 2, CellAttributes{}, 1000))
+	writeLines(b, "one", "two", "six")
+	m := NewMinimap(4)
+	b.UpdateMinimap(m)
+	first := m.Row(0)
+
+	b.lines[0].cells[2].setRune(' ')
+	b.SetPosition(0, 0)
+	b.Write([]rune("  ")...)
+	b.UpdateMinimap(m)
+
+	assert.Equal(t, "### ", inked(m.Row(0)), "lines in the scrollback are kept as they were")
+	assert.Equal(t, &first[0], &m.Row(0)[0])
+	assert.Equal(t, "  # ", inked(m.Row(2)), "lines on screen are drawn again")
+
+	b.ReverseVideo()
+	b.UpdateMinimap(m)
+	assert.Equal(t, "##  ", inked(m.Row(0)), "the whole buffer is drawn again once it's rewritten")
+}
+
+func TestMinimapFollowsTrimmedScrollback(t *testing.T) {
+	b := NewBuffer(NewTerminalState(4, 2, CellAttributes{}, 1000))
+	writeLines(b, "a", "bb", "ccc", "dddd")
+	m := NewMinimap(4)
+	b.UpdateMinimap(m)
+
+	b.TrimScrollback(1)
+	b.UpdateMinimap(m)
+	require.Equal(t, b.Height(), m.Len())
+	assert.Equal(t, "### ", inked(m.Row(0)))
+}
@@ -0,0 +1,39 @@
+package buffer
+
+import "sync"
+
+// cellPool holds the cells of lines which have left the buffer, so new lines can reuse them. Heavy output
+// otherwise leaves a line's worth of cells for the garbage collector with every line scrolled away.
+var cellPool = sync.Pool{
+	New: func() interface{} {
+		return &[]Cell{}
+	},
+}
+
+// newCells returns an empty slice of cells for a new line, reusing one from a dropped line if there is one
+func newCells() []Cell {
+	return (*cellPool.Get().(*[]Cell))[:0]
+}
+
+// releaseCells gives the cells of a line which has left the buffer back to the pool. Nothing else may use them
+// afterwards.
+func releaseCells(cells []Cell) {
+	if cap(cells) == 0 {
+		return
+	}
+	cells = cells[:cap(cells)]
+	// drop references to images and combining characters, so they can be collected while the cells wait
+	for i := range cells {
+		cells[i] = Cell{}
+	}
+	cells = cells[:0]
+	cellPool.Put(&cells)
+}
+
+// releaseLines gives the cells of lines which are being dropped from the buffer back to the pool
+func releaseLines(lines []Line) {
+	for i := range lines {
+		releaseCells(lines[i].cells)
+		lines[i].cells = nil
+	}
+}
@@ -0,0 +1,71 @@
+package buffer
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEraseDisplayKeepsCells(t *testing.T) {
+	b := NewBuffer(NewTerminalState(80, 5, CellAttributes{}, 1000))
+	b.Write([]rune("hello")...)
+	capacity := cap(b.lines[0].cells)
+	require.NotZero(t, capacity)
+
+	b.EraseDisplay()
+
+	assert.Equal(t, "", b.lines[0].String())
+	assert.Equal(t, capacity, cap(b.lines[0].cells))
+	assert.Equal(t, Cell{}, b.lines[0].cells[:1][0], "cleared cells shouldn't hold on to what was in them")
+}
+
+func TestEraseLineClearsWrapping(t *testing.T) {
+	b := NewBuffer(NewTerminalState(80, 5, CellAttributes{}, 1000))
+	b.Write([]rune("hello")...)
+	b.lines[0].setWrapped(true)
+
+	b.EraseLine()
+
+	assert.Equal(t, "", b.lines[0].String())
+	assert.False(t, b.lines[0].Wrapped())
+}
+
+func TestLinesScrolledOutOfTheBufferAreReused(t *testing.T) {
+	b := NewBuffer(NewTerminalState(80, 5, CellAttributes{}, 10))
+	for i := 0; i < 100; i++ {
+		b.Write([]rune(fmt.Sprintf("line %d", i))...)
+		b.CarriageReturn()
+		b.NewLine()
+	}
+
+	require.Equal(t, 10, b.Height())
+	for i := 0; i < 9; i++ {
+		assert.Equal(t, fmt.Sprintf("line %d", 91+i), b.lines[i].String())
+	}
+	assert.Equal(t, "", b.lines[9].String())
+}
+
+func TestReleasedCellsAreEmpty(t *testing.T) {
+	cells := []Cell{{r: 'a', combining: []rune{0x301}}, {r: 'b'}}
+	releaseCells(cells)
+
+	assert.Equal(t, Cell{}, cells[0])
+	assert.Equal(t, Cell{}, cells[1])
+	assert.Len(t, newCells(), 0)
+}
+
+func TestShrinkingDoesNotShareCellsBetweenLines(t *testing.T) {
+	b := NewBuffer(NewTerminalState(10, 5, CellAttributes{}, 1000))
+	b.Write([]rune("0123456789")...)
+	b.ResizeView(5, 5)
+	require.Equal(t, "01234", b.lines[0].String())
+	require.Equal(t, "56789", b.lines[1].String())
+
+	b.lines[0].Append(b.terminalState.DefaultCell(false))
+	b.lines[0].clear()
+	b.lines[0].Append(NewBackgroundCell([3]float32{}))
+
+	assert.Equal(t, "56789", b.lines[1].String())
+}
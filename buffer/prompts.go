@@ -0,0 +1,273 @@
+package buffer
+
+import (
+	"sort"
+	"strings"
+)
+
+// promptMark is what shell integration (OSC 133) has told us about one command: where its prompt starts (A),
+// where the command typed at the prompt starts (B), where its output starts (C) and where it finished (D).
+// Lines are absolute, like bookmarks, so they stay put as the scrollback is trimmed.
+type promptMark struct {
+	line       uint64
+	input      uint64
+	inputCol   uint16
+	output     uint64
+	end        uint64
+	exitStatus int
+	hasInput   bool
+	hasOutput  bool
+	finished   bool
+}
+
+// UnknownExitStatus is the exit status of a command which finished without the shell saying how
+const UnknownExitStatus = -1
+
+// MarkPrompt records that a shell prompt starts on the cursor line, as reported by shell integration (OSC 133).
+// The screen is also snapshotted, as it shows the result of the previous command.
+func (buffer *Buffer) MarkPrompt() {
+	line := buffer.discardedLines + buffer.RawLine()
+	// the screen may have been cleared under us, leaving prompts after this one
+	for len(buffer.prompts) > 0 && buffer.prompts[len(buffer.prompts)-1].line > line {
+		buffer.prompts = buffer.prompts[:len(buffer.prompts)-1]
+	}
+	if n := len(buffer.prompts); n > 0 && buffer.prompts[n-1].line == line {
+		// the same prompt redrawn
+		buffer.prompts[n-1] = promptMark{line: line}
+		return
+	}
+	buffer.prompts = append(buffer.prompts, promptMark{line: line})
+	buffer.takeSnapshot()
+}
+
+// currentPrompt returns the most recent prompt, marking one on the cursor line if the shell hasn't
+func (buffer *Buffer) currentPrompt() *promptMark {
+	if len(buffer.prompts) == 0 {
+		buffer.MarkPrompt()
+	}
+	return &buffer.prompts[len(buffer.prompts)-1]
+}
+
+// MarkCommandStart records that the command typed at the current prompt starts at the cursor (OSC 133 B)
+func (buffer *Buffer) MarkCommandStart() {
+	prompt := buffer.currentPrompt()
+	prompt.input = buffer.discardedLines + buffer.RawLine()
+	prompt.inputCol = buffer.CursorColumn()
+	prompt.hasInput = true
+}
+
+// MarkOutputStart records that the command has been run, and its output starts at the cursor line (OSC 133 C)
+func (buffer *Buffer) MarkOutputStart() {
+	prompt := buffer.currentPrompt()
+	prompt.output = buffer.discardedLines + buffer.RawLine()
+	prompt.hasOutput = true
+}
+
+// MarkCommandEnd records that the command has finished, with its output ending before the cursor line (OSC 133 D)
+func (buffer *Buffer) MarkCommandEnd() {
+	buffer.MarkCommandExit(UnknownExitStatus)
+}
+
+// MarkCommandExit is MarkCommandEnd for when the shell gives the command's exit status. It returns false if there
+// wasn't a command running to finish.
+func (buffer *Buffer) MarkCommandExit(exitStatus int) bool {
+	if len(buffer.prompts) == 0 {
+		return false
+	}
+	prompt := &buffer.prompts[len(buffer.prompts)-1]
+	if !prompt.hasOutput || prompt.finished {
+		// the shell reports the end of an empty command line too, which didn't run anything
+		return false
+	}
+	prompt.end = buffer.discardedLines + buffer.RawLine()
+	if buffer.CursorColumn() > 0 {
+		// the output didn't end with a new line
+		prompt.end++
+	}
+	prompt.exitStatus = exitStatus
+	prompt.finished = true
+	return true
+}
+
+// Prompts returns the raw lines which prompts have been marked on, oldest first
+func (buffer *Buffer) Prompts() []int {
+	buffer.trimPrompts()
+	lines := make([]int, len(buffer.prompts))
+	for i, prompt := range buffer.prompts {
+		lines[i] = int(prompt.line - buffer.discardedLines)
+	}
+	return lines
+}
+
+// FinishedPrompts returns the raw lines of the prompts whose commands have finished, split by whether their exit
+// status says they succeeded or failed. Those the shell didn't give a status for are in neither.
+func (buffer *Buffer) FinishedPrompts() (succeeded []int, failed []int) {
+	buffer.trimPrompts()
+	for _, prompt := range buffer.prompts {
+		switch {
+		case !prompt.finished || prompt.exitStatus == UnknownExitStatus:
+		case prompt.exitStatus == 0:
+			succeeded = append(succeeded, int(prompt.line-buffer.discardedLines))
+		default:
+			failed = append(failed, int(prompt.line-buffer.discardedLines))
+		}
+	}
+	return succeeded, failed
+}
+
+// drop prompts for lines which have fallen out of the scrollback
+func (buffer *Buffer) trimPrompts() {
+	for len(buffer.prompts) > 0 && buffer.prompts[0].line < buffer.discardedLines {
+		buffer.prompts = buffer.prompts[1:]
+	}
+}
+
+// NextPrompt returns the raw line of the first prompt after the given raw line, or -1 if there isn't one
+func (buffer *Buffer) NextPrompt(rawLine int) int {
+	for _, line := range buffer.Prompts() {
+		if line > rawLine {
+			return line
+		}
+	}
+	return -1
+}
+
+// PreviousPrompt returns the raw line of the last prompt before the given raw line, or -1 if there isn't one
+func (buffer *Buffer) PreviousPrompt(rawLine int) int {
+	prompts := buffer.Prompts()
+	for i := len(prompts) - 1; i >= 0; i-- {
+		if prompts[i] < rawLine {
+			return prompts[i]
+		}
+	}
+	return -1
+}
+
+// outputRange returns the [start, end) raw lines of the output of a command. Without the output marks from
+// the shell, the output is taken to be everything from the line after the prompt up to the next prompt.
+func (buffer *Buffer) outputRange(i int) (int, int) {
+	prompt := buffer.prompts[i]
+	start := int(prompt.line-buffer.discardedLines) + 1
+	end := len(buffer.lines)
+	if i+1 < len(buffer.prompts) {
+		end = int(buffer.prompts[i+1].line - buffer.discardedLines)
+	}
+	if prompt.hasOutput && prompt.output >= buffer.discardedLines {
+		start = int(prompt.output - buffer.discardedLines)
+	}
+	if prompt.finished && int(prompt.end-buffer.discardedLines) < end {
+		end = int(prompt.end - buffer.discardedLines)
+	}
+	if end > len(buffer.lines) {
+		end = len(buffer.lines)
+	}
+	for end > start && buffer.lines[end-1].String() == "" {
+		end--
+	}
+	return start, end
+}
+
+// outputAt returns the [start, end) raw lines of the command output which the given raw line is in. It returns
+// false if the line isn't in any output, or the shell hasn't marked where the output starts.
+func (buffer *Buffer) outputAt(rawLine int) (int, int, bool) {
+	if rawLine < 0 {
+		return 0, 0, false
+	}
+	buffer.trimPrompts()
+	line := buffer.discardedLines + uint64(rawLine)
+	i := sort.Search(len(buffer.prompts), func(i int) bool {
+		return buffer.prompts[i].line > line
+	}) - 1
+	if i < 0 || !buffer.prompts[i].hasOutput {
+		return 0, 0, false
+	}
+	start, end := buffer.outputRange(i)
+	if rawLine < start || rawLine >= end {
+		return 0, 0, false
+	}
+	return start, end, true
+}
+
+// commandOutputs returns the [start, end) raw line ranges of the output following each marked prompt which has some
+func (buffer *Buffer) commandOutputs() [][2]int {
+	buffer.trimPrompts()
+	outputs := [][2]int{}
+	for i := range buffer.prompts {
+		if start, end := buffer.outputRange(i); end > start {
+			outputs = append(outputs, [2]int{start, end})
+		}
+	}
+	return outputs
+}
+
+// lastFinished returns the index of the most recent command the shell has reported running, or -1
+func (buffer *Buffer) lastFinished() int {
+	buffer.trimPrompts()
+	for i := len(buffer.prompts) - 1; i >= 0; i-- {
+		if buffer.prompts[i].hasOutput {
+			return i
+		}
+	}
+	return -1
+}
+
+// LastCommandOutput returns the text output by the most recent command, which needs the shell to mark where
+// output starts. It returns false if there isn't a command to take it from.
+func (buffer *Buffer) LastCommandOutput() (string, bool) {
+	i := buffer.lastFinished()
+	if i < 0 {
+		return "", false
+	}
+	start, end := buffer.outputRange(i)
+	return buffer.linesText(start, end), true
+}
+
+// LastCommand returns the most recent command run at a prompt, as typed, which needs the shell to mark where
+// commands start and where their output starts. It returns false if there isn't one.
+func (buffer *Buffer) LastCommand() (string, bool) {
+	i := buffer.lastFinished()
+	if i < 0 || !buffer.prompts[i].hasInput || buffer.prompts[i].input < buffer.discardedLines {
+		return "", false
+	}
+	prompt := buffer.prompts[i]
+	start := int(prompt.input - buffer.discardedLines)
+	end := int(prompt.output - buffer.discardedLines)
+	if end <= start {
+		end = start + 1
+	}
+	if end > len(buffer.lines) {
+		return "", false
+	}
+	command := strings.TrimSpace(buffer.textFrom(start, int(prompt.inputCol), end))
+	return command, command != ""
+}
+
+// linesText returns the text of the [start, end) raw lines, joining wrapped lines back together
+func (buffer *Buffer) linesText(start int, end int) string {
+	return buffer.textFrom(start, 0, end)
+}
+
+// textFrom returns the text from the given column of the start raw line up to the end raw line, joining
+// wrapped lines back together
+func (buffer *Buffer) textFrom(start int, col int, end int) string {
+	var builder strings.Builder
+	for row := start; row < end && row < len(buffer.lines); row++ {
+		line := buffer.lines[row]
+		cells := line.cells
+		if row == start {
+			if col > len(cells) {
+				col = len(cells)
+			}
+			cells = cells[col:]
+		} else if !line.wrapped {
+			builder.WriteString("\n")
+		}
+		for _, cell := range cells {
+			if !cell.continuation {
+				builder.WriteRune(cell.r)
+				builder.WriteString(string(cell.combining))
+			}
+		}
+	}
+	return strings.Replace(strings.TrimRight(builder.String(), "\x00 \n"), "\x00", " ", -1)
+}
@@ -0,0 +1,107 @@
+package buffer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// runCommand writes a prompt, command and output with the marks a shell with OSC 133 integration sends
+func runCommand(b *Buffer, command string, output ...string) {
+	b.MarkPrompt()
+	b.Write([]rune("~ $ ")...)
+	b.MarkCommandStart()
+	b.Write([]rune(command)...)
+	b.CarriageReturn()
+	b.NewLine()
+	b.MarkOutputStart()
+	writeLines(b, output...)
+	b.MarkCommandExit(0)
+}
+
+func TestLastCommandAndOutput(t *testing.T) {
+	b := NewBuffer(NewTerminalState(20, 20, CellAttributes{}, 1000))
+
+	_, ok := b.LastCommand()
+	assert.False(t, ok)
+	_, ok = b.LastCommandOutput()
+	assert.False(t, ok)
+
+	runCommand(b, "ls", "a.txt", "b.txt")
+	runCommand(b, "echo a long line which wraps", "a long line which wraps")
+	b.MarkPrompt()
+	b.Write([]rune("~ $ ")...)
+	b.MarkCommandStart()
+
+	command, ok := b.LastCommand()
+	assert.True(t, ok)
+	assert.Equal(t, "echo a long line which wraps", command)
+
+	output, ok := b.LastCommandOutput()
+	assert.True(t, ok)
+	assert.Equal(t, "a long line which wraps", output)
+
+	assert.Equal(t, []int{0, 3, 7}, b.Prompts(), "the long command and its output each wrap onto a second line")
+	assert.Equal(t, 3, b.PreviousPrompt(7))
+	assert.Equal(t, -1, b.PreviousPrompt(0))
+	assert.Equal(t, 7, b.NextPrompt(3))
+	assert.Equal(t, -1, b.NextPrompt(7))
+}
+
+func TestEmptyCommandLineKeepsLastOutput(t *testing.T) {
+	b := NewBuffer(NewTerminalState(20, 20, CellAttributes{}, 1000))
+	runCommand(b, "date", "Mon 12:00")
+
+	// pressing enter at an empty prompt gives a prompt with no command, and the shell reports it ending
+	b.MarkPrompt()
+	b.Write([]rune("~ $ ")...)
+	b.MarkCommandStart()
+	b.CarriageReturn()
+	b.NewLine()
+	assert.False(t, b.MarkCommandExit(0), "nothing was run")
+	b.MarkPrompt()
+
+	output, ok := b.LastCommandOutput()
+	assert.True(t, ok)
+	assert.Equal(t, "Mon 12:00", output)
+}
+
+func TestOutputSelection(t *testing.T) {
+	b := NewBuffer(NewTerminalState(20, 20, CellAttributes{}, 1000))
+	runCommand(b, "ls", "a.txt", "b.txt")
+	runCommand(b, "echo a long line which wraps", "a long line which wraps")
+	b.MarkPrompt()
+
+	b.StartSelection(3, 2, SelectionOutput)
+	assert.Equal(t, "a.txt\nb.txt", b.GetSelectedText())
+
+	b.StartSelection(0, 6, SelectionOutput)
+	assert.Equal(t, "a long line which wraps", b.GetSelectedText())
+
+	b.StartSelection(0, 4, SelectionOutput)
+	assert.Equal(t, "~ $ echo a long line which wraps", b.GetSelectedText(), "the line is selected outside of any output")
+}
+
+func TestFinishedPrompts(t *testing.T) {
+	b := NewBuffer(NewTerminalState(20, 20, CellAttributes{}, 1000))
+	runCommand(b, "true")
+	b.MarkPrompt()
+	b.Write([]rune("~ $ ls missing")...)
+	b.CarriageReturn()
+	b.NewLine()
+	b.MarkOutputStart()
+	writeLines(b, "no such file")
+	assert.True(t, b.MarkCommandExit(2))
+	assert.False(t, b.MarkCommandExit(0), "the command has already finished")
+	b.MarkPrompt()
+	b.CarriageReturn()
+	b.NewLine()
+	b.MarkOutputStart()
+	b.MarkCommandEnd()
+	b.MarkPrompt()
+
+	succeeded, failed := b.FinishedPrompts()
+	assert.Equal(t, []int{0}, succeeded)
+	assert.Equal(t, []int{1}, failed)
+	assert.Len(t, b.Prompts(), 4, "the command without a status and the prompt still waiting are in neither")
+}
@@ -0,0 +1,68 @@
+package buffer
+
+// selectiveErase erases the cells in [from, to) of a line, leaving those protected by DECSCA as they are
+func (buffer *Buffer) selectiveErase(line *Line, from int, to int) {
+	if to > len(line.cells) {
+		to = len(line.cells)
+	}
+	for i := from; i < to; i++ {
+		if !line.cells[i].attr.Protected {
+			line.cells[i].erase(buffer.terminalState.CursorAttr)
+		}
+	}
+}
+
+// selectiveEraseRows erases the unprotected cells of the view rows [from, to)
+func (buffer *Buffer) selectiveEraseRows(from uint16, to uint16) {
+	for i := from; i < to; i++ {
+		rawLine := buffer.convertViewLineToRawLine(i)
+		if int(rawLine) < len(buffer.lines) {
+			line := &buffer.lines[rawLine]
+			buffer.selectiveErase(line, 0, len(line.cells))
+		}
+	}
+}
+
+// SelectiveEraseLine erases the unprotected cells of the cursor's line (DECSEL 2)
+func (buffer *Buffer) SelectiveEraseLine() {
+	defer buffer.damageCursorRow()
+	line := buffer.getCurrentLine()
+	buffer.selectiveErase(line, 0, len(line.cells))
+}
+
+// SelectiveEraseLineToCursor erases the unprotected cells of the cursor's line up to and including the cursor
+// (DECSEL 1)
+func (buffer *Buffer) SelectiveEraseLineToCursor() {
+	defer buffer.damageCursorRow()
+	buffer.selectiveErase(buffer.getCurrentLine(), 0, int(buffer.terminalState.cursorX)+1)
+}
+
+// SelectiveEraseLineFromCursor erases the unprotected cells from the cursor to the end of its line (DECSEL 0)
+func (buffer *Buffer) SelectiveEraseLineFromCursor() {
+	defer buffer.damageCursorRow()
+	line := buffer.getCurrentLine()
+	buffer.selectiveErase(line, int(buffer.terminalState.cursorX), len(line.cells))
+}
+
+// SelectiveEraseDisplay erases the unprotected cells on the screen (DECSED 2)
+func (buffer *Buffer) SelectiveEraseDisplay() {
+	defer buffer.emitDisplayChange()
+	buffer.selectiveEraseRows(0, buffer.ViewHeight())
+}
+
+// SelectiveEraseDisplayToCursor erases the unprotected cells from the top of the screen up to and including the
+// cursor (DECSED 1)
+func (buffer *Buffer) SelectiveEraseDisplayToCursor() {
+	defer buffer.emitDisplayChange()
+	buffer.selectiveEraseRows(0, buffer.terminalState.cursorY)
+	buffer.selectiveErase(buffer.getCurrentLine(), 0, int(buffer.terminalState.cursorX)+1)
+}
+
+// SelectiveEraseDisplayFromCursor erases the unprotected cells from the cursor to the bottom of the screen
+// (DECSED 0)
+func (buffer *Buffer) SelectiveEraseDisplayFromCursor() {
+	defer buffer.emitDisplayChange()
+	line := buffer.getCurrentLine()
+	buffer.selectiveErase(line, int(buffer.terminalState.cursorX), len(line.cells))
+	buffer.selectiveEraseRows(buffer.terminalState.cursorY+1, buffer.ViewHeight())
+}
@@ -0,0 +1,108 @@
+package buffer
+
+// logicalPosition is a position in a line as it was written, before the terminal wrapped it to fit the view.
+// Unlike a Position it stays the same when the lines are wrapped again at a new width, and when lines are
+// dropped from the top of the buffer.
+type logicalPosition struct {
+	fromBottom int // logical lines after this one
+	offset     int // cells from the start of the logical line
+}
+
+// toLogical finds where a position is in the text as it was written, returning false if it's outside the buffer
+func (buffer *Buffer) toLogical(pos Position) (logicalPosition, bool) {
+	if pos.Line < 0 || pos.Line >= len(buffer.lines) {
+		return logicalPosition{}, false
+	}
+	start := pos.Line
+	offset := pos.Col
+	for start > 0 && buffer.lines[start].wrapped {
+		start--
+		offset += len(buffer.lines[start].cells)
+	}
+	fromBottom := 0
+	for i := start + 1; i < len(buffer.lines); i++ {
+		if !buffer.lines[i].wrapped {
+			fromBottom++
+		}
+	}
+	return logicalPosition{fromBottom: fromBottom, offset: offset}, true
+}
+
+// fromLogical finds where a position in the text as it was written is in the lines as they're wrapped now,
+// returning false if the line is no longer in the buffer
+func (buffer *Buffer) fromLogical(lp logicalPosition) (Position, bool) {
+	start := -1
+	count := 0
+	for i := len(buffer.lines) - 1; i >= 0; i-- {
+		// the first line starts a logical line even if what it was wrapped from has gone
+		if !buffer.lines[i].wrapped || i == 0 {
+			if count == lp.fromBottom {
+				start = i
+				break
+			}
+			count++
+		}
+	}
+	if start < 0 {
+		return Position{}, false
+	}
+
+	row, offset := start, lp.offset
+	for row+1 < len(buffer.lines) && buffer.lines[row+1].wrapped && offset >= len(buffer.lines[row].cells) {
+		offset -= len(buffer.lines[row].cells)
+		row++
+	}
+	if width := int(buffer.ViewWidth()); offset >= width {
+		// past the end of the text, where the selection can run to the edge of the view
+		offset = width - 1
+	}
+	return Position{Line: row, Col: offset}, true
+}
+
+// reflowSelection is the selection as it was written, taken before the lines are wrapped again at a new width
+type reflowSelection struct {
+	start    logicalPosition
+	end      logicalPosition
+	hasStart bool
+	hasEnd   bool
+}
+
+// logicalSelection returns the selection in a form which survives the lines being wrapped again
+func (buffer *Buffer) logicalSelection() reflowSelection {
+	var selection reflowSelection
+	if buffer.selectionStart != nil {
+		selection.start, selection.hasStart = buffer.toLogical(*buffer.selectionStart)
+	}
+	if buffer.selectionEnd != nil {
+		selection.end, selection.hasEnd = buffer.toLogical(*buffer.selectionEnd)
+	}
+	return selection
+}
+
+// restoreSelection moves the selection to where its text has been wrapped to. Blocks of text don't survive
+// being wrapped differently, so a block selection is cleared if the width has changed.
+func (buffer *Buffer) restoreSelection(selection reflowSelection, widthChanged bool) {
+	if buffer.selectionStart == nil {
+		return
+	}
+	if widthChanged && buffer.selectionMode == SelectionBlock {
+		buffer.ClearSelection()
+		return
+	}
+
+	start, ok := buffer.fromLogical(selection.start)
+	if !selection.hasStart || !ok {
+		buffer.ClearSelection()
+		return
+	}
+	buffer.selectionStart = &start
+
+	if buffer.selectionEnd != nil {
+		end, ok := buffer.fromLogical(selection.end)
+		if !selection.hasEnd || !ok {
+			buffer.ClearSelection()
+			return
+		}
+		buffer.selectionEnd = &end
+	}
+}
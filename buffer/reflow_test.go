@@ -0,0 +1,65 @@
+package buffer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelectionFollowsTextWhenShrinking(t *testing.T) {
+	b := NewBuffer(NewTerminalState(10, 5, CellAttributes{}, 1000))
+	b.Write([]rune("0123456789")...)
+	b.CarriageReturn()
+	b.NewLine()
+	b.Write([]rune("abcdefghij")...)
+
+	b.StartSelection(2, 1, SelectionChar)
+	b.ExtendSelection(7, 1, true)
+	require.Equal(t, "cdefgh", b.GetSelectedText())
+
+	b.ResizeView(5, 5)
+
+	assert.Equal(t, "cdefgh", b.GetSelectedText())
+	assert.Equal(t, Position{Line: 2, Col: 2}, *b.selectionStart)
+	assert.Equal(t, Position{Line: 3, Col: 2}, *b.selectionEnd)
+}
+
+func TestSelectionFollowsTextWhenGrowing(t *testing.T) {
+	b := NewBuffer(NewTerminalState(5, 5, CellAttributes{}, 1000))
+	b.Write([]rune("0123456789")...)
+
+	b.StartSelection(3, 0, SelectionChar)
+	b.ExtendSelection(1, 1, true)
+	require.Equal(t, "3456", b.GetSelectedText())
+
+	b.ResizeView(10, 5)
+
+	assert.Equal(t, "3456", b.GetSelectedText())
+	assert.Equal(t, Position{Line: 0, Col: 6}, *b.selectionEnd)
+}
+
+func TestSelectionPastTheEndOfTheTextStaysInTheView(t *testing.T) {
+	b := NewBuffer(NewTerminalState(10, 5, CellAttributes{}, 1000))
+	b.Write([]rune("abc")...)
+
+	b.StartSelection(0, 0, SelectionChar)
+	b.ExtendSelection(9, 0, true)
+	b.ResizeView(5, 5)
+
+	assert.Equal(t, Position{Line: 0, Col: 4}, *b.selectionEnd)
+	assert.Equal(t, "abc", b.GetSelectedText())
+}
+
+func TestBlockSelectionIsClearedWhenTheWidthChanges(t *testing.T) {
+	b := NewBuffer(NewTerminalState(10, 5, CellAttributes{}, 1000))
+	b.Write([]rune("0123456789")...)
+
+	b.StartSelection(2, 0, SelectionBlock)
+	b.ExtendSelection(4, 0, true)
+	b.ResizeView(10, 6)
+	assert.Equal(t, "234", b.GetSelectedText(), "the text isn't wrapped again when only the height changes")
+
+	b.ResizeView(5, 6)
+	assert.Equal(t, "", b.GetSelectedText())
+}
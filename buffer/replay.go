@@ -0,0 +1,111 @@
+package buffer
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// SGR returns the sequence which sets the pen to the attributes, from any pen. Colours are given as they were
+// chosen, so palette and default colours still follow changes to the palette and scheme.
+func (attr CellAttributes) SGR() string {
+	params := []string{"0"}
+	add := func(on bool, param string) {
+		if on {
+			params = append(params, param)
+		}
+	}
+	add(attr.Bold, "1")
+	add(attr.Dim, "2")
+	add(attr.Italic, "3")
+	add(attr.Underline, fmt.Sprintf("4:%d", attr.UnderlineStyle+1))
+	add(attr.Blink, "5")
+	add(attr.Inverse, "7")
+	add(attr.Hidden, "8")
+	add(attr.Strikethrough, "9")
+	add(attr.Overline, "53")
+	add(attr.FgRef != ColourDefaultFg, colourParams("38", attr.FgRef, attr.FgColour))
+	add(attr.BgRef != ColourDefaultBg, colourParams("48", attr.BgRef, attr.BgColour))
+	add(attr.UnderlineColoured, colourParams("58", attr.UnderlineRef, attr.UnderlineColour))
+	return "\x1b[" + strings.Join(params, ";") + "m"
+}
+
+func colourParams(param string, ref ColourRef, colour [3]float32) string {
+	if index, ok := ref.PaletteIndex(); ok {
+		return fmt.Sprintf("%s;5;%d", param, index)
+	}
+	component := func(c float32) int {
+		return int(math.Round(float64(c * 0xff)))
+	}
+	return fmt.Sprintf("%s;2;%d;%d;%d", param, component(colour[0]), component(colour[1]), component(colour[2]))
+}
+
+// Replay returns the sequences which draw the buffer's lines, from the oldest in the scrollback down to the bottom
+// of the screen, onto an empty screen of the same width with its cursor at the top left. Lines wrapped onto from
+// the line above are wrapped again rather than started afresh. The pen is left reset, and images aren't drawn.
+func (buffer *Buffer) Replay() []byte {
+	var out bytes.Buffer
+	width := int(buffer.Width())
+	pen := CellAttributes{FgRef: ColourDefaultFg, BgRef: ColourDefaultBg}
+	out.WriteString(pen.SGR())
+
+	for i := range buffer.lines {
+		cells := buffer.lines[i].cells
+		wrapsOn := i+1 < len(buffer.lines) && buffer.lines[i+1].wrapped
+		if wrapsOn {
+			// the next line only wraps once this one has filled the screen
+			for len(cells) < width {
+				cells = append(cells, Cell{attr: pen})
+			}
+		} else {
+			// trailing blanks don't need drawing on an empty screen
+			for len(cells) > 0 && cells[len(cells)-1].blank() && !cells[len(cells)-1].attr.visibleWhenBlank() {
+				cells = cells[:len(cells)-1]
+			}
+		}
+
+		for j := range cells {
+			cell := &cells[j]
+			if cell.continuation {
+				continue
+			}
+			if attr := cell.attr; attr != pen {
+				if attr.Protected != pen.Protected {
+					out.WriteString(protection(attr.Protected))
+				}
+				out.WriteString(attr.SGR())
+				pen = attr
+			}
+			if cell.image != nil {
+				out.WriteByte(' ')
+				continue
+			}
+			out.WriteString(cell.Text())
+		}
+
+		if !wrapsOn && i+1 < len(buffer.lines) {
+			out.WriteString("\r\n")
+		}
+	}
+	if pen.Protected {
+		out.WriteString(protection(false))
+	}
+	out.WriteString(CellAttributes{FgRef: ColourDefaultFg, BgRef: ColourDefaultBg}.SGR())
+	return out.Bytes()
+}
+
+// protection returns the DECSCA sequence protecting the characters written after it from selective erasure, or
+// not
+func protection(on bool) string {
+	if on {
+		return "\x1b[1\"q"
+	}
+	return "\x1b[0\"q"
+}
+
+// visibleWhenBlank returns true if a blank cell with the attributes still shows something, e.g. a coloured
+// background or an underline
+func (attr CellAttributes) visibleWhenBlank() bool {
+	return attr.BgRef != ColourDefaultBg || attr.Inverse || attr.Underline || attr.Strikethrough || attr.Overline
+}
@@ -0,0 +1,127 @@
+package buffer
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"unicode/utf16"
+)
+
+// rtfUnderlineStyles are the control words for each underline style
+var rtfUnderlineStyles = map[UnderlineStyle]string{
+	UnderlineSingle: `\ul`,
+	UnderlineDouble: `\uldb`,
+	UnderlineCurly:  `\ulwave`,
+	UnderlineDotted: `\uld`,
+	UnderlineDashed: `\uldash`,
+}
+
+// rtfColours is an RTF colour table, built up as colours are used
+type rtfColours struct {
+	colours [][3]float32
+	indices map[[3]float32]int
+}
+
+// index returns the colour's index in the table, adding it if it isn't there yet. Index 0 is the "auto" colour,
+// so the first colour is 1.
+func (table *rtfColours) index(colour [3]float32) int {
+	if i, ok := table.indices[colour]; ok {
+		return i
+	}
+	table.colours = append(table.colours, colour)
+	table.indices[colour] = len(table.colours)
+	return len(table.colours)
+}
+
+func (table *rtfColours) String() string {
+	var out strings.Builder
+	out.WriteString(`{\colortbl;`)
+	component := func(c float32) int {
+		return int(math.Round(float64(c * 0xff)))
+	}
+	for _, c := range table.colours {
+		fmt.Fprintf(&out, `\red%d\green%d\blue%d;`, component(c[0]), component(c[1]), component(c[2]))
+	}
+	out.WriteString("}")
+	return out.String()
+}
+
+// SelectionRTF returns the selected text as an RTF document, in the default colours and font, for pasting into
+// word processors
+func (buffer *Buffer) SelectionRTF(options ExportOptions) string {
+	family := "Courier New"
+	if options.FontFamily != "" {
+		family = strings.TrimSpace(strings.Split(options.FontFamily, ",")[0])
+	}
+	colours := &rtfColours{indices: map[[3]float32]int{}}
+	fg, bg := colours.index(options.Foreground), colours.index(options.Background)
+
+	var text strings.Builder
+	lines := buffer.selectedLines()
+	for i := range lines {
+		if i > 0 && !lines[i].wrapped {
+			text.WriteString(`\line `)
+		}
+		// text is written in groups of the same style
+		cells := exportCells(lines, i)
+		for col := 0; col < len(cells); {
+			style := rtfStyle(&cells[col], colours)
+			end := col
+			text.WriteString("{" + style + " ")
+			for ; end < len(cells) && rtfStyle(&cells[end], colours) == style; end++ {
+				if !cells[end].continuation {
+					text.WriteString(rtfEscape(exportText(&cells[end])))
+				}
+			}
+			text.WriteString("}")
+			col = end
+		}
+	}
+
+	return fmt.Sprintf(`{\rtf1\ansi\deff0{\fonttbl{\f0\fmodern %s;}}%s\f0\fs20 {\cf%d\chcbpat%d %s}}`,
+		rtfEscape(family), colours, fg, bg, text.String())
+}
+
+// rtfStyle returns the control words which style a cell's text, adding its colours to the table
+func rtfStyle(cell *Cell, colours *rtfColours) string {
+	style := fmt.Sprintf(`\cf%d\chcbpat%d`, colours.index(cell.Fg()), colours.index(cell.Bg()))
+	if cell.attr.Bold {
+		style += `\b`
+	}
+	if cell.attr.Italic {
+		style += `\i`
+	}
+	if cell.attr.Underline {
+		style += rtfUnderlineStyles[cell.attr.UnderlineStyle]
+		if cell.attr.UnderlineColoured {
+			style += fmt.Sprintf(`\ulc%d`, colours.index(cell.attr.UnderlineColour))
+		}
+	}
+	if cell.attr.Strikethrough {
+		style += `\strike`
+	}
+	if cell.attr.Hidden {
+		style += `\v`
+	}
+	return style
+}
+
+// rtfEscape escapes the characters RTF gives meanings to, and writes anything outside ASCII as \u escapes of
+// its UTF-16 code units, each followed by a ? for readers which don't understand them
+func rtfEscape(s string) string {
+	var out strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '\\' || r == '{' || r == '}':
+			out.WriteRune('\\')
+			out.WriteRune(r)
+		case r < 0x80:
+			out.WriteRune(r)
+		default:
+			for _, unit := range utf16.Encode([]rune{r}) {
+				fmt.Fprintf(&out, `\u%d?`, int16(unit))
+			}
+		}
+	}
+	return out.String()
+}
@@ -0,0 +1,86 @@
+package buffer
+
+import (
+	"regexp"
+	"unicode"
+)
+
+// SearchMatch is a match found by Search, from Start to End inclusive. Lines are raw buffer lines, and a match can
+// span several lines when a line has wrapped.
+type SearchMatch struct {
+	Start Position
+	End   Position
+}
+
+// Search finds every match of the regular expression pattern in the buffer, oldest first. Lines which have wrapped
+// are joined back together first, so text which spans the edge of the screen is found. Matching is case
+// insensitive unless the pattern contains an upper case character.
+func (buffer *Buffer) Search(pattern string) ([]SearchMatch, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+
+	caseSensitive := false
+	for _, r := range pattern {
+		if unicode.IsUpper(r) {
+			caseSensitive = true
+			break
+		}
+	}
+	if !caseSensitive {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := []SearchMatch{}
+	for start := 0; start < len(buffer.lines); {
+		end := start + 1
+		for end < len(buffer.lines) && buffer.lines[end].wrapped {
+			end++
+		}
+
+		text, positions := buffer.logicalLine(start, end)
+		for _, loc := range re.FindAllStringIndex(text, -1) {
+			if loc[0] == loc[1] {
+				continue
+			}
+			matches = append(matches, SearchMatch{
+				Start: positions[loc[0]],
+				End:   positions[loc[1]-1],
+			})
+		}
+
+		start = end
+	}
+
+	return matches, nil
+}
+
+// logicalLine joins the raw lines from start up to end into one string, returning the position each byte came from
+func (buffer *Buffer) logicalLine(start int, end int) (string, []Position) {
+	text := []byte{}
+	positions := []Position{}
+	for i := start; i < end; i++ {
+		cells := buffer.lines[i].cells
+		if i == end-1 {
+			// trailing space at the end of the line is padding rather than output
+			for len(cells) > 0 && isBlank(cells[len(cells)-1]) {
+				cells = cells[:len(cells)-1]
+			}
+		}
+		for col, cell := range cells {
+			if cell.continuation {
+				continue
+			}
+			encoded := []byte(cell.Text())
+			text = append(text, encoded...)
+			for range encoded {
+				positions = append(positions, Position{Line: i, Col: col})
+			}
+		}
+	}
+	return string(text), positions
+}
@@ -0,0 +1,41 @@
+package buffer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearch(t *testing.T) {
+	b := NewBuffer(NewTerminalState(10, 10, CellAttributes{}, 1000))
+	writeLines(b, "error: one", "ok", "an ERROR")
+
+	matches, err := b.Search("error")
+	require.Nil(t, err)
+	assert.Equal(t, []SearchMatch{
+		{Start: Position{Line: 0, Col: 0}, End: Position{Line: 0, Col: 4}},
+		{Start: Position{Line: 2, Col: 3}, End: Position{Line: 2, Col: 7}},
+	}, matches)
+
+	matches, err = b.Search("ERROR")
+	require.Nil(t, err)
+	assert.Len(t, matches, 1)
+}
+
+func TestSearchAcrossWrappedLines(t *testing.T) {
+	b := NewBuffer(NewTerminalState(5, 10, CellAttributes{}, 1000))
+	writeLines(b, "abc hello", "hel", "lo")
+
+	matches, err := b.Search(`hel+o`)
+	require.Nil(t, err)
+	assert.Equal(t, []SearchMatch{
+		{Start: Position{Line: 0, Col: 4}, End: Position{Line: 1, Col: 3}},
+	}, matches)
+}
+
+func TestSearchInvalidPattern(t *testing.T) {
+	b := NewBuffer(NewTerminalState(5, 10, CellAttributes{}, 1000))
+	_, err := b.Search("(")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,45 @@
+package buffer
+
+import "time"
+
+// maxSnapshots is how many screens are kept for stepping back through, the oldest are dropped first
+const maxSnapshots = 100
+
+// Snapshot is a copy of the screen as it was when a prompt was marked, i.e. after the previous command finished
+type Snapshot struct {
+	Time  time.Time
+	lines []Line
+}
+
+// Lines returns the lines of the screen as they were when the snapshot was taken
+func (snapshot *Snapshot) Lines() []Line {
+	return snapshot.lines
+}
+
+// Snapshot copies the screen as it is now
+func (buffer *Buffer) Snapshot() Snapshot {
+	visible := buffer.GetVisibleLines()
+	lines := make([]Line, len(visible))
+	for i, line := range visible {
+		lines[i] = Line{
+			wrapped: line.wrapped,
+			cells:   append([]Cell{}, line.cells...),
+		}
+	}
+	return Snapshot{
+		Time:  time.Now(),
+		lines: lines,
+	}
+}
+
+func (buffer *Buffer) takeSnapshot() {
+	buffer.snapshots = append(buffer.snapshots, buffer.Snapshot())
+	if len(buffer.snapshots) > maxSnapshots {
+		buffer.snapshots = buffer.snapshots[len(buffer.snapshots)-maxSnapshots:]
+	}
+}
+
+// Snapshots returns the screens recorded at each prompt, oldest first
+func (buffer *Buffer) Snapshots() []Snapshot {
+	return buffer.snapshots
+}
@@ -0,0 +1,43 @@
+package buffer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshotsAtPrompts(t *testing.T) {
+	b := NewBuffer(NewTerminalState(20, 3, CellAttributes{}, 1000))
+	b.MarkPrompt()
+	writeLines(b, "$ date", "Mon 12:00")
+	b.MarkPrompt()
+	b.Write([]rune("$ clear")...)
+	b.EraseDisplay()
+	b.SetPosition(0, 0)
+	b.MarkPrompt()
+	b.MarkPrompt()
+
+	assert.Equal(t, []int{0}, b.Prompts())
+
+	snapshots := b.Snapshots()
+	require.Equal(t, 2, len(snapshots))
+
+	lines := snapshots[1].Lines()
+	require.Equal(t, 3, len(lines))
+	assert.Equal(t, "$ date", lines[0].String())
+	assert.Equal(t, "Mon 12:00", lines[1].String())
+
+	// taking a copy means later changes to the screen don't affect the snapshot
+	assert.Equal(t, "", b.GetVisibleLines()[0].String())
+	assert.Equal(t, "$ date", snapshots[1].Lines()[0].String())
+}
+
+func TestSnapshotsAreLimited(t *testing.T) {
+	b := NewBuffer(NewTerminalState(20, 3, CellAttributes{}, 1000))
+	for i := 0; i < maxSnapshots+10; i++ {
+		b.MarkPrompt()
+		writeLines(b, "$ true")
+	}
+	assert.Equal(t, maxSnapshots, len(b.Snapshots()))
+}
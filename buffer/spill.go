@@ -0,0 +1,204 @@
+package buffer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+// lines are read back from disk in chunks of at least this many, so scrolling back doesn't hit the disk on every step
+const spillRestoreChunk = 100
+
+// Spill is an append-only file of lines which have dropped off the top of the scrollback. Lines are pushed on as
+// they're dropped and popped off again when the user scrolls back to them, so the file always holds the oldest
+// lines in order. Images aren't kept.
+type Spill struct {
+	file    *os.File
+	offsets []int64 // offset of the start of each line in the file
+	size    int64
+}
+
+// spilledCell is how a cell is written to disk. CellAttributes must only contain fixed size fields so it can be
+// written as is. The cell's combining characters follow the line's cells, in order.
+type spilledCell struct {
+	R            int32
+	Combining    uint32 // how many combining characters the cell has
+	Wide         bool
+	Continuation bool
+	Attr         CellAttributes
+}
+
+// NewSpill creates a spill file in dir, or in the default temporary directory if dir is empty
+func NewSpill(dir string) (*Spill, error) {
+	file, err := ioutil.TempFile(dir, "aminal-scrollback-")
+	if err != nil {
+		return nil, err
+	}
+	return &Spill{file: file}, nil
+}
+
+// Len returns the number of lines on disk
+func (spill *Spill) Len() int {
+	return len(spill.offsets)
+}
+
+// Close closes and removes the spill file
+func (spill *Spill) Close() error {
+	err := spill.file.Close()
+	if removeErr := os.Remove(spill.file.Name()); err == nil {
+		err = removeErr
+	}
+	return err
+}
+
+func (spill *Spill) push(lines []Line) error {
+	var buf bytes.Buffer
+	offsets := make([]int64, len(lines))
+	for i, line := range lines {
+		offsets[i] = spill.size + int64(buf.Len())
+		cells := make([]spilledCell, len(line.cells))
+		var combining []rune
+		for j, cell := range line.cells {
+			cells[j] = spilledCell{R: cell.r, Combining: uint32(len(cell.combining)), Wide: cell.wide, Continuation: cell.continuation, Attr: cell.attr}
+			combining = append(combining, cell.combining...)
+		}
+		header := struct {
+			Wrapped bool
+			Cells   uint32
+		}{line.wrapped, uint32(len(cells))}
+		if err := binary.Write(&buf, binary.LittleEndian, header); err != nil {
+			return err
+		}
+		if err := binary.Write(&buf, binary.LittleEndian, cells); err != nil {
+			return err
+		}
+		if err := binary.Write(&buf, binary.LittleEndian, combining); err != nil {
+			return err
+		}
+	}
+	if _, err := spill.file.WriteAt(buf.Bytes(), spill.size); err != nil {
+		return err
+	}
+	spill.offsets = append(spill.offsets, offsets...)
+	spill.size += int64(buf.Len())
+	return nil
+}
+
+// pop removes up to the last n lines from the file and returns them
+func (spill *Spill) pop(n int) ([]Line, error) {
+	if n > len(spill.offsets) {
+		n = len(spill.offsets)
+	}
+	if n <= 0 {
+		return nil, nil
+	}
+	start := spill.offsets[len(spill.offsets)-n]
+	data := make([]byte, spill.size-start)
+	if _, err := spill.file.ReadAt(data, start); err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	reader := bytes.NewReader(data)
+	lines := make([]Line, n)
+	for i := range lines {
+		var header struct {
+			Wrapped bool
+			Cells   uint32
+		}
+		if err := binary.Read(reader, binary.LittleEndian, &header); err != nil {
+			return nil, err
+		}
+		cells := make([]spilledCell, header.Cells)
+		if err := binary.Read(reader, binary.LittleEndian, cells); err != nil {
+			return nil, err
+		}
+		lines[i] = Line{wrapped: header.Wrapped, cells: make([]Cell, len(cells))}
+		for j, cell := range cells {
+			lines[i].cells[j] = Cell{r: cell.R, wide: cell.Wide, continuation: cell.Continuation, attr: cell.Attr}
+			if cell.Combining > 0 {
+				combining := make([]rune, cell.Combining)
+				if err := binary.Read(reader, binary.LittleEndian, combining); err != nil {
+					return nil, err
+				}
+				lines[i].cells[j].combining = combining
+			}
+		}
+	}
+
+	if err := spill.file.Truncate(start); err != nil {
+		return nil, err
+	}
+	spill.offsets = spill.offsets[:len(spill.offsets)-n]
+	spill.size = start
+	return lines, nil
+}
+
+// truncate discards all the lines in the file
+func (spill *Spill) truncate() error {
+	if err := spill.file.Truncate(0); err != nil {
+		return err
+	}
+	spill.offsets = nil
+	spill.size = 0
+	return nil
+}
+
+// SpillTo makes the buffer move lines which no longer fit in the scrollback into spill, rather than discarding them
+func (buffer *Buffer) SpillTo(spill *Spill) {
+	buffer.spill = spill
+	buffer.spillErr = nil
+}
+
+// SpilledLines returns how many lines are waiting on disk above the top of the scrollback
+func (buffer *Buffer) SpilledLines() int {
+	if buffer.spill == nil {
+		return 0
+	}
+	return buffer.spill.Len()
+}
+
+// SpillError returns the error which stopped lines being spilled to disk, if any
+func (buffer *Buffer) SpillError() error {
+	return buffer.spillErr
+}
+
+// spillLines is called with lines about to be dropped off the top of the buffer
+func (buffer *Buffer) spillLines(lines []Line) {
+	if buffer.spill == nil || len(lines) == 0 {
+		return
+	}
+	if err := buffer.spill.push(lines); err != nil {
+		// carry on without the file, dropping lines as if spilling had never been enabled
+		buffer.spillErr = err
+		buffer.spill = nil
+	}
+}
+
+// RestoreSpilled moves at least n lines back from disk to the top of the buffer, so they can be scrolled to,
+// and returns how many were restored. They're spilled again once there's more output.
+func (buffer *Buffer) RestoreSpilled(n int) int {
+	if buffer.spill == nil || n <= 0 {
+		return 0
+	}
+	if n < spillRestoreChunk {
+		n = spillRestoreChunk
+	}
+	restored, err := buffer.spill.pop(n)
+	if err != nil {
+		buffer.spillErr = err
+		buffer.spill = nil
+		return 0
+	}
+
+	defer buffer.emitDisplayChange()
+
+	lines := make([]Line, len(restored)+len(buffer.lines))
+	copy(lines, restored)
+	copy(lines[len(restored):], buffer.lines)
+	buffer.lines = lines
+	buffer.discardedLines -= uint64(len(restored))
+	buffer.ClearSelection() // selection is by raw line, which has just moved
+	return len(restored)
+}
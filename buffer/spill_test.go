@@ -0,0 +1,59 @@
+package buffer
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpillAndRestore(t *testing.T) {
+	spill, err := NewSpill("")
+	require.Nil(t, err)
+	defer spill.Close()
+
+	b := NewBuffer(NewTerminalState(10, 5, CellAttributes{}, 10))
+	b.SpillTo(spill)
+	b.CursorAttr().Bold = true
+	for i := 0; i < 30; i++ {
+		writeLines(b, fmt.Sprintf("line %d", i))
+	}
+
+	require.Equal(t, 10, b.Height())
+	assert.Equal(t, 21, b.SpilledLines())
+	assert.Equal(t, uint64(21), b.discardedLines)
+
+	assert.Equal(t, 21, b.RestoreSpilled(5))
+	assert.Nil(t, b.SpillError())
+	assert.Equal(t, 0, b.SpilledLines())
+	assert.Equal(t, uint64(0), b.discardedLines)
+	require.Equal(t, 31, b.Height())
+	for i := 0; i < 30; i++ {
+		assert.Equal(t, fmt.Sprintf("line %d", i), b.lines[i].String())
+	}
+	assert.True(t, b.lines[0].cells[0].attr.Bold)
+
+	// more output pushes the restored lines back out
+	writeLines(b, "more")
+	assert.Equal(t, 10, b.Height())
+	assert.Equal(t, 22, b.SpilledLines())
+}
+
+func TestSpillKeepsCombiningCharacters(t *testing.T) {
+	spill, err := NewSpill("")
+	require.Nil(t, err)
+	defer spill.Close()
+
+	b := NewBuffer(NewTerminalState(10, 5, CellAttributes{}, 10))
+	b.SpillTo(spill)
+	writeLines(b, "e\u0301 a\u0308\u0323")
+	for i := 0; i < 20; i++ {
+		writeLines(b, "more")
+	}
+	require.Equal(t, 12, b.SpilledLines())
+
+	b.RestoreSpilled(12)
+	assert.Equal(t, "e\u0301 a\u0308\u0323", b.lines[0].String())
+	assert.Equal(t, "more", b.lines[1].String())
+}
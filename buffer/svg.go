@@ -0,0 +1,186 @@
+package buffer
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// SVGFont is a TrueType font to embed in exported SVGs, so they look the same wherever they're opened
+type SVGFont struct {
+	Data []byte
+	Bold bool
+}
+
+// SVGOptions control how WriteSVG draws the view. Sizes are in pixels.
+type SVGOptions struct {
+	FontFamily string // used when no fonts are embedded, or a glyph is missing from them
+	FontSize   float32
+	CellWidth  float32
+	CellHeight float32
+	Baseline   float32 // distance from the top of a cell to the text baseline
+	Foreground [3]float32
+	Background [3]float32
+	Cursor     *[3]float32 // nil to leave the cursor out
+	Fonts      []SVGFont
+}
+
+func svgColour(c [3]float32) string {
+	return fmt.Sprintf(
+		"#%02x%02x%02x",
+		uint8(math.Floor(float64(255*c[0]))),
+		uint8(math.Floor(float64(255*c[1]))),
+		uint8(math.Floor(float64(255*c[2]))),
+	)
+}
+
+// WriteSVG draws the visible lines of the buffer as a standalone SVG image
+func (buffer *Buffer) WriteSVG(w io.Writer, options SVGOptions) error {
+	out := bufio.NewWriter(w)
+
+	width := float32(buffer.ViewWidth()) * options.CellWidth
+	height := float32(buffer.ViewHeight()) * options.CellHeight
+
+	fmt.Fprintf(out, `<svg xmlns="http://www.w3.org/2000/svg" width="%g" height="%g" viewBox="0 0 %g %g">`+"\n", width, height, width, height)
+
+	family := "aminal"
+	if options.FontFamily != "" {
+		family = fmt.Sprintf("aminal, %s", options.FontFamily)
+	}
+	fmt.Fprintf(out, "<style>\n")
+	for _, font := range options.Fonts {
+		weight := "normal"
+		if font.Bold {
+			weight = "bold"
+		}
+		fmt.Fprintf(out, "@font-face { font-family: aminal; font-weight: %s; src: url(data:font/ttf;base64,%s); }\n", weight, base64.StdEncoding.EncodeToString(font.Data))
+	}
+	fmt.Fprintf(out, "text { font-family: %s; font-size: %gpx; white-space: pre; }\n", family, options.FontSize)
+	fmt.Fprintf(out, "</style>\n")
+	fmt.Fprintf(out, `<rect width="100%%" height="100%%" fill="%s"/>`+"\n", svgColour(options.Background))
+
+	cursorX, cursorY := -1, -1
+	if options.Cursor != nil {
+		cursorX = int(buffer.CursorColumn())
+		cursorY = int(buffer.CursorLine()) + int(buffer.terminalState.scrollLinesFromBottom)
+		if buffer.terminalState.OriginMode {
+			cursorY += int(buffer.terminalState.topMargin)
+		}
+	}
+
+	lines := buffer.GetVisibleLines()
+	for row, line := range lines {
+		y := float32(row) * options.CellHeight
+
+		// backgrounds, merging runs of the same colour
+		for col := 0; col < len(line.cells); {
+			bg := line.cells[col].Bg()
+			end := col + 1
+			for end < len(line.cells) && line.cells[end].Bg() == bg {
+				end++
+			}
+			if bg != options.Background {
+				fmt.Fprintf(out, `<rect x="%g" y="%g" width="%g" height="%g" fill="%s"/>`+"\n",
+					float32(col)*options.CellWidth, y, float32(end-col)*options.CellWidth, options.CellHeight, svgColour(bg))
+			}
+			col = end
+		}
+
+		if row == cursorY {
+			fmt.Fprintf(out, `<rect x="%g" y="%g" width="%g" height="%g" fill="%s"/>`+"\n",
+				float32(cursorX)*options.CellWidth, y, options.CellWidth, options.CellHeight, svgColour(*options.Cursor))
+		}
+
+		// text, in runs of the same style so the file stays small
+		for col := 0; col < len(line.cells); {
+			style := svgTextStyle(line.cells[col], row == cursorY && col == cursorX, options)
+			end := col + 1
+			for end < len(line.cells) && svgTextStyle(line.cells[end], row == cursorY && end == cursorX, options) == style {
+				end++
+			}
+			buffer.writeSVGText(out, line.cells[col:end], col, y+options.Baseline, style, options)
+			col = end
+		}
+	}
+
+	if cursorY >= len(lines) && cursorY < int(buffer.ViewHeight()) {
+		// the cursor is on a line with nothing written to it yet
+		fmt.Fprintf(out, `<rect x="%g" y="%g" width="%g" height="%g" fill="%s"/>`+"\n",
+			float32(cursorX)*options.CellWidth, float32(cursorY)*options.CellHeight, options.CellWidth, options.CellHeight, svgColour(*options.Cursor))
+	}
+
+	fmt.Fprintf(out, "</svg>\n")
+	return out.Flush()
+}
+
+// svgUnderlineStyles are the text-decoration styles for the underline styles other than a single line
+var svgUnderlineStyles = map[UnderlineStyle]string{
+	UnderlineDouble: "double",
+	UnderlineCurly:  "wavy",
+	UnderlineDotted: "dotted",
+	UnderlineDashed: "dashed",
+}
+
+// svgTextStyle returns the attributes of the <text> element for a cell
+func svgTextStyle(cell Cell, cursor bool, options SVGOptions) string {
+	fg := cell.Fg()
+	if cursor {
+		fg = options.Background
+	}
+	attrs := []string{fmt.Sprintf(`fill="%s"`, svgColour(fg))}
+	if cell.attr.Bold {
+		attrs = append(attrs, `font-weight="bold"`)
+	}
+	if cell.attr.Italic {
+		attrs = append(attrs, `font-style="italic"`)
+	}
+	decoration := []string{}
+	if cell.attr.Underline {
+		decoration = append(decoration, "underline")
+	}
+	if cell.attr.Strikethrough {
+		decoration = append(decoration, "line-through")
+	}
+	if cell.attr.Overline {
+		decoration = append(decoration, "overline")
+	}
+	if cell.attr.Underline {
+		// SVG has one style and colour for all the lines, so they're taken from the underline
+		if style := svgUnderlineStyles[cell.attr.UnderlineStyle]; style != "" {
+			decoration = append(decoration, style)
+		}
+		if cell.attr.UnderlineColoured {
+			decoration = append(decoration, svgColour(cell.attr.UnderlineColour))
+		}
+	}
+	if len(decoration) > 0 {
+		attrs = append(attrs, fmt.Sprintf(`text-decoration="%s"`, strings.Join(decoration, " ")))
+	}
+	if cell.attr.Dim {
+		attrs = append(attrs, `opacity="0.5"`)
+	}
+	return strings.Join(attrs, " ")
+}
+
+func (buffer *Buffer) writeSVGText(out io.Writer, cells []Cell, startCol int, y float32, style string, options SVGOptions) {
+	var text strings.Builder
+	positions := []string{}
+	for i, cell := range cells {
+		if cell.r == 0 || cell.r == ' ' || cell.attr.Hidden {
+			continue
+		}
+		text.WriteString(cell.Text())
+		// position every glyph, so the grid lines up whatever font the viewer ends up using
+		positions = append(positions, fmt.Sprintf("%g", float32(startCol+i)*options.CellWidth))
+	}
+	if text.Len() == 0 {
+		return
+	}
+	fmt.Fprintf(out, `<text x="%s" y="%g" %s>`, strings.Join(positions, " "), y, style)
+	_ = xml.EscapeText(out, []byte(text.String()))
+	fmt.Fprintf(out, "</text>\n")
+}
@@ -0,0 +1,71 @@
+package buffer
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteSVG(t *testing.T) {
+	b := NewBuffer(NewTerminalState(10, 2, CellAttributes{}, 1000))
+	b.Write([]rune("a<b")...)
+	b.CursorAttr().Bold = true
+	b.CursorAttr().BgColour = [3]float32{1, 0, 0}
+	b.Write([]rune("ok")...)
+
+	cursor := [3]float32{0, 1, 0}
+	var out bytes.Buffer
+	require.Nil(t, b.WriteSVG(&out, SVGOptions{
+		FontFamily: "monospace",
+		FontSize:   12,
+		CellWidth:  8,
+		CellHeight: 16,
+		Baseline:   12,
+		Foreground: [3]float32{1, 1, 1},
+		Cursor:     &cursor,
+		Fonts:      []SVGFont{{Data: []byte("font"), Bold: false}},
+	}))
+	svg := out.String()
+
+	// it should be well formed
+	decoder := xml.NewDecoder(bytes.NewReader(out.Bytes()))
+	for {
+		_, err := decoder.Token()
+		if err != nil {
+			assert.Equal(t, "EOF", err.Error())
+			break
+		}
+	}
+
+	assert.Contains(t, svg, `width="80" height="32"`)
+	assert.Contains(t, svg, `src: url(data:font/ttf;base64,Zm9udA==)`)
+	assert.Contains(t, svg, `<text x="0 8 16" y="12" fill="#000000">a&lt;b</text>`)
+	assert.Contains(t, svg, `<text x="24 32" y="12" fill="#000000" font-weight="bold">ok</text>`)
+	assert.Contains(t, svg, `<rect x="24" y="0" width="16" height="16" fill="#ff0000"/>`)
+	// cursor after the text
+	assert.Contains(t, svg, `<rect x="40" y="0" width="8" height="16" fill="#00ff00"/>`)
+}
+
+func TestSVGTextDecoration(t *testing.T) {
+	b := NewBuffer(NewTerminalState(10, 2, CellAttributes{}, 1000))
+	b.CursorAttr().Underline = true
+	b.Write('a')
+	b.CursorAttr().UnderlineStyle = UnderlineCurly
+	b.CursorAttr().UnderlineColoured = true
+	b.CursorAttr().UnderlineColour = [3]float32{1, 0, 0}
+	b.Write('b')
+	b.CursorAttr().Underline = false
+	b.CursorAttr().Strikethrough = true
+	b.CursorAttr().Overline = true
+	b.Write('c')
+
+	var out bytes.Buffer
+	require.Nil(t, b.WriteSVG(&out, SVGOptions{FontSize: 12, CellWidth: 8, CellHeight: 16, Baseline: 12}))
+	svg := out.String()
+	assert.Contains(t, svg, `text-decoration="underline">a</text>`)
+	assert.Contains(t, svg, `text-decoration="underline wavy #ff0000">b</text>`)
+	assert.Contains(t, svg, `text-decoration="line-through overline">c</text>`)
+}
@@ -0,0 +1,118 @@
+package buffer
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// TargetKind says what sort of thing a Target is
+type TargetKind int
+
+const (
+	TargetLink TargetKind = iota // matched a link rule
+	TargetURL
+	TargetPath
+)
+
+// Target is something on screen which can be opened or copied, found by FindTargets
+type Target struct {
+	Kind     TargetKind
+	Text     string // as shown on screen
+	URL      string // what opening it launches: the URL or path itself, or the expansion of the link rule
+	Rule     int    // index of the link rule matched, for links
+	ViewRow  uint16
+	StartCol uint16
+	EndCol   uint16 // inclusive
+}
+
+var (
+	targetURLPattern = regexp.MustCompile(`[a-zA-Z][a-zA-Z0-9+.-]*://[^\s'"<>{}]+`)
+	// paths are only picked out when they're clearly paths, starting with /, ~/, ./ or ../ after a space or bracket
+	targetPathPattern = regexp.MustCompile(`(?:^|[\s'"(\[=])((?:~|\.\.?)?/[^\s'"()<>{}\[\]:]+)`)
+)
+
+// FindTargets returns the links, URLs and file paths on screen, from the top left. Link rules are matched
+// first, so a rule can take over text which would otherwise be opened as a plain URL or path.
+func (buffer *Buffer) FindTargets(rules []LinkRule) []Target {
+	var targets []Target
+	for viewRow := uint16(0); viewRow < buffer.terminalState.viewHeight; viewRow++ {
+		row := buffer.convertViewLineToRawLine(viewRow) - uint64(buffer.terminalState.scrollLinesFromBottom)
+		if row >= uint64(len(buffer.lines)) {
+			continue
+		}
+		cells := buffer.lines[row].cells
+		text, cols := targetText(cells)
+
+		var found []Target
+		add := func(kind TargetKind, start int, end int, url string, rule int) {
+			end = start + len(strings.TrimRight(text[start:end], ".,;:!?"))
+			if end <= start {
+				return
+			}
+			t := Target{
+				Kind:     kind,
+				Text:     text[start:end],
+				URL:      url,
+				Rule:     rule,
+				ViewRow:  viewRow,
+				StartCol: cols[start],
+				EndCol:   lastColumn(cells, cols[end-1]),
+			}
+			if kind != TargetLink {
+				t.URL = t.Text
+			}
+			for _, other := range found {
+				if t.StartCol <= other.EndCol && other.StartCol <= t.EndCol {
+					return
+				}
+			}
+			found = append(found, t)
+		}
+
+		for i, rule := range rules {
+			for _, match := range rule.pattern.FindAllStringSubmatchIndex(text, -1) {
+				if match[1] > match[0] {
+					add(TargetLink, match[0], match[1], string(rule.pattern.ExpandString(nil, rule.url, text, match)), i)
+				}
+			}
+		}
+		for _, match := range targetURLPattern.FindAllStringIndex(text, -1) {
+			add(TargetURL, match[0], match[1], "", 0)
+		}
+		for _, match := range targetPathPattern.FindAllStringSubmatchIndex(text, -1) {
+			add(TargetPath, match[2], match[3], "", 0)
+		}
+
+		sort.Slice(found, func(i, j int) bool {
+			return found[i].StartCol < found[j].StartCol
+		})
+		targets = append(targets, found...)
+	}
+	return targets
+}
+
+// targetText returns the text of a line, along with the column of the cell each byte of it came from
+func targetText(cells []Cell) (string, []uint16) {
+	var builder strings.Builder
+	var cols []uint16
+	for col := range cells {
+		if cells[col].continuation {
+			continue
+		}
+		text := cells[col].Text()
+		builder.WriteString(text)
+		for range []byte(text) {
+			cols = append(cols, uint16(col))
+		}
+	}
+	return builder.String(), cols
+}
+
+// lastColumn returns the last cell covered by the character starting at col, which is the one after for wide characters
+func lastColumn(cells []Cell, col uint16) uint16 {
+	for int(col)+1 < len(cells) && cells[col+1].continuation {
+		col++
+	}
+	return col
+}
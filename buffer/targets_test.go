@@ -0,0 +1,35 @@
+package buffer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindTargets(t *testing.T) {
+	b := NewBuffer(NewTerminalState(80, 3, CellAttributes{}, 100))
+	b.Write([]rune("see https://example.com/a?b=c, or (./build.sh)")...)
+	b.NewLine()
+	b.CarriageReturn()
+	b.Write([]rune("表 ~/notes.txt JIRA-12 http://x.y/JIRA-3")...)
+
+	jira, err := NewLinkRule(`JIRA-(\d+)`, "https://jira/browse/$0")
+	require.Nil(t, err)
+
+	targets := b.FindTargets([]LinkRule{jira})
+	require.Len(t, targets, 5)
+
+	assert.Equal(t, Target{Kind: TargetURL, Text: "https://example.com/a?b=c", URL: "https://example.com/a?b=c", ViewRow: 0, StartCol: 4, EndCol: 28}, targets[0], "trailing punctuation isn't part of a URL")
+	assert.Equal(t, Target{Kind: TargetPath, Text: "./build.sh", URL: "./build.sh", ViewRow: 0, StartCol: 35, EndCol: 44}, targets[1])
+
+	assert.Equal(t, TargetPath, targets[2].Kind)
+	assert.Equal(t, "~/notes.txt", targets[2].Text)
+	assert.Equal(t, uint16(1), targets[2].ViewRow)
+	assert.Equal(t, uint16(3), targets[2].StartCol, "the wide character takes two cells")
+	assert.Equal(t, uint16(13), targets[2].EndCol)
+
+	assert.Equal(t, Target{Kind: TargetLink, Text: "JIRA-12", URL: "https://jira/browse/JIRA-12", ViewRow: 1, StartCol: 15, EndCol: 21}, targets[3])
+	assert.Equal(t, TargetLink, targets[4].Kind, "link rules take over text in URLs")
+	assert.Equal(t, "JIRA-3", targets[4].Text)
+}
@@ -7,13 +7,18 @@ type TerminalState struct {
 	CursorAttr            CellAttributes
 	viewHeight            uint16
 	viewWidth             uint16
-	topMargin             uint // see DECSTBM docs - this is for scrollable regions
-	bottomMargin          uint // see DECSTBM docs - this is for scrollable regions
-	ReplaceMode           bool // overwrite character at cursor or insert new
+	topMargin             uint   // see DECSTBM docs - this is for scrollable regions
+	bottomMargin          uint   // see DECSTBM docs - this is for scrollable regions
+	leftMargin            uint16 // see DECSLRM docs - the left and right margins only apply in LeftRightMarginMode
+	rightMargin           uint16
+	LeftRightMarginMode   bool // DECLRMM, which lets DECSLRM set left and right margins
+	InsertMode            bool // IRM: characters written push the rest of the line right rather than overwriting it
 	OriginMode            bool // see DECOM docs - whether cursor is positioned within the margins or not
 	LineFeedMode          bool
 	ScreenMode            bool // DECSCNM (black on white background)
 	AutoWrap              bool
+	ReverseWrap           bool // backspace at the left margin moves to the end of the previous line, if it was wrapped onto this one
+	wrapPending           bool // xterm's do_wrap: the last column has been written, so the next character wraps
 	maxLines              uint64
 	tabStops              map[uint16]struct{}
 	Charsets              []*map[rune]rune // array of 2 charsets, nil means ASCII (no conversion)
@@ -32,6 +37,7 @@ func NewTerminalState(viewCols uint16, viewLines uint16, attr CellAttributes, ma
 		viewHeight:   viewLines,
 		topMargin:    0,
 		bottomMargin: uint(viewLines - 1),
+		rightMargin:  viewCols - 1,
 		Charsets:     []*map[rune]rune{nil, nil},
 		LineFeedMode: true,
 	}
@@ -39,11 +45,32 @@ func NewTerminalState(viewCols uint16, viewLines uint16, attr CellAttributes, ma
 	return b
 }
 
+// Reset puts the modes, character sets, tab stops and margins back as they were when the terminal started,
+// with attr as the cursor's attributes
+func (terminalState *TerminalState) Reset(attr CellAttributes) {
+	terminalState.CursorAttr = attr
+	terminalState.InsertMode = false
+	terminalState.OriginMode = false
+	terminalState.LineFeedMode = true
+	terminalState.ScreenMode = false
+	terminalState.AutoWrap = true
+	terminalState.ReverseWrap = false
+	terminalState.wrapPending = false
+	terminalState.Charsets = []*map[rune]rune{nil, nil}
+	terminalState.CurrentCharset = 0
+	terminalState.scrollLinesFromBottom = 0
+	terminalState.LeftRightMarginMode = false
+	terminalState.ResetVerticalMargins()
+	terminalState.ResetHorizontalMargins()
+	terminalState.TabReset()
+}
+
 func (terminalState *TerminalState) DefaultCell(applyEffects bool) Cell {
 	attr := terminalState.CursorAttr
 	if !applyEffects {
 		attr.Blink = false
 		attr.Bold = false
+		attr.Italic = false
 		attr.Dim = false
 		attr.Inverse = false
 		attr.Underline = false
@@ -62,6 +89,33 @@ func (terminalState *TerminalState) ResetVerticalMargins() {
 	terminalState.SetVerticalMargins(0, uint(terminalState.viewHeight-1))
 }
 
+// SetHorizontalMargins sets the left and right margins (DECSLRM), which only apply in LeftRightMarginMode
+func (terminalState *TerminalState) SetHorizontalMargins(left uint16, right uint16) {
+	terminalState.leftMargin = left
+	terminalState.rightMargin = right
+}
+
+// ResetHorizontalMargins moves the left and right margins out to the edges of the view
+func (terminalState *TerminalState) ResetHorizontalMargins() {
+	terminalState.SetHorizontalMargins(0, terminalState.viewWidth-1)
+}
+
+// horizontalMargins returns the left and right margins in use, which are the edges of the view unless
+// LeftRightMarginMode is set
+func (terminalState *TerminalState) horizontalMargins() (left uint16, right uint16) {
+	right = terminalState.viewWidth - 1
+	if !terminalState.LeftRightMarginMode {
+		return 0, right
+	}
+	if terminalState.rightMargin < right {
+		right = terminalState.rightMargin
+	}
+	if terminalState.leftMargin < right {
+		left = terminalState.leftMargin
+	}
+	return left, right
+}
+
 func (terminalState *TerminalState) IsNewLineMode() bool {
 	return terminalState.LineFeedMode == false
 }
@@ -79,11 +133,7 @@ func (terminalState *TerminalState) TabClear(index uint16) {
 }
 
 func (terminalState *TerminalState) getTabIndexFromCursor() uint16 {
-	index := terminalState.cursorX
-	if index == terminalState.viewWidth {
-		index = 0
-	}
-	return index
+	return terminalState.cursorX
 }
 
 func (terminalState *TerminalState) IsTabSetAtCursor() bool {
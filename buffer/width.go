@@ -0,0 +1,130 @@
+package buffer
+
+import (
+	"sort"
+	"unicode"
+)
+
+// runeRange is an inclusive range of code points
+type runeRange struct {
+	first rune
+	last  rune
+}
+
+// wideRanges are the East Asian wide and fullwidth characters, and the emoji which are shown as such, in order.
+// See http://www.unicode.org/reports/tr11/
+var wideRanges = []runeRange{
+	{0x1100, 0x115f}, // hangul jamo initial consonants
+	{0x231a, 0x231b},
+	{0x2329, 0x232a},
+	{0x23e9, 0x23ec},
+	{0x23f0, 0x23f0},
+	{0x23f3, 0x23f3},
+	{0x25fd, 0x25fe},
+	{0x2614, 0x2615},
+	{0x2648, 0x2653},
+	{0x267f, 0x267f},
+	{0x2693, 0x2693},
+	{0x26a1, 0x26a1},
+	{0x26aa, 0x26ab},
+	{0x26bd, 0x26be},
+	{0x26c4, 0x26c5},
+	{0x26ce, 0x26ce},
+	{0x26d4, 0x26d4},
+	{0x26ea, 0x26ea},
+	{0x26f2, 0x26f3},
+	{0x26f5, 0x26f5},
+	{0x26fa, 0x26fa},
+	{0x26fd, 0x26fd},
+	{0x2705, 0x2705},
+	{0x270a, 0x270b},
+	{0x2728, 0x2728},
+	{0x274c, 0x274c},
+	{0x274e, 0x274e},
+	{0x2753, 0x2755},
+	{0x2757, 0x2757},
+	{0x2795, 0x2797},
+	{0x27b0, 0x27b0},
+	{0x27bf, 0x27bf},
+	{0x2b1b, 0x2b1c},
+	{0x2b50, 0x2b50},
+	{0x2b55, 0x2b55},
+	{0x2e80, 0x303e},   // cjk radicals, punctuation
+	{0x3041, 0x33ff},   // kana, bopomofo, cjk compatibility
+	{0x3400, 0x4dbf},   // cjk unified ideographs extension a
+	{0x4e00, 0x9fff},   // cjk unified ideographs
+	{0xa000, 0xa4cf},   // yi
+	{0xa960, 0xa97f},   // hangul jamo extended a
+	{0xac00, 0xd7a3},   // hangul syllables
+	{0xf900, 0xfaff},   // cjk compatibility ideographs
+	{0xfe10, 0xfe19},   // vertical forms
+	{0xfe30, 0xfe6f},   // cjk compatibility forms, small form variants
+	{0xff00, 0xff60},   // fullwidth forms
+	{0xffe0, 0xffe6},   // fullwidth signs
+	{0x16fe0, 0x16fe4}, // ideographic symbols
+	{0x17000, 0x18aff}, // tangut
+	{0x1b000, 0x1b2ff}, // kana supplement, nushu
+	{0x1f004, 0x1f004},
+	{0x1f0cf, 0x1f0cf},
+	{0x1f18e, 0x1f18e},
+	{0x1f191, 0x1f19a},
+	{0x1f200, 0x1f251}, // enclosed ideographic supplement
+	{0x1f260, 0x1f265},
+	{0x1f300, 0x1f320}, // emoji from here on
+	{0x1f32d, 0x1f335},
+	{0x1f337, 0x1f37c},
+	{0x1f37e, 0x1f393},
+	{0x1f3a0, 0x1f3ca},
+	{0x1f3cf, 0x1f3d3},
+	{0x1f3e0, 0x1f3f0},
+	{0x1f3f4, 0x1f3f4},
+	{0x1f3f8, 0x1f43e},
+	{0x1f440, 0x1f440},
+	{0x1f442, 0x1f4fc},
+	{0x1f4ff, 0x1f53d},
+	{0x1f54b, 0x1f54e},
+	{0x1f550, 0x1f567},
+	{0x1f57a, 0x1f57a},
+	{0x1f595, 0x1f596},
+	{0x1f5a4, 0x1f5a4},
+	{0x1f5fb, 0x1f64f},
+	{0x1f680, 0x1f6c5},
+	{0x1f6cc, 0x1f6cc},
+	{0x1f6d0, 0x1f6d2},
+	{0x1f6eb, 0x1f6ec},
+	{0x1f6f4, 0x1f6f9},
+	{0x1f910, 0x1f93e},
+	{0x1f940, 0x1f970},
+	{0x1f973, 0x1f976},
+	{0x1f97a, 0x1f97a},
+	{0x1f97c, 0x1f9a2},
+	{0x1f9b0, 0x1f9b9},
+	{0x1f9c0, 0x1f9c2},
+	{0x1f9d0, 0x1f9ff},
+	{0x20000, 0x2fffd}, // cjk unified ideographs extensions b onwards
+	{0x30000, 0x3fffd},
+}
+
+// RuneWidth returns the number of cells r takes up in the grid: 0 for combining marks and other characters which
+// attach to the one before, 2 for wide characters and 1 for everything else
+func RuneWidth(r rune) int {
+	if r < 0x300 {
+		// fast path for latin, none of which is wide or zero width
+		return 1
+	}
+	if unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf) {
+		// combining marks, variation selectors and format characters like the zero width joiner
+		return 0
+	}
+	if r >= 0x1160 && r <= 0x11ff {
+		// hangul jamo vowels and final consonants join on to the initial consonant before them
+		return 0
+	}
+	i := sort.Search(len(wideRanges), func(i int) bool {
+		return wideRanges[i].last >= r
+	})
+	if i < len(wideRanges) && wideRanges[i].first <= r {
+		return 2
+	}
+	return 1
+}
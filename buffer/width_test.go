@@ -0,0 +1,83 @@
+package buffer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRuneWidth(t *testing.T) {
+	assert.Equal(t, 1, RuneWidth('a'))
+	assert.Equal(t, 1, RuneWidth('é'))
+	assert.Equal(t, 1, RuneWidth('─'))
+	assert.Equal(t, 2, RuneWidth('中'))
+	assert.Equal(t, 2, RuneWidth('한'))
+	assert.Equal(t, 2, RuneWidth('Ａ'))
+	assert.Equal(t, 2, RuneWidth('😀'))
+	assert.Equal(t, 0, RuneWidth('\u0301')) // combining acute accent
+	assert.Equal(t, 0, RuneWidth('\u200d')) // zero width joiner
+	assert.Equal(t, 0, RuneWidth('\ufe0f')) // variation selector
+}
+
+func TestWideCharactersTakeTwoCells(t *testing.T) {
+	b := NewBuffer(NewTerminalState(10, 3, CellAttributes{}, 100))
+	b.Write([]rune("a中b")...)
+
+	assert.Equal(t, uint16(4), b.CursorColumn())
+	cells := b.lines[0].cells
+	assert.Equal(t, 'a', cells[0].Rune())
+	assert.True(t, cells[1].Wide())
+	assert.True(t, cells[2].Continuation())
+	assert.Equal(t, 'b', cells[3].Rune())
+	assert.Equal(t, "a中b", b.lines[0].String())
+}
+
+func TestWideCharacterWrapsWhenOneColumnIsLeft(t *testing.T) {
+	b := NewBuffer(NewTerminalState(5, 3, CellAttributes{}, 100))
+	b.Write([]rune("abcd中")...)
+
+	assert.Equal(t, "abcd", b.lines[0].String())
+	assert.Equal(t, "中", b.lines[1].String())
+	assert.True(t, b.lines[1].Wrapped())
+	assert.Equal(t, uint16(2), b.CursorColumn())
+	assert.Equal(t, uint16(1), b.CursorLine())
+}
+
+func TestWideCharacterFillingTheLastColumnsWrapsLater(t *testing.T) {
+	b := NewBuffer(NewTerminalState(4, 3, CellAttributes{}, 100))
+	b.Write([]rune("ab中")...)
+
+	assert.Equal(t, uint16(3), b.CursorColumn())
+	assert.Equal(t, uint16(0), b.CursorLine())
+
+	b.Write('c')
+	assert.Equal(t, "ab中", b.lines[0].String())
+	assert.Equal(t, "c", b.lines[1].String())
+}
+
+func TestZeroWidthCharactersAttachToThePreviousCell(t *testing.T) {
+	b := NewBuffer(NewTerminalState(10, 3, CellAttributes{}, 100))
+	b.Write([]rune("e\u0301中\u0301x")...)
+
+	assert.Equal(t, uint16(4), b.CursorColumn())
+	cells := b.lines[0].cells
+	assert.Equal(t, []rune{'\u0301'}, cells[0].Combining())
+	assert.Equal(t, []rune{'\u0301'}, cells[1].Combining())
+	assert.Equal(t, "e\u0301", cells[0].Text())
+	assert.Equal(t, "e\u0301中\u0301x", b.lines[0].String())
+}
+
+func TestOverwritingHalfAWideCharacterErasesIt(t *testing.T) {
+	b := NewBuffer(NewTerminalState(10, 3, CellAttributes{}, 100))
+	b.Write([]rune("中文")...)
+	b.SetPosition(1, 0)
+	b.Write('x')
+	b.SetPosition(2, 0)
+	b.Write('y')
+
+	cells := b.lines[0].cells
+	assert.Equal(t, rune(0), cells[0].Rune())
+	assert.Equal(t, 'x', cells[1].Rune())
+	assert.Equal(t, 'y', cells[2].Rune())
+	assert.False(t, cells[3].Continuation())
+}
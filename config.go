@@ -9,6 +9,10 @@ import (
 	"path/filepath"
 
 	"github.com/liamg/aminal/config"
+	"github.com/liamg/aminal/gui"
+	"github.com/liamg/aminal/terminal"
+	"github.com/liamg/aminal/terminfo"
+	"github.com/liamg/aminal/themes"
 	"github.com/liamg/aminal/version"
 )
 
@@ -26,20 +30,58 @@ func getConfig() *config.Config {
 	showVersion := false
 	ignoreConfig := false
 	shell := ""
+	login := false
+	execute := false
+	holdOpen := false
+	restart := false
 	debugMode := false
 	slomo := false
+	latency := false
+	listFonts := false
+	installTerminfo := false
+	geometry := ""
+	startAs := ""
+	runDaemon := false
+	attach := false
+	view := ""
+	connect := ""
+	baud := 0
+	controlSocket := ""
+	profile := ""
 
 	if flag.Parsed() == false {
 		flag.BoolVar(&showVersion, "version", showVersion, "Output version information")
 		flag.BoolVar(&ignoreConfig, "ignore-config", ignoreConfig, "Ignore user config files and use defaults")
 		flag.StringVar(&shell, "shell", shell, "Specify the shell to use")
+		flag.BoolVar(&login, "login", login, "Start the shell as a login shell")
+		flag.BoolVar(&holdOpen, "hold", holdOpen, "Keep the window open when the shell or program exits, until a key is pressed")
+		flag.BoolVar(&restart, "restart", restart, "Start the shell again when it crashes, below a line saying why")
+		flag.StringVar(&profile, "profile", profile, "Start the first tab with the profile of that name from the config")
+		flag.BoolVar(&execute, "e", execute, "Run the program and arguments after the flags in the window instead of the shell, e.g. aminal -e htop")
 		flag.BoolVar(&debugMode, "debug", debugMode, "Enable debug logging")
 		flag.BoolVar(&slomo, "slomo", slomo, "Render in slow motion (useful for debugging)")
+		flag.BoolVar(&latency, "latency", latency, "Measure input latency and report it in the debug overlay")
+		flag.BoolVar(&listFonts, "list-fonts", listFonts, "List the installed monospace fonts which can be used in the [font] config")
+		flag.StringVar(&geometry, "geometry", geometry, "Size and position of the window in columns and rows, like xterm's, e.g. 120x40+100+50")
+		flag.StringVar(&startAs, "start-as", startAs, "State of the window when it opens: normal, maximized or minimized")
+		flag.BoolVar(&runDaemon, "daemon", runDaemon, "Keep shells running without a window, for windows started with --attach to attach to")
+		flag.BoolVar(&attach, "attach", attach, "Start shells in the daemon, starting it if need be, and attach to the sessions it has which aren't shown")
+		flag.StringVar(&view, "view", view, "Watch a session shared read-only by another Aminal, given the link it showed, instead of starting a shell")
+		flag.StringVar(&connect, "connect", connect, "Run the terminal on a serial port, tcp:host:port or exec:command instead of a shell")
+		flag.IntVar(&baud, "baud", baud, "Baud rate of the serial port given with --connect")
+		flag.StringVar(&controlSocket, "control-socket", controlSocket, "Listen on the socket for commands sending input, reading the screen, resizing and taking screenshots")
+		flag.BoolVar(&installTerminfo, "install-terminfo", installTerminfo, "Install aminal's terminfo entry, so that TERM can be set to aminal")
 
 		flag.Parse() // actual parsing and fetching flags from the command line
 	}
 	actuallyProvidedFlags := getActuallyProvidedFlags()
 
+	// "aminal config show" waits until the flags have been applied to the config, to show the result
+	configSubcommand := !execute && flag.Arg(0) == "config"
+	if configSubcommand && flag.Arg(1) != "show" {
+		os.Exit(configCommand(flag.Args()[1:]))
+	}
+
 	if showVersion {
 		v := version.Version
 		if v == "" {
@@ -49,6 +91,31 @@ func getConfig() *config.Config {
 		os.Exit(0)
 	}
 
+	if listFonts {
+		for _, font := range gui.InstalledFonts() {
+			fmt.Printf("%s\n  regular = %q\n", font.Family, font.Regular)
+			if font.Bold != "" {
+				fmt.Printf("  bold    = %q\n", font.Bold)
+			}
+			if font.Italic != "" {
+				fmt.Printf("  italic  = %q\n", font.Italic)
+			}
+			if font.BoldItalic != "" {
+				fmt.Printf("  bold_italic = %q\n", font.BoldItalic)
+			}
+		}
+		os.Exit(0)
+	}
+
+	if installTerminfo {
+		if err := terminfo.Install(); err != nil {
+			fmt.Printf("Failed to install the terminfo entry: %s\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Installed the terminfo entry, set term = %q in the config to use it\n", terminfo.Name)
+		os.Exit(0)
+	}
+
 	var conf *config.Config
 	if ignoreConfig {
 		conf = &config.DefaultConfig
@@ -61,6 +128,26 @@ func getConfig() *config.Config {
 		conf.Shell = shell
 	}
 
+	if actuallyProvidedFlags["login"] {
+		conf.LoginShell = login
+	}
+
+	if actuallyProvidedFlags["hold"] {
+		conf.Hold = holdOpen
+	}
+
+	if actuallyProvidedFlags["restart"] {
+		conf.Restart = restart
+	}
+
+	if execute {
+		if flag.NArg() == 0 {
+			fmt.Println("-e needs a program to run")
+			os.Exit(1)
+		}
+		conf.Command = flag.Args()
+	}
+
 	if actuallyProvidedFlags["debug"] {
 		conf.DebugMode = debugMode
 	}
@@ -69,19 +156,90 @@ func getConfig() *config.Config {
 		conf.Slomo = slomo
 	}
 
+	if actuallyProvidedFlags["latency"] {
+		conf.MeasureLatency = latency
+	}
+
+	if actuallyProvidedFlags["geometry"] {
+		conf.Window.Geometry = geometry
+	}
+
+	if actuallyProvidedFlags["start-as"] {
+		conf.Window.State = startAs
+	}
+
+	if actuallyProvidedFlags["attach"] {
+		conf.Daemon.Attach = attach
+	}
+
+	if actuallyProvidedFlags["view"] {
+		conf.View = view
+	}
+
+	if actuallyProvidedFlags["connect"] {
+		conf.Connect = connect
+	}
+
+	if actuallyProvidedFlags["baud"] {
+		conf.Serial.Baud = baud
+	}
+
+	if actuallyProvidedFlags["control-socket"] {
+		conf.Control.Socket = controlSocket
+	}
+
+	if actuallyProvidedFlags["profile"] {
+		if _, ok := conf.ProfileNamed(profile); !ok {
+			fmt.Printf("There's no profile called %q in the config\n", profile)
+			os.Exit(1)
+		}
+		conf.Profile = profile
+	} else if p, ok := conf.MatchProfile(conf.Command); ok {
+		conf.Profile = p.Name
+	}
+
+	if err := themes.Apply(conf); err != nil {
+		fmt.Printf("Failed to load theme, using the colours from the config instead: %s\n", err)
+	}
+
+	if configSubcommand {
+		os.Exit(showConfig(conf))
+	}
+
+	if runDaemon {
+		os.Exit(daemon(conf))
+	}
+
 	return conf
 }
 
-func loadConfigFile() *config.Config {
+// terminalOptions picks out the parts of the config the terminal emulator needs
+func terminalOptions(conf *config.Config) terminal.Options {
+	scheme := conf.ColourScheme
+	return terminal.Options{
+		Foreground:   scheme.Foreground,
+		Background:   scheme.Background,
+		Cursor:       scheme.Cursor,
+		Palette:      terminal.Palette(scheme.ANSIPalette()),
+		BoldIsBright: conf.BoldIsBright,
+		Bidi:         conf.Bidi,
+		MaxLines:     conf.MaxLines,
+		Slomo:        conf.Slomo,
+		Version:      version.Version,
+	}
+}
+
+// configPlaces returns where the config file is looked for, in order
+func configPlaces() []string {
 	usr, err := user.Current()
 	if err != nil {
 		fmt.Printf("Failed to get current user information: %s\n", err)
-		return &config.DefaultConfig
+		return nil
 	}
 
 	home := usr.HomeDir
 	if home == "" {
-		return &config.DefaultConfig
+		return nil
 	}
 
 	places := []string{}
@@ -93,27 +251,36 @@ func loadConfigFile() *config.Config {
 
 	places = append(places, filepath.Join(home, ".config/aminal/config.toml"))
 	places = append(places, filepath.Join(home, ".aminal.toml"))
+	return places
+}
+
+func loadConfigFile() *config.Config {
+	places := configPlaces()
+	if len(places) == 0 {
+		return &config.DefaultConfig
+	}
 
 	for _, place := range places {
 		if b, err := ioutil.ReadFile(place); err == nil {
 			if c, err := config.Parse(b); err == nil {
+				c.Path = place
 				return c
 			}
 
-			fmt.Printf("Invalid config at %s: %s\n", place, err)
+			fmt.Printf("Invalid config at %s: %s, run \"aminal config check\" for details\n", place, err)
 		}
 	}
 
-	if b, err := config.DefaultConfig.Encode(); err != nil {
-		fmt.Printf("Failed to encode config file: %s\n", err)
+	err := os.MkdirAll(filepath.Dir(places[0]), 0o744)
+	if err != nil {
+		fmt.Printf("Failed to create config file directory: %s\n", err)
 	} else {
-		err = os.MkdirAll(filepath.Dir(places[0]), 0o744)
-		if err != nil {
-			fmt.Printf("Failed to create config file directory: %s\n", err)
+		if err = ioutil.WriteFile(places[0], config.DefaultFile(), 0o644); err != nil {
+			fmt.Printf("Failed to write config file: %s\n", err)
 		} else {
-			if err = ioutil.WriteFile(places[0], b, 0o644); err != nil {
-				fmt.Printf("Failed to encode config file: %s\n", err)
-			}
+			c := config.DefaultConfig
+			c.Path = places[0]
+			return &c
 		}
 	}
 
@@ -3,10 +3,70 @@ package config
 type UserAction string
 
 const (
-	ActionCopy        UserAction = "copy"
-	ActionPaste       UserAction = "paste"
-	ActionSearch      UserAction = "search"
-	ActionReportBug   UserAction = "report"
-	ActionToggleDebug UserAction = "debug"
-	ActionToggleSlomo UserAction = "slomo"
+	ActionCopy         UserAction = "copy"
+	ActionCopyAs       UserAction = "copy_as"
+	ActionCopyHTML     UserAction = "copy_html"
+	ActionCopyRTF      UserAction = "copy_rtf"
+	ActionCopyShell    UserAction = "copy_shell"
+	ActionPaste        UserAction = "paste"
+	ActionSearch       UserAction = "search"
+	ActionReportBug    UserAction = "report"
+	ActionToggleDebug  UserAction = "debug"
+	ActionToggleSlomo  UserAction = "slomo"
+	ActionFilter       UserAction = "filter"
+	ActionBookmark     UserAction = "bookmark"
+	ActionBookmarks    UserAction = "bookmarks"
+	ActionNextBookmark UserAction = "next_bookmark"
+	ActionPrevBookmark UserAction = "prev_bookmark"
+	ActionOpenWith     UserAction = "open_with"
+	ActionPasteLine    UserAction = "paste_single_line"
+	ActionToggleDiff   UserAction = "diff"
+	ActionDiffBaseline UserAction = "diff_baseline"
+	ActionTimeTravel   UserAction = "time_travel"
+	ActionToggleShare  UserAction = "share"
+	ActionExportSVG    UserAction = "export_svg"
+	ActionExportClip   UserAction = "export_clip"
+	ActionExportHTML   UserAction = "export_html"
+	ActionExportANSI   UserAction = "export_ansi"
+	ActionFontPicker   UserAction = "fonts"
+	ActionThemePicker  UserAction = "themes"
+	ActionSettings     UserAction = "settings"
+	ActionFind         UserAction = "find"
+	ActionLinkHints    UserAction = "link_hints"
+	ActionPrevPrompt   UserAction = "prev_prompt"
+	ActionNextPrompt   UserAction = "next_prompt"
+	ActionCopyOutput   UserAction = "copy_output"
+	ActionRerun        UserAction = "rerun_command"
+	ActionNewTab       UserAction = "new_tab"
+	ActionSSH          UserAction = "ssh"
+	ActionProfiles     UserAction = "profiles"
+	ActionNewWindow    UserAction = "new_window"
+	ActionCloseTab     UserAction = "close_tab"
+	ActionNextTab      UserAction = "next_tab"
+	ActionPrevTab      UserAction = "prev_tab"
+	ActionSplitRight   UserAction = "split_right"
+	ActionSplitDown    UserAction = "split_down"
+	ActionClosePane    UserAction = "close_pane"
+	ActionPaneLeft     UserAction = "pane_left"
+	ActionPaneRight    UserAction = "pane_right"
+	ActionPaneUp       UserAction = "pane_up"
+	ActionPaneDown     UserAction = "pane_down"
+	ActionResizeLeft   UserAction = "resize_pane_left"
+	ActionResizeRight  UserAction = "resize_pane_right"
+	ActionResizeUp     UserAction = "resize_pane_up"
+	ActionResizeDown   UserAction = "resize_pane_down"
+	ActionClearHistory UserAction = "clear_scrollback"
+	ActionClearAll     UserAction = "clear_all"
+	ActionReset        UserAction = "reset"
+	ActionScrollUp     UserAction = "scroll_page_up"
+	ActionScrollDown   UserAction = "scroll_page_down"
+	ActionScrollBottom UserAction = "scroll_to_bottom"
+	ActionFontBigger   UserAction = "font_bigger"
+	ActionFontSmaller  UserAction = "font_smaller"
+	ActionFontReset    UserAction = "font_reset"
+	ActionRecordMacro  UserAction = "record_macro"
+	ActionMacros       UserAction = "macros"
+	ActionMoreOpaque   UserAction = "background_more_opaque"
+	ActionLessOpaque   UserAction = "background_less_opaque"
+	ActionMinimap      UserAction = "toggle_minimap"
 )
@@ -0,0 +1,108 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// BindingConfig binds keys to a chain of steps, run in order. Each step is the name of an action, e.g. "copy",
+// "send <text>" to write the text to the shell as if it was typed, "macro <name>" to replay a recorded macro, or
+// "command <shell command>" to run a command with the selection in $AMINAL_SELECTION. A binding with platforms only applies on those (as Go names them, e.g.
+// "darwin"), and takes precedence over a binding of the same keys without any.
+type BindingConfig struct {
+	Keys      string   `toml:"keys"`
+	Run       []string `toml:"run"`
+	Platforms []string `toml:"platforms"`
+}
+
+// BindingStep is one step of a binding, only one field of which is set
+type BindingStep struct {
+	Action  UserAction
+	Send    string
+	Macro   string
+	Command string
+}
+
+// KeyBinding is a parsed binding, with the steps to run when its keys are pressed
+type KeyBinding struct {
+	Keys  *KeyCombination
+	Steps []BindingStep
+}
+
+// appliesTo returns true if the binding is used on the platform
+func (binding BindingConfig) appliesTo(platform string) bool {
+	if len(binding.Platforms) == 0 {
+		return true
+	}
+	for _, p := range binding.Platforms {
+		if strings.EqualFold(strings.TrimSpace(p), platform) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseBindingStep reads a step, e.g. "paste", "send ls\r" or "command notify-send hello"
+func parseBindingStep(step string) (BindingStep, error) {
+	trimmed := strings.TrimLeft(step, " ")
+	name, rest := trimmed, ""
+	if i := strings.IndexByte(trimmed, ' '); i >= 0 {
+		name, rest = trimmed[:i], trimmed[i+1:]
+	}
+	switch name {
+	case "":
+		return BindingStep{}, fmt.Errorf("Empty step in key binding")
+	case "send":
+		if rest == "" {
+			return BindingStep{}, fmt.Errorf("Nothing to send in key binding step '%s'", step)
+		}
+		return BindingStep{Send: rest}, nil
+	case "macro":
+		if strings.TrimSpace(rest) == "" {
+			return BindingStep{}, fmt.Errorf("No macro name in key binding step '%s'", step)
+		}
+		return BindingStep{Macro: strings.TrimSpace(rest)}, nil
+	case "command":
+		if strings.TrimSpace(rest) == "" {
+			return BindingStep{}, fmt.Errorf("No command in key binding step '%s'", step)
+		}
+		return BindingStep{Command: strings.TrimSpace(rest)}, nil
+	}
+	if rest != "" {
+		return BindingStep{}, fmt.Errorf("Unexpected text after action in key binding step '%s'", step)
+	}
+	return BindingStep{Action: UserAction(name)}, nil
+}
+
+// GenerateBindings parses the bindings which apply on the platform, a runtime.GOOS value. Those made for the
+// platform come first, so they're matched before any of the same keys for every platform.
+func GenerateBindings(bindings []BindingConfig, platform string) ([]KeyBinding, error) {
+	specific := []KeyBinding{}
+	general := []KeyBinding{}
+	for _, b := range bindings {
+		if !b.appliesTo(platform) {
+			continue
+		}
+		combi, err := parseKeyCombination(b.Keys)
+		if err != nil {
+			return nil, err
+		}
+		if len(b.Run) == 0 {
+			return nil, fmt.Errorf("Nothing to run for key binding '%s'", b.Keys)
+		}
+		binding := KeyBinding{Keys: combi}
+		for _, s := range b.Run {
+			step, err := parseBindingStep(s)
+			if err != nil {
+				return nil, err
+			}
+			binding.Steps = append(binding.Steps, step)
+		}
+		if len(b.Platforms) > 0 {
+			specific = append(specific, binding)
+		} else {
+			general = append(general, binding)
+		}
+	}
+	return append(specific, general...), nil
+}
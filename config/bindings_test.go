@@ -0,0 +1,63 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/go-gl/glfw/v3.3/glfw"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBindingSteps(t *testing.T) {
+	bindings, err := GenerateBindings([]BindingConfig{
+		{Keys: "ctrl + alt + l", Run: []string{"copy", "send ls -l\r", "macro deploy", "command notify-send copied"}},
+	}, "linux")
+	require.Nil(t, err)
+	require.Len(t, bindings, 1)
+
+	assert.True(t, bindings[0].Keys.Match(glfw.ModControl|glfw.ModAlt, 'l'))
+	assert.Equal(t, []BindingStep{
+		{Action: ActionCopy},
+		{Send: "ls -l\r"},
+		{Macro: "deploy"},
+		{Command: "notify-send copied"},
+	}, bindings[0].Steps)
+}
+
+func TestPlatformBindingsComeFirst(t *testing.T) {
+	config := []BindingConfig{
+		{Keys: "ctrl + shift + c", Run: []string{"copy"}},
+		{Keys: "super + c", Run: []string{"copy"}, Platforms: []string{"darwin"}},
+		{Keys: "ctrl + shift + c", Run: []string{"send \x03"}, Platforms: []string{"windows", "linux"}},
+	}
+
+	bindings, err := GenerateBindings(config, "linux")
+	require.Nil(t, err)
+	require.Len(t, bindings, 2)
+	assert.Equal(t, "\x03", bindings[0].Steps[0].Send)
+	assert.Equal(t, ActionCopy, bindings[1].Steps[0].Action)
+
+	bindings, err = GenerateBindings(config, "darwin")
+	require.Nil(t, err)
+	require.Len(t, bindings, 2)
+	assert.True(t, bindings[0].Keys.Match(glfw.ModSuper, 'c'))
+}
+
+func TestInvalidBindings(t *testing.T) {
+	invalid := []BindingConfig{
+		{Keys: "c", Run: []string{"copy"}},
+		{Keys: "ctrl + c"},
+		{Keys: "ctrl + c", Run: []string{""}},
+		{Keys: "ctrl + c", Run: []string{"send"}},
+		{Keys: "ctrl + c", Run: []string{"command  "}},
+		{Keys: "ctrl + c", Run: []string{"macro"}},
+		{Keys: "ctrl + c", Run: []string{"copy everything"}},
+	}
+	for _, b := range invalid {
+		_, err := GenerateBindings([]BindingConfig{b}, "linux")
+		assert.NotNil(t, err, "%+v", b)
+	}
+
+	_, err := GenerateBindings([]BindingConfig{{Keys: "c", Platforms: []string{"plan9"}}}, "linux")
+	assert.Nil(t, err, "bindings for other platforms aren't parsed")
+}
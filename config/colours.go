@@ -73,4 +73,28 @@ type ColourScheme struct {
 	LightCyan    Colour `toml:"light_cyan"`
 	White        Colour `toml:"white"`
 	Selection    Colour `toml:"selection"`
+	Diff         Colour `toml:"diff"`
+	Match        Colour `toml:"match"`
+}
+
+// ANSIPalette returns the 16 standard ANSI colours, the 8 normal colours followed by their bright variants
+func (scheme ColourScheme) ANSIPalette() [16][3]float32 {
+	return [16][3]float32{
+		scheme.Black,
+		scheme.Red,
+		scheme.Green,
+		scheme.Yellow,
+		scheme.Blue,
+		scheme.Magenta,
+		scheme.Cyan,
+		scheme.White,
+		scheme.DarkGrey,
+		scheme.LightRed,
+		scheme.LightGreen,
+		scheme.LightYellow,
+		scheme.LightBlue,
+		scheme.LightMagenta,
+		scheme.LightCyan,
+		scheme.White,
+	}
 }
@@ -2,6 +2,7 @@ package config
 
 import (
 	"bytes"
+	"io/ioutil"
 
 	"github.com/BurntSushi/toml"
 )
@@ -10,22 +11,271 @@ type Config struct {
 	DebugMode             bool             `toml:"debug"`
 	Slomo                 bool             `toml:"slomo"`
 	ColourScheme          ColourScheme     `toml:"colours"`
+	Theme                 string           `toml:"theme"`            // named colour scheme used instead of ColourScheme, see the themes package
+	ThemesDirectory       string           `toml:"themes_directory"` // where user theme files are kept
+	BoldIsBright          bool             `toml:"bold_is_bright"`   // show bold text in the first 8 colours in their bright versions
+	Bidi                  bool             `toml:"bidi"`             // lay out Arabic and Hebrew text right to left
 	DPIScale              float32          `toml:"dpi-scale"`
+	PluginsDirectory      string           `toml:"plugins_directory"` // where the Lua plugins are kept, see the plugins package
 	Shell                 string           `toml:"shell"`
+	ShellArgs             []string         `toml:"shell_args"`  // passed to the shell, after -l for a login shell
+	LoginShell            bool             `toml:"login_shell"` // start the shell as a login shell, with -l, except on Windows
+	Command               []string         `toml:"command"`     // a program and its arguments to run in the window instead of the shell
+	Hold                  bool             `toml:"hold"`        // keep the terminal open when its program exits, until a key is pressed
+	Restart               bool             `toml:"restart"`     // start the shell again when it crashes, keeping the scrollback
+	Env                   EnvConfig        `toml:"env"`
+	Term                  string           `toml:"term"` // what TERM is set to for the shell
 	KeyMapping            KeyMappingConfig `toml:"keys"`
+	Bindings              []BindingConfig  `toml:"bindings"` // run before the [keys] shortcuts
+	Macros                MacroConfig      `toml:"macros"`   // recorded keyboard input, by name
 	SearchURL             string           `toml:"search_url"`
 	MaxLines              uint64           `toml:"max_lines"`
+	ScrollbackWarning     uint64           `toml:"scrollback_warning_mb"` // 0 to disable
+	ScrollbackSpill       bool             `toml:"scrollback_spill"`
+	ScrollbackSpillDir    string           `toml:"scrollback_spill_directory"`
 	CopyAndPasteWithMouse bool             `toml:"copy_and_paste_with_mouse"`
+	MeasureLatency        bool             `toml:"measure_latency"`
+	ShaderDirectory       string           `toml:"shader_directory"`
+	WrapIndicators        bool             `toml:"wrap_indicators"`
+	Scrollbar             bool             `toml:"scrollbar"` // shown at the right edge of the focused pane while scrolling
+	Scroll                ScrollConfig     `toml:"scroll"`
+	Minimap               bool             `toml:"minimap"` // an overview of the scrollback at the right of the window
+	ExportDirectory       string           `toml:"export_directory"`
+	Links                 []LinkRule       `toml:"links"`
+	Triggers              []Trigger        `toml:"triggers"`
+	LinkModifier          string           `toml:"link_modifier"` // modifiers to hold to underline and click links, e.g. "ctrl"
+	Editor                string           `toml:"editor"`        // shell command opening $AMINAL_FILE at $AMINAL_LINE and $AMINAL_COLUMN
+	Font                  FontConfig       `toml:"font"`
+	Clipboard             ClipboardConfig  `toml:"clipboard"`
+	OpenWith              []OpenWithTarget `toml:"open_with"`
+	SSH                   []SSHProfile     `toml:"ssh"`
+	Profiles              []Profile        `toml:"profiles"` // picked with --profile, from the profiles menu, or by the command a tab runs
+	Hosts                 []HostTheme      `toml:"hosts"`    // colours for shells reporting they're on other hosts, picked by the first pattern matching
+	Paste                 PasteConfig      `toml:"paste"`
+	Drop                  DropConfig       `toml:"drop"`
+	Share                 ShareConfig      `toml:"share"`
+	Daemon                DaemonConfig     `toml:"daemon"`
+	Control               ControlConfig    `toml:"control"`
+	Serial                SerialConfig     `toml:"serial"`
+	Clip                  ClipConfig       `toml:"clip"`
+	Cursor                CursorConfig     `toml:"cursor"`
+	Selection             SelectionConfig  `toml:"selection"`
+	Bell                  BellConfig       `toml:"bell"`
+	Notify                NotifyConfig     `toml:"notify"`
+	Opacity               float32          `toml:"opacity"`            // of the whole window, from 0 to 1
+	BackgroundOpacity     float32          `toml:"background_opacity"` // of the default background only, leaving text and coloured backgrounds opaque
+	Blur                  bool             `toml:"blur"`               // blur what shows through the background, where the compositor supports it
+	Window                WindowConfig     `toml:"window"`
+
+	Path    string `toml:"-"` // where the config was loaded from, if anywhere
+	View    string `toml:"-"` // the link to a session shared by another Aminal to watch instead of starting a shell
+	Connect string `toml:"-"` // a serial port, tcp:host:port or exec:command to run the terminal on instead of a shell
+	Profile string `toml:"-"` // the name of the profile the first tab is started with, if any
+}
+
+// FontConfig selects the fonts to render with. Empty paths use the bundled Hack font.
+type FontConfig struct {
+	Regular       string   `toml:"regular"`
+	Bold          string   `toml:"bold"`
+	Italic        string   `toml:"italic"`      // defaults to the regular font
+	BoldItalic    string   `toml:"bold_italic"` // defaults to the bold font
+	Size          float32  `toml:"size"`
+	LineHeight    float32  `toml:"line_height"`    // multiplies the height of each line, where 0 is the same as 1
+	LetterSpacing float32  `toml:"letter_spacing"` // pixels added to the width of each cell, or taken away if negative
+	Padding       float32  `toml:"padding"`        // pixels of space between the grid and the edges of the window
+	RememberSize  bool     `toml:"remember_size"`  // save the size chosen with font_bigger and font_smaller to the config file
+	Fallback      []string `toml:"fallback"`       // fonts to take characters missing from the others from, in order
+}
+
+// Equal returns true if both configs select the same fonts, laid out the same way
+func (c FontConfig) Equal(other FontConfig) bool {
+	if c.Regular != other.Regular || c.Bold != other.Bold || c.Italic != other.Italic || c.BoldItalic != other.BoldItalic {
+		return false
+	}
+	if c.Size != other.Size || c.LineHeight != other.LineHeight || c.LetterSpacing != other.LetterSpacing || c.Padding != other.Padding {
+		return false
+	}
+	if len(c.Fallback) != len(other.Fallback) {
+		return false
+	}
+	for i := range c.Fallback {
+		if c.Fallback[i] != other.Fallback[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// link actions, run when a link is clicked
+const (
+	LinkOpen    = "open"    // launch the URL
+	LinkCopy    = "copy"    // copy the URL to the clipboard
+	LinkCommand = "command" // pipe the URL to a shell command
+)
+
+// LinkRule turns text matching Pattern into a clickable link to URL, where URL may reference the match with $0, $1 etc.
+// and defaults to the whole match. Clicking runs the action on the URL.
+type LinkRule struct {
+	Pattern string `toml:"pattern"`
+	URL     string `toml:"url"`
+	Action  string `toml:"action"`  // one of the Link actions, defaulting to command if a command is given and open otherwise
+	Command string `toml:"command"` // receives the URL on stdin and in the AMINAL_SELECTION environment variable
+}
+
+// LinkAction returns the action to run when the link is clicked
+func (r LinkRule) LinkAction() string {
+	switch {
+	case r.Action != "":
+		return r.Action
+	case r.Command != "":
+		return LinkCommand
+	default:
+		return LinkOpen
+	}
+}
+
+// trigger actions, run when a line of output matches
+const (
+	TriggerHighlight = "highlight" // colour the match's background
+	TriggerNotify    = "notify"    // show a desktop notification of the line
+	TriggerSound     = "sound"     // play the system's alert sound
+	TriggerLog       = "log"       // append the line to a file
+	TriggerCommand   = "command"   // run a shell command
+)
+
+// Trigger runs an action when a line of output on the main screen matches Pattern, like iTerm2's triggers
+type Trigger struct {
+	Pattern string `toml:"pattern"`
+	Action  string `toml:"action"`  // one of the Trigger actions
+	Colour  string `toml:"colour"`  // the background highlight gives the match, yellow by default
+	File    string `toml:"file"`    // which log appends the line to, with the time
+	Command string `toml:"command"` // run with the match in $AMINAL_MATCH, its groups in $AMINAL_MATCH_1 on, and the line in $AMINAL_LINE
+}
+
+// HighlightColour returns the background colour the highlight action gives the match
+func (t Trigger) HighlightColour() (Colour, error) {
+	if t.Colour == "" {
+		return Colour{0.9, 0.75, 0.2}, nil
+	}
+	return strToColour(t.Colour)
+}
+
+// OpenWithTarget is somewhere the selection can be sent, either a URL containing $QUERY, or a shell
+// command which receives the selection on stdin
+type OpenWithTarget struct {
+	Name    string `toml:"name"`
+	URL     string `toml:"url"`
+	Command string `toml:"command"`
+}
+
+// ShareConfig controls read-only sharing of the session with viewers in a browser
+type ShareConfig struct {
+	Listen string `toml:"listen"` // address to serve on, use 0.0.0.0:<port> to allow viewers from other machines
+}
+
+// DaemonConfig controls keeping the shells in a headless daemon, which windows attach to, so that they outlive the window
+type DaemonConfig struct {
+	Attach bool   `toml:"attach"` // start shells in the daemon, starting it if need be, and attach to its detached sessions on opening
+	Socket string `toml:"socket"` // where the daemon listens, in $XDG_RUNTIME_DIR or a private directory in the temporary directory by default
+}
+
+// ControlConfig sets up the socket other programs can drive the window over, see the control package
+type ControlConfig struct {
+	Socket string `toml:"socket"` // where to listen for commands, or nowhere if empty
+}
+
+// SerialConfig sets up serial ports connected to with --connect
+type SerialConfig struct {
+	Baud  int    `toml:"baud"`
+	Frame string `toml:"frame"` // data bits, parity and stop bits, e.g. 8N1 or 7E1
+}
+
+// ClipConfig controls the recording of recent output, which can be exported as an animation
+type ClipConfig struct {
+	Seconds int     `toml:"seconds"` // how much to keep, 0 to disable recording
+	FPS     int     `toml:"fps"`
+	Scale   float64 `toml:"scale"`
+	Format  string  `toml:"format"` // gif or apng
+}
+
+// ScrollConfig controls how far and which way the mouse wheel scrolls
+type ScrollConfig struct {
+	Lines           float64 `toml:"lines"`            // scrolled by each click of the wheel
+	ShiftMultiplier float64 `toml:"shift_multiplier"` // how many times further the wheel scrolls with shift held
+	Natural         bool    `toml:"natural"`          // scroll the other way, moving the content with the fingers on a touchpad
+}
+
+type CursorConfig struct {
+	Blink              bool `toml:"blink"`                // blink even when the application hasn't asked for a blinking cursor
+	BlinkInterval      int  `toml:"blink_interval"`       // how long the cursor is shown then hidden for, in milliseconds. 0 never blinks.
+	SolidWhenUnfocused bool `toml:"solid_when_unfocused"` // keep the block cursor when the window loses focus, instead of an outline
+}
+
+// BellConfig chooses what happens when an application rings the bell, any number of which can be turned on
+type BellConfig struct {
+	Flash     bool   `toml:"flash"`     // invert the screen briefly
+	Sound     bool   `toml:"sound"`     // play the system's alert sound
+	Command   string `toml:"command"`   // shell command to run, e.g. to play a sound file
+	Urgent    bool   `toml:"urgent"`    // mark the tab the bell rang in, when it isn't the one shown
+	Attention bool   `toml:"attention"` // ask for attention, e.g. by highlighting the window in the taskbar, when it isn't focused
+}
+
+// DropConfig controls what happens when files are dragged onto the window, which normally types their paths
+type DropConfig struct {
+	ChangeDirectory bool `toml:"cd"` // cd to a directory dropped on its own, rather than typing its path
+}
+
+// NotifyConfig chooses what desktop notifications are shown for, which they only are while the window isn't focused
+type NotifyConfig struct {
+	CommandFinished bool   `toml:"command_finished"` // when a command marked by shell integration finishes, with its exit status
+	Command         string `toml:"command"`          // shell command showing $AMINAL_NOTIFY_TITLE and $AMINAL_NOTIFY_BODY, instead of the system's notifications
+}
+
+// SelectionConfig controls where text selected with the mouse goes
+type SelectionConfig struct {
+	CopyOnSelect bool   `toml:"copy_on_select"` // copy to the clipboard as soon as text is selected, also done with CopyAndPasteWithMouse
+	Primary      bool   `toml:"primary"`        // keep the selection in the primary selection, which the middle button pastes
+	JoinWrapped  bool   `toml:"join_wrapped"`   // copy lines the terminal wrapped as one line, rather than as they're shown
+	WordChars    string `toml:"word_chars"`     // characters other than letters and numbers which a double click selects as part of a word
+}
+
+// clipboard access policies
+const (
+	ClipboardAllow = "allow"
+	ClipboardAsk   = "ask" // ask once per session
+	ClipboardDeny  = "deny"
+)
+
+// ClipboardConfig controls access to the clipboard by programs running in the terminal (OSC 52)
+type ClipboardConfig struct {
+	Read     string `toml:"read"`
+	Write    string `toml:"write"`
+	MaxWrite int    `toml:"max_write"` // in bytes, larger writes are refused. 0 for no limit.
 }
 
 type KeyMappingConfig map[string]string
 
+// EnvConfig is the environment variables to set for the shell, by name. $VARIABLES in the values are expanded
+// from Aminal's own environment, e.g. PATH = "$HOME/bin:$PATH".
+type EnvConfig map[string]string
+
+// MacroConfig is the input to type for each macro, by name
+type MacroConfig map[string]string
+
 func Parse(data []byte) (*Config, error) {
 	c := DefaultConfig
+	// copied, so the shortcuts in the file are added to the defaults without changing them
+	c.KeyMapping = KeyMappingConfig(map[string]string{})
+	for action, keys := range DefaultConfig.KeyMapping {
+		c.KeyMapping[action] = keys
+	}
 	err := toml.Unmarshal(data, &c)
 	if c.KeyMapping == nil {
 		c.KeyMapping = KeyMappingConfig(map[string]string{})
 	}
+	if err == nil {
+		err = c.compileProfiles()
+	}
 	return &c, err
 }
 
@@ -38,3 +288,30 @@ func (c *Config) Encode() ([]byte, error) {
 	}
 	return buf.Bytes(), nil
 }
+
+// UpdateFile applies update to the config file at path and writes back the settings it changed, in place, so the
+// rest of the file, comments and all, is left as it was, along with settings which were only changed in memory
+// (e.g. by command line flags)
+func UpdateFile(path string, update func(c *Config)) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	c, err := Parse(data)
+	if err != nil {
+		return err
+	}
+	before, err := c.values()
+	if err != nil {
+		return err
+	}
+	update(c)
+	after, err := c.values()
+	if err != nil {
+		return err
+	}
+	if data, err = editValues(data, nil, before, after); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0o644)
+}
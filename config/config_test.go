@@ -0,0 +1,135 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "aminal")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "config.toml")
+	require.Nil(t, ioutil.WriteFile(path, []byte("shell = \"/bin/zsh\"\n[font]\nsize = 12.0\n"), 0o644))
+
+	require.Nil(t, UpdateFile(path, func(c *Config) {
+		c.Font.Regular = "/fonts/mono.ttf"
+	}))
+
+	data, err := ioutil.ReadFile(path)
+	require.Nil(t, err)
+	c, err := Parse(data)
+	require.Nil(t, err)
+	assert.Equal(t, "/bin/zsh", c.Shell)
+	assert.Equal(t, FontConfig{Regular: "/fonts/mono.ttf", Size: 12}, c.Font)
+}
+
+func TestFontConfigEqual(t *testing.T) {
+	c, err := Parse([]byte("[font]\nsize = 12.0\nfallback = [\"/fonts/emoji.ttf\", \"/fonts/cjk.ttf\"]\n"))
+	require.Nil(t, err)
+	assert.Equal(t, []string{"/fonts/emoji.ttf", "/fonts/cjk.ttf"}, c.Font.Fallback)
+
+	same := FontConfig{Size: 12, Fallback: []string{"/fonts/emoji.ttf", "/fonts/cjk.ttf"}}
+	assert.True(t, c.Font.Equal(same))
+
+	reordered := FontConfig{Size: 12, Fallback: []string{"/fonts/cjk.ttf", "/fonts/emoji.ttf"}}
+	assert.False(t, c.Font.Equal(reordered))
+	assert.False(t, c.Font.Equal(FontConfig{Size: 12}))
+}
+
+func TestFontStylesAndSpacing(t *testing.T) {
+	c, err := Parse([]byte("[font]\nitalic = \"/fonts/italic.ttf\"\nbold_italic = \"/fonts/bold-italic.ttf\"\nline_height = 1.2\nletter_spacing = -0.5\npadding = 4.0\n"))
+	require.Nil(t, err)
+	assert.Equal(t, "/fonts/italic.ttf", c.Font.Italic)
+	assert.Equal(t, "/fonts/bold-italic.ttf", c.Font.BoldItalic)
+	assert.Equal(t, float32(1.2), c.Font.LineHeight)
+	assert.Equal(t, float32(-0.5), c.Font.LetterSpacing)
+	assert.Equal(t, float32(4), c.Font.Padding)
+
+	spaced := c.Font
+	spaced.LetterSpacing = 1
+	assert.False(t, c.Font.Equal(spaced))
+	assert.True(t, c.Font.Equal(c.Font))
+}
+
+func TestTermDefaultsToXterm(t *testing.T) {
+	c, err := Parse([]byte("shell = \"/bin/zsh\"\n"))
+	require.Nil(t, err)
+	assert.Equal(t, "xterm-256color", c.Term)
+
+	c, err = Parse([]byte("term = \"aminal\"\n"))
+	require.Nil(t, err)
+	assert.Equal(t, "aminal", c.Term)
+}
+
+func TestOpacityDefaultsToOpaque(t *testing.T) {
+	c, err := Parse([]byte("shell = \"/bin/zsh\"\n"))
+	require.Nil(t, err)
+	assert.Equal(t, float32(1), c.Opacity)
+
+	c, err = Parse([]byte("opacity = 0.8\n"))
+	require.Nil(t, err)
+	assert.Equal(t, float32(0.8), c.Opacity)
+}
+
+func TestCursorConfig(t *testing.T) {
+	c, err := Parse([]byte("[cursor]\n  blink = true\n"))
+	require.Nil(t, err)
+	assert.True(t, c.Cursor.Blink)
+	assert.Equal(t, 600, c.Cursor.BlinkInterval, "the interval keeps its default when it isn't given")
+	assert.False(t, c.Cursor.SolidWhenUnfocused)
+}
+
+func TestSelectionConfig(t *testing.T) {
+	c, err := Parse([]byte("[selection]\n  copy_on_select = true\n"))
+	require.Nil(t, err)
+	assert.True(t, c.Selection.CopyOnSelect)
+	assert.True(t, c.Selection.Primary, "the primary selection stays on when it isn't given")
+	assert.True(t, c.Selection.JoinWrapped)
+	assert.Equal(t, DefaultConfig.Selection.WordChars, c.Selection.WordChars)
+
+	c, err = Parse([]byte("[selection]\n  join_wrapped = false\n  word_chars = \"-_\"\n"))
+	require.Nil(t, err)
+	assert.False(t, c.Selection.JoinWrapped)
+	assert.Equal(t, "-_", c.Selection.WordChars)
+}
+
+func TestLinkAction(t *testing.T) {
+	assert.Equal(t, LinkOpen, LinkRule{URL: "https://example.com/$0"}.LinkAction())
+	assert.Equal(t, LinkCommand, LinkRule{Command: "code -g \"$AMINAL_SELECTION\""}.LinkAction())
+	assert.Equal(t, LinkCopy, LinkRule{Action: LinkCopy, Command: "unused"}.LinkAction())
+}
+
+func TestTriggers(t *testing.T) {
+	c, err := Parse([]byte("[[triggers]]\n  pattern = \"error: (.*)\"\n  action = \"highlight\"\n  colour = \"#ff0000\"\n"))
+	require.Nil(t, err)
+	require.Len(t, c.Triggers, 1)
+	colour, err := c.Triggers[0].HighlightColour()
+	require.Nil(t, err)
+	assert.Equal(t, Colour{1, 0, 0}, colour)
+
+	colour, err = Trigger{Action: TriggerHighlight}.HighlightColour()
+	require.Nil(t, err)
+	assert.Equal(t, Colour{0.9, 0.75, 0.2}, colour, "yellow when no colour is given")
+
+	_, err = Trigger{Colour: "red"}.HighlightColour()
+	assert.NotNil(t, err)
+}
+
+func TestMacros(t *testing.T) {
+	c, err := Parse([]byte("[macros]\n  status = \"git status\\r\"\n  quit = \"\\u001b:q\\r\"\n"))
+	require.Nil(t, err)
+	assert.Equal(t, MacroConfig{"status": "git status\r", "quit": "\x1b:q\r"}, c.Macros)
+
+	data, err := c.Encode()
+	require.Nil(t, err)
+	c, err = Parse(data)
+	require.Nil(t, err)
+	assert.Equal(t, "\x1b:q\r", c.Macros["quit"], "control characters survive the config being saved")
+}
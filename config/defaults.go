@@ -1,6 +1,10 @@
 package config
 
-import "runtime"
+import (
+	"runtime"
+
+	"github.com/liamg/aminal/buffer"
+)
 
 var DefaultConfig = Config{
 	DebugMode: false,
@@ -25,20 +29,126 @@ var DefaultConfig = Config{
 		LightCyan:    strToColourNoErr("#00ffff"),
 		White:        strToColourNoErr("#ffffff"),
 		Selection:    strToColourNoErr("#333366"),
+		Diff:         strToColourNoErr("#4d3d00"),
+		Match:        strToColourNoErr("#805500"),
 	},
 	KeyMapping:            KeyMappingConfig(map[string]string{}),
+	Term:                  "xterm-256color",
 	SearchURL:             "https://www.google.com/search?q=$QUERY",
 	MaxLines:              1000,
+	ScrollbackWarning:     256,
 	CopyAndPasteWithMouse: true,
+	WrapIndicators:        true,
+	Scrollbar:             true,
+	Opacity:               1,
+	BackgroundOpacity:     1,
+	Font: FontConfig{
+		Size: 10,
+	},
+	Share: ShareConfig{
+		Listen: "localhost:7681",
+	},
+	Serial: SerialConfig{
+		Baud:  115200,
+		Frame: "8N1",
+	},
+	Clip: ClipConfig{
+		Seconds: 10,
+		FPS:     10,
+		Scale:   1,
+		Format:  "gif",
+	},
+	Paste: PasteConfig{
+		ConvertCRLF: true,
+	},
+	Cursor: CursorConfig{
+		BlinkInterval: 600,
+	},
+	Bell: BellConfig{
+		Urgent:    true,
+		Attention: true,
+	},
+	Scroll: ScrollConfig{
+		Lines:           3,
+		ShiftMultiplier: 5,
+	},
+	Notify: NotifyConfig{
+		CommandFinished: true,
+	},
+	Window: WindowConfig{
+		Width:  800,
+		Height: 600,
+		State:  WindowNormal,
+	},
+	Selection: SelectionConfig{
+		Primary:     true,
+		JoinWrapped: true,
+		WordChars:   buffer.DefaultWordChars,
+	},
+	Clipboard: ClipboardConfig{
+		Read:     ClipboardAsk,
+		Write:    ClipboardAllow,
+		MaxWrite: 256 * 1024,
+	},
 }
 
 func init() {
 	DefaultConfig.KeyMapping[string(ActionCopy)] = addMod("c")
+	DefaultConfig.KeyMapping[string(ActionCopyAs)] = addMod("alt + c")
 	DefaultConfig.KeyMapping[string(ActionPaste)] = addMod("v")
 	DefaultConfig.KeyMapping[string(ActionSearch)] = addMod("g")
 	DefaultConfig.KeyMapping[string(ActionToggleDebug)] = addMod("d")
 	DefaultConfig.KeyMapping[string(ActionToggleSlomo)] = addMod(";")
 	DefaultConfig.KeyMapping[string(ActionReportBug)] = addMod("r")
+	DefaultConfig.KeyMapping[string(ActionFilter)] = addMod("l")
+	DefaultConfig.KeyMapping[string(ActionBookmark)] = addMod("b")
+	DefaultConfig.KeyMapping[string(ActionBookmarks)] = addMod("m")
+	DefaultConfig.KeyMapping[string(ActionNextBookmark)] = addMod("]")
+	DefaultConfig.KeyMapping[string(ActionPrevBookmark)] = addMod("[")
+	DefaultConfig.KeyMapping[string(ActionOpenWith)] = addMod("o")
+	DefaultConfig.KeyMapping[string(ActionPasteLine)] = addMod("p")
+	DefaultConfig.KeyMapping[string(ActionToggleDiff)] = addMod("x")
+	DefaultConfig.KeyMapping[string(ActionDiffBaseline)] = addMod("z")
+	DefaultConfig.KeyMapping[string(ActionTimeTravel)] = addMod("h")
+	DefaultConfig.KeyMapping[string(ActionToggleShare)] = addMod("s")
+	DefaultConfig.KeyMapping[string(ActionExportSVG)] = addMod("e")
+	DefaultConfig.KeyMapping[string(ActionExportClip)] = addMod("a")
+	DefaultConfig.KeyMapping[string(ActionExportHTML)] = addMod("alt + e")
+	DefaultConfig.KeyMapping[string(ActionFontPicker)] = addMod("u")
+	DefaultConfig.KeyMapping[string(ActionThemePicker)] = addMod("y")
+	DefaultConfig.KeyMapping[string(ActionSettings)] = addMod(",")
+	DefaultConfig.KeyMapping[string(ActionFind)] = addMod("f")
+	DefaultConfig.KeyMapping[string(ActionLinkHints)] = addMod("j")
+	DefaultConfig.KeyMapping[string(ActionPrevPrompt)] = addMod("pageup")
+	DefaultConfig.KeyMapping[string(ActionNextPrompt)] = addMod("pagedown")
+	DefaultConfig.KeyMapping[string(ActionCopyOutput)] = addMod("i")
+	DefaultConfig.KeyMapping[string(ActionNewTab)] = addMod("t")
+	DefaultConfig.KeyMapping[string(ActionSSH)] = addMod("alt + t")
+	DefaultConfig.KeyMapping[string(ActionProfiles)] = addMod("alt + p")
+	DefaultConfig.KeyMapping[string(ActionCloseTab)] = addMod("w")
+	DefaultConfig.KeyMapping[string(ActionNextTab)] = "ctrl + tab" // super + tab switches apps on a Mac
+	DefaultConfig.KeyMapping[string(ActionPrevTab)] = "ctrl + shift + tab"
+	DefaultConfig.KeyMapping[string(ActionSplitRight)] = addMod("\\")
+	DefaultConfig.KeyMapping[string(ActionSplitDown)] = addMod("-")
+	DefaultConfig.KeyMapping[string(ActionClosePane)] = addMod("k")
+	DefaultConfig.KeyMapping[string(ActionPaneLeft)] = addMod("left")
+	DefaultConfig.KeyMapping[string(ActionPaneRight)] = addMod("right")
+	DefaultConfig.KeyMapping[string(ActionPaneUp)] = addMod("up")
+	DefaultConfig.KeyMapping[string(ActionPaneDown)] = addMod("down")
+	DefaultConfig.KeyMapping[string(ActionResizeLeft)] = addMod("alt + left")
+	DefaultConfig.KeyMapping[string(ActionResizeRight)] = addMod("alt + right")
+	DefaultConfig.KeyMapping[string(ActionResizeUp)] = addMod("alt + up")
+	DefaultConfig.KeyMapping[string(ActionResizeDown)] = addMod("alt + down")
+	DefaultConfig.KeyMapping[string(ActionClearHistory)] = addMod("alt + backspace")
+	DefaultConfig.KeyMapping[string(ActionClearAll)] = addMod("backspace")
+	DefaultConfig.KeyMapping[string(ActionReset)] = addMod("delete")
+	DefaultConfig.KeyMapping[string(ActionRecordMacro)] = addMod("n")
+	DefaultConfig.KeyMapping[string(ActionMacros)] = addMod("alt + n")
+	DefaultConfig.KeyMapping[string(ActionFontBigger)] = "ctrl + ="
+	DefaultConfig.KeyMapping[string(ActionFontSmaller)] = "ctrl + -"
+	DefaultConfig.KeyMapping[string(ActionFontReset)] = "ctrl + 0"
+	DefaultConfig.KeyMapping[string(ActionMoreOpaque)] = addMod("alt + =")
+	DefaultConfig.KeyMapping[string(ActionLessOpaque)] = addMod("alt + -")
 }
 
 func addMod(keys string) string {
@@ -0,0 +1,296 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// bareKeyPattern matches keys which can be written in a config file without quotes
+var bareKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// values returns the settings as the toml package writes them, as tables of values by name
+func (c *Config) values() (map[string]interface{}, error) {
+	encoded, err := c.Encode()
+	if err != nil {
+		return nil, err
+	}
+	var values map[string]interface{}
+	_, err = toml.Decode(string(encoded), &values)
+	return values, err
+}
+
+// editValues changes the settings in the config file data which differ between before and after, the values of
+// the table with the given key, in place. Settings which are the same are left as they are, along with comments
+// and the layout of the file.
+func editValues(data []byte, table []string, before map[string]interface{}, after map[string]interface{}) ([]byte, error) {
+	names := []string{}
+	for name := range before {
+		names = append(names, name)
+	}
+	for name := range after {
+		if _, ok := before[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		old, had := before[name]
+		value, has := after[name]
+		if reflect.DeepEqual(old, value) {
+			continue
+		}
+		key := append(append([]string{}, table...), name)
+		oldTable, oldIsTable := old.(map[string]interface{})
+		newTable, newIsTable := value.(map[string]interface{})
+		_, oldIsArray := old.([]map[string]interface{})
+		_, newIsArray := value.([]map[string]interface{})
+		switch {
+		case oldIsArray || newIsArray || (had && has && oldIsTable != newIsTable):
+			return nil, fmt.Errorf("Can't change '%s' in place", strings.Join(key, "."))
+		case oldIsTable || newIsTable:
+			var err error
+			if data, err = editValues(data, key, oldTable, newTable); err != nil {
+				return nil, err
+			}
+			continue
+		case !has:
+			data = removeKey(data, table, name)
+			continue
+		}
+		formatted, err := formatValue(value)
+		if err != nil {
+			return nil, err
+		}
+		data = setKey(data, table, name, formatted)
+	}
+	return data, nil
+}
+
+// keySpan is where a key is set in the lines of a config file
+type keySpan struct {
+	header int // the line the table starts on, -1 for the top level, or if the table isn't in the file
+	last   int // the line after the last key in the table, or -1 if it has none
+	start  int // the first line the key is set on, or -1 if it isn't in the file
+	end    int // the line after the last line of its value
+}
+
+// findKey finds where name is set in the table, and where the table is
+func findKey(lines []string, table []string, name string) keySpan {
+	want := strings.Join(table, ".")
+	span := keySpan{header: -1, last: -1, start: -1}
+	current := ""
+	for i := 0; i < len(lines); i++ {
+		text := strings.TrimSpace(lines[i])
+		switch {
+		case strings.HasPrefix(text, "[["):
+			current = "[[" // keys in arrays of tables are never edited
+		case strings.HasPrefix(text, "["):
+			current = tableName(text)
+			if current == want {
+				span.header = i
+			}
+		case text == "" || strings.HasPrefix(text, "#"):
+		default:
+			key, ok := parseKey(text)
+			end := valueEnd(lines, i)
+			if ok && current == want {
+				if key == name {
+					span.start, span.end = i, end
+				}
+				span.last = end
+			}
+			i = end - 1
+		}
+	}
+	return span
+}
+
+// setKey sets name in the table to the formatted value, replacing the value it has in the file, or adding it
+// to the table, which is added to the end of the file if it isn't there
+func setKey(data []byte, table []string, name string, value string) []byte {
+	lines := strings.Split(string(data), "\n")
+	span := findKey(lines, table, name)
+	line := formatKey(name) + " = " + value
+
+	switch {
+	case span.start >= 0:
+		old := lines[span.start]
+		indent := old[:len(old)-len(strings.TrimLeft(old, " \t"))]
+		if span.end == span.start+1 {
+			if comment := commentStart(old); comment >= 0 {
+				line += " " + old[comment:]
+			}
+		}
+		lines = splice(lines, span.start, span.end, indent+line)
+	case span.last >= 0:
+		lines = splice(lines, span.last, span.last, line)
+	case span.header >= 0:
+		lines = splice(lines, span.header+1, span.header+1, line)
+	case len(table) == 0:
+		// before the first table, and any comments about it, or at the end if there are no tables
+		at := len(lines)
+		if at > 0 && lines[at-1] == "" {
+			at--
+		}
+		for i, l := range lines {
+			if strings.HasPrefix(strings.TrimSpace(l), "[") {
+				at = i
+				for at > 0 && strings.HasPrefix(strings.TrimSpace(lines[at-1]), "#") {
+					at--
+				}
+				break
+			}
+		}
+		lines = splice(lines, at, at, line)
+	default:
+		for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+			lines = lines[:len(lines)-1]
+		}
+		header := make([]string, len(table))
+		for i, t := range table {
+			header[i] = formatKey(t)
+		}
+		if len(lines) > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, "["+strings.Join(header, ".")+"]", line, "")
+	}
+	return []byte(strings.Join(lines, "\n"))
+}
+
+// removeKey removes the line setting name in the table, if there is one
+func removeKey(data []byte, table []string, name string) []byte {
+	lines := strings.Split(string(data), "\n")
+	span := findKey(lines, table, name)
+	if span.start < 0 {
+		return data
+	}
+	return []byte(strings.Join(splice(lines, span.start, span.end, ""), "\n"))
+}
+
+// splice replaces lines start to end with line, or removes them if line is empty
+func splice(lines []string, start int, end int, line string) []string {
+	spliced := append([]string{}, lines[:start]...)
+	if line != "" {
+		spliced = append(spliced, line)
+	}
+	return append(spliced, lines[end:]...)
+}
+
+// tableName returns the name of the table a [table] line starts, with the parts of its name joined by dots
+func tableName(text string) string {
+	inside := strings.SplitN(strings.TrimPrefix(text, "["), "]", 2)[0]
+	parts := strings.Split(inside, ".")
+	for i, part := range parts {
+		parts[i] = strings.Trim(strings.TrimSpace(part), `"'`)
+	}
+	return strings.Join(parts, ".")
+}
+
+// parseKey returns the key a key = value line sets, unquoted
+func parseKey(text string) (string, bool) {
+	if text[0] != '"' && text[0] != '\'' {
+		parts := strings.SplitN(text, "=", 2)
+		return strings.TrimSpace(parts[0]), len(parts) == 2
+	}
+	for i := 1; i < len(text); i++ {
+		switch {
+		case text[i] == '\\' && text[0] == '"':
+			i++
+		case text[i] == text[0] && text[0] == '\'':
+			return text[1:i], true
+		case text[i] == text[0]:
+			key, err := strconv.Unquote(text[:i+1])
+			return key, err == nil
+		}
+	}
+	return "", false
+}
+
+// valueEnd returns the line after the last one of the value set on line start, which carries on over several
+// lines if it's an array or a multi-line string
+func valueEnd(lines []string, start int) int {
+	depth := 0
+	quote := ""
+	for i := start; i < len(lines); i++ {
+		line := lines[i]
+		for j := 0; j < len(line); j++ {
+			c := line[j]
+			if quote != "" {
+				if c == '\\' && quote[0] == '"' {
+					j++
+				} else if strings.HasPrefix(line[j:], quote) {
+					j += len(quote) - 1
+					quote = ""
+				}
+				continue
+			}
+			switch {
+			case c == '#':
+				j = len(line)
+			case strings.HasPrefix(line[j:], `"""`) || strings.HasPrefix(line[j:], "'''"):
+				quote = line[j : j+3]
+				j += 2
+			case c == '"' || c == '\'':
+				quote = string(c)
+			case c == '[' || c == '{':
+				depth++
+			case c == ']' || c == '}':
+				depth--
+			}
+		}
+		if len(quote) == 1 {
+			quote = "" // single line strings can't go on to the next line
+		}
+		if depth <= 0 && quote == "" {
+			return i + 1
+		}
+	}
+	return len(lines)
+}
+
+// commentStart returns where the comment at the end of a line starts, or -1 if there isn't one
+func commentStart(line string) int {
+	quote := byte(0)
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0 && c == '\\' && quote == '"':
+			i++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#':
+			return i
+		}
+	}
+	return -1
+}
+
+// formatKey writes a key the way it has to be written in a config file
+func formatKey(name string) string {
+	if bareKeyPattern.MatchString(name) {
+		return name
+	}
+	return strconv.Quote(name)
+}
+
+// formatValue writes a value the way the toml package does
+func formatValue(value interface{}) (string, error) {
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(map[string]interface{}{"v": value}); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(strings.TrimPrefix(buf.String(), "v = ")), nil
+}
@@ -0,0 +1,109 @@
+package config
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// updated writes data to a config file, updates it and returns what's in it afterwards
+func updated(t *testing.T, data string, update func(c *Config)) string {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	require.Nil(t, ioutil.WriteFile(path, []byte(data), 0o644))
+	require.Nil(t, UpdateFile(path, update))
+	written, err := ioutil.ReadFile(path)
+	require.Nil(t, err)
+	return string(written)
+}
+
+func TestUpdateFileEditsOnlyChangedSettings(t *testing.T) {
+	file := `# my config
+shell = "/bin/zsh" # the shell
+
+# fonts
+[font]
+size = 12.0 # big enough
+fallback = [
+  "/fonts/emoji.ttf",
+]
+`
+	assert.Equal(t, `# my config
+shell = "/bin/zsh" # the shell
+
+# fonts
+[font]
+size = 14.5 # big enough
+fallback = ["/fonts/cjk.ttf"]
+`, updated(t, file, func(c *Config) {
+		c.Font.Size = 14.5
+		c.Font.Fallback = []string{"/fonts/cjk.ttf"}
+	}))
+}
+
+func TestUpdateFileAddsSettings(t *testing.T) {
+	file := `# my config
+shell = "/bin/zsh"
+
+# fonts
+[font]
+size = 12.0
+`
+	assert.Equal(t, `# my config
+shell = "/bin/zsh"
+theme = "solarized"
+
+# fonts
+[font]
+size = 12.0
+regular = "/fonts/mono.ttf"
+
+[macros]
+"say hi" = "echo hi\n"
+`, updated(t, file, func(c *Config) {
+		c.Theme = "solarized"
+		c.Font.Regular = "/fonts/mono.ttf"
+		c.Macros = MacroConfig{"say hi": "echo hi\n"}
+	}))
+
+	assert.Equal(t, "# nothing yet\ntheme = \"solarized\"\n", updated(t, "# nothing yet\n", func(c *Config) {
+		c.Theme = "solarized"
+	}))
+}
+
+func TestUpdateFileRemovesSettings(t *testing.T) {
+	file := `[macros]
+# greetings
+"say hi" = "echo hi\n"
+bye = "exit\n"
+`
+	assert.Equal(t, `[macros]
+# greetings
+bye = "exit\n"
+`, updated(t, file, func(c *Config) {
+		delete(c.Macros, "say hi")
+	}))
+}
+
+func TestUpdateFileLeavesArraysOfTablesAlone(t *testing.T) {
+	file := "theme = \"dark\"\n\n[[ssh]]\nname = \"box\"\nhost = \"box.example.com\"\n"
+	assert.Equal(t, "theme = \"light\"\n\n[[ssh]]\nname = \"box\"\nhost = \"box.example.com\"\n", updated(t, file, func(c *Config) {
+		c.Theme = "light"
+	}))
+}
+
+func TestUpdateFileEditsTheDefaultFile(t *testing.T) {
+	written := updated(t, string(DefaultFile()), func(c *Config) {
+		c.Theme = "solarized"
+		c.Font.Size = 15
+		c.Macros = MacroConfig{"hi": "echo hi\n"}
+	})
+	assert.Empty(t, Check([]byte(written)))
+	c, err := Parse([]byte(written))
+	require.Nil(t, err)
+	assert.Equal(t, "solarized", c.Theme)
+	assert.Equal(t, float32(15), c.Font.Size)
+	assert.Equal(t, "echo hi\n", c.Macros["hi"])
+}
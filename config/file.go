@@ -0,0 +1,228 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/BurntSushi/toml"
+)
+
+//go:embed default.toml
+var defaultFile string
+
+var defaultFileTemplate = template.Must(template.New("config").Parse(defaultFile))
+
+// unboundActions have no shortcut by default, and are listed in the default file so they can be given one
+var unboundActions = []UserAction{
+	ActionCopyHTML,
+	ActionCopyRTF,
+	ActionCopyShell,
+	ActionExportANSI,
+	ActionRerun,
+	ActionNewWindow,
+	ActionScrollUp,
+	ActionScrollDown,
+	ActionScrollBottom,
+	ActionMinimap,
+}
+
+// DefaultFile returns the config file written the first time Aminal runs, which explains every setting and
+// lists it, commented out, at its default value
+func DefaultFile() []byte {
+	type shortcut struct {
+		Action   string
+		Shortcut string
+	}
+	keys := []shortcut{}
+	for action, combination := range DefaultConfig.KeyMapping {
+		keys = append(keys, shortcut{action, combination})
+	}
+	for _, action := range unboundActions {
+		if _, ok := DefaultConfig.KeyMapping[string(action)]; !ok {
+			keys = append(keys, shortcut{Action: string(action)})
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].Action < keys[j].Action
+	})
+
+	var out bytes.Buffer
+	if err := defaultFileTemplate.Execute(&out, struct{ Keys []shortcut }{keys}); err != nil {
+		panic(err) // the template is part of the program, so this can only be a bug
+	}
+	return out.Bytes()
+}
+
+// Problem is a mistake in a config file
+type Problem struct {
+	Line    int // where the problem is, from 1, or 0 if it isn't on a line of its own
+	Message string
+}
+
+// parseErrorPattern matches the errors the toml package gives for invalid syntax
+var parseErrorPattern = regexp.MustCompile(`^Near line (\d+) \(last key parsed '[^']*'\): (.*)$`)
+
+// Check returns the mistakes in a config file: invalid TOML, values of the wrong type, settings Aminal doesn't
+// have, which are often misspelt, and values it can't use, with the lines they're on
+func Check(data []byte) []Problem {
+	c := DefaultConfig
+	c.KeyMapping = KeyMappingConfig{}
+	meta, err := toml.Decode(string(data), &c)
+	if err != nil {
+		if match := parseErrorPattern.FindStringSubmatch(err.Error()); match != nil {
+			line, _ := strconv.Atoi(match[1])
+			return []Problem{{Line: line, Message: match[2]}}
+		}
+		// the toml package doesn't say which value had the wrong type, so each is decoded in turn to find it
+		var values map[string]toml.Primitive
+		if meta, err := toml.Decode(string(data), &values); err == nil {
+			if key, err := findBadValue(meta, values, reflect.ValueOf(&c).Elem(), nil); key != nil {
+				return []Problem{{Line: keyLine(data, key), Message: fmt.Sprintf("'%s': %s", strings.Join(key, "."), strings.TrimPrefix(err.Error(), "toml: "))}}
+			}
+		}
+		return []Problem{{Message: err.Error()}}
+	}
+
+	problems := []Problem{}
+	for _, key := range meta.Undecoded() {
+		problems = append(problems, Problem{Line: keyLine(data, key), Message: fmt.Sprintf("Unknown setting '%s'", key)})
+	}
+
+	check := func(err error, key ...string) {
+		if err != nil {
+			problems = append(problems, Problem{Line: keyLine(data, key), Message: err.Error()})
+		}
+	}
+	check(c.Window.Validate(), "window")
+	for action, keys := range c.KeyMapping {
+		if strings.TrimSpace(keys) != "" {
+			_, err := parseKeyCombination(keys)
+			check(err, "keys", action)
+		}
+	}
+	for i, b := range c.Bindings {
+		platform := ""
+		if len(b.Platforms) > 0 {
+			platform = b.Platforms[0]
+		}
+		_, err := GenerateBindings([]BindingConfig{b}, platform)
+		check(err, "bindings", strconv.Itoa(i))
+	}
+	for i, p := range c.Profiles {
+		if p.Name == "" {
+			check(fmt.Errorf("Profile without a name"), "profiles", strconv.Itoa(i))
+		}
+		if _, err := regexp.Compile(p.Match); err != nil {
+			check(fmt.Errorf("Invalid match pattern '%s' for profile '%s': %s", p.Match, p.Name, err), "profiles", strconv.Itoa(i), "match")
+		}
+	}
+	for i, h := range c.Hosts {
+		if _, err := regexp.Compile(h.Pattern); err != nil {
+			check(fmt.Errorf("Invalid host pattern '%s': %s", h.Pattern, err), "hosts", strconv.Itoa(i), "pattern")
+		}
+		_, _, err := h.TintColour()
+		check(err, "hosts", strconv.Itoa(i), "tint")
+	}
+	for _, policy := range []struct{ name, value string }{{"read", c.Clipboard.Read}, {"write", c.Clipboard.Write}} {
+		switch policy.value {
+		case ClipboardAllow, ClipboardAsk, ClipboardDeny:
+		default:
+			check(fmt.Errorf("Unknown clipboard policy '%s', expected %s, %s or %s", policy.value, ClipboardAllow, ClipboardAsk, ClipboardDeny), "clipboard", policy.name)
+		}
+	}
+
+	sort.SliceStable(problems, func(i, j int) bool {
+		return problems[i].Line < problems[j].Line
+	})
+	return problems
+}
+
+// findBadValue decodes the values into the fields of v with their names one at a time, going into tables, and
+// returns the key of the first which can't be decoded, with why
+func findBadValue(meta toml.MetaData, values map[string]toml.Primitive, v reflect.Value, parent []string) ([]string, error) {
+	names := []string{}
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		key := append(append([]string{}, parent...), name)
+		field := fieldByTag(v, name)
+		if !field.IsValid() {
+			continue
+		}
+		err := meta.PrimitiveDecode(values[name], field.Addr().Interface())
+		if err == nil {
+			continue
+		}
+		var table map[string]toml.Primitive
+		if field.Kind() == reflect.Struct && meta.PrimitiveDecode(values[name], &table) == nil {
+			if inner, err := findBadValue(meta, table, field, key); inner != nil {
+				return inner, err
+			}
+		}
+		return key, err
+	}
+	return nil, nil
+}
+
+// fieldByTag returns the field of the struct v which the toml package decodes name into, or an invalid value
+func fieldByTag(v reflect.Value, name string) reflect.Value {
+	if v.Kind() != reflect.Struct {
+		return reflect.Value{}
+	}
+	for i := 0; i < v.NumField(); i++ {
+		if strings.Split(v.Type().Field(i).Tag.Get("toml"), ",")[0] == name {
+			return v.Field(i)
+		}
+	}
+	return reflect.Value{}
+}
+
+// keyLine returns the line a key is set on in a config file, from 1, or the line its table starts on if it's
+// a table, or 0 if it can't be found. Tables in arrays of tables are named with or without their index, e.g.
+// bindings.2 for the third [[bindings]].
+func keyLine(data []byte, key []string) int {
+	want := strings.Join(key, ".")
+	tables := []string{""} // the names the current table goes by
+	count := map[string]int{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		names := []string{}
+		switch {
+		case strings.HasPrefix(text, "[["):
+			name := strings.TrimSpace(strings.Trim(strings.SplitN(text, "]]", 2)[0], "["))
+			tables = []string{fmt.Sprintf("%s.%d", name, count[name]), name}
+			count[name]++
+			names = tables
+		case strings.HasPrefix(text, "["):
+			tables = []string{strings.TrimSpace(strings.Trim(strings.SplitN(text, "]", 2)[0], "["))}
+			names = tables
+		case strings.Contains(text, "=") && !strings.HasPrefix(text, "#"):
+			name := strings.Trim(strings.TrimSpace(strings.SplitN(text, "=", 2)[0]), `"'`)
+			for _, table := range tables {
+				if table == "" {
+					names = append(names, name)
+				} else {
+					names = append(names, table+"."+name)
+				}
+			}
+		}
+		for _, name := range names {
+			if name == want {
+				return line
+			}
+		}
+	}
+	return 0
+}
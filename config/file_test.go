@@ -0,0 +1,50 @@
+package config
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultFileIsTheDefaults(t *testing.T) {
+	file := DefaultFile()
+	assert.Empty(t, Check(file))
+
+	c, err := Parse(file)
+	require.Nil(t, err)
+	assertSameSettings(t, &DefaultConfig, c)
+
+	// with every setting uncommented, the values shown are the defaults
+	uncommented := regexp.MustCompile(`(?m)^# ([a-z_-]+ *= )`).ReplaceAll(file, []byte("$1"))
+	assert.Empty(t, Check(uncommented))
+	c, err = Parse(uncommented)
+	require.Nil(t, err)
+	for _, action := range unboundActions {
+		assert.Equal(t, "", c.KeyMapping[string(action)])
+		delete(c.KeyMapping, string(action))
+	}
+	assertSameSettings(t, &DefaultConfig, c)
+}
+
+// assertSameSettings compares the configs, where empty lists and tables are the same as none
+func assertSameSettings(t *testing.T, expected *Config, actual *Config) {
+	c := *actual
+	if len(c.ShellArgs) == 0 {
+		c.ShellArgs = nil
+	}
+	if len(c.Command) == 0 {
+		c.Command = nil
+	}
+	if len(c.Font.Fallback) == 0 {
+		c.Font.Fallback = nil
+	}
+	if len(c.Env) == 0 {
+		c.Env = nil
+	}
+	if len(c.Macros) == 0 {
+		c.Macros = nil
+	}
+	assert.Equal(t, *expected, c)
+}
@@ -0,0 +1,51 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// HostTheme colours a terminal while its shell reports being on a host matching Pattern, with OSC 7 from shell
+// integration, so that e.g. a shell ssh'd into production is unmistakable
+type HostTheme struct {
+	Pattern string `toml:"pattern"` // regular expression matched against the host name
+	Theme   string `toml:"theme"`   // colours to use instead of the tab's
+	Tint    string `toml:"tint"`    // colour mixed into the background, e.g. "#ff0000" for production
+}
+
+// the share of the background a host's tint takes
+const hostTintAmount = 0.3
+
+// HostTheme returns the first of the host themes whose pattern matches the host, which never matches an empty one
+func (c *Config) HostTheme(host string) (HostTheme, bool) {
+	if host == "" {
+		return HostTheme{}, false
+	}
+	for _, h := range c.Hosts {
+		// invalid patterns are reported by Check
+		if pattern, err := regexp.Compile(h.Pattern); err == nil && pattern.MatchString(host) {
+			return h, true
+		}
+	}
+	return HostTheme{}, false
+}
+
+// TintColour returns the colour the host mixes into the background, or false if it doesn't have one
+func (h HostTheme) TintColour() (Colour, bool, error) {
+	if h.Tint == "" {
+		return Colour{}, false, nil
+	}
+	c, err := strToColour(h.Tint)
+	if err != nil {
+		return Colour{}, false, fmt.Errorf("Invalid tint '%s' for hosts matching '%s': %s", h.Tint, h.Pattern, err)
+	}
+	return c, true, nil
+}
+
+// Tinted returns the scheme with the tint mixed into its background
+func (s ColourScheme) Tinted(tint Colour) ColourScheme {
+	for i := range s.Background {
+		s.Background[i] += (tint[i] - s.Background[i]) * hostTintAmount
+	}
+	return s
+}
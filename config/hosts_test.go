@@ -0,0 +1,69 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostTheme(t *testing.T) {
+	c := Config{Hosts: []HostTheme{
+		{Pattern: "(", Theme: "broken"},
+		{Pattern: `^prod-`, Tint: "#ff0000"},
+		{Pattern: `\.example\.com$`, Theme: "dracula"},
+		{Pattern: `.*`, Theme: "anything"},
+	}}
+
+	h, ok := c.HostTheme("prod-db1")
+	assert.True(t, ok)
+	assert.Equal(t, "#ff0000", h.Tint)
+
+	h, ok = c.HostTheme("staging.example.com")
+	assert.True(t, ok)
+	assert.Equal(t, "dracula", h.Theme)
+
+	_, ok = c.HostTheme("")
+	assert.False(t, ok, "this host is never matched")
+}
+
+func TestHostThemeTintColour(t *testing.T) {
+	tint, ok, err := HostTheme{Tint: "#ff0000"}.TintColour()
+	require.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, Colour{1, 0, 0}, tint)
+
+	_, ok, err = HostTheme{}.TintColour()
+	require.Nil(t, err)
+	assert.False(t, ok)
+
+	_, _, err = HostTheme{Tint: "red"}.TintColour()
+	assert.NotNil(t, err)
+}
+
+func TestTinted(t *testing.T) {
+	scheme := ColourScheme{Foreground: Colour{1, 1, 1}, Background: Colour{0, 0, 0.5}}
+
+	tinted := scheme.Tinted(Colour{1, 0, 0})
+	assert.InDelta(t, hostTintAmount, tinted.Background[0], 0.001)
+	assert.InDelta(t, 0, tinted.Background[1], 0.001)
+	assert.InDelta(t, 0.5*(1-hostTintAmount), tinted.Background[2], 0.001)
+	assert.Equal(t, scheme.Foreground, tinted.Foreground)
+	assert.Equal(t, Colour{0, 0, 0.5}, scheme.Background, "the scheme tinted is left alone")
+}
+
+func TestCheckHosts(t *testing.T) {
+	problems := Check([]byte(`[[hosts]]
+pattern = "prod-("
+tint = "#ff0000"
+
+[[hosts]]
+pattern = "staging"
+tint = "orange"
+`))
+	require.Len(t, problems, 2)
+	assert.Equal(t, 2, problems[0].Line)
+	assert.Contains(t, problems[0].Message, "Invalid host pattern 'prod-('")
+	assert.Equal(t, 7, problems[1].Line)
+	assert.Contains(t, problems[1].Message, "Invalid tint 'orange' for hosts matching 'staging'")
+}
@@ -28,6 +28,35 @@ var modMap = map[KeyMod]glfw.ModifierKey{
 	super: glfw.ModSuper,
 }
 
+// keys without a single character name, which can be used in shortcuts by these names instead
+var namedKeys = map[string]rune{
+	"tab":       '\t',
+	"left":      '←',
+	"right":     '→',
+	"up":        '↑',
+	"down":      '↓',
+	"pageup":    '⇞',
+	"pagedown":  '⇟',
+	"backspace": '⌫',
+	"delete":    '⌦',
+}
+
+// ParseModifiers reads modifier names separated by +, e.g. "ctrl + alt". An empty string is no modifiers.
+func ParseModifiers(modStr string) (glfw.ModifierKey, error) {
+	var mods glfw.ModifierKey
+	if strings.TrimSpace(modStr) == "" {
+		return mods, nil
+	}
+	for _, k := range strings.Split(modStr, "+") {
+		mod, ok := modMap[KeyMod(strings.ToLower(strings.TrimSpace(k)))]
+		if !ok {
+			return 0, fmt.Errorf("Unknown modifier key '%s'", strings.TrimSpace(k))
+		}
+		mods |= mod
+	}
+	return mods, nil
+}
+
 // keyStr e.g. "ctrl + alt + a"
 func parseKeyCombination(keyStr string) (*KeyCombination, error) {
 	var mods glfw.ModifierKey
@@ -46,7 +75,11 @@ func parseKeyCombination(keyStr string) (*KeyCombination, error) {
 			return nil, fmt.Errorf("Multiple non-modifier keys specified in keyboard shortcut")
 		}
 
-		key = rune(k[0])
+		if named, ok := namedKeys[k]; ok {
+			key = named
+		} else {
+			key = rune(k[0])
+		}
 	}
 
 	if key == 0 {
@@ -70,6 +103,10 @@ func (combi KeyCombination) Match(pressedMods glfw.ModifierKey, pressedChar rune
 func (keyMapConfig KeyMappingConfig) GenerateActionMap() (map[UserAction]*KeyCombination, error) {
 	m := map[UserAction]*KeyCombination{}
 	for actionStr, keyStr := range keyMapConfig {
+		if strings.TrimSpace(keyStr) == "" {
+			// unbound
+			continue
+		}
 		combi, err := parseKeyCombination(keyStr)
 		if err != nil {
 			return nil, err
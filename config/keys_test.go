@@ -23,3 +23,48 @@ func TestKeyCombinations(t *testing.T) {
 	assert.False(t, combi.Match(0, 'e'))
 	assert.False(t, combi.Match(glfw.ModControl^glfw.ModAlt^glfw.ModShift, 'f'))
 }
+
+func TestNamedKeyCombinations(t *testing.T) {
+	combi, err := parseKeyCombination("ctrl + shift + tab")
+	require.Nil(t, err)
+
+	assert.True(t, combi.Match(glfw.ModControl^glfw.ModShift, '\t'))
+	assert.False(t, combi.Match(glfw.ModControl^glfw.ModShift, 't'))
+
+	combi, err = parseKeyCombination("ctrl + shift + pageup")
+	require.Nil(t, err)
+	assert.True(t, combi.Match(glfw.ModControl^glfw.ModShift, '⇞'))
+
+	combi, err = parseKeyCombination("ctrl + shift + backspace")
+	require.Nil(t, err)
+	assert.True(t, combi.Match(glfw.ModControl^glfw.ModShift, '⌫'))
+	assert.False(t, combi.Match(glfw.ModControl^glfw.ModShift, 'b'))
+}
+
+func TestUnboundActions(t *testing.T) {
+	actions, err := KeyMappingConfig{"copy": "ctrl + c", "rerun_command": ""}.GenerateActionMap()
+	require.Nil(t, err)
+	assert.Len(t, actions, 1)
+}
+
+func TestArrowKeyCombinations(t *testing.T) {
+	combi, err := parseKeyCombination("ctrl + shift + alt + left")
+	require.Nil(t, err)
+
+	assert.True(t, combi.Match(glfw.ModControl^glfw.ModShift^glfw.ModAlt, '←'))
+	assert.False(t, combi.Match(glfw.ModControl^glfw.ModShift^glfw.ModAlt, 'l'))
+	assert.False(t, combi.Match(glfw.ModControl^glfw.ModShift, '←'))
+}
+
+func TestParseModifiers(t *testing.T) {
+	mods, err := ParseModifiers("Ctrl + alt")
+	require.Nil(t, err)
+	assert.Equal(t, glfw.ModControl|glfw.ModAlt, mods)
+
+	mods, err = ParseModifiers("")
+	require.Nil(t, err)
+	assert.Equal(t, glfw.ModifierKey(0), mods)
+
+	_, err = ParseModifiers("ctrl + x")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,105 @@
+package config
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// PasteConfig controls changes made to text as it is pasted, to avoid accidentally running pasted commands
+type PasteConfig struct {
+	StripTrailingNewline bool `toml:"strip_trailing_newline"`
+	ConvertCRLF          bool `toml:"crlf_to_lf"`
+	CollapseBlankLines   bool `toml:"collapse_blank_lines"`
+	StripEscapes         bool `toml:"strip_escapes"` // remove ANSI escape sequences and other control characters
+	Confirm              bool `toml:"confirm"`       // ask before pasting line breaks or control characters, unless the application uses bracketed paste
+}
+
+// pasteStep is one of the changes made to pasted text
+type pasteStep func(text string) string
+
+// Transform applies the configured changes to pasted text. If singleLine is set, line breaks are replaced with spaces.
+func (p PasteConfig) Transform(text string, singleLine bool) string {
+	for _, step := range p.pipeline(singleLine) {
+		text = step(text)
+	}
+	return text
+}
+
+// pipeline returns the changes to make to pasted text, in the order they're made
+func (p PasteConfig) pipeline(singleLine bool) []pasteStep {
+	var steps []pasteStep
+	if p.StripEscapes {
+		steps = append(steps, stripEscapes)
+	}
+	if p.ConvertCRLF || singleLine {
+		steps = append(steps, convertCRLF)
+	}
+	if p.CollapseBlankLines {
+		steps = append(steps, collapseBlankLines)
+	}
+	if p.StripTrailingNewline || singleLine {
+		steps = append(steps, stripTrailingNewlines)
+	}
+	if singleLine {
+		steps = append(steps, joinLines)
+	}
+	return steps
+}
+
+// NeedsConfirmation returns true if the user should be asked before text is pasted, as it would run commands
+// or send control characters to a shell
+func (p PasteConfig) NeedsConfirmation(text string) bool {
+	if !p.Confirm {
+		return false
+	}
+	return strings.IndexFunc(text, func(r rune) bool {
+		return r != '\t' && unicode.IsControl(r)
+	}) >= 0
+}
+
+// escapeSequence matches CSI sequences, OSC and other string sequences up to their terminator, and the
+// remaining two or three byte escape sequences
+var escapeSequence = regexp.MustCompile("(?s)\x1b\\[[0-?]*[ -/]*[@-~]|\x1b[\\]PX^_].*?(?:\x07|\x1b\\\\|$)|\x1b[ -/]*[0-~]")
+
+func stripEscapes(text string) string {
+	text = escapeSequence.ReplaceAllString(text, "")
+	return strings.Map(func(r rune) rune {
+		if r == '\t' || r == '\n' || r == '\r' || !unicode.IsControl(r) {
+			return r
+		}
+		return -1
+	}, text)
+}
+
+func convertCRLF(text string) string {
+	return strings.Replace(text, "\r\n", "\n", -1)
+}
+
+func collapseBlankLines(text string) string {
+	lines := strings.Split(text, "\n")
+	kept := make([]string, 0, len(lines))
+	blank := false
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			if blank {
+				continue
+			}
+			blank = true
+		} else {
+			blank = false
+		}
+		kept = append(kept, line)
+	}
+	return strings.Join(kept, "\n")
+}
+
+func stripTrailingNewlines(text string) string {
+	return strings.TrimRight(text, "\r\n")
+}
+
+func joinLines(text string) string {
+	return strings.Join(strings.FieldsFunc(text, func(r rune) bool {
+		return r == '\n' || r == '\r'
+	}), " ")
+}
@@ -0,0 +1,41 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPasteTransform(t *testing.T) {
+	tests := []struct {
+		name       string
+		config     PasteConfig
+		singleLine bool
+		input      string
+		expected   string
+	}{
+		{"none", PasteConfig{}, false, "ls\r\n\r\n\r\npwd\n", "ls\r\n\r\n\r\npwd\n"},
+		{"strip trailing newline", PasteConfig{StripTrailingNewline: true}, false, "ls -la\n\n", "ls -la"},
+		{"crlf", PasteConfig{ConvertCRLF: true}, false, "a\r\nb\r\n", "a\nb\n"},
+		{"collapse blank lines", PasteConfig{CollapseBlankLines: true}, false, "a\n\n \n\nb\n", "a\n\nb\n"},
+		{"all", PasteConfig{true, true, true, true, false}, false, "a\r\n\r\n\r\n\x1b[1mb\x1b[0m\r\n", "a\n\nb"},
+		{"strip escapes", PasteConfig{StripEscapes: true}, false, "\x1b[31mred\x1b[0m\x1b]0;title\x07\tx\x08\x1b7\n", "red\tx\n"},
+		{"single line", PasteConfig{}, true, "cd /tmp\r\nls\n\nrm x\n", "cd /tmp ls rm x"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, test.config.Transform(test.input, test.singleLine))
+		})
+	}
+}
+
+func TestPasteNeedsConfirmation(t *testing.T) {
+	assert.False(t, PasteConfig{}.NeedsConfirmation("rm -rf /\n"), "nothing is confirmed unless it's turned on")
+
+	p := PasteConfig{Confirm: true}
+	assert.False(t, p.NeedsConfirmation("echo\tdone"))
+	assert.True(t, p.NeedsConfirmation("rm -rf /\n"))
+	assert.True(t, p.NeedsConfirmation("a\rb"))
+	assert.True(t, p.NeedsConfirmation("\x1b[201~"))
+}
@@ -0,0 +1,133 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Profile is a named bundle of settings a tab can be started with, like Windows Terminal's profiles. Anything
+// left out is taken from the rest of the config.
+type Profile struct {
+	Name      string     `toml:"name"`
+	Shell     string     `toml:"shell"`
+	ShellArgs []string   `toml:"shell_args"`
+	Directory string     `toml:"directory"` // where the shell starts, where ~ is the home directory
+	Env       EnvConfig  `toml:"env"`       // set along with those in [env]
+	Theme     string     `toml:"theme"`     // colours for the tab
+	Font      FontConfig `toml:"font"`      // fonts for the tab, changing only what's set
+	Match     string     `toml:"match"`     // regular expression picking the profile for tabs started with a matching command
+
+	pattern *regexp.Regexp // Match, compiled by Parse
+}
+
+// compileProfiles compiles the match patterns of the profiles, once, rather than each time a tab is started
+func (c *Config) compileProfiles() error {
+	for i := range c.Profiles {
+		p := &c.Profiles[i]
+		if p.Match == "" {
+			continue
+		}
+		pattern, err := regexp.Compile(p.Match)
+		if err != nil {
+			return fmt.Errorf("Invalid match pattern '%s' for profile '%s': %s", p.Match, p.Name, err)
+		}
+		p.pattern = pattern
+	}
+	return nil
+}
+
+// ProfileNamed returns the profile with the name, ignoring case
+func (c *Config) ProfileNamed(name string) (Profile, bool) {
+	for _, p := range c.Profiles {
+		if strings.EqualFold(p.Name, name) {
+			return p, true
+		}
+	}
+	return Profile{}, false
+}
+
+// MatchProfile returns the first profile whose match pattern matches the command, a program and its arguments
+// joined with spaces. The patterns are compiled by Parse, so profiles which weren't read from a file never match.
+func (c *Config) MatchProfile(command []string) (Profile, bool) {
+	if len(command) == 0 {
+		return Profile{}, false
+	}
+	line := strings.Join(command, " ")
+	for _, p := range c.Profiles {
+		if p.pattern != nil && p.pattern.MatchString(line) {
+			return p, true
+		}
+	}
+	return Profile{}, false
+}
+
+// ShellCommand returns the program and arguments starting the profile's shell, or the shell in the config if it
+// doesn't have one
+func (p Profile) ShellCommand(c *Config, loginShell string) []string {
+	if p.Shell != "" {
+		withShell := *c
+		withShell.Shell = p.Shell
+		withShell.ShellArgs = p.ShellArgs
+		c = &withShell
+	}
+	return c.ShellCommand(loginShell)
+}
+
+// EnvList returns the profile's environment variables as NAME=value, in order of name, with $VARIABLES in the
+// values expanded from Aminal's environment
+func (p Profile) EnvList() []string {
+	env := []string{}
+	for name, value := range p.Env {
+		env = append(env, name+"="+os.ExpandEnv(value))
+	}
+	sort.Strings(env)
+	return env
+}
+
+// Dir returns the directory the profile's shell starts in, with ~ and $VARIABLES expanded, or "" if it doesn't
+// have one
+func (p Profile) Dir() string {
+	dir := p.Directory
+	if dir == "~" || strings.HasPrefix(dir, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			dir = filepath.Join(home, dir[1:])
+		}
+	}
+	return os.ExpandEnv(dir)
+}
+
+// FontConfig returns font with the font settings the profile has changed
+func (p Profile) FontConfig(font FontConfig) FontConfig {
+	if p.Font.Regular != "" {
+		font.Regular = p.Font.Regular
+	}
+	if p.Font.Bold != "" {
+		font.Bold = p.Font.Bold
+	}
+	if p.Font.Italic != "" {
+		font.Italic = p.Font.Italic
+	}
+	if p.Font.BoldItalic != "" {
+		font.BoldItalic = p.Font.BoldItalic
+	}
+	if p.Font.Size != 0 {
+		font.Size = p.Font.Size
+	}
+	if p.Font.LineHeight != 0 {
+		font.LineHeight = p.Font.LineHeight
+	}
+	if p.Font.LetterSpacing != 0 {
+		font.LetterSpacing = p.Font.LetterSpacing
+	}
+	if p.Font.Padding != 0 {
+		font.Padding = p.Font.Padding
+	}
+	if p.Font.Fallback != nil {
+		font.Fallback = p.Font.Fallback
+	}
+	return font
+}
@@ -0,0 +1,101 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProfileNamed(t *testing.T) {
+	c := Config{Profiles: []Profile{{Name: "Work"}, {Name: "Home"}}}
+
+	p, ok := c.ProfileNamed("work")
+	assert.True(t, ok)
+	assert.Equal(t, "Work", p.Name)
+
+	_, ok = c.ProfileNamed("play")
+	assert.False(t, ok)
+}
+
+func TestMatchProfile(t *testing.T) {
+	c, err := Parse([]byte(`
+[[profiles]]
+name = "Any"
+
+[[profiles]]
+name = "Work"
+match = '^ssh .*\.work\.example\.com'
+
+[[profiles]]
+name = "Also work"
+match = "work"
+`))
+	require.Nil(t, err)
+
+	p, ok := c.MatchProfile([]string{"ssh", "-p", "22", "db.work.example.com"})
+	assert.True(t, ok)
+	assert.Equal(t, "Work", p.Name)
+
+	_, ok = c.MatchProfile([]string{"ssh", "home.example.com"})
+	assert.False(t, ok)
+
+	_, ok = c.MatchProfile(nil)
+	assert.False(t, ok)
+}
+
+func TestParseRejectsInvalidMatchPatterns(t *testing.T) {
+	_, err := Parse([]byte("[[profiles]]\nname = \"Broken\"\nmatch = \"(\"\n"))
+	assert.Error(t, err)
+}
+
+func TestProfileShellCommand(t *testing.T) {
+	c := Config{Shell: "/bin/bash", ShellArgs: []string{"--norc"}}
+
+	assert.Equal(t, []string{"/bin/zsh", "-f"}, Profile{Shell: "/bin/zsh", ShellArgs: []string{"-f"}}.ShellCommand(&c, ""))
+	assert.Equal(t, []string{"/bin/bash", "--norc"}, Profile{}.ShellCommand(&c, ""))
+	assert.Equal(t, "/bin/bash", c.Shell)
+}
+
+func TestProfileEnvList(t *testing.T) {
+	os.Setenv("AMINAL_TEST_HOME", "/home/test")
+	defer os.Unsetenv("AMINAL_TEST_HOME")
+
+	p := Profile{Env: EnvConfig{"PATH": "$AMINAL_TEST_HOME/bin", "AWS_PROFILE": "work"}}
+	assert.Equal(t, []string{"AWS_PROFILE=work", "PATH=/home/test/bin"}, p.EnvList())
+	assert.Empty(t, Profile{}.EnvList())
+}
+
+func TestProfileDir(t *testing.T) {
+	home, err := os.UserHomeDir()
+	require.Nil(t, err)
+
+	assert.Equal(t, filepath.Join(home, "work"), Profile{Directory: "~/work"}.Dir())
+	assert.Equal(t, home, Profile{Directory: "~"}.Dir())
+	assert.Equal(t, "/tmp", Profile{Directory: "/tmp"}.Dir())
+	assert.Equal(t, "", Profile{}.Dir())
+}
+
+func TestProfileFontConfig(t *testing.T) {
+	font := FontConfig{Regular: "/fonts/regular.ttf", Size: 12, LineHeight: 1.2, RememberSize: true}
+
+	changed := Profile{Font: FontConfig{Size: 16, Fallback: []string{"/fonts/emoji.ttf"}}}.FontConfig(font)
+	assert.Equal(t, FontConfig{Regular: "/fonts/regular.ttf", Size: 16, LineHeight: 1.2, RememberSize: true, Fallback: []string{"/fonts/emoji.ttf"}}, changed)
+	assert.True(t, Profile{}.FontConfig(font).Equal(font))
+}
+
+func TestCheckProfiles(t *testing.T) {
+	problems := Check([]byte(`[[profiles]]
+name = "Work"
+match = "("
+
+[[profiles]]
+shell = "/bin/zsh"
+`))
+	require.Len(t, problems, 2)
+	assert.Equal(t, 3, problems[0].Line)
+	assert.Contains(t, problems[0].Message, "Invalid match pattern '(' for profile 'Work'")
+	assert.Equal(t, Problem{Line: 5, Message: "Profile without a name"}, problems[1])
+}
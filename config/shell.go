@@ -0,0 +1,18 @@
+package config
+
+import "runtime"
+
+// ShellCommand returns the program and arguments starting the shell, which is the one given or else loginShell,
+// the user's usual shell
+func (c *Config) ShellCommand(loginShell string) []string {
+	shell := c.Shell
+	if shell == "" {
+		shell = loginShell
+	}
+	command := []string{shell}
+	// there's no such thing as a login shell on Windows
+	if c.LoginShell && runtime.GOOS != "windows" {
+		command = append(command, "-l")
+	}
+	return append(command, c.ShellArgs...)
+}
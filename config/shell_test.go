@@ -0,0 +1,27 @@
+package config
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShellCommand(t *testing.T) {
+	c, err := Parse([]byte("shell_args = [\"--norc\"]\nlogin_shell = true\n[env]\nEDITOR = \"vim\"\n"))
+	require.Nil(t, err)
+	assert.Equal(t, "vim", c.Env["EDITOR"])
+
+	command := c.ShellCommand("/bin/bash")
+	if runtime.GOOS == "windows" {
+		assert.Equal(t, []string{"/bin/bash", "--norc"}, command)
+	} else {
+		assert.Equal(t, []string{"/bin/bash", "-l", "--norc"}, command)
+	}
+
+	c.Shell = "/bin/zsh"
+	c.LoginShell = false
+	c.ShellArgs = nil
+	assert.Equal(t, []string{"/bin/zsh"}, c.ShellCommand("/bin/bash"))
+}
@@ -0,0 +1,49 @@
+package config
+
+import (
+	"strconv"
+	"strings"
+)
+
+// SSHProfile is a host which can be connected to in a new tab, picked from the ssh menu
+type SSHProfile struct {
+	Name     string   `toml:"name"` // shown in the menu, defaulting to user@host
+	Host     string   `toml:"host"`
+	User     string   `toml:"user"`     // defaulting to whatever ssh would use
+	Port     int      `toml:"port"`     // 0 for ssh's default
+	Identity string   `toml:"identity"` // private key file to log in with
+	Jump     []string `toml:"jump"`     // hosts to go through on the way, in order, e.g. "me@bastion:2222"
+	Theme    string   `toml:"theme"`    // colours for the tab, so hosts such as production stand out
+}
+
+// Label returns what the profile is called in the menu
+func (p SSHProfile) Label() string {
+	if p.Name != "" {
+		return p.Name
+	}
+	return p.Destination()
+}
+
+// Destination returns where ssh connects to, with the user if one is set
+func (p SSHProfile) Destination() string {
+	if p.User != "" {
+		return p.User + "@" + p.Host
+	}
+	return p.Host
+}
+
+// Command returns the ssh command line connecting to the host
+func (p SSHProfile) Command() []string {
+	command := []string{"ssh"}
+	if p.Port != 0 {
+		command = append(command, "-p", strconv.Itoa(p.Port))
+	}
+	if p.Identity != "" {
+		command = append(command, "-i", p.Identity)
+	}
+	if len(p.Jump) > 0 {
+		command = append(command, "-J", strings.Join(p.Jump, ","))
+	}
+	// ends the options, so a host can't be taken for one
+	return append(command, "--", p.Destination())
+}
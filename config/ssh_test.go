@@ -0,0 +1,52 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSSHProfileCommand(t *testing.T) {
+	tests := []struct {
+		name     string
+		profile  SSHProfile
+		label    string
+		expected []string
+	}{
+		{"host", SSHProfile{Host: "example.com"}, "example.com", []string{"ssh", "--", "example.com"}},
+		{"user", SSHProfile{Name: "Web", Host: "example.com", User: "deploy"}, "Web", []string{"ssh", "--", "deploy@example.com"}},
+		{
+			"everything",
+			SSHProfile{Host: "db1", User: "me", Port: 2222, Identity: "~/.ssh/prod", Jump: []string{"bastion", "me@inner:22"}},
+			"me@db1",
+			[]string{"ssh", "-p", "2222", "-i", "~/.ssh/prod", "-J", "bastion,me@inner:22", "--", "me@db1"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.label, test.profile.Label())
+			assert.Equal(t, test.expected, test.profile.Command())
+		})
+	}
+}
+
+func TestParseSSHProfiles(t *testing.T) {
+	c, err := Parse([]byte(`
+[[ssh]]
+  name  = "Production"
+  host  = "prod.example.com"
+  jump  = ["bastion.example.com"]
+  theme = "dracula"
+
+[[ssh]]
+  host = "pi.local"
+  user = "pi"
+`))
+	require.Nil(t, err)
+	require.Len(t, c.SSH, 2)
+	assert.Equal(t, "Production", c.SSH[0].Label())
+	assert.Equal(t, "dracula", c.SSH[0].Theme)
+	assert.Equal(t, []string{"ssh", "--", "pi@pi.local"}, c.SSH[1].Command())
+}
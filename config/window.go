@@ -0,0 +1,77 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// window states, which the window is put in when Aminal starts
+const (
+	WindowNormal    = "normal"
+	WindowMaximized = "maximized"
+	WindowMinimized = "minimized"
+)
+
+// WindowConfig sets the size, position and state of the window when Aminal starts
+type WindowConfig struct {
+	Geometry string `toml:"geometry"` // columns and rows, and optionally the position, like xterm's -geometry
+	Width    int    `toml:"width"`    // in pixels, used when the geometry has no size
+	Height   int    `toml:"height"`
+	State    string `toml:"state"` // "normal", "maximized" or "minimized"
+}
+
+// Geometry is the size of the window in cells, and where it goes on the screen
+type Geometry struct {
+	Columns    uint // 0 if the geometry is only a position
+	Rows       uint
+	Positioned bool // false if the window manager places the window
+	X          int  // from the left edge of the screen, or the right if Right is set
+	Y          int  // from the top edge of the screen, or the bottom if Bottom is set
+	Right      bool
+	Bottom     bool
+}
+
+var geometryPattern = regexp.MustCompile(`^(?:(\d+)x(\d+))?(?:([+-]\d+)([+-]\d+))?$`)
+
+// ParseGeometry reads a geometry in the form xterm's -geometry takes: COLUMNSxROWS, followed by an optional
+// position of +X+Y, where each - instead of + measures from the right or bottom edge, e.g. "120x40-0+0". Either
+// part may be left out, and an empty string is no geometry at all.
+func ParseGeometry(geometry string) (Geometry, error) {
+	var g Geometry
+	match := geometryPattern.FindStringSubmatch(geometry)
+	if match == nil {
+		return g, fmt.Errorf("Invalid geometry '%s', expected e.g. 120x40 or 120x40+100+50", geometry)
+	}
+	if match[1] != "" {
+		columns, _ := strconv.Atoi(match[1])
+		rows, _ := strconv.Atoi(match[2])
+		if columns == 0 || rows == 0 {
+			return g, fmt.Errorf("Invalid geometry '%s', the window must be at least 1x1", geometry)
+		}
+		g.Columns, g.Rows = uint(columns), uint(rows)
+	}
+	if match[3] != "" {
+		g.Positioned = true
+		g.X, _ = strconv.Atoi(match[3][1:])
+		g.Y, _ = strconv.Atoi(match[4][1:])
+		g.Right = match[3][0] == '-'
+		g.Bottom = match[4][0] == '-'
+	}
+	return g, nil
+}
+
+// Validate checks the geometry and state can be used
+func (c WindowConfig) Validate() error {
+	if _, err := ParseGeometry(c.Geometry); err != nil {
+		return err
+	}
+	if c.Width <= 0 || c.Height <= 0 {
+		return fmt.Errorf("Invalid window size %dx%d", c.Width, c.Height)
+	}
+	switch c.State {
+	case "", WindowNormal, WindowMaximized, WindowMinimized:
+		return nil
+	}
+	return fmt.Errorf("Unknown window state '%s', expected %s, %s or %s", c.State, WindowNormal, WindowMaximized, WindowMinimized)
+}
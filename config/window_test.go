@@ -0,0 +1,40 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseGeometry(t *testing.T) {
+	g, err := ParseGeometry("120x40")
+	require.Nil(t, err)
+	assert.Equal(t, Geometry{Columns: 120, Rows: 40}, g)
+
+	g, err = ParseGeometry("120x40+100+50")
+	require.Nil(t, err)
+	assert.Equal(t, Geometry{Columns: 120, Rows: 40, Positioned: true, X: 100, Y: 50}, g)
+
+	g, err = ParseGeometry("-0+20")
+	require.Nil(t, err)
+	assert.Equal(t, Geometry{Positioned: true, X: 0, Y: 20, Right: true}, g)
+
+	g, err = ParseGeometry("")
+	require.Nil(t, err)
+	assert.Equal(t, Geometry{}, g)
+}
+
+func TestInvalidGeometry(t *testing.T) {
+	for _, geometry := range []string{"120", "120x", "0x40", "120x40+100", "big"} {
+		_, err := ParseGeometry(geometry)
+		assert.NotNil(t, err, geometry)
+	}
+}
+
+func TestWindowState(t *testing.T) {
+	assert.Nil(t, WindowConfig{Width: 800, Height: 600, State: WindowMaximized}.Validate())
+	assert.NotNil(t, WindowConfig{Width: 800, Height: 600, State: "fullscreen"}.Validate())
+	assert.NotNil(t, WindowConfig{Width: 800, Height: 600, Geometry: "80"}.Validate())
+	assert.NotNil(t, WindowConfig{Height: 600}.Validate())
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/liamg/aminal/config"
+	"github.com/liamg/aminal/themes"
+)
+
+const configUsage = `Usage: aminal config <command>
+
+  init [path]   Write a config file explaining every setting, asking for a few to start with
+  check [path]  Look for mistakes in the config file
+  show          Print the settings Aminal uses, from the defaults, the config file and the flags given before "config"
+`
+
+// configCommand runs "aminal config init" or "aminal config check", returning the exit status. "aminal config
+// show" is handled by showConfig, once the flags have been applied.
+func configCommand(args []string) int {
+	if len(args) == 0 || len(args) > 2 {
+		fmt.Print(configUsage)
+		return 1
+	}
+	path := ""
+	if len(args) == 2 {
+		path = args[1]
+	}
+
+	switch args[0] {
+	case "init":
+		return initConfig(path)
+	case "check":
+		return checkConfig(path)
+	}
+	fmt.Print(configUsage)
+	return 1
+}
+
+// initConfig writes the default config file to path, or where Aminal looks first, with the answers to a few
+// questions filled in if it's run in a terminal
+func initConfig(path string) int {
+	if path == "" {
+		places := configPlaces()
+		if len(places) == 0 {
+			fmt.Println("Failed to find your home directory, give the path to write the config file to")
+			return 1
+		}
+		path = places[0]
+	}
+	if _, err := os.Stat(path); err == nil {
+		fmt.Printf("There's already a config file at %s, use \"aminal config check\" to look for mistakes in it\n", path)
+		return 1
+	}
+
+	data := config.DefaultFile()
+	if stat, err := os.Stdin.Stat(); err == nil && stat.Mode()&os.ModeCharDevice != 0 {
+		data = askForSettings(data, path)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o744); err != nil {
+		fmt.Printf("Failed to create config file directory: %s\n", err)
+		return 1
+	}
+	if err := ioutil.WriteFile(path, data, 0o644); err != nil {
+		fmt.Printf("Failed to write config file: %s\n", err)
+		return 1
+	}
+	fmt.Printf("Wrote %s\n", path)
+	return 0
+}
+
+// askForSettings asks for the settings people most often change, and sets those given in the config file
+func askForSettings(data []byte, path string) []byte {
+	input := bufio.NewReader(os.Stdin)
+	ask := func(question string) string {
+		fmt.Print(question)
+		answer, _ := input.ReadString('\n')
+		return strings.TrimSpace(answer)
+	}
+
+	fmt.Println("Press enter to leave a setting at its default")
+	conf := config.DefaultConfig
+	conf.Path = path
+	if names := themes.Names(themes.Directory(&conf)); len(names) > 0 {
+		fmt.Printf("Themes: %s\n", strings.Join(names, ", "))
+		for {
+			theme := ask("Theme: ")
+			if theme == "" {
+				break
+			}
+			if _, err := themes.Load(theme, themes.Directory(&conf)); err != nil {
+				fmt.Printf("Failed to load theme: %s\n", err)
+				continue
+			}
+			data = setInConfigFile(data, "theme", strconv.Quote(theme))
+			break
+		}
+	}
+	for {
+		size := ask(fmt.Sprintf("Font size (%g): ", config.DefaultConfig.Font.Size))
+		if size == "" {
+			break
+		}
+		value, err := strconv.ParseFloat(size, 32)
+		if err != nil || value <= 0 {
+			fmt.Println("The font size should be a number, e.g. 12 or 10.5")
+			continue
+		}
+		// written as a float, as whole numbers can't be read into one
+		size = strconv.FormatFloat(value, 'f', -1, 32)
+		if !strings.Contains(size, ".") {
+			size += ".0"
+		}
+		data = setInConfigFile(data, "size", size)
+		break
+	}
+	if shell := ask("Shell (your login shell): "); shell != "" {
+		data = setInConfigFile(data, "shell", strconv.Quote(shell))
+	}
+	return data
+}
+
+// setInConfigFile uncomments the first line of the config file setting key, and sets it to value, which should
+// be written as TOML
+func setInConfigFile(data []byte, key string, value string) []byte {
+	pattern := regexp.MustCompile(`(?m)^# ` + regexp.QuoteMeta(key) + ` *= .*$`)
+	done := false
+	return pattern.ReplaceAllFunc(data, func(line []byte) []byte {
+		if done {
+			return line
+		}
+		done = true
+		return []byte(key + " = " + value)
+	})
+}
+
+// checkConfig prints the mistakes in the config file at path, or the one Aminal uses, like a compiler would,
+// returning 1 if there are any
+func checkConfig(path string) int {
+	if path == "" {
+		for _, place := range configPlaces() {
+			if _, err := os.Stat(place); err == nil {
+				path = place
+				break
+			}
+		}
+		if path == "" {
+			fmt.Println("There's no config file, use \"aminal config init\" to write one")
+			return 1
+		}
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		fmt.Printf("Failed to read config file: %s\n", err)
+		return 1
+	}
+
+	problems := config.Check(data)
+	if len(problems) == 0 {
+		// the theme is only worth loading once the rest can be read
+		conf, _ := config.Parse(data)
+		conf.Path = path
+		if err := themes.Apply(conf); err != nil {
+			problems = append(problems, config.Problem{Message: fmt.Sprintf("Failed to load theme: %s", err)})
+		}
+	}
+	for _, problem := range problems {
+		if problem.Line == 0 {
+			fmt.Printf("%s: %s\n", path, problem.Message)
+		} else {
+			fmt.Printf("%s:%d: %s\n", path, problem.Line, problem.Message)
+		}
+	}
+	if len(problems) > 0 {
+		return 1
+	}
+	fmt.Printf("%s is fine\n", path)
+	return 0
+}
+
+// showConfig prints the settings Aminal would use, in the config file's format
+func showConfig(conf *config.Config) int {
+	data, err := conf.Encode()
+	if err != nil {
+		fmt.Printf("Failed to encode config: %s\n", err)
+		return 1
+	}
+	source := "the defaults"
+	if conf.Path != "" {
+		source = "the defaults, " + conf.Path
+	}
+	fmt.Printf("# The settings in use, from %s and the flags given\n%s", source, data)
+	return 0
+}
@@ -0,0 +1,128 @@
+package control
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/liamg/aminal/buffer"
+)
+
+// Aminal holds the commands, named Aminal.<method> in requests. Commands without arguments still take an empty
+// object, as in "params": [{}].
+type Aminal struct {
+	window Window
+}
+
+// SendArgs is the text for Send
+type SendArgs struct {
+	Text string `json:"text"`
+}
+
+// Send writes the text to the program running in the terminal, as if it had been typed, e.g. "ls\r"
+func (a *Aminal) Send(args SendArgs, reply *struct{}) error {
+	if err := a.window.Send([]byte(args.Text)); err != nil {
+		return fmt.Errorf("Failed to send to the terminal: %s", err)
+	}
+	return nil
+}
+
+// Screen is what the terminal is showing
+type Screen struct {
+	Lines []string `json:"lines"` // from the top, without trailing spaces, up to the last line written to
+	Title string   `json:"title"`
+	Cols  int      `json:"cols"`
+	Rows  int      `json:"rows"`
+}
+
+// Screen returns the lines on screen, like tmux's capture-pane
+func (a *Aminal) Screen(args struct{}, reply *Screen) error {
+	t := a.window.Terminal()
+	reply.Lines = []string{}
+	for _, line := range t.GetVisibleLines() {
+		reply.Lines = append(reply.Lines, line.String())
+	}
+	reply.Title = t.GetTitle()
+	reply.Cols, reply.Rows = t.GetSize()
+	return nil
+}
+
+// Cursor is where the terminal's cursor is
+type Cursor struct {
+	Row     int  `json:"row"` // from 0 at the top of the screen
+	Col     int  `json:"col"` // from 0
+	Visible bool `json:"visible"`
+}
+
+// Cursor returns where the cursor is, and whether the program has hidden it
+func (a *Aminal) Cursor(args struct{}, reply *Cursor) error {
+	t := a.window.Terminal()
+	reply.Row = int(t.GetLogicalCursorY())
+	reply.Col = int(t.GetLogicalCursorX())
+	reply.Visible = t.Modes().ShowCursor
+	return nil
+}
+
+// ResizeArgs is the size for Resize
+type ResizeArgs struct {
+	Cols uint `json:"cols"`
+	Rows uint `json:"rows"`
+}
+
+// Resize changes the size of the window to fit the columns and rows
+func (a *Aminal) Resize(args ResizeArgs, reply *struct{}) error {
+	if args.Cols == 0 || args.Rows == 0 {
+		return fmt.Errorf("Invalid size %dx%d", args.Cols, args.Rows)
+	}
+	return a.window.Resize(args.Cols, args.Rows)
+}
+
+// ScreenshotArgs is where Screenshot saves to
+type ScreenshotArgs struct {
+	Path string `json:"path"`
+}
+
+// Screenshot saves the window as a PNG at the path
+func (a *Aminal) Screenshot(args ScreenshotArgs, reply *struct{}) error {
+	if args.Path == "" {
+		return fmt.Errorf("No path to save the screenshot to")
+	}
+	return a.window.Screenshot(args.Path)
+}
+
+// ExportArgs is what Export exports
+type ExportArgs struct {
+	Format     string `json:"format"`     // "html" or "ansi"
+	Scrollback bool   `json:"scrollback"` // every line kept, rather than just those on screen
+}
+
+// Export is the exported text
+type Export struct {
+	Text string `json:"text"`
+}
+
+// Export returns the lines with their colours and styles, as a standalone HTML page or as text with ANSI
+// escape sequences
+func (a *Aminal) Export(args ExportArgs, reply *Export) error {
+	t := a.window.Terminal()
+	var out bytes.Buffer
+	var err error
+	switch args.Format {
+	case "html":
+		options := t.Options()
+		err = t.ActiveBuffer().WriteHTML(&out, buffer.ExportOptions{
+			Title:      t.GetTitle(),
+			Foreground: options.Foreground,
+			Background: options.Background,
+			Scrollback: args.Scrollback,
+		})
+	case "ansi":
+		err = t.ActiveBuffer().WriteANSI(&out, args.Scrollback)
+	default:
+		return fmt.Errorf("Unknown export format %q, expected \"html\" or \"ansi\"", args.Format)
+	}
+	if err != nil {
+		return fmt.Errorf("Failed to export: %s", err)
+	}
+	reply.Text = out.String()
+	return nil
+}
@@ -0,0 +1,63 @@
+// Package control lets other programs drive a window over a local socket, like tmux's send-keys and capture-pane,
+// for integration tests and automation. Requests are JSON-RPC 1.0, one object per request, e.g.
+//
+//	{"method": "Aminal.Send", "params": [{"text": "ls\r"}], "id": 1}
+package control
+
+import (
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"time"
+
+	"github.com/liamg/aminal/platform"
+	"github.com/liamg/aminal/terminal"
+	"go.uber.org/zap"
+)
+
+// connectTimeout is how long an Aminal already listening on the socket has to answer
+const connectTimeout = time.Second
+
+// Window is what the commands act on, the focused terminal of a window
+type Window interface {
+	Terminal() *terminal.Terminal
+	Send(data []byte) error            // writes to the terminal's program, in turn with input from the keyboard
+	Resize(cols uint, rows uint) error // changes the size of the window to fit the columns and rows
+	Screenshot(path string) error      // saves the window as a PNG
+}
+
+// Server answers the commands sent by the programs connecting to it
+type Server struct {
+	rpc    *rpc.Server
+	logger *zap.SugaredLogger
+}
+
+// NewServer creates a server for commands to the window. It does nothing until it's given a listener to serve.
+func NewServer(window Window, logger *zap.SugaredLogger) *Server {
+	s := &Server{
+		rpc:    rpc.NewServer(),
+		logger: logger,
+	}
+	if err := s.rpc.RegisterName("Aminal", &Aminal{window: window}); err != nil {
+		panic(err) // only if Aminal's methods don't suit net/rpc
+	}
+	return s
+}
+
+// Serve answers the connections made to the listener until it is closed
+func (s *Server) Serve(listener net.Listener) error {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		s.logger.Debugf("Control connection opened")
+		go s.rpc.ServeCodec(jsonrpc.NewServerCodec(conn))
+	}
+}
+
+// Listen listens on the socket, replacing one left behind by an Aminal which is no longer running. Only the user
+// can connect to it.
+func Listen(socket string) (net.Listener, error) {
+	return platform.ListenPrivateSocket(socket, connectTimeout)
+}
@@ -0,0 +1,169 @@
+package control
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/liamg/aminal/terminal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// fakeWindow resizes its terminal directly, and keeps what it's sent and the paths of the screenshots it's asked for
+type fakeWindow struct {
+	terminal    *terminal.Terminal
+	lock        sync.Mutex
+	sent        string
+	screenshots []string
+}
+
+func (w *fakeWindow) Terminal() *terminal.Terminal { return w.terminal }
+func (w *fakeWindow) Send(data []byte) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.sent += string(data)
+	return nil
+}
+func (w *fakeWindow) sentToIt() string {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.sent
+}
+func (w *fakeWindow) Resize(cols uint, rows uint) error {
+	return w.terminal.SetSize(cols, rows)
+}
+func (w *fakeWindow) Screenshot(path string) error {
+	w.screenshots = append(w.screenshots, path)
+	return nil
+}
+
+func startServer(t *testing.T) (string, *fakeWindow, *terminal.Headless) {
+	term, err := terminal.NewHeadless(20, 5, terminal.DefaultOptions())
+	require.Nil(t, err)
+	t.Cleanup(func() { term.Close() })
+	window := &fakeWindow{terminal: term.Terminal}
+
+	dir, err := ioutil.TempDir("", "aminal-control")
+	require.Nil(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	socket := filepath.Join(dir, "control.sock")
+	listener, err := Listen(socket)
+	require.Nil(t, err)
+	t.Cleanup(func() { listener.Close() })
+	go NewServer(window, zap.NewNop().Sugar()).Serve(listener)
+	return socket, window, term
+}
+
+func dial(t *testing.T, socket string) *rpc.Client {
+	client, err := jsonrpc.Dial("unix", socket)
+	require.Nil(t, err)
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestSend(t *testing.T) {
+	socket, window, _ := startServer(t)
+	client := dial(t, socket)
+
+	require.Nil(t, client.Call("Aminal.Send", SendArgs{Text: "ls\r"}, &struct{}{}))
+	assert.Equal(t, "ls\r", window.sentToIt())
+}
+
+func TestScreenAndCursor(t *testing.T) {
+	socket, _, term := startServer(t)
+	client := dial(t, socket)
+	term.Feed([]byte("\x1b]2;build\x07$ make\r\nok\r\n$ "))
+
+	var screen Screen
+	require.Nil(t, client.Call("Aminal.Screen", struct{}{}, &screen))
+	assert.Equal(t, Screen{Lines: []string{"$ make", "ok", "$"}, Title: "build", Cols: 20, Rows: 5}, screen)
+
+	var cursor Cursor
+	require.Nil(t, client.Call("Aminal.Cursor", struct{}{}, &cursor))
+	assert.Equal(t, Cursor{Row: 2, Col: 2, Visible: true}, cursor)
+}
+
+func TestResize(t *testing.T) {
+	socket, window, _ := startServer(t)
+	client := dial(t, socket)
+
+	require.Nil(t, client.Call("Aminal.Resize", ResizeArgs{Cols: 30, Rows: 8}, &struct{}{}))
+	cols, rows := window.terminal.GetSize()
+	assert.Equal(t, 30, cols)
+	assert.Equal(t, 8, rows)
+
+	assert.NotNil(t, client.Call("Aminal.Resize", ResizeArgs{Cols: 0, Rows: 8}, &struct{}{}))
+}
+
+func TestScreenshot(t *testing.T) {
+	socket, window, _ := startServer(t)
+	client := dial(t, socket)
+
+	require.Nil(t, client.Call("Aminal.Screenshot", ScreenshotArgs{Path: "screen.png"}, &struct{}{}))
+	assert.Equal(t, []string{"screen.png"}, window.screenshots)
+
+	assert.NotNil(t, client.Call("Aminal.Screenshot", ScreenshotArgs{}, &struct{}{}))
+}
+
+func TestExport(t *testing.T) {
+	socket, _, term := startServer(t)
+	client := dial(t, socket)
+	term.Feed([]byte("\x1b]2;build\x07\x1b[1;31mfail\x1b[0m <1>\r\n"))
+
+	var export Export
+	require.Nil(t, client.Call("Aminal.Export", ExportArgs{Format: "ansi"}, &export))
+	assert.Equal(t, "\x1b[0;1;38;5;1mfail\x1b[0m <1>\n", export.Text)
+
+	require.Nil(t, client.Call("Aminal.Export", ExportArgs{Format: "html", Scrollback: true}, &export))
+	assert.Contains(t, export.Text, "<title>build</title>")
+	assert.Contains(t, export.Text, "font-weight: bold\">fail</span> &lt;1&gt;</pre>")
+
+	assert.NotNil(t, client.Call("Aminal.Export", ExportArgs{Format: "pdf"}, &export))
+}
+
+func TestRawRequests(t *testing.T) {
+	socket, window, _ := startServer(t)
+
+	conn, err := net.Dial("unix", socket)
+	require.Nil(t, err)
+	defer conn.Close()
+	fmt.Fprintf(conn, `{"method": "Aminal.Send", "params": [{"text": "q"}], "id": 7}`)
+
+	var answer struct {
+		ID     int         `json:"id"`
+		Result interface{} `json:"result"`
+		Error  interface{} `json:"error"`
+	}
+	require.Nil(t, json.NewDecoder(conn).Decode(&answer))
+	assert.Equal(t, 7, answer.ID)
+	assert.Nil(t, answer.Error)
+	assert.Equal(t, "q", window.sentToIt())
+}
+
+func TestListenReplacesStaleSockets(t *testing.T) {
+	dir, err := ioutil.TempDir("", "aminal-control")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "control.sock")
+
+	listener, err := Listen(socket)
+	require.Nil(t, err)
+	_, err = Listen(socket)
+	assert.NotNil(t, err, "the socket is in use")
+	listener.(*net.UnixListener).SetUnlinkOnClose(false)
+	listener.Close()
+
+	listener, err = Listen(socket)
+	require.Nil(t, err)
+	listener.Close()
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/liamg/aminal/config"
+	"github.com/liamg/aminal/platform"
+	"github.com/liamg/aminal/session"
+)
+
+// daemonStartTime is how long a daemon started by a window has to begin listening
+const daemonStartTime = 5 * time.Second
+
+// daemonSocket returns where the daemon listens
+func daemonSocket(conf *config.Config) (string, error) {
+	if conf.Daemon.Socket != "" {
+		return conf.Daemon.Socket, nil
+	}
+	return session.SocketPath()
+}
+
+// daemon keeps shells for windows to attach to, until the last of them exits, returning the exit status
+func daemon(conf *config.Config) int {
+	logger, err := getLogger(conf)
+	if err != nil {
+		fmt.Printf("Failed to create logger: %s\n", err)
+		return 1
+	}
+	defer logger.Sync()
+
+	shell, err := getShell(conf)
+	if err != nil {
+		logger.Errorf("Failed to ascertain your shell: %s", err)
+		return 1
+	}
+	setShellEnv(conf)
+
+	socket, err := daemonSocket(conf)
+	if err != nil {
+		logger.Errorf("%s", err)
+		return 1
+	}
+	listener, err := session.Listen(socket)
+	if err != nil {
+		logger.Errorf("%s", err)
+		return 1
+	}
+	defer os.Remove(socket)
+	defer listener.Close()
+
+	start := func(command []string, dir string) (platform.Pty, platform.Process, error) {
+		if len(command) == 0 || command[0] == "" {
+			command = shell
+		}
+		pty, err := platform.NewPty(80, 25)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Failed to allocate pty: %s", err)
+		}
+		process, err := platform.StartCommand(pty, command, dir)
+		if err != nil {
+			pty.Close()
+			return nil, nil, fmt.Errorf("Failed to start %s: %s", command[0], err)
+		}
+		return pty, process, nil
+	}
+
+	server := session.NewServer(start, terminalOptions(conf), logger)
+	go func() {
+		if err := server.Serve(listener); err != nil {
+			logger.Debugf("Stopped listening: %s", err)
+		}
+	}()
+	logger.Infof("Listening for windows on %s", socket)
+
+	<-server.Done()
+	return 0
+}
+
+// startDaemon starts the daemon in the background, unless it's already running, and waits for it to listen
+func startDaemon(conf *config.Config) error {
+	socket, err := daemonSocket(conf)
+	if err != nil {
+		return err
+	}
+	if session.Running(socket) {
+		return nil
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(executable, "--daemon")
+	platform.Detach(cmd)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go cmd.Wait()
+
+	for deadline := time.Now().Add(daemonStartTime); time.Now().Before(deadline); time.Sleep(50 * time.Millisecond) {
+		if session.Running(socket) {
+			return nil
+		}
+	}
+	return fmt.Errorf("The daemon didn't start listening on %s", socket)
+}
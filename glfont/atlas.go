@@ -0,0 +1,91 @@
+package glfont
+
+import (
+	"image"
+
+	"github.com/go-gl/gl/all-core/gl"
+)
+
+// how big each page of the atlas is, in pixels
+const atlasSize = 1024
+
+// atlas packs glyph masks into a few large textures, so a whole string can be drawn from one texture at once
+// rather than binding a texture per character. Glyphs are placed along shelves, left to right, each as tall
+// as the tallest glyph on it.
+type atlas struct {
+	pages     []uint32
+	x         int // where the next glyph goes on the current page
+	y         int
+	rowHeight int
+}
+
+// add uploads a glyph and returns the texture it's in, along with its texture coordinates. Glyphs too big for
+// the atlas are given a texture of their own, and true is returned to say so.
+func (a *atlas) add(img *image.RGBA) (uint32, [4]float32, bool) {
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	// a pixel of padding keeps the neighbours out of the glyph's edges when it's sampled
+	if w+1 > atlasSize || h+1 > atlasSize {
+		return uploadTexture(img), [4]float32{0, 0, 1, 1}, true
+	}
+	if a.x+w+1 > atlasSize {
+		a.x = 0
+		a.y += a.rowHeight
+		a.rowHeight = 0
+	}
+	if len(a.pages) == 0 || a.y+h+1 > atlasSize {
+		a.addPage()
+	}
+
+	page := a.pages[len(a.pages)-1]
+	gl.BindTexture(gl.TEXTURE_2D, page)
+	gl.TexSubImage2D(gl.TEXTURE_2D, 0, int32(a.x), int32(a.y), int32(w), int32(h), gl.RGBA, gl.UNSIGNED_BYTE, gl.Ptr(img.Pix))
+
+	coords := [4]float32{
+		float32(a.x) / atlasSize,
+		float32(a.y) / atlasSize,
+		float32(a.x+w) / atlasSize,
+		float32(a.y+h) / atlasSize,
+	}
+	a.x += w + 1
+	if h+1 > a.rowHeight {
+		a.rowHeight = h + 1
+	}
+	return page, coords, false
+}
+
+func (a *atlas) addPage() {
+	var texture uint32
+	gl.GenTextures(1, &texture)
+	gl.BindTexture(gl.TEXTURE_2D, texture)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.LINEAR)
+	// the padding between glyphs has to be empty, so the page starts out cleared
+	blank := make([]uint8, atlasSize*atlasSize*4)
+	gl.TexImage2D(gl.TEXTURE_2D, 0, gl.RGBA, atlasSize, atlasSize, 0, gl.RGBA, gl.UNSIGNED_BYTE, gl.Ptr(blank))
+
+	a.pages = append(a.pages, texture)
+	a.x, a.y, a.rowHeight = 0, 0, 0
+}
+
+func (a *atlas) free() {
+	for _, page := range a.pages {
+		gl.DeleteTextures(1, &page)
+	}
+	a.pages = nil
+}
+
+// uploadTexture puts a glyph too big for the atlas in a texture of its own
+func uploadTexture(img *image.RGBA) uint32 {
+	var texture uint32
+	gl.GenTextures(1, &texture)
+	gl.BindTexture(gl.TEXTURE_2D, texture)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.LINEAR)
+	gl.TexImage2D(gl.TEXTURE_2D, 0, gl.RGBA, int32(img.Rect.Dx()), int32(img.Rect.Dy()), 0,
+		gl.RGBA, gl.UNSIGNED_BYTE, gl.Ptr(img.Pix))
+	return texture
+}
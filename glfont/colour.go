@@ -0,0 +1,252 @@
+package glfont
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/png"
+)
+
+// colourTables holds the CBLC and CBDT tables of a font with colour bitmap glyphs, such as Noto Color Emoji.
+// Only the largest strike is used, and scaled down to the font size when drawn.
+// See https://docs.microsoft.com/en-us/typography/opentype/spec/cblc
+type colourTables struct {
+	cblc   []byte
+	cbdt   []byte
+	strike []byte // the BitmapSize record of the largest strike
+	ppem   int
+}
+
+// colourGlyph is a bitmap from the CBDT table, with its metrics in pixels at the strike's size
+type colourGlyph struct {
+	image    image.Image
+	width    int
+	height   int
+	bearingX int
+	bearingY int // distance from the baseline up to the top of the bitmap
+	advance  int
+}
+
+// parseColourTables finds the colour bitmap tables in a font file, returning nil if it has none
+func parseColourTables(data []byte) *colourTables {
+	offset := 0
+	if len(data) >= 16 && string(data[:4]) == "ttcf" {
+		// use the first font of a collection
+		offset = int(binary.BigEndian.Uint32(data[12:]))
+	}
+	if len(data) < offset+12 {
+		return nil
+	}
+	tables := &colourTables{}
+	numTables := int(binary.BigEndian.Uint16(data[offset+4:]))
+	for i := 0; i < numTables; i++ {
+		record := offset + 12 + i*16
+		if len(data) < record+16 {
+			return nil
+		}
+		start := int(binary.BigEndian.Uint32(data[record+8:]))
+		length := int(binary.BigEndian.Uint32(data[record+12:]))
+		if start < 0 || length < 0 || start+length > len(data) {
+			return nil
+		}
+		switch string(data[record : record+4]) {
+		case "CBLC":
+			tables.cblc = data[start : start+length]
+		case "CBDT":
+			tables.cbdt = data[start : start+length]
+		}
+	}
+	if tables.cblc == nil || tables.cbdt == nil || len(tables.cblc) < 8 {
+		return nil
+	}
+
+	numSizes := int(binary.BigEndian.Uint32(tables.cblc[4:]))
+	for i := 0; i < numSizes; i++ {
+		size := tables.cblc[8+i*48:]
+		if len(size) < 48 {
+			break
+		}
+		if ppem := int(size[45]); ppem > tables.ppem {
+			tables.ppem = ppem
+			tables.strike = size[:48]
+		}
+	}
+	if tables.strike == nil {
+		return nil
+	}
+	return tables
+}
+
+// has returns true if the strike has a bitmap for the glyph index, without decoding it
+func (t *colourTables) has(index uint16) bool {
+	_, _, _, ok := t.locate(index)
+	return ok
+}
+
+// glyph returns the bitmap for the glyph index, if the strike has one
+func (t *colourTables) glyph(index uint16) (*colourGlyph, bool) {
+	data, imageFormat, metrics, ok := t.locate(index)
+	if !ok {
+		return nil, false
+	}
+
+	glyph := &colourGlyph{}
+	switch imageFormat {
+	case 17:
+		glyph.readSmallMetrics(data.bytes(5))
+	case 18:
+		glyph.readBigMetrics(data.bytes(8))
+	case 19:
+		glyph.readBigMetrics(metrics)
+	}
+	length := int(data.u32())
+	encoded := data.bytes(length)
+	if data.failed {
+		return nil, false
+	}
+	img, err := png.Decode(bytes.NewReader(encoded))
+	if err != nil {
+		return nil, false
+	}
+	glyph.image = img
+	return glyph, true
+}
+
+// locate finds the glyph's entry in the index, returning a reader positioned at its data in the CBDT table,
+// the format of the data, and the metrics the index has for it if any
+func (t *colourTables) locate(index uint16) (*tableReader, uint16, []byte, bool) {
+	arrayOffset := int(binary.BigEndian.Uint32(t.strike[0:]))
+	numSubtables := int(binary.BigEndian.Uint32(t.strike[8:]))
+	for i := 0; i < numSubtables; i++ {
+		entry := arrayOffset + i*8
+		if len(t.cblc) < entry+8 {
+			return nil, 0, nil, false
+		}
+		first := binary.BigEndian.Uint16(t.cblc[entry:])
+		last := binary.BigEndian.Uint16(t.cblc[entry+2:])
+		if index < first || index > last {
+			continue
+		}
+		subtable := arrayOffset + int(binary.BigEndian.Uint32(t.cblc[entry+4:]))
+		return t.locateInSubtable(subtable, index, first)
+	}
+	return nil, 0, nil, false
+}
+
+func (t *colourTables) locateInSubtable(subtable int, index uint16, first uint16) (*tableReader, uint16, []byte, bool) {
+	r := &tableReader{data: t.cblc, offset: subtable}
+	indexFormat := r.u16()
+	imageFormat := r.u16()
+	imageOffset := int(r.u32())
+	i := int(index - first)
+
+	var start, end int
+	var metrics []byte // big glyph metrics from the index, for image format 19
+	switch indexFormat {
+	case 1, 3:
+		// offsets to each glyph's data, with the end of the last one after them
+		entrySize := 4
+		if indexFormat == 3 {
+			entrySize = 2
+		}
+		r.skip(i * entrySize)
+		if entrySize == 4 {
+			start, end = int(r.u32()), int(r.u32())
+		} else {
+			start, end = int(r.u16()), int(r.u16())
+		}
+	case 2:
+		// all glyphs are the same size
+		size := int(r.u32())
+		metrics = r.bytes(8)
+		start, end = i*size, (i+1)*size
+	case 4:
+		// a sparse list of glyph ids and offsets
+		n := int(r.u32())
+		for j := 0; j < n && !r.failed; j++ {
+			id, offset := r.u16(), int(r.u16())
+			if id == index {
+				start = offset
+				r.skip(2)
+				end = int(r.u16())
+				break
+			}
+		}
+	case 5:
+		// a sparse list of glyph ids, all the same size
+		size := int(r.u32())
+		metrics = r.bytes(8)
+		n := int(r.u32())
+		found := false
+		for j := 0; j < n && !r.failed; j++ {
+			if r.u16() == index {
+				start, end, found = j*size, (j+1)*size, true
+				break
+			}
+		}
+		if !found {
+			return nil, 0, nil, false
+		}
+	default:
+		return nil, 0, nil, false
+	}
+	if r.failed || end <= start || imageFormat < 17 || imageFormat > 19 {
+		return nil, 0, nil, false
+	}
+	return &tableReader{data: t.cbdt, offset: imageOffset + start}, imageFormat, metrics, true
+}
+
+func (g *colourGlyph) readSmallMetrics(m []byte) {
+	if len(m) < 5 {
+		return
+	}
+	g.height, g.width = int(m[0]), int(m[1])
+	g.bearingX, g.bearingY = int(int8(m[2])), int(int8(m[3]))
+	g.advance = int(m[4])
+}
+
+func (g *colourGlyph) readBigMetrics(m []byte) {
+	if len(m) < 8 {
+		return
+	}
+	g.height, g.width = int(m[0]), int(m[1])
+	g.bearingX, g.bearingY = int(int8(m[2])), int(int8(m[3]))
+	g.advance = int(m[4])
+}
+
+// tableReader reads big endian values from a font table, remembering if it ran off the end
+type tableReader struct {
+	data   []byte
+	offset int
+	failed bool
+}
+
+func (r *tableReader) bytes(n int) []byte {
+	if r.failed || n < 0 || r.offset < 0 || r.offset+n > len(r.data) {
+		r.failed = true
+		return nil
+	}
+	b := r.data[r.offset : r.offset+n]
+	r.offset += n
+	return b
+}
+
+func (r *tableReader) skip(n int) {
+	r.bytes(n)
+}
+
+func (r *tableReader) u16() uint16 {
+	b := r.bytes(2)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint16(b)
+}
+
+func (r *tableReader) u32() uint32 {
+	b := r.bytes(4)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint32(b)
+}
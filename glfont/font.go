@@ -15,6 +15,9 @@ import (
 
 const DPI = 72
 
+// the number of floats making up each vertex of a glyph quad
+const vertexSize = 9
+
 // A Font allows rendering of text to an OpenGL context.
 type Font struct {
 	characters  map[rune]*character
@@ -22,14 +25,25 @@ type Font struct {
 	vbo         uint32
 	program     uint32
 	texture     uint32 // Holds the glyph texture id.
+	atlas       atlas
+	vertices    []float32 // glyph quads queued to be drawn by Flush
+	batches     []batch
 	color       color
 	ttf         *truetype.Font
 	ttfFace     font.Face
+	colour      *colourTables // colour bitmap glyphs, if the font has them
 	scale       float32
 	linePadding float32
 	lineHeight  float32
 }
 
+// batch is a run of queued quads drawn from the same texture
+type batch struct {
+	texture uint32
+	first   int32
+	count   int32
+}
+
 type color struct {
 	r float32
 	g float32
@@ -58,8 +72,11 @@ func LoadFont(reader io.Reader, scale float32, windowWidth int, windowHeight int
 
 func (f *Font) Free() {
 	for _, chr := range f.characters {
-		gl.DeleteTextures(1, &chr.textureID)
+		if chr.standalone {
+			gl.DeleteTextures(1, &chr.textureID)
+		}
 	}
+	f.atlas.free()
 
 	gl.DeleteBuffers(1, &f.vbo)
 	gl.DeleteVertexArrays(1, &f.vao)
@@ -95,41 +112,33 @@ func (f *Font) LinePadding() float32 {
 	return f.linePadding
 }
 
-// Printf draws a string to the screen, takes a list of arguments like printf
+// Print draws a string to the screen straight away
 func (f *Font) Print(x, y float32, text string) error {
-	indices := []rune(text)
-
-	if len(indices) == 0 {
-		return nil
+	if err := f.Queue(x, y, text); err != nil {
+		return err
 	}
+	f.Flush()
+	return nil
+}
 
-	// setup blending mode
-	gl.Enable(gl.BLEND)
-	gl.BlendFunc(gl.SRC_ALPHA, gl.ONE_MINUS_SRC_ALPHA)
-
-	// Activate corresponding render state
-	gl.UseProgram(f.program)
-	// set text color
-	gl.Uniform4f(gl.GetUniformLocation(f.program, gl.Str("textColor\x00")), f.color.r, f.color.g, f.color.b, f.color.a)
-	// set screen resolution
-	// resUniform := gl.GetUniformLocation(f.program, gl.Str("resolution\x00"))
-	// gl.Uniform2f(resUniform, float32(2560), float32(1440))
-
-	gl.ActiveTexture(gl.TEXTURE0)
-	gl.BindVertexArray(f.vao)
-
-	// Iterate through all characters in string
-	for i := range indices {
-
-		// get rune
-		runeIndex := indices[i]
+// Queue adds a string to be drawn in the current colour the next time the font is flushed, so that a screen
+// full of text can be drawn with a few draw calls rather than one per character
+func (f *Font) Queue(x, y float32, text string) error {
+	r, g, b, a := f.color.r, f.color.g, f.color.b, f.color.a
 
+	for _, runeIndex := range text {
 		// find rune in fontChar list
 		ch, err := f.GetRune(runeIndex)
 		if err != nil {
 			return err // @todo ignore errors?
 		}
 
+		// colour glyphs are drawn as they are, rather than as a mask in the text colour
+		colourGlyph := float32(0)
+		if ch.colour {
+			colourGlyph = 1
+		}
+
 		// calculate position and size for current rune
 		xpos := x + float32(ch.bearingH)
 		ypos := y - float32(+ch.height-ch.bearingV)
@@ -141,42 +150,74 @@ func (f *Font) Print(x, y float32, text string) error {
 		x2 := xpos + w
 		y1 := ypos
 		y2 := ypos + h
-
-		// setup quad array
-		vertices := []float32{
-			//  X, Y, Z, U, V
-			// Front
-			x1, y1, 0.0, 0.0,
-			x2, y1, 1.0, 0.0,
-			x1, y2, 0.0, 1.0,
-			x1, y2, 0.0, 1.0,
-			x2, y1, 1.0, 0.0,
-			x2, y2, 1.0, 1.0,
+		u1, v1, u2, v2 := ch.texCoords[0], ch.texCoords[1], ch.texCoords[2], ch.texCoords[3]
+
+		f.vertices = append(f.vertices,
+			//  X, Y, U, V, colour
+			x1, y1, u1, v1, r, g, b, a, colourGlyph,
+			x2, y1, u2, v1, r, g, b, a, colourGlyph,
+			x1, y2, u1, v2, r, g, b, a, colourGlyph,
+			x1, y2, u1, v2, r, g, b, a, colourGlyph,
+			x2, y1, u2, v1, r, g, b, a, colourGlyph,
+			x2, y2, u2, v2, r, g, b, a, colourGlyph,
+		)
+
+		// consecutive glyphs from the same texture are drawn together
+		if last := len(f.batches) - 1; last >= 0 && f.batches[last].texture == ch.textureID {
+			f.batches[last].count += 6
+		} else {
+			f.batches = append(f.batches, batch{
+				texture: ch.textureID,
+				first:   int32(len(f.vertices)/vertexSize - 6),
+				count:   6,
+			})
 		}
 
-		// Render glyph texture over quad
-		gl.BindTexture(gl.TEXTURE_2D, ch.textureID)
-		// Update content of VBO memory
-		gl.BindBuffer(gl.ARRAY_BUFFER, f.vbo)
-
-		// BufferSubData(target Enum, offset int, data []byte)
-		gl.BufferSubData(gl.ARRAY_BUFFER, 0, len(vertices)*4, gl.Ptr(vertices)) // Be sure to use glBufferSubData and not glBufferData
-		// Render quad
-		gl.DrawArrays(gl.TRIANGLES, 0, 24)
-
-		gl.BindBuffer(gl.ARRAY_BUFFER, 0)
 		// Now advance cursors for next glyph (note that advance is number of 1/64 pixels)
 		x += float32((ch.advance >> 6)) // Bitshift by 6 to get value in pixels (2^6 = 64 (divide amount of 1/64th pixels by 64 to get amount of pixels))
+	}
+
+	return nil
+}
+
+// Pending returns true if there's queued text waiting to be flushed
+func (f *Font) Pending() bool {
+	return len(f.batches) > 0
+}
 
+// Flush draws the text queued since it was last flushed
+func (f *Font) Flush() {
+	if len(f.batches) == 0 {
+		return
 	}
 
+	// setup blending mode
+	gl.Enable(gl.BLEND)
+	// the alpha is accumulated rather than blended, so text over a translucent background is opaque
+	gl.BlendFuncSeparate(gl.SRC_ALPHA, gl.ONE_MINUS_SRC_ALPHA, gl.ONE, gl.ONE_MINUS_SRC_ALPHA)
+
+	// Activate corresponding render state
+	gl.UseProgram(f.program)
+	gl.ActiveTexture(gl.TEXTURE0)
+	gl.BindVertexArray(f.vao)
+
+	// the whole queue goes up in one go, and is drawn a texture at a time
+	gl.BindBuffer(gl.ARRAY_BUFFER, f.vbo)
+	gl.BufferData(gl.ARRAY_BUFFER, len(f.vertices)*4, gl.Ptr(f.vertices), gl.STREAM_DRAW)
+	for _, b := range f.batches {
+		gl.BindTexture(gl.TEXTURE_2D, b.texture)
+		gl.DrawArrays(gl.TRIANGLES, b.first, b.count)
+	}
+
+	f.vertices = f.vertices[:0]
+	f.batches = f.batches[:0]
+
 	// clear opengl textures and programs
+	gl.BindBuffer(gl.ARRAY_BUFFER, 0)
 	gl.BindVertexArray(0)
 	gl.BindTexture(gl.TEXTURE_2D, 0)
 	gl.UseProgram(0)
 	gl.Disable(gl.BLEND)
-
-	return nil
 }
 
 // Width returns the width of a piece of text in pixels
@@ -229,12 +270,32 @@ func (f *Font) MaxY() float32 {
 	return float32(b.Max.Y)
 }
 
+// HasRune returns true if the font has a glyph for r
+func (f *Font) HasRune(r rune) bool {
+	index := f.ttf.Index(r)
+	if index == 0 {
+		return false
+	}
+	if f.colour != nil {
+		return f.colour.has(uint16(index))
+	}
+	return true
+}
+
 func (f *Font) GetRune(r rune) (*character, error) {
 	cc, ok := f.characters[r]
 	if ok {
 		return cc, nil
 	}
 
+	if f.colour != nil {
+		if glyph, ok := f.colour.glyph(uint16(f.ttf.Index(r))); ok {
+			char := f.colourCharacter(glyph)
+			f.characters[r] = char
+			return char, nil
+		}
+	}
+
 	char := new(character)
 
 	gBnd, gAdv, ok := f.ttfFace.GlyphBounds(r)
@@ -295,20 +356,47 @@ func (f *Font) GetRune(r rune) (*character, error) {
 		return nil, err
 	}
 
-	// Generate texture
+	// Add the glyph to the atlas
+	char.textureID, char.texCoords, char.standalone = f.atlas.add(rgba)
+	gl.BindTexture(gl.TEXTURE_2D, 0)
+
+	f.characters[r] = char
+
+	return char, nil
+}
+
+// colourCharacter scales a colour bitmap glyph to the font size and uploads it as a texture
+func (f *Font) colourCharacter(glyph *colourGlyph) *character {
+	scale := f.scale / float32(f.colour.ppem)
+	scaled := func(v int) int {
+		return int(float32(v)*scale + 0.5)
+	}
+
+	char := new(character)
+	char.colour = true
+	char.standalone = true
+	char.texCoords = [4]float32{0, 0, 1, 1}
+	char.width = scaled(glyph.width)
+	char.height = scaled(glyph.height)
+	char.advance = scaled(glyph.advance) << 6
+	char.bearingH = scaled(glyph.bearingX)
+	char.bearingV = char.height - scaled(glyph.bearingY)
+
+	// the texture is left at full size, and mipmaps take care of drawing it smaller
+	rgba := image.NewNRGBA(glyph.image.Bounds().Sub(glyph.image.Bounds().Min))
+	draw.Draw(rgba, rgba.Bounds(), glyph.image, glyph.image.Bounds().Min, draw.Src)
+
 	var texture uint32
 	gl.GenTextures(1, &texture)
 	gl.BindTexture(gl.TEXTURE_2D, texture)
 	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
 	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
 	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
-	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.LINEAR)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.LINEAR_MIPMAP_LINEAR)
 	gl.TexImage2D(gl.TEXTURE_2D, 0, gl.RGBA, int32(rgba.Rect.Dx()), int32(rgba.Rect.Dy()), 0,
 		gl.RGBA, gl.UNSIGNED_BYTE, gl.Ptr(rgba.Pix))
+	gl.GenerateMipmap(gl.TEXTURE_2D)
 
 	char.textureID = texture
-
-	f.characters[r] = char
-
-	return char, nil
+	return char
 }
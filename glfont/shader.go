@@ -69,15 +69,20 @@ func compileShader(source string, shaderType uint32) (uint32, error) {
 
 var fragmentFontShader = `#version 150 core
 in vec2 fragTexCoord;
+in vec4 fragColour;
+in float fragColourGlyph;
 out vec4 outputColor;
 
 uniform sampler2D tex;
-uniform vec4 textColor;
 
 void main()
 {    
+    if (fragColourGlyph > 0.5) {
+        outputColor = texture(tex, fragTexCoord) * vec4(1.0, 1.0, 1.0, fragColour.a);
+        return;
+    }
     vec4 sampled = vec4(1.0, 1.0, 1.0, texture(tex, fragTexCoord).r);
-    outputColor = textColor * sampled;
+    outputColor = fragColour * sampled;
 }` + "\x00"
 
 var vertexFontShader = `#version 150 core
@@ -88,11 +93,17 @@ in vec2 vert;
 //pass through to fragTexCoord
 in vec2 vertTexCoord;
 
+//text colour, and whether the glyph is drawn as it is instead
+in vec4 vertColour;
+in float vertColourGlyph;
+
 //window res
 uniform vec2 resolution;
 
 //pass to frag
 out vec2 fragTexCoord;
+out vec4 fragColour;
+out float fragColourGlyph;
 
 void main() {
    // convert the rectangle from pixels to 0.0 to 1.0
@@ -105,6 +116,8 @@ void main() {
    vec2 clipSpace = zeroToTwo - 1.0;
 
    fragTexCoord = vertTexCoord;
+   fragColour = vertColour;
+   fragColourGlyph = vertColourGlyph;
 
    gl_Position = vec4(clipSpace * vec2(1, -1), 0, 1);
 }` + "\x00"
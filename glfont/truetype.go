@@ -10,12 +10,15 @@ import (
 )
 
 type character struct {
-	textureID uint32 // ID handle of the glyph texture
-	width     int    // glyph width
-	height    int    // glyph height
-	advance   int    // glyph advance
-	bearingH  int    // glyph bearing horizontal
-	bearingV  int    // glyph bearing vertical
+	textureID  uint32     // ID handle of the glyph texture
+	texCoords  [4]float32 // left, top, right and bottom of the glyph within its texture
+	standalone bool       // the texture is the glyph's own, rather than a page of the atlas
+	width      int        // glyph width
+	height     int        // glyph height
+	advance    int        // glyph advance
+	bearingH   int        // glyph bearing horizontal
+	bearingV   int        // glyph bearing vertical
+	colour     bool       // the texture is a colour image rather than a mask
 }
 
 // LoadTrueTypeFont builds a set of textures based on a ttf files glyphs
@@ -35,6 +38,7 @@ func LoadTrueTypeFont(program uint32, r io.Reader, scale float32) (*Font, error)
 	if err != nil {
 		return nil, err
 	}
+	f.colour = parseColourTables(data)
 	f.SetColor(1.0, 1.0, 1.0, 1.0) // set default white
 
 	_, h := f.MaxSize()
@@ -48,17 +52,17 @@ func LoadTrueTypeFont(program uint32, r io.Reader, scale float32) (*Font, error)
 	gl.BindVertexArray(f.vao)
 	gl.BindBuffer(gl.ARRAY_BUFFER, f.vbo)
 
-	gl.BufferData(gl.ARRAY_BUFFER, 6*4*4, nil, gl.STATIC_DRAW)
-
-	vertAttrib := uint32(gl.GetAttribLocation(f.program, gl.Str("vert\x00")))
-	gl.EnableVertexAttribArray(vertAttrib)
-	gl.VertexAttribPointer(vertAttrib, 2, gl.FLOAT, false, 4*4, gl.PtrOffset(0))
-	defer gl.DisableVertexAttribArray(vertAttrib)
-
-	texCoordAttrib := uint32(gl.GetAttribLocation(f.program, gl.Str("vertTexCoord\x00")))
-	gl.EnableVertexAttribArray(texCoordAttrib)
-	gl.VertexAttribPointer(texCoordAttrib, 2, gl.FLOAT, false, 4*4, gl.PtrOffset(2*4))
-	defer gl.DisableVertexAttribArray(texCoordAttrib)
+	// each vertex is a position, texture coordinates, a colour and whether the glyph is in colour
+	stride := int32(vertexSize * 4)
+	attrib := func(name string, size int32, offset int) {
+		location := uint32(gl.GetAttribLocation(f.program, gl.Str(name+"\x00")))
+		gl.EnableVertexAttribArray(location)
+		gl.VertexAttribPointer(location, size, gl.FLOAT, false, stride, gl.PtrOffset(offset*4))
+	}
+	attrib("vert", 2, 0)
+	attrib("vertTexCoord", 2, 2)
+	attrib("vertColour", 4, 4)
+	attrib("vertColourGlyph", 1, 8)
 
 	gl.BindBuffer(gl.ARRAY_BUFFER, 0)
 	gl.BindVertexArray(0)
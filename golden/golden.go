@@ -0,0 +1,134 @@
+// Package golden compares rendered images with golden PNGs kept with the tests, allowing for the small
+// differences in how GPUs and drivers antialias text
+package golden
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Tolerance is how far an image may be from the golden one and still match it
+type Tolerance struct {
+	Channel uint8   // how far a red, green, blue or alpha value may be from the golden one before the pixel differs
+	Pixels  float64 // the fraction of pixels which may differ
+}
+
+// DefaultTolerance allows for antialiasing, but not for a glyph or a cell's colour changing
+var DefaultTolerance = Tolerance{Channel: 24, Pixels: 0.002}
+
+// Diff is how an image differs from a golden one of the same size
+type Diff struct {
+	Pixels int         // how many differ
+	Total  int         // how many there are
+	Image  *image.RGBA // the differing pixels in red, over a faded copy of the golden image
+}
+
+// Compare compares the image with the golden one pixel by pixel, returning the differences, or an error if
+// they aren't the same size
+func Compare(img image.Image, golden image.Image, tolerance Tolerance) (*Diff, error) {
+	bounds := golden.Bounds()
+	if img.Bounds().Size() != bounds.Size() {
+		return nil, fmt.Errorf("The image is %v but the golden image is %v", img.Bounds().Size(), bounds.Size())
+	}
+	offset := img.Bounds().Min.Sub(bounds.Min)
+
+	diff := &Diff{
+		Total: bounds.Dx() * bounds.Dy(),
+		Image: image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy())),
+	}
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			want := color.RGBAModel.Convert(golden.At(x, y)).(color.RGBA)
+			got := color.RGBAModel.Convert(img.At(x+offset.X, y+offset.Y)).(color.RGBA)
+			at := image.Pt(x-bounds.Min.X, y-bounds.Min.Y)
+			if differs(got, want, tolerance.Channel) {
+				diff.Pixels++
+				diff.Image.SetRGBA(at.X, at.Y, color.RGBA{255, 0, 0, 255})
+				continue
+			}
+			grey := uint8((uint16(want.R) + uint16(want.G) + uint16(want.B)) / 3 / 4)
+			diff.Image.SetRGBA(at.X, at.Y, color.RGBA{grey, grey, grey, 255})
+		}
+	}
+	return diff, nil
+}
+
+// Within returns true if few enough pixels differ for the tolerance
+func (d *Diff) Within(tolerance Tolerance) bool {
+	return float64(d.Pixels) <= tolerance.Pixels*float64(d.Total)
+}
+
+func differs(a color.RGBA, b color.RGBA, tolerance uint8) bool {
+	for _, pair := range [][2]uint8{{a.R, b.R}, {a.G, b.G}, {a.B, b.B}, {a.A, b.A}} {
+		d := int(pair[0]) - int(pair[1])
+		if d < -int(tolerance) || d > int(tolerance) {
+			return true
+		}
+	}
+	return false
+}
+
+// Check compares the image with the golden PNG at path. With update, or when there's no golden image yet, the
+// image is saved as the golden one instead, returning true. When they differ by more than the tolerance, the
+// image and the differences are saved beside the golden one, as <name>.actual.png and <name>.diff.png.
+func Check(img image.Image, path string, tolerance Tolerance, update bool) (bool, error) {
+	if _, err := os.Stat(path); update || os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return false, err
+		}
+		return true, Save(img, path)
+	}
+
+	golden, err := Load(path)
+	if err != nil {
+		return false, err
+	}
+	diff, err := Compare(img, golden, tolerance)
+	if err != nil {
+		return false, fmt.Errorf("%s: %s", path, err)
+	}
+	if diff.Within(tolerance) {
+		return false, nil
+	}
+
+	base := strings.TrimSuffix(path, filepath.Ext(path))
+	if err := Save(img, base+".actual.png"); err != nil {
+		return false, err
+	}
+	if err := Save(diff.Image, base+".diff.png"); err != nil {
+		return false, err
+	}
+	return false, fmt.Errorf("%d of %d pixels differ from %s, see %s.diff.png", diff.Pixels, diff.Total, path, base)
+}
+
+// Load reads a PNG
+func Load(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	img, err := png.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read %s: %s", path, err)
+	}
+	return img, nil
+}
+
+// Save writes the image as a PNG
+func Save(img image.Image, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
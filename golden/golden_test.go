@@ -0,0 +1,73 @@
+package golden
+
+import (
+	"image"
+	"image/color"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func filled(w int, h int, c color.RGBA) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.SetRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+func TestCompare(t *testing.T) {
+	golden := filled(10, 10, color.RGBA{100, 100, 100, 255})
+	img := filled(10, 10, color.RGBA{110, 95, 100, 255})
+	img.SetRGBA(3, 4, color.RGBA{255, 255, 255, 255})
+
+	diff, err := Compare(img, golden, Tolerance{Channel: 10, Pixels: 0.01})
+	require.Nil(t, err)
+	assert.Equal(t, 1, diff.Pixels)
+	assert.Equal(t, 100, diff.Total)
+	assert.Equal(t, color.RGBA{255, 0, 0, 255}, diff.Image.RGBAAt(3, 4))
+	assert.True(t, diff.Within(Tolerance{Pixels: 0.01}))
+	assert.False(t, diff.Within(Tolerance{Pixels: 0}))
+
+	diff, err = Compare(img, golden, Tolerance{Channel: 5})
+	require.Nil(t, err)
+	assert.Equal(t, 100, diff.Pixels)
+
+	_, err = Compare(filled(10, 11, color.RGBA{}), golden, DefaultTolerance)
+	assert.NotNil(t, err)
+}
+
+func TestCheck(t *testing.T) {
+	dir, err := ioutil.TempDir("", "aminal-golden")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "testdata", "prompt.png")
+
+	golden := filled(4, 4, color.RGBA{0, 0, 0, 255})
+	recorded, err := Check(golden, path, DefaultTolerance, false)
+	require.Nil(t, err)
+	assert.True(t, recorded, "there was no golden image")
+
+	recorded, err = Check(golden, path, DefaultTolerance, false)
+	require.Nil(t, err)
+	assert.False(t, recorded)
+
+	changed := filled(4, 4, color.RGBA{200, 0, 0, 255})
+	_, err = Check(changed, path, DefaultTolerance, false)
+	assert.NotNil(t, err)
+	assert.FileExists(t, filepath.Join(dir, "testdata", "prompt.actual.png"))
+	assert.FileExists(t, filepath.Join(dir, "testdata", "prompt.diff.png"))
+
+	recorded, err = Check(changed, path, DefaultTolerance, true)
+	require.Nil(t, err)
+	assert.True(t, recorded)
+	saved, err := Load(path)
+	require.Nil(t, err)
+	assert.Equal(t, color.RGBA{200, 0, 0, 255}, color.RGBAModel.Convert(saved.At(1, 1)))
+}
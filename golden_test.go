@@ -0,0 +1,87 @@
+// +build linux
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/liamg/aminal/config"
+	"github.com/liamg/aminal/golden"
+	"github.com/liamg/aminal/gui"
+	"github.com/liamg/aminal/terminal"
+	"go.uber.org/zap"
+)
+
+var updateGolden = flag.Bool("update-golden", false, "Save the rendered frames as the golden images instead of comparing them")
+
+// goldenScreens are drawn from a reset terminal, and compared with testdata/golden/<name>.png
+var goldenScreens = []struct {
+	name   string
+	output string
+}{
+	{"text", "$ ls\r\nbin  config  gui  main.go  README.md\r\n$ "},
+	{"attributes", "\x1b[1mbold\x1b[0m \x1b[3mitalic\x1b[0m \x1b[4munderline\x1b[0m \x1b[7mreverse\x1b[0m \x1b[9mstrike\x1b[0m \x1b[2mfaint\x1b[0m"},
+	{"colours", "\x1b[30m0\x1b[31m1\x1b[32m2\x1b[33m3\x1b[34m4\x1b[35m5\x1b[36m6\x1b[37m7\x1b[0m\r\n" +
+		"\x1b[40m 0 \x1b[41m 1 \x1b[42m 2 \x1b[43m 3 \x1b[44m 4 \x1b[45m 5 \x1b[46m 6 \x1b[47m 7 \x1b[0m\r\n" +
+		"\x1b[100m 8 \x1b[101m 9 \x1b[102m10 \x1b[103m11 \x1b[104m12 \x1b[105m13 \x1b[106m14 \x1b[107m15 \x1b[0m\r\n" +
+		"\x1b[48;5;208m 208 \x1b[48;5;240m 240 \x1b[38;2;255;128;0;48;2;0;64;128m truecolor \x1b[0m"},
+	{"lines", "\x1b(0lqqqwqqqk\r\nx   x   x\r\ntqqqnqqqu\r\nmqqqvqqqj\x1b(B\r\n┌──┬──┐ ╔══╗ ▀▄█░▒▓"},
+	{"wide", "日本語のテキスト\r\nwide: 한국어 中文"},
+	{"hidden-cursor", "\x1b[?25lno cursor here"},
+}
+
+// TestGoldenRendering draws known screens offscreen and compares them with the golden images, which are saved
+// the first time, or with -update-golden, to catch changes to how the renderer draws them
+func TestGoldenRendering(t *testing.T) {
+	runMain(func() {
+		conf := config.DefaultConfig
+		conf.Cursor.BlinkInterval = 0
+		conf.Window.Width = 800
+		conf.Window.Height = 300
+
+		term, err := terminal.NewHeadless(80, 10, terminalOptions(&conf))
+		if err != nil {
+			os.Exit(terminate(fmt.Sprintf("Failed to create terminal: %s\n", err)))
+		}
+		defer term.Close()
+		g, err := gui.New(&conf, term.Terminal, zap.NewNop().Sugar())
+		if err != nil {
+			os.Exit(terminate(fmt.Sprintf("Failed to create GUI: %s\n", err)))
+		}
+
+		failures := []string{}
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			defer g.Close()
+			for _, screen := range goldenScreens {
+				term.Feed([]byte("\x1bc" + screen.output))
+				img, err := g.Capture()
+				if err != nil {
+					failures = append(failures, fmt.Sprintf("%s: %s", screen.name, err))
+					return
+				}
+				path := filepath.Join("testdata", "golden", screen.name+".png")
+				recorded, err := golden.Check(img, path, golden.DefaultTolerance, *updateGolden)
+				if err != nil {
+					failures = append(failures, fmt.Sprintf("%s: %s", screen.name, err))
+				} else if recorded {
+					fmt.Printf("Saved %s as the golden image\n", path)
+				}
+			}
+		}()
+
+		if err := g.Render(); err != nil {
+			os.Exit(terminate(fmt.Sprintf("Render error: %s\n", err)))
+		}
+		<-done
+		if len(failures) > 0 {
+			os.Exit(terminate(strings.Join(failures, "\n") + "\n"))
+		}
+	})
+}
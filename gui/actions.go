@@ -9,26 +9,118 @@ import (
 )
 
 var actionMap = map[config.UserAction]func(gui *GUI){
-	config.ActionCopy:        actionCopy,
-	config.ActionPaste:       actionPaste,
-	config.ActionToggleDebug: actionToggleDebug,
-	config.ActionSearch:      actionSearchSelection,
-	config.ActionToggleSlomo: actionToggleSlomo,
-	config.ActionReportBug:   actionReportBug,
+	config.ActionCopy:         actionCopy,
+	config.ActionCopyAs:       actionCopyAs,
+	config.ActionCopyHTML:     actionCopyHTML,
+	config.ActionCopyRTF:      actionCopyRTF,
+	config.ActionCopyShell:    actionCopyShell,
+	config.ActionPaste:        actionPaste,
+	config.ActionToggleDebug:  actionToggleDebug,
+	config.ActionSearch:       actionSearchSelection,
+	config.ActionToggleSlomo:  actionToggleSlomo,
+	config.ActionReportBug:    actionReportBug,
+	config.ActionFilter:       actionFilter,
+	config.ActionBookmark:     actionAddBookmark,
+	config.ActionBookmarks:    actionListBookmarks,
+	config.ActionNextBookmark: actionNextBookmark,
+	config.ActionPrevBookmark: actionPreviousBookmark,
+	config.ActionOpenWith:     actionOpenWith,
+	config.ActionPasteLine:    actionPasteSingleLine,
+	config.ActionToggleDiff:   actionToggleDiff,
+	config.ActionDiffBaseline: actionSetDiffBaseline,
+	config.ActionTimeTravel:   actionTimeTravel,
+	config.ActionToggleShare:  actionToggleShare,
+	config.ActionExportSVG:    actionExportSVG,
+	config.ActionExportClip:   actionExportClip,
+	config.ActionExportHTML:   actionExportHTML,
+	config.ActionExportANSI:   actionExportANSI,
+	config.ActionFontPicker:   actionFontPicker,
+	config.ActionThemePicker:  actionThemePicker,
+	config.ActionSettings:     actionSettings,
+	config.ActionFind:         actionFind,
+	config.ActionLinkHints:    actionLinkHints,
+	config.ActionPrevPrompt:   actionPreviousPrompt,
+	config.ActionNextPrompt:   actionNextPrompt,
+	config.ActionCopyOutput:   actionCopyLastOutput,
+	config.ActionRerun:        actionRerunLastCommand,
+	config.ActionNewTab:       actionNewTab,
+	config.ActionSSH:          actionSSH,
+	config.ActionProfiles:     actionProfiles,
+	config.ActionNewWindow:    actionNewWindow,
+	config.ActionCloseTab:     actionCloseTab,
+	config.ActionNextTab:      actionNextTab,
+	config.ActionPrevTab:      actionPreviousTab,
+	config.ActionSplitRight:   actionSplitRight,
+	config.ActionSplitDown:    actionSplitDown,
+	config.ActionClosePane:    actionClosePane,
+	config.ActionPaneLeft:     actionPaneLeft,
+	config.ActionPaneRight:    actionPaneRight,
+	config.ActionPaneUp:       actionPaneUp,
+	config.ActionPaneDown:     actionPaneDown,
+	config.ActionResizeLeft:   actionResizePaneLeft,
+	config.ActionResizeRight:  actionResizePaneRight,
+	config.ActionResizeUp:     actionResizePaneUp,
+	config.ActionResizeDown:   actionResizePaneDown,
+	config.ActionClearHistory: actionClearScrollback,
+	config.ActionClearAll:     actionClearAll,
+	config.ActionReset:        actionReset,
+	config.ActionScrollUp:     actionScrollPageUp,
+	config.ActionScrollDown:   actionScrollPageDown,
+	config.ActionScrollBottom: actionScrollToBottom,
+	config.ActionFontBigger:   actionFontBigger,
+	config.ActionFontSmaller:  actionFontSmaller,
+	config.ActionFontReset:    actionFontReset,
+	config.ActionRecordMacro:  actionRecordMacro,
+	config.ActionMacros:       actionMacros,
+	config.ActionMoreOpaque:   actionMoreOpaque,
+	config.ActionLessOpaque:   actionLessOpaque,
+	config.ActionMinimap:      actionToggleMinimap,
 }
 
 func actionCopy(gui *GUI) {
-	selectedText := gui.terminal.ActiveBuffer().GetSelectedText()
+	selectedText := gui.selectedText()
 
 	if selectedText != "" {
-		gui.window.SetClipboardString(selectedText)
+		gui.setSelection(selectionClipboard, selectedText)
 	}
 }
 
 func actionPaste(gui *GUI) {
-	if s := gui.window.GetClipboardString(); s != "" {
-		_ = gui.terminal.Paste([]byte(s))
+	gui.paste(gui.getSelection(selectionClipboard), false)
+}
+
+func actionPasteSingleLine(gui *GUI) {
+	gui.paste(gui.getSelection(selectionClipboard), true)
+}
+
+// paste sends text to the terminal after applying the configured paste transformations
+func (gui *GUI) paste(text string, singleLine bool) {
+	text = gui.config.Paste.Transform(text, singleLine)
+	if text == "" {
+		return
+	}
+	t := gui.terminal
+	if !t.IsBracketedPasteModeEnabled() && gui.config.Paste.NeedsConfirmation(text) {
+		// without bracketed paste the shell can't tell the paste from typing, so each line would run as a command
+		gui.setOverlay(newConfirmation(
+			pasteQuestion(text),
+			func(gui *GUI, yes bool) {
+				if yes {
+					gui.queueWrite(t, t.PasteSequence([]byte(text)))
+				}
+			},
+		))
+		return
+	}
+	gui.queueWrite(t, t.PasteSequence([]byte(text)))
+}
+
+// pasteQuestion asks whether to go ahead with pasting text which needs confirmation
+func pasteQuestion(text string) string {
+	if lines := strings.Count(strings.Replace(text, "\r\n", "\n", -1), "\n") + 1; lines > 1 {
+		return fmt.Sprintf("Paste %d lines, which may run as commands?", lines)
 	}
+	return "Paste text containing control characters?"
 }
 
 func actionToggleDebug(gui *GUI) {
@@ -37,16 +129,61 @@ func actionToggleDebug(gui *GUI) {
 }
 
 func actionSearchSelection(gui *GUI) {
-	keywords := gui.terminal.ActiveBuffer().GetSelectedText()
-	if keywords != "" && gui.config.SearchURL != "" && strings.Contains(gui.config.SearchURL, "$QUERY") {
-		gui.launchTarget(fmt.Sprintf(strings.Replace(gui.config.SearchURL, "$QUERY", "%s", 1), url.QueryEscape(keywords)))
+	keywords := gui.selectedText()
+	if keywords != "" && isValidSearchURL(gui.config.SearchURL) {
+		gui.launchTarget(strings.Replace(gui.config.SearchURL, "$QUERY", url.QueryEscape(keywords), 1))
 	}
 }
 
 func actionToggleSlomo(gui *GUI) {
 	gui.config.Slomo = !gui.config.Slomo
+	gui.terminal.SetSlomo(gui.config.Slomo)
 }
 
 func actionReportBug(gui *GUI) {
 	gui.launchTarget("https://github.com/liamg/aminal/issues/new/choose")
 }
+
+func actionFilter(gui *GUI) {
+	gui.setOverlay(newFilterView())
+}
+
+func actionScrollPageUp(gui *GUI) {
+	gui.terminal.ScrollPageUp()
+}
+
+func actionScrollPageDown(gui *GUI) {
+	gui.terminal.ScrollPageDown()
+}
+
+func actionScrollToBottom(gui *GUI) {
+	gui.terminal.ScrollToEnd()
+}
+
+func actionFontBigger(gui *GUI) {
+	gui.setFontScale(gui.fontScale + 1)
+	gui.rememberFontScale()
+}
+
+func actionFontSmaller(gui *GUI) {
+	gui.setFontScale(gui.fontScale - 1)
+	gui.rememberFontScale()
+}
+
+func actionMoreOpaque(gui *GUI) {
+	gui.setBackgroundOpacity(gui.terminalAlpha + backgroundOpacityStep)
+}
+
+func actionLessOpaque(gui *GUI) {
+	gui.setBackgroundOpacity(gui.terminalAlpha - backgroundOpacityStep)
+}
+
+func actionFontReset(gui *GUI) {
+	if gui.config.Font.RememberSize {
+		// the size in the config is the one being remembered, so go back to the size the text starts at
+		gui.setFontScale(defaultFontScale)
+		gui.rememberFontScale()
+		return
+	}
+	gui.setFontScale(configuredFontScale(gui.config))
+}
@@ -0,0 +1,22 @@
+//+build darwin
+
+package gui
+
+/*
+#cgo darwin CFLAGS: -x objective-c
+#cgo darwin LDFLAGS: -framework Cocoa
+#include <Cocoa/Cocoa.h>
+void cocoa_beep(void) {
+	NSBeep();
+}
+*/
+import "C"
+
+import (
+	"github.com/go-gl/glfw/v3.3/glfw"
+)
+
+// beep plays the alert sound chosen in the system preferences
+func beep(window *glfw.Window) {
+	C.cocoa_beep()
+}
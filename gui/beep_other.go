@@ -0,0 +1,9 @@
+// +build !darwin,!linux,!freebsd,!windows wayland
+
+package gui
+
+import "github.com/go-gl/glfw/v3.3/glfw"
+
+// beep does nothing, as there's no alert sound to play here. The bell's command can play one instead.
+func beep(window *glfw.Window) {
+}
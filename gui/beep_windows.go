@@ -0,0 +1,16 @@
+package gui
+
+/*
+#include <windows.h>
+static void win32_beep(void) {
+	MessageBeep(MB_OK);
+}
+*/
+import "C"
+
+import "github.com/go-gl/glfw/v3.3/glfw"
+
+// beep plays the default sound from the system's sound scheme
+func beep(window *glfw.Window) {
+	C.win32_beep()
+}
@@ -0,0 +1,24 @@
+// +build linux,!wayland freebsd,!wayland
+
+package gui
+
+/*
+#cgo LDFLAGS: -lX11
+#include <X11/Xlib.h>
+static void x11_beep(void *display) {
+	XBell(display, 0);
+	XFlush(display);
+}
+*/
+import "C"
+
+import (
+	"unsafe"
+
+	"github.com/go-gl/glfw/v3.3/glfw"
+)
+
+// beep rings X's bell, which the desktop may turn into a sound of its own
+func beep(window *glfw.Window) {
+	C.x11_beep(unsafe.Pointer(glfw.GetX11Display()))
+}
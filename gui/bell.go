@@ -0,0 +1,63 @@
+package gui
+
+import (
+	"time"
+
+	"github.com/liamg/aminal/platform"
+)
+
+// bellFlashDuration is how long the screen is inverted for by the visual bell, and how often the bell's sound and
+// command can go off, so a stream of bells doesn't pile them up
+const bellFlashDuration = 100 * time.Millisecond
+
+// ringBell answers the bells rung in every tab since they were last checked, in the ways the config asks for
+func (gui *GUI) ringBell() {
+	rung, rungHere := false, false
+	for i, tab := range gui.tabs {
+		for _, p := range tab.root.leaves() {
+			if !p.terminal.CheckBell() {
+				continue
+			}
+			rung = true
+			if i == gui.activeTab {
+				rungHere = true
+			} else if gui.config.Bell.Urgent {
+				tab.bell = true
+			}
+		}
+	}
+	if !rung {
+		return
+	}
+
+	bell := gui.config.Bell
+	now := time.Now()
+	if bell.Flash && rungHere {
+		gui.flashUntil = now.Add(bellFlashDuration)
+		time.AfterFunc(bellFlashDuration, gui.terminal.SetDirty)
+		gui.terminal.SetDirty()
+	}
+	if now.Sub(gui.lastBell) >= bellFlashDuration {
+		gui.lastBell = now
+		if bell.Sound {
+			beep(gui.window)
+		}
+		if bell.Command != "" {
+			go func(command string) {
+				if err := platform.RunCommand(command); err != nil {
+					gui.logger.Errorf("Bell command '%s' failed: %s", command, err)
+				}
+			}(bell.Command)
+		}
+	}
+	if bell.Attention && !gui.windowFocused {
+		gui.window.RequestAttention()
+	}
+}
+
+// renderBell inverts the screen while the visual bell is flashing
+func (gui *GUI) renderBell() {
+	if time.Now().Before(gui.flashUntil) {
+		gui.renderer.Invert()
+	}
+}
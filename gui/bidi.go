@@ -0,0 +1,43 @@
+package gui
+
+import (
+	"github.com/liamg/aminal/buffer"
+	"github.com/liamg/aminal/terminal"
+)
+
+// visualRows returns the cells of each line in the order they're shown, which is only different for lines with
+// right to left text when the terminal lays them out with the bidirectional algorithm. For those, from gives the
+// column each cell shown comes from, so highlights and the cursor can follow the text.
+func (gui *GUI) visualRows(t *terminal.Terminal, lines []buffer.Line, cols int) (rows [][]buffer.Cell, from [][]int) {
+	modes := t.Modes()
+	rows = make([][]buffer.Cell, len(lines))
+	from = make([][]int, len(lines))
+	for y := range lines {
+		rows[y] = lines[y].Cells()
+		if !modes.Bidi {
+			continue
+		}
+		if cells, columns := lines[y].VisualCells(cols, *gui.defaultCell, modes.BidiDirection); cells != nil {
+			rows[y], from[y] = cells, columns
+		}
+	}
+	return rows, from
+}
+
+// logicalColumn returns the column the cell shown in column x of a row comes from
+func logicalColumn(from []int, x int) int {
+	if x < len(from) {
+		return from[x]
+	}
+	return x
+}
+
+// visualColumn returns the column the cell in column x of a row is shown in
+func visualColumn(from []int, x uint) uint {
+	for i, col := range from {
+		if col == int(x) {
+			return uint(i)
+		}
+	}
+	return x
+}
@@ -0,0 +1,46 @@
+package gui
+
+import (
+	"fmt"
+	"runtime"
+
+	"github.com/liamg/aminal/config"
+	"github.com/liamg/aminal/platform"
+)
+
+// newKeyBindings parses the bindings for this platform, checking the actions they run exist
+func newKeyBindings(bindings []config.BindingConfig) ([]config.KeyBinding, error) {
+	keyBindings, err := config.GenerateBindings(bindings, runtime.GOOS)
+	if err != nil {
+		return nil, err
+	}
+	for _, binding := range keyBindings {
+		for _, step := range binding.Steps {
+			if _, ok := actionMap[step.Action]; step.Action != "" && !ok {
+				return nil, fmt.Errorf("Unknown action '%s' in key binding", step.Action)
+			}
+		}
+	}
+	return keyBindings, nil
+}
+
+// runBinding runs the steps of a binding in order, so e.g. new_tab followed by send types into the new tab.
+// Commands run in the background, with the selection at the time in $AMINAL_SELECTION.
+func (gui *GUI) runBinding(binding config.KeyBinding) {
+	for _, step := range binding.Steps {
+		switch {
+		case step.Send != "":
+			gui.writeInput([]byte(step.Send))
+		case step.Macro != "":
+			gui.playMacro(step.Macro)
+		case step.Command != "":
+			go func(command string, selection string) {
+				if err := platform.RunCommand(command, "AMINAL_SELECTION="+selection); err != nil {
+					gui.logger.Errorf("Key binding command '%s' failed: %s", command, err)
+				}
+			}(step.Command, gui.selectedText())
+		default:
+			actionMap[step.Action](gui)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package gui
+
+import (
+	"time"
+
+	"github.com/liamg/aminal/buffer"
+)
+
+// how long blinking text (SGR 5) is shown for, then hidden for
+const textBlinkInterval = 500 * time.Millisecond
+
+// textVisible returns false if the cell's text shouldn't be drawn, as it's concealed or blinking and in the off phase
+func (gui *GUI) textVisible(cell *buffer.Cell) bool {
+	attr := cell.Attr()
+	return !attr.Hidden && (!attr.Blink || gui.blinkOn)
+}
+
+// showsBlinkingText returns true if any terminal in the active tab has blinking text on screen, so the blink
+// timer only causes redraws when there's something to blink
+func (gui *GUI) showsBlinkingText() bool {
+	for _, p := range gui.currentTab().root.leaves() {
+		for _, line := range p.terminal.GetVisibleLines() {
+			for _, cell := range line.Cells() {
+				if cell.Attr().Blink {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,34 @@
+//+build darwin
+
+package gui
+
+/*
+#cgo darwin CFLAGS: -x objective-c
+#cgo darwin LDFLAGS: -framework Cocoa
+#include <Cocoa/Cocoa.h>
+typedef void *CGSConnection;
+extern CGSConnection CGSDefaultConnectionForThread(void);
+extern int CGSSetWindowBackgroundBlurRadius(CGSConnection connection, NSInteger window, int radius);
+void cocoa_set_blur(void *id, int radius) {
+	NSWindow *window = id;
+	CGSSetWindowBackgroundBlurRadius(CGSDefaultConnectionForThread(), [window windowNumber], radius);
+}
+*/
+import "C"
+
+import (
+	"github.com/go-gl/glfw/v3.3/glfw"
+)
+
+// blurRadius is how much what's behind the window is blurred, in points
+const blurRadius = 20
+
+// setBlur blurs what's behind the window, with the same private call other terminals use as Cocoa has no
+// public one for windows
+func setBlur(window *glfw.Window, enable bool) {
+	radius := 0
+	if enable {
+		radius = blurRadius
+	}
+	C.cocoa_set_blur(window.GetCocoaWindow(), C.int(radius))
+}
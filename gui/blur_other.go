@@ -0,0 +1,9 @@
+// +build !darwin,!linux,!freebsd,!windows wayland
+
+package gui
+
+import "github.com/go-gl/glfw/v3.3/glfw"
+
+// setBlur does nothing, as there's no way to ask the compositor here for blur
+func setBlur(window *glfw.Window, enable bool) {
+}
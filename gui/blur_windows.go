@@ -0,0 +1,29 @@
+package gui
+
+/*
+#cgo LDFLAGS: -ldwmapi
+#include <windows.h>
+#include <dwmapi.h>
+static void win32_set_blur(void *window, int enable) {
+	DWM_BLURBEHIND blur = {0};
+	blur.dwFlags = DWM_BB_ENABLE;
+	blur.fEnable = enable;
+	DwmEnableBlurBehindWindow((HWND)window, &blur);
+}
+*/
+import "C"
+
+import (
+	"unsafe"
+
+	"github.com/go-gl/glfw/v3.3/glfw"
+)
+
+// setBlur asks the desktop window manager to blur what's behind the window
+func setBlur(window *glfw.Window, enable bool) {
+	e := 0
+	if enable {
+		e = 1
+	}
+	C.win32_set_blur(unsafe.Pointer(window.GetWin32Window()), C.int(e))
+}
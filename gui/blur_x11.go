@@ -0,0 +1,36 @@
+// +build linux,!wayland freebsd,!wayland
+
+package gui
+
+/*
+#cgo LDFLAGS: -lX11
+#include <X11/Xlib.h>
+#include <X11/Xatom.h>
+static void x11_set_blur(void *display, Window window, int enable) {
+	Atom region = XInternAtom(display, "_KDE_NET_WM_BLUR_BEHIND_REGION", False);
+	if (enable) {
+		// an empty region blurs behind the whole window
+		XChangeProperty(display, window, region, XA_CARDINAL, 32, PropModeReplace, NULL, 0);
+	} else {
+		XDeleteProperty(display, window, region);
+	}
+	XFlush(display);
+}
+*/
+import "C"
+
+import (
+	"unsafe"
+
+	"github.com/go-gl/glfw/v3.3/glfw"
+)
+
+// setBlur asks the compositor to blur what's behind the window, using the property KWin and some other
+// compositors look for. Others ignore it.
+func setBlur(window *glfw.Window, enable bool) {
+	e := 0
+	if enable {
+		e = 1
+	}
+	C.x11_set_blur(unsafe.Pointer(glfw.GetX11Display()), C.Window(window.GetX11Window()), C.int(e))
+}
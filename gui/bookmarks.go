@@ -0,0 +1,51 @@
+package gui
+
+import (
+	"fmt"
+)
+
+func actionAddBookmark(gui *GUI) {
+	buffer := gui.terminal.ActiveBuffer()
+	line := buffer.TopVisibleLine()
+	gui.setOverlay(newPrompt("Bookmark name", func(gui *GUI, name string) {
+		if name == "" {
+			name = fmt.Sprintf("line %d", line+1)
+		}
+		buffer.AddBookmark(name, line)
+	}))
+}
+
+func actionListBookmarks(gui *GUI) {
+	buffer := gui.terminal.ActiveBuffer()
+	bookmarks := buffer.Bookmarks()
+
+	items := make([]string, 0, len(bookmarks))
+	for _, bookmark := range bookmarks {
+		items = append(items, fmt.Sprintf("%-20s line %d", bookmark.Name, buffer.BookmarkLine(bookmark)+1))
+	}
+
+	m := newMenu("Bookmarks (enter to jump, delete to remove)", items, func(gui *GUI, index int) {
+		bookmarks := buffer.Bookmarks()
+		if index < len(bookmarks) {
+			gui.terminal.ScrollToLineAtTop(buffer.BookmarkLine(bookmarks[index]))
+		}
+	})
+	m.onDelete = func(gui *GUI, index int) {
+		buffer.RemoveBookmark(index)
+	}
+	gui.setOverlay(m)
+}
+
+func actionNextBookmark(gui *GUI) {
+	buffer := gui.terminal.ActiveBuffer()
+	if bookmark := buffer.NextBookmark(buffer.TopVisibleLine()); bookmark != nil {
+		gui.terminal.ScrollToLineAtTop(buffer.BookmarkLine(*bookmark))
+	}
+}
+
+func actionPreviousBookmark(gui *GUI) {
+	buffer := gui.terminal.ActiveBuffer()
+	if bookmark := buffer.PreviousBookmark(buffer.TopVisibleLine()); bookmark != nil {
+		gui.terminal.ScrollToLineAtTop(buffer.BookmarkLine(*bookmark))
+	}
+}
@@ -0,0 +1,65 @@
+package gui
+
+import (
+	"fmt"
+	"image"
+
+	"github.com/go-gl/gl/all-core/gl"
+)
+
+// Capture draws the window's current tab into an offscreen framebuffer rather than the window, and returns the
+// image, so what's rendered can be checked whether or not the window can be seen, as by the golden image tests.
+// Post-processing shaders aren't applied. It's run on the OS thread via the render loop, so mustn't be called
+// from it.
+func (gui *GUI) Capture() (*image.RGBA, error) {
+	var img *image.RGBA
+	var err error
+	gui.runOnMainThread(func() {
+		img, err = gui.capture()
+	})
+	return img, err
+}
+
+func (gui *GUI) capture() (*image.RGBA, error) {
+	width, height := int32(gui.width), int32(gui.height)
+	if width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("Failed to capture the frame: the window has no size")
+	}
+
+	var fbo, texture uint32
+	gl.GenFramebuffers(1, &fbo)
+	gl.GenTextures(1, &texture)
+	defer gl.DeleteFramebuffers(1, &fbo)
+	defer gl.DeleteTextures(1, &texture)
+
+	gl.BindTexture(gl.TEXTURE_2D, texture)
+	gl.TexImage2D(gl.TEXTURE_2D, 0, gl.RGBA, width, height, 0, gl.RGBA, gl.UNSIGNED_BYTE, nil)
+	gl.BindTexture(gl.TEXTURE_2D, 0)
+	gl.BindFramebuffer(gl.FRAMEBUFFER, fbo)
+	gl.FramebufferTexture2D(gl.FRAMEBUFFER, gl.COLOR_ATTACHMENT0, gl.TEXTURE_2D, texture, 0)
+	if status := gl.CheckFramebufferStatus(gl.FRAMEBUFFER); status != gl.FRAMEBUFFER_COMPLETE {
+		gl.BindFramebuffer(gl.FRAMEBUFFER, 0)
+		return nil, fmt.Errorf("Failed to capture the frame: framebuffer incomplete: 0x%x", status)
+	}
+
+	// everything is drawn, so the capture doesn't depend on what was drawn before
+	gui.invalidateFrame()
+	gui.captureTarget = fbo
+	gui.redraw()
+	gui.captureTarget = 0
+
+	img := image.NewRGBA(image.Rect(0, 0, int(width), int(height)))
+	gl.BindFramebuffer(gl.FRAMEBUFFER, fbo)
+	gl.ReadPixels(0, 0, width, height, gl.RGBA, gl.UNSIGNED_BYTE, gl.Ptr(&img.Pix[0]))
+	gl.BindFramebuffer(gl.FRAMEBUFFER, 0)
+
+	// OpenGL's rows go up from the bottom
+	stride := img.Stride
+	row := make([]byte, stride)
+	for top, bottom := 0, int(height)-1; top < bottom; top, bottom = top+1, bottom-1 {
+		copy(row, img.Pix[top*stride:(top+1)*stride])
+		copy(img.Pix[top*stride:(top+1)*stride], img.Pix[bottom*stride:(bottom+1)*stride])
+		copy(img.Pix[bottom*stride:(bottom+1)*stride], row)
+	}
+	return img, nil
+}
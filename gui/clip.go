@@ -0,0 +1,83 @@
+package gui
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/liamg/aminal/recording"
+)
+
+// recordFrame adds the current screen to the clip recording
+func (gui *GUI) recordFrame() {
+	gui.recordPending = false
+	gui.recorder.Record(recording.Frame{
+		Snapshot:   gui.terminal.ActiveBuffer().Snapshot(),
+		CursorX:    int(gui.terminal.GetLogicalCursorX()),
+		CursorY:    int(gui.terminal.GetLogicalCursorY()) + int(gui.terminal.GetScrollOffset()),
+		ShowCursor: gui.terminal.Modes().ShowCursor,
+	})
+}
+
+func actionExportClip(gui *GUI) {
+	if gui.recorder == nil {
+		gui.showToast(newNotice("Clip recording is disabled, set seconds in the [clip] config to enable it"))
+		return
+	}
+	if gui.recordPending {
+		gui.recordFrame()
+	}
+
+	format := recording.Format(gui.config.Clip.Format)
+	if format == "" {
+		format = recording.FormatGIF
+	}
+	path := gui.exportPath(format.Extension())
+
+	regular, err := gui.fontData(gui.loadedFont.Regular, regularFont)
+	if err != nil {
+		gui.exported(path, err)
+		return
+	}
+	bold, _ := gui.fontData(gui.loadedFont.Bold, boldFont) // falls back to regular
+
+	cols, rows := gui.terminal.GetSize()
+	renderer, err := recording.NewRenderer(
+		regular,
+		bold,
+		float64(gui.fontScale*gui.dpiScale/gui.scale()),
+		int(cols),
+		int(rows),
+		[3]float32(gui.config.ColourScheme.Background),
+		[3]float32(gui.config.ColourScheme.Cursor),
+	)
+	if err != nil {
+		gui.exported(path, err)
+		return
+	}
+
+	frames := gui.recorder.Frames()
+	since := time.Now().Add(-gui.recorder.Length())
+	gui.showToast(newToast(fmt.Sprintf("Exporting %d frames...", len(frames))))
+
+	// encoding can take a while, so keep it off the render thread
+	go func() {
+		err := exportClip(path, frames, since, renderer, gui.config.Clip.Scale, format)
+		gui.runOnMainThread(func() {
+			gui.exported(path, err)
+		})
+	}()
+}
+
+func exportClip(path string, frames []recording.Frame, since time.Time, renderer *recording.Renderer, scale float64, format recording.Format) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := recording.Export(f, frames, since, renderer, scale, format); err != nil {
+		_ = f.Close()
+		_ = os.Remove(path)
+		return err
+	}
+	return f.Close()
+}
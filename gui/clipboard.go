@@ -0,0 +1,108 @@
+package gui
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/liamg/aminal/config"
+	"github.com/liamg/aminal/terminal"
+)
+
+// clipboardAccess decides whether programs may use the clipboard via OSC 52, asking the user at most once a session
+type clipboardAccess struct {
+	gui       *GUI
+	mutex     sync.Mutex
+	decisions map[string]bool     // answers to permission prompts, by access type
+	waiting   map[string][]func() // access waiting on the user to answer the prompt, by access type
+}
+
+func newClipboardAccess(gui *GUI) *clipboardAccess {
+	return &clipboardAccess{
+		gui:       gui,
+		decisions: map[string]bool{},
+		waiting:   map[string][]func(){},
+	}
+}
+
+func (c *clipboardAccess) ReadClipboard(target terminal.ClipboardTarget, reply func(text string)) error {
+	return c.whenAllowed("read", c.gui.config.Clipboard.Read, func() {
+		reply(c.gui.getSelection(targetSelection(target)))
+	})
+}
+
+func (c *clipboardAccess) WriteClipboard(target terminal.ClipboardTarget, text string) error {
+	if max := c.gui.config.Clipboard.MaxWrite; max > 0 && len(text) > max {
+		c.gui.logger.Infof("Denied clipboard write of %d bytes by terminal program (limit is %d)", len(text), max)
+		return fmt.Errorf("Clipboard write of %d bytes exceeds limit of %d", len(text), max)
+	}
+
+	return c.whenAllowed("write", c.gui.config.Clipboard.Write, func() {
+		c.gui.setSelection(targetSelection(target), text)
+	})
+}
+
+// targetSelection returns the selection an OSC 52 sequence names
+func targetSelection(target terminal.ClipboardTarget) selection {
+	if target == terminal.TargetPrimary {
+		return selectionPrimary
+	}
+	return selectionClipboard
+}
+
+// whenAllowed applies the configured policy, running access on the main thread if it's allowed. The first time
+// the policy is to ask, the user is prompted and access waits, along with any more which come in meanwhile, until
+// they answer, so the program's output carries on being processed in the meantime.
+func (c *clipboardAccess) whenAllowed(access string, policy string, run func()) error {
+	switch policy {
+	case config.ClipboardAllow:
+		c.gui.runOnMainThread(run)
+		return nil
+	case config.ClipboardAsk:
+	default:
+		c.gui.logger.Infof("Denied clipboard %s by terminal program", access)
+		return fmt.Errorf("Clipboard %s denied", access)
+	}
+
+	c.mutex.Lock()
+	decision, decided := c.decisions[access]
+	if !decided {
+		asking := len(c.waiting[access]) > 0
+		c.waiting[access] = append(c.waiting[access], run)
+		c.mutex.Unlock()
+		if !asking {
+			go c.gui.runOnMainThread(func() { c.ask(access) })
+		}
+		return nil
+	}
+	c.mutex.Unlock()
+
+	if !decision {
+		c.gui.logger.Infof("Denied clipboard %s by terminal program", access)
+		return fmt.Errorf("Clipboard %s denied", access)
+	}
+	c.gui.runOnMainThread(run)
+	return nil
+}
+
+// ask prompts the user whether to allow the type of access, then runs or drops what's been waiting on the
+// answer. It's run on the main thread.
+func (c *clipboardAccess) ask(access string) {
+	c.gui.setOverlay(newConfirmation(
+		fmt.Sprintf("Allow programs in this terminal to %s the clipboard?", access),
+		func(gui *GUI, yes bool) {
+			c.mutex.Lock()
+			c.decisions[access] = yes
+			waiting := c.waiting[access]
+			delete(c.waiting, access)
+			c.mutex.Unlock()
+
+			if !yes {
+				gui.logger.Infof("Denied clipboard %s by terminal program", access)
+				return
+			}
+			for _, run := range waiting {
+				run()
+			}
+		},
+	))
+}
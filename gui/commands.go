@@ -0,0 +1,40 @@
+package gui
+
+// These actions use the prompts, commands and output marked by shell integration (OSC 133)
+
+func actionPreviousPrompt(gui *GUI) {
+	buffer := gui.terminal.ActiveBuffer()
+	if line := buffer.PreviousPrompt(buffer.TopVisibleLine()); line >= 0 {
+		gui.terminal.ScrollToLineAtTop(line)
+	}
+}
+
+func actionNextPrompt(gui *GUI) {
+	buffer := gui.terminal.ActiveBuffer()
+	if line := buffer.NextPrompt(buffer.TopVisibleLine()); line >= 0 {
+		gui.terminal.ScrollToLineAtTop(line)
+	}
+}
+
+func actionCopyLastOutput(gui *GUI) {
+	output, ok := gui.terminal.ActiveBuffer().LastCommandOutput()
+	if !ok {
+		gui.showShellIntegrationToast()
+		return
+	}
+	gui.setSelection(selectionClipboard, output)
+}
+
+func actionRerunLastCommand(gui *GUI) {
+	command, ok := gui.terminal.ActiveBuffer().LastCommand()
+	if !ok {
+		gui.showShellIntegrationToast()
+		return
+	}
+	gui.terminal.ScrollToEnd()
+	gui.writeInput([]byte(command + "\r"))
+}
+
+func (gui *GUI) showShellIntegrationToast() {
+	gui.showToast(newNotice("No command found - this needs shell integration to mark commands with OSC 133"))
+}
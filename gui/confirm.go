@@ -0,0 +1,59 @@
+package gui
+
+import (
+	"fmt"
+
+	"github.com/go-gl/glfw/v3.3/glfw"
+)
+
+// confirmation is an overlay asking the user a yes/no question. Closing it without answering counts as no.
+type confirmation struct {
+	question string
+	answered bool
+	onAnswer func(gui *GUI, yes bool)
+}
+
+func newConfirmation(question string, onAnswer func(gui *GUI, yes bool)) *confirmation {
+	return &confirmation{
+		question: question,
+		onAnswer: onAnswer,
+	}
+}
+
+func (c *confirmation) answer(gui *GUI, yes bool) {
+	if c.answered {
+		return
+	}
+	c.answered = true
+	gui.setOverlay(nil)
+	c.onAnswer(gui, yes)
+}
+
+func (c *confirmation) char(gui *GUI, r rune) {
+	switch r {
+	case 'y', 'Y':
+		c.answer(gui, true)
+	case 'n', 'N':
+		c.answer(gui, false)
+	}
+}
+
+func (c *confirmation) key(gui *GUI, key glfw.Key, mods glfw.ModifierKey) {}
+
+func (c *confirmation) closed(gui *GUI) {
+	c.answer(gui, false)
+}
+
+func (c *confirmation) render(gui *GUI) {
+	h := gui.terminal.ActiveBuffer().ViewHeight()
+	if h < 3 {
+		return
+	}
+	gui.textbox(
+		2,
+		h-3,
+		fmt.Sprintf("%s [y/n]", c.question),
+		[3]float32{1, 1, 1},
+		[3]float32{0.5, 0.2, 0},
+	)
+}
@@ -0,0 +1,72 @@
+package gui
+
+import (
+	"image/png"
+	"os"
+
+	"github.com/liamg/aminal/control"
+	"github.com/liamg/aminal/terminal"
+)
+
+// controlWindow is what commands sent over the control socket act on, doing everything with the window on the
+// OS thread
+type controlWindow struct {
+	gui *GUI
+}
+
+func (w controlWindow) Terminal() *terminal.Terminal {
+	var t *terminal.Terminal
+	w.gui.runOnMainThread(func() {
+		t = w.gui.terminal
+	})
+	return t
+}
+
+// Send queues the data behind any input from the keyboard, so it isn't mixed into the middle of it
+func (w controlWindow) Send(data []byte) error {
+	w.gui.queueWrite(w.Terminal(), data)
+	return nil
+}
+
+func (w controlWindow) Resize(cols uint, rows uint) error {
+	w.gui.runOnMainThread(func() {
+		w.gui.resizeToTerminal(cols, rows)
+	})
+	return nil
+}
+
+func (w controlWindow) Screenshot(path string) error {
+	img, err := w.gui.Capture()
+	if err != nil {
+		return err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(file, img); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// startControl listens for commands on the control socket, if one is configured
+func (gui *GUI) startControl() {
+	if gui.config.Control.Socket == "" {
+		return
+	}
+	listener, err := control.Listen(gui.config.Control.Socket)
+	if err != nil {
+		gui.logger.Errorf("Failed to start the control socket: %s", err)
+		return
+	}
+	gui.control = listener
+	server := control.NewServer(controlWindow{gui}, gui.logger)
+	go func() {
+		if err := server.Serve(listener); err != nil {
+			gui.logger.Debugf("Control socket closed: %s", err)
+		}
+	}()
+	gui.logger.Infof("Listening for commands on %s", gui.config.Control.Socket)
+}
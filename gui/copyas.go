@@ -0,0 +1,84 @@
+package gui
+
+import (
+	"fmt"
+
+	"github.com/liamg/aminal/buffer"
+	"github.com/liamg/aminal/platform"
+)
+
+// copyFormat is a way of copying the selection to the clipboard
+type copyFormat struct {
+	name string
+	copy func(gui *GUI)
+}
+
+// copyFormats are the choices in the Copy as menu
+var copyFormats = []copyFormat{
+	{"Plain text", actionCopy},
+	{"HTML, with colours", actionCopyHTML},
+	{"Rich text (RTF), with colours", actionCopyRTF},
+	{"Shell string, quoted", actionCopyShell},
+}
+
+// actionCopyAs shows a menu of the formats the selection can be copied in
+func actionCopyAs(gui *GUI) {
+	selection := gui.selectedText()
+	if selection == "" {
+		return
+	}
+	items := make([]string, len(copyFormats))
+	for i, format := range copyFormats {
+		items[i] = format.name
+	}
+	gui.setOverlay(newMenu(fmt.Sprintf("Copy '%s' as:", abbreviate(selection, 40)), items, func(gui *GUI, index int) {
+		copyFormats[index].copy(gui)
+	}))
+}
+
+func actionCopyHTML(gui *GUI) {
+	if text := gui.selectedText(); text != "" {
+		gui.copyRich("text/html", gui.terminal.ActiveBuffer().SelectionHTML(gui.exportOptions()), text)
+	}
+}
+
+func actionCopyRTF(gui *GUI) {
+	if text := gui.selectedText(); text != "" {
+		gui.copyRich("text/rtf", gui.terminal.ActiveBuffer().SelectionRTF(gui.exportOptions()), text)
+	}
+}
+
+// actionCopyShell copies the selection quoted as a single word for the shell, to paste into a command
+func actionCopyShell(gui *GUI) {
+	if text := gui.selectedText(); text != "" {
+		gui.setSelection(selectionClipboard, platform.ShellQuote(text))
+	}
+}
+
+// exportOptions are the colours and font text is exported and copied in
+func (gui *GUI) exportOptions() buffer.ExportOptions {
+	options := gui.terminal.Options()
+	return buffer.ExportOptions{
+		Title:      gui.terminal.GetTitle(),
+		FontFamily: "Hack, monospace",
+		Foreground: options.Foreground,
+		Background: options.Background,
+	}
+}
+
+// copyRich puts the text on the clipboard with its formatting, which GLFW can't do, so it's left to the platform.
+// If that fails, the plain text is copied instead, so there's still something to paste.
+func (gui *GUI) copyRich(mimeType string, rich string, plain string) {
+	// the clipboard tools can take a moment to start, so keep them off the render thread
+	go func() {
+		err := platform.CopyRich(mimeType, rich, plain)
+		if err == nil {
+			return
+		}
+		gui.logger.Errorf("Failed to copy as %s: %s", mimeType, err)
+		gui.runOnMainThread(func() {
+			gui.setSelection(selectionClipboard, plain)
+			gui.showToast(newNotice(fmt.Sprintf("Copied as plain text, as copying as %s failed: %s", mimeType, err)))
+		})
+	}()
+}
@@ -0,0 +1,75 @@
+package gui
+
+import (
+	"time"
+
+	"github.com/liamg/aminal/terminal"
+)
+
+// how long the cursor takes to fade out and back in as it blinks, at most
+const cursorFadeDuration = 150 * time.Millisecond
+
+// cursorBlinks returns true if the cursor of t should be blinking
+func (gui *GUI) cursorBlinks(t *terminal.Terminal) bool {
+	if gui.config.Cursor.BlinkInterval <= 0 || !gui.windowFocused {
+		return false
+	}
+	return gui.config.Cursor.Blink || t.Modes().BlinkingCursor
+}
+
+// cursorVisibility returns how much of the cursor of t to show right now, from 0 when it has blinked out to 1
+func (gui *GUI) cursorVisibility(t *terminal.Terminal) float32 {
+	if !gui.cursorBlinks(t) {
+		return 1
+	}
+	interval := time.Duration(gui.config.Cursor.BlinkInterval) * time.Millisecond
+	fade := cursorFadeDuration
+	if fade > interval/2 {
+		fade = interval / 2
+	}
+
+	// the cursor is shown for the first interval of the cycle and hidden for the second, fading at the end of each
+	phase := time.Since(gui.cursorBlinkStart) % (2 * interval)
+	switch {
+	case phase < interval-fade:
+		return 1
+	case phase < interval:
+		return 1 - float32(phase-(interval-fade))/float32(fade)
+	case phase < 2*interval-fade:
+		return 0
+	default:
+		return float32(phase-(2*interval-fade)) / float32(fade)
+	}
+}
+
+// resetCursorBlink shows the cursor solidly for a full interval, so it doesn't vanish while the user is typing
+func (gui *GUI) resetCursorBlink() {
+	gui.cursorBlinkStart = time.Now()
+}
+
+// cursorHollow returns true if the cursor should be drawn as an outline, as the window doesn't have focus
+func (gui *GUI) cursorHollow() bool {
+	return !gui.windowFocused && !gui.config.Cursor.SolidWhenUnfocused
+}
+
+// cursorNeedsRedraw returns true if the cursor has moved, or moved on in its blink, since it was last drawn. It's
+// checked each time round the render loop, which already wakes up at least every 20ms, so blinking needs no timer
+// of its own.
+func (gui *GUI) cursorNeedsRedraw() bool {
+	col, row := cursorCell(gui.terminal)
+	return gui.cursorVisibility(gui.terminal) != gui.drawnCursorVisibility || col != gui.drawnCursorCol || row != gui.drawnCursorRow
+}
+
+// cursorCell returns the cell of the view the cursor of t is in
+func cursorCell(t *terminal.Terminal) (uint, uint) {
+	return uint(t.GetLogicalCursorX()), uint(t.GetLogicalCursorY()) + uint(t.GetScrollOffset())
+}
+
+// blend mixes from towards to by the given amount, from 0 to 1
+func blend(from [3]float32, to [3]float32, amount float32) [3]float32 {
+	var c [3]float32
+	for i := range c {
+		c[i] = from[i] + (to[i]-from[i])*amount
+	}
+	return c
+}
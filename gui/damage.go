@@ -0,0 +1,163 @@
+package gui
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/go-gl/gl/all-core/gl"
+	"github.com/liamg/aminal/buffer"
+)
+
+// frameCache keeps the panes of the active tab as they were last drawn, in an offscreen texture. Each frame
+// only the rows which have changed are drawn into it, and rows which have only scrolled are moved up, before
+// it's copied to the window and the tab bar, overlays and so on are drawn over it.
+type frameCache struct {
+	fbo            uint32
+	texture        uint32
+	scratchFBO     uint32 // rows are moved through here, as a framebuffer can't be copied onto itself
+	scratchTexture uint32
+	width          int32
+	height         int32
+	valid          bool // false when everything needs drawing again
+	tab            *tab // the tab and focused pane the cache was drawn for
+	focus          *pane
+}
+
+func newFrameCache() *frameCache {
+	c := &frameCache{}
+	gl.GenFramebuffers(1, &c.fbo)
+	gl.GenTextures(1, &c.texture)
+	gl.GenFramebuffers(1, &c.scratchFBO)
+	gl.GenTextures(1, &c.scratchTexture)
+	return c
+}
+
+// resize (re)allocates the textures for a window of the given size, returning an error if they can't be drawn to
+func (c *frameCache) resize(width int, height int) error {
+	c.width = int32(width)
+	c.height = int32(height)
+	c.valid = false
+
+	for _, target := range [][2]uint32{{c.fbo, c.texture}, {c.scratchFBO, c.scratchTexture}} {
+		gl.BindTexture(gl.TEXTURE_2D, target[1])
+		gl.TexImage2D(gl.TEXTURE_2D, 0, gl.RGBA, c.width, c.height, 0, gl.RGBA, gl.UNSIGNED_BYTE, nil)
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.NEAREST)
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.NEAREST)
+
+		gl.BindFramebuffer(gl.FRAMEBUFFER, target[0])
+		gl.FramebufferTexture2D(gl.FRAMEBUFFER, gl.COLOR_ATTACHMENT0, gl.TEXTURE_2D, target[1], 0)
+		if status := gl.CheckFramebufferStatus(gl.FRAMEBUFFER); status != gl.FRAMEBUFFER_COMPLETE {
+			gl.BindFramebuffer(gl.FRAMEBUFFER, 0)
+			gl.BindTexture(gl.TEXTURE_2D, 0)
+			return fmt.Errorf("framebuffer incomplete: 0x%x", status)
+		}
+	}
+
+	gl.BindTexture(gl.TEXTURE_2D, 0)
+	gl.BindFramebuffer(gl.FRAMEBUFFER, 0)
+	return nil
+}
+
+func (c *frameCache) free() {
+	gl.DeleteFramebuffers(1, &c.fbo)
+	gl.DeleteTextures(1, &c.texture)
+	gl.DeleteFramebuffers(1, &c.scratchFBO)
+	gl.DeleteTextures(1, &c.scratchTexture)
+}
+
+// begin directs drawing into the cache, returning false if everything needs drawing again for the given tab
+func (c *frameCache) begin(t *tab) bool {
+	gl.BindFramebuffer(gl.FRAMEBUFFER, c.fbo)
+	valid := c.valid && c.tab == t && c.focus == t.focus
+	c.valid, c.tab, c.focus = true, t, t.focus
+	return valid
+}
+
+// scroll moves the pixels in a rectangle, given from the top left like the renderer's coordinates, up by dy
+func (c *frameCache) scroll(x float32, y float32, width float32, height float32, dy float32) {
+	x0 := int32(math.Floor(float64(x)))
+	x1 := int32(math.Ceil(float64(x + width)))
+	bottom := c.height - int32(math.Round(float64(y+height)))
+	top := c.height - int32(math.Round(float64(y)))
+	shift := int32(math.Round(float64(dy)))
+	if shift <= 0 || shift >= top-bottom {
+		return
+	}
+
+	gl.BindFramebuffer(gl.READ_FRAMEBUFFER, c.fbo)
+	gl.BindFramebuffer(gl.DRAW_FRAMEBUFFER, c.scratchFBO)
+	gl.BlitFramebuffer(x0, bottom, x1, top-shift, x0, bottom+shift, x1, top, gl.COLOR_BUFFER_BIT, gl.NEAREST)
+	gl.BindFramebuffer(gl.READ_FRAMEBUFFER, c.scratchFBO)
+	gl.BindFramebuffer(gl.DRAW_FRAMEBUFFER, c.fbo)
+	gl.BlitFramebuffer(x0, bottom+shift, x1, top, x0, bottom+shift, x1, top, gl.COLOR_BUFFER_BIT, gl.NEAREST)
+	gl.BindFramebuffer(gl.FRAMEBUFFER, c.fbo)
+}
+
+// present copies the cache to the given framebuffer, and leaves that bound for drawing over it
+func (c *frameCache) present(target uint32) {
+	gl.BindFramebuffer(gl.READ_FRAMEBUFFER, c.fbo)
+	gl.BindFramebuffer(gl.DRAW_FRAMEBUFFER, target)
+	gl.BlitFramebuffer(0, 0, c.width, c.height, 0, 0, c.width, c.height, gl.COLOR_BUFFER_BIT, gl.NEAREST)
+	gl.BindFramebuffer(gl.FRAMEBUFFER, target)
+}
+
+// invalidateFrame draws everything again on the next frame, e.g. when the panes have been laid out again
+func (gui *GUI) invalidateFrame() {
+	if gui.frameCache != nil {
+		gui.frameCache.valid = false
+	}
+}
+
+// clearRows clears the damaged rows of p, which has been drawn at the renderer's viewport, to the background
+func (gui *GUI) clearRows(p *pane, damage *buffer.Damage) {
+	r := gui.renderer
+	x := float32(r.areaX) + float32(p.col)*r.cellWidth
+	x0 := int32(math.Floor(float64(x)))
+	x1 := int32(math.Ceil(float64(x + float32(p.cols)*r.cellWidth)))
+
+	gl.Enable(gl.SCISSOR_TEST)
+	defer gl.Disable(gl.SCISSOR_TEST)
+
+	// runs of damaged rows are cleared together
+	for row := 0; row < int(p.rows); row++ {
+		if !damage.Row(row) {
+			continue
+		}
+		end := row + 1
+		for end < int(p.rows) && damage.Row(end) {
+			end++
+		}
+		top := float32(r.areaY) + float32(p.row+uint(row))*r.cellHeight
+		bottom := float32(r.areaY) + float32(p.row+uint(end))*r.cellHeight
+		y0 := int32(r.areaHeight) - int32(math.Round(float64(bottom)))
+		y1 := int32(r.areaHeight) - int32(math.Round(float64(top)))
+		gl.Scissor(x0, y0, x1-x0, y1-y0)
+		gl.Clear(gl.COLOR_BUFFER_BIT)
+		row = end
+	}
+}
+
+// drawPane draws the rows of p which have changed since it was last drawn into the frame cache, which is bound,
+// moving the rest up first if the view has scrolled
+func (gui *GUI) drawPane(p *pane, damage buffer.Damage, focused bool) {
+	r := gui.renderer
+	if damage.Scrolled > 0 && !damage.All {
+		if focused && gui.hoverLink != nil {
+			// the underline would move with the text, away from where the mouse is
+			damage.All = true
+		} else {
+			gui.frameCache.scroll(float32(r.areaX)+float32(p.col)*r.cellWidth, float32(r.areaY)+float32(p.row)*r.cellHeight,
+				float32(p.cols)*r.cellWidth, float32(p.rows)*r.cellHeight, float32(damage.Scrolled)*r.cellHeight)
+		}
+	}
+	if focused {
+		// the cursor isn't part of the buffer, so the rows it was drawn on and is on now are always drawn again
+		rows := int(p.rows)
+		damage.Add(int(gui.drawnCursorRow)-damage.Scrolled, rows)
+		_, row := cursorCell(p.terminal)
+		damage.Add(int(row), rows)
+	}
+	gui.clearRows(p, &damage)
+	gui.redrawTerminal(p.terminal, focused, &damage)
+	r.Flush()
+}
@@ -0,0 +1,30 @@
+package gui
+
+import "github.com/liamg/aminal/buffer"
+
+func actionToggleDiff(gui *GUI) {
+	gui.showDiff = !gui.showDiff
+	if !gui.showDiff {
+		gui.diffBaseline = nil
+	} else if gui.terminal.ActiveBuffer().PreviousOutputBaseline() == nil {
+		gui.logger.Infof("Nothing to compare yet: diff highlighting needs two commands with output, marked by shell integration")
+	}
+	gui.terminal.SetDirty()
+}
+
+func actionSetDiffBaseline(gui *GUI) {
+	gui.diffBaseline = gui.terminal.ActiveBuffer().ScreenBaseline()
+	gui.showDiff = true
+	gui.terminal.SetDirty()
+}
+
+// currentDiffBaseline returns what the screen should be compared against, or nil when not highlighting changes
+func (gui *GUI) currentDiffBaseline() *buffer.Baseline {
+	if !gui.showDiff {
+		return nil
+	}
+	if gui.diffBaseline != nil {
+		return gui.diffBaseline
+	}
+	return gui.terminal.ActiveBuffer().PreviousOutputBaseline()
+}
@@ -0,0 +1,41 @@
+package gui
+
+import (
+	"os"
+	"strings"
+
+	"github.com/go-gl/glfw/v3.3/glfw"
+	"github.com/liamg/aminal/platform"
+)
+
+// drop types the paths of the files dropped on the window into the pane they were dropped on, quoted for the shell
+// and followed by a space so more can be typed after them. A directory dropped on its own is changed to instead
+// if the config asks for it.
+func (gui *GUI) drop(w *glfw.Window, paths []string) {
+	if len(paths) == 0 {
+		return
+	}
+	if o := gui.inputOverlay(); o != nil {
+		for _, r := range strings.Join(paths, " ") {
+			o.char(gui, r)
+		}
+		return
+	}
+
+	x, y := gui.convertMouseCoordinates(w.GetCursorPos())
+	if gui.tabAt(x, y) < 0 {
+		gui.focusPane(gui.currentTab().root.at(uint(x), uint(y)))
+	}
+
+	if gui.config.Drop.ChangeDirectory && len(paths) == 1 {
+		if info, err := os.Stat(paths[0]); err == nil && info.IsDir() {
+			gui.writeInput([]byte("cd " + platform.ShellQuote(paths[0]) + "\r"))
+			return
+		}
+	}
+	words := make([]string, len(paths))
+	for i, path := range paths {
+		words[i] = platform.ShellQuote(path)
+	}
+	gui.writeInput([]byte(strings.Join(words, " ") + " "))
+}
@@ -1,7 +1,6 @@
 package gui
 
 import (
-	"github.com/go-gl/gl/all-core/gl"
 	"github.com/liamg/aminal/hints"
 )
 
@@ -16,7 +15,7 @@ func newAnnotation(it *hints.Hint) *annotation {
 }
 
 func (a *annotation) render(gui *GUI) {
-	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT | gl.STENCIL_BUFFER_BIT)
+	gui.renderer.Clear()
 
 	lines := gui.terminal.GetVisibleLines()
 	for y := 0; y < len(lines); y++ {
@@ -26,6 +25,9 @@ func (a *annotation) render(gui *GUI) {
 				break
 			}
 			cell := cells[x]
+			if cell.Continuation() {
+				continue
+			}
 
 			var colour [3]float32 = cell.Fg()
 			var alpha float32 = 0.6
@@ -36,7 +38,7 @@ func (a *annotation) render(gui *GUI) {
 					alpha = 1.0
 				}
 			}
-			gui.renderer.DrawCellText(string(cell.Rune()), uint(x), uint(y), alpha, colour, cell.Attr().Bold)
+			gui.renderer.DrawCellText(cell.Text(), uint(x), uint(y), alpha, colour, cell.Attr().Bold, cell.Attr().Italic)
 		}
 	}
 
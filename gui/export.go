@@ -0,0 +1,104 @@
+package gui
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/liamg/aminal/buffer"
+)
+
+// exportPath returns where to save an exported file with the given extension
+func (gui *GUI) exportPath(ext string) string {
+	dir := gui.config.ExportDirectory
+	if dir == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			dir = home
+		}
+	}
+	return filepath.Join(dir, fmt.Sprintf("aminal-%s.%s", time.Now().Format("20060102-150405"), ext))
+}
+
+// exported tells the user where a file was saved
+func (gui *GUI) exported(path string, err error) {
+	if err != nil {
+		gui.logger.Errorf("Failed to export to %s: %s", path, err)
+		gui.showToast(newNotice(fmt.Sprintf("Export failed: %s", err)))
+		return
+	}
+	gui.logger.Infof("Exported to %s", path)
+	gui.showToast(newToast(
+		fmt.Sprintf("Saved %s", path),
+		toastAction{
+			label: "Copy path",
+			run: func(gui *GUI) {
+				gui.window.SetClipboardString(path)
+			},
+		},
+		dismissAction,
+	))
+}
+
+func actionExportSVG(gui *GUI) {
+	options := buffer.SVGOptions{
+		FontFamily: "Hack, monospace",
+		FontSize:   gui.fontScale * gui.dpiScale / gui.scale(),
+		CellWidth:  gui.renderer.CellWidth(),
+		CellHeight: gui.renderer.CellHeight(),
+		Baseline:   gui.renderer.CellHeight() + gui.fontMap.DefaultFont().MinY() - gui.renderer.textLift,
+		Foreground: gui.config.ColourScheme.Foreground,
+		Background: gui.config.ColourScheme.Background,
+	}
+	if gui.terminal.Modes().ShowCursor {
+		cursor := [3]float32(gui.config.ColourScheme.Cursor)
+		options.Cursor = &cursor
+	}
+	if data, err := gui.fontData(gui.loadedFont.Regular, regularFont); err == nil {
+		options.Fonts = append(options.Fonts, buffer.SVGFont{Data: data})
+	}
+	if data, err := gui.fontData(gui.loadedFont.Bold, boldFont); err == nil {
+		options.Fonts = append(options.Fonts, buffer.SVGFont{Data: data, Bold: true})
+	}
+
+	path := gui.exportPath("svg")
+	f, err := os.Create(path)
+	if err != nil {
+		gui.exported(path, err)
+		return
+	}
+	defer f.Close()
+
+	gui.exported(path, gui.terminal.ActiveBuffer().WriteSVG(f, options))
+}
+
+// exportTo saves what write writes to a new file in the export directory, and tells the user where
+func (gui *GUI) exportTo(ext string, write func(w io.Writer) error) {
+	path := gui.exportPath(ext)
+	f, err := os.Create(path)
+	if err != nil {
+		gui.exported(path, err)
+		return
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		gui.exported(path, err)
+		return
+	}
+	gui.exported(path, f.Close())
+}
+
+func actionExportHTML(gui *GUI) {
+	options := gui.exportOptions()
+	options.Scrollback = true
+	gui.exportTo("html", func(w io.Writer) error {
+		return gui.terminal.ActiveBuffer().WriteHTML(w, options)
+	})
+}
+
+func actionExportANSI(gui *GUI) {
+	gui.exportTo("ans", func(w io.Writer) error {
+		return gui.terminal.ActiveBuffer().WriteANSI(w, true)
+	})
+}
@@ -0,0 +1,146 @@
+package gui
+
+import (
+	"fmt"
+
+	"github.com/go-gl/glfw/v3.3/glfw"
+	"github.com/liamg/aminal/buffer"
+)
+
+const maxFilterContext = 10
+
+// filterView collapses the scrollback to lines matching a typed pattern, like grep without leaving the terminal
+type filterView struct {
+	pattern  string
+	context  int
+	selected int
+	results  []buffer.FilteredLine
+}
+
+func newFilterView() *filterView {
+	return &filterView{}
+}
+
+func (f *filterView) update(gui *GUI) {
+	f.results = gui.terminal.ActiveBuffer().FilterLines(f.pattern, f.context)
+	f.selected = 0
+	for i, result := range f.results {
+		if result.Match {
+			f.selected = i
+		}
+	}
+	gui.terminal.SetDirty()
+}
+
+func (f *filterView) char(gui *GUI, r rune) {
+	f.pattern += string(r)
+	f.update(gui)
+}
+
+func (f *filterView) key(gui *GUI, key glfw.Key, mods glfw.ModifierKey) {
+	switch key {
+	case glfw.KeyBackspace:
+		if len(f.pattern) > 0 {
+			runes := []rune(f.pattern)
+			f.pattern = string(runes[:len(runes)-1])
+			f.update(gui)
+		}
+	case glfw.KeyUp:
+		f.moveSelection(-1)
+	case glfw.KeyDown:
+		f.moveSelection(1)
+	case glfw.KeyLeft:
+		if f.context > 0 {
+			f.context--
+			f.update(gui)
+		}
+	case glfw.KeyRight:
+		if f.context < maxFilterContext {
+			f.context++
+			f.update(gui)
+		}
+	case glfw.KeyEnter, glfw.KeyKPEnter:
+		if f.selected < len(f.results) {
+			gui.terminal.ScrollToLine(f.results[f.selected].RawLine)
+		}
+		gui.setOverlay(nil)
+	}
+	gui.terminal.SetDirty()
+}
+
+// moveSelection moves between matching lines, skipping context lines
+func (f *filterView) moveSelection(dir int) {
+	for i := f.selected + dir; i >= 0 && i < len(f.results); i += dir {
+		if f.results[i].Match {
+			f.selected = i
+			return
+		}
+	}
+}
+
+func (f *filterView) render(gui *GUI) {
+	gui.renderer.Clear()
+
+	height := int(gui.terminal.ActiveBuffer().ViewHeight())
+	cols := int(gui.terminal.ActiveBuffer().ViewWidth())
+	rows := height - 4 // leave room for the prompt
+	if rows < 1 {
+		return
+	}
+
+	// keep the selected line in view, leaving matches at the bottom like the live terminal
+	first := 0
+	if len(f.results) > rows {
+		first = len(f.results) - rows
+		if f.selected < first {
+			first = f.selected
+		}
+	}
+
+	for row := 0; row < rows && first+row < len(f.results); row++ {
+		result := f.results[first+row]
+		cells := result.Line.Cells()
+
+		selected := first+row == f.selected
+
+		for x := 0; x < cols; x++ {
+			cell := gui.defaultCell
+			if x < len(cells) {
+				cell = &cells[x]
+			}
+			if selected {
+				bg := gui.config.ColourScheme.Selection
+				gui.renderer.DrawCellBg(*cell, uint(x), uint(row), &bg, true)
+			} else if x < len(cells) {
+				gui.renderer.DrawCellBg(*cell, uint(x), uint(row), nil, false)
+			}
+		}
+
+		var alpha float32 = 1.0
+		if !result.Match {
+			alpha = 0.5
+		}
+		for x := 0; x < cols && x < len(cells); x++ {
+			r := cells[x].Rune()
+			if r == 0 || r == ' ' {
+				continue
+			}
+			gui.renderer.DrawCellText(cells[x].Text(), uint(x), uint(row), alpha, cells[x].Fg(), cells[x].Attr().Bold, cells[x].Attr().Italic)
+		}
+	}
+
+	matches := 0
+	for _, result := range f.results {
+		if result.Match {
+			matches++
+		}
+	}
+
+	gui.textbox(
+		0,
+		uint16(height-3),
+		fmt.Sprintf("filter: %s_  (%d matches, context %d)", f.pattern, matches, f.context),
+		[3]float32{1, 1, 1},
+		[3]float32{0.2, 0.2, 0.4},
+	)
+}
@@ -0,0 +1,131 @@
+package gui
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/go-gl/glfw/v3.3/glfw"
+	"github.com/liamg/aminal/buffer"
+	"github.com/liamg/aminal/config"
+)
+
+// findBar searches the scrollback as a pattern is typed, highlighting matches over the live terminal
+type findBar struct {
+	pattern string
+	matches []buffer.SearchMatch
+	current int // index of the match jumped to, or -1
+	err     error
+}
+
+func actionFind(gui *GUI) {
+	gui.setOverlay(&findBar{current: -1})
+}
+
+// findBar returns the find bar if it's open
+func (gui *GUI) findBar() *findBar {
+	if f, ok := gui.overlay.(*findBar); ok {
+		return f
+	}
+	return nil
+}
+
+func (f *findBar) update(gui *GUI) {
+	f.matches, f.err = gui.terminal.ActiveBuffer().Search(f.pattern)
+	f.current = -1
+	if len(f.matches) > 0 {
+		// start from the most recent output, like the live terminal
+		f.jump(gui, len(f.matches)-1)
+	}
+	gui.terminal.SetDirty()
+}
+
+func (f *findBar) jump(gui *GUI, index int) {
+	f.current = index
+	gui.terminal.ScrollToLine(f.matches[index].Start.Line)
+}
+
+func (f *findBar) char(gui *GUI, r rune) {
+	f.pattern += string(r)
+	f.update(gui)
+}
+
+func (f *findBar) key(gui *GUI, key glfw.Key, mods glfw.ModifierKey) {
+	switch key {
+	case glfw.KeyBackspace:
+		if len(f.pattern) > 0 {
+			runes := []rune(f.pattern)
+			f.pattern = string(runes[:len(runes)-1])
+			f.update(gui)
+		}
+	case glfw.KeyUp:
+		f.step(gui, -1)
+	case glfw.KeyDown:
+		f.step(gui, 1)
+	case glfw.KeyEnter, glfw.KeyKPEnter:
+		// enter works back through older output, shift + enter forwards
+		if mods&glfw.ModShift != 0 {
+			f.step(gui, 1)
+		} else {
+			f.step(gui, -1)
+		}
+	}
+	gui.terminal.SetDirty()
+}
+
+// step moves to the next (dir > 0) or previous match, wrapping around at either end
+func (f *findBar) step(gui *GUI, dir int) {
+	if len(f.matches) == 0 {
+		return
+	}
+	f.jump(gui, (f.current+dir+len(f.matches))%len(f.matches))
+}
+
+// matchAt returns the index of the match covering the cell, or -1
+func (f *findBar) matchAt(rawLine int, col int) int {
+	pos := buffer.Position{Line: rawLine, Col: col}
+	before := func(a buffer.Position, b buffer.Position) bool {
+		return a.Line < b.Line || (a.Line == b.Line && a.Col < b.Col)
+	}
+	// matches are in order and don't overlap, so find the first which doesn't end before the cell
+	i := sort.Search(len(f.matches), func(i int) bool {
+		return !before(f.matches[i].End, pos)
+	})
+	if i < len(f.matches) && !before(pos, f.matches[i].Start) {
+		return i
+	}
+	return -1
+}
+
+// highlight returns the background for the cell at the view position, or nil if it isn't part of a match or the
+// find bar isn't open
+func (f *findBar) highlight(gui *GUI, col int, viewRow int) *config.Colour {
+	if f == nil {
+		return nil
+	}
+	match := f.matchAt(gui.terminal.ActiveBuffer().TopVisibleLine()+viewRow, col)
+	if match < 0 {
+		return nil
+	}
+	if match == f.current {
+		return &gui.config.ColourScheme.Selection
+	}
+	return &gui.config.ColourScheme.Match
+}
+
+func (f *findBar) render(gui *GUI) {
+	height := int(gui.terminal.ActiveBuffer().ViewHeight())
+	status := fmt.Sprintf("%d matches", len(f.matches))
+	if f.err != nil {
+		status = "invalid pattern"
+	} else if f.current >= 0 {
+		status = fmt.Sprintf("%d of %d", f.current+1, len(f.matches))
+	}
+
+	gui.textbox(
+		0,
+		uint16(height-3),
+		fmt.Sprintf("find: %s_  (%s, enter for older, shift + enter for newer)", f.pattern, status),
+		[3]float32{1, 1, 1},
+		[3]float32{0.2, 0.2, 0.4},
+	)
+}
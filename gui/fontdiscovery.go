@@ -0,0 +1,75 @@
+package gui
+
+import (
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/golang/freetype/truetype"
+	"github.com/liamg/aminal/platform"
+	"golang.org/x/image/math/fixed"
+)
+
+// InstalledFont is a monospace font family installed on the system, with the files for each of its faces
+type InstalledFont struct {
+	Family     string
+	Regular    string
+	Bold       string // empty if the family has no bold face
+	Italic     string // empty if the family has no italic face
+	BoldItalic string // empty if the family has no bold italic face
+}
+
+// InstalledFonts returns the monospace fonts installed on the system which Aminal can load, sorted by family
+func InstalledFonts() []InstalledFont {
+	families := map[string]*InstalledFont{}
+	for _, path := range platform.FontFiles() {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		f, err := truetype.Parse(data)
+		if err != nil || !isMonospace(f) {
+			continue
+		}
+		family := f.Name(truetype.NameIDFontFamily)
+		if family == "" {
+			continue
+		}
+		installed, ok := families[family]
+		if !ok {
+			installed = &InstalledFont{Family: family}
+			families[family] = installed
+		}
+		switch strings.ToLower(f.Name(truetype.NameIDFontSubfamily)) {
+		case "regular", "book", "normal", "roman", "":
+			installed.Regular = path
+		case "bold":
+			installed.Bold = path
+		case "italic", "oblique":
+			installed.Italic = path
+		case "bold italic", "bold oblique":
+			installed.BoldItalic = path
+		}
+	}
+
+	fonts := []InstalledFont{}
+	for _, installed := range families {
+		if installed.Regular != "" {
+			fonts = append(fonts, *installed)
+		}
+	}
+	sort.Slice(fonts, func(i, j int) bool {
+		return strings.ToLower(fonts[i].Family) < strings.ToLower(fonts[j].Family)
+	})
+	return fonts
+}
+
+// isMonospace compares the widths of a narrow and a wide glyph, which is more reliable than the flags fonts set about themselves
+func isMonospace(f *truetype.Font) bool {
+	narrow, wide := f.Index('i'), f.Index('M')
+	if narrow == 0 || wide == 0 {
+		return false
+	}
+	scale := fixed.Int26_6(f.FUnitsPerEm())
+	return f.HMetric(scale, narrow).AdvanceWidth == f.HMetric(scale, wide).AdvanceWidth
+}
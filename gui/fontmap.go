@@ -1,10 +1,25 @@
 package gui
 
-import "github.com/liamg/aminal/glfont"
+import (
+	"strings"
+
+	"github.com/liamg/aminal/buffer"
+	"github.com/liamg/aminal/glfont"
+)
 
 type FontMap struct {
 	defaultFont     *glfont.Font
 	defaultBoldFont *glfont.Font
+	italicFont      *glfont.Font   // nil if italic text is drawn with the default font
+	boldItalicFont  *glfont.Font   // nil if bold italic text is drawn with the bold font
+	fallbackFonts   []*glfont.Font // tried in order for characters the default fonts don't have
+}
+
+// fontRun is a piece of text drawn with one font, and the number of cells it covers
+type fontRun struct {
+	font  *glfont.Font
+	text  string
+	cells uint
 }
 
 func NewFontMap(defaultFont *glfont.Font, defaultBoldFont *glfont.Font) *FontMap {
@@ -24,6 +39,9 @@ func (fm *FontMap) Free() {
 		fm.defaultBoldFont.Free()
 		fm.defaultBoldFont = nil
 	}
+
+	fm.AssignItalicFonts(nil, nil)
+	fm.AssignFallbacks(nil)
 }
 
 func (fm *FontMap) AssignFonts(defaultFont *glfont.Font, defaultBoldFont *glfont.Font) {
@@ -34,9 +52,40 @@ func (fm *FontMap) AssignFonts(defaultFont *glfont.Font, defaultBoldFont *glfont
 	fm.defaultBoldFont = defaultBoldFont
 }
 
+// AssignItalicFonts replaces the fonts used for italic and bold italic text, either of which may be nil to draw
+// the text upright instead
+func (fm *FontMap) AssignItalicFonts(italicFont *glfont.Font, boldItalicFont *glfont.Font) {
+	if fm.italicFont != nil {
+		fm.italicFont.Free()
+	}
+	if fm.boldItalicFont != nil {
+		fm.boldItalicFont.Free()
+	}
+
+	fm.italicFont = italicFont
+	fm.boldItalicFont = boldItalicFont
+}
+
+// AssignFallbacks replaces the fonts used for characters missing from the default fonts
+func (fm *FontMap) AssignFallbacks(fonts []*glfont.Font) {
+	for _, f := range fm.fallbackFonts {
+		f.Free()
+	}
+	fm.fallbackFonts = fonts
+}
+
 func (fm *FontMap) UpdateResolution(w int, h int) {
 	fm.defaultFont.UpdateResolution(w, h)
 	fm.defaultBoldFont.UpdateResolution(w, h)
+	if fm.italicFont != nil {
+		fm.italicFont.UpdateResolution(w, h)
+	}
+	if fm.boldItalicFont != nil {
+		fm.boldItalicFont.UpdateResolution(w, h)
+	}
+	for _, f := range fm.fallbackFonts {
+		f.UpdateResolution(w, h)
+	}
 }
 
 func (fm *FontMap) DefaultFont() *glfont.Font {
@@ -46,3 +95,61 @@ func (fm *FontMap) DefaultFont() *glfont.Font {
 func (fm *FontMap) BoldFont() *glfont.Font {
 	return fm.defaultBoldFont
 }
+
+// styleFont returns the font for text in the given style, falling back to the upright faces when there's no
+// italic font
+func (fm *FontMap) styleFont(bold bool, italic bool) *glfont.Font {
+	switch {
+	case bold && italic && fm.boldItalicFont != nil:
+		return fm.boldItalicFont
+	case bold:
+		return fm.defaultBoldFont
+	case italic && fm.italicFont != nil:
+		return fm.italicFont
+	}
+	return fm.defaultFont
+}
+
+// fontFor returns the font to draw r with: the font for the style if it has the character, otherwise the
+// first fallback font which does. Fallback fonts have no bold or italic faces.
+func (fm *FontMap) fontFor(r rune, bold bool, italic bool) *glfont.Font {
+	primary := fm.styleFont(bold, italic)
+	if primary.HasRune(r) {
+		return primary
+	}
+	for _, f := range fm.fallbackFonts {
+		if f.HasRune(r) {
+			return f
+		}
+	}
+	return primary
+}
+
+// runs splits text into the pieces which need drawing with different fonts. Zero width characters stay
+// with the character they attach to.
+func (fm *FontMap) runs(text string, bold bool, italic bool) []fontRun {
+	runs := []fontRun{}
+	var current fontRun
+	var builder strings.Builder
+	for _, r := range text {
+		width := buffer.RuneWidth(r)
+		f := current.font
+		if width > 0 || f == nil {
+			f = fm.fontFor(r, bold, italic)
+		}
+		if f != current.font && builder.Len() > 0 {
+			current.text = builder.String()
+			runs = append(runs, current)
+			builder.Reset()
+			current.cells = 0
+		}
+		current.font = f
+		current.cells += uint(width)
+		builder.WriteRune(r)
+	}
+	if builder.Len() > 0 {
+		current.text = builder.String()
+		runs = append(runs, current)
+	}
+	return runs
+}
@@ -0,0 +1,152 @@
+package gui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-gl/glfw/v3.3/glfw"
+	"github.com/liamg/aminal/config"
+)
+
+// fontPicker lists the installed monospace fonts, previewing each one as it is selected
+type fontPicker struct {
+	fonts    []InstalledFont // nil until discovery has finished
+	filter   string
+	matches  []InstalledFont
+	selected int
+	original config.FontConfig // restored if the picker is closed without choosing
+	chosen   bool
+}
+
+func actionFontPicker(gui *GUI) {
+	picker := &fontPicker{original: gui.config.Font}
+	gui.setOverlay(picker)
+
+	// reading every font on the system can take a moment
+	go func() {
+		fonts := InstalledFonts()
+		gui.runOnMainThread(func() {
+			picker.fonts = fonts
+			picker.update(gui)
+		})
+	}()
+}
+
+func (p *fontPicker) update(gui *GUI) {
+	p.matches = nil
+	filter := strings.ToLower(p.filter)
+	for _, font := range p.fonts {
+		if strings.Contains(strings.ToLower(font.Family), filter) {
+			p.matches = append(p.matches, font)
+		}
+	}
+	p.selected = 0
+	for i, font := range p.matches {
+		if font.Regular == gui.config.Font.Regular {
+			p.selected = i
+		}
+	}
+	p.preview(gui)
+}
+
+// preview shows the terminal in the selected font
+func (p *fontPicker) preview(gui *GUI) {
+	if gui.overlay != p || p.selected >= len(p.matches) {
+		return
+	}
+	font := p.matches[p.selected]
+	if font.Regular == gui.config.Font.Regular && font.Bold == gui.config.Font.Bold {
+		return
+	}
+	gui.config.Font.Regular = font.Regular
+	gui.config.Font.Bold = font.Bold
+	gui.config.Font.Italic = font.Italic
+	gui.config.Font.BoldItalic = font.BoldItalic
+	gui.reloadFonts()
+}
+
+func (p *fontPicker) char(gui *GUI, r rune) {
+	p.filter += string(r)
+	p.update(gui)
+}
+
+func (p *fontPicker) key(gui *GUI, key glfw.Key, mods glfw.ModifierKey) {
+	switch key {
+	case glfw.KeyBackspace:
+		if len(p.filter) > 0 {
+			runes := []rune(p.filter)
+			p.filter = string(runes[:len(runes)-1])
+			p.update(gui)
+		}
+	case glfw.KeyUp:
+		if p.selected > 0 {
+			p.selected--
+			p.preview(gui)
+		}
+	case glfw.KeyDown:
+		if p.selected < len(p.matches)-1 {
+			p.selected++
+			p.preview(gui)
+		}
+	case glfw.KeyEnter, glfw.KeyKPEnter:
+		if p.selected < len(p.matches) {
+			p.chosen = true
+			p.save(gui, p.matches[p.selected])
+		}
+		gui.setOverlay(nil)
+	}
+	gui.terminal.SetDirty()
+}
+
+// save writes the choice to the config file so it's used next time
+func (p *fontPicker) save(gui *GUI, font InstalledFont) {
+	if gui.config.Path == "" {
+		gui.logger.Infof("Using %s for this session only, as there's no config file to save it to", font.Family)
+		return
+	}
+	err := config.UpdateFile(gui.config.Path, func(c *config.Config) {
+		c.Font.Regular = font.Regular
+		c.Font.Bold = font.Bold
+		c.Font.Italic = font.Italic
+		c.Font.BoldItalic = font.BoldItalic
+	})
+	if err != nil {
+		gui.logger.Errorf("Failed to save font to %s: %s", gui.config.Path, err)
+		gui.showToast(newNotice(fmt.Sprintf("Failed to save font: %s", err)))
+	}
+}
+
+func (p *fontPicker) closed(gui *GUI) {
+	if !p.chosen && !gui.config.Font.Equal(p.original) {
+		gui.config.Font = p.original
+		gui.reloadFonts()
+	}
+}
+
+func (p *fontPicker) render(gui *GUI) {
+	lines := []string{fmt.Sprintf("font: %s_", p.filter), ""}
+	if p.fonts == nil {
+		lines = append(lines, "  Searching for installed fonts...")
+	} else if len(p.matches) == 0 {
+		lines = append(lines, "  (no matching monospace fonts)")
+	}
+
+	// keep the selection in view, leaving room for the title
+	rows := int(gui.terminal.ActiveBuffer().ViewHeight())/2 - 4
+	if rows < 1 {
+		rows = 1
+	}
+	first := 0
+	if p.selected >= rows {
+		first = p.selected - rows + 1
+	}
+	for i := first; i < len(p.matches) && i < first+rows; i++ {
+		marker := "  "
+		if i == p.selected {
+			marker = "> "
+		}
+		lines = append(lines, marker+p.matches[i].Family)
+	}
+
+	gui.textbox(2, 2, strings.Join(lines, "\n"), [3]float32{1, 1, 1}, [3]float32{0.2, 0.2, 0.4})
+}
@@ -3,16 +3,30 @@ package gui
 import (
 	"bytes"
 	"fmt"
+	"io/ioutil"
+	"os"
 
 	"github.com/gobuffalo/packr"
+	"github.com/liamg/aminal/config"
 	"github.com/liamg/aminal/glfont"
+	"github.com/liamg/aminal/terminal"
+)
+
+// from https://github.com/ryanoasis/nerd-fonts/tree/master/patched-fonts/Hack
+const (
+	regularFont = "Hack Regular Nerd Font Complete.ttf"
+	boldFont    = "Hack Bold Nerd Font Complete.ttf"
+)
+
+const (
+	defaultFontScale = 10
+	minFontScale     = 4 // the font can't be made any smaller than this with font_smaller
 )
 
 func (gui *GUI) getPackedFont(name string) (*glfont.Font, error) {
-	box := packr.NewBox("./packed-fonts")
-	fontBytes, err := box.Find(name)
+	fontBytes, err := getPackedFontData(name)
 	if err != nil {
-		return nil, fmt.Errorf("packaged font '%s' could not be read: %s", name, err)
+		return nil, err
 	}
 
 	font, err := glfont.LoadFont(bytes.NewReader(fontBytes), gui.fontScale*gui.dpiScale/gui.scale(), gui.width, gui.height)
@@ -23,26 +37,165 @@ func (gui *GUI) getPackedFont(name string) (*glfont.Font, error) {
 	return font, nil
 }
 
-func (gui *GUI) loadFonts() error {
-	// from https://github.com/ryanoasis/nerd-fonts/tree/master/patched-fonts/Hack
+func getPackedFontData(name string) ([]byte, error) {
+	box := packr.NewBox("./packed-fonts")
+	fontBytes, err := box.Find(name)
+	if err != nil {
+		return nil, fmt.Errorf("packaged font '%s' could not be read: %s", name, err)
+	}
+	return fontBytes, nil
+}
 
-	defaultFont, err := gui.getPackedFont("Hack Regular Nerd Font Complete.ttf")
+// fontData returns the contents of the font file at path, or of the named packaged font if there's no path
+func (gui *GUI) fontData(path string, fallback string) ([]byte, error) {
+	if path != "" {
+		return ioutil.ReadFile(path)
+	}
+	return getPackedFontData(fallback)
+}
+
+// loadFontFile loads the font file at path at the current font size
+func (gui *GUI) loadFontFile(path string) (*glfont.Font, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return glfont.LoadFont(f, gui.fontScale*gui.dpiScale/gui.scale(), gui.width, gui.height)
+}
+
+// getFont loads the font file at path, falling back to the named packaged font if there's no path or it can't be loaded
+func (gui *GUI) getFont(path string, fallback string) (*glfont.Font, error) {
+	if path != "" {
+		font, err := gui.loadFontFile(path)
+		if err == nil {
+			return font, nil
+		}
+		gui.logger.Errorf("Failed to load font '%s', using '%s' instead: %s", path, fallback, err)
+	}
+	return gui.getPackedFont(fallback)
+}
+
+// getStyleFont loads the font file for an optional style, returning nil if there's no path or it can't be loaded,
+// so the text is drawn with the upright font instead
+func (gui *GUI) getStyleFont(path string) *glfont.Font {
+	if path == "" {
+		return nil
+	}
+	font, err := gui.loadFontFile(path)
+	if err != nil {
+		gui.logger.Errorf("Failed to load font '%s': %s", path, err)
+		return nil
+	}
+	return font
+}
+
+// font returns the font settings of the tab being shown, which are those in the config unless its profile changes them
+func (gui *GUI) font() config.FontConfig {
+	if gui.activeTab >= 0 && gui.activeTab < len(gui.tabs) && gui.tabs[gui.activeTab].font != nil {
+		return *gui.tabs[gui.activeTab].font
+	}
+	return gui.config.Font
+}
+
+func (gui *GUI) loadFonts() error {
+	font := gui.font()
+	regular, err := gui.getFont(font.Regular, regularFont)
 	if err != nil {
 		return err
 	}
 
-	boldFont, err := gui.getPackedFont("Hack Bold Nerd Font Complete.ttf")
+	bold, err := gui.getFont(font.Bold, boldFont)
 	if err != nil {
 		return err
 	}
 
 	if gui.fontMap == nil {
-		gui.fontMap = NewFontMap(defaultFont, boldFont)
+		gui.fontMap = NewFontMap(regular, bold)
 	} else {
-		gui.fontMap.AssignFonts(defaultFont, boldFont)
+		gui.fontMap.AssignFonts(regular, bold)
 	}
+	gui.fontMap.AssignItalicFonts(gui.getStyleFont(font.Italic), gui.getStyleFont(font.BoldItalic))
 
-	// add special non-ascii fonts here
+	fallbacks := []*glfont.Font{}
+	for _, path := range font.Fallback {
+		font, err := gui.loadFontFile(path)
+		if err != nil {
+			gui.logger.Errorf("Failed to load fallback font '%s': %s", path, err)
+			continue
+		}
+		fallbacks = append(fallbacks, font)
+	}
+	gui.fontMap.AssignFallbacks(fallbacks)
+	gui.loadedFont = font
 
 	return nil
 }
+
+// reloadFonts rebuilds the font map and cell metrics, resizing the grid to fit. Must be called on the OS thread.
+func (gui *GUI) reloadFonts() {
+	if size := gui.font().Size; size > 0 {
+		gui.fontScale = size
+	}
+	// force resize() to recalculate everything even though the window size hasn't changed
+	gui.appliedWidth = 0
+	gui.appliedHeight = 0
+	gui.resize(gui.window, gui.width, gui.height)
+}
+
+// fontPixels converts a length in the config's font section to pixels in the framebuffer, scaling it with the text
+func (gui *GUI) fontPixels(length float32) float32 {
+	return length * gui.dpiScale / gui.scale()
+}
+
+// changeFontSize applies a change to the size of the text asked for by an application
+func (gui *GUI) changeFontSize(change terminal.FontSizeChange) {
+	if change.Reset {
+		gui.setFontScale(configuredFontScale(gui.config))
+		return
+	}
+	scale := gui.fontScale + float32(change.Steps)
+	if scale < minFontScale {
+		scale = minFontScale
+	}
+	gui.setFontScale(scale)
+}
+
+// rememberFontScale saves the size of the text to the config file, if it's set to remember it, so it's the same
+// next time
+func (gui *GUI) rememberFontScale() {
+	if !gui.config.Font.RememberSize || gui.config.Font.Size == gui.fontScale {
+		return
+	}
+	if gui.config.Path == "" {
+		gui.logger.Infof("Can't remember the font size, as there's no config file to save it to")
+		return
+	}
+	// updated first, so the change to the file isn't taken as a new font to load
+	gui.config.Font.Size = gui.fontScale
+	err := config.UpdateFile(gui.config.Path, func(c *config.Config) {
+		c.Font.Size = gui.fontScale
+	})
+	if err != nil {
+		gui.logger.Errorf("Failed to save font size to %s: %s", gui.config.Path, err)
+	}
+}
+
+// configuredFontScale returns the font size set in the config, or the default if there isn't one
+func configuredFontScale(conf *config.Config) float32 {
+	if conf.Font.Size > 0 {
+		return conf.Font.Size
+	}
+	return defaultFontScale
+}
+
+// setFontScale changes the size of the text for the rest of the session, resizing the terminals to fit
+func (gui *GUI) setFontScale(scale float32) {
+	if scale < minFontScale || scale == gui.fontScale {
+		return
+	}
+	gui.fontScale = scale
+	gui.appliedWidth = 0
+	gui.appliedHeight = 0
+	gui.resize(gui.window, gui.width, gui.height)
+}
@@ -5,6 +5,7 @@ import (
 	"image"
 	"image/png"
 	"math"
+	"net"
 	"os"
 	"strconv"
 	"strings"
@@ -18,43 +19,108 @@ import (
 	"github.com/liamg/aminal/buffer"
 	"github.com/liamg/aminal/config"
 	"github.com/liamg/aminal/platform"
+	"github.com/liamg/aminal/plugins"
+	"github.com/liamg/aminal/recording"
+	"github.com/liamg/aminal/share"
 	"github.com/liamg/aminal/terminal"
 	"github.com/liamg/aminal/version"
 	"go.uber.org/zap"
 )
 
 type GUI struct {
-	window            *glfw.Window
-	logger            *zap.SugaredLogger
-	config            *config.Config
-	terminal          *terminal.Terminal
-	width             int // window width in pixels
-	height            int // window height in pixels
-	appliedWidth      int
-	appliedHeight     int
-	resizeCache       *ResizeCache // resize cache formed by resizeToTerminal()
-	dpiScale          float32
-	fontMap           *FontMap
-	fontScale         float32
-	renderer          *OpenGLRenderer
-	colourAttr        uint32
-	mouseDown         bool
-	mouseDownModifier glfw.ModifierKey
-	overlay           overlay
-	terminalAlpha     float32
-	showDebugInfo     bool
-	keyboardShortcuts map[config.UserAction]*config.KeyCombination
-	resizeLock        *sync.Mutex
-	handCursor        *glfw.Cursor
-	arrowCursor       *glfw.Cursor
-	defaultCell       *buffer.Cell
+	window                *glfw.Window
+	logger                *zap.SugaredLogger
+	config                *config.Config
+	terminal              *terminal.Terminal
+	width                 int // window width in pixels
+	height                int // window height in pixels
+	appliedWidth          int
+	appliedHeight         int
+	resizeCache           *ResizeCache // resize cache formed by resizeToTerminal()
+	dpiScale              float32
+	geometry              config.Geometry // the size in cells and position asked for in the config, applied once the window is open
+	fontMap               *FontMap
+	loadedFont            config.FontConfig // the fonts in the font map, which change with tabs with fonts of their own
+	fontScale             float32
+	renderer              *OpenGLRenderer
+	colourAttr            uint32
+	mouseDown             bool
+	mouseDownModifier     glfw.ModifierKey
+	overlay               overlay
+	terminalAlpha         float32 // opacity of the default background, which the window is cleared to
+	showDebugInfo         bool
+	keyboardShortcuts     map[config.UserAction]*config.KeyCombination
+	keyBindings           []config.KeyBinding // matched before keyboardShortcuts
+	resizeLock            *sync.Mutex
+	handCursor            *glfw.Cursor
+	arrowCursor           *glfw.Cursor
+	defaultCell           *buffer.Cell
+	linkRules             []buffer.LinkRule
+	triggers              []terminal.Trigger
+	linkModifier          glfw.ModifierKey // held to underline and click links
+	hoverLink             *buffer.Link     // rule-generated link or file location currently under the mouse
+	hoverLinkRow          uint16
+	input                 chan pendingInput // keyboard input waiting to be written to the pty
+	keyReported           bool              // the last key was sent as an escape code, so its character isn't typed too
+	latency               *latencyTracker   // only set when measuring input latency
+	postProcessor         *postProcessor    // only set when user shaders are loaded
+	frameCache            *frameCache       // only set when offscreen drawing is available, otherwise every frame is drawn in full
+	captureTarget         uint32            // the framebuffer frames are drawn into while being captured, see capture.go
+	hidden                bool              // window is iconified or hidden, so there's no point drawing
+	throttled             int32             // set atomically while the window is hidden or unfocused, so output doesn't wake the render loop
+	mainThreadQueue       chan func()       // work from other goroutines which must run on the OS thread
+	toast                 *toast
+	recordingMacro        bool
+	macroInput            []byte // written to the pty since recording started
+	preedit               preedit
+	scrollbackWarnAt      uint64 // scrollback memory usage at which to warn the user next
+	spillErrorShown       bool
+	tabs                  []*tab
+	activeTab             int
+	newSession            SessionFactory // only set when new tabs can be opened
+	titleChan             chan bool      // events from the terminals in every tab
+	resizeChan            chan bool
+	reverseChan           chan bool
+	coloursChan           chan bool
+	hostChan              chan bool
+	outputChan            chan bool
+	themeChan             chan string // themes asked for by applications
+	fontSizeChan          chan terminal.FontSizeChange
+	commandChan           chan terminal.CommandFinished
+	triggerChan           chan terminal.TriggerMatch
+	bellChan              chan bool
+	flashUntil            time.Time // the visual bell is shown until then
+	lastBell              time.Time // when the bell's sound and command last went off
+	showMinimap           bool
+	minimap               *buffer.Minimap // of the focused terminal, made when the minimap is first drawn
+	minimapDragging       bool
+	showDiff              bool
+	diffBaseline          *buffer.Baseline    // set by the user, otherwise the previous command's output is used
+	share                 *share.Server       // only set once sharing has been started
+	recorder              *recording.Recorder // only set when clip recording is enabled
+	control               net.Listener        // only set while the control socket is listening, see control.go
+	recordPending         bool                // the screen has changed since the last recorded frame
+	resizeIncrements      [4]int              // last base size and resize increments given to the window manager
+	sizeShownUntil        time.Time
+	blinkOn               bool // blinking text is in the shown part of its cycle
+	windowFocused         bool
+	cursorBlinkStart      time.Time // the cursor blink cycle is timed from here
+	drawnCursorVisibility float32   // how much of the cursor was shown when it was last drawn
+	primarySelection      string    // the last text selected with the mouse, pasted with the middle button
+	drawnCursorCol        uint      // where the cursor was last drawn in the focused pane
+	drawnCursorRow        uint
+	scrollbar             scrollbar
+	scrollRemainder       float64 // the part of a line scrolled by the wheel which hasn't been scrolled yet
+	pinchScale            float64 // how much the text has been pinched bigger or smaller and not yet resized by
+	pinchTimer            *time.Timer
+	plugins               *plugins.Manager
 
 	prevLeftClickX                  uint16
 	prevLeftClickY                  uint16
 	prevMotionTX                    int
 	prevMotionTY                    int
 	leftClickTime                   time.Time
-	leftClickCount                  int // number of clicks in a serie - single click, double click, or triple click
+	leftClickCount                  int // number of clicks in a serie - single, double, triple or quadruple click
 	mouseMovedAfterSelectionStarted bool
 	internalResize                  bool
 }
@@ -145,21 +211,82 @@ func New(config *config.Config, terminal *terminal.Terminal, logger *zap.Sugared
 		return nil, err
 	}
 
-	return &GUI{
+	linkRules, err := newLinkRules(config.Links)
+	if err != nil {
+		return nil, err
+	}
+	linkModifier, err := parseLinkModifier(config.LinkModifier)
+	if err != nil {
+		return nil, err
+	}
+
+	keyBindings, err := newKeyBindings(config.Bindings)
+	if err != nil {
+		return nil, err
+	}
+
+	triggers, err := newTriggers(config.Triggers)
+	if err != nil {
+		return nil, err
+	}
+
+	geometry, err := startGeometry(config.Window)
+	if err != nil {
+		return nil, err
+	}
+
+	var latency *latencyTracker
+	if config.MeasureLatency {
+		latency = newLatencyTracker()
+	}
+
+	var recorder *recording.Recorder
+	if config.Clip.Seconds > 0 {
+		recorder = recording.NewRecorder(time.Duration(config.Clip.Seconds)*time.Second, config.Clip.FPS)
+	}
+
+	gui := &GUI{
 		config:            config,
 		logger:            logger,
-		width:             800,
-		height:            600,
+		width:             config.Window.Width,
+		height:            config.Window.Height,
+		geometry:          geometry,
 		appliedWidth:      0,
 		appliedHeight:     0,
 		dpiScale:          1,
 		terminal:          terminal,
-		fontScale:         10.0,
-		terminalAlpha:     1,
+		fontScale:         configuredFontScale(config),
+		terminalAlpha:     clampOpacity(config.BackgroundOpacity, 0),
 		keyboardShortcuts: shortcuts,
+		keyBindings:       keyBindings,
+		linkRules:         linkRules,
+		triggers:          triggers,
+		linkModifier:      linkModifier,
+		input:             make(chan pendingInput, 1024),
+		mainThreadQueue:   make(chan func()),
+		latency:           latency,
+		tabs:              []*tab{newTab(terminal)},
+		titleChan:         make(chan bool, 1),
+		resizeChan:        make(chan bool, 1),
+		reverseChan:       make(chan bool, 1),
+		coloursChan:       make(chan bool, 1),
+		hostChan:          make(chan bool, 1),
+		outputChan:        make(chan bool, 1),
+		themeChan:         make(chan string, 1),
+		bellChan:          make(chan bool, 1),
+		recorder:          recorder,
+		plugins:           plugins.Load(pluginsDirectory(config), logger),
 		resizeLock:        &sync.Mutex{},
 		internalResize:    false,
-	}, nil
+		blinkOn:           true,
+		showMinimap:       config.Minimap,
+		windowFocused:     true,
+		cursorBlinkStart:  time.Now(),
+	}
+	if profile, ok := config.ProfileNamed(config.Profile); ok && config.Profile != "" {
+		gui.useProfile(gui.tabs[0], profile)
+	}
+	return gui, nil
 }
 
 // inspired by https://kylewbanks.com/blog/tutorial-opengl-with-golang-part-1-hello-opengl
@@ -179,7 +306,7 @@ func (gui *GUI) resizeToTerminal(newCols uint, newRows uint) {
 	gui.resizeLock.Lock()
 	defer gui.resizeLock.Unlock()
 
-	cols, rows := gui.renderer.GetTermSize()
+	cols, rows := gui.terminalGridSize()
 	if cols == newCols && rows == newRows {
 		return
 	}
@@ -187,7 +314,7 @@ func (gui *GUI) resizeToTerminal(newCols uint, newRows uint) {
 	gui.logger.Debugf("Initiating GUI resize to columns=%d rows=%d", newCols, newRows)
 
 	gui.logger.Debugf("Calculating size...")
-	width, height := gui.renderer.GetRectangleSize(newCols, newRows)
+	width, height := gui.renderer.GetRectangleSize(newCols+gui.minimapCols(), newRows+gui.tabBarRows())
 
 	roundedWidth := int(math.Ceil(float64(width)))
 	roundedHeight := int(math.Ceil(float64(height)))
@@ -200,25 +327,28 @@ func (gui *GUI) resizeToTerminal(newCols uint, newRows uint) {
 	gui.internalResize = false
 }
 
+// generateDefaultCell takes the background from the focused terminal, whose colours may have been changed by the application
 func (gui *GUI) generateDefaultCell(reverse bool) {
-	color := gui.config.ColourScheme.Background
+	options := gui.terminal.Options()
+	color := options.Background
 	if reverse {
-		color = gui.config.ColourScheme.Foreground
+		color = options.Foreground
 	}
 	cell := buffer.NewBackgroundCell(color)
 	gui.renderer.backgroundColour = color
 	gui.defaultCell = &cell
+	// compositors expect the colour premultiplied by the alpha
 	gl.ClearColor(
-		color[0],
-		color[1],
-		color[2],
-		1.0,
+		color[0]*gui.terminalAlpha,
+		color[1]*gui.terminalAlpha,
+		color[2]*gui.terminalAlpha,
+		gui.terminalAlpha,
 	)
 }
 
 func (gui *GUI) getCursorBg(cell *buffer.Cell) (bg [3]float32) {
-	if gui.config.ColourScheme.Cursor != cell.Bg() {
-		bg = gui.config.ColourScheme.Cursor
+	if cursor := gui.terminal.Options().Cursor; cursor != cell.Bg() {
+		bg = cursor
 	} else {
 		bg = cell.Fg()
 	}
@@ -256,30 +386,47 @@ func (gui *GUI) resize(w *glfw.Window, width int, height int) {
 	gui.loadFonts()
 
 	gui.logger.Debugf("Setting renderer area...")
-	gui.renderer.SetArea(0, 0, gui.width, gui.height)
+	font := gui.font()
+	gui.renderer.SetSpacing(gui.fontPixels(font.LetterSpacing), font.LineHeight)
+	padding := int(gui.fontPixels(font.Padding))
+	gui.renderer.SetArea(padding, padding, gui.width, gui.height)
+	gui.updateResizeIncrements()
 
 	if gui.resizeCache != nil && gui.resizeCache.Width == width && gui.resizeCache.Height == height {
 		gui.logger.Debugf("No need to resize internal terminal!")
 	} else {
-		gui.logger.Debugf("Calculating size in cols/rows...")
-		cols, rows := gui.renderer.GetTermSize()
-		gui.logger.Debugf("Resizing internal terminal...")
-		if err := gui.terminal.SetSize(cols, rows); err != nil {
-			gui.logger.Errorf("Failed to resize terminal to %d cols, %d rows: %s", cols, rows, err)
-		}
+		gui.showSize()
 	}
 
+	// terminals which are already the right size are left alone, so this is safe after a resize the terminal asked for
+	gui.logger.Debugf("Resizing internal terminals...")
+	gui.layoutPanes()
+
 	gui.resizeCache = nil
 
 	gui.logger.Debugf("Setting viewport size...")
 	gl.Viewport(0, 0, int32(gui.width), int32(gui.height))
 
-	gui.terminal.SetCharSize(gui.renderer.cellWidth, gui.renderer.cellHeight)
+	if gui.postProcessor != nil {
+		if err := gui.postProcessor.resize(gui.width, gui.height); err != nil {
+			gui.logger.Errorf("Disabling post-processing shaders: %s", err)
+			gui.postProcessor = nil
+		}
+	}
+
+	if gui.frameCache == nil {
+		gui.frameCache = newFrameCache()
+	}
+	if err := gui.frameCache.resize(gui.width, gui.height); err != nil {
+		gui.logger.Errorf("Drawing every frame in full: %s", err)
+		gui.frameCache.free()
+		gui.frameCache = nil
+	}
 
 	gui.logger.Debugf("Resize complete!")
 
 	gui.redraw()
-	gui.window.SwapBuffers()
+	gui.SwapBuffers()
 }
 
 func (gui *GUI) getTermSize() (uint, uint) {
@@ -289,6 +436,18 @@ func (gui *GUI) getTermSize() (uint, uint) {
 	return gui.renderer.GetTermSize()
 }
 
+// runOnMainThread runs f on the OS thread via the render loop, returning once it has completed.
+// It must not be called from the OS thread itself.
+func (gui *GUI) runOnMainThread(f func()) {
+	done := make(chan struct{})
+	glfw.PostEmptyEvent()
+	gui.mainThreadQueue <- func() {
+		f()
+		close(done)
+	}
+	<-done
+}
+
 func (gui *GUI) Close() {
 	gui.window.SetShouldClose(true)
 }
@@ -319,44 +478,65 @@ func (gui *GUI) Render() error {
 		return fmt.Errorf("Failed to load font: %s", err)
 	}
 
-	titleChan := make(chan bool, 1)
-	resizeChan := make(chan bool, 1)
-	reverseChan := make(chan bool, 1)
-
 	gui.renderer = NewOpenGLRenderer(gui.config, gui.fontMap, 0, 0, gui.width, gui.height, gui.colourAttr, program)
+	gui.postProcessor = gui.loadPostProcessShaders(gui.config.ShaderDirectory)
 
 	gui.window.SetFramebufferSizeCallback(gui.resize)
 	gui.window.SetKeyCallback(gui.key)
 	gui.window.SetCharCallback(gui.char)
+	hookInputMethod(gui)
+	hookPinch(gui)
 	gui.window.SetScrollCallback(gui.glfwScrollCallback)
 	gui.window.SetMouseButtonCallback(gui.mouseButtonCallback)
 	gui.window.SetCursorPosCallback(gui.mouseMoveCallback)
+	gui.window.SetDropCallback(gui.drop)
 	gui.window.SetRefreshCallback(func(w *glfw.Window) {
+		gui.updateVisibility()
 		gui.terminal.SetDirty()
 	})
 	gui.window.SetFocusCallback(func(w *glfw.Window, focused bool) {
+		// the focused terminal only hears it's lost focus while the window still has it
+		if !focused {
+			gui.reportFocus(gui.terminal, false)
+		}
+		gui.windowFocused = focused
+		gui.updateThrottle()
+		gui.resetCursorBlink()
 		if focused {
-			gui.terminal.SetDirty()
+			gui.resync()
+			gui.reportFocus(gui.terminal, true)
+		} else {
+			gui.terminal.SetDirty() // for the cursor
 		}
 	})
 	gui.window.SetPosCallback(gui.windowPosChangeCallback)
+	gui.window.SetIconifyCallback(func(w *glfw.Window, iconified bool) {
+		gui.updateVisibility()
+	})
 	glfw.SetMonitorCallback(gui.monitorChangeCallback)
 
 	gui.generateDefaultCell(false)
+	gui.setWindowOpacity()
+	setBlur(gui.window, gui.config.Blur)
 
 	{
 		w, h := gui.window.GetFramebufferSize()
 		gui.resize(gui.window, w, h)
 	}
+	gui.placeWindow()
 
 	gui.logger.Debugf("Starting pty read handling...")
 
+	go gui.processInput()
+
+	// wake the render loop as soon as output has been processed, rather than waiting for the next event timeout
 	go func() {
-		err := gui.terminal.Read()
-		if err != nil {
-			gui.logger.Errorf("Read from pty failed: %s", err)
+		for range gui.outputChan {
+			if gui.latency != nil {
+				gui.latency.outputParsed()
+			}
+			gui.wakeForOutput()
 		}
-		gui.Close()
 	}()
 
 	gui.logger.Debugf("Starting render...")
@@ -367,9 +547,13 @@ func (gui *GUI) Render() error {
 	gl.Disable(gl.DEPTH_TEST)
 	gl.TexParameterf(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.NEAREST)
 
-	gui.terminal.AttachTitleChangeHandler(titleChan)
-	gui.terminal.AttachResizeHandler(resizeChan)
-	gui.terminal.AttachReverseHandler(reverseChan)
+	// made here rather than in New, where the terminal package is hidden by the parameter
+	gui.fontSizeChan = make(chan terminal.FontSizeChange, 1)
+	gui.commandChan = make(chan terminal.CommandFinished, 1)
+	gui.triggerChan = make(chan terminal.TriggerMatch, 1)
+	for _, tab := range gui.tabs {
+		gui.attachTerminal(tab.focus.terminal)
+	}
 
 	ticker := time.NewTicker(time.Second)
 	defer ticker.Stop()
@@ -381,8 +565,6 @@ func (gui *GUI) Render() error {
 		}
 	}()
 
-	gui.terminal.SetProgram(program)
-
 	latestVersion := ""
 
 	go func() {
@@ -393,6 +575,27 @@ func (gui *GUI) Render() error {
 		}
 	}()
 
+	gui.startControl()
+
+	configChan := make(chan []string, 1)
+	configWatcher := newFileWatcher(gui.watchedFiles()...)
+	go func() {
+		configTicker := time.NewTicker(time.Second)
+		defer configTicker.Stop()
+		for range configTicker.C {
+			if changed := configWatcher.changed(); len(changed) > 0 {
+				configChan <- changed
+				glfw.PostEmptyEvent()
+			}
+		}
+	}()
+
+	scrollbackTicker := time.NewTicker(scrollbackCheckInterval)
+	defer scrollbackTicker.Stop()
+
+	blinkTicker := time.NewTicker(textBlinkInterval)
+	defer blinkTicker.Stop()
+
 	startTime := time.Now()
 	showMessage := true
 
@@ -401,33 +604,105 @@ func (gui *GUI) Render() error {
 		forceRedraw := false
 
 		select {
-		case <-titleChan:
+		case <-gui.titleChan:
 			gui.window.SetTitle(gui.terminal.GetTitle())
-		case <-resizeChan:
-			cols, rows := gui.terminal.GetSize()
-			gui.resizeToTerminal(uint(cols), uint(rows))
-		case reverse := <-reverseChan:
+			forceRedraw = len(gui.tabs) > 1 // for the tab bar
+		case <-gui.resizeChan:
+			// a terminal can only resize the window when it has the window to itself
+			if gui.currentTab().root.isLeaf() {
+				cols, rows := gui.terminal.GetSize()
+				gui.resizeToTerminal(uint(cols), uint(rows))
+			}
+		case reverse := <-gui.reverseChan:
 			gui.generateDefaultCell(reverse)
 			forceRedraw = true
+		case <-gui.coloursChan:
+			gui.generateDefaultCell(gui.terminal.ScreenMode())
+			forceRedraw = true
+		case <-gui.hostChan:
+			gui.checkHosts()
+			forceRedraw = true
+		case name := <-gui.themeChan:
+			if err := gui.setTheme(name); err != nil {
+				gui.logger.Errorf("Failed to switch theme: %s", err)
+			}
+			forceRedraw = true
+		case change := <-gui.fontSizeChan:
+			gui.changeFontSize(change)
+		case finished := <-gui.commandChan:
+			gui.commandFinished(finished)
+		case match := <-gui.triggerChan:
+			gui.runTrigger(match)
+		case <-gui.bellChan:
+			gui.ringBell()
+			forceRedraw = len(gui.tabs) > 1 // for the tab bar
+		case f := <-gui.mainThreadQueue:
+			f()
+		case <-scrollbackTicker.C:
+			gui.checkScrollbackMemory()
+		case <-blinkTicker.C:
+			gui.blinkOn = !gui.blinkOn
+			forceRedraw = gui.showsBlinkingText()
+		case changed := <-configChan:
+			reload := false
+			for _, path := range changed {
+				if path != gui.config.Path {
+					reload = true // a font file itself has changed
+				} else if gui.applyConfigFile() {
+					reload = true
+					configWatcher.watch(gui.watchedFiles()...)
+				}
+			}
+			forceRedraw = true
+			if reload {
+				gui.logger.Infof("Reloading fonts...")
+				gui.reloadFonts()
+			}
 		default:
-			// this is more efficient than glfw.PollEvents()
-			glfw.WaitEventsTimeout(0.02) // up to 50fps on no input, otherwise higher
+			gui.waitEvents()
+		}
+
+		if gui.checkBackgroundTabs() {
+			forceRedraw = true
+		}
+		if forceRedraw {
+			gui.invalidateFrame()
+		}
+		// the cursor and scrollbar are drawn over what's there, so they don't need everything drawn again
+		redraw := forceRedraw || gui.cursorNeedsRedraw() || gui.scrollbarNeedsRedraw()
+
+		if gui.recordPending && gui.recorder.Due() {
+			gui.recordFrame()
 		}
 
-		if gui.terminal.CheckDirty() || forceRedraw {
+		if gui.hidden {
+			// leave the terminal dirty so we redraw everything once we're visible again
+			continue
+		}
+
+		if gui.checkDirty() || redraw {
 
 			gui.redraw()
 
+			if gui.recorder != nil {
+				gui.recordPending = true
+			}
+
 			if gui.showDebugInfo {
+				latency := ""
+				if gui.latency != nil {
+					latency = gui.latency.String()
+				}
 				gui.textbox(2, 2, fmt.Sprintf(`Cursor:      %d,%d
 View Size:   %d,%d
 Buffer Size: %d lines
-`,
+%s`,
 					gui.terminal.GetLogicalCursorX(),
 					gui.terminal.GetLogicalCursorY(),
 					gui.terminal.ActiveBuffer().ViewWidth(),
 					gui.terminal.ActiveBuffer().ViewHeight(),
 					gui.terminal.ActiveBuffer().Height(),
+					latency,
 				),
 					[3]float32{1, 1, 1},
 					[3]float32{0.8, 0, 0},
@@ -456,35 +731,113 @@ Buffer Size: %d lines
 				}
 			}
 
+			gui.renderSize()
+
 			gui.SwapBuffers()
+
+			if gui.latency != nil {
+				gui.latency.framePresented()
+			}
 		}
 
 	}
 
+	if gui.share != nil {
+		_ = gui.share.Stop()
+	}
+	if gui.control != nil {
+		gui.control.Close()
+	}
+	gui.plugins.Close()
+
 	gui.logger.Debugf("Stopping render...")
 	return nil
 }
 
 func (gui *GUI) redraw() {
-	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT | gl.STENCIL_BUFFER_BIT)
-	lines := gui.terminal.GetVisibleLines()
-	lineCount := int(gui.terminal.ActiveBuffer().ViewHeight())
-	colCount := int(gui.terminal.ActiveBuffer().ViewWidth())
-	cx := uint(gui.terminal.GetLogicalCursorX())
-	cy := uint(gui.terminal.GetLogicalCursorY()) + uint(gui.terminal.GetScrollOffset())
+	target := gui.captureTarget // the window or a capture, or the texture the post-processing passes start from
+	if gui.postProcessor != nil && target == 0 {
+		gui.postProcessor.begin()
+		target = gui.postProcessor.fbos[0]
+	}
+	tab := gui.currentTab()
+	all := true // without the cache, every frame is drawn from scratch
+	if gui.frameCache != nil {
+		all = !gui.frameCache.begin(tab)
+	}
+	if all {
+		gui.renderer.Clear()
+	}
+	for _, p := range tab.root.leaves() {
+		damage := p.damage
+		p.damage = buffer.Damage{}
+		if p.cols == 0 || p.rows == 0 {
+			continue // squeezed out, see splitCells
+		}
+		if all {
+			damage.All = true
+		}
+		gui.renderer.SetViewport(p.col, p.row)
+		gui.drawPane(p, damage, p == tab.focus)
+	}
+	if gui.frameCache != nil {
+		gui.frameCache.present(target)
+	}
+	gui.renderer.SetViewport(0, 0)
+	gui.renderDividers(tab.root)
+	gui.renderTabBar()
+	gui.renderMinimap()
+
+	// everything else belongs to the focused terminal, so is drawn over its pane
+	gui.renderer.SetViewport(tab.focus.col, tab.focus.row)
+	gui.renderScrollbar(tab.focus)
+	gui.renderPlugins()
+	gui.renderPreedit()
+	gui.renderOverlay()
+	if gui.toast != nil {
+		gui.toast.render(gui)
+	}
+	gui.renderBell()
+}
+
+// redrawTerminal draws the damaged rows of t from the origin of the renderer's viewport. Selection, find and
+// diff highlights and links under the mouse are only shown when it's the focused terminal.
+func (gui *GUI) redrawTerminal(t *terminal.Terminal, focused bool, damage *buffer.Damage) {
+	lines := t.GetVisibleLines()
+	lineCount := int(t.ActiveBuffer().ViewHeight())
+	colCount := int(t.ActiveBuffer().ViewWidth())
+	cx, cy := cursorCell(t)
 	var colour *config.Colour
+	var diffBaseline *buffer.Baseline
+	var find *findBar
+	showCursor := focused && t.Modes().ShowCursor
+	hollowCursor := gui.cursorHollow()
+	cursorVisibility := gui.cursorVisibility(t)
+	if focused {
+		diffBaseline = gui.currentDiffBaseline()
+		find = gui.findBar()
+		gui.drawnCursorVisibility = cursorVisibility
+		gui.drawnCursorCol, gui.drawnCursorRow = cx, cy
+	}
+	rows, from := gui.visualRows(t, lines, colCount)
+	if int(cy) < len(from) {
+		cx = visualColumn(from[cy], cx)
+	}
 	for y := 0; y < lineCount; y++ {
-		if y < len(lines) {
-			cells := lines[y].Cells()
+		if y < len(lines) && damage.Row(y) {
+			cells := rows[y]
 			for x := 0; x < colCount; x++ {
 
-				cursor := false
-				if gui.terminal.Modes().ShowCursor {
-					cursor = cx == uint(x) && cy == uint(y)
-				}
+				cursor := showCursor && !hollowCursor && cx == uint(x) && cy == uint(y)
 
-				if gui.terminal.ActiveBuffer().InSelection(uint16(x), uint16(y)) {
+				// highlights follow the text, wherever it has been moved to by the bidirectional algorithm
+				lx := logicalColumn(from[y], x)
+				if t.ActiveBuffer().InSelection(uint16(lx), uint16(y)) {
 					colour = &gui.config.ColourScheme.Selection
+				} else if highlight := find.highlight(gui, lx, y); highlight != nil {
+					colour = highlight
+				} else if diffBaseline != nil && t.ActiveBuffer().ChangedSince(diffBaseline, uint16(lx), uint16(y)) {
+					colour = &gui.config.ColourScheme.Diff
 				} else {
 					colour = nil
 				}
@@ -501,7 +854,12 @@ func (gui *GUI) redraw() {
 					}
 
 					if cursor {
-						var bgColour config.Colour = gui.getCursorBg(cell)
+						// the cursor fades in and out over whatever the cell would otherwise have behind it
+						bg := cell.Bg()
+						if colour != nil {
+							bg = *colour
+						}
+						var bgColour config.Colour = blend(bg, gui.getCursorBg(cell), cursorVisibility)
 						colour = &bgColour
 					}
 
@@ -512,87 +870,146 @@ func (gui *GUI) redraw() {
 		}
 	}
 	for y := 0; y < lineCount; y++ {
-		if y < len(lines) {
+		if y < len(lines) && damage.Row(y) {
 
 			var builder strings.Builder
 			bold := false
+			italic := false
 			dim := false
 			col := 0
 			colour := [3]float32{0, 0, 0}
-			cells := lines[y].Cells()
+			cells := rows[y]
+
+			// flush draws the run of text built up so far, and starts the next one at the given column
+			flush := func(next int) {
+				if builder.Len() > 0 {
+					var alpha float32 = 1.0
+					if dim {
+						alpha = 0.5
+					}
+					gui.renderer.DrawCellText(builder.String(), uint(col), uint(y), alpha, colour, bold, italic)
+					builder.Reset()
+				}
+				col = next
+			}
 
 			for x := 0; x < colCount; x++ {
 				if x < len(cells) {
 					cell := cells[x]
 
-					cursor := false
-					if gui.terminal.Modes().ShowCursor {
-						cursor = cx == uint(x) && cy == uint(y)
+					if cell.Continuation() || !gui.textVisible(&cell) {
+						// the wide character before has already been drawn over this cell, or there's nothing to show
+						flush(x + 1)
+						continue
 					}
 
+					cursor := showCursor && !hollowCursor && cx == uint(x) && cy == uint(y)
+
 					var newFg [3]float32
 					if cursor {
-						newFg = gui.getCursorFg(&cell)
+						newFg = blend(cell.Fg(), gui.getCursorFg(&cell), cursorVisibility)
 					} else {
 						newFg = cell.Fg()
 					}
 
-					if builder.Len() > 0 && (cell.Attr().Dim != dim || cell.Attr().Bold != bold || colour != newFg) {
-						var alpha float32 = 1.0
-						if dim {
-							alpha = 0.5
-						}
-						gui.renderer.DrawCellText(builder.String(), uint(col), uint(y), alpha, colour, bold)
-						col = x
-						builder.Reset()
+					// wide and combined characters aren't the width of one cell in the font, so they're
+					// drawn on their own to keep the rest of the line on the grid
+					alone := cell.Wide() || len(cell.Combining()) > 0
+					if builder.Len() > 0 && (alone || cell.Attr().Dim != dim || cell.Attr().Bold != bold || cell.Attr().Italic != italic || colour != newFg) {
+						flush(x)
 					}
 					dim = cell.Attr().Dim
 					colour = newFg
 					bold = cell.Attr().Bold
-					r := cell.Rune()
-					if r == 0 {
-						r = ' '
+					italic = cell.Attr().Italic
+					builder.WriteString(cell.Text())
+					if alone {
+						flush(x + 1)
 					}
-					builder.WriteRune(r)
 				}
 			}
-			if builder.Len() > 0 {
-				var alpha float32 = 1.0
-				if dim {
-					alpha = 0.5
-				}
-				gui.renderer.DrawCellText(builder.String(), uint(col), uint(y), alpha, colour, bold)
-			}
+			flush(0)
 		}
 	}
-	// underlines
+	// underlines, strikethroughs and overlines
 	for y := 0; y < lineCount; y++ {
-		if y < len(lines) {
+		if y < len(lines) && damage.Row(y) {
 
 			span := 0
 			colour := [3]float32{0, 0, 0}
-			cells := lines[y].Cells()
+			style := buffer.UnderlineSingle
+			cells := rows[y]
 
 			var x int
 
 			for x = 0; x < colCount && x < len(cells); x++ {
 				cell := cells[x]
-				if span > 0 && (!cell.Attr().Underline || colour != cell.Fg()) {
-					gui.renderer.DrawUnderline(span, uint(x-span), uint(y), colour)
+				underlined := cell.Attr().Underline && gui.textVisible(&cell)
+				if span > 0 && (!underlined || colour != cell.UnderlineColour() || style != cell.Attr().UnderlineStyle) {
+					gui.renderer.DrawUnderline(span, uint(x-span), uint(y), colour, style)
 					span = 0
 				}
 
-				colour = cell.Fg()
-				if cell.Attr().Underline {
+				colour = cell.UnderlineColour()
+				style = cell.Attr().UnderlineStyle
+				if underlined {
 					span++
 				}
 			}
 			if span > 0 {
-				gui.renderer.DrawUnderline(span, uint(x-span), uint(y), colour)
+				gui.renderer.DrawUnderline(span, uint(x-span), uint(y), colour, style)
 			}
+
+			gui.drawLines(cells, colCount, y, func(attr buffer.CellAttributes) bool { return attr.Strikethrough }, gui.renderer.DrawStrikethrough)
+			gui.drawLines(cells, colCount, y, func(attr buffer.CellAttributes) bool { return attr.Overline }, gui.renderer.DrawOverline)
 		}
 	}
-	gui.renderOverlay()
+	if showCursor && hollowCursor && cy < uint(lineCount) && cx < uint(colCount) && damage.Row(int(cy)) {
+		gui.renderer.DrawCursorOutline(cx, cy, t.Options().Cursor)
+	}
+	if gui.config.WrapIndicators {
+		fg, bg := gui.config.ColourScheme.Foreground, gui.config.ColourScheme.Background
+		colour := [3]float32{}
+		for i := range colour {
+			colour[i] = bg[i] + (fg[i]-bg[i])*0.3
+		}
+		for y := 0; y < lineCount && y < len(lines); y++ {
+			if lines[y].Wrapped() && damage.Row(y) {
+				gui.renderer.DrawWrapIndicator(uint(y), colour)
+			}
+		}
+	}
+	if link := gui.hoverLink; focused && link != nil && int(gui.hoverLinkRow) < len(lines) && damage.Row(int(gui.hoverLinkRow)) {
+		cells := lines[gui.hoverLinkRow].Cells()
+		colour := gui.config.ColourScheme.Foreground
+		if link.StartCol < len(cells) {
+			colour = cells[link.StartCol].Fg()
+		}
+		gui.renderer.DrawUnderline(link.EndCol-link.StartCol+1, uint(link.StartCol), uint(gui.hoverLinkRow), colour, buffer.UnderlineSingle)
+	}
+}
+
+// drawLines draws a line through or over each run of visible cells on row y which have the same colour and
+// whose attributes pass has
+func (gui *GUI) drawLines(cells []buffer.Cell, colCount int, y int, has func(buffer.CellAttributes) bool, draw func(span int, col uint, row uint, colour [3]float32)) {
+	span := 0
+	colour := [3]float32{}
+	x := 0
+	for ; x < colCount && x < len(cells); x++ {
+		cell := &cells[x]
+		line := has(cell.Attr()) && gui.textVisible(cell)
+		if span > 0 && (!line || colour != cell.Fg()) {
+			draw(span, uint(x-span), uint(y), colour)
+			span = 0
+		}
+		colour = cell.Fg()
+		if line {
+			span++
+		}
+	}
+	if span > 0 {
+		draw(span, uint(x-span), uint(y), colour)
+	}
 }
 
 func (gui *GUI) createWindow() (*glfw.Window, error) {
@@ -603,6 +1020,7 @@ func (gui *GUI) createWindow() (*glfw.Window, error) {
 	glfw.WindowHint(glfw.Resizable, glfw.True)
 	glfw.WindowHint(glfw.OpenGLProfile, glfw.OpenGLCoreProfile)
 	glfw.WindowHint(glfw.OpenGLForwardCompatible, glfw.True)
+	glfw.WindowHint(glfw.TransparentFramebuffer, glfw.True) // for background_opacity, opaque until it's set
 
 	versions := [][2]int{
 		{4, 6},
@@ -712,6 +1130,10 @@ func (gui *GUI) launchTarget(target string) {
 }
 
 func (gui *GUI) SwapBuffers() {
+	gui.renderer.Flush()
+	if gui.postProcessor != nil {
+		gui.postProcessor.apply()
+	}
 	UpdateNSGLContext(gui.window)
 	gui.window.SwapBuffers()
 }
@@ -732,6 +1154,17 @@ func (gui *GUI) Screenshot(path string) {
 	png.Encode(file, img)
 }
 
+// updateVisibility pauses drawing while the window can't be seen, and forces a full redraw when it reappears.
+// pty output is still processed into the buffer as normal while we're hidden.
+func (gui *GUI) updateVisibility() {
+	hidden := gui.window.GetAttrib(glfw.Iconified) != 0 || gui.window.GetAttrib(glfw.Visible) == 0
+	if gui.hidden && !hidden {
+		gui.resync()
+	}
+	gui.hidden = hidden
+	gui.updateThrottle()
+}
+
 func (gui *GUI) windowPosChangeCallback(w *glfw.Window, xpos int, ypos int) {
 	gui.SetDPIScale()
 }
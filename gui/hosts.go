@@ -0,0 +1,56 @@
+package gui
+
+import (
+	"github.com/liamg/aminal/config"
+	"github.com/liamg/aminal/themes"
+)
+
+// checkHosts recolours the panes whose shells have reported being on another host since they were last checked,
+// with the colours the config gives the host, or back to the tab's
+func (gui *GUI) checkHosts() {
+	for _, tab := range gui.tabs {
+		for _, p := range tab.root.leaves() {
+			host := p.terminal.Host()
+			if host == p.host {
+				continue
+			}
+			p.host = host
+			p.hostScheme = nil
+			p.hostTint = nil
+			if h, ok := gui.config.HostTheme(host); ok {
+				gui.logger.Infof("Using the colours for %s", host)
+				if h.Theme != "" {
+					scheme, err := themes.Load(h.Theme, themes.Directory(gui.config))
+					if err != nil {
+						gui.logger.Errorf("Failed to load the %s theme for %s, using the usual colours: %s", h.Theme, host, err)
+					} else {
+						p.hostScheme = &scheme
+					}
+				}
+				if tint, ok, err := h.TintColour(); err != nil {
+					gui.logger.Errorf("Failed to tint the background for %s: %s", host, err)
+				} else if ok {
+					p.hostTint = &tint
+				}
+			}
+			setColours(p.terminal, gui.paneScheme(tab, p))
+		}
+	}
+	gui.generateDefaultCell(gui.terminal.ScreenMode())
+}
+
+// paneScheme returns the colours for the pane: those for the host its shell is on, if the config gives it any,
+// or else the tab's own, or else the theme's
+func (gui *GUI) paneScheme(tab *tab, p *pane) config.ColourScheme {
+	scheme := gui.config.ColourScheme
+	if tab.scheme != nil {
+		scheme = *tab.scheme
+	}
+	if p.hostScheme != nil {
+		scheme = *p.hostScheme
+	}
+	if p.hostTint != nil {
+		scheme = scheme.Tinted(*p.hostTint)
+	}
+	return scheme
+}
@@ -0,0 +1,110 @@
+package gui
+
+import (
+	"unicode/utf8"
+
+	"github.com/liamg/aminal/buffer"
+)
+
+// preedit is the text an input method is composing, e.g. the kana typed before converting them to kanji. It's
+// drawn at the cursor until it's committed, when it arrives through the char callback like any other text. Only
+// the platforms with an ime_*.go hook report it, elsewhere the input method draws it in a window of its own.
+type preedit struct {
+	text  []rune
+	caret int // where the input method's cursor is in the text
+}
+
+// preeditCell is where a character of the preedit text is drawn
+type preeditCell struct {
+	r     rune
+	col   uint
+	row   uint
+	width int
+}
+
+// setPreedit is called by the input method hook as the composition changes, caret being a byte offset into
+// text. An empty text ends the composition. Must be called on the OS thread.
+func (gui *GUI) setPreedit(text string, caret int) {
+	if text == "" && !gui.composing() {
+		return
+	}
+	if caret > len(text) {
+		caret = len(text)
+	}
+	gui.preedit = preedit{
+		text:  []rune(text),
+		caret: utf8.RuneCountInString(text[:caret]),
+	}
+	gui.terminal.SetDirty()
+}
+
+// composing returns true while an input method is composing text, when the keys pressed are its to handle
+func (gui *GUI) composing() bool {
+	return len(gui.preedit.text) > 0
+}
+
+// layoutPreedit works out where each character of the preedit text goes, starting at the cursor and wrapping
+// at the edge of the screen like typed text, and where the caret is
+func (gui *GUI) layoutPreedit() (cells []preeditCell, caretCol uint, caretRow uint) {
+	b := gui.terminal.ActiveBuffer()
+	cols, rows := uint(b.ViewWidth()), uint(b.ViewHeight())
+	col, row := cursorCell(gui.terminal)
+	caretCol, caretRow = col, row
+	for i, r := range gui.preedit.text {
+		width := buffer.RuneWidth(r)
+		if width < 1 {
+			width = 1
+		}
+		if col+uint(width) > cols {
+			col = 0
+			row++
+		}
+		if row >= rows {
+			break
+		}
+		if i == gui.preedit.caret {
+			caretCol, caretRow = col, row
+		}
+		cells = append(cells, preeditCell{r: r, col: col, row: row, width: width})
+		col += uint(width)
+		if i+1 == gui.preedit.caret {
+			caretCol, caretRow = col, row
+		}
+	}
+	return cells, caretCol, caretRow
+}
+
+// renderPreedit draws the text being composed over the screen from the cursor, underlined so it isn't mistaken
+// for text the terminal has received
+func (gui *GUI) renderPreedit() {
+	if !gui.composing() {
+		return
+	}
+	cells, caretCol, caretRow := gui.layoutPreedit()
+	scheme := gui.config.ColourScheme
+
+	for _, c := range cells {
+		for i := 0; i < c.width; i++ {
+			gui.renderer.DrawCellBg(buffer.NewBackgroundCell(scheme.Background), c.col+uint(i), c.row, nil, true)
+		}
+	}
+	// the text is drawn straight away, so the background has to be drawn first
+	gui.renderer.Flush()
+	for _, c := range cells {
+		gui.renderer.DrawCellText(string(c.r), c.col, c.row, 1, scheme.Foreground, false, false)
+		gui.renderer.DrawUnderline(c.width, c.col, c.row, scheme.Foreground, buffer.UnderlineSingle)
+	}
+	gui.renderer.DrawCursorOutline(caretCol, caretRow, scheme.Cursor)
+}
+
+// preeditRect returns the cell the caret of the composition is in, in window coordinates, so the input method
+// can show its list of candidates beside it
+func (gui *GUI) preeditRect() (x float64, y float64, width float64, height float64) {
+	_, col, row := gui.layoutPreedit()
+	focus := gui.currentTab().focus
+	scale := float64(gui.scale())
+	cellWidth, cellHeight := float64(gui.renderer.CellWidth()), float64(gui.renderer.CellHeight())
+	x = (float64(gui.renderer.areaX) + float64(focus.col+col)*cellWidth) * scale
+	y = (float64(gui.renderer.areaY) + float64(focus.row+row)*cellHeight) * scale
+	return x, y, cellWidth * scale, cellHeight * scale
+}
@@ -0,0 +1,27 @@
+package gui
+
+/*
+void cocoa_hook_input_method(void *window);
+*/
+import "C"
+
+// inputMethodGUI is the GUI the input method hooks report to, as there's only ever one window
+var inputMethodGUI *GUI
+
+// hookInputMethod takes over the NSTextInputClient methods of GLFW's view which deal with marked text, which GLFW
+// keeps to itself, so the composition can be drawn at the cursor with the candidates shown beside it
+func hookInputMethod(gui *GUI) {
+	inputMethodGUI = gui
+	C.cocoa_hook_input_method(gui.window.GetCocoaWindow())
+}
+
+//export goSetPreedit
+func goSetPreedit(text *C.char, caret C.int) {
+	inputMethodGUI.setPreedit(C.GoString(text), int(caret))
+}
+
+//export goPreeditRect
+func goPreeditRect(x *C.double, y *C.double, width *C.double, height *C.double) {
+	rx, ry, rw, rh := inputMethodGUI.preeditRect()
+	*x, *y, *width, *height = C.double(rx), C.double(ry), C.double(rw), C.double(rh)
+}
@@ -0,0 +1,62 @@
+#include <Cocoa/Cocoa.h>
+#include <objc/runtime.h>
+#include <string.h>
+
+extern void goSetPreedit(char *text, int caret);
+extern void goPreeditRect(double *x, double *y, double *width, double *height);
+
+static IMP glfwSetMarkedText;
+static IMP glfwUnmarkText;
+static IMP glfwInsertText;
+
+// reportMarkedText passes the text being composed on with the caret as a byte offset into its UTF-8
+static void reportMarkedText(id string, NSRange selected) {
+	NSString *text = [string isKindOfClass:[NSAttributedString class]] ? [string string] : string;
+	NSUInteger location = selected.location;
+	if (location == NSNotFound || location > [text length]) {
+		location = [text length];
+	}
+	const char *before = [[text substringToIndex:location] UTF8String];
+	goSetPreedit((char *)[text UTF8String], (int)strlen(before));
+}
+
+static void aminalSetMarkedText(id self, SEL cmd, id string, NSRange selected, NSRange replacement) {
+	((void (*)(id, SEL, id, NSRange, NSRange))glfwSetMarkedText)(self, cmd, string, selected, replacement);
+	reportMarkedText(string, selected);
+}
+
+static void aminalUnmarkText(id self, SEL cmd) {
+	((void (*)(id, SEL))glfwUnmarkText)(self, cmd);
+	goSetPreedit("", 0);
+}
+
+static void aminalInsertText(id self, SEL cmd, id string, NSRange replacement) {
+	// the composition is over once its text is inserted, which GLFW passes on as characters
+	goSetPreedit("", 0);
+	((void (*)(id, SEL, id, NSRange))glfwInsertText)(self, cmd, string, replacement);
+}
+
+// aminalFirstRect puts the candidate window beside the caret rather than at the corner of the window
+static NSRect aminalFirstRect(id self, SEL cmd, NSRange range, NSRangePointer actual) {
+	NSView *view = self;
+	double x, y, width, height;
+	goPreeditRect(&x, &y, &width, &height);
+	NSRect rect = NSMakeRect(x, [view frame].size.height - y - height, width, height);
+	rect = [view convertRect:rect toView:nil];
+	return [[view window] convertRectToScreen:rect];
+}
+
+void cocoa_hook_input_method(void *window) {
+	Class view = [[(NSWindow *)window contentView] class];
+	if (glfwSetMarkedText != NULL) {
+		return;
+	}
+	glfwSetMarkedText = method_setImplementation(
+		class_getInstanceMethod(view, @selector(setMarkedText:selectedRange:replacementRange:)), (IMP)aminalSetMarkedText);
+	glfwUnmarkText = method_setImplementation(
+		class_getInstanceMethod(view, @selector(unmarkText)), (IMP)aminalUnmarkText);
+	glfwInsertText = method_setImplementation(
+		class_getInstanceMethod(view, @selector(insertText:replacementRange:)), (IMP)aminalInsertText);
+	method_setImplementation(
+		class_getInstanceMethod(view, @selector(firstRectForCharacterRange:actualRange:)), (IMP)aminalFirstRect);
+}
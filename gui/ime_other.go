@@ -0,0 +1,8 @@
+// +build !darwin,!windows
+
+package gui
+
+// hookInputMethod does nothing, as GLFW creates its X11 input context with XIMPreeditNothing, leaving the input
+// method to draw the composition in a window of its own. What it commits still arrives as characters.
+func hookInputMethod(gui *GUI) {
+}
@@ -0,0 +1,93 @@
+#include <windows.h>
+#include <imm.h>
+#include <stdlib.h>
+#include <string.h>
+
+extern void goSetPreedit(char *text, int caret);
+extern void goPreeditRect(double *x, double *y, double *width, double *height);
+
+static WNDPROC glfwWindowProc;
+
+// toUTF8 converts the first length characters of text, returning NULL if it can't
+static char *toUTF8(const WCHAR *text, int length) {
+	int size = WideCharToMultiByte(CP_UTF8, 0, text, length, NULL, 0, NULL, NULL);
+	char *utf8 = calloc(size + 1, 1);
+	if (utf8 != NULL) {
+		WideCharToMultiByte(CP_UTF8, 0, text, length, utf8, size, NULL, NULL);
+	}
+	return utf8;
+}
+
+// placeCandidates moves the candidate window to just below the caret
+static void placeCandidates(HWND window, HIMC context) {
+	double x, y, width, height;
+	goPreeditRect(&x, &y, &width, &height);
+
+	CANDIDATEFORM candidates = {0};
+	candidates.dwStyle = CFS_EXCLUDE;
+	candidates.ptCurrentPos.x = (LONG)x;
+	candidates.ptCurrentPos.y = (LONG)(y + height);
+	candidates.rcArea.left = (LONG)x;
+	candidates.rcArea.top = (LONG)y;
+	candidates.rcArea.right = (LONG)(x + width);
+	candidates.rcArea.bottom = (LONG)(y + height);
+	ImmSetCandidateWindow(context, &candidates);
+}
+
+// reportComposition passes the text being composed on with the caret as a byte offset into its UTF-8
+static void reportComposition(HWND window) {
+	HIMC context = ImmGetContext(window);
+	if (context == NULL) {
+		return;
+	}
+	LONG size = ImmGetCompositionStringW(context, GCS_COMPSTR, NULL, 0);
+	WCHAR *text = calloc(size / sizeof(WCHAR) + 1, sizeof(WCHAR));
+	if (text != NULL) {
+		int length = ImmGetCompositionStringW(context, GCS_COMPSTR, text, size) / sizeof(WCHAR);
+		int caret = LOWORD(ImmGetCompositionStringW(context, GCS_CURSORPOS, NULL, 0));
+		if (caret > length) {
+			caret = length;
+		}
+		char *all = toUTF8(text, length);
+		char *before = toUTF8(text, caret);
+		if (all != NULL && before != NULL) {
+			goSetPreedit(all, (int)strlen(before));
+		}
+		free(all);
+		free(before);
+		free(text);
+	}
+	placeCandidates(window, context);
+	ImmReleaseContext(window, context);
+}
+
+static LRESULT CALLBACK aminalWindowProc(HWND window, UINT message, WPARAM wParam, LPARAM lParam) {
+	switch (message) {
+	case WM_IME_SETCONTEXT:
+		// the composition is drawn at the cursor, so the IME mustn't show its own
+		lParam &= ~ISC_SHOWUICOMPOSITIONWINDOW;
+		break;
+	case WM_IME_STARTCOMPOSITION:
+		return 0;
+	case WM_IME_COMPOSITION:
+		if (lParam & GCS_COMPSTR) {
+			reportComposition(window);
+		}
+		if (!(lParam & GCS_RESULTSTR)) {
+			return 0;
+		}
+		// the committed text goes on to arrive as WM_CHAR, which GLFW passes on as characters
+		break;
+	case WM_IME_ENDCOMPOSITION:
+		goSetPreedit("", 0);
+		break;
+	}
+	return CallWindowProcW(glfwWindowProc, window, message, wParam, lParam);
+}
+
+void win32_hook_input_method(void *window) {
+	if (glfwWindowProc != NULL) {
+		return;
+	}
+	glfwWindowProc = (WNDPROC)SetWindowLongPtrW((HWND)window, GWLP_WNDPROC, (LONG_PTR)aminalWindowProc);
+}
@@ -0,0 +1,30 @@
+package gui
+
+/*
+#cgo LDFLAGS: -limm32
+void win32_hook_input_method(void *window);
+*/
+import "C"
+
+import "unsafe"
+
+// inputMethodGUI is the GUI the input method hooks report to, as there's only ever one window
+var inputMethodGUI *GUI
+
+// hookInputMethod subclasses GLFW's window procedure to follow the IME composition, which GLFW leaves to the
+// default composition window in the corner of the screen, so it can be drawn at the cursor instead
+func hookInputMethod(gui *GUI) {
+	inputMethodGUI = gui
+	C.win32_hook_input_method(unsafe.Pointer(gui.window.GetWin32Window()))
+}
+
+//export goSetPreedit
+func goSetPreedit(text *C.char, caret C.int) {
+	inputMethodGUI.setPreedit(C.GoString(text), int(caret))
+}
+
+//export goPreeditRect
+func goPreeditRect(x *C.double, y *C.double, width *C.double, height *C.double) {
+	rx, ry, rw, rh := inputMethodGUI.preeditRect()
+	*x, *y, *width, *height = C.double(rx), C.double(ry), C.double(rw), C.double(rh)
+}
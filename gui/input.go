@@ -3,13 +3,167 @@ package gui
 import (
 	"fmt"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/go-gl/glfw/v3.3/glfw"
+	"github.com/liamg/aminal/terminal"
 )
 
+// writeInput queues keyboard input to be written to the pty, so a slow pty write can never hold up the event loop
+func (gui *GUI) writeInput(data []byte) {
+	if gui.latency != nil {
+		gui.latency.keyPressed()
+	}
+	gui.resetCursorBlink()
+	if gui.recordingMacro {
+		gui.macroInput = append(gui.macroInput, data...)
+	}
+	gui.queueWrite(gui.terminal, data)
+}
+
+// queueWrite queues data to be written to the pty of t, after any input already waiting. Everything the window
+// sends to a pty goes this way, so it arrives in the order it was sent in.
+func (gui *GUI) queueWrite(t *terminal.Terminal, data []byte) {
+	gui.input <- pendingInput{terminal: t, data: data}
+}
+
+// pendingInput is input waiting to be written to the pty of the terminal which had focus when it was typed
+type pendingInput struct {
+	terminal *terminal.Terminal
+	data     []byte
+}
+
+func (gui *GUI) processInput() {
+	for input := range gui.input {
+		if err := input.terminal.Write(input.data); err != nil {
+			gui.logger.Errorf("Failed to write input to pty: %s", err)
+		}
+	}
+}
+
 // send typed runes straight through to the pty
 func (gui *GUI) char(w *glfw.Window, r rune) {
-	gui.terminal.Write([]byte(string(r)))
+	if o := gui.inputOverlay(); o != nil {
+		o.char(gui, r)
+		return
+	}
+	if gui.keyReported {
+		gui.keyReported = false
+		return
+	}
+	gui.writeInput([]byte(string(r)))
+}
+
+// protocolKeys are the keys without a character, as the extended keyboard protocols know them
+var protocolKeys = map[glfw.Key]terminal.Key{
+	glfw.KeyTab:       terminal.KeyTab,
+	glfw.KeyEnter:     terminal.KeyEnter,
+	glfw.KeyEscape:    terminal.KeyEscape,
+	glfw.KeyBackspace: terminal.KeyBackspace,
+	glfw.KeyInsert:    terminal.KeyInsert,
+	glfw.KeyDelete:    terminal.KeyDelete,
+	glfw.KeyLeft:      terminal.KeyLeft,
+	glfw.KeyRight:     terminal.KeyRight,
+	glfw.KeyUp:        terminal.KeyUp,
+	glfw.KeyDown:      terminal.KeyDown,
+	glfw.KeyPageUp:    terminal.KeyPageUp,
+	glfw.KeyPageDown:  terminal.KeyPageDown,
+	glfw.KeyHome:      terminal.KeyHome,
+	glfw.KeyEnd:       terminal.KeyEnd,
+	glfw.KeyKPEnter:   terminal.KeyKPEnter,
+	glfw.KeySpace:     ' ',
+	glfw.KeyF1:        terminal.KeyF1,
+	glfw.KeyF2:        terminal.KeyF1 + 1,
+	glfw.KeyF3:        terminal.KeyF1 + 2,
+	glfw.KeyF4:        terminal.KeyF1 + 3,
+	glfw.KeyF5:        terminal.KeyF1 + 4,
+	glfw.KeyF6:        terminal.KeyF1 + 5,
+	glfw.KeyF7:        terminal.KeyF1 + 6,
+	glfw.KeyF8:        terminal.KeyF1 + 7,
+	glfw.KeyF9:        terminal.KeyF1 + 8,
+	glfw.KeyF10:       terminal.KeyF1 + 9,
+	glfw.KeyF11:       terminal.KeyF1 + 10,
+	glfw.KeyF12:       terminal.KeyF1 + 11,
+}
+
+// keyActions maps glfw's key actions to the terminal's
+var keyActions = map[glfw.Action]terminal.KeyAction{
+	glfw.Press:   terminal.KeyPress,
+	glfw.Repeat:  terminal.KeyRepeat,
+	glfw.Release: terminal.KeyRelease,
+}
+
+// keyModifiers maps glfw's modifier keys to the terminal's
+var keyModifiers = map[glfw.ModifierKey]terminal.KeyModifiers{
+	glfw.ModShift:    terminal.KeyModShift,
+	glfw.ModAlt:      terminal.KeyModAlt,
+	glfw.ModControl:  terminal.KeyModCtrl,
+	glfw.ModSuper:    terminal.KeyModSuper,
+	glfw.ModCapsLock: terminal.KeyModCapsLock,
+	glfw.ModNumLock:  terminal.KeyModNumLock,
+}
+
+// reportKey sends the key as an escape code if the application has asked for keys to be reported with the kitty
+// keyboard protocol or modifyOtherKeys, returning false if it should be sent as it always has been
+func (gui *GUI) reportKey(key glfw.Key, scancode int, action glfw.Action, mods glfw.ModifierKey) bool {
+	e := terminal.KeyEvent{Action: keyActions[action]}
+	if k, ok := protocolKeys[key]; ok {
+		e.Key = k
+	} else {
+		// the character on the key in the current layout
+		name := glfw.GetKeyName(key, scancode)
+		r, size := utf8.DecodeRuneInString(name)
+		if size == 0 || size != len(name) || r == utf8.RuneError {
+			return false
+		}
+		e.Key = terminal.Key(unicode.ToLower(r))
+	}
+
+	for glfwMod, mod := range keyModifiers {
+		if mods&glfwMod != 0 {
+			e.Modifiers |= mod
+		}
+	}
+
+	data := gui.terminal.KeyReport(e)
+	if data == nil {
+		return false
+	}
+	gui.writeInput(data)
+	return true
+}
+
+// keys which can be used in shortcuts but have no key name, with the characters they're given in the config
+var namedShortcutKeys = map[glfw.Key]rune{
+	glfw.KeyTab:       '\t',
+	glfw.KeyLeft:      '←',
+	glfw.KeyRight:     '→',
+	glfw.KeyUp:        '↑',
+	glfw.KeyDown:      '↓',
+	glfw.KeyPageUp:    '⇞',
+	glfw.KeyPageDown:  '⇟',
+	glfw.KeyBackspace: '⌫',
+	glfw.KeyDelete:    '⌦',
+}
+
+// runShortcut runs the binding or action for the key combination, returning false if there isn't one
+func (gui *GUI) runShortcut(mods glfw.ModifierKey, r rune) bool {
+	for _, binding := range gui.keyBindings {
+		if binding.Keys.Match(mods, r) {
+			gui.runBinding(binding)
+			return true
+		}
+	}
+	for userAction, shortcut := range gui.keyboardShortcuts {
+		if shortcut.Match(mods, r) {
+			if f, ok := actionMap[userAction]; ok {
+				f(gui)
+				return true
+			}
+		}
+	}
+	return false
 }
 
 func modsPressed(pressed glfw.ModifierKey, mods ...glfw.ModifierKey) bool {
@@ -44,7 +198,36 @@ func getModStr(mods glfw.ModifierKey) string {
 }
 
 func (gui *GUI) key(w *glfw.Window, key glfw.Key, scancode int, action glfw.Action, mods glfw.ModifierKey) {
+	if isModifierKey(key) {
+		// links and file locations are underlined as soon as the modifier is pressed, without waiting for the mouse to move
+		if x, y, inside := gui.paneCoordinates(gui.convertMouseCoordinates(w.GetCursorPos())); inside {
+			gui.updateHoverLink(w, x, y)
+		}
+	}
+
+	if action == glfw.Release {
+		if gui.inputOverlay() == nil {
+			gui.reportKey(key, scancode, action, mods)
+		}
+		return
+	}
+
 	if action == glfw.Repeat || action == glfw.Press {
+		gui.keyReported = false
+
+		if gui.composing() {
+			// the input method is using the key, e.g. enter to commit the text or backspace to edit it
+			return
+		}
+
+		if o := gui.inputOverlay(); o != nil {
+			if key == glfw.KeyEscape {
+				gui.setOverlay(nil)
+			} else {
+				o.key(gui, key, mods)
+			}
+			return
+		}
 
 		if gui.overlay != nil {
 			if key == glfw.KeyEscape {
@@ -52,27 +235,41 @@ func (gui *GUI) key(w *glfw.Window, key glfw.Key, scancode int, action glfw.Acti
 			}
 		}
 
+		if name := pluginKeyName(key, scancode, mods); name != "" && gui.plugins.Key(gui.terminal, name) {
+			gui.keyReported = true // so its character isn't typed
+			return
+		}
+
+		if gui.extendSelectionWithKey(key, mods) {
+			return
+		}
+
+		// shortcuts can use keys without a printable name, like tab
+		if r, ok := namedShortcutKeys[key]; ok && gui.runShortcut(mods, r) {
+			return
+		}
+
 		// get key name to handle alternative keyboard layouts
 		name := glfw.GetKeyName(key, scancode)
+		if len(name) == 1 && gui.runShortcut(mods, rune(strings.ToLower(name)[0])) {
+			return
+		}
+
+		if !isModifierKey(key) && gui.reportKey(key, scancode, action, mods) {
+			gui.keyReported = true
+			return
+		}
+
 		if len(name) == 1 {
 			r := rune(strings.ToLower(name)[0])
-			for userAction, shortcut := range gui.keyboardShortcuts {
-				if shortcut.Match(mods, r) {
-					f, ok := actionMap[userAction]
-					if ok {
-						f(gui)
-						break
-					}
-				}
-			}
 
 			// standard ctrl codes e.g. ^C
 			if modsPressed(mods, glfw.ModControl) {
 				if r >= 97 && r < 123 {
-					gui.terminal.Write([]byte{byte(r) - 96})
+					gui.writeInput([]byte{byte(r) - 96})
 					return
 				} else if r >= 65 && r < 91 {
-					gui.terminal.Write([]byte{byte(r) - 64})
+					gui.writeInput([]byte{byte(r) - 64})
 					return
 				}
 			}
@@ -80,7 +277,7 @@ func (gui *GUI) key(w *glfw.Window, key glfw.Key, scancode int, action glfw.Acti
 			// pass through alt codes
 			if modsPressed(mods, glfw.ModAlt) {
 				if r >= 97 && r < 123 || r >= 65 && r < 91 {
-					gui.terminal.Write([]byte{0x1b, byte(r)})
+					gui.writeInput([]byte{0x1b, byte(r)})
 					return
 				}
 			}
@@ -90,85 +287,85 @@ func (gui *GUI) key(w *glfw.Window, key glfw.Key, scancode int, action glfw.Acti
 
 		switch key {
 		case glfw.KeyF1:
-			gui.terminal.Write([]byte{
+			gui.writeInput([]byte{
 				0x1b,
 				'O',
 				'P',
 			})
 		case glfw.KeyF2:
-			gui.terminal.Write([]byte{
+			gui.writeInput([]byte{
 				0x1b,
 				'O',
 				'Q',
 			})
 		case glfw.KeyF3:
-			gui.terminal.Write([]byte{
+			gui.writeInput([]byte{
 				0x1b,
 				'O',
 				'R',
 			})
 		case glfw.KeyF4:
-			gui.terminal.Write([]byte{
+			gui.writeInput([]byte{
 				0x1b,
 				'O',
 				'S',
 			})
 		case glfw.KeyF5:
-			gui.terminal.Write([]byte{
+			gui.writeInput([]byte{
 				0x1b,
 				'[',
 				'1', '5', '~',
 			})
 		case glfw.KeyF6:
-			gui.terminal.Write([]byte{
+			gui.writeInput([]byte{
 				0x1b,
 				'[',
 				'1', '7', '~',
 			})
 		case glfw.KeyF7:
-			gui.terminal.Write([]byte{
+			gui.writeInput([]byte{
 				0x1b,
 				'[',
 				'1', '8', '~',
 			})
 		case glfw.KeyF8:
-			gui.terminal.Write([]byte{
+			gui.writeInput([]byte{
 				0x1b,
 				'[',
 				'1', '9', '~',
 			})
 		case glfw.KeyF9:
-			gui.terminal.Write([]byte{
+			gui.writeInput([]byte{
 				0x1b,
 				'[',
 				'2', '0', '~',
 			})
 		case glfw.KeyF10:
-			gui.terminal.Write([]byte{
+			gui.writeInput([]byte{
 				0x1b,
 				'[',
 				'2', '1', '~',
 			})
 		case glfw.KeyF11:
-			gui.terminal.Write([]byte{
+			gui.writeInput([]byte{
 				0x1b,
 				'[',
 				'2', '3', '~',
 			})
 		case glfw.KeyF12:
-			gui.terminal.Write([]byte{
+			gui.writeInput([]byte{
 				0x1b,
 				'[',
 				'2', '4', '~',
 			})
 		case glfw.KeyInsert:
-			gui.terminal.Write([]byte{
+			gui.writeInput([]byte{
 				0x1b,
 				'[',
 				'2', '~',
 			})
 		case glfw.KeyDelete:
-			gui.terminal.Write([]byte{
+			gui.writeInput([]byte{
 				0x1b,
 				'[',
 				'3', '~',
@@ -176,70 +373,70 @@ func (gui *GUI) key(w *glfw.Window, key glfw.Key, scancode int, action glfw.Acti
 		case glfw.KeyHome:
 			if gui.terminal.IsApplicationCursorKeysModeEnabled() {
 				if modStr == "" {
-					gui.terminal.Write([]byte("\x1b[1~"))
+					gui.writeInput([]byte("\x1b[1~"))
 				} else {
-					gui.terminal.Write([]byte(fmt.Sprintf("\x1b[1;%s~", modStr)))
+					gui.writeInput([]byte(fmt.Sprintf("\x1b[1;%s~", modStr)))
 				}
 			} else {
-				gui.terminal.Write([]byte("\x1b[H"))
+				gui.writeInput([]byte("\x1b[H"))
 			}
 		case glfw.KeyEnd:
 			if modStr == "" {
-				gui.terminal.Write([]byte("\x1b[4~"))
+				gui.writeInput([]byte("\x1b[4~"))
 			} else {
-				gui.terminal.Write([]byte(fmt.Sprintf("\x1b[4;%s~", modStr)))
+				gui.writeInput([]byte(fmt.Sprintf("\x1b[4;%s~", modStr)))
 			}
 		case glfw.KeyPageUp:
 			if modStr == "" {
-				gui.terminal.Write([]byte("\x1b[5~"))
+				gui.writeInput([]byte("\x1b[5~"))
 			} else {
-				gui.terminal.Write([]byte(fmt.Sprintf("\x1b[5;%s~", modStr)))
+				gui.writeInput([]byte(fmt.Sprintf("\x1b[5;%s~", modStr)))
 			}
 		case glfw.KeyPageDown:
 			if modStr == "" {
-				gui.terminal.Write([]byte("\x1b[6~"))
+				gui.writeInput([]byte("\x1b[6~"))
 			} else {
-				gui.terminal.Write([]byte(fmt.Sprintf("\x1b[6;%s~", modStr)))
+				gui.writeInput([]byte(fmt.Sprintf("\x1b[6;%s~", modStr)))
 			}
 		case glfw.KeyEscape:
-			gui.terminal.Write([]byte{
+			gui.writeInput([]byte{
 				0x1b,
 			})
 		case glfw.KeyTab:
-			gui.terminal.Write([]byte{
+			gui.writeInput([]byte{
 				0x09,
 			})
 		case glfw.KeyEnter:
-			gui.terminal.WriteReturn()
+			gui.writeInput(gui.terminal.ReturnSequence())
 		case glfw.KeyKPEnter:
 			if gui.terminal.IsApplicationCursorKeysModeEnabled() {
-				gui.terminal.Write([]byte{
+				gui.writeInput([]byte{
 					0x1b,
 					'O',
 					'M',
 				})
 			} else {
-				gui.terminal.WriteReturn()
+				gui.writeInput(gui.terminal.ReturnSequence())
 			}
 		case glfw.KeyBackspace:
 			if modsPressed(mods, glfw.ModAlt) {
-				gui.terminal.Write([]byte{0x17}) // ctrl-w/delete word
+				gui.writeInput([]byte{0x17}) // ctrl-w/delete word
 			} else {
-				gui.terminal.Write([]byte{0x7f}) // 0x7f is DEL
+				gui.writeInput([]byte{0x7f}) // 0x7f is DEL
 			}
 		case glfw.KeyUp:
 			if modStr != "" {
-				gui.terminal.Write([]byte(fmt.Sprintf("\x1b[1;%sA", modStr)))
+				gui.writeInput([]byte(fmt.Sprintf("\x1b[1;%sA", modStr)))
 			}
 
 			if gui.terminal.IsApplicationCursorKeysModeEnabled() {
-				gui.terminal.Write([]byte{
+				gui.writeInput([]byte{
 					0x1b,
 					'O',
 					'A',
 				})
 			} else {
-				gui.terminal.Write([]byte{
+				gui.writeInput([]byte{
 					0x1b,
 					'[',
 					'A',
@@ -248,17 +445,17 @@ func (gui *GUI) key(w *glfw.Window, key glfw.Key, scancode int, action glfw.Acti
 		case glfw.KeyDown:
 
 			if modStr != "" {
-				gui.terminal.Write([]byte(fmt.Sprintf("\x1b[1;%sB", modStr)))
+				gui.writeInput([]byte(fmt.Sprintf("\x1b[1;%sB", modStr)))
 			}
 
 			if gui.terminal.IsApplicationCursorKeysModeEnabled() {
-				gui.terminal.Write([]byte{
+				gui.writeInput([]byte{
 					0x1b,
 					'O',
 					'B',
 				})
 			} else {
-				gui.terminal.Write([]byte{
+				gui.writeInput([]byte{
 					0x1b,
 					'[',
 					'B',
@@ -266,17 +463,17 @@ func (gui *GUI) key(w *glfw.Window, key glfw.Key, scancode int, action glfw.Acti
 			}
 		case glfw.KeyLeft:
 			if modStr != "" {
-				gui.terminal.Write([]byte(fmt.Sprintf("\x1b[1;%sD", modStr)))
+				gui.writeInput([]byte(fmt.Sprintf("\x1b[1;%sD", modStr)))
 			}
 
 			if gui.terminal.IsApplicationCursorKeysModeEnabled() {
-				gui.terminal.Write([]byte{
+				gui.writeInput([]byte{
 					0x1b,
 					'O',
 					'D',
 				})
 			} else {
-				gui.terminal.Write([]byte{
+				gui.writeInput([]byte{
 					0x1b,
 					'[',
 					'D',
@@ -284,17 +481,17 @@ func (gui *GUI) key(w *glfw.Window, key glfw.Key, scancode int, action glfw.Acti
 			}
 		case glfw.KeyRight:
 			if modStr != "" {
-				gui.terminal.Write([]byte(fmt.Sprintf("\x1b[1;%sC", modStr)))
+				gui.writeInput([]byte(fmt.Sprintf("\x1b[1;%sC", modStr)))
 			}
 
 			if gui.terminal.IsApplicationCursorKeysModeEnabled() {
-				gui.terminal.Write([]byte{
+				gui.writeInput([]byte{
 					0x1b,
 					'O',
 					'C',
 				})
 			} else {
-				gui.terminal.Write([]byte{
+				gui.writeInput([]byte{
 					0x1b,
 					'[',
 					'C',
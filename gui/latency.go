@@ -0,0 +1,100 @@
+package gui
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	maxLatencySamples = 1000
+	maxLatencyPending = 64
+	// keystrokes which aren't echoed within this time (e.g. ctrl codes, typing a password) are dropped
+	latencyEchoTimeout = time.Second
+)
+
+// latencyTracker measures the time from a keystroke arriving at the GLFW callback until the terminal has
+// parsed the resulting output, and until that output has been presented on screen
+type latencyTracker struct {
+	mutex     sync.Mutex
+	pending   []time.Time // keystrokes waiting for an echo
+	parsed    []time.Time // keystrokes which have been echoed but not yet presented
+	parseTime []time.Duration
+	frameTime []time.Duration
+}
+
+func newLatencyTracker() *latencyTracker {
+	return &latencyTracker{}
+}
+
+func (l *latencyTracker) keyPressed() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := time.Now()
+	for len(l.pending) > 0 && (now.Sub(l.pending[0]) > latencyEchoTimeout || len(l.pending) >= maxLatencyPending) {
+		l.pending = l.pending[1:]
+	}
+	l.pending = append(l.pending, now)
+}
+
+// outputParsed should be called once the terminal has finished processing a batch of output
+func (l *latencyTracker) outputParsed() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := time.Now()
+	for _, t := range l.pending {
+		l.parseTime = addLatencySample(l.parseTime, now.Sub(t))
+	}
+	l.parsed = append(l.parsed, l.pending...)
+	l.pending = l.pending[:0]
+}
+
+// framePresented should be called after each buffer swap
+func (l *latencyTracker) framePresented() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := time.Now()
+	for _, t := range l.parsed {
+		l.frameTime = addLatencySample(l.frameTime, now.Sub(t))
+	}
+	l.parsed = l.parsed[:0]
+}
+
+func addLatencySample(samples []time.Duration, d time.Duration) []time.Duration {
+	if len(samples) >= maxLatencySamples {
+		samples = samples[1:]
+	}
+	return append(samples, d)
+}
+
+func percentiles(samples []time.Duration) (p50 time.Duration, p90 time.Duration, p99 time.Duration) {
+	if len(samples) == 0 {
+		return 0, 0, 0
+	}
+	sorted := make([]time.Duration, len(samples))
+	copy(sorted, samples)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	at := func(p int) time.Duration {
+		return sorted[(len(sorted)-1)*p/100]
+	}
+	return at(50), at(90), at(99)
+}
+
+func (l *latencyTracker) String() string {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	p50, p90, p99 := percentiles(l.parseTime)
+	f50, f90, f99 := percentiles(l.frameTime)
+	return fmt.Sprintf(`Key to parse: p50 %s p90 %s p99 %s
+Key to frame: p50 %s p90 %s p99 %s (%d samples)
+`,
+		p50.Round(time.Microsecond), p90.Round(time.Microsecond), p99.Round(time.Microsecond),
+		f50.Round(time.Microsecond), f90.Round(time.Microsecond), f99.Round(time.Microsecond),
+		len(l.frameTime),
+	)
+}
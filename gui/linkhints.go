@@ -0,0 +1,117 @@
+package gui
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/go-gl/glfw/v3.3/glfw"
+	"github.com/liamg/aminal/buffer"
+	"github.com/liamg/aminal/config"
+)
+
+// the characters hint labels are made from, easiest to reach first
+const hintAlphabet = "asdfghjklqwertyuiopzxcvbnm"
+
+// linkHints labels the links, URLs and paths on screen with short key sequences, opening the one whose
+// label is typed (or running its link rule's action), or copying it if the label is typed with shift held
+type linkHints struct {
+	targets []buffer.Target
+	labels  []string
+	typed   string
+}
+
+func actionLinkHints(gui *GUI) {
+	targets := gui.terminal.ActiveBuffer().FindTargets(gui.linkRules)
+	if len(targets) == 0 {
+		gui.showToast(newNotice("There are no links on screen"))
+		return
+	}
+	gui.setOverlay(&linkHints{
+		targets: targets,
+		labels:  hintLabels(len(targets)),
+	})
+}
+
+// hintLabels returns n labels of the same length, so none is the start of another
+func hintLabels(n int) []string {
+	labels := []string{""}
+	for len(labels) < n {
+		longer := make([]string, 0, len(labels)*len(hintAlphabet))
+		for _, label := range labels {
+			for _, c := range hintAlphabet {
+				longer = append(longer, label+string(c))
+			}
+		}
+		labels = longer
+	}
+	return labels[:n]
+}
+
+func (h *linkHints) char(gui *GUI, r rune) {
+	typed := h.typed + string(unicode.ToLower(r))
+	for i, label := range h.labels {
+		if label == typed {
+			gui.setOverlay(nil)
+			h.choose(gui, h.targets[i], unicode.IsUpper(r))
+			return
+		}
+		if strings.HasPrefix(label, typed) {
+			h.typed = typed
+			gui.terminal.SetDirty()
+			return
+		}
+	}
+	// keys which don't lead to a label are ignored
+}
+
+func (h *linkHints) key(gui *GUI, key glfw.Key, mods glfw.ModifierKey) {
+	if key == glfw.KeyBackspace && len(h.typed) > 0 {
+		h.typed = h.typed[:len(h.typed)-1]
+		gui.terminal.SetDirty()
+	}
+}
+
+func (h *linkHints) choose(gui *GUI, target buffer.Target, copyTarget bool) {
+	if copyTarget {
+		gui.setSelection(selectionClipboard, target.URL)
+		return
+	}
+	if target.Kind == buffer.TargetLink {
+		gui.runLink(buffer.Link{URL: target.URL, Rule: target.Rule})
+		return
+	}
+	url := target.URL
+	if target.Kind == buffer.TargetPath && strings.HasPrefix(url, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			url = filepath.Join(home, url[2:])
+		}
+	}
+	go gui.launchTarget(url)
+}
+
+func (h *linkHints) render(gui *GUI) {
+	highlight := config.Colour{0.9, 0.75, 0.2}
+	for i, target := range h.targets {
+		label := h.labels[i]
+		if !strings.HasPrefix(label, h.typed) {
+			continue
+		}
+		// the rest of the label is shown over the start of the target
+		remaining := label[len(h.typed):]
+		for j := range remaining {
+			gui.renderer.DrawCellBg(*gui.defaultCell, uint(target.StartCol)+uint(j), uint(target.ViewRow), &highlight, true)
+		}
+		gui.renderer.DrawCellText(remaining, uint(target.StartCol), uint(target.ViewRow), 1, [3]float32{0, 0, 0}, true, false)
+	}
+
+	height := int(gui.terminal.ActiveBuffer().ViewHeight())
+	gui.textbox(
+		0,
+		uint16(height-3),
+		"type a label to open it, or with shift to copy it (esc to cancel)",
+		[3]float32{1, 1, 1},
+		[3]float32{0.2, 0.2, 0.4},
+	)
+}
@@ -0,0 +1,154 @@
+package gui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/go-gl/glfw/v3.3/glfw"
+	"github.com/liamg/aminal/buffer"
+	"github.com/liamg/aminal/config"
+	"github.com/liamg/aminal/platform"
+)
+
+// newLinkRules compiles the configured link patterns, in the same order so links can be matched back to their rule
+func newLinkRules(rules []config.LinkRule) ([]buffer.LinkRule, error) {
+	linkRules := []buffer.LinkRule{}
+	for _, rule := range rules {
+		linkRule, err := buffer.NewLinkRule(rule.Pattern, rule.URL)
+		if err != nil {
+			return nil, err
+		}
+		switch rule.LinkAction() {
+		case config.LinkOpen, config.LinkCopy, config.LinkCommand:
+		default:
+			return nil, fmt.Errorf("Unknown action '%s' for link pattern '%s'", rule.Action, rule.Pattern)
+		}
+		linkRules = append(linkRules, linkRule)
+	}
+	return linkRules, nil
+}
+
+func parseLinkModifier(mods string) (glfw.ModifierKey, error) {
+	modifier, err := config.ParseModifiers(mods)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid link_modifier: %s", err)
+	}
+	return modifier, nil
+}
+
+// heldModifiers returns the modifier keys held down right now, for events which don't say
+func heldModifiers(w *glfw.Window) glfw.ModifierKey {
+	var mods glfw.ModifierKey
+	held := func(keys ...glfw.Key) bool {
+		for _, key := range keys {
+			if w.GetKey(key) == glfw.Press {
+				return true
+			}
+		}
+		return false
+	}
+	if held(glfw.KeyLeftControl, glfw.KeyRightControl) {
+		mods |= glfw.ModControl
+	}
+	if held(glfw.KeyLeftAlt, glfw.KeyRightAlt) {
+		mods |= glfw.ModAlt
+	}
+	if held(glfw.KeyLeftShift, glfw.KeyRightShift) {
+		mods |= glfw.ModShift
+	}
+	if held(glfw.KeyLeftSuper, glfw.KeyRightSuper) {
+		mods |= glfw.ModSuper
+	}
+	return mods
+}
+
+func isModifierKey(key glfw.Key) bool {
+	switch key {
+	case glfw.KeyLeftControl, glfw.KeyRightControl, glfw.KeyLeftAlt, glfw.KeyRightAlt,
+		glfw.KeyLeftShift, glfw.KeyRightShift, glfw.KeyLeftSuper, glfw.KeyRightSuper:
+		return true
+	}
+	return false
+}
+
+// linkAt returns the rule-generated link at the cell if the link modifier is held, or nil
+func (gui *GUI) linkAt(x uint16, y uint16, mods glfw.ModifierKey) *buffer.Link {
+	if mods&gui.linkModifier != gui.linkModifier {
+		return nil
+	}
+	return gui.terminal.ActiveBuffer().GetLinkAtPosition(x, y, gui.linkRules)
+}
+
+// locationAt returns the file location at the cell if ctrl is held, or nil
+func (gui *GUI) locationAt(x uint16, y uint16, mods glfw.ModifierKey) *buffer.FileLocation {
+	if mods&glfw.ModControl == 0 {
+		return nil
+	}
+	return gui.terminal.ActiveBuffer().GetFileLocationAtPosition(x, y)
+}
+
+// updateHoverLink underlines the link or file location under the mouse, and shows the hand cursor over it
+func (gui *GUI) updateHoverLink(w *glfw.Window, x uint16, y uint16) {
+	mods := heldModifiers(w)
+	link := gui.linkAt(x, y, mods)
+	if location := gui.locationAt(x, y, mods); link == nil && location != nil {
+		// only the span matters for underlining
+		link = &buffer.Link{URL: location.Path, StartCol: location.StartCol, EndCol: location.EndCol}
+	}
+	if link != gui.hoverLink && (link == nil || gui.hoverLink == nil || *link != *gui.hoverLink || y != gui.hoverLinkRow) {
+		gui.terminal.SetDirty()
+	}
+	gui.hoverLink = link
+	gui.hoverLinkRow = y
+
+	if url := gui.terminal.ActiveBuffer().GetURLAtPosition(x, y); url != "" || link != nil {
+		w.SetCursor(gui.getHandCursor())
+	} else {
+		w.SetCursor(gui.getArrowCursor())
+	}
+}
+
+// runLink does what the rule which generated the link says to
+func (gui *GUI) runLink(link buffer.Link) {
+	rule := gui.config.Links[link.Rule]
+	switch rule.LinkAction() {
+	case config.LinkCopy:
+		gui.setSelection(selectionClipboard, link.URL)
+	case config.LinkCommand:
+		go func() {
+			if err := platform.PipeToCommand(rule.Command, link.URL); err != nil {
+				gui.logger.Errorf("Link command '%s' failed: %s", rule.Command, err)
+			}
+		}()
+	default:
+		go gui.launchTarget(link.URL)
+	}
+}
+
+// openLocation opens the file at the line with the configured editor command, or with the default
+// application for the file if there isn't one
+func (gui *GUI) openLocation(location buffer.FileLocation) {
+	path := location.Path
+	if strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, path[2:])
+		}
+	}
+	if gui.config.Editor == "" {
+		go gui.launchTarget(path)
+		return
+	}
+	go func() {
+		err := platform.RunCommand(gui.config.Editor,
+			"AMINAL_FILE="+path,
+			"AMINAL_LINE="+strconv.Itoa(location.Line),
+			"AMINAL_COLUMN="+strconv.Itoa(location.Column),
+		)
+		if err != nil {
+			gui.logger.Errorf("Editor command '%s' failed: %s", gui.config.Editor, err)
+		}
+	}()
+}
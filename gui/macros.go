@@ -0,0 +1,113 @@
+package gui
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/liamg/aminal/config"
+)
+
+const macroPreviewLength = 40
+
+// actionRecordMacro starts recording what's typed into the terminal, or stops and asks for a name to save it under
+func actionRecordMacro(gui *GUI) {
+	if !gui.recordingMacro {
+		gui.recordingMacro = true
+		gui.macroInput = nil
+		gui.showToast(newToast("Recording a macro", toastAction{
+			label: "Stop",
+			run:   actionRecordMacro,
+		}))
+		return
+	}
+
+	gui.recordingMacro = false
+	gui.dismissToast()
+	input := string(gui.macroInput)
+	gui.macroInput = nil
+	if input == "" {
+		gui.logger.Infof("Nothing was typed while recording, so no macro was saved")
+		return
+	}
+	gui.setOverlay(newPrompt("Macro name", func(gui *GUI, name string) {
+		if name == "" {
+			name = fmt.Sprintf("macro %d", len(gui.config.Macros)+1)
+		}
+		gui.saveMacro(name, input)
+	}))
+}
+
+// actionMacros lists the saved macros to pick one to replay
+func actionMacros(gui *GUI) {
+	names := make([]string, 0, len(gui.config.Macros))
+	for name := range gui.config.Macros {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	items := make([]string, 0, len(names))
+	for _, name := range names {
+		preview := strconv.Quote(gui.config.Macros[name])
+		if len(preview) > macroPreviewLength {
+			preview = preview[:macroPreviewLength-3] + "..."
+		}
+		items = append(items, fmt.Sprintf("%-20s %s", name, preview))
+	}
+
+	m := newMenu("Macros (enter to replay, delete to remove)", items, func(gui *GUI, index int) {
+		gui.playMacro(names[index])
+	})
+	m.onDelete = func(gui *GUI, index int) {
+		gui.deleteMacro(names[index])
+		names = append(names[:index], names[index+1:]...)
+	}
+	gui.setOverlay(m)
+}
+
+// playMacro types the named macro into the focused terminal
+func (gui *GUI) playMacro(name string) {
+	input, ok := gui.config.Macros[name]
+	if !ok {
+		gui.logger.Errorf("There's no macro called '%s'", name)
+		return
+	}
+	gui.writeInput([]byte(input))
+}
+
+// saveMacro keeps the macro for the rest of the session, and in the config file so it's there next time
+func (gui *GUI) saveMacro(name string, input string) {
+	if gui.config.Macros == nil {
+		gui.config.Macros = map[string]string{}
+	}
+	gui.config.Macros[name] = input
+	gui.updateMacros(func(macros map[string]string) {
+		macros[name] = input
+	})
+}
+
+// deleteMacro removes the macro from the session and the config file
+func (gui *GUI) deleteMacro(name string) {
+	delete(gui.config.Macros, name)
+	gui.updateMacros(func(macros map[string]string) {
+		delete(macros, name)
+	})
+}
+
+// updateMacros applies update to the macros in the config file, if there is one
+func (gui *GUI) updateMacros(update func(macros map[string]string)) {
+	if gui.config.Path == "" {
+		gui.logger.Infof("Keeping macros for this session only, as there's no config file to save them to")
+		return
+	}
+	err := config.UpdateFile(gui.config.Path, func(c *config.Config) {
+		if c.Macros == nil {
+			c.Macros = map[string]string{}
+		}
+		update(c.Macros)
+	})
+	if err != nil {
+		gui.logger.Errorf("Failed to save macros to %s: %s", gui.config.Path, err)
+		gui.showToast(newNotice(fmt.Sprintf("Failed to save macros: %s", err)))
+	}
+}
@@ -0,0 +1,75 @@
+package gui
+
+import (
+	"strings"
+
+	"github.com/go-gl/glfw/v3.3/glfw"
+)
+
+// menu is an overlay listing options for the user to pick from with the keyboard
+type menu struct {
+	title    string
+	items    []string
+	selected int
+	onSelect func(gui *GUI, index int)
+	onDelete func(gui *GUI, index int) // optional, called when the user presses delete on an item
+}
+
+func newMenu(title string, items []string, onSelect func(gui *GUI, index int)) *menu {
+	return &menu{
+		title:    title,
+		items:    items,
+		onSelect: onSelect,
+	}
+}
+
+func (m *menu) char(gui *GUI, r rune) {
+	// allow quick selection of the first few items by number
+	if r >= '1' && r <= '9' && int(r-'1') < len(m.items) {
+		gui.setOverlay(nil)
+		m.onSelect(gui, int(r-'1'))
+	}
+}
+
+func (m *menu) key(gui *GUI, key glfw.Key, mods glfw.ModifierKey) {
+	switch key {
+	case glfw.KeyUp:
+		if m.selected > 0 {
+			m.selected--
+		}
+	case glfw.KeyDown:
+		if m.selected < len(m.items)-1 {
+			m.selected++
+		}
+	case glfw.KeyDelete:
+		if m.onDelete != nil && m.selected < len(m.items) {
+			m.onDelete(gui, m.selected)
+			m.items = append(m.items[:m.selected], m.items[m.selected+1:]...)
+			if m.selected >= len(m.items) && m.selected > 0 {
+				m.selected--
+			}
+		}
+	case glfw.KeyEnter, glfw.KeyKPEnter:
+		gui.setOverlay(nil)
+		if m.selected < len(m.items) {
+			m.onSelect(gui, m.selected)
+		}
+	}
+	gui.terminal.SetDirty()
+}
+
+func (m *menu) render(gui *GUI) {
+	lines := []string{m.title, ""}
+	if len(m.items) == 0 {
+		lines = append(lines, "  (empty)")
+	}
+	for i, item := range m.items {
+		marker := "  "
+		if i == m.selected {
+			marker = "> "
+		}
+		lines = append(lines, marker+item)
+	}
+
+	gui.textbox(2, 2, strings.Join(lines, "\n"), [3]float32{1, 1, 1}, [3]float32{0.2, 0.2, 0.4})
+}
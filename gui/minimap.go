@@ -0,0 +1,165 @@
+package gui
+
+import (
+	"github.com/liamg/aminal/buffer"
+)
+
+const (
+	minimapColumns uint = 12 // taken from the right of the window by the minimap
+	minimapSamples      = 4  // most lines looked at for each row of pixels, when the scrollback doesn't fit
+)
+
+// minimapLayout is where the minimap is drawn, in pixels from the top left of the terminal grid, and how many
+// lines of the buffer each row of its pixels shows, which is more than one once the scrollback doesn't fit
+type minimapLayout struct {
+	left        float32
+	width       float32
+	height      float32
+	pixel       float32
+	linesPerRow float32
+}
+
+func (gui *GUI) minimapLayout(lines int) minimapLayout {
+	cols, rows := gui.terminalGridSize()
+	cellWidth, cellHeight := gui.renderer.CellWidth(), gui.renderer.CellHeight()
+	l := minimapLayout{
+		left:        float32(cols) * cellWidth,
+		width:       float32(gui.minimapCols()) * cellWidth,
+		height:      float32(rows) * cellHeight,
+		pixel:       2 * gui.renderer.lineThickness(),
+		linesPerRow: 1,
+	}
+	if capacity := int(l.height / l.pixel); capacity > 0 && lines > capacity {
+		l.linesPerRow = float32(lines) / float32(capacity)
+	}
+	return l
+}
+
+// y returns how far down the minimap a raw line is drawn
+func (l minimapLayout) y(rawLine int) float32 {
+	return float32(int(float32(rawLine)/l.linesPerRow)) * l.pixel
+}
+
+// line returns the raw line drawn at a distance down the minimap
+func (l minimapLayout) line(y float32) int {
+	if y < 0 {
+		return 0
+	}
+	return int(float32(int(y/l.pixel)) * l.linesPerRow)
+}
+
+// minimapCols returns how many columns at the right of the window the minimap takes, none if it's hidden or the
+// window is too narrow to spare them
+func (gui *GUI) minimapCols() uint {
+	if !gui.showMinimap {
+		return 0
+	}
+	if cols, _ := gui.renderer.GetTermSize(); cols < 4*minimapColumns {
+		return 0
+	}
+	return minimapColumns
+}
+
+func actionToggleMinimap(gui *GUI) {
+	gui.showMinimap = !gui.showMinimap
+	gui.minimap = nil
+	gui.relayout()
+}
+
+// renderMinimap draws the picture of the focused terminal's buffer to the right of the panes, with the lines in
+// view highlighted. Only the lines which can have changed are rasterised again, so it's cheap to draw every frame.
+func (gui *GUI) renderMinimap() {
+	if gui.minimapCols() == 0 {
+		return
+	}
+	b := gui.terminal.ActiveBuffer()
+	l := gui.minimapLayout(b.Height())
+	columns := int(l.width / l.pixel)
+	if gui.minimap == nil || gui.minimap.Columns() != columns {
+		gui.minimap = buffer.NewMinimap(columns)
+	}
+	m := gui.minimap
+	b.UpdateMinimap(m)
+
+	scheme := gui.config.ColourScheme
+	bg := blend(scheme.Background, scheme.Foreground, 0.05)
+	gui.renderer.queueRect(l.left, l.height, l.width, l.height, bg)
+
+	top := b.TopVisibleLine()
+	viewTop, viewBottom := l.y(top), l.y(top+int(b.ViewHeight()))
+	if viewBottom > l.height {
+		viewBottom = l.height
+	}
+	gui.renderer.queueRect(l.left, viewBottom, l.width, viewBottom-viewTop, blend(bg, scheme.Foreground, 0.15))
+
+	// a row of pixels shows the ink of the first of its lines with any there, and runs of pixels of the same
+	// colour are drawn together
+	for row := 0; float32(row)*l.linesPerRow < float32(m.Len()); row++ {
+		from := int(float32(row) * l.linesPerRow)
+		to := int(float32(row+1) * l.linesPerRow)
+		if to > from+minimapSamples {
+			to = from + minimapSamples
+		}
+		if to <= from {
+			to = from + 1
+		}
+		sample := func(x int) (buffer.MinimapPixel, bool) {
+			for line := from; line < to; line++ {
+				if pixels := m.Row(line); x < len(pixels) && pixels[x].Ink {
+					return pixels[x], true
+				}
+			}
+			return buffer.MinimapPixel{}, false
+		}
+
+		// each line is drawn half the height of its row, leaving gaps between lines like those between text
+		bottom := float32(row)*l.pixel + l.pixel/2
+		start := -1
+		var colour [3]float32
+		for x := 0; x <= columns; x++ {
+			pixel, ink := sample(x)
+			if start >= 0 && (!ink || pixel.Colour != colour) {
+				gui.renderer.queueRect(l.left+float32(start)*l.pixel, bottom, float32(x-start)*l.pixel, l.pixel/2, blend(bg, colour, 0.7))
+				start = -1
+			}
+			if ink && start < 0 {
+				start, colour = x, pixel.Colour
+			}
+		}
+	}
+}
+
+// minimapAt returns the raw line of the focused terminal's buffer drawn at a point in the window, or false if the
+// point isn't over the minimap
+func (gui *GUI) minimapAt(px float64, py float64) (int, bool) {
+	if gui.minimapCols() == 0 {
+		return 0, false
+	}
+	scale := gui.scale()
+	x := float32(px)/scale - float32(gui.renderer.areaX)
+	y := float32(py)/scale - float32(gui.renderer.areaY)
+	l := gui.minimapLayout(gui.terminal.ActiveBuffer().Height())
+	if x < l.left || x >= l.left+l.width || y < 0 || y >= l.height {
+		return 0, false
+	}
+	return l.line(y), true
+}
+
+// startMinimapDrag jumps to the line clicked on if the mouse is over the minimap, and keeps following the mouse
+// until the button is released
+func (gui *GUI) startMinimapDrag(px float64, py float64) bool {
+	if _, ok := gui.minimapAt(px, py); !ok {
+		return false
+	}
+	gui.minimapDragging = true
+	gui.dragMinimap(py)
+	return true
+}
+
+// dragMinimap scrolls the line level with the mouse on the minimap into the middle of the screen
+func (gui *GUI) dragMinimap(py float64) {
+	b := gui.terminal.ActiveBuffer()
+	l := gui.minimapLayout(b.Height())
+	y := float32(py)/gui.scale() - float32(gui.renderer.areaY)
+	gui.terminal.ScrollToLineAtTop(l.line(y) - int(b.ViewHeight())/2)
+}
@@ -1,7 +1,7 @@
 package gui
 
 import (
-	"fmt"
+	"bytes"
 	"math"
 	"time"
 
@@ -11,11 +11,77 @@ import (
 )
 
 func (gui *GUI) glfwScrollCallback(w *glfw.Window, xoff float64, yoff float64) {
-	if yoff > 0 {
-		gui.terminal.ScreenScrollUp(1)
+	if yoff != 0 && (w.GetKey(glfw.KeyLeftControl) == glfw.Press || w.GetKey(glfw.KeyRightControl) == glfw.Press) {
+		gui.zoomWheel(yoff)
+		return
+	}
+
+	scroll := gui.config.Scroll
+	if scroll.Natural {
+		xoff, yoff = -xoff, -yoff
+	}
+
+	if gui.terminal.GetMouseMode() != terminal.MouseModeNone {
+		x, y, inside := gui.paneCoordinates(gui.convertMouseCoordinates(w.GetCursorPos()))
+		if inside && gui.reportWheel(xoff, yoff, int(x)+1, int(y)+1) {
+			return
+		}
+	}
+	if yoff == 0 {
+		return // only applications can be scrolled sideways
+	}
+
+	// touchpads scroll by fractions of a click, which add up until there's a whole line to scroll
+	lines := yoff * scroll.Lines
+	if w.GetKey(glfw.KeyLeftShift) == glfw.Press || w.GetKey(glfw.KeyRightShift) == glfw.Press {
+		lines *= scroll.ShiftMultiplier
+	}
+	if (lines > 0) != (gui.scrollRemainder > 0) {
+		gui.scrollRemainder = 0
+	}
+	gui.scrollRemainder += lines
+	n := int(gui.scrollRemainder)
+	gui.scrollRemainder -= float64(n)
+	if n == 0 {
+		return
+	}
+
+	if !gui.terminal.UsingMainBuffer() {
+		// the alt screen has no scrollback, so the wheel moves the cursor instead, as xterm's alternateScroll does
+		key, count := byte('B'), -n
+		if n > 0 {
+			key, count = 'A', n
+		}
+		prefix := byte('[')
+		if gui.terminal.IsApplicationCursorKeysModeEnabled() {
+			prefix = 'O'
+		}
+		gui.writeInput(bytes.Repeat([]byte{0x1b, prefix, key}, count))
+		return
+	}
+
+	if n > 0 {
+		gui.terminal.ScreenScrollUp(uint16(n))
 	} else {
-		gui.terminal.ScreenScrollDown(1)
+		gui.terminal.ScreenScrollDown(uint16(-n))
+	}
+}
+
+// reportWheel reports the wheel turning to the application, sideways too if the mouse has a wheel which does. It
+// returns false if the application doesn't want wheel events.
+func (gui *GUI) reportWheel(xoff float64, yoff float64, tx int, ty int) bool {
+	var button byte
+	switch {
+	case yoff > 0:
+		button = terminal.MouseWheelUp
+	case yoff < 0:
+		button = terminal.MouseWheelDown
+	case xoff > 0:
+		button = terminal.MouseWheelLeft
+	default:
+		button = terminal.MouseWheelRight
 	}
+	return gui.reportMouse(terminal.MouseEvent{Button: button, X: tx, Y: ty})
 }
 
 func (gui *GUI) getHandCursor() *glfw.Cursor {
@@ -35,17 +101,34 @@ func (gui *GUI) getArrowCursor() *glfw.Cursor {
 }
 
 func (gui *GUI) mouseMoveCallback(w *glfw.Window, px float64, py float64) {
-	x, y := gui.convertMouseCoordinates(px, py)
+	if gui.scrollbar.dragging {
+		gui.dragScrollbar(py)
+		return
+	}
+	if gui.minimapDragging {
+		gui.dragMinimap(py)
+		return
+	}
+	if gui.updateScrollbarHover(w, px, py) && !gui.mouseDown {
+		return
+	}
 
-	if gui.mouseDown {
-		if gui.terminal.GetMouseMode() == terminal.MouseModeButtonEvent {
-			tx := int(x) + 1 // vt100 is 1 indexed
-			ty := int(y) + 1
-			gui.emitButtonEventToTerminal(tx, ty, glfw.MouseButtonLeft, nil, gui.mouseDownModifier)
-		} else {
-			gui.terminal.ActiveBuffer().ExtendSelection(x, y, false)
+	x, y, inside := gui.paneCoordinates(gui.convertMouseCoordinates(px, py))
+	if !inside && !gui.mouseDown {
+		// other panes only react to the mouse once they're clicked on and focused
+		if gui.hoverLink != nil {
+			gui.hoverLink = nil
+			gui.terminal.SetDirty()
 		}
-	} else {
+		w.SetCursor(gui.getArrowCursor())
+		return
+	}
+
+	if gui.mouseDown && gui.localMouse(gui.mouseDownModifier) {
+		gui.terminal.ActiveBuffer().ExtendSelection(x, y, false)
+	} else if gui.terminal.GetMouseMode() != terminal.MouseModeNone {
+		gui.reportMouseMotion(w, int(x)+1, int(y)+1) // vt100 is 1 indexed
+	} else if !gui.mouseDown && gui.inputOverlay() == nil {
 
 		hint := gui.terminal.ActiveBuffer().GetHintAtPosition(x, y)
 		if hint != nil {
@@ -55,11 +138,7 @@ func (gui *GUI) mouseMoveCallback(w *glfw.Window, px float64, py float64) {
 		}
 	}
 
-	if url := gui.terminal.ActiveBuffer().GetURLAtPosition(x, y); url != "" {
-		w.SetCursor(gui.getHandCursor())
-	} else {
-		w.SetCursor(gui.getArrowCursor())
-	}
+	gui.updateHoverLink(w, x, y)
 }
 
 func (gui *GUI) convertMouseCoordinates(px float64, py float64) (uint16, uint16) {
@@ -80,10 +159,8 @@ func (gui *GUI) updateLeftClickCount(x uint16, y uint16) int {
 	}()
 
 	if gui.prevLeftClickX == x && gui.prevLeftClickY == y && time.Since(gui.leftClickTime) < time.Millisecond*500 {
-		gui.leftClickCount++
-		if gui.leftClickCount > 3 {
-			gui.leftClickCount = 3
-		}
+		// another click after selecting a whole command's output starts again from a single character
+		gui.leftClickCount = gui.leftClickCount%4 + 1
 	} else {
 		gui.leftClickCount = 1
 	}
@@ -91,32 +168,37 @@ func (gui *GUI) updateLeftClickCount(x uint16, y uint16) int {
 	return gui.leftClickCount
 }
 
-func btnCode(button glfw.MouseButton, release bool, mod glfw.ModifierKey) (b byte, ok bool) {
-	if release {
-		b = 3
-	} else {
-		switch button {
-		case glfw.MouseButton1:
-			b = 0
-		case glfw.MouseButton2:
-			b = 1
-		case glfw.MouseButton3:
-			b = 2
-		default:
-			return 0, false
-		}
+// mouseButtonCode returns the code a button is reported to the application with
+func mouseButtonCode(button glfw.MouseButton) (byte, bool) {
+	switch button {
+	case glfw.MouseButtonLeft:
+		return terminal.MouseButtonLeft, true
+	case glfw.MouseButtonMiddle:
+		return terminal.MouseButtonMiddle, true
+	case glfw.MouseButtonRight:
+		return terminal.MouseButtonRight, true
 	}
+	return 0, false
+}
 
+func mouseModifiers(mod glfw.ModifierKey) byte {
+	var b byte
 	if mod&glfw.ModShift > 0 {
-		b |= 4
+		b |= terminal.MouseModShift
 	}
-	if mod&glfw.ModSuper > 0 {
-		b |= 8
+	if mod&glfw.ModAlt > 0 {
+		b |= terminal.MouseModMeta
 	}
 	if mod&glfw.ModControl > 0 {
-		b |= 16
+		b |= terminal.MouseModCtrl
 	}
-	return b, true
+	return b
+}
+
+// localMouse returns true if the mouse should select text, open links and so on, rather than being reported
+// to the application. As in xterm, holding shift gives local handling even when the application wants the mouse.
+func (gui *GUI) localMouse(mod glfw.ModifierKey) bool {
+	return gui.terminal.GetMouseMode() == terminal.MouseModeNone || mod&glfw.ModShift > 0
 }
 
 func (gui *GUI) mouseButtonCallback(w *glfw.Window, button glfw.MouseButton, action glfw.Action, mod glfw.ModifierKey) {
@@ -127,138 +209,140 @@ func (gui *GUI) mouseButtonCallback(w *glfw.Window, button glfw.MouseButton, act
 		return
 	}
 
+	if button == glfw.MouseButtonLeft && gui.scrollbar.dragging {
+		if action == glfw.Release {
+			gui.stopScrollbarDrag()
+		}
+		return
+	}
+	if button == glfw.MouseButtonLeft && gui.minimapDragging {
+		if action == glfw.Release {
+			gui.minimapDragging = false
+		}
+		return
+	}
+	if _, ok := gui.minimapAt(w.GetCursorPos()); ok {
+		if button == glfw.MouseButtonLeft && action == glfw.Press {
+			gui.startMinimapDrag(w.GetCursorPos())
+		}
+		return
+	}
+
 	// before we forward clicks on (below), we need to handle them locally for url clicking, text highlighting etc.
 	x, y := gui.convertMouseCoordinates(w.GetCursorPos())
+
+	if i := gui.tabAt(x, y); i >= 0 {
+		if button == glfw.MouseButtonLeft && action == glfw.Press {
+			gui.switchTab(i)
+		}
+		return
+	}
+
+	if action == glfw.Press {
+		gui.focusPane(gui.currentTab().root.at(uint(x), uint(y)))
+		if button == glfw.MouseButtonLeft && gui.startScrollbarDrag(w.GetCursorPos()) {
+			return
+		}
+	}
+	x, y, inside := gui.paneCoordinates(x, y)
+	if !inside && action == glfw.Press {
+		return // on a divider
+	}
+
 	tx := int(x) + 1 // vt100 is 1 indexed
 	ty := int(y) + 1
 
+	local := gui.localMouse(mod)
+	if action == glfw.Press {
+		gui.mouseDownModifier = mod
+	} else {
+		// a drag finishes the same way it started, whatever is held now
+		local = gui.localMouse(gui.mouseDownModifier)
+	}
+
 	switch button {
 	case glfw.MouseButtonLeft:
+		if action == glfw.Press && gui.handleToastClick(x, y) {
+			return
+		}
 		if action == glfw.Press {
-			gui.mouseDownModifier = mod
 			gui.mouseDown = true
 
-			if gui.terminal.GetMouseMode() != terminal.MouseModeButtonEvent {
-				gui.handleSelectionButtonPress(x, y)
+			if local {
+				gui.handleSelectionButtonPress(x, y, mod)
 			}
 		} else if action == glfw.Release {
 			gui.mouseDown = false
 
-			if gui.terminal.GetMouseMode() != terminal.MouseModeButtonEvent {
+			if local {
 				gui.handleSelectionButtonRelease(x, y)
 			}
 		}
 
 	case glfw.MouseButtonRight:
-		if gui.config.CopyAndPasteWithMouse && action == glfw.Press && gui.terminal.GetMouseMode() == terminal.MouseModeNone {
-			if str := gui.window.GetClipboardString(); str != "" {
+		// ctrl + right click opens the context menu for the selection
+		if mod&glfw.ModControl > 0 && local {
+			if action == glfw.Release {
+				actionOpenWith(gui)
+			}
+			return
+		}
+		if gui.config.CopyAndPasteWithMouse && action == glfw.Press && local {
+			if str := gui.getSelection(selectionClipboard); str != "" {
 				activeBuffer := gui.terminal.ActiveBuffer()
 				activeBuffer.ClearSelection()
-				_ = gui.terminal.Paste([]byte(str))
+				gui.paste(str, false)
 			}
 		}
-	}
-
-	// https://www.xfree86.org/4.8.0/ctlseqs.html
-
-	/*
-		Parameters (such as pointer position and button number) for all mouse tracking escape sequences
-		generated by xterm encode numeric parameters in a single character as value+32. For example,
-		! specifies the value 1. The upper left character position on the terminal is denoted as 1,1.
-	*/
-
-	switch gui.terminal.GetMouseMode() {
-	case terminal.MouseModeNone:
-
-		// handle clicks locally
-
-		return
-	case terminal.MouseModeX10: // X10 compatibility mode
 
-		/*
-			X10 compatibility mode sends an escape sequence only on button press, encoding the location and the mouse button pressed.
-
-			It is enabled by specifying parameter 9 to DECSET.
-
-			On button press, xterm sends CSI M C b C x C y (6 characters).
-
-			C b is button−1.
-
-			C x and C y are the x and y coordinates of the mouse when the button was pressed.
-		*/
-
-		if action == glfw.Press {
-			b := rune(button)
-			packet := fmt.Sprintf("\x1b[M%c%c%c", (rune(b + 32)), (rune(tx + 32)), (rune(ty + 32)))
-
-			gui.terminal.Write([]byte(packet))
+	case glfw.MouseButtonMiddle:
+		if gui.config.Selection.Primary && action == glfw.Press && local {
+			if str := gui.getSelection(selectionPrimary); str != "" {
+				gui.paste(str, false)
+			}
+			return
 		}
-	case terminal.MouseModeVT200: // normal
-		/*
-
-			Normal tracking mode sends an escape sequence on both button press and release.
-
-			Modifier key (shift, ctrl, meta) information is also sent.
-
-			It is enabled by specifying parameter 1000 to DECSET.
-
-			On button press or release, xterm sends CSI M C b C x C y .
-
-			The low two bits of C b encode button information: 0=MB1 pressed, 1=MB2 pressed, 2=MB3 pressed, 3=release.
-
-			The next three bits encode the modifiers which were down when the button was pressed and are added together: 4=Shift, 8=Meta, 16=Control.
-
-			Note however that the shift and control bits are normally unavailable because xterm uses the control modifier with mouse for popup menus,
-			and the shift modifier is used in the default translations for button events. The Meta modifier recognized by xterm is the mod1 mask, and
-			is not necessarily the "Meta" key (see xmodmap).
-
-			C x and C y are the x and y coordinates of the mouse event, encoded as in X10 mode.
-
-			Wheel mice may return buttons 4 and 5. Those buttons are represented by the same event codes as buttons 1 and 2 respectively, except that 64 is added to the event code. Release events for the wheel buttons are not reported.
-		*/
-		gui.emitButtonEventToTerminal(tx, ty, button, &action, mod)
-
-	case terminal.MouseModeVT200Highlight:
-		/*
-		   Mouse highlight tracking notifies a program of a button press, receives a range of lines from the program, highlights the region covered by the mouse within that range until button release, and then sends the program the release coordinates. It is enabled by specifying parameter 1001 to DECSET. Highlighting is performed only for button 1, though other button events can be received. Warning: use of this mode requires a cooperating program or it will hang xterm. On button press, the same information as for normal tracking is generated; xterm then waits for the program to send mouse tracking information. All X events are ignored until the proper escape sequence is received from the pty: CSI P s ; P s ; P s ; P s ; P s T . The parameters are func, startx, starty, firstrow, and lastrow. func is non-zero to initiate highlight tracking and zero to abort. startx and starty give the starting x and y location for the highlighted region. The ending location tracks the mouse, but will never be above row firstrow and will always be above row lastrow. (The top of the screen is row 1.) When the button is released, xterm reports the ending position one of two ways: if the start and end coordinates are valid text locations: CSI t C x C y . If either coordinate is past the end of the line: CSI T C x C y C x C y C x C y . The parameters are startx, starty, endx, endy, mousex, and mousey. startx, starty, endx, and endy give the starting and ending character positions of the region. mousex and mousey give the location of the mouse at button up, which may not be over a character.
-		*/
-		panic("VT200 mouse highlight mode not supported")
-
-	case terminal.MouseModeButtonEvent:
-		/*
-		   Button-event tracking is essentially the same as normal tracking, but xterm also reports button-motion events.
-		   Motion events are reported only if the mouse pointer has moved to a different character cell. It is enabled by specifying parameter 1002 to DECSET.
-		   On button press or release, xterm sends the same codes used by normal tracking mode.
-		   On button-motion events, xterm adds 32 to the event code (the third character, C b ).
-		   The other bits of the event code specify button and modifier keys as in normal mode.
-		   For example, motion into cell x,y with button 1 down is reported as CSI M @ C x C y . ( @ = 32 + 0 (button 1) + 32 (motion indicator) ).
-		   Similarly, motion with button 3 down is reported as CSI M B C x C y . ( B = 32 + 2 (button 3) + 32 (motion indicator) ).
-		*/
-		gui.emitButtonEventToTerminal(tx, ty, button, &action, mod)
-
-	case terminal.MouseModeAnyEvent:
-		/*
-		   Any-event mode is the same as button-event mode, except that all motion events are reported, even if no mouse button is down. It is enabled by specifying 1003 to DECSET.
-
-
-		*/
-		panic("Mouse any event mode not supported")
+	}
 
-	default:
-		panic("Unsupported mouse mode")
+	if !local {
+		if code, ok := mouseButtonCode(button); ok {
+			gui.reportMouse(terminal.MouseEvent{
+				Button:    code,
+				Modifiers: mouseModifiers(mod),
+				Release:   action == glfw.Release,
+				X:         tx,
+				Y:         ty,
+			})
+		}
 	}
 }
 
-func (gui *GUI) handleSelectionButtonPress(x uint16, y uint16) {
+func (gui *GUI) handleSelectionButtonPress(x uint16, y uint16, mod glfw.ModifierKey) {
 	activeBuffer := gui.terminal.ActiveBuffer()
 	clickCount := gui.updateLeftClickCount(x, y)
+	if mod&glfw.ModShift > 0 && activeBuffer.ExtendSelectionTo(x, y) {
+		// shift + click moves the nearer end of the selection here, and dragging carries on moving it
+		gui.mouseMovedAfterSelectionStarted = true
+		return
+	}
+	if mod&glfw.ModAlt > 0 {
+		// alt + drag selects a rectangle rather than running text
+		activeBuffer.StartSelection(x, y, buffer.SelectionBlock)
+		gui.mouseMovedAfterSelectionStarted = false
+		return
+	}
+	activeBuffer.SetWordChars(gui.config.Selection.WordChars)
 	switch clickCount {
 	case 1:
 		activeBuffer.StartSelection(x, y, buffer.SelectionChar)
 	case 2:
 		activeBuffer.StartSelection(x, y, buffer.SelectionWord)
 	case 3:
+		// the whole line, as it was written before it was wrapped
 		activeBuffer.StartSelection(x, y, buffer.SelectionLine)
+	case 4:
+		// the output of the command under the mouse, if the shell marks it
+		activeBuffer.StartSelection(x, y, buffer.SelectionOutput)
 	}
 	gui.mouseMovedAfterSelectionStarted = false
 }
@@ -273,68 +357,43 @@ func (gui *GUI) handleSelectionButtonRelease(x uint16, y uint16) {
 		activeBuffer.ExtendSelection(x, y, true)
 	}
 
-	// Do copy to clipboard *or* open URL, but not both.
-	handled := false
-	if gui.config.CopyAndPasteWithMouse {
-		selectedText := activeBuffer.GetSelectedText()
-		if selectedText != "" {
-			gui.window.SetClipboardString(selectedText)
-			handled = true
-		}
-	}
-
-	if !handled {
-		if url := activeBuffer.GetURLAtPosition(x, y); url != "" {
+	// Copy the selection *or* open URL, but not both.
+	if !gui.selected(gui.selectedText()) {
+		if link := gui.linkAt(x, y, gui.mouseDownModifier); link != nil {
+			gui.runLink(*link)
+		} else if location := gui.locationAt(x, y, gui.mouseDownModifier); location != nil {
+			gui.openLocation(*location)
+		} else if url := activeBuffer.GetURLAtPosition(x, y); url != "" {
 			go gui.launchTarget(url)
 		}
 	}
 }
 
-func (gui *GUI) emitButtonEventToTerminal(tx int, ty int, button glfw.MouseButton, action *glfw.Action, mod glfw.ModifierKey) {
-	motion := action == nil
-
-	release := false
-	if !motion {
-		if *action == glfw.Release {
-			release = true
-		} else if *action != glfw.Press {
-			return
-		}
-	}
-
-	ext := gui.terminal.GetMouseExtMode()
-
-	// For SGR, normal button encoding (as for Press event)
-	b, ok := btnCode(button, release && ext != terminal.MouseExtSGR, mod)
-
-	if !ok {
-		return // unknown button
+// reportMouse sends a mouse event to the application, returning false if it didn't ask for events of that kind
+func (gui *GUI) reportMouse(e terminal.MouseEvent) bool {
+	packet := gui.terminal.MouseReport(e)
+	if packet == nil {
+		return false
 	}
+	gui.queueWrite(gui.terminal, packet)
+	return true
+}
 
-	// @todo check limits for non-SGR encoding
-
-	if motion {
-		b |= 32
-
-		// after applying limits we can check the final values
-		if tx == gui.prevMotionTX && ty == gui.prevMotionTY {
-			return
-		}
+// reportMouseMotion reports the mouse moving into a different cell, along with the button held down if there is one
+func (gui *GUI) reportMouseMotion(w *glfw.Window, tx int, ty int) {
+	if tx == gui.prevMotionTX && ty == gui.prevMotionTY {
+		return
 	}
-
 	gui.prevMotionTX = tx
 	gui.prevMotionTY = ty
 
-	var packet string
-	if ext == terminal.MouseExtSGR {
-		final := 'M'
-		if release {
-			final = 'm'
+	e := terminal.MouseEvent{Button: terminal.MouseButtonNone, Motion: true, X: tx, Y: ty}
+	for _, button := range []glfw.MouseButton{glfw.MouseButtonLeft, glfw.MouseButtonMiddle, glfw.MouseButtonRight} {
+		if w.GetMouseButton(button) == glfw.Press {
+			e.Button, _ = mouseButtonCode(button)
+			e.Modifiers = mouseModifiers(gui.mouseDownModifier)
+			break
 		}
-		packet = fmt.Sprintf("\x1b[<%d;%d;%d%c", b, tx, ty, final)
-	} else {
-		packet = fmt.Sprintf("\x1b[M%c%c%c", (rune(b + 32)), (rune(tx + 32)), (rune(ty + 32)))
 	}
-	gui.logger.Infof("Sending mouse packet: '%v'", packet)
-	gui.terminal.Write([]byte(packet))
+	gui.reportMouse(e)
 }
@@ -0,0 +1,40 @@
+package gui
+
+import (
+	"fmt"
+
+	"github.com/liamg/aminal/buffer"
+	"github.com/liamg/aminal/platform"
+	"github.com/liamg/aminal/terminal"
+)
+
+// commandFinished tells the user how a command went when it finishes while they're looking at another window
+func (gui *GUI) commandFinished(finished terminal.CommandFinished) {
+	if gui.windowFocused || !gui.config.Notify.CommandFinished {
+		return
+	}
+	title := "Command finished"
+	switch finished.ExitStatus {
+	case buffer.UnknownExitStatus:
+	case 0:
+		title = "Command succeeded"
+	default:
+		title = fmt.Sprintf("Command failed with exit status %d", finished.ExitStatus)
+	}
+	gui.notify(title, finished.Command)
+}
+
+// notify shows a desktop notification, with the command from the config if there is one
+func (gui *GUI) notify(title string, body string) {
+	go func(command string) {
+		var err error
+		if command != "" {
+			err = platform.RunCommand(command, "AMINAL_NOTIFY_TITLE="+title, "AMINAL_NOTIFY_BODY="+body)
+		} else {
+			err = platform.Notify(title, body)
+		}
+		if err != nil {
+			gui.logger.Errorf("Failed to show notification '%s': %s", title, err)
+		}
+	}(gui.config.Notify.Command)
+}
@@ -0,0 +1,68 @@
+package gui
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/liamg/aminal/config"
+	"github.com/liamg/aminal/platform"
+)
+
+// actionOpenWith shows a menu of the places the current selection can be sent: a web search, or any configured open_with targets
+func actionOpenWith(gui *GUI) {
+	selection := gui.selectedText()
+	if selection == "" {
+		return
+	}
+
+	targets := []config.OpenWithTarget{}
+	if isValidSearchURL(gui.config.SearchURL) {
+		targets = append(targets, config.OpenWithTarget{Name: "Search the web", URL: gui.config.SearchURL})
+	}
+	targets = append(targets, gui.config.OpenWith...)
+	if len(targets) == 0 {
+		return
+	}
+
+	items := make([]string, len(targets))
+	for i, target := range targets {
+		items[i] = target.Name
+	}
+
+	gui.setOverlay(newMenu(fmt.Sprintf("Open '%s' with:", abbreviate(selection, 40)), items, func(gui *GUI, index int) {
+		gui.openWith(targets[index], selection)
+	}))
+}
+
+func (gui *GUI) openWith(target config.OpenWithTarget, selection string) {
+	if target.URL != "" {
+		if !isValidSearchURL(target.URL) {
+			gui.logger.Errorf("Open with target '%s' has no $QUERY in its url", target.Name)
+			return
+		}
+		go gui.launchTarget(strings.Replace(target.URL, "$QUERY", url.QueryEscape(selection), 1))
+		return
+	}
+
+	if target.Command != "" {
+		go func() {
+			if err := platform.PipeToCommand(target.Command, selection); err != nil {
+				gui.logger.Errorf("Open with '%s' failed: %s", target.Name, err)
+			}
+		}()
+	}
+}
+
+func isValidSearchURL(u string) bool {
+	return u != "" && strings.Contains(u, "$QUERY")
+}
+
+func abbreviate(s string, max int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max-3]) + "..."
+}
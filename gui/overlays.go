@@ -1,12 +1,31 @@
 package gui
 
+import "github.com/go-gl/glfw/v3.3/glfw"
+
 type overlay interface {
 	render(gui *GUI)
 }
 
+// inputOverlay is an overlay which captures keyboard input while it is shown
+type inputOverlay interface {
+	overlay
+	key(gui *GUI, key glfw.Key, mods glfw.ModifierKey)
+	char(gui *GUI, r rune)
+}
+
+// closableOverlay is an overlay which needs to know when it has been closed or replaced
+type closableOverlay interface {
+	overlay
+	closed(gui *GUI)
+}
+
 func (gui *GUI) setOverlay(m overlay) {
 	defer gui.terminal.SetDirty()
+	previous := gui.overlay
 	gui.overlay = m
+	if c, ok := previous.(closableOverlay); ok && previous != m {
+		c.closed(gui)
+	}
 }
 
 func (gui *GUI) renderOverlay() {
@@ -16,3 +35,13 @@ func (gui *GUI) renderOverlay() {
 
 	gui.overlay.render(gui)
 }
+
+func (gui *GUI) inputOverlay() inputOverlay {
+	if gui.overlay == nil {
+		return nil
+	}
+	if o, ok := gui.overlay.(inputOverlay); ok {
+		return o
+	}
+	return nil
+}
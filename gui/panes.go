@@ -0,0 +1,427 @@
+package gui
+
+import (
+	"fmt"
+
+	"github.com/liamg/aminal/buffer"
+	"github.com/liamg/aminal/config"
+	"github.com/liamg/aminal/terminal"
+)
+
+// how far a pane resize shortcut moves the divider, in cells
+const paneResizeStep = 2
+
+type direction int
+
+const (
+	directionLeft direction = iota
+	directionRight
+	directionUp
+	directionDown
+)
+
+// pane is a node in the tree a tab's area is split into. Leaves show a terminal, and the other nodes
+// are split between two children, either side by side or one above the other, with a divider between them.
+type pane struct {
+	parent   *pane
+	terminal *terminal.Terminal // only set on leaves
+	children [2]*pane
+	vertical bool    // the children are side by side, with a vertical divider
+	ratio    float64 // share of the space given to the first child
+	col      uint    // the cells the pane was last laid out in
+	row      uint
+	cols     uint
+	rows     uint
+	damage   buffer.Damage // changes to the terminal waiting to be drawn
+
+	host       string               // the host the terminal's shell was last seen on, where it isn't this one
+	hostScheme *config.ColourScheme // colours for that host, used instead of the tab's, or nil
+	hostTint   *config.Colour       // colour mixed into the background for that host, or nil
+}
+
+func newPane(t *terminal.Terminal) *pane {
+	return &pane{terminal: t}
+}
+
+func (p *pane) isLeaf() bool {
+	return p.terminal != nil
+}
+
+// leaves returns the panes showing a terminal, from left to right and top to bottom
+func (p *pane) leaves() []*pane {
+	if p.isLeaf() {
+		return []*pane{p}
+	}
+	return append(p.children[0].leaves(), p.children[1].leaves()...)
+}
+
+// find returns the leaf showing t, or nil
+func (p *pane) find(t *terminal.Terminal) *pane {
+	for _, leaf := range p.leaves() {
+		if leaf.terminal == t {
+			return leaf
+		}
+	}
+	return nil
+}
+
+// at returns the leaf laid out over the cell, or nil if it's on a divider
+func (p *pane) at(col uint, row uint) *pane {
+	for _, leaf := range p.leaves() {
+		if col >= leaf.col && col < leaf.col+leaf.cols && row >= leaf.row && row < leaf.row+leaf.rows {
+			return leaf
+		}
+	}
+	return nil
+}
+
+// layout divides the given cells between the pane and its descendants
+func (p *pane) layout(col uint, row uint, cols uint, rows uint) {
+	p.col, p.row, p.cols, p.rows = col, row, cols, rows
+	if p.isLeaf() {
+		return
+	}
+	// a second side without any cells is put at the far edge, rather than past it
+	if p.vertical {
+		first, second := splitCells(cols, p.ratio)
+		p.children[0].layout(col, row, first, rows)
+		p.children[1].layout(col+cols-second, row, second, rows)
+	} else {
+		first, second := splitCells(rows, p.ratio)
+		p.children[0].layout(col, row, cols, first)
+		p.children[1].layout(col, row+rows-second, cols, second)
+	}
+}
+
+// splitCells shares out the cells either side of a one cell divider, leaving each side at least one cell where
+// possible. Without room for both sides and the divider, the first side gets every cell and the second none.
+func splitCells(cells uint, ratio float64) (uint, uint) {
+	if cells < 3 {
+		return cells, 0
+	}
+	available := cells - 1
+	first := uint(float64(available)*ratio + 0.5)
+	if first < 1 {
+		first = 1
+	} else if first > available-1 {
+		first = available - 1
+	}
+	return first, available - first
+}
+
+// split turns a leaf into two, with t shown to the right of or below the existing terminal. It returns the new leaf.
+func (p *pane) split(t *terminal.Terminal, vertical bool) *pane {
+	existing := &pane{parent: p, terminal: p.terminal}
+	added := &pane{parent: p, terminal: t}
+	p.terminal = nil
+	p.vertical = vertical
+	p.ratio = 0.5
+	p.children = [2]*pane{existing, added}
+	p.layout(p.col, p.row, p.cols, p.rows)
+	return added
+}
+
+// sibling returns the other child of p's parent, or nil if p is the root
+func (p *pane) sibling() *pane {
+	if p.parent == nil {
+		return nil
+	}
+	if p.parent.children[0] == p {
+		return p.parent.children[1]
+	}
+	return p.parent.children[0]
+}
+
+// remove takes a leaf out of the tree, giving its space to its sibling, and returns the new root
+func (p *pane) remove(root *pane) *pane {
+	parent := p.parent
+	if parent == nil {
+		return nil
+	}
+	sibling := p.sibling()
+	sibling.parent = parent.parent
+	if parent.parent == nil {
+		return sibling
+	}
+	if parent.parent.children[0] == parent {
+		parent.parent.children[0] = sibling
+	} else {
+		parent.parent.children[1] = sibling
+	}
+	return root
+}
+
+// neighbour returns the leaf across the divider from p in the given direction, or nil if p is at the edge
+func (p *pane) neighbour(root *pane, dir direction) *pane {
+	var best *pane
+	bestOverlap := 0
+	for _, leaf := range root.leaves() {
+		var adjacent bool
+		var overlap int
+		switch dir {
+		case directionLeft:
+			adjacent = leaf.col+leaf.cols+1 == p.col
+		case directionRight:
+			adjacent = p.col+p.cols+1 == leaf.col
+		case directionUp:
+			adjacent = leaf.row+leaf.rows+1 == p.row
+		case directionDown:
+			adjacent = p.row+p.rows+1 == leaf.row
+		}
+		if !adjacent {
+			continue
+		}
+		if dir == directionLeft || dir == directionRight {
+			overlap = spanOverlap(p.row, p.rows, leaf.row, leaf.rows)
+		} else {
+			overlap = spanOverlap(p.col, p.cols, leaf.col, leaf.cols)
+		}
+		if overlap > bestOverlap {
+			best = leaf
+			bestOverlap = overlap
+		}
+	}
+	return best
+}
+
+func spanOverlap(start1 uint, len1 uint, start2 uint, len2 uint) int {
+	start, end := start1, start1+len1
+	if start2 > start {
+		start = start2
+	}
+	if start2+len2 < end {
+		end = start2 + len2
+	}
+	return int(end) - int(start)
+}
+
+// moveDivider moves the nearest divider of p in the given direction by the given number of cells,
+// returning false if there's no divider which moves that way
+func (p *pane) moveDivider(dir direction, cells int) bool {
+	vertical := dir == directionLeft || dir == directionRight
+	for node := p.parent; node != nil; node = node.parent {
+		if node.vertical != vertical {
+			continue
+		}
+		size := node.rows
+		if vertical {
+			size = node.cols
+		}
+		if size < 3 {
+			return false
+		}
+		delta := float64(cells) / float64(size-1)
+		if dir == directionLeft || dir == directionUp {
+			delta = -delta
+		}
+		node.ratio += delta
+		if node.ratio < 0.05 {
+			node.ratio = 0.05
+		} else if node.ratio > 0.95 {
+			node.ratio = 0.95
+		}
+		return true
+	}
+	return false
+}
+
+func (gui *GUI) currentTab() *tab {
+	return gui.tabs[gui.activeTab]
+}
+
+// layoutPanes fits the active tab's panes into the terminal grid, and resizes each terminal to its pane
+func (gui *GUI) layoutPanes() {
+	cols, rows := gui.terminalGridSize()
+	tab := gui.currentTab()
+	tab.root.layout(0, 0, cols, rows)
+	gui.invalidateFrame()
+	for _, p := range tab.root.leaves() {
+		if p.cols == 0 || p.rows == 0 {
+			continue // squeezed out by a small window, so left at its size until there's room for it again
+		}
+		if err := p.terminal.SetSize(p.cols, p.rows); err != nil {
+			gui.logger.Errorf("Failed to resize terminal to %d cols, %d rows: %s", p.cols, p.rows, err)
+		}
+		p.terminal.SetCharSize(gui.renderer.cellWidth, gui.renderer.cellHeight)
+		p.terminal.SetDirty()
+	}
+}
+
+// focusPane sends keyboard input to the terminal in p, which must be in the active tab
+func (gui *GUI) focusPane(p *pane) {
+	tab := gui.currentTab()
+	if p == nil || p == tab.focus {
+		return
+	}
+	gui.setOverlay(nil)
+	gui.hoverLink = nil
+	tab.focus = p
+	gui.reportFocus(gui.terminal, false)
+	gui.terminal = p.terminal
+	gui.reportFocus(gui.terminal, true)
+	gui.window.SetTitle(gui.terminal.GetTitle())
+	gui.generateDefaultCell(gui.terminal.ScreenMode())
+	gui.terminal.SetDirty()
+}
+
+// reportFocus tells the application in t that it has gained or lost focus, if it wants to know. Terminals only
+// have focus while the window does.
+func (gui *GUI) reportFocus(t *terminal.Terminal, focused bool) {
+	if !gui.windowFocused {
+		return
+	}
+	if report := t.FocusReport(focused); report != nil {
+		gui.queueWrite(t, report)
+	}
+}
+
+// paneCoordinates converts cell coordinates in the window to ones in the focused pane, clamped to
+// its edges. It returns false if the coordinates were outside the pane.
+func (gui *GUI) paneCoordinates(x uint16, y uint16) (uint16, uint16, bool) {
+	p := gui.currentTab().focus
+	inside := true
+	clamp := func(v uint16, start uint, size uint) uint16 {
+		if uint(v) < start {
+			inside = false
+			return 0
+		}
+		if uint(v) >= start+size {
+			inside = false
+			if size == 0 {
+				return 0
+			}
+			return uint16(size - 1)
+		}
+		return v - uint16(start)
+	}
+	x = clamp(x, p.col, p.cols)
+	y = clamp(y, p.row, p.rows)
+	return x, y, inside
+}
+
+// checkDirty takes the changes to each terminal in the active tab, to be drawn on the next frame, returning
+// true if there are any
+func (gui *GUI) checkDirty() bool {
+	dirty := false
+	for _, p := range gui.currentTab().root.leaves() {
+		p.damage = p.terminal.TakeDamage()
+		if !p.damage.Empty() {
+			dirty = true
+		}
+	}
+	return dirty
+}
+
+// renderDividers draws the lines between the panes of a tab
+func (gui *GUI) renderDividers(p *pane) {
+	if p.isLeaf() {
+		return
+	}
+	fg, bg := gui.config.ColourScheme.Foreground, gui.config.ColourScheme.Background
+	var colour config.Colour
+	for i := range colour {
+		colour[i] = bg[i] + (fg[i]-bg[i])*0.3
+	}
+	first := p.children[0]
+	// the divider is only drawn while there's room for it
+	if p.vertical && first.cols < p.cols {
+		for row := p.row; row < p.row+p.rows; row++ {
+			gui.renderer.DrawCellBg(*gui.defaultCell, first.col+first.cols, row, &colour, true)
+		}
+	} else if !p.vertical && first.rows < p.rows {
+		for col := p.col; col < p.col+p.cols; col++ {
+			gui.renderer.DrawCellBg(*gui.defaultCell, col, first.row+first.rows, &colour, true)
+		}
+	}
+	gui.renderDividers(p.children[0])
+	gui.renderDividers(p.children[1])
+}
+
+func (gui *GUI) splitPane(vertical bool) {
+	if gui.newSession == nil {
+		gui.logger.Infof("Panes aren't available when Aminal is used as a library without a session factory")
+		return
+	}
+	tab := gui.currentTab()
+	cells := tab.focus.rows
+	if vertical {
+		cells = tab.focus.cols
+	}
+	if cells < 3 {
+		// each side needs a cell, with one for the divider between them
+		gui.showToast(newNotice("There's no room to split this pane"))
+		return
+	}
+	t, err := gui.newSession(gui.terminal.WorkingDirectory(), tab.profile)
+	if err != nil {
+		gui.logger.Errorf("Failed to open a new pane: %s", err)
+		gui.showToast(newNotice(fmt.Sprintf("Failed to open a new pane: %s", err)))
+		return
+	}
+	if tab.scheme != nil {
+		setColours(t, *tab.scheme)
+	}
+	added := tab.focus.split(t, vertical)
+	gui.attachTerminal(t)
+	gui.layoutPanes()
+	gui.focusPane(added)
+}
+
+func actionSplitRight(gui *GUI) {
+	gui.splitPane(true)
+}
+
+func actionSplitDown(gui *GUI) {
+	gui.splitPane(false)
+}
+
+func actionClosePane(gui *GUI) {
+	t := gui.terminal
+	gui.removeTerminal(t)
+	if err := t.Close(); err != nil {
+		gui.logger.Errorf("Failed to close pty: %s", err)
+	}
+}
+
+func (gui *GUI) focusNeighbour(dir direction) {
+	tab := gui.currentTab()
+	gui.focusPane(tab.focus.neighbour(tab.root, dir))
+}
+
+func actionPaneLeft(gui *GUI) {
+	gui.focusNeighbour(directionLeft)
+}
+
+func actionPaneRight(gui *GUI) {
+	gui.focusNeighbour(directionRight)
+}
+
+func actionPaneUp(gui *GUI) {
+	gui.focusNeighbour(directionUp)
+}
+
+func actionPaneDown(gui *GUI) {
+	gui.focusNeighbour(directionDown)
+}
+
+func (gui *GUI) resizePane(dir direction) {
+	if gui.currentTab().focus.moveDivider(dir, paneResizeStep) {
+		gui.layoutPanes()
+	}
+}
+
+func actionResizePaneLeft(gui *GUI) {
+	gui.resizePane(directionLeft)
+}
+
+func actionResizePaneRight(gui *GUI) {
+	gui.resizePane(directionRight)
+}
+
+func actionResizePaneUp(gui *GUI) {
+	gui.resizePane(directionUp)
+}
+
+func actionResizePaneDown(gui *GUI) {
+	gui.resizePane(directionDown)
+}
@@ -0,0 +1,57 @@
+package gui
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	// pinchStep is the finest change to the size of the text made while pinching, so the fonts aren't reloaded for
+	// every tiny movement of the fingers
+	pinchStep = 0.25
+	// pinchSettleTime is how long the size has to stay the same before it's remembered, when there's no telling
+	// when the gesture has ended
+	pinchSettleTime = 500 * time.Millisecond
+)
+
+// pinch scales the text by the magnification of a pinch on a touchpad as the fingers move, e.g. by 0.1 to make it
+// 10% bigger. Once the gesture has ended, the size is remembered if the config asks for it.
+func (gui *GUI) pinch(magnification float64, ended bool) {
+	gui.pinchScale += magnification * float64(gui.fontScale)
+	gui.applyPinch()
+	if ended {
+		gui.pinchScale = 0
+		gui.rememberFontScale()
+	}
+}
+
+// zoomWheel changes the size of the text by a step for each click of the wheel, which is how touchpads on Windows
+// report pinches
+func (gui *GUI) zoomWheel(clicks float64) {
+	gui.pinchScale += clicks
+	gui.applyPinch()
+	if gui.pinchTimer != nil {
+		gui.pinchTimer.Stop()
+	}
+	gui.pinchTimer = time.AfterFunc(pinchSettleTime, func() {
+		gui.mainThreadQueue <- func() {
+			gui.pinchScale = 0
+			gui.rememberFontScale()
+		}
+	})
+}
+
+// applyPinch changes the size of the text by as many whole pinch steps as the fingers have moved, keeping the rest
+// for the next movement
+func (gui *GUI) applyPinch() {
+	steps := math.Trunc(gui.pinchScale / pinchStep)
+	if steps == 0 {
+		return
+	}
+	gui.pinchScale -= steps * pinchStep
+	scale := gui.fontScale + float32(steps*pinchStep)
+	if scale < minFontScale {
+		scale = minFontScale
+	}
+	gui.setFontScale(scale)
+}
@@ -0,0 +1,20 @@
+package gui
+
+/*
+void cocoa_hook_pinch(void *window);
+*/
+import "C"
+
+// pinchGUI is the GUI the pinch gesture hook reports to, as there's only ever one window
+var pinchGUI *GUI
+
+// hookPinch watches for magnification gestures on the window, which GLFW ignores
+func hookPinch(gui *GUI) {
+	pinchGUI = gui
+	C.cocoa_hook_pinch(gui.window.GetCocoaWindow())
+}
+
+//export goPinch
+func goPinch(magnification C.double, ended C.int) {
+	pinchGUI.pinch(float64(magnification), ended != 0)
+}
@@ -0,0 +1,18 @@
+#include <Cocoa/Cocoa.h>
+
+extern void goPinch(double magnification, int ended);
+
+static id monitor;
+
+void cocoa_hook_pinch(void *window) {
+	if (monitor != nil) {
+		return;
+	}
+	monitor = [NSEvent addLocalMonitorForEventsMatchingMask:NSEventMaskMagnify handler:^NSEvent *(NSEvent *event) {
+		if ([event window] == (NSWindow *)window) {
+			NSEventPhase phase = [event phase];
+			goPinch([event magnification], phase == NSEventPhaseEnded || phase == NSEventPhaseCancelled);
+		}
+		return event;
+	}];
+}
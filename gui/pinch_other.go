@@ -0,0 +1,8 @@
+// +build !darwin
+
+package gui
+
+// hookPinch does nothing, as there's no pinch gesture to watch for here. Touchpads on Windows report pinches as
+// the wheel turning with ctrl held, which zooms the text anyway.
+func hookPinch(gui *GUI) {
+}
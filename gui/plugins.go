@@ -0,0 +1,100 @@
+package gui
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/go-gl/glfw/v3.3/glfw"
+	"github.com/liamg/aminal/config"
+)
+
+// pluginsDirectory returns where the Lua plugins are kept, which is a plugins directory next to the config file
+// unless the config says otherwise
+func pluginsDirectory(conf *config.Config) string {
+	if conf.PluginsDirectory != "" {
+		return conf.PluginsDirectory
+	}
+	if conf.Path != "" {
+		return filepath.Join(filepath.Dir(conf.Path), "plugins")
+	}
+	return ""
+}
+
+// pluginKeyNames are the names plugins are given for the keys without a single character name
+var pluginKeyNames = map[glfw.Key]string{
+	glfw.KeyTab:       "tab",
+	glfw.KeyEnter:     "enter",
+	glfw.KeyKPEnter:   "enter",
+	glfw.KeyEscape:    "escape",
+	glfw.KeySpace:     "space",
+	glfw.KeyBackspace: "backspace",
+	glfw.KeyInsert:    "insert",
+	glfw.KeyDelete:    "delete",
+	glfw.KeyLeft:      "left",
+	glfw.KeyRight:     "right",
+	glfw.KeyUp:        "up",
+	glfw.KeyDown:      "down",
+	glfw.KeyPageUp:    "pageup",
+	glfw.KeyPageDown:  "pagedown",
+	glfw.KeyHome:      "home",
+	glfw.KeyEnd:       "end",
+	glfw.KeyF1:        "f1",
+	glfw.KeyF2:        "f2",
+	glfw.KeyF3:        "f3",
+	glfw.KeyF4:        "f4",
+	glfw.KeyF5:        "f5",
+	glfw.KeyF6:        "f6",
+	glfw.KeyF7:        "f7",
+	glfw.KeyF8:        "f8",
+	glfw.KeyF9:        "f9",
+	glfw.KeyF10:       "f10",
+	glfw.KeyF11:       "f11",
+	glfw.KeyF12:       "f12",
+}
+
+// pluginKeyName names the key for the plugins like shortcuts are named in the config, e.g. "ctrl+shift+k", or
+// returns an empty string if it doesn't have a name
+func pluginKeyName(key glfw.Key, scancode int, mods glfw.ModifierKey) string {
+	name, ok := pluginKeyNames[key]
+	if !ok {
+		name = strings.ToLower(glfw.GetKeyName(key, scancode))
+	}
+	if name == "" {
+		return ""
+	}
+	for _, mod := range []struct {
+		mod  glfw.ModifierKey
+		name string
+	}{{glfw.ModSuper, "super"}, {glfw.ModShift, "shift"}, {glfw.ModAlt, "alt"}, {glfw.ModControl, "ctrl"}} {
+		if mods&mod.mod != 0 {
+			name = mod.name + "+" + name
+		}
+	}
+	return name
+}
+
+// renderPlugins draws the marks and status the plugins have asked to be shown over the focused terminal
+func (gui *GUI) renderPlugins() {
+	overlay := gui.plugins.Overlay(gui.terminal)
+	width := int(gui.terminal.ActiveBuffer().ViewWidth())
+	height := int(gui.terminal.ActiveBuffer().ViewHeight())
+
+	highlight := config.Colour{0.4, 0.75, 0.9}
+	for _, mark := range overlay.Marks {
+		if mark.Row < 0 || mark.Row >= height || mark.Col < 0 {
+			continue
+		}
+		text := []rune(mark.Text)
+		if len(text) > width-mark.Col {
+			text = text[:width-mark.Col]
+		}
+		for i := range text {
+			gui.renderer.DrawCellBg(*gui.defaultCell, uint(mark.Col+i), uint(mark.Row), &highlight, true)
+		}
+		gui.renderer.DrawCellText(string(text), uint(mark.Col), uint(mark.Row), 1, [3]float32{0, 0, 0}, true, false)
+	}
+
+	if overlay.Status != "" && height >= 3 {
+		gui.textbox(0, uint16(height-2), overlay.Status, [3]float32{1, 1, 1}, [3]float32{0.2, 0.3, 0.4})
+	}
+}
@@ -0,0 +1,201 @@
+package gui
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-gl/gl/all-core/gl"
+)
+
+// post-processing shaders are fragment shaders run over the finished frame, e.g. for CRT or bloom effects.
+// they receive the frame as the 'frame' texture sampled at 'texCoord', plus 'resolution' (pixels) and 'time' (seconds)
+const postProcessVertexShaderSource = `
+	#version 150
+	in vec2 vp;
+	out vec2 texCoord;
+	void main() {
+		gl_Position = vec4(vp, 0.0, 1.0);
+		texCoord = (vp + 1.0) / 2.0;
+	}
+` + "\x00"
+
+type postProcessPass struct {
+	name    string
+	program uint32
+}
+
+// postProcessor renders the frame into a texture, then runs each pass over it in turn, the last one drawing to the window
+type postProcessor struct {
+	passes    []postProcessPass
+	fbos      [2]uint32
+	textures  [2]uint32
+	vao       uint32
+	vbo       uint32
+	width     int32
+	height    int32
+	startTime time.Time
+}
+
+// loadPostProcessShaders compiles every *.glsl/*.frag file in dir, in name order. Shaders which fail to
+// compile are skipped, and nil is returned if there is nothing usable, so rendering carries on as normal.
+func (gui *GUI) loadPostProcessShaders(dir string) *postProcessor {
+	if dir == "" {
+		return nil
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		gui.logger.Errorf("Failed to read shader directory %s: %s", dir, err)
+		return nil
+	}
+
+	names := []string{}
+	for _, file := range files {
+		ext := strings.ToLower(filepath.Ext(file.Name()))
+		if !file.IsDir() && (ext == ".glsl" || ext == ".frag") {
+			names = append(names, file.Name())
+		}
+	}
+	sort.Strings(names)
+
+	p := &postProcessor{startTime: time.Now()}
+	for _, name := range names {
+		program, err := compilePostProcessShader(filepath.Join(dir, name))
+		if err != nil {
+			gui.logger.Errorf("Ignoring shader %s: %s", name, err)
+			continue
+		}
+		gui.logger.Infof("Loaded post-processing shader %s", name)
+		p.passes = append(p.passes, postProcessPass{name: name, program: program})
+	}
+
+	if len(p.passes) == 0 {
+		return nil
+	}
+
+	points := []float32{
+		-1, -1, 1, -1, 1, 1,
+		-1, -1, 1, 1, -1, 1,
+	}
+	gl.GenVertexArrays(1, &p.vao)
+	gl.BindVertexArray(p.vao)
+	gl.GenBuffers(1, &p.vbo)
+	gl.BindBuffer(gl.ARRAY_BUFFER, p.vbo)
+	gl.BufferData(gl.ARRAY_BUFFER, 4*len(points), gl.Ptr(points), gl.STATIC_DRAW)
+	gl.EnableVertexAttribArray(0)
+	gl.VertexAttribPointer(0, 2, gl.FLOAT, false, 0, nil)
+	gl.BindVertexArray(0)
+
+	gl.GenFramebuffers(2, &p.fbos[0])
+	gl.GenTextures(2, &p.textures[0])
+
+	return p
+}
+
+func compilePostProcessShader(path string) (uint32, error) {
+	source, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	vertexShader, err := compileShader(postProcessVertexShaderSource, gl.VERTEX_SHADER)
+	if err != nil {
+		return 0, err
+	}
+	defer gl.DeleteShader(vertexShader)
+
+	fragmentShader, err := compileShader(string(source)+"\x00", gl.FRAGMENT_SHADER)
+	if err != nil {
+		return 0, err
+	}
+	defer gl.DeleteShader(fragmentShader)
+
+	prog := gl.CreateProgram()
+	gl.AttachShader(prog, vertexShader)
+	gl.AttachShader(prog, fragmentShader)
+	gl.BindAttribLocation(prog, 0, gl.Str("vp\x00"))
+	gl.LinkProgram(prog)
+
+	var status int32
+	gl.GetProgramiv(prog, gl.LINK_STATUS, &status)
+	if status == gl.FALSE {
+		var logLength int32
+		gl.GetProgramiv(prog, gl.INFO_LOG_LENGTH, &logLength)
+
+		log := strings.Repeat("\x00", int(logLength+1))
+		gl.GetProgramInfoLog(prog, logLength, nil, gl.Str(log))
+		gl.DeleteProgram(prog)
+
+		return 0, fmt.Errorf("failed to link: %v", log)
+	}
+
+	return prog, nil
+}
+
+// resize (re)allocates the offscreen frame textures, returning an error if the framebuffer can't be used
+func (p *postProcessor) resize(width int, height int) error {
+	p.width = int32(width)
+	p.height = int32(height)
+
+	for i := range p.fbos {
+		gl.BindTexture(gl.TEXTURE_2D, p.textures[i])
+		gl.TexImage2D(gl.TEXTURE_2D, 0, gl.RGBA, p.width, p.height, 0, gl.RGBA, gl.UNSIGNED_BYTE, nil)
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.LINEAR)
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
+		gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
+
+		gl.BindFramebuffer(gl.FRAMEBUFFER, p.fbos[i])
+		gl.FramebufferTexture2D(gl.FRAMEBUFFER, gl.COLOR_ATTACHMENT0, gl.TEXTURE_2D, p.textures[i], 0)
+		if status := gl.CheckFramebufferStatus(gl.FRAMEBUFFER); status != gl.FRAMEBUFFER_COMPLETE {
+			gl.BindFramebuffer(gl.FRAMEBUFFER, 0)
+			gl.BindTexture(gl.TEXTURE_2D, 0)
+			return fmt.Errorf("framebuffer incomplete: 0x%x", status)
+		}
+	}
+
+	gl.BindTexture(gl.TEXTURE_2D, 0)
+	gl.BindFramebuffer(gl.FRAMEBUFFER, p.fbos[0])
+	return nil
+}
+
+// begin directs all drawing for the next frame into the offscreen texture
+func (p *postProcessor) begin() {
+	gl.BindFramebuffer(gl.FRAMEBUFFER, p.fbos[0])
+}
+
+// apply runs the passes over the frame, leaving the result in the window's framebuffer
+func (p *postProcessor) apply() {
+	gl.Disable(gl.BLEND)
+
+	elapsed := float32(time.Since(p.startTime).Seconds())
+	source := 0
+
+	for i, pass := range p.passes {
+		if i == len(p.passes)-1 {
+			gl.BindFramebuffer(gl.FRAMEBUFFER, 0)
+		} else {
+			gl.BindFramebuffer(gl.FRAMEBUFFER, p.fbos[1-source])
+		}
+
+		gl.UseProgram(pass.program)
+		gl.ActiveTexture(gl.TEXTURE0)
+		gl.BindTexture(gl.TEXTURE_2D, p.textures[source])
+		gl.Uniform1i(gl.GetUniformLocation(pass.program, gl.Str("frame\x00")), 0)
+		gl.Uniform2f(gl.GetUniformLocation(pass.program, gl.Str("resolution\x00")), float32(p.width), float32(p.height))
+		gl.Uniform1f(gl.GetUniformLocation(pass.program, gl.Str("time\x00")), elapsed)
+
+		gl.BindVertexArray(p.vao)
+		gl.DrawArrays(gl.TRIANGLES, 0, 6)
+
+		source = 1 - source
+	}
+
+	gl.BindVertexArray(0)
+	gl.BindTexture(gl.TEXTURE_2D, 0)
+	gl.Enable(gl.BLEND)
+}
@@ -0,0 +1,70 @@
+package gui
+
+import (
+	"fmt"
+
+	"github.com/liamg/aminal/config"
+	"github.com/liamg/aminal/themes"
+)
+
+// actionProfiles shows a menu of the profiles in the config, opening a tab with the one picked
+func actionProfiles(gui *GUI) {
+	profiles := gui.config.Profiles
+	if len(profiles) == 0 {
+		gui.showToast(newNotice("Add [[profiles]] to the config to open tabs with them from here"))
+		return
+	}
+
+	items := make([]string, len(profiles))
+	for i, profile := range profiles {
+		items[i] = profile.Name
+	}
+	gui.setOverlay(newMenu("Open profile:", items, func(gui *GUI, index int) {
+		gui.openProfile(profiles[index])
+	}))
+}
+
+// openProfile opens a tab running the profile's shell, in its directory or else the focused shell's, with its
+// colours and fonts
+func (gui *GUI) openProfile(profile config.Profile) {
+	if gui.newSession == nil {
+		gui.logger.Infof("Tabs aren't available when Aminal is used as a library without a session factory")
+		return
+	}
+
+	dir := ""
+	if profile.Directory == "" {
+		dir = gui.terminal.WorkingDirectory()
+	}
+	t, err := gui.newSession(dir, &profile)
+	if err != nil {
+		gui.logger.Errorf("Failed to open the %s profile: %s", profile.Name, err)
+		gui.showToast(newNotice(fmt.Sprintf("Failed to open the %s profile: %s", profile.Name, err)))
+		return
+	}
+	// shown in the tab bar until the shell sets a title of its own
+	t.SetTitle(profile.Name)
+
+	tab := newTab(t)
+	gui.useProfile(tab, profile)
+	gui.openTab(tab)
+}
+
+// useProfile gives the tab the profile's colours, if it has a theme, and its fonts, if it changes them
+func (gui *GUI) useProfile(tab *tab, profile config.Profile) {
+	tab.profile = &profile
+	if profile.Theme != "" {
+		scheme, err := themes.Load(profile.Theme, themes.Directory(gui.config))
+		if err != nil {
+			gui.logger.Errorf("Failed to load the %s theme for the %s profile, using the usual colours: %s", profile.Theme, profile.Name, err)
+		} else {
+			tab.scheme = &scheme
+			for _, p := range tab.root.leaves() {
+				setColours(p.terminal, scheme)
+			}
+		}
+	}
+	if font := profile.FontConfig(gui.config.Font); !font.Equal(gui.config.Font) {
+		tab.font = &font
+	}
+}
@@ -0,0 +1,54 @@
+package gui
+
+import (
+	"fmt"
+
+	"github.com/go-gl/glfw/v3.3/glfw"
+)
+
+// prompt is an overlay which reads a single line of text from the user
+type prompt struct {
+	label    string
+	value    string
+	onSubmit func(gui *GUI, value string)
+}
+
+func newPrompt(label string, onSubmit func(gui *GUI, value string)) *prompt {
+	return &prompt{
+		label:    label,
+		onSubmit: onSubmit,
+	}
+}
+
+func (p *prompt) char(gui *GUI, r rune) {
+	p.value += string(r)
+	gui.terminal.SetDirty()
+}
+
+func (p *prompt) key(gui *GUI, key glfw.Key, mods glfw.ModifierKey) {
+	switch key {
+	case glfw.KeyBackspace:
+		if len(p.value) > 0 {
+			runes := []rune(p.value)
+			p.value = string(runes[:len(runes)-1])
+		}
+	case glfw.KeyEnter, glfw.KeyKPEnter:
+		gui.setOverlay(nil)
+		p.onSubmit(gui, p.value)
+	}
+	gui.terminal.SetDirty()
+}
+
+func (p *prompt) render(gui *GUI) {
+	h := gui.terminal.ActiveBuffer().ViewHeight()
+	if h < 3 {
+		return
+	}
+	gui.textbox(
+		2,
+		h-3,
+		fmt.Sprintf("%s: %s_", p.label, p.value),
+		[3]float32{1, 1, 1},
+		[3]float32{0.2, 0.2, 0.4},
+	)
+}
@@ -0,0 +1,179 @@
+package gui
+
+import (
+	"io/ioutil"
+	"reflect"
+
+	"github.com/liamg/aminal/config"
+	"github.com/liamg/aminal/terminal"
+	"github.com/liamg/aminal/themes"
+)
+
+// watchedFiles returns the config file and the font files it refers to, which are reloaded when they change
+func (gui *GUI) watchedFiles() []string {
+	files := []string{}
+	paths := append([]string{gui.config.Font.Regular, gui.config.Font.Bold, gui.config.Font.Italic, gui.config.Font.BoldItalic, gui.config.Path}, gui.config.Font.Fallback...)
+	for _, path := range paths {
+		if path != "" {
+			files = append(files, path)
+		}
+	}
+	return files
+}
+
+// applyConfigFile picks up changes made to the colours, fonts, key bindings and opacity in the config file,
+// returning true if the fonts need reloading. Must be called on the OS thread.
+func (gui *GUI) applyConfigFile() bool {
+	if gui.config.Path == "" {
+		return false
+	}
+	data, err := ioutil.ReadFile(gui.config.Path)
+	if err != nil {
+		return false
+	}
+	conf, err := config.Parse(data)
+	if err != nil {
+		gui.logger.Errorf("Ignoring invalid config file %s: %s", gui.config.Path, err)
+		return false
+	}
+	conf.Path = gui.config.Path
+	if err := themes.Apply(conf); err != nil {
+		gui.logger.Errorf("Failed to load theme: %s", err)
+	}
+	gui.config.Theme = conf.Theme
+	gui.config.ThemesDirectory = conf.ThemesDirectory
+	gui.config.Macros = conf.Macros
+
+	gui.applyColourScheme(conf.ColourScheme)
+	gui.applyBoldIsBright(conf.BoldIsBright)
+	gui.applyKeyMapping(conf.KeyMapping)
+	gui.applyBindings(conf.Bindings)
+	gui.applyOpacity(conf.Opacity)
+	gui.applyBackground(conf.BackgroundOpacity, conf.Blur)
+
+	gui.config.Font.RememberSize = conf.Font.RememberSize
+	if conf.Font.Equal(gui.config.Font) {
+		return false
+	}
+	gui.config.Font = conf.Font
+	return true
+}
+
+// setTheme switches to the named theme's colours for the rest of the session
+func (gui *GUI) setTheme(name string) error {
+	scheme, err := themes.Load(name, themes.Directory(gui.config))
+	if err != nil {
+		return err
+	}
+	gui.config.Theme = name
+	gui.applyColourScheme(scheme)
+	return nil
+}
+
+// applyColourScheme recolours every terminal, including the text already in them
+func (gui *GUI) applyColourScheme(scheme config.ColourScheme) {
+	if scheme == gui.config.ColourScheme {
+		return
+	}
+	gui.logger.Infof("Applying new colour scheme...")
+	gui.config.ColourScheme = scheme
+	for _, tab := range gui.tabs {
+		if tab.scheme != nil {
+			continue
+		}
+		for _, p := range tab.root.leaves() {
+			setColours(p.terminal, gui.paneScheme(tab, p))
+		}
+	}
+	gui.generateDefaultCell(gui.terminal.ScreenMode())
+}
+
+func setColours(t *terminal.Terminal, scheme config.ColourScheme) {
+	t.SetColours(scheme.Foreground, scheme.Background, scheme.Cursor, terminal.Palette(scheme.ANSIPalette()))
+}
+
+func (gui *GUI) applyBoldIsBright(enabled bool) {
+	if enabled == gui.config.BoldIsBright {
+		return
+	}
+	gui.config.BoldIsBright = enabled
+	for _, tab := range gui.tabs {
+		for _, p := range tab.root.leaves() {
+			p.terminal.SetBoldIsBright(enabled)
+		}
+	}
+}
+
+func (gui *GUI) applyKeyMapping(mapping config.KeyMappingConfig) {
+	if reflect.DeepEqual(mapping, gui.config.KeyMapping) {
+		return
+	}
+	shortcuts, err := mapping.GenerateActionMap()
+	if err != nil {
+		gui.logger.Errorf("Ignoring invalid key bindings in %s: %s", gui.config.Path, err)
+		return
+	}
+	gui.logger.Infof("Applying new key bindings...")
+	gui.config.KeyMapping = mapping
+	gui.keyboardShortcuts = shortcuts
+}
+
+func (gui *GUI) applyBindings(bindings []config.BindingConfig) {
+	if reflect.DeepEqual(bindings, gui.config.Bindings) {
+		return
+	}
+	keyBindings, err := newKeyBindings(bindings)
+	if err != nil {
+		gui.logger.Errorf("Ignoring invalid key bindings in %s: %s", gui.config.Path, err)
+		return
+	}
+	gui.logger.Infof("Applying new key bindings...")
+	gui.config.Bindings = bindings
+	gui.keyBindings = keyBindings
+}
+
+func (gui *GUI) applyOpacity(opacity float32) {
+	if opacity == gui.config.Opacity {
+		return
+	}
+	gui.config.Opacity = opacity
+	gui.setWindowOpacity()
+}
+
+// setWindowOpacity makes the window as opaque as the config says, where the window system supports it
+func (gui *GUI) setWindowOpacity() {
+	// don't let a typo make the window disappear entirely
+	gui.window.SetOpacity(clampOpacity(gui.config.Opacity, 0.1))
+}
+
+func (gui *GUI) applyBackground(opacity float32, blur bool) {
+	if opacity != gui.config.BackgroundOpacity {
+		gui.config.BackgroundOpacity = opacity
+		gui.setBackgroundOpacity(opacity)
+	}
+	if blur != gui.config.Blur {
+		gui.config.Blur = blur
+		setBlur(gui.window, blur)
+	}
+}
+
+// backgroundOpacityStep is how much background_more_opaque and background_less_opaque change the opacity by
+const backgroundOpacityStep = 0.1
+
+// setBackgroundOpacity changes how much shows through the default background, for the rest of the session
+func (gui *GUI) setBackgroundOpacity(opacity float32) {
+	gui.terminalAlpha = clampOpacity(opacity, 0)
+	gui.generateDefaultCell(gui.terminal.ScreenMode())
+	gui.invalidateFrame()
+	gui.terminal.SetDirty()
+}
+
+// clampOpacity keeps an opacity between min and 1
+func clampOpacity(opacity float32, min float32) float32 {
+	if opacity < min {
+		return min
+	} else if opacity > 1 {
+		return 1
+	}
+	return opacity
+}
@@ -13,30 +13,32 @@ import (
 type OpenGLRenderer struct {
 	areaWidth        int
 	areaHeight       int
-	areaX            int
-	areaY            int
+	areaX            int // the padding between the grid and the left edge of the area
+	areaY            int // the padding between the grid and the top edge of the area
 	cellWidth        float32
 	cellHeight       float32
+	letterSpacing    float32 // added to the width of the font's characters to make the width of a cell
+	lineHeight       float32 // multiplies the height of the font's lines to make the height of a cell
+	textLift         float32 // how far text is raised from the bottom of its cell, to centre it in taller cells
 	termCols         uint
 	termRows         uint
+	viewCol          uint // cell offset applied to everything drawn, so each pane can draw from 0,0
+	viewRow          uint
 	cellPositions    map[[2]uint][2]float32
 	config           *config.Config
 	colourAttr       uint32
 	program          uint32
+	vao              uint32
+	vbo              uint32
+	rects            []float32      // rectangles queued to be drawn by Flush
+	textFonts        []*glfont.Font // fonts with text queued to be drawn by Flush
 	textureMap       map[*image.RGBA]uint32
 	fontMap          *FontMap
 	backgroundColour [3]float32
 }
 
-type rectangle struct {
-	vao        uint32
-	vbo        uint32
-	cv         uint32
-	colourAttr uint32
-	colour     [3]float32
-	points     [18]float32
-	prog       uint32
-}
+// the number of floats making up each vertex of a queued rectangle: a position and a colour
+const rectVertexSize = 5
 
 func (r *OpenGLRenderer) CellWidth() float32 {
 	return r.cellWidth
@@ -46,91 +48,67 @@ func (r *OpenGLRenderer) CellHeight() float32 {
 	return r.cellHeight
 }
 
-func (r *OpenGLRenderer) newRectangleEx(x float32, y float32, width float32, height float32, colourAttr uint32) *rectangle {
-	rect := &rectangle{}
+// queueRect adds a rectangle, whose bottom edge is at y, to be drawn the next time the renderer is flushed.
+// Text is drawn after rectangles, so any text queued already is flushed first to keep it underneath.
+func (r *OpenGLRenderer) queueRect(x float32, y float32, width float32, height float32, colour [3]float32) {
+	if len(r.textFonts) > 0 {
+		r.Flush()
+	}
 
 	halfAreaWidth := float32(r.areaWidth / 2)
 	halfAreaHeight := float32(r.areaHeight / 2)
 
-	x = (x - halfAreaWidth) / halfAreaWidth
-	y = -(y - (halfAreaHeight)) / halfAreaHeight
+	x = (x + float32(r.areaX) - halfAreaWidth) / halfAreaWidth
+	y = -(y + float32(r.areaY) - halfAreaHeight) / halfAreaHeight
 	w := width / halfAreaWidth
 	h := height / halfAreaHeight
 
-	rect.points = [18]float32{
-		x, y, 0,
-		x, y + h, 0,
-		x + w, y + h, 0,
-
-		x + w, y, 0,
-		x, y, 0,
-		x + w, y + h, 0,
-	}
-
-	rect.colourAttr = colourAttr
-	rect.prog = r.program
-
-	// SHAPE
-	gl.GenBuffers(1, &rect.vbo)
-	gl.BindBuffer(gl.ARRAY_BUFFER, rect.vbo)
-	gl.BufferData(gl.ARRAY_BUFFER, 4*len(rect.points), gl.Ptr(&rect.points[0]), gl.STATIC_DRAW)
-
-	gl.GenVertexArrays(1, &rect.vao)
-	gl.BindVertexArray(rect.vao)
-	gl.EnableVertexAttribArray(0)
-
-	gl.BindBuffer(gl.ARRAY_BUFFER, rect.vbo)
-	gl.VertexAttribPointer(0, 3, gl.FLOAT, false, 0, nil)
+	red, green, blue := colour[0], colour[1], colour[2]
+	r.rects = append(r.rects,
+		x, y, red, green, blue,
+		x, y+h, red, green, blue,
+		x+w, y+h, red, green, blue,
 
-	// colour
-	gl.GenBuffers(1, &rect.cv)
-
-	rect.setColour([3]float32{0, 1, 0})
-
-	return rect
-}
-
-func (r *OpenGLRenderer) newRectangle(x float32, y float32, colourAttr uint32) *rectangle {
-	return r.newRectangleEx(x, y, r.cellWidth, r.cellHeight, colourAttr)
+		x+w, y, red, green, blue,
+		x, y, red, green, blue,
+		x+w, y+h, red, green, blue,
+	)
 }
 
-func (rect *rectangle) Draw() {
-	gl.UseProgram(rect.prog)
-	gl.BindVertexArray(rect.vao)
-	gl.DrawArrays(gl.TRIANGLES, 0, 6)
-}
-
-func (rect *rectangle) setColour(colour [3]float32) {
-	if rect.colour == colour {
-		return
+// Flush draws everything queued since the renderer was last flushed, rectangles first and then text. It must be
+// called before anything is drawn to the framebuffer other than through the renderer, and before it's shown.
+func (r *OpenGLRenderer) Flush() {
+	if len(r.rects) > 0 {
+		gl.UseProgram(r.program)
+		gl.BindVertexArray(r.vao)
+		gl.BindBuffer(gl.ARRAY_BUFFER, r.vbo)
+		gl.BufferData(gl.ARRAY_BUFFER, len(r.rects)*4, gl.Ptr(r.rects), gl.STREAM_DRAW)
+		gl.DrawArrays(gl.TRIANGLES, 0, int32(len(r.rects)/rectVertexSize))
+		gl.BindBuffer(gl.ARRAY_BUFFER, 0)
+		gl.BindVertexArray(0)
+		r.rects = r.rects[:0]
 	}
-
-	c := []float32{
-		colour[0], colour[1], colour[2],
-		colour[0], colour[1], colour[2],
-		colour[0], colour[1], colour[2],
-		colour[0], colour[1], colour[2],
-		colour[0], colour[1], colour[2],
-		colour[0], colour[1], colour[2],
+	for _, f := range r.textFonts {
+		f.Flush()
 	}
-
-	gl.UseProgram(rect.prog)
-	gl.BindBuffer(gl.ARRAY_BUFFER, rect.cv)
-	gl.BufferData(gl.ARRAY_BUFFER, len(c)*4, gl.Ptr(c), gl.STATIC_DRAW)
-	gl.EnableVertexAttribArray(rect.colourAttr)
-	gl.VertexAttribPointer(rect.colourAttr, 3, gl.FLOAT, false, 0, gl.PtrOffset(0))
-
-	rect.colour = colour
+	r.textFonts = r.textFonts[:0]
 }
 
-func (rect *rectangle) Free() {
-	gl.DeleteVertexArrays(1, &rect.vao)
-	gl.DeleteBuffers(1, &rect.vbo)
-	gl.DeleteBuffers(1, &rect.cv)
+// Invert inverts the colours of everything drawn so far, over the whole area
+func (r *OpenGLRenderer) Invert() {
+	r.Flush()
+	r.queueRect(-float32(r.areaX), float32(r.areaHeight-r.areaY), float32(r.areaWidth), float32(r.areaHeight), [3]float32{1, 1, 1})
+	gl.Enable(gl.BLEND)
+	// white becomes one minus what's there, and the alpha is left alone
+	gl.BlendFuncSeparate(gl.ONE_MINUS_DST_COLOR, gl.ZERO, gl.ZERO, gl.ONE)
+	r.Flush()
+	gl.Disable(gl.BLEND)
+}
 
-	rect.vao = 0
-	rect.vbo = 0
-	rect.cv = 0
+// Clear clears the framebuffer, after drawing anything still queued so it isn't drawn over what comes next
+func (r *OpenGLRenderer) Clear() {
+	r.Flush()
+	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT | gl.STENCIL_BUFFER_BIT)
 }
 
 func NewOpenGLRenderer(config *config.Config, fontMap *FontMap, areaX int, areaY int, areaWidth int, areaHeight int, colourAttr uint32, program uint32) *OpenGLRenderer {
@@ -147,6 +125,20 @@ func NewOpenGLRenderer(config *config.Config, fontMap *FontMap, areaX int, areaY
 		fontMap:       fontMap,
 	}
 	r.SetArea(areaX, areaY, areaWidth, areaHeight)
+
+	// everything queued is uploaded to the one buffer, and drawn in one call
+	positionAttr := uint32(gl.GetAttribLocation(program, gl.Str("vp\x00")))
+	gl.GenVertexArrays(1, &r.vao)
+	gl.GenBuffers(1, &r.vbo)
+	gl.BindVertexArray(r.vao)
+	gl.BindBuffer(gl.ARRAY_BUFFER, r.vbo)
+	gl.EnableVertexAttribArray(positionAttr)
+	gl.VertexAttribPointer(positionAttr, 2, gl.FLOAT, false, rectVertexSize*4, gl.PtrOffset(0))
+	gl.EnableVertexAttribArray(colourAttr)
+	gl.VertexAttribPointer(colourAttr, 3, gl.FLOAT, false, rectVertexSize*4, gl.PtrOffset(2*4))
+	gl.BindBuffer(gl.ARRAY_BUFFER, 0)
+	gl.BindVertexArray(0)
+
 	return r
 }
 
@@ -159,6 +151,11 @@ func (r *OpenGLRenderer) Free() {
 
 	r.fontMap.Free()
 
+	gl.DeleteVertexArrays(1, &r.vao)
+	gl.DeleteBuffers(1, &r.vbo)
+	r.vao = 0
+	r.vbo = 0
+
 	gl.DeleteProgram(r.program)
 	r.program = 0
 }
@@ -167,39 +164,77 @@ func (r *OpenGLRenderer) GetTermSize() (uint, uint) {
 	return r.termCols, r.termRows
 }
 
+// SetSpacing changes the space between characters and lines, which is applied the next time the area is set
+func (r *OpenGLRenderer) SetSpacing(letterSpacing float32, lineHeight float32) {
+	r.letterSpacing = letterSpacing
+	r.lineHeight = lineHeight
+}
+
+// SetArea sets the size of the area to draw in, and the padding between its edges and the grid, which is the
+// same on the right and bottom as on the left and top. The cell metrics are recalculated from the default font.
 func (r *OpenGLRenderer) SetArea(areaX int, areaY int, areaWidth int, areaHeight int) {
 	r.areaWidth = areaWidth
 	r.areaHeight = areaHeight
 	r.areaX = areaX
 	r.areaY = areaY
 	f := r.fontMap.DefaultFont()
-	_, r.cellHeight = f.MaxSize()
-	r.cellWidth, _ = f.Size("X")
-	//= f.LineHeight()   // includes vertical padding
-	r.termCols = uint(math.Floor(float64(float32(r.areaWidth) / r.cellWidth)))
-	r.termRows = uint(math.Floor(float64(float32(r.areaHeight) / r.cellHeight)))
+	_, fontHeight := f.MaxSize()
+	fontWidth, _ := f.Size("X")
+	r.cellWidth = fontWidth + r.letterSpacing
+	if r.cellWidth < 1 {
+		r.cellWidth = 1
+	}
+	r.cellHeight = fontHeight
+	if r.lineHeight > 0 {
+		r.cellHeight = float32(math.Ceil(float64(fontHeight * r.lineHeight)))
+	}
+	if r.cellHeight < 1 {
+		r.cellHeight = 1
+	}
+	r.textLift = (r.cellHeight - fontHeight) / 2
+	r.termCols = uint(math.Floor(float64(float32(r.areaWidth-2*r.areaX) / r.cellWidth)))
+	r.termRows = uint(math.Floor(float64(float32(r.areaHeight-2*r.areaY) / r.cellHeight)))
+}
+
+// SetViewport moves the origin of the cell grid to (col, row), until it's set back to (0, 0)
+func (r *OpenGLRenderer) SetViewport(col uint, row uint) {
+	r.viewCol = col
+	r.viewRow = row
 }
 
+// GetRectangleSize returns the size of the area needed to fit a grid of the given size, padding included
 func (r *OpenGLRenderer) GetRectangleSize(col uint, row uint) (float32, float32) {
-	x := float32(float32(col) * r.cellWidth)
-	y := float32(float32(row) * r.cellHeight)
+	x := float32(float32(col)*r.cellWidth) + float32(2*r.areaX)
+	y := float32(float32(row)*r.cellHeight) + float32(2*r.areaY)
 
 	return x, y
 }
 
-func (r *OpenGLRenderer) getRectangle(col uint, row uint) *rectangle {
+// queueCell adds a rectangle filling the cell at (col, row)
+func (r *OpenGLRenderer) queueCell(col uint, row uint, colour [3]float32) {
+	col += r.viewCol
+	row += r.viewRow
 	x := float32(float32(col) * r.cellWidth)
 	y := float32(float32(row)*r.cellHeight) + r.cellHeight
 
-	return r.newRectangle(x, y, r.colourAttr)
+	r.queueRect(x, y, r.cellWidth, r.cellHeight, colour)
 }
 
 func (r *OpenGLRenderer) DrawCursor(col uint, row uint, colour config.Colour) {
-	rect := r.getRectangle(col, row)
-	rect.setColour(colour)
-	rect.Draw()
+	r.queueCell(col, row, colour)
+}
 
-	rect.Free()
+// DrawCursorOutline draws a hollow cursor around the cell, as shown when the window doesn't have focus
+func (r *OpenGLRenderer) DrawCursorOutline(col uint, row uint, colour config.Colour) {
+	thickness := r.lineThickness()
+	x := float32(col+r.viewCol) * r.cellWidth
+	top := float32(row+r.viewRow) * r.cellHeight
+	bottom := top + r.cellHeight
+	r.drawLine(x, top+thickness, r.cellWidth, thickness, colour)
+	r.drawLine(x, bottom, r.cellWidth, thickness, colour)
+	// the sides are lines one thickness wide and the height of the cell
+	r.drawLine(x, bottom, thickness, r.cellHeight, colour)
+	r.drawLine(x+r.cellWidth-thickness, bottom, thickness, r.cellHeight, colour)
 }
 
 func (r *OpenGLRenderer) DrawCellBg(cell buffer.Cell, col uint, row uint, colour *config.Colour, force bool) {
@@ -212,46 +247,166 @@ func (r *OpenGLRenderer) DrawCellBg(cell buffer.Cell, col uint, row uint, colour
 	}
 
 	if bg != r.backgroundColour || force {
-		rect := r.getRectangle(col, row)
-		rect.setColour(bg)
-		rect.Draw()
-
-		rect.Free()
+		r.queueCell(col, row, bg)
 	}
 }
 
-// DrawUnderline draws a line under 'span' characters starting at (col, row)
-func (r *OpenGLRenderer) DrawUnderline(span int, col uint, row uint, colour [3]float32) {
+// DrawUnderline draws a line in the given style under 'span' characters starting at (col, row)
+func (r *OpenGLRenderer) DrawUnderline(span int, col uint, row uint, colour [3]float32, style buffer.UnderlineStyle) {
 	// calculate coordinates
+	col += r.viewCol
+	row += r.viewRow
 	x := float32(float32(col) * r.cellWidth)
-	y := (float32(row+1))*r.cellHeight + r.fontMap.DefaultFont().MinY()*0.25
+	y := (float32(row+1))*r.cellHeight - r.textLift + r.fontMap.DefaultFont().MinY()*0.25
+	width := r.cellWidth * float32(span)
+	thickness := r.lineThickness()
+
+	line := func(x float32, y float32, width float32) {
+		r.drawLine(x, y, width, thickness, colour)
+	}
+
+	switch style {
+	case buffer.UnderlineDouble:
+		line(x, y-thickness, width)
+		line(x, y+thickness, width)
+	case buffer.UnderlineDotted:
+		for dx := float32(0); dx < width; dx += thickness * 2 {
+			line(x+dx, y, thickness)
+		}
+	case buffer.UnderlineDashed:
+		// a dash in the middle of each cell, so the gaps line up between the characters
+		dash := r.cellWidth / 2
+		for dx := float32(0); dx < width; dx += r.cellWidth {
+			line(x+dx+dash/2, y, dash)
+		}
+	case buffer.UnderlineCurly:
+		// a wave with one period per cell, drawn as short steps
+		amplitude := thickness * 1.5
+		for dx := float32(0); dx < width; dx += thickness {
+			phase := 2 * math.Pi * float64(dx) / float64(r.cellWidth)
+			line(x+dx, y+amplitude*float32(math.Sin(phase)), thickness)
+		}
+	default:
+		line(x, y, width)
+	}
+}
 
+// DrawStrikethrough draws a line through the middle of 'span' characters starting at (col, row)
+func (r *OpenGLRenderer) DrawStrikethrough(span int, col uint, row uint, colour [3]float32) {
+	thickness := r.lineThickness()
+	x := float32(col+r.viewCol) * r.cellWidth
+	y := float32(row+r.viewRow)*r.cellHeight + (r.cellHeight+thickness)/2
+	r.drawLine(x, y, r.cellWidth*float32(span), thickness, colour)
+}
+
+// DrawOverline draws a line along the top of 'span' characters starting at (col, row)
+func (r *OpenGLRenderer) DrawOverline(span int, col uint, row uint, colour [3]float32) {
+	thickness := r.lineThickness()
+	x := float32(col+r.viewCol) * r.cellWidth
+	y := float32(row+r.viewRow)*r.cellHeight + thickness
+	r.drawLine(x, y, r.cellWidth*float32(span), thickness, colour)
+}
+
+// lineThickness is how thick the lines drawn under, through and over text are
+func (r *OpenGLRenderer) lineThickness() float32 {
 	thickness := r.cellHeight / 16
 	if thickness < 1 {
 		thickness = 1
 	}
-	rect := r.newRectangleEx(x, y, r.cellWidth*float32(span), thickness, r.colourAttr)
+	return thickness
+}
 
-	rect.setColour(colour)
-	rect.Draw()
+// drawLine draws a horizontal line whose bottom edge is at y
+func (r *OpenGLRenderer) drawLine(x float32, y float32, width float32, thickness float32, colour [3]float32) {
+	r.queueRect(x, y, width, thickness, colour)
+}
 
-	rect.Free()
+// DrawWrapIndicator draws a thin bar in the left edge of a row, marking it as a continuation of the row above
+func (r *OpenGLRenderer) DrawWrapIndicator(row uint, colour [3]float32) {
+	thickness := r.cellWidth / 8
+	if thickness < 1 {
+		thickness = 1
+	}
+	x := float32(r.viewCol) * r.cellWidth
+	r.queueRect(x, float32(row+r.viewRow+1)*r.cellHeight, thickness, r.cellHeight, colour)
 }
 
-func (r *OpenGLRenderer) DrawCellText(text string, col uint, row uint, alpha float32, colour [3]float32, bold bool) {
-	var f *glfont.Font
-	if bold {
-		f = r.fontMap.BoldFont()
-	} else {
-		f = r.fontMap.DefaultFont()
+// ScrollbarWidth is how wide the scrollbar at the right edge of a pane is, in pixels
+func (r *OpenGLRenderer) ScrollbarWidth() float32 {
+	width := r.cellWidth / 3
+	if width < 3 {
+		width = 3
+	}
+	return width
+}
+
+// DrawScrollbarSpan fills the scrollbar of a pane of the given size at the viewport, between two fractions of
+// its height. Spans too short to see are drawn a line thick, so single lines can be marked.
+func (r *OpenGLRenderer) DrawScrollbarSpan(cols uint, rows uint, from float32, to float32, colour [3]float32) {
+	width := r.ScrollbarWidth()
+	x := float32(r.viewCol+cols)*r.cellWidth - width
+	top := float32(r.viewRow) * r.cellHeight
+	trackHeight := float32(rows) * r.cellHeight
+	height := (to - from) * trackHeight
+	if thickness := r.lineThickness(); height < thickness {
+		height = thickness
+	}
+	bottom := top + from*trackHeight + height
+	if bottom > top+trackHeight {
+		bottom = top + trackHeight
 	}
+	r.queueRect(x, bottom, width, height, colour)
+}
 
-	f.SetColor(colour[0], colour[1], colour[2], alpha)
+func (r *OpenGLRenderer) DrawCellText(text string, col uint, row uint, alpha float32, colour [3]float32, bold bool, italic bool) {
+	f := r.fontMap.styleFont(bold, italic)
+
+	col += r.viewCol
+	row += r.viewRow
+	y := float32(r.areaY) + (float32(row+1) * r.cellHeight) + f.MinY() - r.textLift
+
+	// characters missing from the font come from the fallback fonts, whose glyphs are a different
+	// width, so each run starts at its own cell to keep the text on the grid
+	for _, run := range r.fontMap.runs(text, bold, italic) {
+		run.font.SetColor(colour[0], colour[1], colour[2], alpha)
+		r.queueFont(run.font)
+		if r.letterSpacing == 0 {
+			run.font.Queue(float32(r.areaX)+float32(col)*r.cellWidth, y, run.text)
+			col += run.cells
+			continue
+		}
+		// spaced out text no longer lines up with the font's own advances, so each character is queued
+		// in the middle of its cell, along with any zero width characters attached to it
+		for _, piece := range cellPieces(run.text) {
+			x := float32(r.areaX) + float32(col)*r.cellWidth + r.letterSpacing/2
+			run.font.Queue(x, y, piece.text)
+			col += piece.cells
+		}
+	}
+}
 
-	x := float32(r.areaX) + float32(col)*r.cellWidth
-	y := float32(r.areaY) + (float32(row+1) * r.cellHeight) + f.MinY()
+// cellPieces splits text into the characters which start a cell, each with the zero width characters after it
+func cellPieces(text string) []fontRun {
+	pieces := []fontRun{}
+	for _, r := range text {
+		width := buffer.RuneWidth(r)
+		if width == 0 && len(pieces) > 0 {
+			pieces[len(pieces)-1].text += string(r)
+			continue
+		}
+		pieces = append(pieces, fontRun{text: string(r), cells: uint(width)})
+	}
+	return pieces
+}
 
-	f.Print(x, y, text)
+// queueFont notes that f has text queued, to be flushed after the rectangles
+func (r *OpenGLRenderer) queueFont(f *glfont.Font) {
+	for _, queued := range r.textFonts {
+		if queued == f {
+			return
+		}
+	}
+	r.textFonts = append(r.textFonts, f)
 }
 
 func (r *OpenGLRenderer) DrawCellImage(cell buffer.Cell, col uint, row uint) {
@@ -261,8 +416,11 @@ func (r *OpenGLRenderer) DrawCellImage(cell buffer.Cell, col uint, row uint) {
 		return
 	}
 
-	ix := float32(col) * r.cellWidth
-	iy := float32(r.areaHeight) - (float32(row+1) * r.cellHeight)
+	// the image is copied straight into the framebuffer, so whatever should be beneath it goes first
+	r.Flush()
+
+	ix := float32(r.areaX) + float32(col+r.viewCol)*r.cellWidth
+	iy := float32(r.areaHeight-r.areaY) - (float32(row+r.viewRow+1) * r.cellHeight)
 	iy -= float32(cell.Image().Bounds().Size().Y)
 	gl.UseProgram(r.program)
 
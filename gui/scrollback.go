@@ -0,0 +1,130 @@
+package gui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const scrollbackCheckInterval = time.Second * 5
+
+// checkScrollbackMemory offers to trim the scrollback when it grows past the configured size, or to save the
+// lines trimmed to disk first. Once warned, the user isn't asked again until it has doubled.
+func (gui *GUI) checkScrollbackMemory() {
+	if err := gui.terminal.SpillError(); err != nil && !gui.spillErrorShown {
+		gui.spillErrorShown = true
+		gui.logger.Errorf("Stopped writing scrollback to disk: %s", err)
+		gui.showToast(newNotice(fmt.Sprintf("Old scrollback is being discarded, writing it to disk failed: %s", err)))
+	}
+
+	if gui.config.ScrollbackWarning == 0 {
+		return
+	}
+
+	if gui.scrollbackWarnAt == 0 {
+		gui.scrollbackWarnAt = gui.config.ScrollbackWarning * 1024 * 1024
+	}
+
+	usage := gui.terminal.ScrollbackMemory()
+	if usage < gui.scrollbackWarnAt {
+		return
+	}
+	gui.scrollbackWarnAt = usage * 2
+
+	gui.logger.Warnf("Scrollback is using %s of memory", formatBytes(usage))
+
+	actions := []toastAction{{
+		label: "Trim",
+		run: func(gui *GUI) {
+			gui.trimScrollback(usage)
+		},
+	}, {
+		label: "Save to disk",
+		run: func(gui *GUI) {
+			gui.saveScrollback(usage)
+		},
+	}}
+	if gui.terminal.SpillsScrollback() {
+		// trimmed lines are spilled rather than lost, so there's no need to save them separately
+		actions = actions[:1]
+		actions[0].label = "Move to disk"
+	}
+	actions = append(actions, dismissAction)
+
+	gui.showToast(newToast(fmt.Sprintf("Scrollback is using %s of memory.", formatBytes(usage)), actions...))
+}
+
+// scrollbackToKeep returns the number of lines of scrollback which use roughly half of the warning threshold
+func (gui *GUI) scrollbackToKeep(usage uint64) int {
+	limit := gui.config.ScrollbackWarning * 1024 * 1024
+	// the main buffer's lines, as it's the one trimmed, even while e.g. vim has the alternate screen up
+	lines := gui.terminal.ScrollbackLines()
+	return int(uint64(lines) * (limit / 2) / usage)
+}
+
+// saveScrollback writes the lines trimming would discard to a file in the home directory, then trims them
+func (gui *GUI) saveScrollback(usage uint64) {
+	path, err := gui.writeTrimmedScrollback(gui.scrollbackToKeep(usage))
+	if err != nil {
+		gui.logger.Errorf("Failed to save scrollback: %s", err)
+		gui.showToast(newNotice(fmt.Sprintf("Failed to save scrollback: %s", err)))
+		return
+	}
+	gui.trimScrollback(usage)
+	gui.showToast(newNotice(fmt.Sprintf("Saved the old scrollback to %s", path)))
+}
+
+func (gui *GUI) writeTrimmedScrollback(keep int) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(home, fmt.Sprintf("aminal-scrollback-%s.txt", time.Now().Format("2006-01-02-150405")))
+	f, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	if err := gui.terminal.WriteTrimmedScrollback(f, keep); err != nil {
+		f.Close()
+		return "", err
+	}
+	return path, f.Close()
+}
+
+func actionClearScrollback(gui *GUI) {
+	gui.terminal.ClearScrollback()
+	gui.scrollbackWarnAt = 0
+}
+
+func actionClearAll(gui *GUI) {
+	gui.terminal.ClearAll()
+	gui.scrollbackWarnAt = 0
+}
+
+// actionReset resets the terminal as if the application had sent RIS, for when it has left it in a mess
+func actionReset(gui *GUI) {
+	gui.terminal.Reset()
+}
+
+// trimScrollback cuts the scrollback down to roughly half of the warning threshold
+func (gui *GUI) trimScrollback(usage uint64) {
+	limit := gui.config.ScrollbackWarning * 1024 * 1024
+	keep := gui.scrollbackToKeep(usage)
+	gui.terminal.TrimScrollback(keep)
+	gui.scrollbackWarnAt = limit
+	gui.logger.Infof("Trimmed scrollback to %d lines, now using %s", keep, formatBytes(gui.terminal.ScrollbackMemory()))
+}
+
+func formatBytes(size uint64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%dB", size)
+	}
+	div, exp := uint64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%cB", float64(size)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,190 @@
+package gui
+
+import (
+	"math"
+	"time"
+
+	"github.com/go-gl/glfw/v3.3/glfw"
+	"github.com/liamg/aminal/buffer"
+)
+
+const (
+	// how long the scrollbar stays after the view last scrolled, before it fades out
+	scrollbarShowDuration = 1500 * time.Millisecond
+	scrollbarFadeDuration = 300 * time.Millisecond
+)
+
+// scrollbar is the state of the scrollbar drawn over the focused pane
+type scrollbar struct {
+	shownAt   time.Time // the view last scrolled, or the mouse last left the scrollbar
+	offset    uint      // scroll offset when last checked, to notice scrolling
+	hovered   bool
+	dragging  bool
+	grab      float32 // where the thumb was grabbed, as a fraction of the track below its top
+	drawnShow float32 // how much of the scrollbar was shown when it was last drawn
+}
+
+// scrollbarThumb returns where the thumb sits in the track, and how much of it it fills, as fractions of the
+// track's height. It returns false if there's nothing to scroll.
+func scrollbarThumb(b *buffer.Buffer, rows uint) (float32, float32, bool) {
+	total := b.Height()
+	view := int(b.ViewHeight())
+	if total <= view || rows == 0 {
+		return 0, 0, false
+	}
+	size := float32(view) / float32(total)
+	if min := 1 / float32(rows); size < min {
+		// keep the thumb big enough to grab in long scrollback
+		size = min
+	}
+	position := float32(b.TopVisibleLine()) / float32(total-view)
+	return position * (1 - size), size, true
+}
+
+// scrollbarVisibility returns how much of the scrollbar to show right now, from 0 when it has faded out to 1
+func (gui *GUI) scrollbarVisibility() float32 {
+	if !gui.config.Scrollbar || !gui.terminal.UsingMainBuffer() {
+		return 0
+	}
+	if _, _, ok := scrollbarThumb(gui.terminal.ActiveBuffer(), gui.currentTab().focus.rows); !ok {
+		return 0
+	}
+	if gui.scrollbar.hovered || gui.scrollbar.dragging || gui.findBar() != nil {
+		return 1
+	}
+	since := time.Since(gui.scrollbar.shownAt)
+	switch {
+	case since < scrollbarShowDuration:
+		return 1
+	case since < scrollbarShowDuration+scrollbarFadeDuration:
+		return 1 - float32(since-scrollbarShowDuration)/float32(scrollbarFadeDuration)
+	default:
+		return 0
+	}
+}
+
+// scrollbarNeedsRedraw shows the scrollbar if the view has scrolled since it was last checked, and returns true
+// if it has faded in or out since it was last drawn. Like the cursor, it relies on the render loop waking up often.
+func (gui *GUI) scrollbarNeedsRedraw() bool {
+	if offset := gui.terminal.GetScrollOffset(); offset != gui.scrollbar.offset {
+		gui.scrollbar.offset = offset
+		gui.scrollbar.shownAt = time.Now()
+	}
+	return gui.scrollbarVisibility() != gui.scrollbar.drawnShow
+}
+
+// renderScrollbar draws the scrollbar over the right edge of p, which must be the focused pane, at the viewport
+func (gui *GUI) renderScrollbar(p *pane) {
+	visibility := gui.scrollbarVisibility()
+	gui.scrollbar.drawnShow = visibility
+	if visibility == 0 {
+		return
+	}
+	b := p.terminal.ActiveBuffer()
+	top, size, _ := scrollbarThumb(b, p.rows)
+	scheme := gui.config.ColourScheme
+	bg := scheme.Background
+
+	gui.renderer.DrawScrollbarSpan(p.cols, p.rows, 0, 1, blend(bg, scheme.Foreground, 0.1*visibility))
+	gui.renderer.DrawScrollbarSpan(p.cols, p.rows, top, top+size, blend(bg, scheme.Foreground, 0.45*visibility))
+
+	// marks for lines further than a pixel apart, so a lot of matches don't mean a lot of drawing
+	total := float32(b.Height())
+	trackHeight := float32(p.rows) * gui.renderer.CellHeight()
+	mark := func(lines []int, colour [3]float32) {
+		last := -1
+		for _, line := range lines {
+			pixel := int(float32(line) / total * trackHeight)
+			if pixel == last {
+				continue
+			}
+			last = pixel
+			gui.renderer.DrawScrollbarSpan(p.cols, p.rows, float32(line)/total, float32(line)/total, colour)
+		}
+	}
+	// prompts are marked in the cursor colour, or by how their command went if the shell gave its exit status
+	succeeded, failed := b.FinishedPrompts()
+	mark(b.Prompts(), blend(bg, scheme.Cursor, visibility))
+	mark(succeeded, blend(bg, scheme.Green, visibility))
+	mark(failed, blend(bg, scheme.Red, visibility))
+	if f := gui.findBar(); f != nil {
+		lines := make([]int, len(f.matches))
+		for i, match := range f.matches {
+			lines[i] = match.Start.Line
+		}
+		mark(lines, blend(bg, scheme.Match, visibility))
+	}
+}
+
+// scrollbarAt returns how far down the focused pane's scrollbar a point in the window is, as a fraction of its
+// height, or false if the point isn't over the scrollbar or it isn't shown
+func (gui *GUI) scrollbarAt(px float64, py float64) (float32, bool) {
+	if gui.scrollbarVisibility() == 0 {
+		return 0, false
+	}
+	scale := gui.scale()
+	x := float32(px)/scale - float32(gui.renderer.areaX)
+	y := float32(py)/scale - float32(gui.renderer.areaY)
+	p := gui.currentTab().focus
+	right := float32(p.col+p.cols) * gui.renderer.CellWidth()
+	top := float32(p.row) * gui.renderer.CellHeight()
+	height := float32(p.rows) * gui.renderer.CellHeight()
+
+	// it's easier to grab with a little leeway either side
+	if x < right-2*gui.renderer.ScrollbarWidth() || x >= right || y < top || y >= top+height {
+		return 0, false
+	}
+	return (y - top) / height, true
+}
+
+// startScrollbarDrag starts dragging the scrollbar if the mouse is over it. Grabbing the track rather than the
+// thumb jumps the thumb there first.
+func (gui *GUI) startScrollbarDrag(px float64, py float64) bool {
+	fraction, ok := gui.scrollbarAt(px, py)
+	if !ok {
+		return false
+	}
+	top, size, _ := scrollbarThumb(gui.terminal.ActiveBuffer(), gui.currentTab().focus.rows)
+	if fraction >= top && fraction < top+size {
+		gui.scrollbar.grab = fraction - top
+	} else {
+		gui.scrollbar.grab = size / 2
+	}
+	gui.scrollbar.dragging = true
+	gui.dragScrollbar(py)
+	return true
+}
+
+// dragScrollbar scrolls so that the grabbed part of the thumb is level with the mouse
+func (gui *GUI) dragScrollbar(py float64) {
+	p := gui.currentTab().focus
+	b := gui.terminal.ActiveBuffer()
+	_, size, ok := scrollbarThumb(b, p.rows)
+	if !ok || size >= 1 {
+		return
+	}
+	y := float32(py)/gui.scale() - float32(gui.renderer.areaY)
+	top := float32(p.row) * gui.renderer.CellHeight()
+	fraction := (y-top)/(float32(p.rows)*gui.renderer.CellHeight()) - gui.scrollbar.grab
+	position := fraction / (1 - size)
+	maxTop := b.Height() - int(b.ViewHeight())
+	gui.terminal.ScrollToLineAtTop(int(math.Round(float64(position * float32(maxTop)))))
+}
+
+func (gui *GUI) stopScrollbarDrag() {
+	gui.scrollbar.dragging = false
+	gui.scrollbar.shownAt = time.Now()
+}
+
+// updateScrollbarHover keeps the scrollbar shown while the mouse is over it
+func (gui *GUI) updateScrollbarHover(w *glfw.Window, px float64, py float64) bool {
+	_, over := gui.scrollbarAt(px, py)
+	if over != gui.scrollbar.hovered {
+		gui.scrollbar.hovered = over
+		gui.scrollbar.shownAt = time.Now()
+	}
+	if over {
+		w.SetCursor(gui.getArrowCursor())
+	}
+	return over
+}
@@ -0,0 +1,89 @@
+package gui
+
+import "github.com/go-gl/glfw/v3.3/glfw"
+
+// selection is somewhere copied text is kept for pasting: the system clipboard, or the primary selection,
+// which holds whatever was last selected with the mouse and is pasted with the middle button
+type selection int
+
+const (
+	selectionClipboard selection = iota
+	selectionPrimary
+)
+
+// setSelection copies text to the given selection. The primary selection is also kept here, so it works
+// within Aminal on platforms which don't have one.
+func (gui *GUI) setSelection(s selection, text string) {
+	switch s {
+	case selectionClipboard:
+		gui.window.SetClipboardString(text)
+	case selectionPrimary:
+		gui.primarySelection = text
+		setSystemPrimarySelection(text)
+	}
+}
+
+// getSelection returns the text in the given selection
+func (gui *GUI) getSelection(s selection) string {
+	if s == selectionClipboard {
+		return gui.window.GetClipboardString()
+	}
+	if text, ok := systemPrimarySelection(); ok {
+		return text
+	}
+	return gui.primarySelection
+}
+
+// copyOnSelect returns true if text selected with the mouse should go to the clipboard as well
+func (gui *GUI) copyOnSelect() bool {
+	return gui.config.Selection.CopyOnSelect || gui.config.CopyAndPasteWithMouse
+}
+
+// selectedText returns the text selected in the focused terminal, with wrapped lines joined as configured
+func (gui *GUI) selectedText() string {
+	return gui.terminal.ActiveBuffer().GetSelectedTextEx(gui.config.Selection.JoinWrapped)
+}
+
+// selectionKeys move the end of the selection with shift and the arrow keys
+var selectionKeys = map[glfw.Key][2]int{
+	glfw.KeyLeft:  {-1, 0},
+	glfw.KeyRight: {1, 0},
+	glfw.KeyUp:    {0, -1},
+	glfw.KeyDown:  {0, 1},
+}
+
+// extendSelectionWithKey moves the end of the selection for shift and an arrow key, scrolling to keep it in view.
+// It returns false, leaving the key for the application, if there's no selection.
+func (gui *GUI) extendSelectionWithKey(key glfw.Key, mods glfw.ModifierKey) bool {
+	move, ok := selectionKeys[key]
+	if !ok || !modsPressed(mods, glfw.ModShift) {
+		return false
+	}
+	b := gui.terminal.ActiveBuffer()
+	end, ok := b.MoveSelectionEnd(move[0], move[1])
+	if !ok {
+		return false
+	}
+	rows := int(b.ViewHeight())
+	if top := b.TopVisibleLine(); end.Line < top {
+		gui.terminal.ScrollToLineAtTop(end.Line)
+	} else if end.Line >= top+rows {
+		gui.terminal.ScrollToLineAtTop(end.Line - rows + 1)
+	}
+	gui.selected(gui.selectedText())
+	return true
+}
+
+// selected takes the text just selected with the mouse, returning false if there wasn't any
+func (gui *GUI) selected(text string) bool {
+	if text == "" {
+		return false
+	}
+	if gui.config.Selection.Primary {
+		gui.setSelection(selectionPrimary, text)
+	}
+	if gui.copyOnSelect() {
+		gui.setSelection(selectionClipboard, text)
+	}
+	return true
+}
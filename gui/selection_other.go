@@ -0,0 +1,12 @@
+// +build !linux,!freebsd wayland
+
+package gui
+
+// setSystemPrimarySelection does nothing, as there's no primary selection shared with other programs here
+func setSystemPrimarySelection(text string) {
+}
+
+// systemPrimarySelection returns false, so only text selected within Aminal is pasted with the middle button
+func systemPrimarySelection() (string, bool) {
+	return "", false
+}
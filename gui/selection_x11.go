@@ -0,0 +1,15 @@
+// +build linux,!wayland freebsd,!wayland
+
+package gui
+
+import "github.com/go-gl/glfw/v3.3/glfw"
+
+// setSystemPrimarySelection takes ownership of the X11 PRIMARY selection, so other programs paste text selected here
+func setSystemPrimarySelection(text string) {
+	glfw.SetX11SelectionString(text)
+}
+
+// systemPrimarySelection returns the X11 PRIMARY selection, which may have been set by another program
+func systemPrimarySelection() (string, bool) {
+	return glfw.GetX11SelectionString(), true
+}
@@ -0,0 +1,254 @@
+package gui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-gl/glfw/v3.3/glfw"
+	"github.com/liamg/aminal/config"
+	"github.com/liamg/aminal/themes"
+)
+
+// setting is an option the settings overlay changes, which takes effect straight away
+type setting struct {
+	name   string
+	value  func(gui *GUI) string
+	change func(gui *GUI, step int) // step is -1 for left and 1 for right
+	save   func(gui *GUI, c *config.Config)
+}
+
+// opacityStep is how much the settings overlay changes the opacities by
+const opacityStep = 0.1
+
+// settings are listed by the settings overlay, in order
+var settings = []setting{
+	{
+		name: "Theme",
+		value: func(gui *GUI) string {
+			if gui.config.Theme == "" {
+				return "(colours from the config)"
+			}
+			return gui.config.Theme
+		},
+		change: func(gui *GUI, step int) {
+			names := themes.Names(themes.Directory(gui.config))
+			if len(names) == 0 {
+				return
+			}
+			next := 0
+			if step < 0 {
+				next = len(names) - 1
+			}
+			for i, name := range names {
+				if name == gui.config.Theme {
+					next = (i + step + len(names)) % len(names)
+				}
+			}
+			if err := gui.setTheme(names[next]); err != nil {
+				gui.logger.Errorf("Failed to load theme: %s", err)
+			}
+		},
+		save: func(gui *GUI, c *config.Config) {
+			c.Theme = gui.config.Theme
+		},
+	},
+	{
+		name: "Font size",
+		value: func(gui *GUI) string {
+			return fmt.Sprintf("%g", gui.fontScale)
+		},
+		change: func(gui *GUI, step int) {
+			gui.setFontScale(gui.fontScale + float32(step))
+		},
+		save: func(gui *GUI, c *config.Config) {
+			// updated first, so the change to the file isn't taken as a new font to load
+			gui.config.Font.Size = gui.fontScale
+			c.Font.Size = gui.fontScale
+		},
+	},
+	{
+		name: "Window opacity",
+		value: func(gui *GUI) string {
+			return fmt.Sprintf("%.0f%%", clampOpacity(gui.config.Opacity, 0.1)*100)
+		},
+		change: func(gui *GUI, step int) {
+			gui.applyOpacity(clampOpacity(gui.config.Opacity+float32(step)*opacityStep, 0.1))
+		},
+		save: func(gui *GUI, c *config.Config) {
+			c.Opacity = gui.config.Opacity
+		},
+	},
+	{
+		name: "Background opacity",
+		value: func(gui *GUI) string {
+			return fmt.Sprintf("%.0f%%", gui.terminalAlpha*100)
+		},
+		change: func(gui *GUI, step int) {
+			gui.setBackgroundOpacity(gui.terminalAlpha + float32(step)*opacityStep)
+		},
+		save: func(gui *GUI, c *config.Config) {
+			gui.config.BackgroundOpacity = gui.terminalAlpha
+			c.BackgroundOpacity = gui.terminalAlpha
+		},
+	},
+	{
+		name: "Cursor",
+		value: func(gui *GUI) string {
+			if gui.config.Cursor.Blink {
+				return "always blinks"
+			}
+			return "blinks when asked to"
+		},
+		change: func(gui *GUI, step int) {
+			gui.config.Cursor.Blink = !gui.config.Cursor.Blink
+			gui.resetCursorBlink()
+		},
+		save: func(gui *GUI, c *config.Config) {
+			c.Cursor.Blink = gui.config.Cursor.Blink
+		},
+	},
+	{
+		name: "Cursor when unfocused",
+		value: func(gui *GUI) string {
+			if gui.config.Cursor.SolidWhenUnfocused {
+				return "solid"
+			}
+			return "outline"
+		},
+		change: func(gui *GUI, step int) {
+			gui.config.Cursor.SolidWhenUnfocused = !gui.config.Cursor.SolidWhenUnfocused
+		},
+		save: func(gui *GUI, c *config.Config) {
+			c.Cursor.SolidWhenUnfocused = gui.config.Cursor.SolidWhenUnfocused
+		},
+	},
+	{
+		name: "Bold text",
+		value: func(gui *GUI) string {
+			if gui.config.BoldIsBright {
+				return "bright colours"
+			}
+			return "same colours"
+		},
+		change: func(gui *GUI, step int) {
+			gui.applyBoldIsBright(!gui.config.BoldIsBright)
+		},
+		save: func(gui *GUI, c *config.Config) {
+			c.BoldIsBright = gui.config.BoldIsBright
+		},
+	},
+}
+
+// settingsOverlay lists the settings to change with the keyboard, for those who'd rather not edit the config
+// file. Changes are previewed, then saved to the config file with enter, or undone if it's closed without.
+type settingsOverlay struct {
+	selected int
+	original settingsSnapshot
+	values   []string // of the settings when the overlay was opened, to tell which have been changed
+	saved    bool
+}
+
+// settingsSnapshot is what the settings were, so they can be put back
+type settingsSnapshot struct {
+	theme             string
+	scheme            config.ColourScheme
+	fontScale         float32
+	opacity           float32
+	backgroundOpacity float32
+	cursor            config.CursorConfig
+	boldIsBright      bool
+}
+
+func actionSettings(gui *GUI) {
+	o := &settingsOverlay{
+		original: settingsSnapshot{
+			theme:             gui.config.Theme,
+			scheme:            gui.config.ColourScheme,
+			fontScale:         gui.fontScale,
+			opacity:           gui.config.Opacity,
+			backgroundOpacity: gui.terminalAlpha,
+			cursor:            gui.config.Cursor,
+			boldIsBright:      gui.config.BoldIsBright,
+		},
+	}
+	for _, s := range settings {
+		o.values = append(o.values, s.value(gui))
+	}
+	gui.setOverlay(o)
+}
+
+func (o *settingsOverlay) char(gui *GUI, r rune) {
+}
+
+func (o *settingsOverlay) key(gui *GUI, key glfw.Key, mods glfw.ModifierKey) {
+	switch key {
+	case glfw.KeyUp:
+		if o.selected > 0 {
+			o.selected--
+		}
+	case glfw.KeyDown:
+		if o.selected < len(settings)-1 {
+			o.selected++
+		}
+	case glfw.KeyLeft:
+		settings[o.selected].change(gui, -1)
+	case glfw.KeyRight:
+		settings[o.selected].change(gui, 1)
+	case glfw.KeyEnter, glfw.KeyKPEnter:
+		o.saved = true
+		o.save(gui)
+		gui.setOverlay(nil)
+	}
+	gui.terminal.SetDirty()
+}
+
+// save writes the settings which were changed to the config file, so they're used next time
+func (o *settingsOverlay) save(gui *GUI) {
+	changed := []setting{}
+	for i, s := range settings {
+		if s.value(gui) != o.values[i] {
+			changed = append(changed, s)
+		}
+	}
+	if len(changed) == 0 {
+		return
+	}
+	if gui.config.Path == "" {
+		gui.logger.Infof("Using the settings for this session only, as there's no config file to save them to")
+		return
+	}
+	err := config.UpdateFile(gui.config.Path, func(c *config.Config) {
+		for _, s := range changed {
+			s.save(gui, c)
+		}
+	})
+	if err != nil {
+		gui.logger.Errorf("Failed to save settings to %s: %s", gui.config.Path, err)
+		gui.showToast(newNotice(fmt.Sprintf("Failed to save settings: %s", err)))
+	}
+}
+
+func (o *settingsOverlay) closed(gui *GUI) {
+	if o.saved {
+		return
+	}
+	gui.config.Theme = o.original.theme
+	gui.applyColourScheme(o.original.scheme)
+	gui.setFontScale(o.original.fontScale)
+	gui.applyOpacity(o.original.opacity)
+	gui.setBackgroundOpacity(o.original.backgroundOpacity)
+	gui.config.Cursor = o.original.cursor
+	gui.applyBoldIsBright(o.original.boldIsBright)
+}
+
+func (o *settingsOverlay) render(gui *GUI) {
+	lines := []string{"Settings (left/right to change, enter to save, esc to cancel)", ""}
+	for i, s := range settings {
+		marker := "  "
+		if i == o.selected {
+			marker = "> "
+		}
+		lines = append(lines, fmt.Sprintf("%s%-22s < %s >", marker, s.name, s.value(gui)))
+	}
+	gui.textbox(2, 2, strings.Join(lines, "\n"), [3]float32{1, 1, 1}, [3]float32{0.2, 0.2, 0.4})
+}
@@ -10,11 +10,11 @@ import (
 const (
 	vertexShaderSource = `
 		#version 150
-		in vec3 vp;
+		in vec2 vp;
 		in vec3 inColour;
 		smooth out vec3 theColour;
 		void main() {
-			gl_Position = vec4(vp, 1.0);
+			gl_Position = vec4(vp, 0.0, 1.0);
 			theColour = inColour;
 		}
 	` + "\x00"
@@ -0,0 +1,52 @@
+package gui
+
+import (
+	"fmt"
+
+	"github.com/liamg/aminal/share"
+)
+
+func actionToggleShare(gui *GUI) {
+	if gui.share == nil {
+		gui.share = share.NewServer(gui.terminal, gui.logger)
+	}
+
+	if gui.share.Running() {
+		gui.stopSharing()
+		return
+	}
+
+	url, err := gui.share.Start(gui.config.Share.Listen)
+	if err != nil {
+		gui.logger.Errorf("Failed to share session: %s", err)
+		gui.showToast(newNotice(fmt.Sprintf("Failed to share session: %s", err)))
+		return
+	}
+
+	// each link lets one viewer in, so the link copied is the one for the next viewer
+	gui.showToast(newToast(
+		fmt.Sprintf("Sharing read-only at %s, a link for one viewer", url),
+		toastAction{
+			label: "Copy link",
+			run: func(gui *GUI) {
+				gui.window.SetClipboardString(gui.share.URL())
+			},
+		},
+		toastAction{
+			label: "Stop",
+			run: func(gui *GUI) {
+				gui.stopSharing()
+			},
+		},
+	))
+}
+
+func (gui *GUI) stopSharing() {
+	if gui.share == nil || !gui.share.Running() {
+		return
+	}
+	if err := gui.share.Stop(); err != nil {
+		gui.logger.Errorf("Failed to stop sharing: %s", err)
+	}
+	gui.showToast(newNotice("Stopped sharing"))
+}
@@ -0,0 +1,46 @@
+package gui
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// how long the size is shown for after the grid is resized
+const sizeDisplayDuration = time.Second
+
+// updateResizeIncrements keeps the window manager's resize increments in step with the cell size, so dragging the
+// window edge snaps to whole columns and rows
+func (gui *GUI) updateResizeIncrements() {
+	scale := float64(gui.scale()) // the window manager works in screen coordinates rather than pixels
+	cellWidth := int(math.Ceil(float64(gui.renderer.CellWidth()) * scale))
+	cellHeight := int(math.Ceil(float64(gui.renderer.CellHeight()) * scale))
+	// the padding around the grid is the base size, which the window is a whole number of cells larger than
+	baseWidth := int(math.Ceil(float64(2*gui.renderer.areaX) * scale))
+	baseHeight := int(math.Ceil(float64(2*gui.renderer.areaY) * scale))
+	increments := [4]int{baseWidth, baseHeight, cellWidth, cellHeight}
+	if cellWidth < 1 || cellHeight < 1 || increments == gui.resizeIncrements {
+		return
+	}
+	gui.resizeIncrements = increments
+	setResizeIncrements(gui.window, baseWidth, baseHeight, cellWidth, cellHeight)
+}
+
+// showSize briefly shows the size of the grid over the terminal
+func (gui *GUI) showSize() {
+	gui.sizeShownUntil = time.Now().Add(sizeDisplayDuration)
+	time.AfterFunc(sizeDisplayDuration, gui.terminal.SetDirty)
+}
+
+func (gui *GUI) renderSize() {
+	if time.Now().After(gui.sizeShownUntil) {
+		return
+	}
+	cols, rows := gui.terminal.GetSize()
+	text := fmt.Sprintf("%dx%d", cols, rows)
+	col, row := (int(cols)-len(text))/2-1, int(rows)/2-1
+	if col < 0 || row < 0 {
+		return
+	}
+	gui.textbox(uint16(col), uint16(row), text, [3]float32{1, 1, 1}, [3]float32{0.2, 0.2, 0.4})
+}
@@ -0,0 +1,24 @@
+//+build darwin
+
+package gui
+
+/*
+#cgo darwin CFLAGS: -x objective-c
+#cgo darwin LDFLAGS: -framework Cocoa
+#include <Cocoa/Cocoa.h>
+void cocoa_set_resize_increments(void *id, int width, int height) {
+	NSWindow *window = id;
+	[window setContentResizeIncrements:NSMakeSize(width, height)];
+}
+*/
+import "C"
+
+import (
+	"github.com/go-gl/glfw/v3.3/glfw"
+)
+
+// setResizeIncrements asks the window manager to resize the window in steps of a cell, in screen coordinates.
+// Cocoa has no base size, the increments apply from the current size.
+func setResizeIncrements(window *glfw.Window, baseWidth int, baseHeight int, cellWidth int, cellHeight int) {
+	C.cocoa_set_resize_increments(window.GetCocoaWindow(), C.int(cellWidth), C.int(cellHeight))
+}
@@ -0,0 +1,10 @@
+// +build !darwin,!linux,!freebsd wayland
+
+package gui
+
+import "github.com/go-gl/glfw/v3.3/glfw"
+
+// setResizeIncrements does nothing, as neither GLFW nor the window system here lets us hint resize increments.
+// The grid still fits to whole cells, leaving any remainder as a margin.
+func setResizeIncrements(window *glfw.Window, baseWidth int, baseHeight int, cellWidth int, cellHeight int) {
+}
@@ -0,0 +1,41 @@
+// +build linux,!wayland freebsd,!wayland
+
+package gui
+
+/*
+#cgo LDFLAGS: -lX11
+#include <X11/Xlib.h>
+#include <X11/Xutil.h>
+static void x11_set_resize_increments(void *display, Window window, int baseWidth, int baseHeight, int incWidth, int incHeight) {
+	XSizeHints *hints = XAllocSizeHints();
+	long supplied;
+	XGetWMNormalHints(display, window, hints, &supplied);
+	hints->flags |= PResizeInc | PBaseSize;
+	hints->base_width = baseWidth;
+	hints->base_height = baseHeight;
+	hints->width_inc = incWidth;
+	hints->height_inc = incHeight;
+	XSetWMNormalHints(display, window, hints);
+	XFree(hints);
+	XFlush(display);
+}
+*/
+import "C"
+
+import (
+	"unsafe"
+
+	"github.com/go-gl/glfw/v3.3/glfw"
+)
+
+// setResizeIncrements asks the window manager to resize the window in steps of a cell, in screen coordinates
+func setResizeIncrements(window *glfw.Window, baseWidth int, baseHeight int, cellWidth int, cellHeight int) {
+	C.x11_set_resize_increments(
+		unsafe.Pointer(glfw.GetX11Display()),
+		C.Window(window.GetX11Window()),
+		C.int(baseWidth),
+		C.int(baseHeight),
+		C.int(cellWidth),
+		C.int(cellHeight),
+	)
+}
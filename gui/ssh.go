@@ -0,0 +1,70 @@
+package gui
+
+import (
+	"fmt"
+
+	"github.com/liamg/aminal/config"
+	"github.com/liamg/aminal/themes"
+)
+
+// actionSSH shows a menu of the ssh profiles in the config, connecting to the one picked in a new tab
+func actionSSH(gui *GUI) {
+	profiles := gui.config.SSH
+	if len(profiles) == 0 {
+		gui.showToast(newNotice("Add [[ssh]] profiles to the config to connect to them from here"))
+		return
+	}
+
+	items := make([]string, len(profiles))
+	for i, profile := range profiles {
+		items[i] = profile.Label()
+	}
+	gui.setOverlay(newMenu("Connect to:", items, func(gui *GUI, index int) {
+		gui.openSSH(profiles[index])
+	}))
+}
+
+// openSSH opens a tab running ssh to the profile's host, in the profile's colours if it has a theme, with the
+// profile whose match pattern matches the ssh command, if there is one
+func (gui *GUI) openSSH(profile config.SSHProfile) {
+	if gui.newSession == nil {
+		gui.logger.Infof("Tabs aren't available when Aminal is used as a library without a session factory")
+		return
+	}
+
+	var scheme *config.ColourScheme
+	if profile.Theme != "" {
+		s, err := themes.Load(profile.Theme, themes.Directory(gui.config))
+		if err != nil {
+			gui.logger.Errorf("Failed to load the %s theme for %s, using the usual colours: %s", profile.Theme, profile.Label(), err)
+		} else {
+			scheme = &s
+		}
+	}
+
+	command := profile.Command()
+	matched, hasMatch := gui.config.MatchProfile(command)
+	var match *config.Profile
+	if hasMatch {
+		match = &matched
+	}
+
+	t, err := gui.newSession("", match, command...)
+	if err != nil {
+		gui.logger.Errorf("Failed to connect to %s: %s", profile.Label(), err)
+		gui.showToast(newNotice(fmt.Sprintf("Failed to connect to %s: %s", profile.Label(), err)))
+		return
+	}
+	// shown in the tab bar until the remote shell sets a title of its own
+	t.SetTitle(profile.Label())
+
+	tab := newTab(t)
+	if hasMatch {
+		gui.useProfile(tab, matched)
+	}
+	if scheme != nil {
+		tab.scheme = scheme
+		setColours(t, *scheme)
+	}
+	gui.openTab(tab)
+}
@@ -0,0 +1,326 @@
+package gui
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/liamg/aminal/config"
+	"github.com/liamg/aminal/terminal"
+)
+
+// the longest a tab's title can be in the tab bar
+const maxTabTitle = 24
+
+// SessionFactory starts a new terminal with a shell running in it, for a new tab, in dir if it isn't empty. If a
+// command is given, the program and its arguments, it's run instead of the shell. With a profile, its shell,
+// directory and environment are used.
+type SessionFactory func(dir string, profile *config.Profile, command ...string) (*terminal.Terminal, error)
+
+type tab struct {
+	root     *pane // the tree of panes the tab is split into
+	focus    *pane // the leaf with the terminal which gets keyboard input
+	activity bool  // output has arrived since the tab was last shown
+	bell     bool  // the bell has rung since the tab was last shown
+	startCol int   // where the tab was last drawn in the tab bar
+	endCol   int
+
+	scheme  *config.ColourScheme // the tab's own colours, kept when the theme changes, or nil
+	font    *config.FontConfig   // the tab's own fonts, from its profile, or nil
+	profile *config.Profile      // the profile the tab was opened with, which its panes are opened with too, or nil
+}
+
+// SetSessionFactory enables tabs, using factory to start the terminal in each new one
+func (gui *GUI) SetSessionFactory(factory SessionFactory) {
+	gui.newSession = factory
+}
+
+// AddTab opens another tab showing t, behind the first. It's for terminals there from the start, and must be
+// called before Render.
+func (gui *GUI) AddTab(t *terminal.Terminal) {
+	gui.tabs = append(gui.tabs, newTab(t))
+}
+
+func newTab(t *terminal.Terminal) *tab {
+	root := newPane(t)
+	return &tab{root: root, focus: root}
+}
+
+// attachTerminal connects a terminal to the window, and closes its pane when its pty closes
+func (gui *GUI) attachTerminal(t *terminal.Terminal) {
+	t.SetClipboard(newClipboardAccess(gui))
+	t.SetProgram(gui.renderer.program)
+	t.AttachOutputHandler(gui.outputChan)
+	t.AttachTitleChangeHandler(gui.titleChan)
+	t.AttachResizeHandler(gui.resizeChan)
+	t.AttachReverseHandler(gui.reverseChan)
+	t.AttachColourChangeHandler(gui.coloursChan)
+	t.AttachHostChangeHandler(gui.hostChan)
+	t.AttachThemeHandler(gui.themeChan)
+	t.AttachFontSizeHandler(gui.fontSizeChan)
+	t.AttachBellHandler(gui.bellChan)
+	t.AttachCommandFinishedHandler(gui.commandChan)
+	t.SetTriggers(gui.triggers)
+	t.AttachTriggerHandler(gui.triggerChan)
+	gui.plugins.Attach(t)
+
+	go func() {
+		if err := t.Read(); err != nil {
+			gui.logger.Errorf("Read from pty failed: %s", err)
+		}
+		gui.CloseTerminal(t)
+	}()
+}
+
+// CloseTerminal closes the pane showing t, along with its tab if it was the only pane, and the window
+// if that was the last tab. It can be called from any goroutine.
+func (gui *GUI) CloseTerminal(t *terminal.Terminal) {
+	gui.runOnMainThread(func() {
+		gui.plugins.Detach(t)
+		gui.removeTerminal(t)
+	})
+}
+
+func (gui *GUI) removeTerminal(t *terminal.Terminal) {
+	for i, tab := range gui.tabs {
+		p := tab.root.find(t)
+		if p == nil {
+			continue
+		}
+		if p != tab.root {
+			gui.removePane(i, p)
+			return
+		}
+		gui.tabs = append(gui.tabs[:i], gui.tabs[i+1:]...)
+		if len(gui.tabs) == 0 {
+			gui.Close()
+			return
+		}
+		active := gui.activeTab
+		if i < active || active >= len(gui.tabs) {
+			active--
+		}
+		gui.activeTab = -1 // force the switch, as the index may not have changed
+		gui.switchTab(active)
+		if len(gui.tabs) == 1 {
+			gui.relayout() // the tab bar has gone
+		}
+		return
+	}
+}
+
+// removePane closes a pane which isn't the only one in its tab
+func (gui *GUI) removePane(index int, p *pane) {
+	tab := gui.tabs[index]
+	focusLost := tab.focus == p
+	if focusLost {
+		tab.focus = p.sibling().leaves()[0]
+	}
+	tab.root = p.remove(tab.root)
+	if index != gui.activeTab {
+		return
+	}
+	if focusLost {
+		gui.setOverlay(nil)
+		gui.hoverLink = nil
+		gui.terminal = tab.focus.terminal
+		gui.reportFocus(gui.terminal, true)
+		gui.window.SetTitle(gui.terminal.GetTitle())
+		gui.generateDefaultCell(gui.terminal.ScreenMode())
+	}
+	gui.layoutPanes()
+}
+
+func (gui *GUI) closeTab(tab *tab) {
+	for _, p := range tab.root.leaves() {
+		gui.removeTerminal(p.terminal)
+		if err := p.terminal.Close(); err != nil {
+			gui.logger.Errorf("Failed to close pty: %s", err)
+		}
+	}
+}
+
+// tabBarRows returns the number of rows taken from the bottom of the window by the tab bar
+func (gui *GUI) tabBarRows() uint {
+	if len(gui.tabs) > 1 {
+		return 1
+	}
+	return 0
+}
+
+// terminalGridSize returns the size of the grid the terminal is shown in, which is the window less the tab bar
+// and the minimap
+func (gui *GUI) terminalGridSize() (uint, uint) {
+	cols, rows := gui.renderer.GetTermSize()
+	cols -= gui.minimapCols()
+	if rows > gui.tabBarRows() {
+		rows -= gui.tabBarRows()
+	}
+	return cols, rows
+}
+
+// relayout recalculates the terminal grid even though the window size hasn't changed
+func (gui *GUI) relayout() {
+	gui.appliedWidth = 0
+	gui.appliedHeight = 0
+	gui.resize(gui.window, gui.width, gui.height)
+}
+
+func (gui *GUI) switchTab(index int) {
+	if index < 0 || index >= len(gui.tabs) || index == gui.activeTab {
+		return
+	}
+	gui.setOverlay(nil)
+	gui.activeTab = index
+	tab := gui.tabs[index]
+	tab.activity = false
+	tab.bell = false
+	gui.hoverLink = nil
+	gui.reportFocus(gui.terminal, false)
+	gui.terminal = tab.focus.terminal
+	gui.reportFocus(gui.terminal, true)
+	gui.generateDefaultCell(gui.terminal.ScreenMode())
+
+	if !gui.font().Equal(gui.loadedFont) {
+		gui.reloadFonts()
+	}
+	// only the visible tab is resized along with the window, so catch this one up
+	gui.layoutPanes()
+	gui.window.SetTitle(gui.terminal.GetTitle())
+}
+
+// checkBackgroundTabs marks tabs which have had output while hidden, returning true if the tab bar needs redrawing
+func (gui *GUI) checkBackgroundTabs() bool {
+	changed := false
+	for i, tab := range gui.tabs {
+		if i == gui.activeTab {
+			continue
+		}
+		for _, p := range tab.root.leaves() {
+			if p.terminal.CheckDirty() && !tab.activity {
+				tab.activity = true
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+func actionNewTab(gui *GUI) {
+	if gui.newSession == nil {
+		gui.logger.Infof("Tabs aren't available when Aminal is used as a library without a session factory")
+		return
+	}
+	t, err := gui.newSession(gui.terminal.WorkingDirectory(), nil)
+	if err != nil {
+		gui.logger.Errorf("Failed to open a new tab: %s", err)
+		gui.showToast(newNotice(fmt.Sprintf("Failed to open a new tab: %s", err)))
+		return
+	}
+	gui.openTab(newTab(t))
+}
+
+// openTab adds a tab and switches to it
+func (gui *GUI) openTab(tab *tab) {
+	gui.tabs = append(gui.tabs, tab)
+	gui.attachTerminal(tab.focus.terminal)
+	if len(gui.tabs) == 2 {
+		gui.relayout() // make room for the tab bar
+	}
+	gui.switchTab(len(gui.tabs) - 1)
+}
+
+// actionNewWindow starts another Aminal with the same arguments, with its shell in the focused shell's directory
+func actionNewWindow(gui *GUI) {
+	executable, err := os.Executable()
+	if err == nil {
+		cmd := exec.Command(executable, os.Args[1:]...)
+		if dir := gui.terminal.WorkingDirectory(); dir != "" {
+			if info, err := os.Stat(dir); err == nil && info.IsDir() {
+				cmd.Dir = dir
+			}
+		}
+		if err = cmd.Start(); err == nil {
+			go cmd.Wait()
+			return
+		}
+	}
+	gui.logger.Errorf("Failed to open a new window: %s", err)
+	gui.showToast(newNotice(fmt.Sprintf("Failed to open a new window: %s", err)))
+}
+
+func actionCloseTab(gui *GUI) {
+	gui.closeTab(gui.currentTab())
+}
+
+func actionNextTab(gui *GUI) {
+	gui.switchTab((gui.activeTab + 1) % len(gui.tabs))
+}
+
+func actionPreviousTab(gui *GUI) {
+	gui.switchTab((gui.activeTab + len(gui.tabs) - 1) % len(gui.tabs))
+}
+
+// tabAt returns the index of the tab drawn at the cell, or -1
+func (gui *GUI) tabAt(col uint16, row uint16) int {
+	if gui.tabBarRows() == 0 {
+		return -1
+	}
+	if _, rows := gui.terminalGridSize(); uint(row) != rows {
+		return -1
+	}
+	for i, tab := range gui.tabs {
+		if int(col) >= tab.startCol && int(col) < tab.endCol {
+			return i
+		}
+	}
+	return -1
+}
+
+// renderTabBar draws the tabs in the row below the terminal, when there's more than one
+func (gui *GUI) renderTabBar() {
+	if gui.tabBarRows() == 0 {
+		return
+	}
+	cols, rows := gui.terminalGridSize()
+	row := rows // the first row after the terminal
+
+	col := 0
+	for i, tab := range gui.tabs {
+		title := []rune(tab.focus.terminal.GetTitle())
+		if len(title) > maxTabTitle {
+			title = append(title[:maxTabTitle-1], '…')
+		}
+		marker := ""
+		if tab.activity {
+			marker = "*"
+		}
+		if tab.bell {
+			marker = "!"
+		}
+		label := []rune(fmt.Sprintf(" %d: %s%s ", i+1, string(title), marker))
+
+		bg := config.Colour{0.15, 0.15, 0.2}
+		fg := [3]float32{0.7, 0.7, 0.7}
+		if tab.bell {
+			bg = config.Colour{0.5, 0.15, 0.15}
+			fg = [3]float32{1, 1, 1}
+		}
+		if i == gui.activeTab {
+			bg = gui.config.ColourScheme.Selection
+			fg = gui.config.ColourScheme.Foreground
+		}
+
+		tab.startCol = col
+		for _, r := range label {
+			if uint(col) >= cols {
+				break
+			}
+			gui.renderer.DrawCellBg(*gui.defaultCell, uint(col), row, &bg, true)
+			gui.renderer.DrawCellText(string(r), uint(col), row, 1, fg, i == gui.activeTab, false)
+			col++
+		}
+		tab.endCol = col
+		col++ // leave a gap between tabs
+	}
+}
@@ -84,13 +84,16 @@ DONE:
 		}
 	}
 
-	x := float32(col) * gui.renderer.cellWidth
+	x := float32(gui.renderer.areaX) + float32(col)*gui.renderer.cellWidth
+
+	// the text is drawn straight away, so the background has to be drawn first
+	gui.renderer.Flush()
 
 	f := gui.fontMap.DefaultFont()
 	f.SetColor(fg[0], fg[1], fg[2], 1)
 
 	for i, line := range lines {
-		y := float32(row+1+uint16(i))*gui.renderer.cellHeight + f.MinY()
+		y := float32(gui.renderer.areaY) + float32(row+1+uint16(i))*gui.renderer.cellHeight + f.MinY() - gui.renderer.textLift
 		f.Print(x, y, fmt.Sprintf(" %s", line))
 	}
 }
@@ -0,0 +1,143 @@
+package gui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-gl/glfw/v3.3/glfw"
+	"github.com/liamg/aminal/config"
+	"github.com/liamg/aminal/themes"
+)
+
+// themePicker lists the built in and user themes, previewing each one as it is selected
+type themePicker struct {
+	themes   []string
+	filter   string
+	matches  []string
+	selected int
+	original config.ColourScheme // restored if the picker is closed without choosing
+	theme    string
+	chosen   bool
+}
+
+func actionThemePicker(gui *GUI) {
+	picker := &themePicker{
+		themes:   themes.Names(themes.Directory(gui.config)),
+		original: gui.config.ColourScheme,
+		theme:    gui.config.Theme,
+	}
+	gui.setOverlay(picker)
+	picker.update(gui)
+}
+
+func (p *themePicker) update(gui *GUI) {
+	p.matches = nil
+	filter := strings.ToLower(p.filter)
+	for _, name := range p.themes {
+		if strings.Contains(strings.ToLower(name), filter) {
+			p.matches = append(p.matches, name)
+		}
+	}
+	p.selected = 0
+	for i, name := range p.matches {
+		if name == gui.config.Theme {
+			p.selected = i
+		}
+	}
+	p.preview(gui)
+}
+
+// preview shows the terminal in the selected theme
+func (p *themePicker) preview(gui *GUI) {
+	if gui.overlay != p || p.selected >= len(p.matches) {
+		return
+	}
+	name := p.matches[p.selected]
+	if name == gui.config.Theme {
+		return
+	}
+	if err := gui.setTheme(name); err != nil {
+		gui.logger.Errorf("Failed to preview theme: %s", err)
+	}
+}
+
+func (p *themePicker) char(gui *GUI, r rune) {
+	p.filter += string(r)
+	p.update(gui)
+}
+
+func (p *themePicker) key(gui *GUI, key glfw.Key, mods glfw.ModifierKey) {
+	switch key {
+	case glfw.KeyBackspace:
+		if len(p.filter) > 0 {
+			runes := []rune(p.filter)
+			p.filter = string(runes[:len(runes)-1])
+			p.update(gui)
+		}
+	case glfw.KeyUp:
+		if p.selected > 0 {
+			p.selected--
+			p.preview(gui)
+		}
+	case glfw.KeyDown:
+		if p.selected < len(p.matches)-1 {
+			p.selected++
+			p.preview(gui)
+		}
+	case glfw.KeyEnter, glfw.KeyKPEnter:
+		if p.selected < len(p.matches) {
+			p.chosen = true
+			p.save(gui, p.matches[p.selected])
+		}
+		gui.setOverlay(nil)
+	}
+	gui.terminal.SetDirty()
+}
+
+// save writes the choice to the config file so it's used next time
+func (p *themePicker) save(gui *GUI, name string) {
+	if gui.config.Path == "" {
+		gui.logger.Infof("Using the %s theme for this session only, as there's no config file to save it to", name)
+		return
+	}
+	err := config.UpdateFile(gui.config.Path, func(c *config.Config) {
+		c.Theme = name
+	})
+	if err != nil {
+		gui.logger.Errorf("Failed to save theme to %s: %s", gui.config.Path, err)
+		gui.showToast(newNotice(fmt.Sprintf("Failed to save theme: %s", err)))
+	}
+}
+
+func (p *themePicker) closed(gui *GUI) {
+	if !p.chosen && gui.config.ColourScheme != p.original {
+		gui.config.Theme = p.theme
+		gui.applyColourScheme(p.original)
+	}
+}
+
+func (p *themePicker) render(gui *GUI) {
+	lines := []string{fmt.Sprintf("theme: %s_", p.filter), ""}
+	if len(p.matches) == 0 {
+		lines = append(lines, "  (no matching themes)")
+	}
+
+	// keep the selection in view, leaving room for the title
+	rows := int(gui.terminal.ActiveBuffer().ViewHeight())/2 - 4
+	if rows < 1 {
+		rows = 1
+	}
+	first := 0
+	if p.selected >= rows {
+		first = p.selected - rows + 1
+	}
+	for i := first; i < len(p.matches) && i < first+rows; i++ {
+		marker := "  "
+		if i == p.selected {
+			marker = "> "
+		}
+		lines = append(lines, marker+p.matches[i])
+	}
+
+	gui.textbox(2, 2, strings.Join(lines, "\n"), [3]float32{1, 1, 1}, [3]float32{0.2, 0.2, 0.4})
+}
@@ -0,0 +1,56 @@
+package gui
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/go-gl/glfw/v3.3/glfw"
+)
+
+const (
+	// how long the render loop waits for events, which is how often it draws when nothing else wakes it
+	focusedWait = 20 * time.Millisecond // up to 50fps on no input, otherwise higher
+	// output no longer wakes the loop in the background, so it's only drawn this often
+	unfocusedWait = 250 * time.Millisecond
+	// nothing is drawn while the window can't be seen, so the loop only wakes for the odd housekeeping task
+	hiddenWait = 2 * time.Second
+)
+
+// updateThrottle slows the render loop down while the window is in the background, so an idle terminal
+// doesn't keep the machine busy. It must be called whenever the window's focus or visibility changes.
+func (gui *GUI) updateThrottle() {
+	var throttled int32
+	if gui.hidden || !gui.windowFocused {
+		throttled = 1
+	}
+	atomic.StoreInt32(&gui.throttled, throttled)
+}
+
+// wakeForOutput wakes the render loop to draw new output straight away, unless the window is in the background
+func (gui *GUI) wakeForOutput() {
+	if atomic.LoadInt32(&gui.throttled) == 0 {
+		glfw.PostEmptyEvent()
+	}
+}
+
+// waitEvents waits for input, or until it's time to check for anything else to draw
+func (gui *GUI) waitEvents() {
+	wait := focusedWait
+	switch {
+	case gui.hidden:
+		wait = hiddenWait
+	case !gui.windowFocused:
+		wait = unfocusedWait
+	}
+	// this is more efficient than glfw.PollEvents()
+	glfw.WaitEventsTimeout(wait.Seconds())
+}
+
+// resync draws everything again when the window comes back from the background, as anything could have
+// changed while it was drawn less often or not at all
+func (gui *GUI) resync() {
+	gui.invalidateFrame()
+	for _, p := range gui.currentTab().root.leaves() {
+		p.terminal.SetDirty()
+	}
+}
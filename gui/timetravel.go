@@ -0,0 +1,87 @@
+package gui
+
+import (
+	"fmt"
+
+	"github.com/go-gl/glfw/v3.3/glfw"
+	"github.com/liamg/aminal/buffer"
+)
+
+// timeTravel shows the screen as it was after each command, as recorded at each prompt
+type timeTravel struct {
+	snapshots []buffer.Snapshot
+	index     int
+}
+
+func actionTimeTravel(gui *GUI) {
+	snapshots := gui.terminal.ActiveBuffer().Snapshots()
+	if len(snapshots) == 0 {
+		gui.logger.Infof("No snapshots to browse: they are taken at each prompt, which needs shell integration")
+		return
+	}
+	gui.setOverlay(&timeTravel{
+		snapshots: snapshots,
+		index:     len(snapshots) - 1,
+	})
+}
+
+func (t *timeTravel) char(gui *GUI, r rune) {}
+
+func (t *timeTravel) key(gui *GUI, key glfw.Key, mods glfw.ModifierKey) {
+	switch key {
+	case glfw.KeyLeft, glfw.KeyUp, glfw.KeyPageUp:
+		if t.index > 0 {
+			t.index--
+		}
+	case glfw.KeyRight, glfw.KeyDown, glfw.KeyPageDown:
+		if t.index < len(t.snapshots)-1 {
+			t.index++
+		}
+	case glfw.KeyHome:
+		t.index = 0
+	case glfw.KeyEnd:
+		t.index = len(t.snapshots) - 1
+	case glfw.KeyEnter, glfw.KeyKPEnter:
+		gui.setOverlay(nil)
+	}
+	gui.terminal.SetDirty()
+}
+
+func (t *timeTravel) render(gui *GUI) {
+	gui.renderer.Clear()
+
+	height := int(gui.terminal.ActiveBuffer().ViewHeight())
+	cols := int(gui.terminal.ActiveBuffer().ViewWidth())
+	snapshot := t.snapshots[t.index]
+	lines := snapshot.Lines()
+
+	for row := 0; row < height && row < len(lines); row++ {
+		cells := lines[row].Cells()
+		for x := 0; x < cols && x < len(cells); x++ {
+			gui.renderer.DrawCellBg(cells[x], uint(x), uint(row), nil, false)
+		}
+		for x := 0; x < cols && x < len(cells); x++ {
+			r := cells[x].Rune()
+			if r == 0 || r == ' ' {
+				continue
+			}
+			gui.renderer.DrawCellText(cells[x].Text(), uint(x), uint(row), 1.0, cells[x].Fg(), cells[x].Attr().Bold, cells[x].Attr().Italic)
+		}
+	}
+
+	if height < 3 {
+		return
+	}
+	gui.textbox(
+		2,
+		uint16(height-3),
+		fmt.Sprintf(
+			"%d/%d  after the command finished at %s  (left/right to step, esc to return)",
+			t.index+1,
+			len(t.snapshots),
+			snapshot.Time.Format("15:04:05"),
+		),
+		[3]float32{1, 1, 1},
+		[3]float32{0.2, 0.2, 0.4},
+	)
+}
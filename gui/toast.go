@@ -0,0 +1,98 @@
+package gui
+
+import "fmt"
+
+type toastAction struct {
+	label string
+	run   func(gui *GUI)
+}
+
+// toast is a notification shown in the corner of the window which doesn't take keyboard input, so it
+// can be left on screen while the user carries on typing. Its actions are picked by clicking them.
+type toast struct {
+	message string
+	actions []toastAction
+	col     uint16 // where the toast was last drawn
+	row     uint16
+}
+
+func newToast(message string, actions ...toastAction) *toast {
+	return &toast{
+		message: message,
+		actions: actions,
+	}
+}
+
+// dismissAction closes the toast without doing anything else
+var dismissAction = toastAction{label: "Dismiss", run: func(gui *GUI) {}}
+
+// newNotice creates a toast which only tells the user something, so all it can do is be dismissed
+func newNotice(message string) *toast {
+	return newToast(message, dismissAction)
+}
+
+func (t *toast) text() string {
+	text := t.message
+	for _, action := range t.actions {
+		text += fmt.Sprintf("  [%s]", action.label)
+	}
+	return text
+}
+
+func (t *toast) render(gui *GUI) {
+	w := gui.terminal.ActiveBuffer().ViewWidth()
+	h := gui.terminal.ActiveBuffer().ViewHeight()
+	if h < 3 {
+		return
+	}
+
+	text := t.text()
+	t.row = h - 2
+	t.col = 0
+	if int(w)-len(text)-3 > 0 {
+		t.col = w - uint16(len(text)) - 3
+	}
+
+	gui.textbox(t.col, t.row, text, [3]float32{1, 1, 1}, [3]float32{0.5, 0.3, 0})
+}
+
+// actionAt returns the action drawn at the given cell, if any
+func (t *toast) actionAt(col uint16, row uint16) *toastAction {
+	if row != t.row {
+		return nil
+	}
+	offset := int(t.col) + 1 + len(t.message) // textbox pads with a space
+	for i, action := range t.actions {
+		offset += 2
+		end := offset + len(action.label) + 2
+		if int(col) >= offset && int(col) < end {
+			return &t.actions[i]
+		}
+		offset = end
+	}
+	return nil
+}
+
+func (gui *GUI) showToast(t *toast) {
+	gui.toast = t
+	gui.terminal.SetDirty()
+}
+
+func (gui *GUI) dismissToast() {
+	gui.toast = nil
+	gui.terminal.SetDirty()
+}
+
+// handleToastClick runs the toast action under the mouse, returning false if there wasn't one
+func (gui *GUI) handleToastClick(col uint16, row uint16) bool {
+	if gui.toast == nil {
+		return false
+	}
+	action := gui.toast.actionAt(col, row)
+	if action == nil {
+		return false
+	}
+	gui.dismissToast()
+	action.run(gui)
+	return true
+}
@@ -0,0 +1,92 @@
+package gui
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/liamg/aminal/config"
+	"github.com/liamg/aminal/platform"
+	"github.com/liamg/aminal/terminal"
+)
+
+// newTriggers compiles the configured triggers for the terminals, in the same order so matches can be taken back
+// to their trigger's action
+func newTriggers(triggers []config.Trigger) ([]terminal.Trigger, error) {
+	compiled := []terminal.Trigger{}
+	for _, trigger := range triggers {
+		pattern, err := regexp.Compile(trigger.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid trigger pattern '%s': %s", trigger.Pattern, err)
+		}
+		t := terminal.Trigger{Pattern: pattern}
+		switch trigger.Action {
+		case config.TriggerHighlight:
+			colour, err := trigger.HighlightColour()
+			if err != nil {
+				return nil, fmt.Errorf("Invalid colour for trigger pattern '%s': %s", trigger.Pattern, err)
+			}
+			highlight := [3]float32(colour)
+			t.Highlight = &highlight
+		case config.TriggerNotify, config.TriggerSound:
+		case config.TriggerLog:
+			if trigger.File == "" {
+				return nil, fmt.Errorf("No file to log to for trigger pattern '%s'", trigger.Pattern)
+			}
+		case config.TriggerCommand:
+			if trigger.Command == "" {
+				return nil, fmt.Errorf("No command to run for trigger pattern '%s'", trigger.Pattern)
+			}
+		default:
+			return nil, fmt.Errorf("Unknown action '%s' for trigger pattern '%s'", trigger.Action, trigger.Pattern)
+		}
+		compiled = append(compiled, t)
+	}
+	return compiled, nil
+}
+
+// runTrigger runs the action of the trigger a line matched, other than highlighting, which the terminal has done
+func (gui *GUI) runTrigger(match terminal.TriggerMatch) {
+	if match.Trigger >= len(gui.config.Triggers) {
+		return
+	}
+	trigger := gui.config.Triggers[match.Trigger]
+	switch trigger.Action {
+	case config.TriggerNotify:
+		gui.notify(match.Match, match.Line)
+	case config.TriggerSound:
+		// limited like the bell, so a flood of matching lines doesn't pile up sounds
+		if now := time.Now(); now.Sub(gui.lastBell) >= bellFlashDuration {
+			gui.lastBell = now
+			beep(gui.window)
+		}
+	case config.TriggerLog:
+		if err := appendTriggerLog(trigger.File, match.Line); err != nil {
+			gui.logger.Errorf("Failed to log the line matching trigger pattern '%s': %s", trigger.Pattern, err)
+		}
+	case config.TriggerCommand:
+		env := []string{"AMINAL_MATCH=" + match.Match, "AMINAL_LINE=" + match.Line}
+		for i, group := range match.Groups {
+			env = append(env, fmt.Sprintf("AMINAL_MATCH_%d=%s", i+1, group))
+		}
+		go func(command string) {
+			if err := platform.RunCommand(command, env...); err != nil {
+				gui.logger.Errorf("Trigger command '%s' failed: %s", command, err)
+			}
+		}(trigger.Command)
+	}
+}
+
+// appendTriggerLog adds the line to the end of the file, after the time
+func appendTriggerLog(path string, line string) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(f, "%s %s\n", time.Now().Format("2006-01-02 15:04:05"), line); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,53 @@
+package gui
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+// fileWatcher polls a set of files for modifications
+type fileWatcher struct {
+	mutex    sync.Mutex
+	modTimes map[string]time.Time
+}
+
+func newFileWatcher(paths ...string) *fileWatcher {
+	w := &fileWatcher{}
+	w.watch(paths...)
+	return w
+}
+
+// watch replaces the set of watched files
+func (w *fileWatcher) watch(paths ...string) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.modTimes = map[string]time.Time{}
+	for _, path := range paths {
+		w.modTimes[path] = modTime(path)
+	}
+}
+
+// changed returns the files which have been modified, created or removed since the last call
+func (w *fileWatcher) changed() []string {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	changed := []string{}
+	for path, previous := range w.modTimes {
+		if current := modTime(path); !current.Equal(previous) {
+			w.modTimes[path] = current
+			changed = append(changed, path)
+		}
+	}
+	return changed
+}
+
+func modTime(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
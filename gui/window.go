@@ -0,0 +1,47 @@
+package gui
+
+import (
+	"github.com/go-gl/glfw/v3.3/glfw"
+	"github.com/liamg/aminal/config"
+)
+
+// startGeometry checks the window config, returning the geometry the window starts with
+func startGeometry(conf config.WindowConfig) (config.Geometry, error) {
+	if err := conf.Validate(); err != nil {
+		return config.Geometry{}, err
+	}
+	return config.ParseGeometry(conf.Geometry)
+}
+
+// placeWindow sizes the window to the columns and rows in the config, moves it to the position there and
+// maximizes or minimizes it. Must be called once the fonts are loaded, so the size of a cell is known.
+func (gui *GUI) placeWindow() {
+	g := gui.geometry
+	if g.Columns > 0 && gui.config.Window.State != config.WindowMaximized {
+		gui.resizeToTerminal(g.Columns, g.Rows)
+	}
+
+	if g.Positioned {
+		x, y := g.X, g.Y
+		if g.Right || g.Bottom {
+			// measured from the far edges of the screen, to the far edges of the window's frame
+			areaX, areaY, areaWidth, areaHeight := glfw.GetPrimaryMonitor().GetWorkarea()
+			width, height := gui.window.GetSize()
+			left, top, right, bottom := gui.window.GetFrameSize()
+			if g.Right {
+				x = areaX + areaWidth - x - width - left - right
+			}
+			if g.Bottom {
+				y = areaY + areaHeight - y - height - top - bottom
+			}
+		}
+		gui.window.SetPos(x, y)
+	}
+
+	switch gui.config.Window.State {
+	case config.WindowMaximized:
+		gui.window.Maximize()
+	case config.WindowMinimized:
+		gui.window.Iconify()
+	}
+}
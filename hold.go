@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/liamg/aminal/platform"
+)
+
+// holdSettleTime is how long the pty must have been quiet after the program exits before its output is taken to
+// have all arrived
+const holdSettleTime = 100 * time.Millisecond
+
+// reportingOff turns off the mouse and focus reporting a program may have left on
+const reportingOff = "\x1b[?1000l\x1b[?1002l\x1b[?1003l\x1b[?1004l"
+
+// holdPty keeps a terminal open once its program has exited, showing how it exited below the last of its output.
+// A key pressed after that closes it, as does closing the pty.
+type holdPty struct {
+	platform.Pty
+	data    chan []byte // output pumped from the pty, closed when reading it fails
+	banner  chan []byte // shown once the program has exited
+	pending []byte      // read but not yet passed on
+	shown   bool        // the banner has been passed on
+
+	lock      sync.Mutex
+	exited    bool
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// holdProcess waits for the key pressed to close the terminal once its program has exited
+type holdProcess struct {
+	platform.Process
+	pty *holdPty
+}
+
+// hold wraps a pty and the program running on it so the terminal stays open after the program exits
+func hold(pty platform.Pty, process platform.Process) (*holdPty, *holdProcess) {
+	h := &holdPty{
+		Pty:    pty,
+		data:   make(chan []byte),
+		banner: make(chan []byte, 1),
+		closed: make(chan struct{}),
+	}
+	go h.pump()
+	return h, &holdProcess{Process: process, pty: h}
+}
+
+// Wait waits for the program to exit and then for the terminal to be closed, returning what waiting for the
+// program returned
+func (p *holdProcess) Wait() error {
+	err := p.Process.Wait()
+	p.pty.exit(err)
+	<-p.pty.closed
+	return err
+}
+
+func (h *holdPty) pump() {
+	for {
+		b := make([]byte, 4096)
+		n, err := h.Pty.Read(b)
+		if n > 0 {
+			select {
+			case h.data <- b[:n]:
+			case <-h.closed:
+				return
+			}
+		}
+		if err != nil {
+			close(h.data)
+			return
+		}
+	}
+}
+
+// exit shows how the program exited, turning off the modes it may have left on which would report to it
+// without a key being pressed
+func (h *holdPty) exit(err error) {
+	h.lock.Lock()
+	h.exited = true
+	h.lock.Unlock()
+
+	message := "Process exited"
+	switch status := platform.ExitStatus(err); {
+	case status >= 0:
+		message = fmt.Sprintf("Process exited with status %d", status)
+	case err != nil:
+		message = fmt.Sprintf("Process exited: %s", err)
+	}
+	h.banner <- []byte(fmt.Sprintf(reportingOff+"\x1b[0m\r\n\x1b[7m %s — press any key to close \x1b[0m", message))
+}
+
+func (h *holdPty) Read(b []byte) (int, error) {
+	for len(h.pending) == 0 {
+		if h.shown {
+			<-h.closed
+			return 0, io.EOF
+		}
+		select {
+		case data, ok := <-h.data:
+			if ok {
+				h.pending = data
+			} else {
+				h.data = nil // wait for the banner
+			}
+		case banner := <-h.banner:
+			h.pending = append(h.flush(), banner...)
+			h.shown = true
+		case <-h.closed:
+			return 0, io.EOF
+		}
+	}
+	n := copy(b, h.pending)
+	h.pending = h.pending[n:]
+	return n, nil
+}
+
+// flush returns the output still arriving after the program exited, which is all there once the pty has been
+// quiet for a moment
+func (h *holdPty) flush() []byte {
+	var output []byte
+	for h.data != nil {
+		select {
+		case data, ok := <-h.data:
+			if !ok {
+				h.data = nil
+			}
+			output = append(output, data...)
+		case <-time.After(holdSettleTime):
+			return output
+		}
+	}
+	return output
+}
+
+// Write passes input on to the program, or closes the terminal once it has exited
+func (h *holdPty) Write(b []byte) (int, error) {
+	h.lock.Lock()
+	exited := h.exited
+	h.lock.Unlock()
+	if exited {
+		return len(b), h.Close()
+	}
+	return h.Pty.Write(b)
+}
+
+func (h *holdPty) Close() error {
+	var err error
+	h.closeOnce.Do(func() {
+		close(h.closed)
+		err = h.Pty.Close()
+	})
+	return err
+}
@@ -5,10 +5,14 @@ import (
 	"os"
 	"runtime"
 
+	"github.com/liamg/aminal/config"
 	"github.com/liamg/aminal/gui"
 	"github.com/liamg/aminal/platform"
+	"github.com/liamg/aminal/session"
+	"github.com/liamg/aminal/share"
 	"github.com/liamg/aminal/terminal"
 	"github.com/riywo/loginshell"
+	"go.uber.org/zap"
 )
 
 type callback func(terminal *terminal.Terminal, g *gui.GUI)
@@ -30,48 +34,96 @@ func initialize(unitTestfunc callback) {
 	}
 	defer logger.Sync()
 
-	logger.Infof("Allocating pty...")
-
-	pty, err := platform.NewPty(80, 25)
+	shell, err := getShell(conf)
 	if err != nil {
-		logger.Fatalf("Failed to allocate pty: %s", err)
+		logger.Fatalf("Failed to ascertain your shell: %s", err)
 	}
+	setShellEnv(conf)
 
-	shellStr := conf.Shell
-	if shellStr == "" {
-		loginShell, err := loginshell.Shell()
+	// a window attaching to the daemon shows the sessions left without one, or starts a new one if there are none
+	var detached []session.Info
+	if conf.Daemon.Attach && conf.View == "" && conf.Connect == "" {
+		if err := startDaemon(conf); err != nil {
+			logger.Fatalf("Failed to start the daemon: %s", err)
+		}
+		socket, err := daemonSocket(conf)
+		if err != nil {
+			logger.Fatalf("Failed to find the daemon: %s", err)
+		}
+		sessions, err := session.List(socket)
 		if err != nil {
-			logger.Fatalf("Failed to ascertain your shell: %s", err)
+			logger.Errorf("Failed to list the daemon's sessions: %s", err)
 		}
-		shellStr = loginShell
+		for _, info := range sessions {
+			if !info.Attached {
+				detached = append(detached, info)
+			}
+		}
+	}
+	// a startup command gets a session of its own, with the detached sessions in tabs beside it
+	first := session.Info{}
+	if len(detached) > 0 && len(conf.Command) == 0 {
+		first, detached = detached[0], detached[1:]
 	}
 
-	os.Setenv("TERM", "xterm-256color") // controversial! easier than installing terminfo everywhere, but obviously going to be slightly different to xterm functionality, so we'll see...
-	os.Setenv("COLORTERM", "truecolor")
+	logger.Infof("Allocating pty...")
 
-	guestProcess, err := pty.CreateGuestProcess(shellStr)
+	var pty platform.Pty
+	var guestProcess platform.Process
+	if conf.View != "" {
+		viewer := share.NewViewer(conf.View)
+		pty = viewer
+		guestProcess, err = viewer.CreateGuestProcess("", "")
+	} else if conf.Connect != "" {
+		pty, guestProcess, err = openStream(conf)
+	} else {
+		dir, command := "", conf.Command
+		if profile, ok := conf.ProfileNamed(conf.Profile); ok && conf.Profile != "" && first.ID == "" {
+			dir, command = profile.Dir(), profileCommand(conf, shell, profile, command)
+		}
+		pty, guestProcess, err = openSession(conf, logger, shell, dir, first.ID, command)
+	}
 	if err != nil {
-		pty.Close()
-		logger.Fatalf("Failed to start your shell: %s", err)
+		logger.Fatalf("Cannot start: %s", err)
 	}
 	defer guestProcess.Close()
 
 	logger.Infof("Creating terminal...")
-	terminal := terminal.New(pty, logger, conf)
+	terminal := terminal.New(pty, logger, terminalOptions(conf))
+	terminal.SetTitle(first.Title)
+	if conf.Connect != "" {
+		terminal.SetTitle(conf.Connect)
+	}
+	if conf.ScrollbackSpill {
+		if err := terminal.SpillScrollback(conf.ScrollbackSpillDir); err != nil {
+			logger.Errorf("Failed to create scrollback file, old lines will be discarded: %s", err)
+		}
+		defer terminal.CloseSpill()
+	}
 
 	g, err := gui.New(conf, terminal, logger)
 	if err != nil {
 		logger.Fatalf("Cannot start: %s", err)
 	}
+	g.SetSessionFactory(sessionFactory(conf, logger, shell, g))
+	for _, info := range detached {
+		t, err := newSession(conf, logger, shell, "", info.ID, nil, nil, g)
+		if err != nil {
+			logger.Errorf("Failed to attach to session %s: %s", info.ID, err)
+			continue
+		}
+		t.SetTitle(info.Title)
+		g.AddTab(t)
+	}
 
 	if unitTestfunc != nil {
 		go unitTestfunc(terminal, g)
 	} else {
 		go func() {
 			if err := guestProcess.Wait(); err != nil {
-				logger.Fatalf("Failed to wait for guest process: %s", err)
+				logger.Errorf("Failed to wait for guest process: %s", err)
 			}
-			g.Close()
+			g.CloseTerminal(terminal)
 		}()
 	}
 
@@ -79,3 +131,167 @@ func initialize(unitTestfunc callback) {
 		logger.Fatalf("Render error: %s", err)
 	}
 }
+
+func sessionFactory(conf *config.Config, logger *zap.SugaredLogger, shell []string, g *gui.GUI) gui.SessionFactory {
+	return func(dir string, profile *config.Profile, command ...string) (*terminal.Terminal, error) {
+		return newSession(conf, logger, shell, dir, "", command, profile, g)
+	}
+}
+
+// newSession starts another shell, or the command if one is given, in dir for a new tab, or attaches to the
+// daemon's session with the id if it isn't empty. With a profile, its shell is started in its directory if none
+// is given, with its environment. The tab is closed when the shell exits.
+func newSession(conf *config.Config, logger *zap.SugaredLogger, shell []string, dir string, id string, command []string, profile *config.Profile, g *gui.GUI) (*terminal.Terminal, error) {
+	if profile != nil {
+		if dir == "" {
+			dir = profile.Dir()
+		}
+		command = profileCommand(conf, shell, *profile, command)
+	}
+	if info, err := os.Stat(dir); dir != "" && (err != nil || !info.IsDir()) {
+		logger.Infof("Starting the new shell in Aminal's own directory, as %s isn't a directory", dir)
+		dir = ""
+	}
+
+	pty, guestProcess, err := openSession(conf, logger, shell, dir, id, command)
+	if err != nil {
+		return nil, err
+	}
+
+	t := terminal.New(pty, logger, terminalOptions(conf))
+	if conf.ScrollbackSpill {
+		if err := t.SpillScrollback(conf.ScrollbackSpillDir); err != nil {
+			logger.Errorf("Failed to create scrollback file, old lines will be discarded: %s", err)
+		}
+	}
+
+	go func() {
+		if err := guestProcess.Wait(); err != nil {
+			logger.Errorf("Failed to wait for guest process: %s", err)
+		}
+		guestProcess.Close()
+		t.CloseSpill()
+		g.CloseTerminal(t)
+	}()
+
+	return t, nil
+}
+
+// openSession starts the shell, or the command if one is given, in dir on a pty of its own, or in the daemon if
+// windows attach to it, where the session with the id is attached to instead if it isn't empty. With restart, the
+// program is started again when it crashes, and with hold, the terminal stays open after it exits.
+func openSession(conf *config.Config, logger *zap.SugaredLogger, shell []string, dir string, id string, command []string) (platform.Pty, platform.Process, error) {
+	pty, process, err := startSession(conf, shell, dir, id, command)
+	if err != nil {
+		return nil, nil, err
+	}
+	if conf.Restart {
+		pty, process = supervise(pty, process, func() (platform.Pty, platform.Process, error) {
+			return startSession(conf, shell, dir, "", command)
+		}, logger)
+	}
+	if conf.Hold {
+		pty, process = hold(pty, process)
+	}
+	return pty, process, nil
+}
+
+// profileCommand returns the command started for a tab with the profile: its shell, or the shell from the config
+// if it hasn't one, unless a command is given, with the profile's environment variables set
+func profileCommand(conf *config.Config, shell []string, profile config.Profile, command []string) []string {
+	if len(command) == 0 {
+		command = shell
+		if profile.Shell != "" {
+			command = profile.ShellCommand(conf, "")
+		}
+	}
+	return platform.WithEnv(command, profile.EnvList())
+}
+
+func startSession(conf *config.Config, shell []string, dir string, id string, command []string) (platform.Pty, platform.Process, error) {
+	if len(command) == 0 {
+		command = shell
+	}
+
+	if conf.Daemon.Attach {
+		socket, err := daemonSocket(conf)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to find the daemon: %s", err)
+		}
+		pty := session.NewPty(socket)
+		if id != "" {
+			process, err := pty.Attach(id)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to attach to the session: %s", err)
+			}
+			return pty, process, nil
+		}
+		// the daemon exits with its last session, which may have been the last shell in another window
+		if err := startDaemon(conf); err != nil {
+			return nil, nil, fmt.Errorf("failed to start the daemon: %s", err)
+		}
+		process, err := pty.CreateGuestCommand(command, dir)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to start %s in the daemon: %s", command[0], err)
+		}
+		return pty, process, nil
+	}
+
+	pty, err := platform.NewPty(80, 25)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to allocate pty: %s", err)
+	}
+
+	process, err := platform.StartCommand(pty, command, dir)
+	if err != nil {
+		pty.Close()
+		return nil, nil, fmt.Errorf("failed to start %s: %s", command[0], err)
+	}
+	return pty, process, nil
+}
+
+// openStream connects to the serial port, TCP address or command given with --connect, in place of a shell
+func openStream(conf *config.Config) (platform.Pty, platform.Process, error) {
+	options, err := platform.ParseSerialOptions(conf.Serial.Baud, conf.Serial.Frame)
+	if err != nil {
+		return nil, nil, err
+	}
+	stream, err := platform.OpenStream(conf.Connect, options)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to %s: %s", conf.Connect, err)
+	}
+	pty := platform.NewStreamPty(stream)
+	process, err := pty.CreateGuestProcess("", "")
+	return pty, process, err
+}
+
+// getShell returns the program and arguments starting the shell from the config, or the user's login shell
+func getShell(conf *config.Config) ([]string, error) {
+	if conf.Shell != "" {
+		return conf.ShellCommand(""), nil
+	}
+	loginShell, err := loginshell.Shell()
+	if err != nil {
+		return nil, err
+	}
+	return conf.ShellCommand(loginShell), nil
+}
+
+// setShellEnv sets the environment shells are started with
+func setShellEnv(conf *config.Config) {
+	// xterm-256color by default, as hosts we ssh to are more likely to know it than aminal's own entry
+	os.Setenv("TERM", conf.Term)
+	os.Setenv("COLORTERM", "truecolor")
+	if conf.Control.Socket != "" {
+		os.Setenv("AMINAL_CONTROL_SOCKET", conf.Control.Socket)
+	}
+
+	// expanded before any are set, so each refers to Aminal's environment rather than to one another
+	env := map[string]string{}
+	for name, value := range conf.Env {
+		env[name] = os.ExpandEnv(value)
+	}
+	for name, value := range env {
+		os.Setenv(name, value)
+	}
+}
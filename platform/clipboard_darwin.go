@@ -0,0 +1,28 @@
+// +build darwin
+
+package platform
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// pasteboardClasses are the AppleScript classes of the rich formats, by MIME type
+var pasteboardClasses = map[string]string{
+	"text/html": "HTML",
+	"text/rtf":  "RTF ",
+}
+
+// CopyRich puts text in a rich format, given by its MIME type, e.g. text/html, on the clipboard along with the
+// plain text, with AppleScript
+func CopyRich(mimeType string, rich string, plain string) error {
+	class, ok := pasteboardClasses[mimeType]
+	if !ok {
+		return fmt.Errorf("Unsupported clipboard format %s", mimeType)
+	}
+	// the script is read from stdin, as it can be too long for the command line
+	cmd := exec.Command("osascript")
+	cmd.Stdin = strings.NewReader(fmt.Sprintf("set the clipboard to {«class utf8»:%s, «class %s»:«data %s%X»}", appleScriptString(plain), class, class, []byte(rich)))
+	return cmd.Run()
+}
@@ -0,0 +1,22 @@
+// +build linux freebsd netbsd openbsd
+
+package platform
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// CopyRich puts text in a rich format, given by its MIME type, e.g. text/html, on the clipboard, with wl-copy
+// under Wayland or xclip under X11. They only offer the one format, so the plain text isn't used, and callers
+// should put it on the clipboard themselves if this fails, e.g. because neither is installed.
+func CopyRich(mimeType string, rich string, plain string) error {
+	cmd := exec.Command("xclip", "-selection", "clipboard", "-t", mimeType)
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		cmd = exec.Command("wl-copy", "--type", mimeType)
+	}
+	// both stay in the background to serve the clipboard, so their output mustn't be waited for
+	cmd.Stdin = strings.NewReader(rich)
+	return cmd.Run()
+}
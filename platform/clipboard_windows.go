@@ -0,0 +1,57 @@
+// +build windows
+
+package platform
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+)
+
+// copyRichScript puts the rich and plain text it's given as JSON on stdin on the clipboard, through PowerShell as
+// GLFW only handles plain text
+const copyRichScript = `
+Add-Type -AssemblyName System.Windows.Forms
+[Console]::InputEncoding = [Text.Encoding]::UTF8
+$in = [Console]::In.ReadToEnd() | ConvertFrom-Json
+$data = New-Object Windows.Forms.DataObject
+$data.SetData($in.format, $in.rich)
+$data.SetData([Windows.Forms.DataFormats]::UnicodeText, $in.plain)
+[Windows.Forms.Clipboard]::SetDataObject($data, $true)
+`
+
+// CopyRich puts text in a rich format, given by its MIME type, e.g. text/html, on the clipboard along with the
+// plain text
+func CopyRich(mimeType string, rich string, plain string) error {
+	var format string
+	switch mimeType {
+	case "text/html":
+		format, rich = "HTML Format", cfHTML(rich)
+	case "text/rtf":
+		format = "Rich Text Format"
+	default:
+		return fmt.Errorf("Unsupported clipboard format %s", mimeType)
+	}
+	input, err := json.Marshal(map[string]string{"format": format, "rich": rich, "plain": plain})
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Sta", "-Command", copyRichScript)
+	cmd.Stdin = bytes.NewReader(input)
+	return cmd.Run()
+}
+
+// cfHTML wraps an HTML fragment in the header of the Windows HTML clipboard format, which gives the byte offsets
+// of the document and of the fragment within it
+func cfHTML(fragment string) string {
+	const header = "Version:0.9\r\nStartHTML:%010d\r\nEndHTML:%010d\r\nStartFragment:%010d\r\nEndFragment:%010d\r\n"
+	const before = "<html><body><!--StartFragment-->"
+	const after = "<!--EndFragment--></body></html>"
+
+	startHTML := len(fmt.Sprintf(header, 0, 0, 0, 0))
+	startFragment := startHTML + len(before)
+	endFragment := startFragment + len(fragment)
+	endHTML := endFragment + len(after)
+	return fmt.Sprintf(header, startHTML, endHTML, startFragment, endFragment) + before + fragment + after
+}
@@ -3,9 +3,38 @@
 package platform
 
 import (
+	"fmt"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"strings"
 )
 
 func LaunchTarget(target string) error {
 	return exec.Command("open", target).Run()
 }
+
+// Notify shows a notification in the Notification Center with AppleScript
+func Notify(title string, body string) error {
+	script := fmt.Sprintf("display notification %s with title %s", appleScriptString(body), appleScriptString(title))
+	return exec.Command("osascript", "-e", script).Run()
+}
+
+// appleScriptString quotes s for use in AppleScript
+func appleScriptString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+func shellCommand(command string) *exec.Cmd {
+	return exec.Command("sh", "-c", command)
+}
+
+// FontFiles returns the fonts installed in the system and user font directories
+func FontFiles() []string {
+	home, _ := os.UserHomeDir()
+	return findFontFiles(
+		"/System/Library/Fonts",
+		"/Library/Fonts",
+		filepath.Join(home, "Library/Fonts"),
+	)
+}
@@ -0,0 +1,13 @@
+// +build !windows
+
+package platform
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// Detach makes cmd run in a session of its own, so it carries on when the terminal it was started from closes
+func Detach(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+}
@@ -0,0 +1,13 @@
+// +build !windows
+
+package platform
+
+// WithEnv returns a command running the program and arguments in args with the environment variables in env,
+// given as NAME=value, added to Aminal's own, so that they're kept when it's restarted or run by the daemon
+func WithEnv(args []string, env []string) []string {
+	if len(env) == 0 {
+		return args
+	}
+	command := append([]string{"env"}, env...)
+	return append(command, args...)
+}
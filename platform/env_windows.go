@@ -0,0 +1,23 @@
+package platform
+
+import "strings"
+
+// WithEnv returns a command running the program and arguments in args with the environment variables in env,
+// given as NAME=value, added to Aminal's own, so that they're kept when it's restarted or run by the daemon. On
+// Windows they're set by cmd.exe before it runs the program.
+func WithEnv(args []string, env []string) []string {
+	if len(env) == 0 {
+		return args
+	}
+	command := []string{"cmd.exe", "/c"}
+	for _, variable := range env {
+		// cmd.exe keeps the space before && in the value, unless the variable is quoted, which it is when it
+		// has spaces in it
+		if strings.ContainsAny(variable, " \t") {
+			command = append(command, "set", variable, "&&")
+		} else {
+			command = append(command, "set", variable+"&&")
+		}
+	}
+	return append(command, args...)
+}
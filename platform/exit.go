@@ -0,0 +1,54 @@
+package platform
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"syscall"
+)
+
+// ExitError is returned by a Process's Wait when the program exited with a non-zero status, or was killed
+type ExitError struct {
+	Status int    // -1 if the program was killed
+	Signal string // what killed it, if it was: a signal, or on Windows an exception
+}
+
+func (e *ExitError) Error() string {
+	if e.Signal != "" {
+		return "signal: " + e.Signal
+	}
+	return fmt.Sprintf("exit status %d", e.Status)
+}
+
+// ExitStatus returns the status a program exited with, given what its Process's Wait returned, or -1 if it was
+// killed or waiting for it failed
+func ExitStatus(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.Status
+	}
+	var cmdErr *exec.ExitError
+	if errors.As(err, &cmdErr) {
+		return cmdErr.ExitCode()
+	}
+	return -1
+}
+
+// KilledBy returns what killed a program, given what its Process's Wait returned, or an empty string if it
+// exited by itself or waiting for it failed
+func KilledBy(err error) string {
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.Signal
+	}
+	var cmdErr *exec.ExitError
+	if errors.As(err, &cmdErr) {
+		if status, ok := cmdErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+			return status.Signal().String()
+		}
+	}
+	return ""
+}
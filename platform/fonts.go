@@ -0,0 +1,27 @@
+package platform
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// isFontFile returns true for the font formats Aminal can load
+func isFontFile(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".ttf" || ext == ".otf"
+}
+
+// findFontFiles searches dirs and their subdirectories for fonts, ignoring any which don't exist
+func findFontFiles(dirs ...string) []string {
+	files := []string{}
+	for _, dir := range dirs {
+		_ = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err == nil && !info.IsDir() && isFontFile(path) {
+				files = append(files, path)
+			}
+			return nil
+		})
+	}
+	return files
+}
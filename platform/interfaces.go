@@ -1,6 +1,7 @@
 package platform
 
 import (
+	"errors"
 	"io"
 )
 
@@ -17,6 +18,27 @@ type Pty interface {
 	io.ReadWriteCloser
 
 	Resize(x int, y int) error
-	CreateGuestProcess(imagePath string) (Process, error)
+	CreateGuestProcess(imagePath string, dir string) (Process, error) // started in dir, or the current directory if it's empty
 	GetPlatformDependentSettings() PlatformDependentSettings
 }
+
+// CommandPty is a Pty which can run a program with arguments rather than only a shell
+type CommandPty interface {
+	Pty
+
+	CreateGuestCommand(args []string, dir string) (Process, error) // started in dir, or the current directory if it's empty
+}
+
+// StartCommand runs args on pty, which must be a CommandPty unless args is a program alone
+func StartCommand(pty Pty, args []string, dir string) (Process, error) {
+	if len(args) == 0 {
+		return nil, errors.New("No command to run")
+	}
+	if cp, ok := pty.(CommandPty); ok {
+		return cp.CreateGuestCommand(args, dir)
+	}
+	if len(args) > 1 {
+		return nil, errors.New("The pty can't pass arguments to the program")
+	}
+	return pty.CreateGuestProcess(args[0], dir)
+}
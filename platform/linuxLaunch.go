@@ -3,9 +3,41 @@
 package platform
 
 import (
+	"os"
 	"os/exec"
+	"path/filepath"
+	"strings"
 )
 
 func LaunchTarget(target string) error {
 	return exec.Command("xdg-open", target).Run()
 }
+
+// Notify shows a desktop notification with notify-send
+func Notify(title string, body string) error {
+	return exec.Command("notify-send", "--app-name=Aminal", title, body).Run()
+}
+
+func shellCommand(command string) *exec.Cmd {
+	return exec.Command("sh", "-c", command)
+}
+
+// FontFiles asks fontconfig for the installed fonts, falling back to searching the usual directories if it isn't available
+func FontFiles() []string {
+	if output, err := exec.Command("fc-list", "--format", "%{file}\n").Output(); err == nil {
+		files := []string{}
+		for _, file := range strings.Split(string(output), "\n") {
+			if isFontFile(file) {
+				files = append(files, file)
+			}
+		}
+		return files
+	}
+	home, _ := os.UserHomeDir()
+	return findFontFiles(
+		"/usr/share/fonts",
+		"/usr/local/share/fonts",
+		filepath.Join(home, ".local/share/fonts"),
+		filepath.Join(home, ".fonts"),
+	)
+}
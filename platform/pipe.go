@@ -0,0 +1,21 @@
+package platform
+
+import (
+	"os"
+	"strings"
+)
+
+// PipeToCommand runs command with the system shell, giving it input on stdin and in the AMINAL_SELECTION environment variable
+func PipeToCommand(command string, input string) error {
+	cmd := shellCommand(command)
+	cmd.Stdin = strings.NewReader(input)
+	cmd.Env = append(os.Environ(), "AMINAL_SELECTION="+input)
+	return cmd.Run()
+}
+
+// RunCommand runs command with the system shell, adding the given NAME=value variables to its environment
+func RunCommand(command string, env ...string) error {
+	cmd := shellCommand(command)
+	cmd.Env = append(os.Environ(), env...)
+	return cmd.Run()
+}
@@ -0,0 +1,14 @@
+// +build !windows
+
+package platform
+
+import "strings"
+
+// ShellQuote quotes s so the shell takes it as a single word, e.g. a path with spaces in it. Words which don't need
+// quoting are left as they are.
+func ShellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./,:=+@%") == "" {
+		return s
+	}
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
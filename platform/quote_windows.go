@@ -0,0 +1,13 @@
+package platform
+
+import "strings"
+
+// ShellQuote quotes s so the shell takes it as a single word, e.g. a path with spaces in it. Words which don't need
+// quoting are left as they are. Double quotes within it are escaped with backslashes, as programs' command line
+// parsing expects.
+func ShellQuote(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t&()[]{}^=;!'+,`~%\"") {
+		return s
+	}
+	return `"` + strings.Replace(s, `"`, `\"`, -1) + `"`
+}
@@ -0,0 +1,45 @@
+package platform
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// SerialOptions sets up a serial port
+type SerialOptions struct {
+	Baud     int
+	DataBits int  // 5 to 8
+	Parity   byte // 'N'one, 'E'ven or 'O'dd
+	StopBits int  // 1 or 2
+}
+
+// ParseSerialOptions reads the baud rate and a frame given like "8N1": data bits, parity and stop bits
+func ParseSerialOptions(baud int, frame string) (SerialOptions, error) {
+	options := SerialOptions{Baud: baud, DataBits: 8, Parity: 'N', StopBits: 1}
+	if baud <= 0 {
+		return options, fmt.Errorf("Invalid baud rate: %d", baud)
+	}
+	if frame == "" {
+		return options, nil
+	}
+	if len(frame) != 3 {
+		return options, fmt.Errorf("Invalid serial frame %q, expected e.g. 8N1", frame)
+	}
+	dataBits, err := strconv.Atoi(frame[:1])
+	if err != nil || dataBits < 5 || dataBits > 8 {
+		return options, fmt.Errorf("Invalid data bits in serial frame %q", frame)
+	}
+	parity := frame[1]
+	if parity >= 'a' {
+		parity -= 'a' - 'A'
+	}
+	if parity != 'N' && parity != 'E' && parity != 'O' {
+		return options, fmt.Errorf("Invalid parity in serial frame %q, expected N, E or O", frame)
+	}
+	stopBits, err := strconv.Atoi(frame[2:])
+	if err != nil || stopBits < 1 || stopBits > 2 {
+		return options, fmt.Errorf("Invalid stop bits in serial frame %q", frame)
+	}
+	options.DataBits, options.Parity, options.StopBits = dataBits, parity, stopBits
+	return options, nil
+}
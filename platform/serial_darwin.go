@@ -0,0 +1,37 @@
+package platform
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+var dataBits = map[int]uint64{5: unix.CS5, 6: unix.CS6, 7: unix.CS7, 8: unix.CS8}
+
+// configureSerial makes the port a raw line with the options' speed and framing, and no flow control
+func configureSerial(fd int, options SerialOptions) error {
+	t, err := unix.IoctlGetTermios(fd, unix.TIOCGETA)
+	if err != nil {
+		return errNotSerial
+	}
+
+	t.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP | unix.INLCR | unix.IGNCR | unix.ICRNL | unix.IXON | unix.IXOFF
+	t.Oflag &^= unix.OPOST
+	t.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+	t.Cflag &^= unix.CSIZE | unix.PARENB | unix.PARODD | unix.CSTOPB | unix.CRTSCTS
+	t.Cflag |= dataBits[options.DataBits] | unix.CREAD | unix.CLOCAL
+	switch options.Parity {
+	case 'E':
+		t.Cflag |= unix.PARENB
+	case 'O':
+		t.Cflag |= unix.PARENB | unix.PARODD
+	}
+	if options.StopBits == 2 {
+		t.Cflag |= unix.CSTOPB
+	}
+	// speeds are given as they are, rather than as constants
+	t.Ispeed = uint64(options.Baud)
+	t.Ospeed = uint64(options.Baud)
+	t.Cc[unix.VMIN] = 1
+	t.Cc[unix.VTIME] = 0
+
+	return unix.IoctlSetTermios(fd, unix.TIOCSETA, t)
+}
@@ -0,0 +1,13 @@
+// +build !linux,!darwin
+
+package platform
+
+import (
+	"errors"
+	"io"
+)
+
+// OpenSerial isn't available on this platform yet
+func OpenSerial(device string, options SerialOptions) (io.ReadWriteCloser, error) {
+	return nil, errors.New("Serial ports aren't supported on this platform yet, try a tcp: target to a serial server")
+}
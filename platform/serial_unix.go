@@ -0,0 +1,39 @@
+// +build linux darwin
+
+package platform
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+var errNotSerial = errors.New("Not a serial port")
+
+// OpenSerial opens a serial port as a raw line, with no flow control
+func OpenSerial(device string, options SerialOptions) (io.ReadWriteCloser, error) {
+	// opened non-blocking so Go's poller waits for input, which lets closing the port interrupt a read
+	port, err := os.OpenFile(device, os.O_RDWR|unix.O_NOCTTY|unix.O_NONBLOCK, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	// Fd would put the port back into blocking mode
+	raw, err := port.SyscallConn()
+	if err == nil {
+		controlErr := raw.Control(func(fd uintptr) {
+			err = configureSerial(int(fd), options)
+		})
+		if controlErr != nil {
+			err = controlErr
+		}
+	}
+	if err != nil {
+		port.Close()
+		return nil, fmt.Errorf("Failed to set up %s: %s", device, err)
+	}
+	return port, nil
+}
@@ -1,6 +1,20 @@
 package platform
 
+import "runtime"
+
 // PlatformDependentSettings Settings specific to the platform
 type PlatformDependentSettings struct {
 	OSCTerminators map[rune]struct{}
 }
+
+// DefaultSettings returns the settings of the ptys NewPty creates on this platform
+func DefaultSettings() PlatformDependentSettings {
+	if runtime.GOOS == "windows" {
+		return PlatformDependentSettings{
+			OSCTerminators: map[rune]struct{}{0x00: {}, 0x07: {}},
+		}
+	}
+	return PlatformDependentSettings{
+		OSCTerminators: map[rune]struct{}{0x07: {}, 0x5c: {}},
+	}
+}
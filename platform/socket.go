@@ -0,0 +1,32 @@
+package platform
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"time"
+)
+
+// SocketInUse returns true if a process of the user's is listening on the unix socket
+func SocketInUse(socket string, timeout time.Duration) bool {
+	conn, err := DialSocket(socket, timeout)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+// ListenPrivateSocket listens on the unix socket, which only the user can connect to, replacing one left behind
+// by a process which is no longer listening on it. It fails if one still is.
+func ListenPrivateSocket(socket string, timeout time.Duration) (net.Listener, error) {
+	if SocketInUse(socket, timeout) {
+		return nil, fmt.Errorf("Something is already listening on %s", socket)
+	}
+	os.Remove(socket)
+	listener, err := ListenSocket(socket)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to listen on %s: %s", socket, err)
+	}
+	return listener, nil
+}
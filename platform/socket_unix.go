@@ -0,0 +1,73 @@
+// +build !windows
+
+package platform
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+	"time"
+)
+
+// PrivateDir creates dir so that only the user can use it, unless it's there already, and checks that it's a
+// directory rather than a link, that it belongs to the user and that no one else can use it
+func PrivateDir(dir string) error {
+	if err := os.Mkdir(dir, 0700); err != nil && !os.IsExist(err) {
+		return err
+	}
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s isn't a directory", dir)
+	}
+	if !ownedByUser(info) {
+		return fmt.Errorf("%s belongs to another user", dir)
+	}
+	if info.Mode().Perm()&0077 != 0 {
+		return fmt.Errorf("%s can be used by other users", dir)
+	}
+	return nil
+}
+
+// DialSocket connects to the unix socket, after checking that the socket belongs to the user, and checks that
+// the process listening on it is the user's too, so that another user can't stand in for it
+func DialSocket(socket string, timeout time.Duration) (net.Conn, error) {
+	info, err := os.Lstat(socket)
+	if err != nil {
+		return nil, err
+	}
+	if info.Mode()&os.ModeSocket == 0 || !ownedByUser(info) {
+		return nil, fmt.Errorf("%s isn't a socket of yours", socket)
+	}
+	conn, err := net.DialTimeout("unix", socket, timeout)
+	if err != nil {
+		return nil, err
+	}
+	uid, err := peerUID(conn.(*net.UnixConn))
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("Failed to check who is listening on %s: %s", socket, err)
+	}
+	if uid != os.Getuid() {
+		conn.Close()
+		return nil, fmt.Errorf("Another user is listening on %s", socket)
+	}
+	return conn, nil
+}
+
+// ListenSocket listens on the unix socket, which is created so that only the user can connect to it
+func ListenSocket(socket string) (net.Listener, error) {
+	// the socket takes its permissions from the umask, so it's never there for others to connect to in between
+	// being created and changed. The umask is the process's, but nothing else creates files in the meantime.
+	umask := syscall.Umask(0177)
+	defer syscall.Umask(umask)
+	return net.Listen("unix", socket)
+}
+
+func ownedByUser(info os.FileInfo) bool {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	return ok && int(stat.Uid) == os.Getuid()
+}
@@ -0,0 +1,23 @@
+package platform
+
+import (
+	"net"
+	"os"
+	"time"
+)
+
+// PrivateDir creates dir unless it's there already. On Windows it's private by being in the user's own temporary
+// directory.
+func PrivateDir(dir string) error {
+	return os.MkdirAll(dir, 0700)
+}
+
+// DialSocket connects to the unix socket
+func DialSocket(socket string, timeout time.Duration) (net.Conn, error) {
+	return net.DialTimeout("unix", socket, timeout)
+}
+
+// ListenSocket listens on the unix socket
+func ListenSocket(socket string) (net.Listener, error) {
+	return net.Listen("unix", socket)
+}
@@ -0,0 +1,144 @@
+package platform
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// dialTimeout is how long a TCP connection has to be made
+const dialTimeout = 10 * time.Second
+
+// StreamPty runs a terminal on a byte stream other than a pty, such as a serial port or a TCP connection. There's no
+// program started on it, so the process CreateGuestProcess returns only finishes when the stream ends or is closed,
+// and there's nothing to tell about resizes.
+type StreamPty struct {
+	stream io.ReadWriteCloser
+	ended  chan struct{}
+	once   sync.Once
+}
+
+// NewStreamPty creates a pty for the terminal to read from and write to stream
+func NewStreamPty(stream io.ReadWriteCloser) *StreamPty {
+	return &StreamPty{
+		stream: stream,
+		ended:  make(chan struct{}),
+	}
+}
+
+func (p *StreamPty) end() {
+	p.once.Do(func() {
+		close(p.ended)
+	})
+}
+
+func (p *StreamPty) Read(b []byte) (int, error) {
+	n, err := p.stream.Read(b)
+	if err != nil {
+		p.end()
+	}
+	return n, err
+}
+
+func (p *StreamPty) Write(b []byte) (int, error) {
+	return p.stream.Write(b)
+}
+
+func (p *StreamPty) Close() error {
+	defer p.end()
+	return p.stream.Close()
+}
+
+func (p *StreamPty) Resize(x int, y int) error {
+	return nil
+}
+
+// CreateGuestProcess returns the stream as a process, ignoring the arguments
+func (p *StreamPty) CreateGuestProcess(imagePath string, dir string) (Process, error) {
+	return &streamProcess{pty: p}, nil
+}
+
+func (p *StreamPty) GetPlatformDependentSettings() PlatformDependentSettings {
+	return DefaultSettings()
+}
+
+type streamProcess struct {
+	pty *StreamPty
+}
+
+func (p *streamProcess) Wait() error {
+	<-p.pty.ended
+	return nil
+}
+
+func (p *streamProcess) Close() error {
+	return p.pty.Close()
+}
+
+// OpenStream opens the target of a terminal which isn't running a shell:
+//
+//	tcp:host:port    a TCP connection, e.g. to a serial server or a device's telnet-less console
+//	exec:command     the stdin and stdout of a command run with the system shell, with stderr mixed in
+//	serial:device    a serial port, set up with the options
+//	device           a serial port, as with serial:
+func OpenStream(target string, serial SerialOptions) (io.ReadWriteCloser, error) {
+	switch {
+	case strings.HasPrefix(target, "tcp:"):
+		address := strings.TrimPrefix(strings.TrimPrefix(target, "tcp:"), "//")
+		return net.DialTimeout("tcp", address, dialTimeout)
+	case strings.HasPrefix(target, "exec:"):
+		return startPipes(strings.TrimPrefix(target, "exec:"))
+	default:
+		return OpenSerial(strings.TrimPrefix(target, "serial:"), serial)
+	}
+}
+
+// pipes is a command's stdin and stdout
+type pipes struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout *os.File
+}
+
+func startPipes(command string) (*pipes, error) {
+	cmd := shellCommand(command)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, output, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	cmd.Stdout = output
+	cmd.Stderr = output
+	if err := cmd.Start(); err != nil {
+		stdout.Close()
+		output.Close()
+		return nil, fmt.Errorf("Failed to run %s: %s", command, err)
+	}
+	// the command has its own copy, so reading ends when it exits
+	output.Close()
+	go cmd.Wait()
+	return &pipes{cmd: cmd, stdin: stdin, stdout: stdout}, nil
+}
+
+func (p *pipes) Read(b []byte) (int, error) {
+	return p.stdout.Read(b)
+}
+
+func (p *pipes) Write(b []byte) (int, error) {
+	return p.stdin.Write(b)
+}
+
+// Close kills the command
+func (p *pipes) Close() error {
+	p.stdin.Close()
+	p.stdout.Close()
+	return p.cmd.Process.Kill()
+}
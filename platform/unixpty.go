@@ -66,14 +66,19 @@ func (p *unixPty) Resize(x, y int) error {
 	return nil
 }
 
-func (p *unixPty) CreateGuestProcess(imagePath string) (Process, error) {
+func (p *unixPty) CreateGuestProcess(imagePath string, dir string) (Process, error) {
+	return p.CreateGuestCommand([]string{imagePath}, dir)
+}
+
+func (p *unixPty) CreateGuestCommand(args []string, dir string) (Process, error) {
 	if p == nil || p.tty == nil {
 		return nil, errors.New("Attempted to create a process on a deallocated pty")
 	}
-	shell := newCmdProc(exec.Command(imagePath))
+	shell := newCmdProc(exec.Command(args[0], args[1:]...))
 	shell.cmd.Stdout = p.tty
 	shell.cmd.Stdin = p.tty
 	shell.cmd.Stderr = p.tty
+	shell.cmd.Dir = dir
 	shell.cmd.SysProcAttr = &syscall.SysProcAttr{Setctty: true, Setsid: true}
 	if err := shell.cmd.Start(); err != nil {
 		return nil, err
@@ -92,10 +97,8 @@ func NewPty(x, y int) (Pty, error) {
 		return nil, err
 	}
 	return &unixPty{
-		pty: innerPty,
-		tty: innerTty,
-		platformDependentSettings: PlatformDependentSettings{
-			OSCTerminators: map[rune]struct{}{0x07: {}, 0x5c: {}},
-		},
+		pty:                       innerPty,
+		tty:                       innerTty,
+		platformDependentSettings: DefaultSettings(),
 	}, nil
 }
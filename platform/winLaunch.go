@@ -3,9 +3,45 @@
 package platform
 
 import (
+	"os"
+	"os/exec"
+	"path/filepath"
+
 	"github.com/MaxRis/w32"
 )
 
 func LaunchTarget(target string) error {
 	return w32.ShellExecute(0, "", target, "", "", w32.SW_SHOW)
 }
+
+// notifyScript shows a toast notification with the title and body from the environment, through PowerShell as
+// toasts are only available to Windows Runtime apps
+const notifyScript = `
+[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] > $null
+$template = [Windows.UI.Notifications.ToastTemplateType]::ToastText02
+$xml = [Windows.UI.Notifications.ToastNotificationManager]::GetTemplateContent($template)
+$text = $xml.GetElementsByTagName('text')
+$text.Item(0).AppendChild($xml.CreateTextNode($env:AMINAL_NOTIFY_TITLE)) > $null
+$text.Item(1).AppendChild($xml.CreateTextNode($env:AMINAL_NOTIFY_BODY)) > $null
+$toast = [Windows.UI.Notifications.ToastNotification]::new($xml)
+[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier('Aminal').Show($toast)
+`
+
+// Notify shows a toast notification
+func Notify(title string, body string) error {
+	cmd := exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", notifyScript)
+	cmd.Env = append(os.Environ(), "AMINAL_NOTIFY_TITLE="+title, "AMINAL_NOTIFY_BODY="+body)
+	return cmd.Run()
+}
+
+func shellCommand(command string) *exec.Cmd {
+	return exec.Command("cmd", "/C", command)
+}
+
+// FontFiles returns the fonts installed for all users and for the current user
+func FontFiles() []string {
+	return findFontFiles(
+		filepath.Join(os.Getenv("WINDIR"), "Fonts"),
+		filepath.Join(os.Getenv("LOCALAPPDATA"), "Microsoft", "Windows", "Fonts"),
+	)
+}
@@ -1,184 +1,125 @@
-// +build windows,cgo
+// +build windows
 
 package platform
 
 import (
 	"errors"
-	"syscall"
-	"time"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"unsafe"
 
 	"github.com/MaxRis/w32"
+	"golang.org/x/sys/windows"
 )
 
-// #include "windows.h"
-//
-//  /*    Until we can specify the platform SDK and target version for Windows.h      *
-//   * without breaking our ability to gracefully display an error message, these     *
-//   * definitions will be copied from the platform SDK headers and made to work.     *
-//   */
-//
-//  typedef HRESULT (* CreatePseudoConsoleProcType)( COORD, HANDLE, HANDLE, DWORD, uintptr_t * );
-//  typedef HRESULT (* ResizePseudoConsoleProcType)( uintptr_t, COORD );
-//  typedef HRESULT (* ClosePseudoConsoleProcType)( uintptr_t );
-//
-//
-//  CreatePseudoConsoleProcType pfnCreatePseudoConsole = NULL;
-//  ResizePseudoConsoleProcType pfnResizePseudoConsole = NULL;
-//  ClosePseudoConsoleProcType pfnClosePseudoConsole = NULL;
-//
-//  HMODULE hLibKernel32_Kern = NULL;
-//
-//  DWORD initPtyKernFuncs()
-//  {
-//      hLibKernel32_Kern = LoadLibrary( "kernel32.dll" );
-//      if( hLibKernel32_Kern == NULL )
-//      {
-//          return -1;
-//      }
-//
-//      pfnCreatePseudoConsole = (CreatePseudoConsoleProcType) GetProcAddress(hLibKernel32_Kern, "CreatePseudoConsole" );
-//      if( pfnCreatePseudoConsole == NULL )
-//      {
-//          return -1;
-//      }
-//
-//      pfnResizePseudoConsole = (ResizePseudoConsoleProcType) GetProcAddress(hLibKernel32_Kern, "ResizePseudoConsole" );
-//      if( pfnResizePseudoConsole == NULL )
-//      {
-//          return -1;
-//      }
-//
-//      pfnClosePseudoConsole = (ClosePseudoConsoleProcType) GetProcAddress(hLibKernel32_Kern, "ClosePseudoConsole" );
-//      if( pfnClosePseudoConsole == NULL )
-//      {
-//          return -1;
-//      }
-//
-//      return 0;
-//  }
-//
-//  DWORD createPtyHelper( int xSize, int ySize, HANDLE input, HANDLE output, DWORD flags, uintptr_t * phPC )
-//  {
-//      COORD size;
-//      size.X = xSize;
-//      size.Y = ySize;
-//      return (DWORD) (*pfnCreatePseudoConsole)( size, input, output, flags, phPC );
-//  }
-//
-//  DWORD resizePtyHelper( uintptr_t hpc, int xSize, int ySize )
-//  {
-//      COORD size;
-//      size.X = xSize;
-//      size.Y = ySize;
-//      return (DWORD) (*pfnResizePseudoConsole)( hpc, size );
-//  }
-//
-//  DWORD closePtyHelper( uintptr_t hpc )
-//  {
-//      return (DWORD) (*pfnClosePseudoConsole)( hpc );
-//  }
-//
-//  int hr_succeeded( DWORD hResult )
-//  {
-//      return SUCCEEDED( hResult );
-//  }
-import "C"
-
-var ptyInitSucceeded = false
-
-func init() {
-	ret := int(C.initPtyKernFuncs())
-	ptyInitSucceeded = (ret == 0)
+// The pseudo console functions are looked up when they're first needed rather than linked, so that Aminal can say
+// which version of Windows it needs instead of failing to start on older ones
+var (
+	kernel32                      = windows.NewLazySystemDLL("kernel32.dll")
+	procCreatePseudoConsole       = kernel32.NewProc("CreatePseudoConsole")
+	procResizePseudoConsole       = kernel32.NewProc("ResizePseudoConsole")
+	procClosePseudoConsole        = kernel32.NewProc("ClosePseudoConsole")
+	procUpdateProcThreadAttribute = kernel32.NewProc("UpdateProcThreadAttribute")
+)
+
+// procThreadAttributePseudoConsole is PROC_THREAD_ATTRIBUTE_PSEUDOCONSOLE, which attaches a new process to a pseudo console
+const procThreadAttributePseudoConsole = 0x00020016
+
+func conPtyAvailable() bool {
+	return procCreatePseudoConsole.Find() == nil && procResizePseudoConsole.Find() == nil && procClosePseudoConsole.Find() == nil
+}
+
+// coord packs a size into a COORD, which the pseudo console functions take by value
+func coord(x, y int) uintptr {
+	clamp := func(n int) uint16 {
+		if n < 1 {
+			return 1
+		}
+		if n > 0x7fff {
+			return 0x7fff
+		}
+		return uint16(n)
+	}
+	return uintptr(clamp(x)) | uintptr(clamp(y))<<16
+}
+
+// hresultError describes a failed HRESULT, using the Windows error message where it wraps one
+func hresultError(hr uintptr) error {
+	if hr&0xffff0000 == 0x80070000 {
+		return windows.Errno(hr & 0xffff)
+	}
+	return fmt.Errorf("HRESULT 0x%08x", uint32(hr))
 }
 
 type winConPty struct {
-	inPipe                    syscall.Handle
-	outPipe                   syscall.Handle
-	innerInPipe               syscall.Handle
-	innerOutPipe              syscall.Handle
-	hcon                      uintptr
+	input                     *os.File // written to, for the pseudo console's programs to read
+	output                    *os.File // read from, the pseudo console's screen as VT sequences
+	lock                      sync.Mutex
+	hcon                      windows.Handle // 0 once closed
 	platformDependentSettings PlatformDependentSettings
 }
 
+// Read reads the pseudo console's output, which is UTF-8. It returns io.EOF once the pseudo console has been closed.
 func (pty *winConPty) Read(p []byte) (n int, err error) {
-	return syscall.Read(pty.inPipe, p)
+	return pty.output.Read(p)
 }
 
+// Write sends UTF-8 input to the pseudo console, which turns control characters like ctrl+c into the console's
+// signals for the programs attached to it
 func (pty *winConPty) Write(p []byte) (n int, err error) {
-	return syscall.Write(pty.outPipe, p)
+	return pty.input.Write(p)
 }
 
+// Close closes the pseudo console, which sends CTRL_CLOSE_EVENT to the programs attached to it so they can exit
+// cleanly, and ends reading once the last of its output has been read
 func (pty *winConPty) Close() error {
-	C.closePtyHelper(C.uintptr_t(pty.hcon))
-
-	err := syscall.CloseHandle(pty.inPipe)
-	if err != nil {
-		return err
+	pty.lock.Lock()
+	if pty.hcon != 0 {
+		procClosePseudoConsole.Call(uintptr(pty.hcon))
+		pty.hcon = 0
 	}
-	err = syscall.CloseHandle(pty.outPipe)
-	if err != nil {
-		return err
-	}
-	err = syscall.CloseHandle(pty.innerInPipe)
-	if err != nil {
-		return err
-	}
-	err = syscall.CloseHandle(pty.innerOutPipe)
-	if err != nil {
-		return err
-	}
-
-	pty.hcon = 0
+	pty.lock.Unlock()
 
-	return nil
+	err := pty.input.Close()
+	if outputErr := pty.output.Close(); err == nil {
+		err = outputErr
+	}
+	return err
 }
 
-func (pty *winConPty) CreateGuestProcess(imagePath string) (Process, error) {
-	process, err := createPtyChildProcess(imagePath, pty.hcon)
-
-	if err == nil {
-		setupChildConsole(C.DWORD(process.processID), C.STD_OUTPUT_HANDLE, C.ENABLE_PROCESSED_OUTPUT|C.ENABLE_WRAP_AT_EOL_OUTPUT)
+// CreateGuestProcess runs a command line on the pseudo console
+func (pty *winConPty) CreateGuestProcess(imagePath string, dir string) (Process, error) {
+	pty.lock.Lock()
+	hcon := pty.hcon
+	pty.lock.Unlock()
+	if hcon == 0 {
+		return nil, errors.New("Attempted to create a process on a closed ConPTY")
 	}
-
-	return process, err
+	return createPtyChildProcess(imagePath, dir, hcon)
 }
 
-func setupChildConsole(processID C.DWORD, nStdHandle C.DWORD, mode uint) bool {
-	C.FreeConsole()
-	defer C.AttachConsole(^C.DWORD(0)) // attach to parent process console
-
-	// process may not be ready so we'll do retries
-	const maxWaitMilliSeconds = 5000
-	const waitStepMilliSeconds = 200
-	count := maxWaitMilliSeconds / waitStepMilliSeconds
-
-	for {
-		if r := C.AttachConsole(processID); r != 0 {
-			break // success
-		}
-		lastError := C.GetLastError()
-		if lastError != C.ERROR_GEN_FAILURE || count <= 0 {
-			return false
-		}
-
-		time.Sleep(time.Millisecond * time.Duration(waitStepMilliSeconds))
-		count--
+// CreateGuestCommand runs args, which are quoted into the command line Windows passes to the program
+func (pty *winConPty) CreateGuestCommand(args []string, dir string) (Process, error) {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = windows.EscapeArg(arg)
 	}
-
-	h := C.GetStdHandle(nStdHandle)
-	C.SetConsoleMode(h, C.DWORD(mode))
-	C.FreeConsole()
-
-	return true
+	return pty.CreateGuestProcess(strings.Join(quoted, " "), dir)
 }
 
+// Resize resizes the pseudo console, which redraws its screen at the new size
 func (pty *winConPty) Resize(x, y int) error {
-	cret := C.resizePtyHelper(C.uintptr_t(pty.hcon), C.int(x), C.int(y))
-
-	if int(C.hr_succeeded(cret)) == 0 {
-		return errors.New("Failed to resize ConPTY")
+	pty.lock.Lock()
+	defer pty.lock.Unlock()
+	if pty.hcon == 0 {
+		return nil
 	}
 
+	if hr, _, _ := procResizePseudoConsole.Call(uintptr(pty.hcon), coord(x, y)); int32(hr) < 0 {
+		return fmt.Errorf("Failed to resize ConPTY: %s", hresultError(hr))
+	}
 	return nil
 }
 
@@ -187,45 +128,41 @@ func (pty *winConPty) GetPlatformDependentSettings() PlatformDependentSettings {
 }
 
 // NewPty creates a new instance of a Pty implementation for Windows on a newly allocated ConPTY
-func NewPty(x, y int) (pty Pty, err error) {
-	if !ptyInitSucceeded {
+func NewPty(x, y int) (Pty, error) {
+	if !conPtyAvailable() {
 		w32.MessageBox(0, "Aminal requires APIs that are only available on Windows 10 1809 (October 2018 Update) or above. Please upgrade", "Aminal", 0)
 		return nil, errors.New("Windows PseudoConsole API unavailable on this version of Windows")
 	}
-	pty = nil
 
-	var inputReadSide, inputWriteSide syscall.Handle
-	var outputReadSide, outputWriteSide syscall.Handle
+	var inputReadSide, inputWriteSide windows.Handle
+	var outputReadSide, outputWriteSide windows.Handle
 
-	err = syscall.CreatePipe(&inputReadSide, &inputWriteSide, nil, 0)
-	if err != nil {
-		return
+	if err := windows.CreatePipe(&inputReadSide, &inputWriteSide, nil, 0); err != nil {
+		return nil, fmt.Errorf("Failed to create the ConPTY's input pipe: %s", err)
 	}
-
-	err = syscall.CreatePipe(&outputReadSide, &outputWriteSide, nil, 0)
-	if err != nil {
-		return
+	if err := windows.CreatePipe(&outputReadSide, &outputWriteSide, nil, 0); err != nil {
+		windows.CloseHandle(inputReadSide)
+		windows.CloseHandle(inputWriteSide)
+		return nil, fmt.Errorf("Failed to create the ConPTY's output pipe: %s", err)
 	}
 
-	var hc C.uintptr_t
+	var hcon windows.Handle
+	hr, _, _ := procCreatePseudoConsole.Call(coord(x, y), uintptr(inputReadSide), uintptr(outputWriteSide), 0, uintptr(unsafe.Pointer(&hcon)))
 
-	cret := C.createPtyHelper(C.int(x), C.int(y), C.HANDLE(inputReadSide), C.HANDLE(outputWriteSide), 0, &hc)
-	ret := int(cret)
-
-	if ret != 0 {
-		return nil, errors.New("Failed to allocate a ConPTY instance")
-	}
+	// the pseudo console keeps its own copies of its ends of the pipes, so closing ours lets reading end when it does
+	windows.CloseHandle(inputReadSide)
+	windows.CloseHandle(outputWriteSide)
 
-	pty = &winConPty{
-		inPipe:       outputReadSide,
-		outPipe:      inputWriteSide,
-		innerInPipe:  inputReadSide,
-		innerOutPipe: outputWriteSide,
-		hcon:         uintptr(hc),
-		platformDependentSettings: PlatformDependentSettings{
-			OSCTerminators: map[rune]struct{}{0x00: {}, 0x07: {}},
-		},
+	if int32(hr) < 0 {
+		windows.CloseHandle(inputWriteSide)
+		windows.CloseHandle(outputReadSide)
+		return nil, fmt.Errorf("Failed to allocate a ConPTY instance: %s", hresultError(hr))
 	}
 
-	return pty, nil
+	return &winConPty{
+		input:                     os.NewFile(uintptr(inputWriteSide), "conpty-input"),
+		output:                    os.NewFile(uintptr(outputReadSide), "conpty-output"),
+		hcon:                      hcon,
+		platformDependentSettings: DefaultSettings(),
+	}, nil
 }
@@ -0,0 +1,149 @@
+package plugins
+
+import (
+	"github.com/liamg/aminal/terminal"
+	lua "github.com/yuin/gopher-lua"
+)
+
+const terminalType = "aminal.terminal"
+
+// register gives the plugin's state the aminal table, and the methods of the terminal objects its hooks are given
+func (p *plugin) register() {
+	L := p.state
+	aminal := L.NewTable()
+	L.SetFuncs(aminal, map[string]lua.LGFunction{
+		// on_line(function(term, line)) runs the function with each line of output, once it's finished
+		"on_line": func(L *lua.LState) int {
+			p.lines = append(p.lines, L.CheckFunction(1))
+			return 0
+		},
+		// on_sequence(code, function(term, params)) runs the function with the OSC sequences starting with the
+		// code, e.g. "1338", which the terminal doesn't handle itself
+		"on_sequence": func(L *lua.LState) int {
+			code := L.CheckString(1)
+			p.sequences[code] = append(p.sequences[code], L.CheckFunction(2))
+			return 0
+		},
+		// on_key(function(term, key)) runs the function with each key pressed, which is used for nothing else if
+		// it returns true
+		"on_key": func(L *lua.LState) int {
+			p.keys = append(p.keys, L.CheckFunction(1))
+			return 0
+		},
+		// log(message) writes the message to Aminal's log
+		"log": func(L *lua.LState) int {
+			p.manager.logger.Infof("%s: %s", p.name, L.CheckString(1))
+			return 0
+		},
+	})
+	L.SetGlobal("aminal", aminal)
+
+	methods := L.NewTable()
+	L.SetFuncs(methods, map[string]lua.LGFunction{
+		"send":        p.send,
+		"screen":      p.screen,
+		"cursor":      p.cursor,
+		"size":        p.size,
+		"title":       p.title,
+		"status":      p.status,
+		"mark":        p.mark,
+		"clear_marks": p.clearMarks,
+	})
+	mt := L.NewTypeMetatable(terminalType)
+	L.SetField(mt, "__index", methods)
+}
+
+// terminal returns the object standing for the terminal in the plugin, the same one each time so scripts can
+// keep track of terminals by it
+func (p *plugin) terminal(t *terminal.Terminal) *lua.LUserData {
+	if ud, ok := p.terminals[t]; ok {
+		return ud
+	}
+	ud := p.state.NewUserData()
+	ud.Value = t
+	p.state.SetMetatable(ud, p.state.GetTypeMetatable(terminalType))
+	p.terminals[t] = ud
+	return ud
+}
+
+func checkTerminal(L *lua.LState) *terminal.Terminal {
+	ud := L.CheckUserData(1)
+	if t, ok := ud.Value.(*terminal.Terminal); ok {
+		return t
+	}
+	L.ArgError(1, "terminal expected")
+	return nil
+}
+
+// term:send(text) writes the text to the program running in the terminal, as if it had been typed
+func (p *plugin) send(L *lua.LState) int {
+	t := checkTerminal(L)
+	if err := t.Write([]byte(L.CheckString(2))); err != nil {
+		L.RaiseError("Failed to send to the terminal: %s", err)
+	}
+	return 0
+}
+
+// term:screen() returns a table of the lines on screen, from the top
+func (p *plugin) screen(L *lua.LState) int {
+	t := checkTerminal(L)
+	lines := L.NewTable()
+	for _, line := range t.GetVisibleLines() {
+		lines.Append(lua.LString(line.String()))
+	}
+	L.Push(lines)
+	return 1
+}
+
+// term:cursor() returns the row and column the cursor is in, from 0
+func (p *plugin) cursor(L *lua.LState) int {
+	t := checkTerminal(L)
+	L.Push(lua.LNumber(t.GetLogicalCursorY()))
+	L.Push(lua.LNumber(t.GetLogicalCursorX()))
+	return 2
+}
+
+// term:size() returns the number of columns and rows
+func (p *plugin) size(L *lua.LState) int {
+	t := checkTerminal(L)
+	cols, rows := t.GetSize()
+	L.Push(lua.LNumber(cols))
+	L.Push(lua.LNumber(rows))
+	return 2
+}
+
+// term:title() returns the terminal's title
+func (p *plugin) title(L *lua.LState) int {
+	t := checkTerminal(L)
+	L.Push(lua.LString(t.GetTitle()))
+	return 1
+}
+
+// term:status(text) shows the text at the bottom of the terminal, or stops showing it if it's empty
+func (p *plugin) status(L *lua.LState) int {
+	t := checkTerminal(L)
+	text := L.OptString(2, "")
+	p.manager.updateOverlay(t, p, func(o *Overlay) {
+		o.Status = text
+	})
+	return 0
+}
+
+// term:mark(row, col, text) shows the text highlighted over the screen at the row and column, from 0
+func (p *plugin) mark(L *lua.LState) int {
+	t := checkTerminal(L)
+	mark := Mark{Row: L.CheckInt(2), Col: L.CheckInt(3), Text: L.CheckString(4)}
+	p.manager.updateOverlay(t, p, func(o *Overlay) {
+		o.Marks = append(o.Marks, mark)
+	})
+	return 0
+}
+
+// term:clear_marks() stops showing the marks the plugin has made over the terminal
+func (p *plugin) clearMarks(L *lua.LState) int {
+	t := checkTerminal(L)
+	p.manager.updateOverlay(t, p, func(o *Overlay) {
+		o.Marks = nil
+	})
+	return 0
+}
@@ -0,0 +1,342 @@
+// Package plugins runs Lua scripts which extend the terminal without changing Aminal itself. Each script is
+// given an aminal table to register hooks with, for lines of output, OSC sequences the terminal doesn't know and
+// keys pressed, and each hook is given the terminal it's for, to read the screen, send input, and show a status
+// or marks over the screen. See the README for the whole API.
+package plugins
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/liamg/aminal/terminal"
+	lua "github.com/yuin/gopher-lua"
+	"go.uber.org/zap"
+)
+
+const fileExtension = ".lua"
+
+// hookTimeout is how long a hook can run for before it's stopped, so a script stuck in a loop can't hang the
+// terminal or the keyboard
+const hookTimeout = time.Second
+
+// Overlay is what the plugins have asked to be shown over a terminal
+type Overlay struct {
+	Status string // shown at the bottom of the terminal, if it isn't empty
+	Marks  []Mark
+}
+
+// Mark is text shown over the screen, highlighted, until the plugin which made it clears its marks
+type Mark struct {
+	Row  int // from the top of the screen, starting from 0
+	Col  int
+	Text string
+}
+
+// Manager runs the plugins, passing them the events from the terminals attached to it
+type Manager struct {
+	logger  *zap.SugaredLogger
+	plugins []*plugin
+
+	lock     sync.Mutex
+	overlays map[*terminal.Terminal]map[*plugin]*Overlay
+	detach   map[*terminal.Terminal]chan struct{}
+}
+
+// plugin is a script, with the hooks it registered when it was run
+type plugin struct {
+	name    string
+	manager *Manager
+
+	// a Lua state can't be used by more than one goroutine at once, and the terminals' hooks come from several
+	lock      sync.Mutex
+	state     *lua.LState
+	lines     []*lua.LFunction
+	sequences map[string][]*lua.LFunction
+	keys      []*lua.LFunction
+	terminals map[*terminal.Terminal]*lua.LUserData
+}
+
+// Load runs the scripts in dir, in order of their names. A script which fails is logged and left out, so the
+// others still run. There are no plugins if dir is empty or doesn't exist.
+func Load(dir string, logger *zap.SugaredLogger) *Manager {
+	m := &Manager{
+		logger:   logger,
+		overlays: map[*terminal.Terminal]map[*plugin]*Overlay{},
+		detach:   map[*terminal.Terminal]chan struct{}{},
+	}
+	if dir == "" {
+		return m
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logger.Errorf("Failed to read the plugins directory: %s", err)
+		}
+		return m
+	}
+	names := []string{}
+	for _, file := range files {
+		if !file.IsDir() && strings.HasSuffix(file.Name(), fileExtension) {
+			names = append(names, file.Name())
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		p, err := m.load(filepath.Join(dir, name))
+		if err != nil {
+			logger.Errorf("Failed to load the %s plugin: %s", name, err)
+			continue
+		}
+		logger.Infof("Loaded the %s plugin", name)
+		m.plugins = append(m.plugins, p)
+	}
+	return m
+}
+
+// LoadScript runs the script as a plugin, alongside any already loaded
+func (m *Manager) LoadScript(name string, script string) error {
+	p := m.newPlugin(name)
+	if err := p.state.DoString(script); err != nil {
+		p.state.Close()
+		return err
+	}
+	m.plugins = append(m.plugins, p)
+	return nil
+}
+
+func (m *Manager) load(path string) (*plugin, error) {
+	p := m.newPlugin(strings.TrimSuffix(filepath.Base(path), fileExtension))
+	if err := p.state.DoFile(path); err != nil {
+		p.state.Close()
+		return nil, err
+	}
+	return p, nil
+}
+
+func (m *Manager) newPlugin(name string) *plugin {
+	p := &plugin{
+		name:      name,
+		manager:   m,
+		state:     lua.NewState(),
+		sequences: map[string][]*lua.LFunction{},
+		terminals: map[*terminal.Terminal]*lua.LUserData{},
+	}
+	p.register()
+	return p
+}
+
+// Close stops the plugins. Hooks aren't run after it.
+func (m *Manager) Close() {
+	for _, p := range m.plugins {
+		p.lock.Lock()
+		p.state.Close()
+		p.state = nil
+		p.lock.Unlock()
+	}
+}
+
+// Attach passes the terminal's output to the plugins' hooks for it, until it's detached. Without any hooks for
+// sequences, the terminal goes on logging the sequences it doesn't know itself.
+func (m *Manager) Attach(t *terminal.Terminal) {
+	var lines chan string
+	var sequences chan terminal.Sequence
+	for _, p := range m.plugins {
+		if len(p.lines) > 0 && lines == nil {
+			lines = make(chan string, 64)
+			m.relayLines(t, lines)
+		}
+		if len(p.sequences) > 0 && sequences == nil {
+			sequences = make(chan terminal.Sequence, 16)
+			t.AttachSequenceHandler(sequences)
+		}
+	}
+	if lines == nil && sequences == nil {
+		return
+	}
+
+	detach := make(chan struct{})
+	m.lock.Lock()
+	m.detach[t] = detach
+	m.lock.Unlock()
+
+	go func() {
+		for {
+			select {
+			case line := <-lines:
+				for _, p := range m.plugins {
+					p.line(t, line)
+				}
+			case sequence := <-sequences:
+				handled := false
+				for _, p := range m.plugins {
+					handled = p.sequence(t, sequence) || handled
+				}
+				if !handled {
+					m.logger.Errorf("Unknown OSC control sequence: %s", strings.Join(append([]string{sequence.Code}, sequence.Params...), ";"))
+				}
+			case <-detach:
+				return
+			}
+		}
+	}()
+}
+
+// relayLines passes the terminal's lines on to lines while it has room, dropping those the hooks can't keep up
+// with rather than holding up the terminal's output, which waits for its line handlers. The terminal can't be
+// told to stop sending lines, so they go on being drained, and dropped, once it's detached.
+func (m *Manager) relayLines(t *terminal.Terminal, lines chan string) {
+	from := make(chan string)
+	t.AttachLineHandler(from)
+	go func() {
+		dropped := 0
+		for line := range from {
+			select {
+			case lines <- line:
+				if dropped > 0 {
+					m.logger.Errorf("Plugins missed %d lines of output, as their hooks couldn't keep up", dropped)
+					dropped = 0
+				}
+			default:
+				dropped++
+			}
+		}
+	}()
+}
+
+// Detach stops passing the terminal's output to the plugins, forgetting what they've shown over it
+func (m *Manager) Detach(t *terminal.Terminal) {
+	m.lock.Lock()
+	if detach, ok := m.detach[t]; ok {
+		close(detach)
+		delete(m.detach, t)
+	}
+	delete(m.overlays, t)
+	m.lock.Unlock()
+
+	for _, p := range m.plugins {
+		p.lock.Lock()
+		delete(p.terminals, t)
+		p.lock.Unlock()
+	}
+}
+
+// Key passes a key pressed in the terminal to the plugins' key hooks, returning true if one of them handled it,
+// in which case it isn't used for anything else. The key is named like the keys in the config, e.g. "ctrl+shift+k".
+func (m *Manager) Key(t *terminal.Terminal, key string) bool {
+	for _, p := range m.plugins {
+		if p.key(t, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// Overlay returns what the plugins have asked to be shown over the terminal, with their statuses joined together
+func (m *Manager) Overlay(t *terminal.Terminal) Overlay {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	overlay := Overlay{}
+	statuses := []string{}
+	for _, p := range m.plugins {
+		o, ok := m.overlays[t][p]
+		if !ok {
+			continue
+		}
+		if o.Status != "" {
+			statuses = append(statuses, o.Status)
+		}
+		overlay.Marks = append(overlay.Marks, o.Marks...)
+	}
+	overlay.Status = strings.Join(statuses, "  |  ")
+	return overlay
+}
+
+// updateOverlay changes what the plugin shows over the terminal, marking the terminal to be drawn again
+func (m *Manager) updateOverlay(t *terminal.Terminal, p *plugin, update func(o *Overlay)) {
+	m.lock.Lock()
+	if m.overlays[t] == nil {
+		m.overlays[t] = map[*plugin]*Overlay{}
+	}
+	o, ok := m.overlays[t][p]
+	if !ok {
+		o = &Overlay{}
+		m.overlays[t][p] = o
+	}
+	update(o)
+	m.lock.Unlock()
+	t.SetDirty()
+}
+
+func (p *plugin) line(t *terminal.Terminal, line string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	for _, hook := range p.lines {
+		p.call(hook, t, lua.LString(line))
+	}
+}
+
+// sequence runs the hooks for the sequence's code, returning false if there aren't any
+func (p *plugin) sequence(t *terminal.Terminal, sequence terminal.Sequence) bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	hooks := p.sequences[sequence.Code]
+	if len(hooks) == 0 {
+		return false
+	}
+	if p.state == nil {
+		return true
+	}
+	table := p.state.NewTable()
+	for _, param := range sequence.Params {
+		table.Append(lua.LString(param))
+	}
+	for _, hook := range hooks {
+		p.call(hook, t, table)
+	}
+	return true
+}
+
+func (p *plugin) key(t *terminal.Terminal, key string) bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	for _, hook := range p.keys {
+		if lua.LVAsBool(p.call(hook, t, lua.LString(key))) {
+			return true
+		}
+	}
+	return false
+}
+
+// call runs a hook with the terminal's object and the arguments, returning what it returns. Errors are logged.
+// The plugin must be locked.
+func (p *plugin) call(hook *lua.LFunction, t *terminal.Terminal, args ...lua.LValue) lua.LValue {
+	L := p.state
+	if L == nil {
+		return lua.LNil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), hookTimeout)
+	defer cancel()
+	L.SetContext(ctx)
+	defer L.RemoveContext()
+
+	args = append([]lua.LValue{p.terminal(t)}, args...)
+	if err := L.CallByParam(lua.P{Fn: hook, NRet: 1, Protect: true}, args...); err != nil {
+		p.manager.logger.Errorf("%s", p.error(err))
+		return lua.LNil
+	}
+	result := L.Get(-1)
+	L.Pop(1)
+	return result
+}
+
+func (p *plugin) error(err error) error {
+	return fmt.Errorf("The %s plugin failed: %s", p.name, err)
+}
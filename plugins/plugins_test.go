@@ -0,0 +1,136 @@
+package plugins
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/liamg/aminal/terminal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func newTerminal(t *testing.T, m *Manager) *terminal.Headless {
+	term, err := terminal.NewHeadless(40, 10, terminal.DefaultOptions())
+	require.Nil(t, err)
+	t.Cleanup(func() { term.Close() })
+	m.Attach(term.Terminal)
+	return term
+}
+
+func newManager(t *testing.T, script string) *Manager {
+	m := Load("", zap.NewNop().Sugar())
+	require.Nil(t, m.LoadScript("test", script))
+	return m
+}
+
+func TestLineHooks(t *testing.T) {
+	m := newManager(t, `
+		aminal.on_line(function(term, line)
+			if line:find("Continue%?") then
+				term:send("y\r")
+			end
+		end)
+	`)
+	defer m.Close()
+	term := newTerminal(t, m)
+
+	term.Feed([]byte("Working...\r\nContinue?\r\n"))
+
+	replies := ""
+	assert.Eventually(t, func() bool {
+		replies += string(term.Replies())
+		return replies == "y\r"
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestSequenceHooks(t *testing.T) {
+	m := newManager(t, `
+		aminal.on_sequence("1338", function(term, params)
+			term:status(params[1] .. " " .. params[2])
+		end)
+	`)
+	defer m.Close()
+	term := newTerminal(t, m)
+
+	term.Feed([]byte("\x1b]1338;build;passed\x07"))
+
+	assert.Eventually(t, func() bool { return m.Overlay(term.Terminal).Status == "build passed" }, time.Second, 10*time.Millisecond)
+}
+
+func TestKeyHooks(t *testing.T) {
+	m := newManager(t, `
+		aminal.on_key(function(term, key)
+			if key ~= "ctrl+k" then
+				return false
+			end
+			local row, col = term:cursor()
+			term:mark(row, col, term:screen()[row + 1])
+			return true
+		end)
+	`)
+	defer m.Close()
+	term := newTerminal(t, m)
+	term.Feed([]byte("$ "))
+
+	assert.False(t, m.Key(term.Terminal, "ctrl+j"))
+	assert.True(t, m.Key(term.Terminal, "ctrl+k"))
+	assert.Equal(t, []Mark{{Row: 0, Col: 2, Text: "$"}}, m.Overlay(term.Terminal).Marks)
+
+	m.Detach(term.Terminal)
+	assert.Equal(t, Overlay{}, m.Overlay(term.Terminal))
+}
+
+func TestHooksWhichRunTooLongAreStopped(t *testing.T) {
+	m := newManager(t, `
+		aminal.on_key(function(term, key)
+			while true do end
+		end)
+	`)
+	defer m.Close()
+	term := newTerminal(t, m)
+
+	assert.False(t, m.Key(term.Terminal, "a"))
+}
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "aminal-plugins")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	require.Nil(t, ioutil.WriteFile(filepath.Join(dir, "a.lua"), []byte(`aminal.on_key(function(term, key) return key == "a" end)`), 0644))
+	require.Nil(t, ioutil.WriteFile(filepath.Join(dir, "b.lua"), []byte(`this isn't lua`), 0644))
+	require.Nil(t, ioutil.WriteFile(filepath.Join(dir, "c.txt"), []byte(`neither is this`), 0644))
+
+	m := Load(dir, zap.NewNop().Sugar())
+	defer m.Close()
+	require.Len(t, m.plugins, 1)
+	assert.Equal(t, "a", m.plugins[0].name)
+}
+
+func TestSlowLineHooksDontHoldUpOutput(t *testing.T) {
+	m := newManager(t, `
+		aminal.on_line(function(term, line)
+			local start = os.clock()
+			while os.clock() - start < 0.5 do end
+		end)
+	`)
+	defer m.Close()
+	term := newTerminal(t, m)
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 200; i++ {
+			term.Feed([]byte("line\r\n"))
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Output waited for the hooks")
+	}
+}
@@ -0,0 +1,219 @@
+package recording
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"image/png"
+	"io"
+	"time"
+)
+
+// Format is the type of animation to export
+type Format string
+
+const (
+	FormatGIF  Format = "gif"
+	FormatAPNG Format = "apng"
+)
+
+// Extension returns the file extension used for the format
+func (format Format) Extension() string {
+	if format == FormatAPNG {
+		return "png"
+	}
+	return string(format)
+}
+
+// Export renders the frames from since onwards and writes them to w as an animation. The frame on screen at since
+// is shown from then, however long before it was recorded, and the final frame is held for a second so the clip
+// doesn't end abruptly.
+func Export(w io.Writer, frames []Frame, since time.Time, renderer *Renderer, scale float64, format Format) error {
+	frames = clip(frames, since)
+	if len(frames) == 0 {
+		return fmt.Errorf("Nothing has been recorded yet")
+	}
+
+	images := make([]image.Image, len(frames))
+	for i, frame := range frames {
+		images[i] = scaleImage(renderer.Render(frame), scale)
+	}
+
+	delays := make([]time.Duration, len(frames))
+	for i := range frames {
+		if i+1 < len(frames) {
+			delays[i] = frames[i+1].Snapshot.Time.Sub(frames[i].Snapshot.Time)
+		} else {
+			delays[i] = time.Second
+		}
+	}
+
+	switch format {
+	case FormatGIF:
+		return writeGIF(w, images, delays)
+	case FormatAPNG:
+		return writeAPNG(w, images, delays)
+	}
+	return fmt.Errorf("Unknown clip format '%s', expected gif or apng", format)
+}
+
+// clip drops the frames which were replaced before since, and moves the one on screen at since up to it
+func clip(frames []Frame, since time.Time) []Frame {
+	first := 0
+	for first+1 < len(frames) && !frames[first+1].Snapshot.Time.After(since) {
+		first++
+	}
+	frames = append([]Frame{}, frames[first:]...)
+	if len(frames) > 0 && frames[0].Snapshot.Time.Before(since) {
+		frames[0].Snapshot.Time = since
+	}
+	return frames
+}
+
+// scaleImage resizes using nearest neighbour, which keeps text crisp at whole number scales
+func scaleImage(src *image.RGBA, scale float64) image.Image {
+	if scale <= 0 || scale == 1 {
+		return src
+	}
+	bounds := src.Bounds()
+	width, height := int(float64(bounds.Dx())*scale), int(float64(bounds.Dy())*scale)
+	if width < 1 || height < 1 {
+		return src
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		sy := bounds.Min.Y + int(float64(y)/scale)
+		for x := 0; x < width; x++ {
+			dst.SetRGBA(x, y, src.RGBAAt(bounds.Min.X+int(float64(x)/scale), sy))
+		}
+	}
+	return dst
+}
+
+func writeGIF(w io.Writer, images []image.Image, delays []time.Duration) error {
+	anim := &gif.GIF{}
+	for i, img := range images {
+		paletted := image.NewPaletted(img.Bounds(), palette.Plan9)
+		draw.Draw(paletted, img.Bounds(), img, image.Point{}, draw.Src)
+		anim.Image = append(anim.Image, paletted)
+		anim.Delay = append(anim.Delay, centiseconds(delays[i]))
+	}
+	return gif.EncodeAll(w, anim)
+}
+
+// centiseconds returns a frame delay as both formats store it, in hundredths of a second in 16 bits
+func centiseconds(d time.Duration) int {
+	cs := int(d / (10 * time.Millisecond))
+	if cs < 2 {
+		// most viewers treat anything shorter than this as 10
+		cs = 2
+	}
+	if cs > 0xffff {
+		cs = 0xffff
+	}
+	return cs
+}
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// writeAPNG builds an animated PNG from individually encoded frames, reusing their IHDR and IDAT chunks.
+// See https://wiki.mozilla.org/APNG_Specification
+func writeAPNG(w io.Writer, images []image.Image, delays []time.Duration) error {
+	sequence := uint32(0)
+	bounds := images[0].Bounds()
+
+	if _, err := w.Write(pngSignature); err != nil {
+		return err
+	}
+
+	for i, img := range images {
+		chunks, err := encodePNGChunks(img)
+		if err != nil {
+			return err
+		}
+
+		if i == 0 {
+			if err := writeChunk(w, "IHDR", chunks["IHDR"][0]); err != nil {
+				return err
+			}
+			actl := make([]byte, 8)
+			binary.BigEndian.PutUint32(actl[0:], uint32(len(images)))
+			binary.BigEndian.PutUint32(actl[4:], 0) // loop forever
+			if err := writeChunk(w, "acTL", actl); err != nil {
+				return err
+			}
+		}
+
+		fctl := make([]byte, 26)
+		binary.BigEndian.PutUint32(fctl[0:], sequence)
+		binary.BigEndian.PutUint32(fctl[4:], uint32(bounds.Dx()))
+		binary.BigEndian.PutUint32(fctl[8:], uint32(bounds.Dy()))
+		// x and y offsets are left at zero as every frame covers the whole image
+		binary.BigEndian.PutUint16(fctl[20:], uint16(centiseconds(delays[i])))
+		binary.BigEndian.PutUint16(fctl[22:], 100)
+		// dispose and blend ops are left at zero, as every frame is opaque
+		sequence++
+		if err := writeChunk(w, "fcTL", fctl); err != nil {
+			return err
+		}
+
+		for _, data := range chunks["IDAT"] {
+			if i == 0 {
+				err = writeChunk(w, "IDAT", data)
+			} else {
+				fdat := make([]byte, 4+len(data))
+				binary.BigEndian.PutUint32(fdat, sequence)
+				copy(fdat[4:], data)
+				sequence++
+				err = writeChunk(w, "fdAT", fdat)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return writeChunk(w, "IEND", nil)
+}
+
+// encodePNGChunks encodes an image as a PNG and returns the data of each chunk, by type
+func encodePNGChunks(img image.Image) (map[string][][]byte, error) {
+	buf := &bytes.Buffer{}
+	if err := png.Encode(buf, img); err != nil {
+		return nil, err
+	}
+	data := buf.Bytes()[len(pngSignature):]
+	chunks := map[string][][]byte{}
+	for len(data) >= 12 {
+		length := binary.BigEndian.Uint32(data)
+		if uint32(len(data)) < 12+length {
+			return nil, fmt.Errorf("Truncated PNG chunk")
+		}
+		kind := string(data[4:8])
+		chunks[kind] = append(chunks[kind], data[8:8+length])
+		data = data[12+length:]
+	}
+	return chunks, nil
+}
+
+func writeChunk(w io.Writer, kind string, data []byte) error {
+	header := make([]byte, 8)
+	binary.BigEndian.PutUint32(header, uint32(len(data)))
+	copy(header[4:], kind)
+	crc := crc32.NewIEEE()
+	crc.Write(header[4:])
+	crc.Write(data)
+	footer := make([]byte, 4)
+	binary.BigEndian.PutUint32(footer, crc.Sum32())
+	for _, b := range [][]byte{header, data, footer} {
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+// Package recording keeps a rolling record of the screen, which can be exported as an animation
+package recording
+
+import (
+	"time"
+
+	"github.com/liamg/aminal/buffer"
+)
+
+// Frame is the screen at a moment in time
+type Frame struct {
+	Snapshot   buffer.Snapshot
+	CursorX    int
+	CursorY    int
+	ShowCursor bool
+}
+
+// Recorder holds frames from the last stretch of time, at up to a fixed rate
+type Recorder struct {
+	length   time.Duration
+	interval time.Duration
+	frames   []Frame
+}
+
+// NewRecorder creates a recorder which keeps the last length of time at up to fps frames per second
+func NewRecorder(length time.Duration, fps int) *Recorder {
+	if fps <= 0 {
+		fps = 10
+	}
+	return &Recorder{
+		length:   length,
+		interval: time.Second / time.Duration(fps),
+	}
+}
+
+// Length returns how much time the recorder keeps
+func (r *Recorder) Length() time.Duration {
+	return r.length
+}
+
+// Due returns true if enough time has passed since the last frame to record another
+func (r *Recorder) Due() bool {
+	return len(r.frames) == 0 || time.Since(r.frames[len(r.frames)-1].Snapshot.Time) >= r.interval
+}
+
+// Record adds a frame, dropping any which have become too old
+func (r *Recorder) Record(frame Frame) {
+	r.frames = append(r.frames, frame)
+
+	cutoff := frame.Snapshot.Time.Add(-r.length)
+	drop := 0
+	// keep the last frame before the cutoff, as it's what was on screen at the start of the clip
+	for drop+1 < len(r.frames) && !r.frames[drop+1].Snapshot.Time.After(cutoff) {
+		drop++
+	}
+	if drop > 0 {
+		r.frames = append([]Frame{}, r.frames[drop:]...)
+	}
+}
+
+// Frames returns the recorded frames, oldest first
+func (r *Recorder) Frames() []Frame {
+	return append([]Frame{}, r.frames...)
+}
@@ -0,0 +1,103 @@
+package recording
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image/gif"
+	"image/png"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/liamg/aminal/buffer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testFrames(t *testing.T) ([]Frame, *Renderer) {
+	fontData, err := ioutil.ReadFile("../gui/packed-fonts/Hack Regular Nerd Font Complete.ttf")
+	require.Nil(t, err)
+
+	renderer, err := NewRenderer(fontData, nil, 12, 10, 2, [3]float32{0, 0, 0}, [3]float32{1, 1, 1})
+	require.Nil(t, err)
+
+	b := buffer.NewBuffer(buffer.NewTerminalState(10, 2, buffer.CellAttributes{}, 1000))
+	frames := []Frame{}
+	for _, text := range []string{"a", "b", "c"} {
+		b.Write([]rune(text)...)
+		frame := Frame{Snapshot: b.Snapshot(), CursorX: int(b.CursorColumn()), ShowCursor: true}
+		frame.Snapshot.Time = time.Unix(0, 0).Add(time.Duration(len(frames)) * 100 * time.Millisecond)
+		frames = append(frames, frame)
+	}
+	return frames, renderer
+}
+
+func TestRecorderDropsOldFrames(t *testing.T) {
+	r := NewRecorder(time.Second, 10)
+	start := time.Now()
+	for i := 0; i < 30; i++ {
+		r.Record(Frame{Snapshot: buffer.Snapshot{Time: start.Add(time.Duration(i) * 100 * time.Millisecond)}})
+	}
+	frames := r.Frames()
+	require.Len(t, frames, 11)
+	assert.Equal(t, start.Add(1900*time.Millisecond), frames[0].Snapshot.Time)
+}
+
+func TestExportGIF(t *testing.T) {
+	frames, renderer := testFrames(t)
+	var out bytes.Buffer
+	require.Nil(t, Export(&out, frames, time.Unix(0, 0), renderer, 2, FormatGIF))
+
+	anim, err := gif.DecodeAll(&out)
+	require.Nil(t, err)
+	require.Len(t, anim.Image, 3)
+	assert.Equal(t, []int{10, 10, 100}, anim.Delay)
+	assert.Equal(t, renderer.Bounds().Dx()*2, anim.Image[0].Bounds().Dx())
+}
+
+func TestExportAPNG(t *testing.T) {
+	frames, renderer := testFrames(t)
+	var out bytes.Buffer
+	require.Nil(t, Export(&out, frames, time.Unix(0, 0), renderer, 1, FormatAPNG))
+
+	// viewers without APNG support show the first frame, so it must still be a valid PNG
+	img, err := png.Decode(bytes.NewReader(out.Bytes()))
+	require.Nil(t, err)
+	assert.Equal(t, renderer.Bounds(), img.Bounds())
+
+	kinds := []string{}
+	data := out.Bytes()[len(pngSignature):]
+	for len(data) >= 12 {
+		length := binary.BigEndian.Uint32(data)
+		kind := string(data[4:8])
+		if len(kinds) == 0 || kinds[len(kinds)-1] != kind {
+			kinds = append(kinds, kind)
+		}
+		data = data[12+length:]
+	}
+	assert.Equal(t, []string{"IHDR", "acTL", "fcTL", "IDAT", "fcTL", "fdAT", "fcTL", "fdAT", "IEND"}, kinds)
+}
+
+func TestExportStartsAtTheStartOfTheClip(t *testing.T) {
+	frames, renderer := testFrames(t)
+	// the screen sat unchanged on the first frame for an hour before the others
+	frames[0].Snapshot.Time = time.Unix(0, 0).Add(-time.Hour)
+	var out bytes.Buffer
+	require.Nil(t, Export(&out, frames, time.Unix(0, 0).Add(50*time.Millisecond), renderer, 1, FormatGIF))
+
+	anim, err := gif.DecodeAll(&out)
+	require.Nil(t, err)
+	assert.Equal(t, []int{5, 10, 100}, anim.Delay)
+
+	// frames replaced before the clip starts are left out
+	out.Reset()
+	require.Nil(t, Export(&out, frames, time.Unix(0, 0).Add(150*time.Millisecond), renderer, 1, FormatGIF))
+	anim, err = gif.DecodeAll(&out)
+	require.Nil(t, err)
+	assert.Equal(t, []int{5, 100}, anim.Delay)
+}
+
+func TestLongDelaysAreClamped(t *testing.T) {
+	assert.Equal(t, 0xffff, centiseconds(time.Hour))
+	assert.Equal(t, 2, centiseconds(time.Millisecond))
+}
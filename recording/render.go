@@ -0,0 +1,145 @@
+package recording
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+
+	"github.com/golang/freetype/truetype"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// Renderer draws frames into images without needing a window or GPU
+type Renderer struct {
+	regular    font.Face
+	bold       font.Face
+	cellWidth  int
+	cellHeight int
+	ascent     int
+	cols       int
+	rows       int
+	background [3]float32
+	cursor     [3]float32
+}
+
+// NewRenderer creates a renderer for a cols x rows grid, using the given TrueType fonts at size pixels
+func NewRenderer(regularFont []byte, boldFont []byte, size float64, cols int, rows int, background [3]float32, cursor [3]float32) (*Renderer, error) {
+	regular, err := loadFace(regularFont, size)
+	if err != nil {
+		return nil, err
+	}
+	bold := regular
+	if boldFont != nil {
+		if bold, err = loadFace(boldFont, size); err != nil {
+			return nil, err
+		}
+	}
+
+	metrics := regular.Metrics()
+	advance, ok := regular.GlyphAdvance('M')
+	if !ok {
+		return nil, fmt.Errorf("Font has no glyph for 'M', is it a monospace font?")
+	}
+
+	return &Renderer{
+		regular:    regular,
+		bold:       bold,
+		cellWidth:  advance.Ceil(),
+		cellHeight: (metrics.Ascent + metrics.Descent).Ceil(),
+		ascent:     metrics.Ascent.Ceil(),
+		cols:       cols,
+		rows:       rows,
+		background: background,
+		cursor:     cursor,
+	}, nil
+}
+
+func loadFace(data []byte, size float64) (font.Face, error) {
+	f, err := truetype.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse font: %s", err)
+	}
+	return truetype.NewFace(f, &truetype.Options{Size: size, DPI: 72, Hinting: font.HintingFull}), nil
+}
+
+func toRGBA(c [3]float32) color.RGBA {
+	return color.RGBA{
+		R: uint8(math.Floor(float64(255 * c[0]))),
+		G: uint8(math.Floor(float64(255 * c[1]))),
+		B: uint8(math.Floor(float64(255 * c[2]))),
+		A: 0xff,
+	}
+}
+
+// toNRGBA is toRGBA with some transparency, which color.RGBA can't be given without scaling the colour down to match
+func toNRGBA(c [3]float32, alpha uint8) color.NRGBA {
+	rgba := toRGBA(c)
+	return color.NRGBA{R: rgba.R, G: rgba.G, B: rgba.B, A: alpha}
+}
+
+// Bounds returns the size of the images drawn by the renderer
+func (r *Renderer) Bounds() image.Rectangle {
+	return image.Rect(0, 0, r.cols*r.cellWidth, r.rows*r.cellHeight)
+}
+
+// Render draws a frame
+func (r *Renderer) Render(frame Frame) *image.RGBA {
+	img := image.NewRGBA(r.Bounds())
+	draw.Draw(img, img.Bounds(), image.NewUniform(toRGBA(r.background)), image.Point{}, draw.Src)
+
+	for row, line := range frame.Snapshot.Lines() {
+		if row >= r.rows {
+			break
+		}
+		cells := line.Cells()
+		for col := 0; col < len(cells) && col < r.cols; col++ {
+			cell := cells[col]
+			bg, fg := cell.Bg(), cell.Fg()
+			if frame.ShowCursor && col == frame.CursorX && row == frame.CursorY {
+				bg, fg = r.cursor, r.background
+			}
+			cellRect := image.Rect(col*r.cellWidth, row*r.cellHeight, (col+1)*r.cellWidth, (row+1)*r.cellHeight)
+			if bg != r.background {
+				draw.Draw(img, cellRect, image.NewUniform(toRGBA(bg)), image.Point{}, draw.Src)
+			}
+
+			ch := cell.Rune()
+			if ch == 0 || ch == ' ' || cell.Attr().Hidden {
+				continue
+			}
+			face := r.regular
+			if cell.Attr().Bold {
+				face = r.bold
+			}
+			alpha := uint8(0xff)
+			if cell.Attr().Dim {
+				alpha = 0x80
+			}
+			colour := toNRGBA(fg, alpha)
+			d := font.Drawer{
+				Dst:  img,
+				Src:  image.NewUniform(colour),
+				Face: face,
+				Dot:  fixed.P(col*r.cellWidth, row*r.cellHeight+r.ascent),
+			}
+			d.DrawString(cell.Text())
+			if cell.Attr().Underline {
+				y := row*r.cellHeight + r.ascent + 1
+				underline := toNRGBA(cell.UnderlineColour(), alpha)
+				draw.Draw(img, image.Rect(cellRect.Min.X, y, cellRect.Max.X, y+1), image.NewUniform(underline), image.Point{}, draw.Over)
+			}
+		}
+	}
+
+	lines := frame.Snapshot.Lines()
+	if frame.ShowCursor && (frame.CursorY >= len(lines) || frame.CursorX >= len(lines[frame.CursorY].Cells())) {
+		// the cursor is past the end of what has been written, so there was no cell to draw it on above
+		x, y := frame.CursorX*r.cellWidth, frame.CursorY*r.cellHeight
+		draw.Draw(img, image.Rect(x, y, x+r.cellWidth, y+r.cellHeight), image.NewUniform(toRGBA(r.cursor)), image.Point{}, draw.Src)
+	}
+
+	return img
+}
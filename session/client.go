@@ -0,0 +1,216 @@
+package session
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+
+	"github.com/liamg/aminal/platform"
+)
+
+// Pty is a session kept by the daemon, standing in for a local pty under a window's terminal. A new shell is
+// started with CreateGuestProcess or CreateGuestCommand, or a session left running is attached to with Attach.
+type Pty struct {
+	socket string
+	conn   net.Conn
+	output *io.PipeReader
+	writer *io.PipeWriter
+	exited chan struct{}
+	err    error // why the session ended, nil if its shell exited or it was closed
+
+	lock    sync.Mutex // messages are sent whole from the terminal's and the window's goroutines
+	pending string     // the session to attach to once the terminal has its size
+	closed  bool
+}
+
+// NewPty creates a pty for a session kept by the daemon listening on socket
+func NewPty(socket string) *Pty {
+	output, writer := io.Pipe()
+	return &Pty{
+		socket: socket,
+		output: output,
+		writer: writer,
+		exited: make(chan struct{}),
+	}
+}
+
+func (p *Pty) dial() error {
+	conn, err := platform.DialSocket(p.socket, connectTimeout)
+	if err != nil {
+		return fmt.Errorf("Failed to connect to the daemon: %s", err)
+	}
+	p.conn = conn
+	return nil
+}
+
+// CreateGuestProcess asks the daemon to start the shell in a new session
+func (p *Pty) CreateGuestProcess(imagePath string, dir string) (platform.Process, error) {
+	return p.CreateGuestCommand([]string{imagePath}, dir)
+}
+
+// CreateGuestCommand asks the daemon to start a program with arguments in a new session
+func (p *Pty) CreateGuestCommand(args []string, dir string) (platform.Process, error) {
+	if err := p.dial(); err != nil {
+		return nil, err
+	}
+	err := writeJSON(p.conn, msgOpen, openRequest{Command: args, Dir: dir})
+	if err == nil {
+		var kind byte
+		var payload []byte
+		kind, payload, err = readMessage(p.conn)
+		switch {
+		case err != nil:
+		case kind == msgError:
+			err = errors.New(string(payload))
+		case kind != msgReady:
+			err = fmt.Errorf("Unexpected answer from the daemon: %q", kind)
+		}
+	}
+	if err != nil {
+		p.conn.Close()
+		return nil, err
+	}
+	go p.receive()
+	return &remoteProcess{pty: p}, nil
+}
+
+// Attach connects to a session left running in the daemon. The session's terminal is resized to match the
+// window's before the window's terminal is brought up to date with it, so that's left until the window's
+// terminal is first given its size.
+func (p *Pty) Attach(id string) (platform.Process, error) {
+	if err := p.dial(); err != nil {
+		return nil, err
+	}
+	p.pending = id
+	go p.receive()
+	return &remoteProcess{pty: p}, nil
+}
+
+// receive passes the session's output to the terminal until the session ends or the window detaches
+func (p *Pty) receive() {
+	for {
+		kind, payload, err := readMessage(p.conn)
+		if err != nil {
+			p.lock.Lock()
+			closed := p.closed
+			p.lock.Unlock()
+			if closed {
+				err = nil
+			} else {
+				err = fmt.Errorf("Lost the connection to the daemon: %s", err)
+			}
+			p.end(err)
+			return
+		}
+		switch kind {
+		case msgOutput:
+			if _, err := p.writer.Write(payload); err != nil {
+				p.end(nil)
+				return
+			}
+		case msgExit:
+			p.exit(payload)
+			return
+		case msgError:
+			p.end(errors.New(string(payload)))
+			return
+		}
+	}
+}
+
+// exit ends the session once its shell has exited, with how it exited as the daemon reported it. Reading ends
+// as it would with a local pty, while waiting for the shell returns a platform.ExitError unless it exited with 0.
+func (p *Pty) exit(payload []byte) {
+	var report exitReport
+	if err := json.Unmarshal(payload, &report); err == nil && (report.Status != 0 || report.Signal != "") {
+		p.err = &platform.ExitError{Status: report.Status, Signal: report.Signal}
+	}
+	p.conn.Close()
+	p.writer.Close()
+	close(p.exited)
+}
+
+func (p *Pty) end(err error) {
+	p.err = err
+	p.conn.Close()
+	p.writer.CloseWithError(err)
+	close(p.exited)
+}
+
+func (p *Pty) send(kind byte, payload []byte) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.conn == nil {
+		return errors.New("The session hasn't been started")
+	}
+	if p.pending != "" {
+		return nil
+	}
+	return writeMessage(p.conn, kind, payload)
+}
+
+func (p *Pty) Read(b []byte) (int, error) {
+	return p.output.Read(b)
+}
+
+// Write sends input to the shell. Anything written before the session has been attached to is dropped, which
+// can only be the terminal reporting focus.
+func (p *Pty) Write(b []byte) (int, error) {
+	if err := p.send(msgInput, b); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// Resize resizes the session's terminal and pty, attaching to the session if it's the first size given
+func (p *Pty) Resize(x int, y int) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.conn == nil {
+		return nil
+	}
+	size := windowSize{Cols: x, Rows: y}
+	if p.pending != "" {
+		id := p.pending
+		p.pending = ""
+		return writeJSON(p.conn, msgAttach, attachRequest{ID: id, windowSize: size})
+	}
+	return writeJSON(p.conn, msgResize, size)
+}
+
+// Close ends the session, as closing a local pty would
+func (p *Pty) Close() error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.conn == nil {
+		return p.output.Close()
+	}
+	p.closed = true
+	if p.pending == "" {
+		writeMessage(p.conn, msgClose, nil)
+	}
+	return p.conn.Close()
+}
+
+func (p *Pty) GetPlatformDependentSettings() platform.PlatformDependentSettings {
+	return platform.DefaultSettings()
+}
+
+// remoteProcess is the shell of a session kept by the daemon
+type remoteProcess struct {
+	pty *Pty
+}
+
+// Wait waits for the session to end, returning an error if the connection to it was lost or the shell exited
+// with a non-zero status
+func (p *remoteProcess) Wait() error {
+	<-p.pty.exited
+	return p.pty.err
+}
+
+func (p *remoteProcess) Close() error {
+	return nil
+}
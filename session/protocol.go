@@ -0,0 +1,149 @@
+// Package session keeps shells going in a headless daemon (aminal --daemon), which windows attach to and detach
+// from over a local socket. The daemon runs a terminal for each shell, so a window attaching to it, even after the
+// one before crashed, is sent everything it needs to show the scrollback and screen as they are, followed by the
+// shell's output as it arrives.
+package session
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/user"
+	"path/filepath"
+	"time"
+
+	"github.com/liamg/aminal/platform"
+)
+
+// Messages are a type byte and a big endian uint32 length, followed by that many bytes of payload
+const (
+	msgOpen   byte = 'n' // start a shell or program, with an openRequest
+	msgAttach byte = 'a' // attach to a detached session, with an attachRequest
+	msgList   byte = 'l' // list the sessions, answered with a JSON []Info
+	msgReady  byte = 'y' // the session was opened or attached to, with its Info
+	msgInput  byte = 'i' // typed into the shell
+	msgResize byte = 'r' // the window's terminal has been resized, with a windowSize
+	msgClose  byte = 'c' // end the session
+	msgOutput byte = 'o' // the shell's output
+	msgExit   byte = 'x' // the shell has exited, with an exitReport
+	msgError  byte = 'e' // the request failed, with the reason
+)
+
+// maxMessage is the most a message can carry, well above any output read at once, to catch a corrupt stream
+const maxMessage = 64 << 20
+
+// connectTimeout is how long the daemon has to answer before it's taken not to be running
+const connectTimeout = 2 * time.Second
+
+type windowSize struct {
+	Cols int `json:"cols"`
+	Rows int `json:"rows"`
+}
+
+type openRequest struct {
+	Command []string `json:"command"` // the shell, or a program and its arguments
+	Dir     string   `json:"dir"`
+}
+
+type attachRequest struct {
+	ID string `json:"id"`
+	windowSize
+}
+
+type exitReport struct {
+	Status int    `json:"status"` // -1 if the shell was killed or it isn't known
+	Signal string `json:"signal"` // what killed the shell, if it was
+}
+
+// Info describes a session kept by the daemon
+type Info struct {
+	ID       string `json:"id"`
+	Title    string `json:"title"`
+	Dir      string `json:"dir"` // the shell's working directory, if it reports it
+	Attached bool   `json:"attached"`
+}
+
+func writeMessage(w io.Writer, kind byte, payload []byte) error {
+	header := make([]byte, 5, 5+len(payload))
+	header[0] = kind
+	binary.BigEndian.PutUint32(header[1:], uint32(len(payload)))
+	_, err := w.Write(append(header, payload...))
+	return err
+}
+
+func writeJSON(w io.Writer, kind byte, v interface{}) error {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return writeMessage(w, kind, payload)
+}
+
+func readMessage(r io.Reader) (byte, []byte, error) {
+	header := make([]byte, 5)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, nil, err
+	}
+	length := binary.BigEndian.Uint32(header[1:])
+	if length > maxMessage {
+		return 0, nil, fmt.Errorf("Message of %d bytes is too big", length)
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, err
+	}
+	return header[0], payload, nil
+}
+
+// SocketPath returns where the daemon listens unless the config says otherwise: in $XDG_RUNTIME_DIR, or else in
+// a directory of the user's own in the temporary directory, which is checked that no one else can get into
+func SocketPath() (string, error) {
+	dir := os.Getenv("XDG_RUNTIME_DIR")
+	if dir == "" {
+		name := "aminal"
+		if u, err := user.Current(); err == nil {
+			name = fmt.Sprintf("aminal-%s", u.Uid)
+		}
+		dir = filepath.Join(os.TempDir(), name)
+	}
+	if err := platform.PrivateDir(dir); err != nil {
+		return "", fmt.Errorf("Failed to make a private directory for the daemon's socket: %s", err)
+	}
+	return filepath.Join(dir, "aminal.sock"), nil
+}
+
+// Running returns true if a daemon of the user's is listening on the socket
+func Running(socket string) bool {
+	return platform.SocketInUse(socket, connectTimeout)
+}
+
+// Listen listens on the socket for windows to connect, replacing a socket left behind by a daemon which is no
+// longer running. Only the user can connect to it.
+func Listen(socket string) (net.Listener, error) {
+	return platform.ListenPrivateSocket(socket, connectTimeout)
+}
+
+// List returns the sessions the daemon listening on the socket is keeping
+func List(socket string) ([]Info, error) {
+	conn, err := platform.DialSocket(socket, connectTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if err := writeMessage(conn, msgList, nil); err != nil {
+		return nil, err
+	}
+	kind, payload, err := readMessage(conn)
+	if err != nil {
+		return nil, err
+	}
+	if kind != msgList {
+		return nil, fmt.Errorf("Unexpected answer from the daemon: %q", kind)
+	}
+	var sessions []Info
+	err = json.Unmarshal(payload, &sessions)
+	return sessions, err
+}
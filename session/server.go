@@ -0,0 +1,364 @@
+package session
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/liamg/aminal/platform"
+	"github.com/liamg/aminal/terminal"
+	"go.uber.org/zap"
+)
+
+// settleTime is how long a terminal must have been quiet before it's taken to have caught up with its output
+const settleTime = 50 * time.Millisecond
+
+// idleTimeout is how long a daemon waits for its first session before exiting, so one started for a window which
+// never asked it for a shell doesn't linger
+const idleTimeout = time.Minute
+
+// StartFunc starts a command, the program and its arguments, in dir, or the daemon's own directory if dir is empty,
+// on a pty of its own. An empty command starts the daemon's shell.
+type StartFunc func(command []string, dir string) (platform.Pty, platform.Process, error)
+
+// Server is the daemon's side of the sessions: it starts shells for windows, runs a terminal for each to keep its
+// screen and scrollback, and passes the shell's output on to the window attached to it, if there is one
+type Server struct {
+	start   StartFunc
+	options terminal.Options
+	logger  *zap.SugaredLogger
+	done    chan struct{} // closed once the last session ends, or if none is started in time
+	idle    time.Duration // how long to wait for the first session
+
+	lock     sync.Mutex
+	sessions map[string]*hostedSession
+	nextID   int
+}
+
+// hostedSession is a shell kept by the daemon
+type hostedSession struct {
+	id       string
+	pty      platform.Pty
+	process  platform.Process
+	terminal *terminal.Terminal
+	output   chan bool
+
+	// lock is held while output is passed on to the window, so a window attaching is sent each part of the output
+	// exactly once, either in the replay or afterwards
+	lock   sync.Mutex
+	client net.Conn // nil while detached
+}
+
+// NewServer creates a daemon starting shells with start and running terminals with the given options
+func NewServer(start StartFunc, options terminal.Options, logger *zap.SugaredLogger) *Server {
+	return &Server{
+		start:    start,
+		options:  options,
+		logger:   logger,
+		done:     make(chan struct{}),
+		idle:     idleTimeout,
+		sessions: map[string]*hostedSession{},
+	}
+}
+
+// Serve answers the windows connecting to listener until it's closed. If none of them starts a session within
+// idleTimeout, the server is done.
+func (s *Server) Serve(listener net.Listener) error {
+	time.AfterFunc(s.idle, func() {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		if s.nextID == 0 {
+			s.logger.Infof("No sessions were started, exiting")
+			s.finish()
+		}
+	})
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		go s.handle(conn)
+	}
+}
+
+// Done is closed when the last session has ended, or when none has been started for idleTimeout after it started
+// serving, which is when the daemon has nothing left to do
+func (s *Server) Done() <-chan struct{} {
+	return s.done
+}
+
+// Sessions describes the sessions being kept, oldest first
+func (s *Server) Sessions() []Info {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	sessions := []Info{}
+	for i := 1; i <= s.nextID; i++ {
+		if hs, ok := s.sessions[strconv.Itoa(i)]; ok {
+			sessions = append(sessions, hs.info())
+		}
+	}
+	return sessions
+}
+
+func (s *Server) handle(conn net.Conn) {
+	kind, payload, err := readMessage(conn)
+	if err != nil {
+		conn.Close()
+		return
+	}
+
+	var hs *hostedSession
+	switch kind {
+	case msgList:
+		writeJSON(conn, msgList, s.Sessions())
+		conn.Close()
+		return
+	case msgOpen:
+		var request openRequest
+		if err = json.Unmarshal(payload, &request); err == nil {
+			hs, err = s.open(request)
+		}
+		if err == nil {
+			err = hs.attach(conn, windowSize{}, false)
+		}
+	case msgAttach:
+		var request attachRequest
+		if err = json.Unmarshal(payload, &request); err == nil {
+			s.lock.Lock()
+			hs = s.sessions[request.ID]
+			s.lock.Unlock()
+			if hs == nil {
+				err = fmt.Errorf("There's no session %s", request.ID)
+			} else {
+				err = hs.attach(conn, request.windowSize, true)
+			}
+		}
+	default:
+		err = fmt.Errorf("Unexpected request: %q", kind)
+	}
+	if err != nil {
+		s.logger.Errorf("Failed to open session: %s", err)
+		writeMessage(conn, msgError, []byte(err.Error()))
+		conn.Close()
+		return
+	}
+
+	hs.serve(conn, s.logger)
+}
+
+// open starts a shell and the terminal keeping its screen
+func (s *Server) open(request openRequest) (*hostedSession, error) {
+	pty, process, err := s.start(request.Command, request.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	hs := &hostedSession{
+		pty:     pty,
+		process: process,
+		output:  make(chan bool, 1),
+	}
+	// the terminal's answers to the shell's queries are thrown away, as the attached window's terminal answers
+	hs.terminal = terminal.New(&hostPty{session: hs}, s.logger, s.options)
+	hs.terminal.SetSize(80, 25)
+	hs.terminal.AttachOutputHandler(hs.output)
+
+	s.lock.Lock()
+	s.nextID++
+	hs.id = strconv.Itoa(s.nextID)
+	s.sessions[hs.id] = hs
+	s.lock.Unlock()
+
+	go func() {
+		if err := hs.terminal.Read(); err != nil {
+			s.logger.Debugf("Read from session %s ended: %s", hs.id, err)
+		}
+	}()
+	go func() {
+		err := process.Wait()
+		report := exitReport{Status: platform.ExitStatus(err), Signal: platform.KilledBy(err)}
+		if report.Status < 0 && report.Signal == "" {
+			s.logger.Errorf("Failed to wait for the shell of session %s: %s", hs.id, err)
+		}
+		s.end(hs, report)
+	}()
+
+	s.logger.Infof("Started session %s: %s", hs.id, strings.Join(request.Command, " "))
+	return hs, nil
+}
+
+// end forgets a session once its shell has exited, telling the window attached to it how it exited
+func (s *Server) end(hs *hostedSession, report exitReport) {
+	hs.lock.Lock()
+	if hs.client != nil {
+		writeJSON(hs.client, msgExit, report)
+		hs.client.Close()
+		hs.client = nil
+	}
+	hs.lock.Unlock()
+	hs.process.Close()
+	hs.pty.Close()
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.sessions, hs.id)
+	s.logger.Infof("Session %s ended", hs.id)
+	if len(s.sessions) == 0 {
+		s.finish()
+	}
+}
+
+// finish closes done, if it isn't already. The server must be locked.
+func (s *Server) finish() {
+	select {
+	case <-s.done:
+	default:
+		close(s.done)
+	}
+}
+
+func (hs *hostedSession) info() Info {
+	hs.lock.Lock()
+	defer hs.lock.Unlock()
+	return Info{
+		ID:       hs.id,
+		Title:    hs.terminal.GetTitle(),
+		Dir:      hs.terminal.WorkingDirectory(),
+		Attached: hs.client != nil,
+	}
+}
+
+// attach makes conn the session's window, detaching any window already attached. With replay, the window is
+// sent the sequences bringing its terminal to the state of the session's, once it has caught up with the output.
+func (hs *hostedSession) attach(conn net.Conn, size windowSize, replay bool) error {
+	hs.lock.Lock()
+	defer hs.lock.Unlock()
+
+	if hs.client != nil {
+		writeMessage(hs.client, msgError, []byte("Attached to from another window"))
+		hs.client.Close()
+		hs.client = nil
+	}
+	if size.Cols > 0 && size.Rows > 0 {
+		hs.terminal.SetSize(uint(size.Cols), uint(size.Rows))
+	}
+
+	info := Info{ID: hs.id, Title: hs.terminal.GetTitle(), Dir: hs.terminal.WorkingDirectory(), Attached: true}
+	if err := writeJSON(conn, msgReady, info); err != nil {
+		return err
+	}
+	if replay {
+		hs.settle()
+		if err := writeMessage(conn, msgOutput, hs.terminal.Replay()); err != nil {
+			return err
+		}
+	}
+	hs.client = conn
+	return nil
+}
+
+// settle waits for the terminal to finish with the output it has been given, which it has once it has stopped
+// reporting output for a moment. No more can arrive while the lock is held.
+func (hs *hostedSession) settle() {
+	select {
+	case <-hs.output:
+	default:
+	}
+	for {
+		select {
+		case <-hs.output:
+		case <-time.After(settleTime):
+			return
+		}
+	}
+}
+
+// serve handles what the window sends until it detaches, or the connection is lost
+func (hs *hostedSession) serve(conn net.Conn, logger *zap.SugaredLogger) {
+	defer hs.detach(conn)
+	for {
+		kind, payload, err := readMessage(conn)
+		if err != nil {
+			return
+		}
+		switch kind {
+		case msgInput:
+			if _, err := hs.pty.Write(payload); err != nil {
+				logger.Errorf("Failed to write to session %s: %s", hs.id, err)
+			}
+		case msgResize:
+			var size windowSize
+			if err := json.Unmarshal(payload, &size); err == nil && size.Cols > 0 && size.Rows > 0 {
+				hs.terminal.SetSize(uint(size.Cols), uint(size.Rows))
+			}
+		case msgClose:
+			// the shell exits once its pty has gone, which ends the session
+			hs.pty.Close()
+			return
+		default:
+			logger.Errorf("Unexpected message for session %s: %q", hs.id, kind)
+		}
+	}
+}
+
+func (hs *hostedSession) detach(conn net.Conn) {
+	hs.lock.Lock()
+	defer hs.lock.Unlock()
+	if hs.client == conn {
+		hs.client = nil
+	}
+	conn.Close()
+}
+
+// forward passes output on to the attached window. A window which can't keep up holds the shell up, as it would
+// a local terminal, and a window which has gone is detached.
+func (hs *hostedSession) forward(data []byte) {
+	hs.lock.Lock()
+	defer hs.lock.Unlock()
+	if hs.client == nil {
+		return
+	}
+	if err := writeMessage(hs.client, msgOutput, data); err != nil {
+		hs.client.Close()
+		hs.client = nil
+	}
+}
+
+// hostPty is the session's pty as its terminal in the daemon sees it: output is passed on to the window as the
+// terminal reads it, and the terminal's answers to queries go nowhere
+type hostPty struct {
+	session *hostedSession
+}
+
+func (p *hostPty) Read(b []byte) (int, error) {
+	n, err := p.session.pty.Read(b)
+	if n > 0 {
+		p.session.forward(b[:n])
+	}
+	return n, err
+}
+
+func (p *hostPty) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+func (p *hostPty) Close() error {
+	return p.session.pty.Close()
+}
+
+func (p *hostPty) Resize(x int, y int) error {
+	return p.session.pty.Resize(x, y)
+}
+
+func (p *hostPty) CreateGuestProcess(imagePath string, dir string) (platform.Process, error) {
+	return nil, errors.New("The session's shell has already been started")
+}
+
+func (p *hostPty) GetPlatformDependentSettings() platform.PlatformDependentSettings {
+	return p.session.pty.GetPlatformDependentSettings()
+}
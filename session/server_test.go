@@ -0,0 +1,248 @@
+package session
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/liamg/aminal/platform"
+	"github.com/liamg/aminal/terminal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// fakeShell is a shell whose output is written by the test, and whose input is collected
+type fakeShell struct {
+	output *io.PipeReader
+	writer *io.PipeWriter
+	input  chan []byte
+	exited chan struct{}
+	err    error // what waiting for the shell returns once it has exited
+}
+
+func newFakeShell() *fakeShell {
+	output, writer := io.Pipe()
+	return &fakeShell{
+		output: output,
+		writer: writer,
+		input:  make(chan []byte, 16),
+		exited: make(chan struct{}),
+	}
+}
+
+func (s *fakeShell) Read(b []byte) (int, error) { return s.output.Read(b) }
+func (s *fakeShell) Write(b []byte) (int, error) {
+	s.input <- append([]byte{}, b...)
+	return len(b), nil
+}
+func (s *fakeShell) Close() error {
+	s.writer.Close()
+	s.exit()
+	return nil
+}
+func (s *fakeShell) Resize(x int, y int) error { return nil }
+func (s *fakeShell) CreateGuestProcess(imagePath string, dir string) (platform.Process, error) {
+	return nil, nil
+}
+func (s *fakeShell) GetPlatformDependentSettings() platform.PlatformDependentSettings {
+	return platform.DefaultSettings()
+}
+func (s *fakeShell) Wait() error { <-s.exited; return s.err }
+
+func (s *fakeShell) exit() {
+	select {
+	case <-s.exited:
+	default:
+		close(s.exited)
+	}
+}
+
+type fakeProcess struct{ shell *fakeShell }
+
+func (p *fakeProcess) Wait() error  { return p.shell.Wait() }
+func (p *fakeProcess) Close() error { return nil }
+
+func startServer(t *testing.T) (*Server, string, chan *fakeShell) {
+	dir, err := ioutil.TempDir("", "aminal-session")
+	require.Nil(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	socket := filepath.Join(dir, "daemon.sock")
+	listener, err := Listen(socket)
+	require.Nil(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	shells := make(chan *fakeShell, 4)
+	start := func(command []string, dir string) (platform.Pty, platform.Process, error) {
+		s := newFakeShell()
+		shells <- s
+		return s, &fakeProcess{shell: s}, nil
+	}
+	server := NewServer(start, terminal.DefaultOptions(), zap.NewNop().Sugar())
+	go server.Serve(listener)
+	return server, socket, shells
+}
+
+// readUntil reads from r until what has been read contains want
+func readUntil(t *testing.T, r io.Reader, want string) string {
+	var read bytes.Buffer
+	found := make(chan struct{})
+	go func() {
+		b := make([]byte, 1024)
+		for !strings.Contains(read.String(), want) {
+			n, err := r.Read(b)
+			read.Write(b[:n])
+			if err != nil {
+				return
+			}
+		}
+		close(found)
+	}()
+	select {
+	case <-found:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Timed out waiting for %q", want)
+	}
+	return read.String()
+}
+
+func TestListenRefusesASecondDaemon(t *testing.T) {
+	_, socket, _ := startServer(t)
+	assert.True(t, Running(socket))
+	_, err := Listen(socket)
+	assert.NotNil(t, err)
+}
+
+func TestDaemonWithoutSessionsExits(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	defer listener.Close()
+
+	server := NewServer(nil, terminal.DefaultOptions(), zap.NewNop().Sugar())
+	server.idle = 10 * time.Millisecond
+	go server.Serve(listener)
+
+	select {
+	case <-server.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("The daemon is still waiting for a session")
+	}
+}
+
+func TestSessionsSurviveTheWindowDetaching(t *testing.T) {
+	server, socket, shells := startServer(t)
+
+	window := NewPty(socket)
+	process, err := window.CreateGuestProcess("/bin/sh", "")
+	require.Nil(t, err)
+	require.Nil(t, window.Resize(20, 5))
+	shell := <-shells
+
+	shell.writer.Write([]byte("$ echo hello\r\nhello\r\n$ "))
+	readUntil(t, window, "hello\r\n$ ")
+
+	_, err = window.Write([]byte("ls\r"))
+	require.Nil(t, err)
+	select {
+	case input := <-shell.input:
+		assert.Equal(t, "ls\r", string(input))
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for input")
+	}
+
+	// the window crashing leaves the session running
+	window.conn.Close()
+	process.Wait()
+	require.Eventually(t, func() bool {
+		sessions, err := List(socket)
+		return err == nil && len(sessions) == 1 && !sessions[0].Attached
+	}, 5*time.Second, 10*time.Millisecond)
+
+	shell.writer.Write([]byte("file.txt\r\n$ "))
+
+	window = NewPty(socket)
+	process, err = window.Attach(server.Sessions()[0].ID)
+	require.Nil(t, err)
+	require.Nil(t, window.Resize(20, 5))
+	replay := readUntil(t, window, "file.txt")
+	assert.Contains(t, replay, "hello")
+	assert.True(t, server.Sessions()[0].Attached)
+
+	shell.writer.Write([]byte("more"))
+	readUntil(t, window, "more")
+
+	// closing the window's terminal ends the session, after which the daemon has nothing to do
+	require.Nil(t, window.Close())
+	assert.Nil(t, process.Wait())
+	select {
+	case <-server.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the daemon to finish")
+	}
+	assert.Empty(t, server.Sessions())
+}
+
+func TestShellExitingEndsTheSession(t *testing.T) {
+	server, socket, shells := startServer(t)
+
+	window := NewPty(socket)
+	process, err := window.CreateGuestProcess("/bin/sh", "")
+	require.Nil(t, err)
+	shell := <-shells
+
+	shell.writer.Close()
+	shell.exit()
+	assert.Nil(t, process.Wait())
+	_, err = window.Read(make([]byte, 1))
+	assert.Equal(t, io.EOF, err)
+	<-server.Done()
+}
+
+func TestExitStatusIsPassedOn(t *testing.T) {
+	_, socket, shells := startServer(t)
+
+	window := NewPty(socket)
+	process, err := window.CreateGuestProcess("/bin/sh", "")
+	require.Nil(t, err)
+	shell := <-shells
+
+	shell.err = &platform.ExitError{Status: 3}
+	shell.writer.Close()
+	shell.exit()
+	assert.Equal(t, 3, platform.ExitStatus(process.Wait()))
+	_, err = window.Read(make([]byte, 1))
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestShellBeingKilledIsPassedOn(t *testing.T) {
+	_, socket, shells := startServer(t)
+
+	window := NewPty(socket)
+	process, err := window.CreateGuestProcess("/bin/sh", "")
+	require.Nil(t, err)
+	shell := <-shells
+
+	shell.err = &platform.ExitError{Status: -1, Signal: "segmentation fault"}
+	shell.writer.Close()
+	shell.exit()
+	err = process.Wait()
+	assert.Equal(t, "segmentation fault", platform.KilledBy(err))
+	assert.Equal(t, -1, platform.ExitStatus(err))
+}
+
+func TestAttachingToAMissingSessionFails(t *testing.T) {
+	_, socket, _ := startServer(t)
+
+	window := NewPty(socket)
+	process, err := window.Attach("7")
+	require.Nil(t, err)
+	require.Nil(t, window.Resize(20, 5))
+	assert.NotNil(t, process.Wait())
+}
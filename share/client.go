@@ -0,0 +1,54 @@
+package share
+
+// clientHTML is the page viewers open, which draws frames from the websocket into a <pre>
+const clientHTML = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Aminal</title>
+<style>
+body { margin: 0; }
+pre { margin: 8px; font-family: "Hack", monospace; font-size: 14px; line-height: 1.2; }
+#status { position: fixed; top: 4px; right: 8px; font-family: sans-serif; font-size: 12px; opacity: 0.6; }
+</style>
+</head>
+<body>
+<div id="status">connecting...</div>
+<pre id="screen"></pre>
+<script>
+const screen = document.getElementById("screen");
+const status = document.getElementById("status");
+const scheme = location.protocol === "https:" ? "wss://" : "ws://";
+const ws = new WebSocket(scheme + location.host + "/ws" + location.search);
+
+ws.onopen = () => { status.textContent = "watching (read-only)"; };
+ws.onclose = () => { status.textContent = "disconnected"; screen.style.opacity = 0.5; };
+ws.onmessage = (event) => {
+	const frame = JSON.parse(event.data);
+	document.title = frame.title || "Aminal";
+	document.body.style.background = frame.background;
+	document.body.style.color = frame.foreground;
+	status.style.color = frame.foreground;
+
+	const lines = document.createDocumentFragment();
+	for (const line of frame.lines) {
+		for (const s of line) {
+			const el = document.createElement("span");
+			el.textContent = s.t;
+			el.style.color = s.f;
+			if (s.b !== frame.background) {
+				el.style.background = s.b;
+			}
+			if (s.o) {
+				el.style.fontWeight = "bold";
+			}
+			lines.appendChild(el);
+		}
+		lines.appendChild(document.createTextNode("\n"));
+	}
+	screen.replaceChildren(lines);
+};
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,124 @@
+package share
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/liamg/aminal/buffer"
+	"github.com/liamg/aminal/terminal"
+)
+
+// span is a run of text on a line which shares the same attributes
+type span struct {
+	Text string `json:"t"`
+	Fg   string `json:"f"`
+	Bg   string `json:"b"`
+	Bold bool   `json:"o,omitempty"`
+}
+
+// frame is the state of the screen sent to viewers
+type frame struct {
+	Title      string   `json:"title"`
+	Foreground string   `json:"foreground"`
+	Background string   `json:"background"`
+	Lines      [][]span `json:"lines"`
+}
+
+func colourToHex(c [3]float32) string {
+	return fmt.Sprintf(
+		"#%02x%02x%02x",
+		uint8(math.Floor(float64(255*c[0]))),
+		uint8(math.Floor(float64(255*c[1]))),
+		uint8(math.Floor(float64(255*c[2]))),
+	)
+}
+
+func newFrame(t *terminal.Terminal) frame {
+	options := t.Options()
+	f := frame{
+		Title:      t.GetTitle(),
+		Foreground: colourToHex(options.Foreground),
+		Background: colourToHex(options.Background),
+	}
+
+	activeBuffer := t.ActiveBuffer()
+	width := int(activeBuffer.ViewWidth())
+	showCursor := t.Modes().ShowCursor && t.GetScrollOffset() == 0
+	cx, cy := int(t.GetLogicalCursorX()), int(t.GetLogicalCursorY())
+
+	for y, line := range t.GetVisibleLines() {
+		spans := []span{}
+		cells := line.Cells()
+		for x := 0; x < width && (x < len(cells) || (showCursor && y == cy && x <= cx)); x++ {
+			s := span{Text: " ", Fg: f.Foreground, Bg: f.Background}
+			if x < len(cells) {
+				s = cellSpan(cells[x])
+			}
+			if showCursor && x == cx && y == cy {
+				s.Fg, s.Bg = s.Bg, s.Fg
+			}
+			if n := len(spans); n > 0 && spans[n-1].Fg == s.Fg && spans[n-1].Bg == s.Bg && spans[n-1].Bold == s.Bold {
+				spans[n-1].Text += s.Text
+			} else {
+				spans = append(spans, s)
+			}
+		}
+		f.Lines = append(f.Lines, spans)
+	}
+
+	return f
+}
+
+func cellSpan(cell buffer.Cell) span {
+	text := cell.Text()
+	if cell.Continuation() {
+		// the wide character in the cell before already covers this one
+		text = ""
+	} else if cell.Attr().Hidden {
+		text = " "
+	}
+	return span{
+		Text: text,
+		Fg:   colourToHex(cell.Fg()),
+		Bg:   colourToHex(cell.Bg()),
+		Bold: cell.Attr().Bold,
+	}
+}
+
+// hexToParams turns a colour from colourToHex into the parameters of a truecolour SGR
+func hexToParams(hex string) string {
+	var r, g, b uint8
+	if _, err := fmt.Sscanf(hex, "#%02x%02x%02x", &r, &g, &b); err != nil {
+		return "0;0;0"
+	}
+	return fmt.Sprintf("%d;%d;%d", r, g, b)
+}
+
+// sequences draws the frame on a terminal with the given number of rows, which has autowrap off and its own cursor
+// hidden, as the cursor is already drawn in the frame. Lines which don't fit are left off.
+func (f frame) sequences(rows int) []byte {
+	var out bytes.Buffer
+	background := hexToParams(f.Background)
+	for y := 0; y < rows; y++ {
+		fmt.Fprintf(&out, "\x1b[%d;1H", y+1)
+		if y < len(f.Lines) {
+			for _, s := range f.Lines[y] {
+				out.WriteString("\x1b[0")
+				if s.Bold {
+					out.WriteString(";1")
+				}
+				fmt.Fprintf(&out, ";38;2;%s;48;2;%sm%s", hexToParams(s.Fg), hexToParams(s.Bg), s.Text)
+			}
+		}
+		fmt.Fprintf(&out, "\x1b[0;48;2;%sm\x1b[K", background)
+	}
+	fmt.Fprintf(&out, "\x1b[0m\x1b]2;%s\x07", strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return -1
+		}
+		return r
+	}, f.Title))
+	return out.Bytes()
+}
@@ -0,0 +1,299 @@
+// Package share serves a live, read-only view of a terminal over a websocket, so others can watch a session
+package share
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/liamg/aminal/terminal"
+	"go.uber.org/zap"
+)
+
+// frames are sent at most this often, however chatty the terminal is
+const frameInterval = time.Millisecond * 50
+
+// Server shares a terminal with viewers connecting from a browser. Nothing viewers send is passed to the terminal.
+type Server struct {
+	terminal *terminal.Terminal
+	logger   *zap.SugaredLogger
+	output   chan bool
+
+	lock      sync.Mutex // guards everything below, which is changed by Start and Stop and read by the handlers
+	address   string     // host and port viewers connect to
+	token     string     // lets one viewer in, then is replaced
+	url       string
+	listener  net.Listener
+	http      *http.Server
+	stop      chan struct{}
+	viewers   map[*viewer]struct{}
+	lastFrame []byte
+}
+
+type viewer struct {
+	frames chan []byte
+	ws     *websocket
+}
+
+// NewServer creates a server for sharing the given terminal. It does nothing until started.
+func NewServer(t *terminal.Terminal, logger *zap.SugaredLogger) *Server {
+	s := &Server{
+		terminal: t,
+		logger:   logger,
+		output:   make(chan bool, 1),
+	}
+	t.AttachOutputHandler(s.output)
+	return s
+}
+
+func generateToken() (string, error) {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(token), nil
+}
+
+// newLink replaces the token, so the link with the old one stops working. The server must be locked.
+func (s *Server) newLink() error {
+	token, err := generateToken()
+	if err != nil {
+		return fmt.Errorf("Failed to generate token: %s", err)
+	}
+	s.token = token
+	s.url = fmt.Sprintf("http://%s/?token=%s", s.address, token)
+	return nil
+}
+
+// Start listens on address (e.g. "localhost:7681") and returns the url the first viewer should open. Each link's
+// token lets one viewer in, after which URL returns a new link for the next.
+func (s *Server) Start(address string) (string, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.listener != nil {
+		return s.url, nil
+	}
+
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return "", fmt.Errorf("Failed to listen on %s: %s", address, err)
+	}
+
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	if ip := net.ParseIP(host); ip != nil && ip.IsUnspecified() {
+		// listening on every interface, so give out a name the other machine has a chance of resolving
+		if hostname, err := os.Hostname(); err == nil {
+			host = hostname
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.serveClient)
+	mux.HandleFunc("/ws", s.serveWebsocket)
+
+	s.address = net.JoinHostPort(host, port)
+	if err := s.newLink(); err != nil {
+		listener.Close()
+		return "", err
+	}
+	s.listener = listener
+	s.http = &http.Server{Handler: mux}
+	s.stop = make(chan struct{})
+	s.viewers = map[*viewer]struct{}{}
+	s.lastFrame = nil
+
+	go func() {
+		if err := s.http.Serve(listener); err != nil && err != http.ErrServerClosed {
+			s.logger.Errorf("Session sharing stopped: %s", err)
+		}
+	}()
+	go s.broadcast(s.stop)
+
+	s.logger.Infof("Sharing session read-only at %s", s.url)
+	return s.url, nil
+}
+
+// Stop disconnects all viewers and stops listening
+func (s *Server) Stop() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.listener == nil {
+		return nil
+	}
+	close(s.stop)
+	err := s.http.Close()
+	// closing the server leaves the connections hijacked for websockets open, and a viewer which has stopped
+	// reading would otherwise never notice the stop
+	for v := range s.viewers {
+		_ = v.ws.close()
+	}
+	s.listener = nil
+	s.url = ""
+	s.token = ""
+	s.logger.Infof("Stopped sharing session")
+	return err
+}
+
+// Running returns true if the session is currently being shared
+func (s *Server) Running() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.listener != nil
+}
+
+// URL returns the address the next viewer should open, or an empty string if not running
+func (s *Server) URL() string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.url
+}
+
+// Viewers returns the number of people currently watching
+func (s *Server) Viewers() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return len(s.viewers)
+}
+
+// authorised returns true if the request has the current token. The server must be locked.
+func (s *Server) authorised(r *http.Request) bool {
+	return s.token != "" && subtle.ConstantTimeCompare([]byte(r.URL.Query().Get("token")), []byte(s.token)) == 1
+}
+
+// sameOrigin returns false if a browser is connecting from a page served by another site, which mustn't be able to
+// watch even if it has the link. Other programs, like a watching Aminal, don't send an origin.
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
+
+func (s *Server) serveClient(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	s.lock.Lock()
+	authorised := s.authorised(r)
+	s.lock.Unlock()
+	if !authorised {
+		http.Error(w, "Invalid token", http.StatusForbidden)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = w.Write([]byte(clientHTML))
+}
+
+func (s *Server) serveWebsocket(w http.ResponseWriter, r *http.Request) {
+	if !sameOrigin(r) {
+		http.Error(w, "Cross-origin viewers are not allowed", http.StatusForbidden)
+		return
+	}
+
+	// locked from checking the token until it's replaced, so only one viewer gets in with it
+	s.lock.Lock()
+	if !s.authorised(r) {
+		s.lock.Unlock()
+		http.Error(w, "Invalid token", http.StatusForbidden)
+		return
+	}
+	ws, err := upgrade(w, r)
+	if err != nil {
+		s.lock.Unlock()
+		s.logger.Errorf("Failed to accept viewer: %s", err)
+		return
+	}
+	defer ws.close()
+	if err := s.newLink(); err != nil {
+		// without a new token the old one would go on letting viewers in
+		s.token = ""
+		s.logger.Errorf("%s", err)
+	}
+
+	v := &viewer{frames: make(chan []byte, 1), ws: ws}
+	s.viewers[v] = struct{}{}
+	if s.lastFrame != nil {
+		v.frames <- s.lastFrame
+	}
+	stop := s.stop
+	s.lock.Unlock()
+
+	s.logger.Infof("Viewer connected from %s", r.RemoteAddr)
+
+	done := make(chan struct{})
+	go func() {
+		_ = ws.discardIncoming()
+		close(done)
+	}()
+
+	defer func() {
+		s.lock.Lock()
+		delete(s.viewers, v)
+		s.lock.Unlock()
+		s.logger.Infof("Viewer disconnected from %s", r.RemoteAddr)
+	}()
+
+	for {
+		select {
+		case data := <-v.frames:
+			if err := ws.writeText(data); err != nil {
+				return
+			}
+		case <-done:
+			return
+		case <-stop:
+			_ = ws.writeFrame(opClose, nil)
+			return
+		}
+	}
+}
+
+// broadcast sends the screen to viewers whenever it changes
+func (s *Server) broadcast(stop chan struct{}) {
+	ticker := time.NewTicker(time.Second) // catches changes which don't come from output, e.g. scrolling
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-s.output:
+		case <-ticker.C:
+		}
+
+		data, err := json.Marshal(newFrame(s.terminal))
+		if err != nil {
+			s.logger.Errorf("Failed to encode frame: %s", err)
+			continue
+		}
+
+		s.lock.Lock()
+		if !bytes.Equal(data, s.lastFrame) {
+			s.lastFrame = data
+			for v := range s.viewers {
+				// viewers which can't keep up only get the latest frame
+				select {
+				case <-v.frames:
+				default:
+				}
+				v.frames <- data
+			}
+		}
+		s.lock.Unlock()
+
+		time.Sleep(frameInterval)
+	}
+}
@@ -0,0 +1,175 @@
+package share
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/liamg/aminal/terminal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestAcceptKey(t *testing.T) {
+	// example from RFC 6455
+	assert.Equal(t, "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=", acceptKey("dGhlIHNhbXBsZSBub25jZQ=="))
+}
+
+func readFrame(t *testing.T, r *bufio.Reader) []byte {
+	header := make([]byte, 2)
+	_, err := io.ReadFull(r, header)
+	require.Nil(t, err)
+	require.Equal(t, byte(0x81), header[0])
+
+	length := uint64(header[1])
+	switch length {
+	case 126:
+		ext := make([]byte, 2)
+		_, err = io.ReadFull(r, ext)
+		require.Nil(t, err)
+		length = uint64(binary.BigEndian.Uint16(ext))
+	case 127:
+		ext := make([]byte, 8)
+		_, err = io.ReadFull(r, ext)
+		require.Nil(t, err)
+		length = binary.BigEndian.Uint64(ext)
+	}
+
+	payload := make([]byte, length)
+	_, err = io.ReadFull(r, payload)
+	require.Nil(t, err)
+	return payload
+}
+
+func TestSharing(t *testing.T) {
+	term, err := terminal.NewHeadless(20, 5, terminal.DefaultOptions())
+	require.Nil(t, err)
+	defer term.Close()
+	term.Feed([]byte("hello"))
+
+	server := NewServer(term.Terminal, zap.NewNop().Sugar())
+	shareURL, err := server.Start("127.0.0.1:0")
+	require.Nil(t, err)
+	defer server.Stop()
+
+	u, err := url.Parse(shareURL)
+	require.Nil(t, err)
+	token := u.Query().Get("token")
+	require.NotEmpty(t, token)
+
+	resp, err := http.Get(fmt.Sprintf("http://%s/?token=wrong", u.Host))
+	require.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	resp, err = http.Get(shareURL)
+	require.Nil(t, err)
+	page, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, string(page), "<pre")
+
+	crossOrigin, err := net.Dial("tcp", u.Host)
+	require.Nil(t, err)
+	defer crossOrigin.Close()
+	fmt.Fprintf(crossOrigin, "GET /ws?token=%s HTTP/1.1\r\nHost: %s\r\nOrigin: http://example.com\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n", token, u.Host)
+	refused, err := http.ReadResponse(bufio.NewReader(crossOrigin), nil)
+	require.Nil(t, err)
+	assert.Equal(t, http.StatusForbidden, refused.StatusCode, "pages on other sites can't watch")
+
+	conn, err := net.Dial("tcp", u.Host)
+	require.Nil(t, err)
+	defer conn.Close()
+
+	fmt.Fprintf(conn, "GET /ws?token=%s HTTP/1.1\r\nHost: %s\r\nOrigin: http://%s\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n", token, u.Host, u.Host)
+	r := bufio.NewReader(conn)
+	handshake, err := http.ReadResponse(r, nil)
+	require.Nil(t, err)
+	require.Equal(t, http.StatusSwitchingProtocols, handshake.StatusCode)
+	assert.Equal(t, "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=", handshake.Header.Get("Sec-WebSocket-Accept"))
+
+	server.output <- true
+
+	var f frame
+	require.Nil(t, json.Unmarshal(readFrame(t, r), &f))
+	require.NotEmpty(t, f.Lines)
+	text := ""
+	for _, s := range f.Lines[0] {
+		text += s.Text
+	}
+	assert.True(t, strings.HasPrefix(text, "hello"))
+
+	// the link only lets one viewer in, and there's a new one for the next
+	again, err := net.Dial("tcp", u.Host)
+	require.Nil(t, err)
+	defer again.Close()
+	fmt.Fprintf(again, "GET /ws?token=%s HTTP/1.1\r\nHost: %s\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n", token, u.Host)
+	refused, err = http.ReadResponse(bufio.NewReader(again), nil)
+	require.Nil(t, err)
+	assert.Equal(t, http.StatusForbidden, refused.StatusCode)
+	assert.NotEqual(t, shareURL, server.URL())
+	resp, err = http.Get(server.URL())
+	require.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// stopping hangs up on viewers
+	require.Nil(t, server.Stop())
+	require.Nil(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	_, err = io.Copy(ioutil.Discard, r)
+	assert.Nil(t, err, "the connection is closed rather than timing out")
+}
+
+func TestViewer(t *testing.T) {
+	term, err := terminal.NewHeadless(20, 5, terminal.DefaultOptions())
+	require.Nil(t, err)
+	defer term.Close()
+	term.Feed([]byte("hello"))
+
+	server := NewServer(term.Terminal, zap.NewNop().Sugar())
+	shareURL, err := server.Start("127.0.0.1:0")
+	require.Nil(t, err)
+	defer server.Stop()
+
+	_, err = NewViewer(strings.Replace(shareURL, "token=", "token=wrong", 1)).CreateGuestProcess("", "")
+	assert.NotNil(t, err)
+
+	viewer := NewViewer(shareURL)
+	process, err := viewer.CreateGuestProcess("", "")
+	require.Nil(t, err)
+	require.Nil(t, viewer.Resize(20, 5))
+	server.output <- true
+
+	// shown on a terminal of its own, the frame looks like the shared screen
+	watching := terminal.New(viewer, zap.NewNop().Sugar(), terminal.DefaultOptions())
+	require.Nil(t, watching.SetSize(20, 5))
+	go watching.Read()
+	require.Eventually(t, func() bool {
+		lines := watching.GetVisibleLines()
+		return len(lines) > 0 && strings.HasPrefix(lines[0].String(), "hello")
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.False(t, watching.Modes().ShowCursor)
+
+	n, err := viewer.Write([]byte("rm -rf /\r"))
+	assert.Nil(t, err)
+	assert.Equal(t, 9, n)
+
+	require.Nil(t, server.Stop())
+	require.Eventually(t, func() bool {
+		lines := watching.GetVisibleLines()
+		return len(lines) == 5 && strings.Contains(lines[4].String(), "Sharing stopped")
+	}, 5*time.Second, 10*time.Millisecond)
+
+	require.Nil(t, viewer.Close())
+	assert.Nil(t, process.Wait())
+}
@@ -0,0 +1,148 @@
+package share
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"sync"
+
+	"github.com/liamg/aminal/platform"
+)
+
+// viewerSetup hides the cursor, which frames draw themselves, and turns autowrap off so lines too long for the
+// window are cut off rather than spilling onto the next
+const viewerSetup = "\x1b[?25l\x1b[?7l\x1b[2J"
+
+// Viewer watches a session shared by another Aminal, standing in for the pty of the terminal it's shown in.
+// What's typed into that terminal is thrown away.
+type Viewer struct {
+	url    string
+	ws     *websocket
+	output *io.PipeReader
+	writer *io.PipeWriter
+	closed chan struct{}
+	once   sync.Once
+
+	lock sync.Mutex // held while drawing, so frames aren't interleaved
+	rows int
+	last *frame // drawn again when the window is resized
+}
+
+// NewViewer creates a viewer for the session shared at shareURL, the link given when sharing started
+func NewViewer(shareURL string) *Viewer {
+	output, writer := io.Pipe()
+	return &Viewer{
+		url:    shareURL,
+		output: output,
+		writer: writer,
+		closed: make(chan struct{}),
+		rows:   25,
+	}
+}
+
+// CreateGuestProcess connects to the sharing Aminal. The process returned finishes when the viewer is closed,
+// and not when sharing stops, so the last of the screen can still be seen.
+func (v *Viewer) CreateGuestProcess(imagePath string, dir string) (platform.Process, error) {
+	u, err := url.Parse(v.url)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid link: %s", err)
+	}
+	if u.Scheme != "http" || u.Host == "" {
+		return nil, fmt.Errorf("Invalid link: expected the http:// link given when sharing started")
+	}
+	u.Path = "/ws"
+	ws, err := dial(u)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to connect to %s: %s", u.Host, err)
+	}
+	v.ws = ws
+	go v.receive()
+	return &viewerProcess{viewer: v}, nil
+}
+
+// receive draws the frames the sharing Aminal sends until it stops sharing
+func (v *Viewer) receive() {
+	if _, err := v.writer.Write([]byte(viewerSetup)); err != nil {
+		return
+	}
+	for {
+		data, err := v.ws.readText()
+		if err != nil {
+			break
+		}
+		var f frame
+		if err := json.Unmarshal(data, &f); err != nil {
+			continue
+		}
+		if err := v.draw(&f); err != nil {
+			return
+		}
+	}
+
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	fmt.Fprintf(v.writer, "\x1b[%d;1H\x1b[0m\x1b[7m Sharing stopped \x1b[0m", v.rows)
+}
+
+// draw shows the frame, or the last one again if f is nil
+func (v *Viewer) draw(f *frame) error {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	if f == nil {
+		f = v.last
+		if f == nil {
+			return nil
+		}
+	}
+	v.last = f
+	_, err := v.writer.Write(f.sequences(v.rows))
+	return err
+}
+
+func (v *Viewer) Read(b []byte) (int, error) {
+	return v.output.Read(b)
+}
+
+func (v *Viewer) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+// Resize draws the last frame again to fit the new size
+func (v *Viewer) Resize(x int, y int) error {
+	v.lock.Lock()
+	v.rows = y
+	v.lock.Unlock()
+	// the terminal being resized may be waiting to read what's drawn
+	go v.draw(nil)
+	return nil
+}
+
+// Close disconnects from the sharing Aminal
+func (v *Viewer) Close() error {
+	v.once.Do(func() {
+		if v.ws != nil {
+			v.ws.close()
+		}
+		v.output.Close()
+		close(v.closed)
+	})
+	return nil
+}
+
+func (v *Viewer) GetPlatformDependentSettings() platform.PlatformDependentSettings {
+	return platform.DefaultSettings()
+}
+
+type viewerProcess struct {
+	viewer *Viewer
+}
+
+func (p *viewerProcess) Wait() error {
+	<-p.viewer.closed
+	return nil
+}
+
+func (p *viewerProcess) Close() error {
+	return p.viewer.Close()
+}
@@ -0,0 +1,274 @@
+package share
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// just enough of RFC 6455 to push text frames to a browser, anything the browser sends is discarded, and to
+// receive them in another Aminal watching
+
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// dialTimeout is how long a watching Aminal waits for the sharing one to answer
+const dialTimeout = 10 * time.Second
+
+// maxFrame is the most a frame read can carry, well above the largest screen, to catch a corrupt stream
+const maxFrame = 64 << 20
+
+const (
+	opText  = 0x1
+	opClose = 0x8
+	opPing  = 0x9
+	opPong  = 0xa
+)
+
+type websocket struct {
+	conn      net.Conn
+	rw        *bufio.ReadWriter
+	client    bool // frames sent by clients must be masked
+	writeLock sync.Mutex
+}
+
+func acceptKey(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + websocketGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+func headerContains(r *http.Request, name string, value string) bool {
+	for _, v := range strings.Split(r.Header.Get(name), ",") {
+		if strings.EqualFold(strings.TrimSpace(v), value) {
+			return true
+		}
+	}
+	return false
+}
+
+func upgrade(w http.ResponseWriter, r *http.Request) (*websocket, error) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if !headerContains(r, "Connection", "upgrade") || !headerContains(r, "Upgrade", "websocket") || key == "" {
+		http.Error(w, "Expected a websocket", http.StatusBadRequest)
+		return nil, fmt.Errorf("Request is not a websocket upgrade")
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "Websockets are not supported", http.StatusInternalServerError)
+		return nil, fmt.Errorf("Connection cannot be hijacked")
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Fprintf(rw, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n", acceptKey(key))
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return &websocket{conn: conn, rw: rw}, nil
+}
+
+// dial opens a websocket to the server at u, which is an http url
+func dial(u *url.URL) (*websocket, error) {
+	conn, err := net.DialTimeout("tcp", u.Host, dialTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	key := base64.StdEncoding.EncodeToString(nonce)
+
+	rw := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+	fmt.Fprintf(rw, "GET %s HTTP/1.1\r\nHost: %s\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Key: %s\r\nSec-WebSocket-Version: 13\r\n\r\n", u.RequestURI(), u.Host, key)
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	resp, err := http.ReadResponse(rw.Reader, nil)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		conn.Close()
+		return nil, fmt.Errorf("Server refused the connection: %s", resp.Status)
+	}
+	if resp.Header.Get("Sec-WebSocket-Accept") != acceptKey(key) {
+		conn.Close()
+		return nil, fmt.Errorf("Server answered with the wrong key")
+	}
+
+	return &websocket{conn: conn, rw: rw, client: true}, nil
+}
+
+func (ws *websocket) writeFrame(opcode byte, payload []byte) error {
+	ws.writeLock.Lock()
+	defer ws.writeLock.Unlock()
+
+	header := []byte{0x80 | opcode} // always a final frame
+	switch length := len(payload); {
+	case length < 126:
+		header = append(header, byte(length))
+	case length <= 0xffff:
+		header = append(header, 126, 0, 0)
+		binary.BigEndian.PutUint16(header[2:], uint16(length))
+	default:
+		header = append(header, 127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(header[2:], uint64(length))
+	}
+	if ws.client {
+		mask := make([]byte, 4)
+		if _, err := rand.Read(mask); err != nil {
+			return err
+		}
+		header[1] |= 0x80
+		header = append(header, mask...)
+		masked := make([]byte, len(payload))
+		for i := range payload {
+			masked[i] = payload[i] ^ mask[i%4]
+		}
+		payload = masked
+	}
+	if _, err := ws.rw.Write(header); err != nil {
+		return err
+	}
+	if _, err := ws.rw.Write(payload); err != nil {
+		return err
+	}
+	return ws.rw.Flush()
+}
+
+func (ws *websocket) writeText(data []byte) error {
+	return ws.writeFrame(opText, data)
+}
+
+// discardIncoming reads and throws away frames from the client until it closes the connection
+func (ws *websocket) discardIncoming() error {
+	header := make([]byte, 2)
+	for {
+		if _, err := io.ReadFull(ws.rw, header); err != nil {
+			return err
+		}
+		opcode := header[0] & 0x0f
+		length := uint64(header[1] & 0x7f)
+		switch length {
+		case 126:
+			ext := make([]byte, 2)
+			if _, err := io.ReadFull(ws.rw, ext); err != nil {
+				return err
+			}
+			length = uint64(binary.BigEndian.Uint16(ext))
+		case 127:
+			ext := make([]byte, 8)
+			if _, err := io.ReadFull(ws.rw, ext); err != nil {
+				return err
+			}
+			length = binary.BigEndian.Uint64(ext)
+		}
+		if header[1]&0x80 != 0 {
+			length += 4 // masking key
+		}
+
+		switch opcode {
+		case opClose:
+			_ = ws.writeFrame(opClose, nil)
+			return nil
+		case opPing:
+			// pings are small, so keep the payload to echo it back
+			if length > 4+125 {
+				return fmt.Errorf("Ping frame too large")
+			}
+			data := make([]byte, length)
+			if _, err := io.ReadFull(ws.rw, data); err != nil {
+				return err
+			}
+			payload := data
+			if header[1]&0x80 != 0 {
+				mask, masked := data[:4], data[4:]
+				payload = make([]byte, len(masked))
+				for i := range masked {
+					payload[i] = masked[i] ^ mask[i%4]
+				}
+			}
+			if err := ws.writeFrame(opPong, payload); err != nil {
+				return err
+			}
+		default:
+			if _, err := io.CopyN(io.Discard, ws.rw, int64(length)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// readText returns the next text frame from the server, answering pings on the way
+func (ws *websocket) readText() ([]byte, error) {
+	header := make([]byte, 2)
+	for {
+		if _, err := io.ReadFull(ws.rw, header); err != nil {
+			return nil, err
+		}
+		opcode := header[0] & 0x0f
+		length := uint64(header[1] & 0x7f)
+		switch length {
+		case 126:
+			ext := make([]byte, 2)
+			if _, err := io.ReadFull(ws.rw, ext); err != nil {
+				return nil, err
+			}
+			length = uint64(binary.BigEndian.Uint16(ext))
+		case 127:
+			ext := make([]byte, 8)
+			if _, err := io.ReadFull(ws.rw, ext); err != nil {
+				return nil, err
+			}
+			length = binary.BigEndian.Uint64(ext)
+		}
+		if header[1]&0x80 != 0 {
+			return nil, fmt.Errorf("Server sent a masked frame")
+		}
+		if length > maxFrame {
+			return nil, fmt.Errorf("Frame of %d bytes is too large", length)
+		}
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(ws.rw, payload); err != nil {
+			return nil, err
+		}
+
+		switch opcode {
+		case opText:
+			return payload, nil
+		case opClose:
+			_ = ws.writeFrame(opClose, nil)
+			return nil, io.EOF
+		case opPing:
+			if err := ws.writeFrame(opPong, payload); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+func (ws *websocket) close() error {
+	return ws.conn.Close()
+}
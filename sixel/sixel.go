@@ -16,8 +16,11 @@ type Sixel struct {
 
 type colour [3]uint8
 
+// maxRepeat caps the repeat count of a single sixel, so a malformed count can't exhaust memory
+const maxRepeat = 10000
+
 func decompress(data string) string {
-	output := ""
+	var output strings.Builder
 
 	inMarker := false
 	countStr := ""
@@ -29,21 +32,26 @@ func decompress(data string) string {
 				inMarker = true
 				countStr = ""
 			} else {
-				output += string(r)
+				output.WriteRune(r)
 			}
 			continue
 		}
 
 		if r >= 0x30 && r <= 0x39 {
-			countStr = fmt.Sprintf("%s%c", countStr, r)
+			if len(countStr) < 6 {
+				countStr = fmt.Sprintf("%s%c", countStr, r)
+			}
 		} else {
 			count, _ := strconv.Atoi(countStr)
-			output += strings.Repeat(string(r), count)
+			if count > maxRepeat {
+				count = maxRepeat
+			}
+			output.WriteString(strings.Repeat(string(r), count))
 			inMarker = false
 		}
 	}
 
-	return output
+	return output.String()
 }
 
 // pass in everything after ESC+P and before ST
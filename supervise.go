@@ -0,0 +1,221 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/liamg/aminal/platform"
+	"go.uber.org/zap"
+)
+
+// restartSettleTime is how long the output of a shell which has crashed is given to arrive before its pty is closed
+const restartSettleTime = 100 * time.Millisecond
+
+// restartMinUptime is how long a shell must have been running for it to be restarted when it crashes, so a shell
+// crashing as it starts isn't restarted over and over
+const restartMinUptime = 2 * time.Second
+
+// resetModes puts back the modes a crashed program may have left on, so the new shell starts on a sane terminal
+const resetModes = "\x1b[?1049l\x1b[?25h\x1b[?1l\x1b>\x1b[?2004l" + reportingOff + "\x1b[0m"
+
+// startFunc starts a new shell, with its pty
+type startFunc func() (platform.Pty, platform.Process, error)
+
+// supervisedPty restarts the shell on a new pty when it crashes, below a divider saying why, so the terminal and
+// its scrollback carry on as they were. The shell exiting by itself ends it as usual.
+type supervisedPty struct {
+	start   startFunc
+	logger  *zap.SugaredLogger
+	output  chan []byte   // from each shell's pty in turn, closed once the last has finished
+	pending []byte        // read from output but not yet passed on
+	done    chan struct{} // closed once the last shell has exited
+	err     error         // what waiting for the last shell returned
+
+	lock     sync.Mutex
+	pty      platform.Pty
+	process  platform.Process
+	cols     int // the size given to the pty, for the next one
+	rows     int
+	closed   bool
+	finished chan struct{} // closed once the current pty's output has all been pumped
+}
+
+// supervisedProcess waits for the last of the shells started by a supervisedPty
+type supervisedProcess struct {
+	pty *supervisedPty
+}
+
+// supervise runs pty and process, the shell's, restarting the shell with start whenever it crashes
+func supervise(pty platform.Pty, process platform.Process, start startFunc, logger *zap.SugaredLogger) (*supervisedPty, *supervisedProcess) {
+	s := &supervisedPty{
+		start:  start,
+		logger: logger,
+		output: make(chan []byte),
+		done:   make(chan struct{}),
+	}
+	s.run(pty, process)
+	go s.supervise()
+	return s, &supervisedProcess{pty: s}
+}
+
+// run makes pty and process the current shell's
+func (s *supervisedPty) run(pty platform.Pty, process platform.Process) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.pty = pty
+	s.process = process
+	s.finished = make(chan struct{})
+	if s.cols > 0 && s.rows > 0 {
+		pty.Resize(s.cols, s.rows)
+	}
+	go s.pump(pty, s.finished)
+}
+
+func (s *supervisedPty) pump(pty platform.Pty, finished chan struct{}) {
+	defer close(finished)
+	for {
+		b := make([]byte, 4096)
+		n, err := pty.Read(b)
+		if n > 0 {
+			s.output <- b[:n]
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (s *supervisedPty) supervise() {
+	finished := s.end(s.supervised())
+	<-finished
+	close(s.output)
+}
+
+// end records what waiting for the last shell returned, returning when its output will have all been pumped
+func (s *supervisedPty) end(err error) chan struct{} {
+	s.err = err
+	close(s.done)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.finished
+}
+
+// supervised waits for each shell in turn, restarting it if it crashes, until one exits by itself or can't be
+// restarted, returning what waiting for that one returned
+func (s *supervisedPty) supervised() error {
+	for {
+		s.lock.Lock()
+		pty, process, finished := s.pty, s.process, s.finished
+		s.lock.Unlock()
+
+		started := time.Now()
+		err := process.Wait()
+		killedBy := platform.KilledBy(err)
+
+		s.lock.Lock()
+		closed := s.closed
+		s.lock.Unlock()
+		if killedBy == "" || closed {
+			return err
+		}
+
+		// the output the shell wrote before it crashed goes above the divider
+		time.Sleep(restartSettleTime)
+		process.Close()
+		pty.Close()
+		<-finished
+
+		if time.Since(started) < restartMinUptime {
+			s.logger.Errorf("Not restarting the shell, as it was killed by %s as soon as it started", killedBy)
+			s.output <- []byte(s.divider(fmt.Sprintf("Shell killed by %s as soon as it started, not restarting", killedBy)))
+			return err
+		}
+
+		s.logger.Infof("Restarting the shell, which was killed by %s", killedBy)
+		pty, process, startErr := s.start()
+		if startErr != nil {
+			s.output <- []byte(s.divider(fmt.Sprintf("Shell killed by %s, and failed to restart: %s", killedBy, startErr)))
+			return err
+		}
+		s.output <- []byte(resetModes + s.divider(fmt.Sprintf("Shell killed by %s, restarted", killedBy)))
+		s.run(pty, process)
+	}
+}
+
+// divider returns a line across the terminal saying what happened to the shell, on a line of its own
+func (s *supervisedPty) divider(message string) string {
+	s.lock.Lock()
+	cols := s.cols
+	s.lock.Unlock()
+
+	line := "── " + message + " "
+	if rest := cols - len([]rune(line)); rest > 0 {
+		line += strings.Repeat("─", rest)
+	}
+	return "\x1b[0m\r\n\x1b[2m" + line + "\x1b[0m\r\n"
+}
+
+func (s *supervisedPty) Read(b []byte) (int, error) {
+	if len(s.pending) == 0 {
+		data, ok := <-s.output
+		if !ok {
+			return 0, io.EOF
+		}
+		s.pending = data
+	}
+	n := copy(b, s.pending)
+	s.pending = s.pending[n:]
+	return n, nil
+}
+
+func (s *supervisedPty) current() platform.Pty {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.pty
+}
+
+func (s *supervisedPty) Write(b []byte) (int, error) {
+	return s.current().Write(b)
+}
+
+func (s *supervisedPty) Resize(x int, y int) error {
+	s.lock.Lock()
+	s.cols, s.rows = x, y
+	pty := s.pty
+	s.lock.Unlock()
+	return pty.Resize(x, y)
+}
+
+// Close closes the current shell's pty, which ends it as usual rather than restarting it
+func (s *supervisedPty) Close() error {
+	s.lock.Lock()
+	s.closed = true
+	pty := s.pty
+	s.lock.Unlock()
+	return pty.Close()
+}
+
+func (s *supervisedPty) CreateGuestProcess(imagePath string, dir string) (platform.Process, error) {
+	return nil, errors.New("The supervised shell has already been started")
+}
+
+func (s *supervisedPty) GetPlatformDependentSettings() platform.PlatformDependentSettings {
+	return s.current().GetPlatformDependentSettings()
+}
+
+// Wait waits for a shell to exit without crashing, returning what waiting for it returned
+func (p *supervisedProcess) Wait() error {
+	<-p.pty.done
+	return p.pty.err
+}
+
+func (p *supervisedProcess) Close() error {
+	p.pty.lock.Lock()
+	process := p.pty.process
+	p.pty.lock.Unlock()
+	return process.Close()
+}
@@ -0,0 +1,34 @@
+package terminal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAltScreen(t *testing.T) {
+	term := newHeadlessTerminal(20, 5)
+	term.processBytes([]byte("main\r\n\x1b[1;31mred\x1b[3;4H"))
+
+	attr := term.terminalState.CursorAttr
+
+	term.processBytes([]byte("\x1b[?1049h"))
+	assert.False(t, term.UsingMainBuffer())
+	assert.Equal(t, "", term.ActiveBuffer().GetVisibleLines()[0].String())
+	term.processBytes([]byte("\x1b[0m\x1b[5;1H"))
+	for i := 0; i < 10; i++ {
+		term.processBytes([]byte("scroll\r\n"))
+	}
+	assert.Equal(t, 5, term.ActiveBuffer().Height())
+
+	term.processBytes([]byte("\x1b[?1049l"))
+	assert.True(t, term.UsingMainBuffer())
+	assert.Equal(t, uint16(3), term.GetLogicalCursorX())
+	assert.Equal(t, uint16(2), term.GetLogicalCursorY())
+	assert.Equal(t, attr, term.terminalState.CursorAttr)
+	assert.Equal(t, "main", term.ActiveBuffer().GetVisibleLines()[0].String())
+
+	// the alt screen starts blank every time
+	term.processBytes([]byte("\x1b[?1049h"))
+	assert.Equal(t, "", term.ActiveBuffer().GetVisibleLines()[0].String())
+}
@@ -8,13 +8,14 @@ import "fmt"
 var ansiSequenceMap = map[rune]escapeSequenceHandler{
 	'[': csiHandler,
 	']': oscHandler,
+	'6': backIndexHandler, // DECBI
 	'7': saveCursorHandler,
 	'8': restoreCursorHandler,
+	'9': forwardIndexHandler, // DECFI
 	'D': indexHandler,
 	'E': nextLineHandler, // NEL
 	'H': tabSetHandler,   // HTS
 	'M': reverseIndexHandler,
-	'P': sixelHandler,
 	'c': risHandler, // RIS
 	'#': screenStateHandler,
 	'(': scs0Handler,       // select character set into G0
@@ -25,17 +26,30 @@ var ansiSequenceMap = map[rune]escapeSequenceHandler{
 	'=': swallowHandler(0), // alt char selection  //@todo
 }
 
+// sequences whose changes to the screen are marked by the buffer as they're made, so they needn't redraw
+// the whole view. CSI sequences which change more than the cursor's line are marked in csiSequences.
+var damageTrackedSequences = map[rune]bool{
+	'[': true,
+	'6': true,
+	'7': true,
+	'8': true,
+	'9': true,
+	'D': true,
+	'E': true,
+	'M': true,
+}
+
 func swallowHandler(n int) func(pty chan rune, terminal *Terminal) error {
 	return func(pty chan rune, terminal *Terminal) error {
 		for i := 0; i < n; i++ {
-			<-pty
+			readRune(pty)
 		}
 		return nil
 	}
 }
 
 func risHandler(pty chan rune, terminal *Terminal) error {
-	terminal.ActiveBuffer().Clear()
+	terminal.Reset()
 	return nil
 }
 
@@ -49,6 +63,16 @@ func reverseIndexHandler(pty chan rune, terminal *Terminal) error {
 	return nil
 }
 
+func backIndexHandler(pty chan rune, terminal *Terminal) error {
+	terminal.ActiveBuffer().BackIndex()
+	return nil
+}
+
+func forwardIndexHandler(pty chan rune, terminal *Terminal) error {
+	terminal.ActiveBuffer().ForwardIndex()
+	return nil
+}
+
 func saveCursorHandler(pty chan rune, terminal *Terminal) error {
 	terminal.ActiveBuffer().SaveCursor()
 	return nil
@@ -61,11 +85,14 @@ func restoreCursorHandler(pty chan rune, terminal *Terminal) error {
 
 func ansiHandler(pty chan rune, terminal *Terminal) error {
 	// if the byte is an escape character, read the next byte to determine which one
-	b := <-pty
+	b := readRune(pty)
 
 	handler, ok := ansiSequenceMap[b]
 	if ok {
 		// terminal.logger.Debugf("Handling ansi sequence %c", b)
+		if !damageTrackedSequences[b] {
+			defer terminal.SetDirty()
+		}
 		return handler(pty, terminal)
 	}
 
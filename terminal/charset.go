@@ -52,7 +52,7 @@ func scs1Handler(pty chan rune, terminal *Terminal) error {
 }
 
 func scsHandler(pty chan rune, terminal *Terminal, which int) error {
-	b := <-pty
+	b := readRune(pty)
 
 	cs, ok := charSets[b]
 	if ok {
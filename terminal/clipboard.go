@@ -0,0 +1,72 @@
+package terminal
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// ClipboardTarget is the selection an OSC 52 sequence reads or writes
+type ClipboardTarget int
+
+const (
+	TargetClipboard ClipboardTarget = iota // the system clipboard
+	TargetPrimary                          // the primary selection, pasted with the middle button
+)
+
+// Clipboard gives programs running in the terminal access to the system clipboard via OSC 52.
+// Implementations decide whether each access is allowed, returning an error if it is not. They're called on the
+// parser goroutine, so mustn't wait on the user: a read may reply later, from another goroutine.
+type Clipboard interface {
+	ReadClipboard(target ClipboardTarget, reply func(text string)) error
+	WriteClipboard(target ClipboardTarget, text string) error
+}
+
+// SetClipboard sets the clipboard used for OSC 52 - without one, clipboard sequences are ignored
+func (terminal *Terminal) SetClipboard(clipboard Clipboard) {
+	terminal.clipboard = clipboard
+}
+
+// clipboardTarget picks the selection named by the Pc parameter of OSC 52. It can list several of c (clipboard),
+// p (primary), s (select) and 0-7 (cut buffers); the first of c or p wins and the rest go to the clipboard,
+// as does an empty list, which xterm treats as "s 0".
+func clipboardTarget(selection string) ClipboardTarget {
+	for _, c := range selection {
+		switch c {
+		case 'c':
+			return TargetClipboard
+		case 'p':
+			return TargetPrimary
+		}
+	}
+	return TargetClipboard
+}
+
+// OSC 52 ; Pc ; Pd - Pd is either base64 data to copy, or ? to request the clipboard content, which is
+// sent back with the given string terminator
+func (terminal *Terminal) handleClipboardSequence(selection string, data string, terminator string) error {
+	if terminal.clipboard == nil {
+		return fmt.Errorf("No clipboard available for OSC 52")
+	}
+	target := clipboardTarget(selection)
+
+	if data == "?" {
+		return terminal.clipboard.ReadClipboard(target, func(text string) {
+			reply := fmt.Sprintf("\x1b]52;%s;%s%s", selection, base64.StdEncoding.EncodeToString([]byte(text)), terminator)
+			if err := terminal.Write([]byte(reply)); err != nil {
+				terminal.logger.Errorf("Failed to send the clipboard: %s", err)
+			}
+		})
+	}
+
+	// some programs leave out the padding, or wrap long data over several lines
+	data = strings.NewReplacer("\r", "", "\n", "").Replace(data)
+	decoded, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		decoded, err = base64.RawStdEncoding.DecodeString(strings.TrimRight(data, "="))
+	}
+	if err != nil {
+		return fmt.Errorf("Invalid OSC 52 data: %s", err)
+	}
+	return terminal.clipboard.WriteClipboard(target, string(decoded))
+}
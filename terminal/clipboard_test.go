@@ -0,0 +1,49 @@
+package terminal
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// memoryClipboard keeps each selection in memory, refusing reads unless they're allowed
+type memoryClipboard struct {
+	selections map[ClipboardTarget]string
+	allowRead  bool
+}
+
+func (c *memoryClipboard) ReadClipboard(target ClipboardTarget, reply func(text string)) error {
+	if !c.allowRead {
+		return fmt.Errorf("Clipboard read denied")
+	}
+	reply(c.selections[target])
+	return nil
+}
+
+func (c *memoryClipboard) WriteClipboard(target ClipboardTarget, text string) error {
+	c.selections[target] = text
+	return nil
+}
+
+func TestClipboardSequences(t *testing.T) {
+	pty := &recordingPty{}
+	term := New(pty, zap.NewNop().Sugar(), DefaultOptions())
+	_ = term.SetSize(20, 5)
+	clipboard := &memoryClipboard{selections: map[ClipboardTarget]string{}}
+	term.SetClipboard(clipboard)
+
+	term.processBytes([]byte("\x1b]52;c;aGVsbG8=\x07"))
+	term.processBytes([]byte("\x1b]52;p;d29ybGQ\x1b\\"))
+	term.processBytes([]byte("\x1b]52;;YWdhaW4=\x07"))
+	assert.Equal(t, "again", clipboard.selections[TargetClipboard], "an empty selection means the clipboard")
+	assert.Equal(t, "world", clipboard.selections[TargetPrimary], "padding may be left out")
+
+	term.processBytes([]byte("\x1b]52;c;?\x07"))
+	assert.Empty(t, pty.written.String(), "nothing is sent back when reads are denied")
+
+	clipboard.allowRead = true
+	term.processBytes([]byte("\x1b]52;p;?\x1b\\"))
+	assert.Equal(t, "\x1b]52;p;d29ybGQ=\x1b\\", pty.written.String(), "the reply is terminated like the query")
+}
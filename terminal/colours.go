@@ -0,0 +1,274 @@
+package terminal
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/liamg/aminal/buffer"
+)
+
+// Codes of the dynamic colours which OSC 10, 11 and 12 set and query. Each sequence can carry several
+// colours, which go to the following codes in turn.
+const (
+	dynamicForeground = 10
+	dynamicBackground = 11
+	dynamicCursor     = 12
+)
+
+// resolveColour gives the colour a cell colour reference currently stands for
+func (terminal *Terminal) resolveColour(ref buffer.ColourRef, bold bool) [3]float32 {
+	switch ref {
+	case buffer.ColourDefaultFg:
+		return terminal.options.Foreground
+	case buffer.ColourDefaultBg:
+		return terminal.options.Background
+	}
+	index, _ := ref.PaletteIndex()
+	if bold && index < 8 && terminal.options.BoldIsBright {
+		index += 8
+	}
+	return terminal.paletteColour(index)
+}
+
+// resolveColours looks up the colours of attr from its colour references
+func (terminal *Terminal) resolveColours(attr *buffer.CellAttributes) {
+	attr.Resolve(terminal.resolveColour)
+}
+
+// recolour changes text already written to the current default colours and palette
+func (terminal *Terminal) recolour() {
+	terminal.resolveColours(&terminal.terminalState.CursorAttr)
+	for _, buffer := range terminal.buffers {
+		buffer.Resolve(terminal.resolveColour)
+	}
+	terminal.SetDirty()
+	terminal.emitColourChange()
+}
+
+// paletteColour returns one of the 256 indexed colours, as changed by the application with OSC 4
+func (terminal *Terminal) paletteColour(index uint8) [3]float32 {
+	if index < 16 {
+		return terminal.options.Palette[index]
+	}
+	if c, ok := terminal.extendedPalette[index]; ok {
+		return c
+	}
+	return extendedColour(index)
+}
+
+// setPaletteColour changes a palette colour, returning true if it changed and text needs recolouring. The
+// sequences changing colours recolour once they've made all their changes, as it means going over every cell.
+func (terminal *Terminal) setPaletteColour(index uint8, colour [3]float32) bool {
+	if terminal.paletteColour(index) == colour {
+		return false
+	}
+	if index < 16 {
+		terminal.options.Palette[index] = colour
+	} else {
+		terminal.extendedPalette[index] = colour
+	}
+	return true
+}
+
+// resetPaletteColour puts a palette colour back as it was configured, returning true if text needs recolouring
+func (terminal *Terminal) resetPaletteColour(index uint8) bool {
+	if index < 16 {
+		return terminal.setPaletteColour(index, terminal.configured.Palette[index])
+	}
+	if _, ok := terminal.extendedPalette[index]; ok {
+		delete(terminal.extendedPalette, index)
+		return true
+	}
+	return false
+}
+
+// dynamicColour returns the current colour for an OSC 10-12 code
+func (terminal *Terminal) dynamicColour(code int) [3]float32 {
+	switch code {
+	case dynamicForeground:
+		return terminal.options.Foreground
+	case dynamicBackground:
+		return terminal.options.Background
+	default:
+		return terminal.options.Cursor
+	}
+}
+
+// setDynamicColour changes the colour for an OSC 10-12 code, returning true if text needs recolouring. The
+// cursor colour is only drawn with the cursor, so changing it doesn't.
+func (terminal *Terminal) setDynamicColour(code int, colour [3]float32) bool {
+	if terminal.dynamicColour(code) == colour {
+		return false
+	}
+	switch code {
+	case dynamicForeground:
+		terminal.options.Foreground = colour
+	case dynamicBackground:
+		terminal.options.Background = colour
+	case dynamicCursor:
+		terminal.options.Cursor = colour
+		terminal.SetDirty()
+		terminal.emitColourChange()
+		return false
+	}
+	return true
+}
+
+// resetDynamicColour puts the colour for an OSC 10-12 code back as it was configured, returning true if text
+// needs recolouring
+func (terminal *Terminal) resetDynamicColour(code int) bool {
+	switch code {
+	case dynamicForeground:
+		return terminal.setDynamicColour(code, terminal.configured.Foreground)
+	case dynamicBackground:
+		return terminal.setDynamicColour(code, terminal.configured.Background)
+	case dynamicCursor:
+		return terminal.setDynamicColour(code, terminal.configured.Cursor)
+	}
+	return false
+}
+
+// paletteColourSequence handles OSC 4, which has pairs of palette index and colour. A colour of ? asks for
+// the current one, which is sent back with the given string terminator.
+func (terminal *Terminal) paletteColourSequence(args []string, terminator string) error {
+	if len(args)%2 != 0 {
+		return fmt.Errorf("Missing colour for palette index %s", args[len(args)-1])
+	}
+	changed := false
+	defer func() {
+		if changed {
+			terminal.recolour()
+		}
+	}()
+	for i := 0; i < len(args); i += 2 {
+		index, err := strconv.ParseUint(args[i], 10, 8)
+		if err != nil {
+			return fmt.Errorf("Invalid palette index %q", args[i])
+		}
+		if args[i+1] == "?" {
+			colour := terminal.paletteColour(uint8(index))
+			terminal.Write([]byte(fmt.Sprintf("\x1b]4;%d;%s%s", index, formatColourSpec(colour), terminator)))
+			continue
+		}
+		colour, err := parseColourSpec(args[i+1])
+		if err != nil {
+			return err
+		}
+		if terminal.setPaletteColour(uint8(index), colour) {
+			changed = true
+		}
+	}
+	return nil
+}
+
+// resetPaletteSequence handles OSC 104, which resets the given palette indices, or all of them if there are none
+func (terminal *Terminal) resetPaletteSequence(args []string) error {
+	if len(args) == 0 || (len(args) == 1 && args[0] == "") {
+		if terminal.resetPalette() {
+			terminal.recolour()
+		}
+		return nil
+	}
+	changed := false
+	defer func() {
+		if changed {
+			terminal.recolour()
+		}
+	}()
+	for _, arg := range args {
+		index, err := strconv.ParseUint(arg, 10, 8)
+		if err != nil {
+			return fmt.Errorf("Invalid palette index %q", arg)
+		}
+		if terminal.resetPaletteColour(uint8(index)) {
+			changed = true
+		}
+	}
+	return nil
+}
+
+// resetPalette puts all the palette colours back as they were configured, returning true if text needs recolouring
+func (terminal *Terminal) resetPalette() bool {
+	changed := false
+	for i := 0; i < 16; i++ {
+		if terminal.resetPaletteColour(uint8(i)) {
+			changed = true
+		}
+	}
+	for index := range terminal.extendedPalette {
+		if terminal.resetPaletteColour(index) {
+			changed = true
+		}
+	}
+	return changed
+}
+
+// dynamicColourSequence handles OSC 10, 11 and 12, setting or querying the colour for code and then
+// moving on to the following codes if there are more colours
+func (terminal *Terminal) dynamicColourSequence(code int, args []string, terminator string) error {
+	changed := false
+	defer func() {
+		if changed {
+			terminal.recolour()
+		}
+	}()
+	for _, spec := range args {
+		if code > dynamicCursor {
+			// the mouse and highlight colours which follow aren't supported
+			return nil
+		}
+		if spec == "?" {
+			terminal.Write([]byte(fmt.Sprintf("\x1b]%d;%s%s", code, formatColourSpec(terminal.dynamicColour(code)), terminator)))
+		} else {
+			colour, err := parseColourSpec(spec)
+			if err != nil {
+				return err
+			}
+			if terminal.setDynamicColour(code, colour) {
+				changed = true
+			}
+		}
+		code++
+	}
+	return nil
+}
+
+// parseColourSpec reads a colour in one of the X11 forms applications use: rgb:r/g/b with 1-4 hex digits
+// per component, or #rgb with 1-4 hex digits per component
+func parseColourSpec(spec string) ([3]float32, error) {
+	var components []string
+	if strings.HasPrefix(spec, "rgb:") {
+		components = strings.Split(spec[4:], "/")
+	} else if strings.HasPrefix(spec, "#") && len(spec) > 1 && (len(spec)-1)%3 == 0 && len(spec) <= 13 {
+		size := (len(spec) - 1) / 3
+		for i := 1; i < len(spec); i += size {
+			components = append(components, spec[i:i+size])
+		}
+	}
+	if len(components) != 3 {
+		return [3]float32{}, fmt.Errorf("Unsupported colour %q", spec)
+	}
+	var colour [3]float32
+	for i, component := range components {
+		if len(component) < 1 || len(component) > 4 {
+			return [3]float32{}, fmt.Errorf("Unsupported colour %q", spec)
+		}
+		v, err := strconv.ParseUint(component, 16, 16)
+		if err != nil {
+			return [3]float32{}, fmt.Errorf("Unsupported colour %q", spec)
+		}
+		colour[i] = float32(v) / float32(uint64(1)<<(4*uint(len(component)))-1)
+	}
+	return colour, nil
+}
+
+// formatColourSpec writes a colour the way xterm replies to colour queries
+func formatColourSpec(colour [3]float32) string {
+	var components [3]uint16
+	for i, c := range colour {
+		components[i] = uint16(math.Round(float64(c) * 0xffff))
+	}
+	return fmt.Sprintf("rgb:%04x/%04x/%04x", components[0], components[1], components[2])
+}
@@ -0,0 +1,165 @@
+package terminal
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// recordingPty keeps what the terminal sends back to the application
+type recordingPty struct {
+	nullPty
+	written bytes.Buffer
+}
+
+func (p *recordingPty) Write(b []byte) (int, error) { return p.written.Write(b) }
+
+func TestSetColoursRecoloursExistingText(t *testing.T) {
+	term := newHeadlessTerminal(20, 5)
+	term.processBytes([]byte("a\x1b[31mb\x1b[38;2;1;2;3mc"))
+
+	palette := DefaultOptions().Palette
+	palette[1] = [3]float32{1, 0.5, 0.5}
+	foreground, background := [3]float32{0.1, 0.2, 0.3}, [3]float32{0.4, 0.5, 0.6}
+	term.SetColours(foreground, background, foreground, palette)
+
+	cells := term.ActiveBuffer().GetVisibleLines()[0].Cells()
+	assert.Equal(t, foreground, cells[0].Fg())
+	assert.Equal(t, background, cells[0].Bg())
+	assert.Equal(t, palette[1], cells[1].Fg())
+	assert.Equal(t, [3]float32{1.0 / 255, 2.0 / 255, 3.0 / 255}, cells[2].Fg(), "true colours aren't from the palette")
+
+	term.processBytes([]byte("\x1b[0md"))
+	assert.Equal(t, foreground, term.ActiveBuffer().GetVisibleLines()[0].Cells()[3].Fg())
+}
+
+func TestPaletteColourSequences(t *testing.T) {
+	term := newHeadlessTerminal(20, 5)
+	term.processBytes([]byte("\x1b[31ma\x1b[38;5;100mb"))
+
+	term.processBytes([]byte("\x1b]4;1;rgb:ff/80/00;100;#000080\x07"))
+	cells := term.ActiveBuffer().GetVisibleLines()[0].Cells()
+	assert.Equal(t, [3]float32{1, 128.0 / 255, 0}, cells[0].Fg(), "existing text takes the new colour")
+	assert.Equal(t, [3]float32{0, 0, 128.0 / 255}, cells[1].Fg())
+
+	term.processBytes([]byte("\x1b[38;5;100mc"))
+	assert.Equal(t, [3]float32{0, 0, 128.0 / 255}, term.ActiveBuffer().GetVisibleLines()[0].Cells()[2].Fg())
+
+	term.processBytes([]byte("\x1b]104\x07"))
+	cells = term.ActiveBuffer().GetVisibleLines()[0].Cells()
+	assert.Equal(t, DefaultOptions().Palette[1], cells[0].Fg())
+	assert.Equal(t, extendedColour(100), cells[1].Fg())
+}
+
+func TestDynamicColourSequences(t *testing.T) {
+	term := newHeadlessTerminal(20, 5)
+	term.processBytes([]byte("a"))
+
+	term.processBytes([]byte("\x1b]11;#102030\x1b\\"))
+	background := [3]float32{0x10 / 255.0, 0x20 / 255.0, 0x30 / 255.0}
+	assert.Equal(t, background, term.Options().Background)
+	assert.Equal(t, background, term.ActiveBuffer().GetVisibleLines()[0].Cells()[0].Bg())
+
+	term.processBytes([]byte("\x1b]10;rgb:0/f/0;rgb:ffff/0000/0000;#00f\x07"))
+	assert.Equal(t, [3]float32{0, 1, 0}, term.Options().Foreground)
+	assert.Equal(t, [3]float32{1, 0, 0}, term.Options().Background)
+	assert.Equal(t, [3]float32{0, 0, 1}, term.Options().Cursor)
+
+	term.processBytes([]byte("\x1b]110\x07\x1b]111\x07\x1b]112\x07"))
+	assert.Equal(t, DefaultOptions().Foreground, term.Options().Foreground)
+	assert.Equal(t, DefaultOptions().Background, term.Options().Background)
+	assert.Equal(t, DefaultOptions().Cursor, term.Options().Cursor)
+	assert.Equal(t, DefaultOptions().Background, term.ActiveBuffer().GetVisibleLines()[0].Cells()[0].Bg())
+}
+
+func TestColourQueries(t *testing.T) {
+	pty := &recordingPty{}
+	term := New(pty, zap.NewNop().Sugar(), DefaultOptions())
+	_ = term.SetSize(20, 5)
+
+	term.processBytes([]byte("\x1b]4;9;?\x07"))
+	assert.Equal(t, "\x1b]4;9;rgb:ffff/0000/0000\x07", pty.written.String())
+
+	pty.written.Reset()
+	term.processBytes([]byte("\x1b]11;?\x1b\\"))
+	assert.Equal(t, "\x1b]11;rgb:0000/0000/0000\x1b\\", pty.written.String(), "replies end with the same terminator")
+}
+
+func TestParseColourSpec(t *testing.T) {
+	for spec, want := range map[string][3]float32{
+		"rgb:f/0/8":       {1, 0, 8.0 / 15},
+		"rgb:ffff/8000/0": {1, float32(0x8000) / 0xffff, 0},
+		"#fff":            {1, 1, 1},
+		"#ff0000":         {1, 0, 0},
+		"#ffff00000000":   {1, 0, 0},
+	} {
+		got, err := parseColourSpec(spec)
+		if assert.NoError(t, err, spec) {
+			assert.Equal(t, want, got, spec)
+		}
+	}
+	for _, spec := range []string{"", "red", "rgb:1/2", "rgb:12345/0/0", "#12345", "rgb:g/0/0"} {
+		_, err := parseColourSpec(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestPaletteChangesOnlyAffectPaletteColours(t *testing.T) {
+	term := newHeadlessTerminal(20, 5)
+	red := DefaultOptions().Palette[1]
+	term.processBytes([]byte("\x1b[31ma\x1b[38;2;205;0;0mb\x1b[38;5;1mc"))
+	cells := term.ActiveBuffer().GetVisibleLines()[0].Cells()
+	assert.Equal(t, cells[0].Fg(), cells[2].Fg())
+
+	term.processBytes([]byte("\x1b]4;1;#00ff00\x07"))
+	cells = term.ActiveBuffer().GetVisibleLines()[0].Cells()
+	assert.Equal(t, [3]float32{0, 1, 0}, cells[0].Fg())
+	assert.Equal(t, [3]float32{205.0 / 255, 0, 0}, cells[1].Fg(), "a true colour which happens to match stays as it is")
+	assert.Equal(t, [3]float32{0, 1, 0}, cells[2].Fg())
+	assert.NotEqual(t, red, cells[2].Fg())
+}
+
+func TestBoldIsBright(t *testing.T) {
+	term := newHeadlessTerminal(20, 5)
+	palette := DefaultOptions().Palette
+	term.processBytes([]byte("\x1b[1;31ma\x1b[22mb\x1b[1;38;5;100mc"))
+
+	cells := term.ActiveBuffer().GetVisibleLines()[0].Cells()
+	assert.Equal(t, palette[1], cells[0].Fg(), "bold is only bright when enabled")
+
+	term.SetBoldIsBright(true)
+	cells = term.ActiveBuffer().GetVisibleLines()[0].Cells()
+	assert.Equal(t, palette[9], cells[0].Fg())
+	assert.Equal(t, palette[1], cells[1].Fg())
+	assert.Equal(t, extendedColour(100), cells[2].Fg(), "only the first 8 colours have bright versions")
+
+	term.processBytes([]byte("\x1b[1;32md"))
+	assert.Equal(t, palette[10], term.ActiveBuffer().GetVisibleLines()[0].Cells()[3].Fg())
+}
+
+func TestReverseVideoFollowsDefaultColours(t *testing.T) {
+	term := newHeadlessTerminal(20, 5)
+	term.processBytes([]byte("a\x1b[?5h"))
+	foreground, background := [3]float32{0.1, 0.2, 0.3}, [3]float32{0.4, 0.5, 0.6}
+	term.SetColours(foreground, background, foreground, DefaultOptions().Palette)
+
+	cell := term.ActiveBuffer().GetVisibleLines()[0].Cells()[0]
+	assert.Equal(t, background, cell.Fg())
+	assert.Equal(t, foreground, cell.Bg())
+}
+
+func TestExtendedColourForms(t *testing.T) {
+	term := newHeadlessTerminal(20, 5)
+	term.processBytes([]byte("\x1b[38;5;100;1ma\x1b[0;38;2;10;20;30;48;5;3mb\x1b[0;38:2::10:20:30m\x1b[48:5:4mc\x1b[38:2:1:2:3md"))
+
+	cells := term.ActiveBuffer().GetVisibleLines()[0].Cells()
+	assert.Equal(t, extendedColour(100), cells[0].Fg())
+	assert.True(t, cells[0].Attr().Bold, "params after the colour are still applied")
+	assert.Equal(t, [3]float32{10.0 / 255, 20.0 / 255, 30.0 / 255}, cells[1].Fg())
+	assert.Equal(t, DefaultOptions().Palette[3], cells[1].Bg())
+	assert.Equal(t, [3]float32{10.0 / 255, 20.0 / 255, 30.0 / 255}, cells[2].Fg())
+	assert.Equal(t, DefaultOptions().Palette[4], cells[2].Bg())
+	assert.Equal(t, [3]float32{1.0 / 255, 2.0 / 255, 3.0 / 255}, cells[3].Fg())
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+
+	"github.com/liamg/aminal/buffer"
 )
 
 type csiSequenceHandler func(params []string, terminal *Terminal) error
@@ -13,6 +15,8 @@ type csiMapping struct {
 	handler        csiSequenceHandler
 	description    string
 	expectedParams *expectedParams
+	intermediate   string // the characters between the parameters and the final character, like the " in DECSCA
+	local          bool   // only changes the cursor's line, which the buffer marks as it goes, so the whole view needn't be redrawn
 }
 
 type expectedParams struct {
@@ -21,33 +25,39 @@ type expectedParams struct {
 }
 
 var csiSequences = []csiMapping{
-	{id: 'c', handler: csiSendDeviceAttributesHandler, description: " Send Device Attributes (Primary/Secondary/Tertiary DA)"},
-	{id: 'd', handler: csiLinePositionAbsolute, expectedParams: &expectedParams{min: 0, max: 1}, description: "Line Position Absolute  [row] (default = [1,column]) (VPA)"},
-	{id: 'f', handler: csiCursorPositionHandler, description: "Horizontal and Vertical Position [row;column] (default = [1,1]) (HVP)"},
-	{id: 'g', handler: csiTabClearHandler, description: "Tab Clear (TBC)"},
+	{id: 'c', handler: csiSendDeviceAttributesHandler, description: "Send Device Attributes (Primary/Secondary/Tertiary DA)", local: true},
+	{id: 'd', handler: csiLinePositionAbsolute, expectedParams: &expectedParams{min: 0, max: 1}, description: "Line Position Absolute  [row] (default = [1,column]) (VPA)", local: true},
+	{id: 'f', handler: csiCursorPositionHandler, description: "Horizontal and Vertical Position [row;column] (default = [1,1]) (HVP)", local: true},
+	{id: 'g', handler: csiTabClearHandler, description: "Tab Clear (TBC)", local: true},
+	{id: 'k', intermediate: " ", handler: csiSelectCharacterPathHandler, expectedParams: &expectedParams{min: 0, max: 2}, description: "Select Character Path (SCP)"},
 	{id: 'h', handler: csiSetModeHandler, expectedParams: &expectedParams{min: 1, max: ^uint8(0)}, description: "Set Mode (SM)"},
 	{id: 'l', handler: csiResetModeHandler, expectedParams: &expectedParams{min: 1, max: ^uint8(0)}, description: "Reset Mode (RM)"},
-	{id: 'm', handler: sgrSequenceHandler, description: "Character Attributes (SGR)"},
-	{id: 'n', handler: csiDeviceStatusReportHandler, description: "Device Status Report (DSR)"},
+	{id: 'm', handler: sgrSequenceHandler, description: "Character Attributes (SGR)", local: true},
+	{id: 'n', handler: csiDeviceStatusReportHandler, description: "Device Status Report (DSR)", local: true},
+	{id: 'p', intermediate: "$", handler: csiRequestModeHandler, expectedParams: &expectedParams{min: 1, max: 1}, description: "Request Mode (DECRQM), VT300", local: true},
+	{id: 'q', handler: csiReportVersionHandler, expectedParams: &expectedParams{min: 1, max: 1}, description: "Report xterm Name and Version (XTVERSION)", local: true},
+	{id: 'q', intermediate: "\"", handler: csiSelectCharacterProtectionHandler, expectedParams: &expectedParams{min: 0, max: 1}, description: "Select Character Protection Attribute (DECSCA), VT220", local: true},
 	{id: 'r', handler: csiSetMarginsHandler, expectedParams: &expectedParams{min: 0, max: 2}, description: "Set Scrolling Region [top;bottom] (default = full size of window) (DECSTBM), VT100"},
+	{id: 's', handler: csiSetLeftRightMarginsHandler, expectedParams: &expectedParams{min: 0, max: 2}, description: "Set Left and Right Margins [left;right] (DECSLRM), VT420, or Save Cursor (SCOSC)"},
 	{id: 't', handler: csiWindowManipulation, description: "Window manipulation"},
-	{id: 'A', handler: csiCursorUpHandler, description: "Cursor Up Ps Times (default = 1) (CUU)"},
-	{id: 'B', handler: csiCursorDownHandler, description: "Cursor Down Ps Times (default = 1) (CUD)"},
-	{id: 'C', handler: csiCursorForwardHandler, description: "Cursor Forward Ps Times (default = 1) (CUF)"},
-	{id: 'D', handler: csiCursorBackwardHandler, description: "Cursor Backward Ps Times (default = 1) (CUB)"},
-	{id: 'E', handler: csiCursorNextLineHandler, description: "Cursor Next Line Ps Times (default = 1) (CNL)"},
-	{id: 'F', handler: csiCursorPrecedingLineHandler, description: "Cursor Preceding Line Ps Times (default = 1) (CPL)"},
-	{id: 'G', handler: csiCursorCharacterAbsoluteHandler, description: "Cursor Horizontal Absolute  [column] (default = [row,1]) (CHA)"},
-	{id: 'H', handler: csiCursorPositionHandler, description: "Cursor Position [row;column] (default = [1,1]) (CUP)"},
-	{id: 'J', handler: csiEraseInDisplayHandler, description: "Erase in Display (ED), VT100"},
-	{id: 'K', handler: csiEraseInLineHandler, description: "Erase in Line (EL), VT100"},
+	{id: 'u', handler: csiRestoreCursorHandler, expectedParams: &expectedParams{min: 0, max: 2}, description: "Restore Cursor (SCORC), or the kitty keyboard protocol"},
+	{id: 'A', handler: csiCursorUpHandler, description: "Cursor Up Ps Times (default = 1) (CUU)", local: true},
+	{id: 'B', handler: csiCursorDownHandler, description: "Cursor Down Ps Times (default = 1) (CUD)", local: true},
+	{id: 'C', handler: csiCursorForwardHandler, description: "Cursor Forward Ps Times (default = 1) (CUF)", local: true},
+	{id: 'D', handler: csiCursorBackwardHandler, description: "Cursor Backward Ps Times (default = 1) (CUB)", local: true},
+	{id: 'E', handler: csiCursorNextLineHandler, description: "Cursor Next Line Ps Times (default = 1) (CNL)", local: true},
+	{id: 'F', handler: csiCursorPrecedingLineHandler, description: "Cursor Preceding Line Ps Times (default = 1) (CPL)", local: true},
+	{id: 'G', handler: csiCursorCharacterAbsoluteHandler, description: "Cursor Horizontal Absolute  [column] (default = [row,1]) (CHA)", local: true},
+	{id: 'H', handler: csiCursorPositionHandler, description: "Cursor Position [row;column] (default = [1,1]) (CUP)", local: true},
+	{id: 'J', handler: csiEraseInDisplayHandler, description: "Erase in Display (ED), VT100, or Selective Erase in Display (DECSED), VT220"},
+	{id: 'K', handler: csiEraseInLineHandler, description: "Erase in Line (EL), VT100, or Selective Erase in Line (DECSEL), VT220", local: true},
 	{id: 'L', handler: csiInsertLinesHandler, description: "Insert Ps Line(s) (default = 1) (IL)"},
 	{id: 'M', handler: csiDeleteLinesHandler, description: "Delete Ps Line(s) (default = 1) (DL)"},
-	{id: 'P', handler: csiDeleteHandler, description: " Delete Ps Character(s) (default = 1) (DCH)"},
+	{id: 'P', handler: csiDeleteHandler, description: " Delete Ps Character(s) (default = 1) (DCH)", local: true},
 	{id: 'S', handler: csiScrollUpHandler, description: "Scroll up Ps lines (default = 1) (SU), VT420, ECMA-48"},
 	{id: 'T', handler: csiScrollDownHandler, description: "Scroll down Ps lines (default = 1) (SD), VT420"},
-	{id: 'X', handler: csiEraseCharactersHandler, description: "Erase Ps Character(s) (default = 1) (ECH"},
-	{id: '@', handler: csiInsertBlankCharactersHandler, description: "Insert Ps (Blank) Character(s) (default = 1) (ICH)"},
+	{id: 'X', handler: csiEraseCharactersHandler, description: "Erase Ps Character(s) (default = 1) (ECH", local: true},
+	{id: '@', handler: csiInsertBlankCharactersHandler, description: "Insert Ps (Blank) Character(s) (default = 1) (ICH)", local: true},
 }
 
 type runeRange struct {
@@ -57,16 +67,25 @@ type runeRange struct {
 
 var csiTerminators = runeRange{0x40, 0x7e}
 
+// numeric parameters are clamped to maxCSIParam, and anything beyond maxCSIParamLength is dropped,
+// so that malformed sequences can't overflow counts or cause excessive looping/allocation
+const (
+	maxCSIParam       = 9999
+	maxCSIParamLength = 1024
+)
+
 func loadCSI(pty chan rune) (final rune, param string, intermediate []rune) {
 	var b rune
 	param = ""
 	intermediate = []rune{}
 CSI:
 	for {
-		b = <-pty
+		b = readRune(pty)
 		switch true {
 		case b >= 0x30 && b <= 0x3F:
-			param = param + string(b)
+			if len(param) < maxCSIParamLength {
+				param = param + string(b)
+			}
 		case b > 0 && b <= 0x2F:
 			intermediate = append(intermediate, b)
 		case b >= csiTerminators.min && b <= csiTerminators.max:
@@ -83,50 +102,105 @@ func splitParams(paramString string) []string {
 	if paramString == "" {
 		params = []string{}
 	}
+	for i, param := range params {
+		if len(param) > 4 && strings.Trim(param, "0123456789") == "" {
+			if n, err := strconv.Atoi(param); err != nil || n > maxCSIParam {
+				params[i] = strconv.Itoa(maxCSIParam)
+			}
+		}
+	}
 	return params
 }
 
 func csiHandler(pty chan rune, terminal *Terminal) error {
 	final, param, intermediate := loadCSI(pty)
 
-	// process intermediate control codes before the CSI
+	// process control codes embedded in the sequence before it, keeping the intermediate characters, which are
+	// part of it
+	inter := ""
 	for _, b := range intermediate {
-		terminal.processRune(b)
+		if b < 0x20 {
+			terminal.processRune(b)
+		} else {
+			inter += string(b)
+		}
 	}
 
 	params := splitParams(param)
 
 	for _, sequence := range csiSequences {
-		if sequence.id == final {
+		if sequence.id == final && sequence.intermediate == inter {
 			if sequence.expectedParams != nil && (uint8(len(params)) < sequence.expectedParams.min || uint8(len(params)) > sequence.expectedParams.max) {
 				continue
 			}
+			if !sequence.local {
+				defer terminal.SetDirty()
+			}
 			x, y := terminal.ActiveBuffer().CursorColumn(), terminal.ActiveBuffer().CursorLine()
 			err := sequence.handler(params, terminal)
-			terminal.logger.Debugf("CSI 0x%02X (ESC[%s%s) %s - %d,%d -> %d,%d", final, param, string(final), sequence.description, x, y, terminal.ActiveBuffer().CursorColumn(), terminal.ActiveBuffer().CursorLine())
+			terminal.logger.Debugf("CSI 0x%02X (ESC[%s%s%s) %s - %d,%d -> %d,%d", final, param, inter, string(final), sequence.description, x, y, terminal.ActiveBuffer().CursorColumn(), terminal.ActiveBuffer().CursorLine())
 			return err
 		}
 	}
 
-	return fmt.Errorf("Unknown CSI control sequence: 0x%02X (ESC[%s%s)", final, param, string(final))
+	return fmt.Errorf("Unknown CSI control sequence: 0x%02X (ESC[%s%s%s)", final, param, inter, string(final))
 }
 
 func csiSendDeviceAttributesHandler(params []string, terminal *Terminal) error {
-	// we are VT100
-	// for DA1 we'll respond ?1;2
-	// for DA2 we'll respond >0;0;0
+	request := ""
+	if len(params) > 0 {
+		request = params[0]
+	}
+
+	switch request {
+	case "", "0": // DA1
+		// a VT220 with 132 columns (1), sixel graphics (4), selective erase (6) and ANSI colour (22)
+		_ = terminal.Write([]byte("\x1b[?62;1;4;6;22c"))
+	case ">", ">0": // DA2
+		// a VT220, then the version of aminal and the ROM cartridge number, which is always 0
+		_ = terminal.Write([]byte(fmt.Sprintf("\x1b[>1;%d;0c", versionNumber(terminal.options.Version))))
+	case "=", "=0": // DA3
+		// the unit ID, which we don't have
+		_ = terminal.Write([]byte("\x1bP!|00000000\x1b\\"))
+	default:
+		return fmt.Errorf("Unsupported Device Attributes request: CSI %s c", request)
+	}
 
-	response := "?1;2"
+	return nil
+}
 
-	if len(params) > 0 && len(params[0]) > 0 && params[0][0] == '>' {
-		response = ">0;0;0"
+// CSI > Ps q
+func csiReportVersionHandler(params []string, terminal *Terminal) error {
+	if len(params) == 0 || (params[0] != ">" && params[0] != ">0") {
+		return fmt.Errorf("Unsupported CSI %s q", strings.Join(params, ";"))
 	}
 
-	_ = terminal.Write([]byte("\x1b[" + response + "c"))
-
+	v := terminal.options.Version
+	if v == "" {
+		v = "development"
+	}
+	_ = terminal.Write([]byte(fmt.Sprintf("\x1bP>|aminal(%s)\x1b\\", strings.TrimPrefix(v, "v"))))
 	return nil
 }
 
+// versionNumber turns a version like v1.2.3 into a number like 10203, as DA2 reports it, or 0 if it can't be read
+func versionNumber(v string) int {
+	parts := strings.SplitN(strings.TrimPrefix(v, "v"), ".", 3)
+	if len(parts) != 3 {
+		return 0
+	}
+	number := 0
+	for _, part := range parts {
+		// anything after the patch number, like the commits since the tag from git describe, is dropped
+		n, err := strconv.Atoi(strings.SplitN(part, "-", 2)[0])
+		if err != nil || n < 0 || n > 99 {
+			return 0
+		}
+		number = number*100 + n
+	}
+	return number
+}
+
 func csiDeviceStatusReportHandler(params []string, terminal *Terminal) error {
 	if len(params) == 0 {
 		return fmt.Errorf("Missing Device Status Report identifier")
@@ -139,7 +213,13 @@ func csiDeviceStatusReportHandler(params []string, terminal *Terminal) error {
 		_ = terminal.Write([]byte(fmt.Sprintf(
 			"\x1b[%d;%dR",
 			terminal.ActiveBuffer().CursorLine()+1,
-			terminal.ActiveBuffer().CursorColumn()+1,
+			terminal.ActiveBuffer().CursorColumnRelative()+1,
+		)))
+	case "?6": // DECXCPR, which is the same but for the ? and the page the cursor is on
+		_ = terminal.Write([]byte(fmt.Sprintf(
+			"\x1b[?%d;%d;1R",
+			terminal.ActiveBuffer().CursorLine()+1,
+			terminal.ActiveBuffer().CursorColumnRelative()+1,
 		)))
 	default:
 		return fmt.Errorf("Unknown Device Status Report identifier: %s", params[0])
@@ -275,7 +355,7 @@ func csiCursorPositionHandler(params []string, terminal *Terminal) error {
 
 func csiScrollUpHandler(params []string, terminal *Terminal) error {
 	distance := 1
-	if len(params) > 1 {
+	if len(params) > 1 || (len(params) == 1 && strings.HasPrefix(params[0], "?")) {
 		return fmt.Errorf("Not supported")
 	}
 	if len(params) == 1 {
@@ -285,6 +365,10 @@ func csiScrollUpHandler(params []string, terminal *Terminal) error {
 			distance = 1
 		}
 	}
+	// scrolling further than the height of the screen clears it, just as scrolling the height does
+	if height := int(terminal.ActiveBuffer().ViewHeight()); distance > height {
+		distance = height
+	}
 	terminal.logger.Debugf("Scrolling up %d", distance)
 	terminal.AreaScrollUp(uint16(distance))
 	return nil
@@ -346,7 +430,7 @@ func csiDeleteLinesHandler(params []string, terminal *Terminal) error {
 
 func csiScrollDownHandler(params []string, terminal *Terminal) error {
 	distance := 1
-	if len(params) > 1 {
+	if len(params) > 1 || (len(params) == 1 && strings.HasPrefix(params[0], "?")) {
 		return fmt.Errorf("Not supported")
 	}
 	if len(params) == 1 {
@@ -356,6 +440,10 @@ func csiScrollDownHandler(params []string, terminal *Terminal) error {
 			distance = 1
 		}
 	}
+	// scrolling further than the height of the screen clears it, just as scrolling the height does
+	if height := int(terminal.ActiveBuffer().ViewHeight()); distance > height {
+		distance = height
+	}
 	terminal.logger.Debugf("Scrolling down %d", distance)
 	terminal.AreaScrollDown(uint16(distance))
 	return nil
@@ -394,6 +482,49 @@ func csiSetMarginsHandler(params []string, terminal *Terminal) error {
 	return nil
 }
 
+// CSI Pl ; Pr s sets the left and right margins while they're enabled with DECLRMM (DECSET 69), and otherwise
+// saves the cursor, as in the SCO console
+func csiSetLeftRightMarginsHandler(params []string, terminal *Terminal) error {
+	if len(params) > 0 && strings.HasPrefix(params[0], "?") {
+		return fmt.Errorf("Saving private modes is not supported")
+	}
+	if !terminal.terminalState.LeftRightMarginMode {
+		terminal.ActiveBuffer().SaveCursor()
+		return nil
+	}
+
+	width := int(terminal.ActiveBuffer().ViewWidth())
+	left, right := 1, width
+	if len(params) > 0 && params[0] != "" {
+		if n, err := strconv.Atoi(params[0]); err == nil && n >= 1 {
+			left = n
+		}
+	}
+	if len(params) > 1 && params[1] != "" {
+		if n, err := strconv.Atoi(params[1]); err == nil && n >= 1 && n <= width {
+			right = n
+		}
+	}
+	if left >= right {
+		return fmt.Errorf("Invalid left and right margins: %d and %d", left, right)
+	}
+
+	terminal.terminalState.SetHorizontalMargins(uint16(left-1), uint16(right-1))
+	terminal.ActiveBuffer().SetPosition(0, 0)
+	return nil
+}
+
+func csiRestoreCursorHandler(params []string, terminal *Terminal) error {
+	if len(params) > 0 {
+		if params[0] != "" && strings.ContainsRune("?><=", rune(params[0][0])) {
+			return csiKeyboardProtocolHandler(params, terminal)
+		}
+		return fmt.Errorf("Not supported")
+	}
+	terminal.ActiveBuffer().RestoreCursor()
+	return nil
+}
+
 func csiEraseCharactersHandler(params []string, terminal *Terminal) error {
 	count := 1
 	if len(params) > 0 {
@@ -432,7 +563,7 @@ func csiLinePositionAbsolute(params []string, terminal *Terminal) error {
 		}
 	}
 
-	terminal.ActiveBuffer().SetPosition(terminal.ActiveBuffer().CursorColumn(), uint16(row-1))
+	terminal.ActiveBuffer().SetPosition(terminal.ActiveBuffer().CursorColumnRelative(), uint16(row-1))
 
 	return nil
 }
@@ -482,8 +613,17 @@ func csiEraseInDisplayHandler(params []string, terminal *Terminal) error {
 		terminal.ActiveBuffer().EraseDisplayFromCursor()
 	case "1":
 		terminal.ActiveBuffer().EraseDisplayToCursor()
-	case "2", "3":
+	case "2":
 		terminal.ActiveBuffer().EraseDisplay()
+	case "3":
+		// xterm's E3, which clears the scrollback and leaves the screen alone
+		terminal.ActiveBuffer().ClearScrollback()
+	case "?0", "?":
+		terminal.ActiveBuffer().SelectiveEraseDisplayFromCursor()
+	case "?1":
+		terminal.ActiveBuffer().SelectiveEraseDisplayToCursor()
+	case "?2":
+		terminal.ActiveBuffer().SelectiveEraseDisplay()
 	default:
 		return fmt.Errorf("Unsupported ED: CSI %s J", n)
 	}
@@ -505,8 +645,60 @@ func csiEraseInLineHandler(params []string, terminal *Terminal) error {
 		terminal.ActiveBuffer().EraseLineToCursor()
 	case "2": // erase entire
 		terminal.ActiveBuffer().EraseLine()
+	case "?0", "?": // the same, leaving protected characters
+		terminal.ActiveBuffer().SelectiveEraseLineFromCursor()
+	case "?1":
+		terminal.ActiveBuffer().SelectiveEraseLineToCursor()
+	case "?2":
+		terminal.ActiveBuffer().SelectiveEraseLine()
 	default:
 		return fmt.Errorf("Unsupported EL: CSI %s K", n)
 	}
 	return nil
 }
+
+// CSI Ps " q
+func csiSelectCharacterProtectionHandler(params []string, terminal *Terminal) error {
+	n := "0"
+	if len(params) > 0 {
+		n = params[0]
+	}
+
+	switch n {
+	case "0", "", "2":
+		terminal.ActiveBuffer().CursorAttr().Protected = false
+	case "1": // characters written from now on can't be erased by DECSED and DECSEL
+		terminal.ActiveBuffer().CursorAttr().Protected = true
+	default:
+		return fmt.Errorf("Unsupported DECSCA: CSI %s \" q", n)
+	}
+	return nil
+}
+
+// CSI Ps ; Ps SP k sets the direction lines run in, which bidirectional text is laid out from: 1 for left to right,
+// 2 for right to left, or 0 to go by the first character with a direction. The second parameter is ignored.
+func csiSelectCharacterPathHandler(params []string, terminal *Terminal) error {
+	path := "0"
+	if len(params) > 0 {
+		path = params[0]
+	}
+
+	switch path {
+	case "0", "":
+		terminal.modes.BidiDirection = buffer.BidiAuto
+	case "1":
+		terminal.modes.BidiDirection = buffer.BidiLTR
+	case "2":
+		terminal.modes.BidiDirection = buffer.BidiRTL
+	default:
+		return fmt.Errorf("Unsupported SCP: CSI %s SP k", path)
+	}
+	return nil
+}
+
+// CSI Ps $ p, or CSI ? Ps $ p for DEC private modes
+func csiRequestModeHandler(params []string, terminal *Terminal) error {
+	mode := params[0]
+	_ = terminal.Write([]byte(fmt.Sprintf("\x1b[%s;%d$y", mode, modeState(mode, terminal))))
+	return nil
+}
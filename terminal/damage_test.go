@@ -0,0 +1,29 @@
+package terminal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDamage(t *testing.T) {
+	term := newHeadlessTerminal(20, 5)
+	term.processBytes([]byte("one\r\ntwo\r\n"))
+	term.TakeDamage()
+
+	// coloured text and cursor movement only change the rows written to
+	term.processBytes([]byte("\x1b[1;31mred\x1b[0m\x1b[1;1H\x1b[K"))
+	damage := term.TakeDamage()
+	assert.False(t, damage.All)
+	for row := 0; row < 5; row++ {
+		assert.Equal(t, row == 0 || row == 2, damage.Row(row), "row %d", row)
+	}
+
+	// but anything else redraws the whole view
+	term.processBytes([]byte("\x1b[2J"))
+	assert.True(t, term.TakeDamage().All)
+	term.processBytes([]byte("\x1b]0;title\x07"))
+	assert.True(t, term.TakeDamage().All)
+	term.processBytes([]byte("\x1b[?25l"))
+	assert.True(t, term.TakeDamage().All)
+}
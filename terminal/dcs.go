@@ -0,0 +1,128 @@
+package terminal
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/liamg/aminal/terminfo"
+)
+
+// tmux wraps sequences meant for the outer terminal in a DCS string starting with this, with each ESC doubled
+const tmuxPassthroughPrefix = "tmux;"
+
+// maxDCSLength caps how much of a DCS string we hold on to, which is enough for large sixel images. Longer
+// strings are read to their end and ignored.
+const maxDCSLength = 1 << 22
+
+func init() {
+	// registered here rather than in the map literal, as passthrough feeds back into the ansi handlers
+	ansiSequenceMap['P'] = dcsHandler
+}
+
+// dcsHandler reads a device control string, ESC P ... ST, and passes it on to the handler for its type
+func dcsHandler(pty chan rune, terminal *Terminal) error {
+	data := []rune{}
+	passthrough := false
+	abandoned := false
+	for {
+		if len(data) > maxDCSLength {
+			abandoned = true
+			data = data[:0]
+		}
+		b := readRune(pty)
+		if b == 0x1b {
+			next := readRune(pty)
+			if passthrough && next == 0x1b {
+				// a doubled ESC is a literal one in the wrapped sequence
+				data = append(data, next)
+				continue
+			}
+			if next == '\\' || (!passthrough && next == 0x07) {
+				break
+			}
+			data = append(data, b, next)
+			continue
+		}
+		data = append(data, b)
+		if !abandoned && len(data) == len(tmuxPassthroughPrefix) && string(data) == tmuxPassthroughPrefix {
+			passthrough = true
+			data = data[:0]
+		}
+	}
+
+	if abandoned {
+		return fmt.Errorf("Ignoring DCS string longer than %d characters", maxDCSLength)
+	}
+
+	if passthrough {
+		// the wrapped sequences are handled as though they'd been written directly
+		terminal.processBytes([]byte(string(data)))
+		return nil
+	}
+
+	if isSixel(data) {
+		return replaySixel(data, terminal)
+	}
+
+	if strings.HasPrefix(string(data), "+q") {
+		return requestTermcap(string(data[2:]), terminal)
+	}
+
+	terminal.logger.Debugf("Ignoring unsupported DCS string: %q", string(data))
+	return nil
+}
+
+// isSixel returns true if the DCS string is sixel graphics, which start with numeric parameters and 'q'
+func isSixel(data []rune) bool {
+	for _, r := range data {
+		if (r < '0' || r > '9') && r != ';' {
+			return r == 'q'
+		}
+	}
+	return false
+}
+
+// replaySixel hands the contents of a DCS string to the sixel handler, which reads it from its own channel
+func replaySixel(data []rune, terminal *Terminal) (err error) {
+	input := make(chan rune, len(data)+2)
+	for _, r := range data {
+		input <- r
+	}
+	input <- 0x1b
+	input <- '\\'
+	close(input)
+
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(endOfInput); !ok {
+				panic(r)
+			}
+			err = fmt.Errorf("Incomplete sequence in sixel data")
+		}
+	}()
+	return sixelHandler(input, terminal)
+}
+
+// requestTermcap answers XTGETTCAP, DCS + q Pt ST, where Pt is a list of hex encoded terminfo capability names
+// separated by semicolons. Each is answered with DCS 1 + r name=value ST, where the value is also hex encoded
+// and left out for a boolean, or DCS 0 + r name ST if it isn't one of ours.
+func requestTermcap(names string, terminal *Terminal) error {
+	for _, encoded := range strings.Split(names, ";") {
+		name, err := hex.DecodeString(encoded)
+		if err != nil {
+			_ = terminal.Write([]byte("\x1bP0+r" + encoded + "\x1b\\"))
+			return fmt.Errorf("Invalid XTGETTCAP capability name: %q", encoded)
+		}
+		value, ok := terminfo.Lookup(string(name))
+		switch {
+		case !ok:
+			_ = terminal.Write([]byte("\x1bP0+r" + encoded + "\x1b\\"))
+		case value == "":
+			_ = terminal.Write([]byte("\x1bP1+r" + encoded + "\x1b\\"))
+		default:
+			_ = terminal.Write([]byte("\x1bP1+r" + encoded + "=" + hex.EncodeToString([]byte(value)) + "\x1b\\"))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package terminal
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestTmuxPassthrough(t *testing.T) {
+	term := newHeadlessTerminal(20, 5)
+	term.processBytes([]byte("\x1bPtmux;\x1b\x1b]0;inner title\x07\x1b\\"))
+	assert.Equal(t, "inner title", term.GetTitle())
+
+	term.processBytes([]byte("\x1bPtmux;\x1b\x1b[1mhi\x1b\\after"))
+	cells := term.ActiveBuffer().GetVisibleLines()[0].Cells()
+	assert.Equal(t, "hiafter", term.ActiveBuffer().GetVisibleLines()[0].String())
+	assert.True(t, cells[0].Attr().Bold)
+}
+
+func TestUnknownDCSIsNotWritten(t *testing.T) {
+	term := newHeadlessTerminal(20, 5)
+	term.processBytes([]byte("\x1bP$qm\x1b\\ok"))
+	assert.Equal(t, "ok", term.ActiveBuffer().GetVisibleLines()[0].String())
+}
+
+func TestOverlongDCSIsAbandoned(t *testing.T) {
+	term := newHeadlessTerminal(20, 5)
+	term.processBytes([]byte("\x1bPtmux;\x1b\x1b]0;title" + strings.Repeat("x", maxDCSLength) + "\x07\x1b\\ok"))
+	assert.Equal(t, "", term.GetTitle())
+	assert.Equal(t, "ok", term.ActiveBuffer().GetVisibleLines()[0].String())
+}
+
+func TestRequestingTermcap(t *testing.T) {
+	pty := &recordingPty{}
+	term := New(pty, zap.NewNop().Sugar(), DefaultOptions())
+	_ = term.SetSize(20, 5)
+
+	// TN, colors and RGB, then something we don't have
+	term.processBytes([]byte("\x1bP+q544e;636f6c6f7273;524742;6b63757531\x1b\\"))
+
+	assert.Equal(t, "\x1bP1+r544e=616d696e616c\x1b\\"+
+		"\x1bP1+r636f6c6f7273=323536\x1b\\"+
+		"\x1bP1+r524742\x1b\\"+
+		"\x1bP0+r6b63757531\x1b\\", pty.written.String())
+}
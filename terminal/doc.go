@@ -0,0 +1,25 @@
+// Package terminal implements Aminal's terminal emulator: it reads output from a pty, parses control
+// codes and escape sequences, and maintains the screen state in a set of buffers.
+//
+// The package has no GUI dependencies and can be imported on its own, e.g. for multiplexers, SSH clients
+// or testing tools. The exported API of this package and the buffer package is considered stable and
+// follows semantic versioning - breaking changes will only be made in a new major version.
+//
+// A minimal headless terminal looks like this:
+//
+//	pty, _ := platform.NewPty(80, 25)
+//	proc, _ := pty.CreateGuestProcess("/bin/sh", "")
+//	term := terminal.New(pty, logger, terminal.DefaultOptions())
+//	_ = term.SetSize(80, 25)
+//	go term.Read()
+//	proc.Wait()
+//	fmt.Println(term.ActiveBuffer().GetVisibleLines()[0].String())
+//
+// To test a TUI without running it in a pty, NewHeadless gives a terminal which is fed the program's output
+// directly, returning once it has been processed, so the screen can be checked straight after:
+//
+//	term, _ := terminal.NewHeadless(80, 24, terminal.DefaultOptions())
+//	defer term.Close()
+//	term.Feed(output)
+//	fmt.Println(term.Screen()[0], term.GetLogicalCursorX(), string(term.Replies()))
+package terminal
@@ -0,0 +1,72 @@
+//go:build go1.18
+// +build go1.18
+
+package terminal
+
+import (
+	"testing"
+	"time"
+)
+
+var fuzzSeeds = []string{
+	"hello world\r\n",
+	"\x1b[1;31mred\x1b[0m",
+	"\x1b[2J\x1b[H\x1b[10;20r\x1b[5L\x1b[3M",
+	"\x1b[?1049h\x1b[?1049l",
+	"\x1b]0;title\x07",
+	"\x1b[38;5;200m\x1b[48;2;1;2;3m",
+	"\x1b#8",
+	"\x1b(0lqk\x1b(B",
+	"\x1bPq#0;2;0;0;0#1!10~-\x1b\\",
+	"\x1b[9999;9999H\x1b[99999@\x1b[99999P",
+	"\x1b[20;5r\x1b[3L\x1b[3M\x1bM\x1bD",
+	"\x1b[?6h\x1b[5;10r\x1b[99;99H\x1b[2S\x1b[2T",
+	"\x1b7\x1b[?1049h\x1b8\x1b[K\x1b[1K\x1b[2K\x1b[J\x1b[1J\x1b[3J",
+	"\x1b[99999999999999999999L",
+	"\x1b[2M",
+	"\x1b[?69h\x1b[5;10s\x1b[?6h\x1b[3L\x1b[3M\x1b[9@\x1b[9P\x1bD\x1bM\x1b[99;99Hwrapped text",
+	"\x1b[",
+	"\x1b]",
+}
+
+// FuzzTerminal feeds the output in two parts split anywhere, as reads from a pty can be, resizing the terminal in
+// between, then reads the screen back as an embedding program would
+func FuzzTerminal(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add(uint8(80), uint8(24), uint8(5), uint8(3), uint16(len(seed)/2), []byte(seed))
+		f.Add(uint8(1), uint8(1), uint8(80), uint8(24), uint16(len(seed)), []byte(seed))
+	}
+	f.Fuzz(func(t *testing.T, cols uint8, rows uint8, newCols uint8, newRows uint8, split uint16, data []byte) {
+		if cols == 0 || rows == 0 || newCols == 0 || newRows == 0 {
+			return
+		}
+		if int(split) > len(data) {
+			split = uint16(len(data))
+		}
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			term, err := NewHeadless(uint(cols), uint(rows), DefaultOptions())
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer term.Close()
+			term.Feed(data[:split])
+			if err := term.SetSize(uint(newCols), uint(newRows)); err != nil {
+				t.Error(err)
+				return
+			}
+			term.Feed(data[split:])
+			term.Screen()
+			term.GetLogicalCursorX()
+			term.GetLogicalCursorY()
+			term.Replies()
+		}()
+		select {
+		case <-done:
+		case <-time.After(time.Second * 5):
+			t.Fatalf("Processing %q did not complete", data)
+		}
+	})
+}
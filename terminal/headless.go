@@ -0,0 +1,126 @@
+package terminal
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/liamg/aminal/platform"
+	"go.uber.org/zap"
+)
+
+// Headless is a terminal with no pty or window, for embedding the emulator in other programs, e.g. to test TUIs.
+// Output is fed to it directly, and what the terminal would send back to the program, such as answers to queries
+// or what's passed to Write, is kept to be taken with Replies.
+//
+//	term, _ := terminal.NewHeadless(80, 24, terminal.DefaultOptions())
+//	defer term.Close()
+//	term.Feed([]byte("\x1b[1mhello\x1b[0m"))
+//	fmt.Println(term.Screen()[0], term.GetCell(0, 0).Attr().Bold)
+type Headless struct {
+	*Terminal
+	pty     *headlessPty
+	input   chan rune
+	lock    sync.Mutex // held while feeding
+	partial []byte     // the start of a UTF-8 character whose end hasn't been fed yet
+	closed  bool
+}
+
+// NewHeadless creates a terminal of the given size, ready to be fed
+func NewHeadless(cols uint, rows uint, options Options) (*Headless, error) {
+	pty := &headlessPty{closed: make(chan struct{})}
+	t := New(pty, zap.NewNop().Sugar(), options)
+	t.SetCharSize(8, 16) // for sizing images in cells
+	if err := t.SetSize(cols, rows); err != nil {
+		return nil, err
+	}
+	h := &Headless{
+		Terminal: t,
+		pty:      pty,
+		input:    make(chan rune), // unbuffered, see flushInput
+	}
+	t.input = h.input
+	go t.processInput(h.input)
+	return h, nil
+}
+
+// Feed processes the data as output from the program, returning once all of it has been. Escape sequences and
+// characters may be split across calls, as they are by reads from a pty.
+func (h *Headless) Feed(data []byte) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	data = append(h.partial, data...)
+	for len(data) > 0 && utf8.FullRune(data) {
+		r, size := utf8.DecodeRune(data)
+		h.input <- r
+		data = data[size:]
+	}
+	h.partial = append([]byte{}, data...)
+	h.input <- flushInput
+}
+
+// Replies returns what the terminal has sent to the program since it was last called
+func (h *Headless) Replies() []byte {
+	return h.pty.take()
+}
+
+// Screen returns the text of each row on screen, from the top, without trailing spaces
+func (h *Headless) Screen() []string {
+	_, rows := h.GetSize()
+	screen := make([]string, rows)
+	for i, line := range h.GetVisibleLines() {
+		if i < rows {
+			screen[i] = line.String()
+		}
+	}
+	return screen
+}
+
+// Close stops processing output. The terminal can still be inspected but mustn't be fed.
+func (h *Headless) Close() error {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if !h.closed {
+		close(h.input)
+		close(h.pty.closed)
+		h.closed = true
+	}
+	return nil
+}
+
+// headlessPty keeps what the terminal writes to the program. Reading it gives nothing until the terminal is
+// closed, so a window showing the terminal keeps it open until then.
+type headlessPty struct {
+	closed  chan struct{}
+	lock    sync.Mutex
+	written []byte
+}
+
+func (p *headlessPty) Read(b []byte) (int, error) {
+	<-p.closed
+	return 0, io.EOF
+}
+func (p *headlessPty) Write(b []byte) (int, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.written = append(p.written, b...)
+	return len(b), nil
+}
+func (p *headlessPty) Close() error              { return nil }
+func (p *headlessPty) Resize(x int, y int) error { return nil }
+func (p *headlessPty) CreateGuestProcess(imagePath string, dir string) (platform.Process, error) {
+	return nil, fmt.Errorf("A headless terminal can't run programs")
+}
+func (p *headlessPty) GetPlatformDependentSettings() platform.PlatformDependentSettings {
+	return platform.DefaultSettings()
+}
+
+func (p *headlessPty) take() []byte {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	written := p.written
+	p.written = nil
+	return written
+}
@@ -0,0 +1,51 @@
+package terminal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeadlessFeed(t *testing.T) {
+	term, err := NewHeadless(10, 3, DefaultOptions())
+	require.Nil(t, err)
+	defer term.Close()
+
+	term.Feed([]byte("one\r\n\x1b[1"))
+	term.Feed([]byte("mtwo\x1b[0m \xe2\x9c"))
+	term.Feed([]byte("\x93"))
+
+	assert.Equal(t, []string{"one", "two ✓", ""}, term.Screen())
+	assert.True(t, term.GetCell(0, 1).Attr().Bold)
+	assert.False(t, term.GetCell(4, 1).Attr().Bold)
+	assert.Equal(t, uint16(5), term.GetLogicalCursorX())
+	assert.Equal(t, uint16(1), term.GetLogicalCursorY())
+}
+
+func TestHeadlessReplies(t *testing.T) {
+	term, err := NewHeadless(10, 3, DefaultOptions())
+	require.Nil(t, err)
+	defer term.Close()
+
+	term.Feed([]byte("ab\x1b[6n"))
+	assert.Equal(t, "\x1b[1;3R", string(term.Replies()))
+	assert.Empty(t, term.Replies())
+
+	require.Nil(t, term.Write([]byte("q")))
+	assert.Equal(t, "q", string(term.Replies()))
+}
+
+func TestSetSizeRejectsInvalidSizes(t *testing.T) {
+	term, err := NewHeadless(10, 3, DefaultOptions())
+	require.Nil(t, err)
+	defer term.Close()
+
+	assert.NotNil(t, term.SetSize(0, 3))
+	assert.NotNil(t, term.SetSize(10, 0))
+	assert.NotNil(t, term.SetSize(70000, 3))
+	assert.Equal(t, uint16(10), term.ActiveBuffer().ViewWidth())
+
+	_, err = NewHeadless(0, 0, DefaultOptions())
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,295 @@
+package terminal
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Key is the key pressed: the character on it, unshifted, or one of the Key codes for keys without one. They
+// are the codes the kitty keyboard protocol reports, see https://sw.kovidgoyal.net/kitty/keyboard-protocol/
+type Key rune
+
+const (
+	KeyTab       Key = 9
+	KeyEnter     Key = 13
+	KeyEscape    Key = 27
+	KeyBackspace Key = 127
+	KeyInsert    Key = 57348
+	KeyDelete    Key = 57349
+	KeyLeft      Key = 57350
+	KeyRight     Key = 57351
+	KeyUp        Key = 57352
+	KeyDown      Key = 57353
+	KeyPageUp    Key = 57354
+	KeyPageDown  Key = 57355
+	KeyHome      Key = 57356
+	KeyEnd       Key = 57357
+	KeyF1        Key = 57364 // F2 to F12 follow on from F1
+	KeyKPEnter   Key = 57414
+)
+
+// functionalKeys are the keys the kitty protocol reports as xterm does, with a number and a final character
+var functionalKeys = map[Key]struct {
+	number int
+	final  byte
+}{
+	KeyInsert:   {2, '~'},
+	KeyDelete:   {3, '~'},
+	KeyLeft:     {1, 'D'},
+	KeyRight:    {1, 'C'},
+	KeyUp:       {1, 'A'},
+	KeyDown:     {1, 'B'},
+	KeyPageUp:   {5, '~'},
+	KeyPageDown: {6, '~'},
+	KeyHome:     {1, 'H'},
+	KeyEnd:      {1, 'F'},
+	KeyF1:       {1, 'P'},
+	KeyF1 + 1:   {1, 'Q'},
+	KeyF1 + 2:   {13, '~'},
+	KeyF1 + 3:   {1, 'S'},
+	KeyF1 + 4:   {15, '~'},
+	KeyF1 + 5:   {17, '~'},
+	KeyF1 + 6:   {18, '~'},
+	KeyF1 + 7:   {19, '~'},
+	KeyF1 + 8:   {20, '~'},
+	KeyF1 + 9:   {21, '~'},
+	KeyF1 + 10:  {23, '~'},
+	KeyF1 + 11:  {24, '~'},
+}
+
+// KeyModifiers are the modifier keys held down with a key, with the values both keyboard protocols give them
+type KeyModifiers uint8
+
+const (
+	KeyModShift KeyModifiers = 1 << iota
+	KeyModAlt
+	KeyModCtrl
+	KeyModSuper
+	KeyModCapsLock KeyModifiers = 64
+	KeyModNumLock  KeyModifiers = 128
+
+	keyModLocks = KeyModCapsLock | KeyModNumLock
+)
+
+// KeyAction is what happened to the key
+type KeyAction uint8
+
+const (
+	KeyPress KeyAction = iota + 1
+	KeyRepeat
+	KeyRelease
+)
+
+// KeyEvent is a key which may be reported to the application with one of the extended keyboard protocols
+type KeyEvent struct {
+	Key       Key
+	Modifiers KeyModifiers
+	Action    KeyAction
+}
+
+// flags the application can ask for with the kitty keyboard protocol
+const (
+	kittyDisambiguate = 1 // keys which would be ambiguous, like ctrl + i and tab, are sent as escape codes
+	kittyEventTypes   = 2 // repeats and releases are reported too
+	kittyAllKeys      = 8 // every key is sent as an escape code, even those which type text
+
+	// the flags we support, which are all the application is told it has. Alternate keys (4) and associated
+	// text (16) aren't supported.
+	kittySupportedFlags = kittyDisambiguate | kittyEventTypes | kittyAllKeys
+
+	// how many sets of flags each screen remembers, the oldest being dropped when there are more
+	kittyStackSize = 16
+)
+
+// KeyReport encodes the key as the application has asked for it with the kitty keyboard protocol, CSI > u, or
+// xterm's modifyOtherKeys, CSI > 4 m. It returns nil if the key should be sent as it always has been, or not
+// at all in the case of a release.
+func (terminal *Terminal) KeyReport(e KeyEvent) []byte {
+	if flags := terminal.keyboardFlags(); flags != 0 {
+		return kittyKeyReport(e, flags)
+	}
+	if terminal.modifyOtherKeys > 0 && e.Action != KeyRelease {
+		return modifyOtherKeysReport(e, terminal.modifyOtherKeys)
+	}
+	return nil
+}
+
+func kittyKeyReport(e KeyEvent, flags int) []byte {
+	if e.Action == KeyRelease && flags&kittyEventTypes == 0 {
+		return nil
+	}
+	mods := e.Modifiers
+	if flags&kittyAllKeys == 0 {
+		// caps and num lock are only reported when every key is
+		mods &^= keyModLocks
+	}
+	legacy := flags&kittyAllKeys == 0 && e.Action != KeyRelease
+
+	params := ""
+	if mods != 0 || (e.Action != KeyPress && flags&kittyEventTypes != 0) {
+		params = strconv.Itoa(int(mods) + 1)
+		if e.Action != KeyPress && flags&kittyEventTypes != 0 {
+			params += fmt.Sprintf(":%d", e.Action)
+		}
+	}
+
+	if key, ok := functionalKeys[e.Key]; ok {
+		if legacy && (e.Action == KeyPress || flags&kittyEventTypes == 0) {
+			// sent as xterm would, which the protocol keeps to
+			return nil
+		}
+		number := ""
+		if key.number != 1 || params != "" || key.final == '~' {
+			number = strconv.Itoa(key.number)
+		}
+		if params != "" {
+			params = ";" + params
+		}
+		return []byte(fmt.Sprintf("\x1b[%s%s%c", number, params, key.final))
+	}
+
+	switch {
+	case e.Key == KeyEscape:
+		// always sent as an escape code, so it can't be mistaken for the start of one
+	case e.Key == KeyEnter || e.Key == KeyTab || e.Key == KeyBackspace:
+		// these aren't released until every key is reported, so that shells which don't know the protocol
+		// aren't sent releases after the command they're running has turned it on
+		if (legacy && mods == 0) || (e.Action == KeyRelease && flags&kittyAllKeys == 0) {
+			return nil
+		}
+	case legacy && mods&^KeyModShift == 0:
+		// text, which is typed as it always has been
+		return nil
+	}
+
+	if params != "" {
+		params = ";" + params
+	}
+	return []byte(fmt.Sprintf("\x1b[%d%su", e.Key, params))
+}
+
+// modifyOtherKeysReport encodes a key held with modifiers as CSI 27 ; modifiers ; character ~. At level 1 that's
+// only keys which have no code of their own, like ctrl + 1, and at level 2 it's every key but shifted text.
+func modifyOtherKeysReport(e KeyEvent, level int) []byte {
+	mods := e.Modifiers &^ keyModLocks
+	if mods == 0 {
+		return nil
+	}
+	if _, ok := functionalKeys[e.Key]; ok {
+		return nil
+	}
+
+	r := rune(e.Key)
+	letter := r >= 'a' && r <= 'z'
+	special := e.Key == KeyEnter || e.Key == KeyTab || e.Key == KeyBackspace || e.Key == KeyEscape
+	switch {
+	case level >= 2:
+		if mods == KeyModShift && !special {
+			return nil
+		}
+	case special || mods&KeyModCtrl == 0 || (letter && mods&KeyModShift == 0):
+		return nil
+	}
+
+	if letter && mods&KeyModShift != 0 {
+		r -= 'a' - 'A'
+	}
+	return []byte(fmt.Sprintf("\x1b[27;%d;%d~", int(mods)+1, r))
+}
+
+// keyboardFlags returns the kitty keyboard protocol flags the application has set for the active screen
+func (terminal *Terminal) keyboardFlags() int {
+	stack := terminal.keyboardStack()
+	if len(*stack) == 0 {
+		return 0
+	}
+	return (*stack)[len(*stack)-1]
+}
+
+// keyboardStack returns the kitty keyboard protocol flags pushed for the active screen, which each has its own of
+func (terminal *Terminal) keyboardStack() *[]int {
+	if terminal.UsingMainBuffer() {
+		return &terminal.keyboardStacks[MainBuffer]
+	}
+	return &terminal.keyboardStacks[AltBuffer]
+}
+
+// setKeyboardFlags replaces the flags at the top of the active screen's stack, or pushes them if it's empty
+func (terminal *Terminal) setKeyboardFlags(flags int) {
+	stack := terminal.keyboardStack()
+	if len(*stack) == 0 {
+		*stack = append(*stack, flags&kittySupportedFlags)
+		return
+	}
+	(*stack)[len(*stack)-1] = flags & kittySupportedFlags
+}
+
+// CSI ? u, CSI > flags u, CSI < number u and CSI = flags ; mode u, from the kitty keyboard protocol
+func csiKeyboardProtocolHandler(params []string, terminal *Terminal) error {
+	prefix := params[0][0]
+	params[0] = params[0][1:]
+	numbers := make([]int, 2)
+	for i := 0; i < len(params) && i < len(numbers); i++ {
+		if params[i] == "" {
+			continue
+		}
+		n, err := strconv.Atoi(params[i])
+		if err != nil || n < 0 {
+			return fmt.Errorf("Invalid keyboard protocol parameter: %q", params[i])
+		}
+		numbers[i] = n
+	}
+
+	stack := terminal.keyboardStack()
+	switch prefix {
+	case '?':
+		_ = terminal.Write([]byte(fmt.Sprintf("\x1b[?%du", terminal.keyboardFlags())))
+	case '>':
+		if len(*stack) == kittyStackSize {
+			*stack = (*stack)[1:]
+		}
+		*stack = append(*stack, numbers[0]&kittySupportedFlags)
+	case '<':
+		pop := numbers[0]
+		if pop == 0 {
+			pop = 1
+		}
+		if pop > len(*stack) {
+			pop = len(*stack)
+		}
+		*stack = (*stack)[:len(*stack)-pop]
+	case '=':
+		flags := terminal.keyboardFlags()
+		switch numbers[1] {
+		case 0, 1:
+			flags = numbers[0]
+		case 2:
+			flags |= numbers[0]
+		case 3:
+			flags &^= numbers[0]
+		default:
+			return fmt.Errorf("Unsupported keyboard protocol mode: %d", numbers[1])
+		}
+		terminal.setKeyboardFlags(flags)
+	}
+	return nil
+}
+
+// CSI > 4 ; Pv m sets xterm's modifyOtherKeys, which CSI > 4 m resets
+func csiModifyKeysHandler(params []string, terminal *Terminal) error {
+	resource := strings.TrimPrefix(params[0], ">")
+	if resource != "4" {
+		return fmt.Errorf("Unsupported key modifier resource: %s", resource)
+	}
+	level := 0
+	if len(params) > 1 && params[1] != "" {
+		n, err := strconv.Atoi(params[1])
+		if err != nil || n < 0 || n > 2 {
+			return fmt.Errorf("Invalid modifyOtherKeys level: %q", params[1])
+		}
+		level = n
+	}
+	terminal.modifyOtherKeys = level
+	return nil
+}
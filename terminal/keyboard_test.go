@@ -0,0 +1,93 @@
+package terminal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func newKeyboardTerminal() (*Terminal, *recordingPty) {
+	pty := &recordingPty{}
+	term := New(pty, zap.NewNop().Sugar(), DefaultOptions())
+	_ = term.SetSize(20, 5)
+	return term, pty
+}
+
+func TestKeysAreSentAsBeforeUntilAsked(t *testing.T) {
+	term, _ := newKeyboardTerminal()
+	assert.Nil(t, term.KeyReport(KeyEvent{Key: 'i', Modifiers: KeyModCtrl, Action: KeyPress}))
+	assert.Nil(t, term.KeyReport(KeyEvent{Key: 'a', Action: KeyRelease}))
+}
+
+func TestKittyDisambiguatesKeys(t *testing.T) {
+	term, _ := newKeyboardTerminal()
+	term.processBytes([]byte("\x1b[>1u"))
+
+	assert.Equal(t, "\x1b[105;5u", string(term.KeyReport(KeyEvent{Key: 'i', Modifiers: KeyModCtrl, Action: KeyPress})))
+	assert.Nil(t, term.KeyReport(KeyEvent{Key: KeyTab, Action: KeyPress}), "tab is still a tab")
+	assert.Equal(t, "\x1b[9;2u", string(term.KeyReport(KeyEvent{Key: KeyTab, Modifiers: KeyModShift, Action: KeyPress})))
+	assert.Equal(t, "\x1b[27u", string(term.KeyReport(KeyEvent{Key: KeyEscape, Action: KeyPress})))
+	assert.Equal(t, "\x1b[97;3u", string(term.KeyReport(KeyEvent{Key: 'a', Modifiers: KeyModAlt, Action: KeyPress})))
+	assert.Nil(t, term.KeyReport(KeyEvent{Key: 'a', Modifiers: KeyModShift | KeyModCapsLock, Action: KeyPress}), "text is typed")
+	assert.Nil(t, term.KeyReport(KeyEvent{Key: KeyUp, Action: KeyPress}), "functional keys are sent as before")
+	assert.Nil(t, term.KeyReport(KeyEvent{Key: 'a', Action: KeyRelease}))
+}
+
+func TestKittyReportsEventTypes(t *testing.T) {
+	term, _ := newKeyboardTerminal()
+	term.processBytes([]byte("\x1b[>3u"))
+
+	assert.Equal(t, "\x1b[97;1:3u", string(term.KeyReport(KeyEvent{Key: 'a', Action: KeyRelease})))
+	assert.Equal(t, "\x1b[1;1:2A", string(term.KeyReport(KeyEvent{Key: KeyUp, Action: KeyRepeat})))
+	assert.Equal(t, "\x1b[3;5:3~", string(term.KeyReport(KeyEvent{Key: KeyDelete, Modifiers: KeyModCtrl, Action: KeyRelease})))
+	assert.Nil(t, term.KeyReport(KeyEvent{Key: KeyEnter, Action: KeyRelease}))
+}
+
+func TestKittyReportsAllKeys(t *testing.T) {
+	term, _ := newKeyboardTerminal()
+	term.processBytes([]byte("\x1b[>8u"))
+
+	assert.Equal(t, "\x1b[97u", string(term.KeyReport(KeyEvent{Key: 'a', Action: KeyPress})))
+	assert.Equal(t, "\x1b[97;66u", string(term.KeyReport(KeyEvent{Key: 'a', Modifiers: KeyModShift | KeyModCapsLock, Action: KeyPress})))
+	assert.Equal(t, "\x1b[13u", string(term.KeyReport(KeyEvent{Key: KeyEnter, Action: KeyPress})))
+	assert.Equal(t, "\x1b[A", string(term.KeyReport(KeyEvent{Key: KeyUp, Action: KeyPress})))
+	assert.Equal(t, "\x1b[13~", string(term.KeyReport(KeyEvent{Key: KeyF1 + 2, Action: KeyPress})))
+}
+
+func TestKittyFlagStack(t *testing.T) {
+	term, pty := newKeyboardTerminal()
+
+	term.processBytes([]byte("\x1b[?u\x1b[>1u\x1b[>31u\x1b[?u"))
+	assert.Equal(t, "\x1b[?0u\x1b[?11u", pty.written.String(), "only the supported flags are set")
+
+	pty.written.Reset()
+	term.processBytes([]byte("\x1b[=2;2u\x1b[?u\x1b[=8;3u\x1b[?u\x1b[<u\x1b[?u\x1b[<5u\x1b[?u"))
+	assert.Equal(t, "\x1b[?11u\x1b[?3u\x1b[?1u\x1b[?0u", pty.written.String())
+
+	term.processBytes([]byte("\x1b[>1u\x1b[?1049h"))
+	assert.Nil(t, term.KeyReport(KeyEvent{Key: 'i', Modifiers: KeyModCtrl, Action: KeyPress}), "the alt screen has its own flags")
+	term.processBytes([]byte("\x1b[?1049l"))
+	assert.NotNil(t, term.KeyReport(KeyEvent{Key: 'i', Modifiers: KeyModCtrl, Action: KeyPress}))
+
+	term.processBytes([]byte("\x1bc"))
+	assert.Nil(t, term.KeyReport(KeyEvent{Key: 'i', Modifiers: KeyModCtrl, Action: KeyPress}), "a reset clears the flags")
+}
+
+func TestModifyOtherKeys(t *testing.T) {
+	term, _ := newKeyboardTerminal()
+	term.processBytes([]byte("\x1b[>4;1m"))
+
+	assert.Nil(t, term.KeyReport(KeyEvent{Key: 'i', Modifiers: KeyModCtrl, Action: KeyPress}), "ctrl + letters have their own codes")
+	assert.Equal(t, "\x1b[27;5;49~", string(term.KeyReport(KeyEvent{Key: '1', Modifiers: KeyModCtrl, Action: KeyPress})))
+	assert.Equal(t, "\x1b[27;6;73~", string(term.KeyReport(KeyEvent{Key: 'i', Modifiers: KeyModCtrl | KeyModShift, Action: KeyPress})))
+
+	term.processBytes([]byte("\x1b[>4;2m"))
+	assert.Equal(t, "\x1b[27;5;105~", string(term.KeyReport(KeyEvent{Key: 'i', Modifiers: KeyModCtrl, Action: KeyPress})))
+	assert.Equal(t, "\x1b[27;2;9~", string(term.KeyReport(KeyEvent{Key: KeyTab, Modifiers: KeyModShift, Action: KeyPress})))
+	assert.Nil(t, term.KeyReport(KeyEvent{Key: 'a', Modifiers: KeyModShift, Action: KeyPress}), "shifted text is typed")
+	assert.Nil(t, term.KeyReport(KeyEvent{Key: 'a', Modifiers: KeyModCtrl, Action: KeyRelease}))
+
+	term.processBytes([]byte("\x1b[>4m"))
+	assert.Nil(t, term.KeyReport(KeyEvent{Key: '1', Modifiers: KeyModCtrl, Action: KeyPress}))
+}
@@ -0,0 +1,100 @@
+package terminal
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// screenText returns the lines on screen, with the blank cells in them as spaces
+func screenText(term *Terminal) []string {
+	lines := []string{}
+	for _, line := range term.ActiveBuffer().GetVisibleLines() {
+		lines = append(lines, strings.Replace(line.String(), "\x00", " ", -1))
+	}
+	return lines
+}
+
+// fillScreen writes a different letter across each row of the screen
+func fillScreen(term *Terminal, rows int, cols int) {
+	for row := 0; row < rows; row++ {
+		term.processBytes([]byte(fmt.Sprintf("\x1b[%d;1H%s", row+1, strings.Repeat(string(rune('a'+row)), cols))))
+	}
+}
+
+func TestTextWrapsBetweenLeftAndRightMargins(t *testing.T) {
+	term := newHeadlessTerminal(10, 4)
+	term.processBytes([]byte("\x1b[?69h\x1b[3;6s"))
+	assert.Equal(t, uint16(0), term.GetLogicalCursorX(), "setting the margins moves the cursor home")
+
+	term.processBytes([]byte("\x1b[1;3Hhello world\r!"))
+
+	assert.Equal(t, []string{"  hell", "  o wo", "  !ld"}, screenText(term)[:3])
+	assert.False(t, term.ActiveBuffer().GetVisibleLines()[1].Wrapped(), "text between margins isn't joined when copied")
+}
+
+func TestScrollingBetweenLeftAndRightMargins(t *testing.T) {
+	term := newHeadlessTerminal(6, 3)
+	fillScreen(term, 3, 6)
+	term.processBytes([]byte("\x1b[?69h\x1b[2;4s\x1b[3;2H\n"))
+
+	assert.Equal(t, []string{"abbbaa", "bcccbb", "c   cc"}, screenText(term))
+	assert.Equal(t, 3, term.ActiveBuffer().Height(), "nothing is scrolled into the scrollback")
+
+	term.processBytes([]byte("\x1b[1;2H\x1bM"))
+	assert.Equal(t, []string{"a   aa", "bbbbbb", "cccccc"}, screenText(term))
+}
+
+func TestInsertAndDeleteBetweenLeftAndRightMargins(t *testing.T) {
+	term := newHeadlessTerminal(6, 3)
+	fillScreen(term, 3, 6)
+	term.processBytes([]byte("\x1b[?69h\x1b[2;4s"))
+
+	term.processBytes([]byte("\x1b[1;2H\x1b[@"))
+	assert.Equal(t, "a aaaa", screenText(term)[0], "characters pushed past the right margin are lost")
+
+	term.processBytes([]byte("\x1b[2;3H\x1b[P"))
+	assert.Equal(t, "bbb bb", screenText(term)[1])
+
+	term.processBytes([]byte("\x1b[2;2H\x1b[L"))
+	assert.Equal(t, []string{"a aaaa", "b   bb", "cbb cc"}, screenText(term))
+	assert.Equal(t, uint16(1), term.GetLogicalCursorX(), "the cursor moves to the left margin")
+
+	term.processBytes([]byte("\x1b[M"))
+	assert.Equal(t, []string{"a aaaa", "bbb bb", "c   cc"}, screenText(term))
+
+	term.processBytes([]byte("\x1b[1;6H\x1b[L\x1b[P"))
+	assert.Equal(t, "a aaaa", screenText(term)[0], "nothing changes with the cursor outside the margins")
+}
+
+func TestOriginModeWithLeftAndRightMargins(t *testing.T) {
+	pty := &recordingPty{}
+	term := New(pty, zap.NewNop().Sugar(), DefaultOptions())
+	_ = term.SetSize(20, 10)
+
+	term.processBytes([]byte("\x1b[?69h\x1b[5;10s\x1b[3;6r\x1b[?6h"))
+	assert.Equal(t, uint16(4), term.GetLogicalCursorX())
+	assert.Equal(t, uint16(2), term.GetLogicalCursorY())
+
+	term.processBytes([]byte("\x1b[2;3H\x1b[6n"))
+	assert.Equal(t, uint16(6), term.GetLogicalCursorX())
+	assert.Equal(t, uint16(3), term.GetLogicalCursorY())
+	assert.Equal(t, "\x1b[2;3R", pty.written.String(), "the position is reported relative to the margins")
+
+	term.processBytes([]byte("\x1b[99C"))
+	assert.Equal(t, uint16(9), term.GetLogicalCursorX(), "the cursor can't leave the margins")
+	term.processBytes([]byte("\x1b[99D"))
+	assert.Equal(t, uint16(4), term.GetLogicalCursorX())
+}
+
+func TestLeftRightMarginModeOff(t *testing.T) {
+	term := newHeadlessTerminal(10, 4)
+	term.processBytes([]byte("\x1b[2;5Habc\x1b[s\x1b[1;1H\x1b[u!"))
+	assert.Equal(t, "    abc!", screenText(term)[1], "without DECLRMM, CSI s saves the cursor")
+
+	term.processBytes([]byte("\x1b[?69h\x1b[3;6s\x1b[?69l\x1b[4;1H0123456789"))
+	assert.Equal(t, "0123456789", screenText(term)[3], "turning DECLRMM off resets the margins")
+}
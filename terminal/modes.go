@@ -1,7 +1,6 @@
 package terminal
 
 import (
-	"errors"
 	"fmt"
 	"strings"
 )
@@ -68,11 +67,14 @@ func csiSetMode(modeStr string, enabled bool, terminal *Terminal) error {
 
 	switch modeStr {
 	case "4":
-		if enabled { // @todo support replace mode
+		if enabled {
 			terminal.SetInsertMode()
 		} else {
 			terminal.SetReplaceMode()
 		}
+	case "8":
+		// BDSM: set when the application lays out bidirectional text itself, reset for the terminal to do it
+		terminal.modes.Bidi = !enabled
 	case "20":
 		if enabled {
 			terminal.SetNewLineMode()
@@ -105,6 +107,12 @@ func csiSetMode(modeStr string, enabled bool, terminal *Terminal) error {
 		// auto-wrap mode
 		// DECAWM
 		terminal.SetAutoWrap(enabled)
+	case "?45":
+		// reverse-wraparound mode
+		terminal.terminalState.ReverseWrap = enabled
+	case "?69":
+		// DECLRMM - left and right margins can be set with DECSLRM
+		terminal.SetLeftRightMarginMode(enabled)
 	case "?9":
 		if enabled {
 			terminal.logger.Infof("Turning on X10 mouse mode")
@@ -117,12 +125,22 @@ func csiSetMode(modeStr string, enabled bool, terminal *Terminal) error {
 		terminal.modes.BlinkingCursor = enabled
 	case "?25":
 		terminal.modes.ShowCursor = enabled
-	case "?47", "?1047":
+	case "?47":
 		if enabled {
 			terminal.UseAltBuffer()
 		} else {
 			terminal.UseMainBuffer()
 		}
+	case "?1047":
+		// as 47, but the alt buffer is cleared when leaving it
+		if enabled {
+			terminal.UseAltBuffer()
+		} else {
+			if !terminal.UsingMainBuffer() {
+				terminal.ActiveBuffer().Clear()
+			}
+			terminal.UseMainBuffer()
+		}
 	case "?1000", "?10061000": // ?10061000 seen from htop
 		// enable mouse tracking
 		// 1000 refers to ext mode for extended mouse click area - otherwise only x <= 255-31
@@ -142,27 +160,21 @@ func csiSetMode(modeStr string, enabled bool, terminal *Terminal) error {
 			terminal.SetMouseMode(MouseModeNone)
 		}
 	case "?1003":
-		return errors.New("Any Event mouse mode is not supported")
-		/*
-			if enabled {
-				terminal.logger.Infof("Turning on Any Event mouse mode")
-				terminal.SetMouseMode(MouseModeAnyEvent)
-			} else {
-				terminal.logger.Infof("Turning off Any Event mouse mode")
-				terminal.SetMouseMode(MouseModeNone)
-			}
-		*/
+		if enabled {
+			terminal.logger.Infof("Turning on Any Event mouse mode")
+			terminal.SetMouseMode(MouseModeAnyEvent)
+		} else {
+			terminal.logger.Infof("Turning off Any Event mouse mode")
+			terminal.SetMouseMode(MouseModeNone)
+		}
 	case "?1005":
-		return errors.New("UTF-8 ext mouse mode is not supported")
-		/*
-			if enabled {
-				terminal.logger.Infof("Turning on UTF-8 ext mouse mode")
-				terminal.SetMouseExtMode(MouseExtUTF)
-			} else {
-				terminal.logger.Infof("Turning off UTF-8 ext mouse mode")
-				terminal.SetMouseExtMode(MouseExtNone)
-			}
-		*/
+		if enabled {
+			terminal.logger.Infof("Turning on UTF-8 ext mouse mode")
+			terminal.SetMouseExtMode(MouseExtUTF)
+		} else {
+			terminal.logger.Infof("Turning off UTF-8 ext mouse mode")
+			terminal.SetMouseExtMode(MouseExtNone)
+		}
 	case "?1006":
 		if enabled {
 			terminal.logger.Infof("Turning on SGR ext mouse mode")
@@ -171,6 +183,14 @@ func csiSetMode(modeStr string, enabled bool, terminal *Terminal) error {
 			terminal.logger.Infof("Turning off SGR ext mouse mode")
 			terminal.SetMouseExtMode(MouseExtNone)
 		}
+	case "?1015":
+		if enabled {
+			terminal.logger.Infof("Turning on URXVT ext mouse mode")
+			terminal.SetMouseExtMode(MouseExtURXVT)
+		} else {
+			terminal.logger.Infof("Turning off URXVT ext mouse mode")
+			terminal.SetMouseExtMode(MouseExtNone)
+		}
 	case "?1048":
 		if enabled {
 			terminal.ActiveBuffer().SaveCursor()
@@ -179,10 +199,12 @@ func csiSetMode(modeStr string, enabled bool, terminal *Terminal) error {
 		}
 	case "?1049":
 		if enabled {
-			terminal.UseAltBuffer()
+			terminal.EnterAltScreen()
 		} else {
-			terminal.UseMainBuffer()
+			terminal.ExitAltScreen()
 		}
+	case "?1004":
+		terminal.modes.ReportFocus = enabled
 	case "?2004":
 		terminal.SetBracketedPasteMode(enabled)
 	default:
@@ -191,3 +213,55 @@ func csiSetMode(modeStr string, enabled bool, terminal *Terminal) error {
 
 	return nil
 }
+
+// the states DECRPM reports a mode to be in
+const (
+	modeNotRecognised    = 0
+	modeSet              = 1
+	modeReset            = 2
+	modePermanentlySet   = 3
+	modePermanentlyReset = 4
+)
+
+// modeStates says whether each mode csiSetMode knows is set, so that DECRQM can report it. A mode missing from
+// here is reported as not recognised.
+var modeStates = map[string]func(terminal *Terminal) bool{
+	"4":   func(terminal *Terminal) bool { return terminal.terminalState.InsertMode },
+	"8":   func(terminal *Terminal) bool { return !terminal.modes.Bidi },
+	"20":  func(terminal *Terminal) bool { return terminal.terminalState.IsNewLineMode() },
+	"?1":  func(terminal *Terminal) bool { return terminal.modes.ApplicationCursorKeys },
+	"?3":  func(terminal *Terminal) bool { cols, _ := terminal.GetSize(); return cols == 132 },
+	"?5":  func(terminal *Terminal) bool { return terminal.terminalState.ScreenMode },
+	"?6":  func(terminal *Terminal) bool { return terminal.terminalState.OriginMode },
+	"?7":  func(terminal *Terminal) bool { return terminal.terminalState.AutoWrap },
+	"?9":  func(terminal *Terminal) bool { return terminal.mouseMode == MouseModeX10 },
+	"?12": func(terminal *Terminal) bool { return terminal.modes.BlinkingCursor },
+	"?13": func(terminal *Terminal) bool { return terminal.modes.BlinkingCursor },
+	"?25": func(terminal *Terminal) bool { return terminal.modes.ShowCursor },
+	"?45": func(terminal *Terminal) bool { return terminal.terminalState.ReverseWrap },
+	"?47": func(terminal *Terminal) bool { return !terminal.UsingMainBuffer() },
+	"?69": func(terminal *Terminal) bool { return terminal.terminalState.LeftRightMarginMode },
+
+	"?1000": func(terminal *Terminal) bool { return terminal.mouseMode == MouseModeVT200 },
+	"?1002": func(terminal *Terminal) bool { return terminal.mouseMode == MouseModeButtonEvent },
+	"?1003": func(terminal *Terminal) bool { return terminal.mouseMode == MouseModeAnyEvent },
+	"?1004": func(terminal *Terminal) bool { return terminal.modes.ReportFocus },
+	"?1005": func(terminal *Terminal) bool { return terminal.mouseExtMode == MouseExtUTF },
+	"?1006": func(terminal *Terminal) bool { return terminal.mouseExtMode == MouseExtSGR },
+	"?1015": func(terminal *Terminal) bool { return terminal.mouseExtMode == MouseExtURXVT },
+	"?1047": func(terminal *Terminal) bool { return !terminal.UsingMainBuffer() },
+	"?1049": func(terminal *Terminal) bool { return !terminal.UsingMainBuffer() },
+	"?2004": func(terminal *Terminal) bool { return terminal.bracketedPasteMode },
+}
+
+// modeState returns the DECRPM state of a mode, which is given as it would be to SM or DECSET
+func modeState(mode string, terminal *Terminal) int {
+	state, ok := modeStates[mode]
+	if !ok {
+		return modeNotRecognised
+	}
+	if state(terminal) {
+		return modeSet
+	}
+	return modeReset
+}
@@ -0,0 +1,91 @@
+package terminal
+
+import (
+	"testing"
+
+	"github.com/liamg/aminal/buffer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestFocusReporting(t *testing.T) {
+	pty := &recordingPty{}
+	term := New(pty, zap.NewNop().Sugar(), DefaultOptions())
+	_ = term.SetSize(20, 5)
+
+	require.Nil(t, term.ReportFocus(true))
+	assert.Empty(t, pty.written.String(), "focus isn't reported until the application asks for it")
+
+	term.processBytes([]byte("\x1b[?1004h"))
+	require.Nil(t, term.ReportFocus(false))
+	require.Nil(t, term.ReportFocus(true))
+	assert.Equal(t, "\x1b[O\x1b[I", pty.written.String())
+
+	pty.written.Reset()
+	term.processBytes([]byte("\x1b[?1004l"))
+	require.Nil(t, term.ReportFocus(false))
+	assert.Empty(t, pty.written.String())
+}
+
+func TestRequestingModes(t *testing.T) {
+	pty := &recordingPty{}
+	term := New(pty, zap.NewNop().Sugar(), DefaultOptions())
+	_ = term.SetSize(80, 5)
+
+	for mode := range modeStates {
+		pty.written.Reset()
+		term.processBytes([]byte("\x1b[" + mode + "h\x1b[" + mode + "$p"))
+		assert.Equal(t, "\x1b["+mode+";1$y", pty.written.String(), "mode %s is set", mode)
+
+		pty.written.Reset()
+		term.processBytes([]byte("\x1b[" + mode + "l\x1b[" + mode + "$p"))
+		assert.Equal(t, "\x1b["+mode+";2$y", pty.written.String(), "mode %s is reset", mode)
+	}
+}
+
+func TestRequestingUnknownModes(t *testing.T) {
+	pty := &recordingPty{}
+	term := New(pty, zap.NewNop().Sugar(), DefaultOptions())
+	_ = term.SetSize(80, 5)
+
+	term.processBytes([]byte("\x1b[?31337$p\x1b[99$p"))
+	assert.Equal(t, "\x1b[?31337;0$y\x1b[99;0$y", pty.written.String())
+}
+
+func TestBidiModes(t *testing.T) {
+	options := DefaultOptions()
+	options.Bidi = true
+	term := New(&recordingPty{}, zap.NewNop().Sugar(), options)
+	_ = term.SetSize(20, 5)
+	assert.True(t, term.Modes().Bidi)
+
+	term.processBytes([]byte("\x1b[8h"))
+	assert.False(t, term.Modes().Bidi, "BDSM says the application lays out the text itself")
+	term.processBytes([]byte("\x1b[8l"))
+	assert.True(t, term.Modes().Bidi)
+
+	term.processBytes([]byte("\x1b[2 k"))
+	assert.Equal(t, buffer.BidiRTL, term.Modes().BidiDirection)
+	term.processBytes([]byte("\x1b[1;0 k"))
+	assert.Equal(t, buffer.BidiLTR, term.Modes().BidiDirection)
+
+	term.processBytes([]byte("\x1b[8h\x1b[2 k\x1bc"))
+	assert.True(t, term.Modes().Bidi, "a reset goes back to the configured default")
+	assert.Equal(t, buffer.BidiAuto, term.Modes().BidiDirection)
+}
+
+func TestInsertMode(t *testing.T) {
+	pty := &recordingPty{}
+	term := New(pty, zap.NewNop().Sugar(), DefaultOptions())
+	_ = term.SetSize(8, 3)
+
+	term.processBytes([]byte("abcdef\r\x1b[4hxy\x1b[4$p"))
+	assert.Equal(t, "xyabcdef", screenText(term)[0], "the rest of the line moves right")
+	term.processBytes([]byte("\x1b[4lz"))
+	assert.Equal(t, "xyzbcdef", screenText(term)[0], "replace mode writes over it again")
+	assert.Equal(t, "\x1b[4;1$y", pty.written.String())
+
+	term.processBytes([]byte("\r\n12345678\r\x1b[4h日"))
+	assert.Equal(t, "日123456", screenText(term)[1], "characters pushed off the end of the line are lost")
+}
@@ -0,0 +1,111 @@
+package terminal
+
+import "fmt"
+
+// Button codes reported to the application, see https://invisible-island.net/xterm/ctlseqs/ctlseqs.html#h2-Mouse-Tracking
+const (
+	MouseButtonLeft   byte = 0
+	MouseButtonMiddle byte = 1
+	MouseButtonRight  byte = 2
+	MouseButtonNone   byte = 3 // motion without a button down, and releases in encodings which don't say which button
+	MouseWheelUp      byte = 64
+	MouseWheelDown    byte = 65
+	MouseWheelLeft    byte = 66
+	MouseWheelRight   byte = 67
+)
+
+// Modifier flags added to the button code
+const (
+	MouseModShift byte = 4
+	MouseModMeta  byte = 8
+	MouseModCtrl  byte = 16
+
+	mouseMotion byte = 32
+)
+
+// MouseEvent is a mouse action which may be reported to the application
+type MouseEvent struct {
+	Button    byte // one of the MouseButton or MouseWheel codes
+	Modifiers byte // MouseMod flags, ignored in X10 mode
+	Motion    bool // the mouse moved, with Button held down
+	Release   bool
+	X         int // cell the mouse is over, where the top left is 1,1
+	Y         int
+}
+
+// MouseReport encodes the event as the application has asked for it with DECSET 9/1000/1002/1003, and
+// 1005/1006/1015 for the extended encodings. It returns nil if the application doesn't want the event,
+// or it can't be encoded.
+func (terminal *Terminal) MouseReport(e MouseEvent) []byte {
+	wheel := e.Button >= MouseWheelUp
+	if wheel && (e.Release || e.Motion) {
+		return nil
+	}
+
+	switch terminal.GetMouseMode() {
+	case MouseModeX10:
+		// X10 compatibility mode only reports button presses, without modifiers
+		if e.Release || e.Motion || wheel {
+			return nil
+		}
+		e.Modifiers = 0
+	case MouseModeVT200, MouseModeVT200Highlight:
+		// normal tracking reports presses and releases
+		if e.Motion {
+			return nil
+		}
+	case MouseModeButtonEvent:
+		// button-event tracking also reports motion while a button is held
+		if e.Motion && e.Button == MouseButtonNone {
+			return nil
+		}
+	case MouseModeAnyEvent:
+		// any-event tracking reports all motion
+	default:
+		return nil
+	}
+
+	return encodeMouseEvent(e, terminal.GetMouseExtMode())
+}
+
+func encodeMouseEvent(e MouseEvent, ext MouseExtMode) []byte {
+	code := e.Button | e.Modifiers
+	if e.Motion {
+		code |= mouseMotion
+	}
+
+	// SGR is the only encoding which says which button was released
+	if ext == MouseExtSGR {
+		final := 'M'
+		if e.Release {
+			final = 'm'
+		}
+		return []byte(fmt.Sprintf("\x1b[<%d;%d;%d%c", code, e.X, e.Y, final))
+	}
+
+	if e.Release {
+		code = MouseButtonNone | e.Modifiers
+	}
+
+	switch ext {
+	case MouseExtURXVT:
+		return []byte(fmt.Sprintf("\x1b[%d;%d;%dM", code+32, e.X, e.Y))
+	case MouseExtUTF:
+		// as the normal encoding, but values over 95 are utf-8 encoded, so coordinates up to 2015 can be sent
+		const max = 0x7ff - 32
+		if e.X > max || e.Y > max {
+			return nil
+		}
+		packet := []byte("\x1b[M")
+		for _, v := range []int{int(code), e.X, e.Y} {
+			packet = append(packet, []byte(string(rune(v+32)))...)
+		}
+		return packet
+	default:
+		// each value is sent as a single byte of value + 32, so coordinates past 223 can't be reported
+		if e.X > 0xff-32 || e.Y > 0xff-32 {
+			return nil
+		}
+		return []byte{0x1b, '[', 'M', code + 32, byte(e.X + 32), byte(e.Y + 32)}
+	}
+}
@@ -0,0 +1,53 @@
+package terminal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMouseReport(t *testing.T) {
+	press := MouseEvent{Button: MouseButtonLeft, Modifiers: MouseModCtrl, X: 3, Y: 5}
+	release := MouseEvent{Button: MouseButtonRight, Release: true, X: 3, Y: 5}
+	drag := MouseEvent{Button: MouseButtonLeft, Motion: true, X: 4, Y: 5}
+	move := MouseEvent{Button: MouseButtonNone, Motion: true, X: 4, Y: 5}
+	wheel := MouseEvent{Button: MouseWheelDown, X: 1, Y: 1}
+	far := MouseEvent{Button: MouseButtonLeft, X: 300, Y: 2}
+
+	tests := []struct {
+		name  string
+		mode  MouseMode
+		ext   MouseExtMode
+		event MouseEvent
+		out   string
+	}{
+		{"off", MouseModeNone, MouseExtNone, press, ""},
+		{"x10 press", MouseModeX10, MouseExtNone, press, "\x1b[M #%"},
+		{"x10 release", MouseModeX10, MouseExtNone, release, ""},
+		{"x10 wheel", MouseModeX10, MouseExtNone, wheel, ""},
+		{"normal press", MouseModeVT200, MouseExtNone, press, "\x1b[M0#%"},
+		{"normal release", MouseModeVT200, MouseExtNone, release, "\x1b[M##%"},
+		{"normal drag", MouseModeVT200, MouseExtNone, drag, ""},
+		{"normal wheel", MouseModeVT200, MouseExtNone, wheel, "\x1b[Ma!!"},
+		{"sgr wheel right", MouseModeVT200, MouseExtSGR, MouseEvent{Button: MouseWheelRight, X: 2, Y: 3}, "\x1b[<67;2;3M"},
+		{"normal too far", MouseModeVT200, MouseExtNone, far, ""},
+		{"button event drag", MouseModeButtonEvent, MouseExtNone, drag, "\x1b[M@$%"},
+		{"button event move", MouseModeButtonEvent, MouseExtNone, move, ""},
+		{"any event move", MouseModeAnyEvent, MouseExtNone, move, "\x1b[MC$%"},
+		{"sgr press", MouseModeVT200, MouseExtSGR, press, "\x1b[<16;3;5M"},
+		{"sgr release", MouseModeVT200, MouseExtSGR, release, "\x1b[<2;3;5m"},
+		{"sgr far", MouseModeVT200, MouseExtSGR, far, "\x1b[<0;300;2M"},
+		{"urxvt release", MouseModeVT200, MouseExtURXVT, release, "\x1b[35;3;5M"},
+		{"urxvt far", MouseModeVT200, MouseExtURXVT, far, "\x1b[32;300;2M"},
+		{"utf-8 far", MouseModeVT200, MouseExtUTF, far, "\x1b[M Ō\""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			term := newHeadlessTerminal(80, 24)
+			term.SetMouseMode(test.mode)
+			term.SetMouseExtMode(test.ext)
+			assert.Equal(t, test.out, string(term.MouseReport(test.event)))
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package terminal
+
+// Palette holds the 16 standard ANSI colours - the 8 normal colours followed by their bright variants
+type Palette [16][3]float32
+
+// Options configures a Terminal. It only carries what the emulator itself needs, so that the
+// terminal and buffer packages can be used without the GUI (the config package pulls in glfw).
+type Options struct {
+	Foreground [3]float32
+	Background [3]float32
+	Cursor     [3]float32
+	Palette    Palette
+	// BoldIsBright shows bold text in one of the 8 normal palette colours in its bright variant instead
+	BoldIsBright bool
+	MaxLines     uint64
+	Slomo        bool // delay the handling of each incoming rune by 100ms, useful for debugging
+	// Version is the version of aminal reported to applications which ask for it, with XTVERSION and DA2
+	Version string
+	// Bidi lays out right to left text with the bidirectional algorithm until the application turns it off
+	Bidi bool
+}
+
+// DefaultOptions returns options suitable for using the terminal headlessly, with the xterm palette
+func DefaultOptions() Options {
+	return Options{
+		Foreground: [3]float32{0.9, 0.9, 0.9},
+		Background: [3]float32{0, 0, 0},
+		Cursor:     [3]float32{0.9, 0.9, 0.9},
+		Palette: Palette{
+			{0, 0, 0},
+			{0.804, 0, 0},
+			{0, 0.804, 0},
+			{0.804, 0.804, 0},
+			{0, 0, 0.933},
+			{0.804, 0, 0.804},
+			{0, 0.804, 0.804},
+			{0.898, 0.898, 0.898},
+			{0.498, 0.498, 0.498},
+			{1, 0, 0},
+			{0, 1, 0},
+			{1, 1, 0},
+			{0.361, 0.361, 1},
+			{1, 0, 1},
+			{0, 1, 1},
+			{1, 1, 1},
+		},
+		MaxLines: 1000,
+	}
+}
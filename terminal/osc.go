@@ -2,25 +2,64 @@ package terminal
 
 import (
 	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
+
+	"github.com/liamg/aminal/buffer"
 )
 
+// maxOSCLength caps the amount of an OSC sequence we hold on to, the rest is discarded
+const maxOSCLength = 1 << 20
+
+// FontSizeChange is a change to the size of the text asked for by the application with xterm's OSC 50
+type FontSizeChange struct {
+	Steps int  // how many sizes bigger to make the text, or smaller if negative
+	Reset bool // return the text to the size it started at
+}
+
+// CommandFinished is a command which shell integration (OSC 133) has reported finishing
+type CommandFinished struct {
+	Command    string // as typed at the prompt, if the shell marked where it starts
+	ExitStatus int    // buffer.UnknownExitStatus if the shell didn't say
+}
+
+// Sequence is an OSC sequence the terminal doesn't handle itself, for the handlers attached to receive them
+type Sequence struct {
+	Code   string   // the number the sequence starts with, e.g. "1338"
+	Params []string // what follows it, split at the semicolons
+}
+
 func oscHandler(pty chan rune, terminal *Terminal) error {
 	params := []string{}
-	param := ""
+	var param strings.Builder
+	length := 0
+	// replies are terminated the same way as the sequence which asked for them
+	terminator := "\x07"
 
 	for {
-		b := <-pty
+		b := readRune(pty)
 		if terminal.IsOSCTerminator(b) {
-			params = append(params, param)
+			last := param.String()
+			if b == '\\' && strings.HasSuffix(last, "\x1b") {
+				last = strings.TrimSuffix(last, "\x1b")
+				terminator = "\x1b\\"
+			}
+			params = append(params, last)
 			break
 		}
+		if length >= maxOSCLength {
+			continue
+		}
+		length++
 		if b == ';' {
-			params = append(params, param)
-			param = ""
+			params = append(params, param.String())
+			param.Reset()
 			continue
 		}
-		param = fmt.Sprintf("%s%c", param, b)
+		param.WriteRune(b)
 	}
 
 	if len(params) == 0 {
@@ -38,20 +77,128 @@ func oscHandler(pty chan rune, terminal *Terminal) error {
 	switch pS[0] {
 	case "0", "2":
 		terminal.SetTitle(pT)
-	case "10": // get/set foreground colour
-		if len(pS) > 1 {
-			if pS[1] == "?" {
-				terminal.Write([]byte("\x1b]10;15"))
-			}
+	case "4": // get/set palette colours
+		return terminal.paletteColourSequence(params[1:], terminator)
+	case "7": // the shell's working directory
+		return terminal.setWorkingDirectory(pT)
+	case "10", "11", "12": // get/set foreground, background and cursor colours
+		code, _ := strconv.Atoi(pS[0])
+		return terminal.dynamicColourSequence(code, params[1:], terminator)
+	case "104": // reset palette colours
+		return terminal.resetPaletteSequence(params[1:])
+	case "110", "111", "112": // reset foreground, background and cursor colours
+		code, _ := strconv.Atoi(pS[0])
+		if terminal.resetDynamicColour(code - 100) {
+			terminal.recolour()
 		}
-	case "11": // get/set background colour
+	case "133": // semantic prompt marks from shell integration
+		mark := pT
 		if len(pS) > 1 {
-			if pS[1] == "?" {
-				terminal.Write([]byte("\x1b]10;0"))
-			}
+			mark = pS[1]
+		}
+		switch mark {
+		case "A": // prompt start
+			terminal.ActiveBuffer().MarkPrompt()
+		case "B": // command start, after the prompt
+			terminal.ActiveBuffer().MarkCommandStart()
+		case "C": // output start, once the command is run
+			terminal.ActiveBuffer().MarkOutputStart()
+		case "D": // command finished, followed by its exit status
+			terminal.commandEnd(params[2:])
+		}
+	case "1337": // iTerm2 style extensions
+		if name := strings.TrimPrefix(pT, "SetTheme="); name != pT {
+			terminal.emitThemeChange(name)
 		}
+	case "50": // font changes, of which only the relative sizes are supported
+		return terminal.fontSequence(pT)
+	case "52": // clipboard access
+		if len(pS) < 2 {
+			return fmt.Errorf("Missing OSC 52 selection")
+		}
+		return terminal.handleClipboardSequence(pS[1], pT, terminator)
 	default:
+		if len(terminal.sequenceHandlers) > 0 {
+			terminal.emitSequence(Sequence{Code: pS[0], Params: params[1:]})
+			return nil
+		}
 		return fmt.Errorf("Unknown OSC control sequence: %s", strings.Join(params, ";"))
 	}
 	return nil
 }
+
+// setWorkingDirectory records the directory the shell reports being in, as a file:// URL, and the host it's on.
+// Directories on other hosts, e.g. reported by a shell over ssh, are forgotten, as local shells can't be started
+// in them.
+func (terminal *Terminal) setWorkingDirectory(location string) error {
+	u, err := url.Parse(location)
+	if err != nil || u.Scheme != "file" {
+		return fmt.Errorf("Invalid OSC 7 location: %s", location)
+	}
+	host := ""
+	if u.Host != "" && u.Host != "localhost" {
+		if hostname, _ := os.Hostname(); !strings.EqualFold(u.Host, hostname) {
+			host = u.Hostname()
+		}
+	}
+	if host != terminal.host {
+		terminal.host = host
+		terminal.emitHostChange()
+	}
+	if host != "" {
+		terminal.workingDirectory = ""
+		return nil
+	}
+	dir := u.Path
+	if len(dir) > 2 && dir[0] == '/' && dir[2] == ':' {
+		// a Windows drive, as in file:///C:/Users
+		dir = dir[1:]
+	}
+	terminal.workingDirectory = filepath.FromSlash(dir)
+	return nil
+}
+
+// commandEnd marks the end of the running command, reporting it finished with the exit status in params if there is one
+func (terminal *Terminal) commandEnd(params []string) {
+	exitStatus := buffer.UnknownExitStatus
+	if len(params) > 0 {
+		if status, err := strconv.Atoi(params[0]); err == nil && status >= 0 {
+			exitStatus = status
+		}
+	}
+	b := terminal.ActiveBuffer()
+	if !b.MarkCommandExit(exitStatus) {
+		return
+	}
+	command, _ := b.LastCommand()
+	terminal.emitCommandFinished(CommandFinished{Command: command, ExitStatus: exitStatus})
+}
+
+// fontSequence handles OSC 50 with the font menu selections of xterm: "#+n" and "#-n" to make the text n sizes
+// bigger or smaller, defaulting to 1, and "#" or "#0" for the default size. Fonts can't be queried or chosen by name.
+func (terminal *Terminal) fontSequence(font string) error {
+	if !strings.HasPrefix(font, "#") {
+		return fmt.Errorf("Unsupported OSC 50 font: %s", font)
+	}
+	selection := strings.TrimPrefix(font, "#")
+	if selection == "" || selection == "0" {
+		terminal.emitFontSizeChange(FontSizeChange{Reset: true})
+		return nil
+	}
+	if selection[0] != '+' && selection[0] != '-' {
+		return fmt.Errorf("Unsupported OSC 50 font menu entry: %s", selection)
+	}
+	steps := 1
+	if len(selection) > 1 {
+		n, err := strconv.Atoi(selection[1:])
+		if err != nil || n < 0 {
+			return fmt.Errorf("Invalid OSC 50 font size change: %s", selection)
+		}
+		steps = n
+	}
+	if selection[0] == '-' {
+		steps = -steps
+	}
+	terminal.emitFontSizeChange(FontSizeChange{Steps: steps})
+	return nil
+}
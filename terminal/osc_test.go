@@ -0,0 +1,143 @@
+package terminal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/liamg/aminal/buffer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetThemeSequence(t *testing.T) {
+	term := newHeadlessTerminal(20, 5)
+	themes := make(chan string, 1)
+	term.AttachThemeHandler(themes)
+
+	term.processBytes([]byte("\x1b]1337;SetTheme=solarized-dark\x07"))
+
+	select {
+	case name := <-themes:
+		assert.Equal(t, "solarized-dark", name)
+	case <-time.After(time.Second):
+		t.Fatal("theme change wasn't reported")
+	}
+}
+
+func TestFontSizeSequences(t *testing.T) {
+	term := newHeadlessTerminal(20, 5)
+	changes := make(chan FontSizeChange, 1)
+	term.AttachFontSizeHandler(changes)
+
+	for sequence, expected := range map[string]FontSizeChange{
+		"#+":  {Steps: 1},
+		"#+3": {Steps: 3},
+		"#-2": {Steps: -2},
+		"#0":  {Reset: true},
+		"#":   {Reset: true},
+	} {
+		term.processBytes([]byte("\x1b]50;" + sequence + "\x07"))
+		select {
+		case change := <-changes:
+			assert.Equal(t, expected, change, sequence)
+		case <-time.After(time.Second):
+			t.Fatalf("font size change %q wasn't reported", sequence)
+		}
+	}
+
+	term.processBytes([]byte("\x1b]50;-misc-fixed-medium-r-normal--20-*\x07"))
+	select {
+	case change := <-changes:
+		t.Fatalf("choosing a font by name was reported as %v", change)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestSemanticPromptSequences(t *testing.T) {
+	term := newHeadlessTerminal(40, 10)
+	term.processBytes([]byte("\x1b]133;A\x07$ \x1b]133;B\x07echo hi\r\n\x1b]133;C\x07hi\r\n\x1b]133;D;0\x07\x1b]133;A\x07$ "))
+
+	command, ok := term.ActiveBuffer().LastCommand()
+	assert.True(t, ok)
+	assert.Equal(t, "echo hi", command)
+
+	output, ok := term.ActiveBuffer().LastCommandOutput()
+	assert.True(t, ok)
+	assert.Equal(t, "hi", output)
+}
+
+func TestCommandFinishedSequences(t *testing.T) {
+	term := newHeadlessTerminal(40, 10)
+	finished := make(chan CommandFinished, 1)
+	term.AttachCommandFinishedHandler(finished)
+
+	expect := func(expected CommandFinished) {
+		select {
+		case command := <-finished:
+			assert.Equal(t, expected, command)
+		case <-time.After(time.Second):
+			t.Fatalf("%q finishing wasn't reported", expected.Command)
+		}
+	}
+
+	term.processBytes([]byte("\x1b]133;A\x07$ \x1b]133;B\x07false\r\n\x1b]133;C\x07\x1b]133;D;1\x07"))
+	expect(CommandFinished{Command: "false", ExitStatus: 1})
+	term.processBytes([]byte("\x1b]133;A\x07$ \x1b]133;B\x07true\r\n\x1b]133;C\x07\x1b]133;D\x07"))
+	expect(CommandFinished{Command: "true", ExitStatus: buffer.UnknownExitStatus})
+
+	term.processBytes([]byte("\x1b]133;A\x07$ \x1b]133;B\x07\r\n\x1b]133;D;0\x07"))
+	select {
+	case command := <-finished:
+		t.Fatalf("an empty command line was reported finishing as %v", command)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestWorkingDirectorySequence(t *testing.T) {
+	term := newHeadlessTerminal(20, 5)
+	assert.Equal(t, "", term.WorkingDirectory())
+
+	term.processBytes([]byte("\x1b]7;file:///home/liam/my%20code\x07"))
+	assert.Equal(t, filepath.FromSlash("/home/liam/my code"), term.WorkingDirectory())
+
+	hostname, _ := os.Hostname()
+	term.processBytes([]byte("\x1b]7;file://" + hostname + "/tmp\x1b\\"))
+	assert.Equal(t, filepath.FromSlash("/tmp"), term.WorkingDirectory())
+
+	term.processBytes([]byte("\x1b]7;file://elsewhere.example.com/srv\x07"))
+	assert.Equal(t, "", term.WorkingDirectory(), "directories on other hosts are forgotten")
+
+	term.processBytes([]byte("\x1b]7;file:///tmp\x07\x1b]7;http://example.com/\x07"))
+	assert.Equal(t, filepath.FromSlash("/tmp"), term.WorkingDirectory(), "other URLs are ignored")
+}
+
+func TestHostSequence(t *testing.T) {
+	term := newHeadlessTerminal(20, 5)
+	changes := make(chan bool, 4)
+	term.AttachHostChangeHandler(changes)
+
+	term.processBytes([]byte("\x1b]7;file://db1.example.com/srv\x07"))
+	assert.Equal(t, "db1.example.com", term.Host())
+	assert.True(t, <-changes)
+
+	term.processBytes([]byte("\x1b]7;file://db1.example.com/var\x07"))
+	assert.Equal(t, "db1.example.com", term.Host())
+
+	hostname, _ := os.Hostname()
+	term.processBytes([]byte("\x1b]7;file://" + hostname + "/tmp\x07"))
+	assert.Equal(t, "", term.Host(), "this host isn't another")
+	assert.True(t, <-changes)
+	assert.Empty(t, changes, "reporting the same host again isn't a change")
+}
+
+func TestUnknownSequencesArePassedOn(t *testing.T) {
+	term := newHeadlessTerminal(20, 5)
+	sequences := make(chan Sequence, 2)
+	term.AttachSequenceHandler(sequences)
+
+	term.processBytes([]byte("\x1b]1338;notify;done\x07\x1b]9999\x1b\\"))
+
+	assert.Equal(t, Sequence{Code: "1338", Params: []string{"notify", "done"}}, <-sequences)
+	assert.Equal(t, Sequence{Code: "9999", Params: []string{}}, <-sequences)
+}
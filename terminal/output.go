@@ -27,31 +27,33 @@ var runeMap = map[rune]runeHandler{
 }
 
 func newLineHandler(terminal *Terminal) error {
+	if (len(terminal.lineHandlers) > 0 || len(terminal.triggers) > 0) && terminal.UsingMainBuffer() {
+		line := terminal.ActiveBuffer().CursorLineText()
+		terminal.runTriggers(line)
+		terminal.emitLine(line)
+	}
 	terminal.ActiveBuffer().NewLine()
-	terminal.isDirty = true
 	return nil
 }
 
 func tabHandler(terminal *Terminal) error {
 	terminal.ActiveBuffer().Tab()
-	terminal.isDirty = true
 	return nil
 }
 
 func carriageReturnHandler(terminal *Terminal) error {
 	terminal.ActiveBuffer().CarriageReturn()
-	terminal.isDirty = true
 	return nil
 }
 
 func backspaceHandler(terminal *Terminal) error {
 	terminal.ActiveBuffer().Backspace()
-	terminal.isDirty = true
 	return nil
 }
 
 func bellHandler(terminal *Terminal) error {
-	// @todo ring bell - flash red or some shit?
+	terminal.bellRung = true
+	terminal.emitBell()
 	return nil
 }
 
@@ -77,12 +79,10 @@ func (terminal *Terminal) processRune(b rune) {
 		if err := handler(terminal); err != nil {
 			terminal.logger.Errorf("Error handling control code: %s", err)
 		}
-		terminal.isDirty = true
 		return
 	}
 	// terminal.logger.Debugf("Received character 0x%X: %q", b, string(b))
 	terminal.ActiveBuffer().Write(terminal.translateRune(b))
-	terminal.isDirty = true
 }
 
 func (terminal *Terminal) translateRune(b rune) rune {
@@ -97,28 +97,82 @@ func (terminal *Terminal) translateRune(b rune) rune {
 	return b
 }
 
+// endOfInput is raised by readRune when the input is closed part way through a sequence
+type endOfInput struct{}
+
+// flushInput is sent on the input after some output to find out when the output has been processed: the send
+// completes once everything before it has been, when the input is unbuffered. It's dropped by readRune.
+const flushInput rune = -1
+
+// wakeInput is sent on the input to have the parser goroutine look for work other than output, such as lines to
+// restore from disk, when there's no output to wake it. It's dropped by readRune, part way through a sequence,
+// and the work is done once the sequence has been handled.
+const wakeInput rune = -2
+
+// readRune reads the next rune of input. Handlers part way through a sequence must use this rather than
+// reading pty directly, so that closing the input can't leave them spinning on zero runes.
+func readRune(pty chan rune) rune {
+	for {
+		b, ok := <-pty
+		if !ok {
+			panic(endOfInput{})
+		}
+		if b != flushInput && b != wakeInput {
+			return b
+		}
+	}
+}
+
 func (terminal *Terminal) processInput(pty chan rune) {
 	// https://en.wikipedia.org/wiki/ANSI_escape_code
 
-	var b rune
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(endOfInput); !ok {
+				panic(r)
+			}
+		}
+	}()
 
 	for {
 
-		if terminal.config.Slomo {
+		if terminal.options.Slomo {
 			time.Sleep(time.Millisecond * 100)
 		}
 
-		b = <-pty
+		terminal.restoreSpilled()
+
+		b, ok := <-pty
+		if !ok {
+			return
+		}
+		if b == flushInput || b == wakeInput {
+			continue
+		}
 
 		if b == 0x1b {
 			// terminal.logger.Debugf("Handling escape sequence: 0x%x", b)
 			if err := ansiHandler(pty, terminal); err != nil {
 				terminal.logger.Errorf("Error handling escape sequence: %s", err)
 			}
-			terminal.isDirty = true
-			continue
+		} else {
+			terminal.processRune(b)
 		}
 
-		terminal.processRune(b)
+		if len(pty) == 0 {
+			terminal.emitOutput()
+		}
 	}
 }
+
+// processBytes handles data as if it had been read from the pty, returning once it has all been processed
+func (terminal *Terminal) processBytes(data []byte) {
+	input := make(chan rune, 0xffff)
+	go func() {
+		for _, r := range string(data) {
+			input <- r
+		}
+		close(input)
+	}()
+	terminal.processInput(input)
+}
@@ -0,0 +1,62 @@
+package terminal
+
+import (
+	"testing"
+
+	"github.com/liamg/aminal/platform"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// nullPty is a pty with nothing on the other end, for driving the terminal headlessly
+type nullPty struct{}
+
+func (p *nullPty) Read(b []byte) (int, error)  { select {} }
+func (p *nullPty) Write(b []byte) (int, error) { return len(b), nil }
+func (p *nullPty) Close() error                { return nil }
+func (p *nullPty) Resize(x int, y int) error   { return nil }
+func (p *nullPty) CreateGuestProcess(imagePath string, dir string) (platform.Process, error) {
+	return nil, nil
+}
+func (p *nullPty) GetPlatformDependentSettings() platform.PlatformDependentSettings {
+	return platform.PlatformDependentSettings{
+		OSCTerminators: map[rune]struct{}{0x07: {}, 0x5c: {}},
+	}
+}
+
+func newHeadlessTerminal(cols uint, rows uint) *Terminal {
+	t := New(&nullPty{}, zap.NewNop().Sugar(), DefaultOptions())
+	t.SetCharSize(8, 16)
+	_ = t.SetSize(cols, rows)
+	return t
+}
+
+func TestBell(t *testing.T) {
+	term := newHeadlessTerminal(20, 5)
+	bells := make(chan bool, 1)
+	term.AttachBellHandler(bells)
+
+	assert.False(t, term.CheckBell())
+	term.processBytes([]byte("ding\a\a"))
+	assert.True(t, term.CheckBell())
+	assert.False(t, term.CheckBell(), "checking the bell forgets it")
+	assert.Len(t, bells, 1, "bells rung together are reported once")
+	assert.Equal(t, "ding", screenText(term)[0])
+}
+
+func TestLinesAreReportedAsTheyAreFinished(t *testing.T) {
+	term := newHeadlessTerminal(10, 5)
+	lines := make(chan string, 10)
+	term.AttachLineHandler(lines)
+
+	term.processBytes([]byte("one\r\nfifteen characters\r\nhalf"))
+	term.processBytes([]byte("\x1b[?1049hfull screen\r\n\x1b[?1049l"))
+	term.processBytes([]byte("\rthree\r\n"))
+	close(lines)
+
+	reported := []string{}
+	for line := range lines {
+		reported = append(reported, line)
+	}
+	assert.Equal(t, []string{"one", "fifteen characters", "three"}, reported)
+}
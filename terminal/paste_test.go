@@ -0,0 +1,43 @@
+package terminal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// capturePty records everything written to it
+type capturePty struct {
+	nullPty
+	written []byte
+}
+
+func (p *capturePty) Write(b []byte) (int, error) {
+	p.written = append(p.written, b...)
+	return len(b), nil
+}
+
+func TestPaste(t *testing.T) {
+	tests := []struct {
+		name      string
+		bracketed bool
+		in        string
+		out       string
+	}{
+		{"plain", false, "ls\nrm -rf x\x1b[201~\n", "ls\nrm -rf x\x1b[201~\n"},
+		{"bracketed", true, "ls\r\npwd\n", "\x1b[200~ls\r\npwd\n\x1b[201~"},
+		{"bracketed end marker", true, "a\x1b[201~rm -rf x\n", "\x1b[200~a[201~rm -rf x\n\x1b[201~"},
+		{"bracketed controls", true, "a\tb\x03c\x7fd\u009be\xffé", "\x1b[200~a\tbcdeé\x1b[201~"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pty := &capturePty{}
+			term := New(pty, zap.NewNop().Sugar(), DefaultOptions())
+			term.SetBracketedPasteMode(test.bracketed)
+			assert.Nil(t, term.Paste([]byte(test.in)))
+			assert.Equal(t, test.out, string(pty.written))
+		})
+	}
+}
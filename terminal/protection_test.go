@@ -0,0 +1,51 @@
+package terminal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelectiveEraseLeavesProtectedCharacters(t *testing.T) {
+	term := newHeadlessTerminal(10, 3)
+	term.processBytes([]byte("ab\x1b[1\"qcd\x1b[0\"qef\r\n12\x1b[1\"q34\x1b[m56"))
+
+	term.processBytes([]byte("\x1b[?2J"))
+	assert.Equal(t, []string{"  cd", "  3456"}, screenText(term)[:2], "SGR 0 doesn't turn protection off")
+
+	term.processBytes([]byte("\x1b[2J"))
+	assert.Equal(t, []string{"", ""}, screenText(term)[:2], "ED erases protected characters")
+}
+
+func TestSelectiveEraseInLine(t *testing.T) {
+	term := newHeadlessTerminal(10, 1)
+	term.processBytes([]byte("ab\x1b[1\"qcd\x1b[2\"qefgh"))
+
+	term.processBytes([]byte("\x1b[1;6H\x1b[?K"))
+	assert.Equal(t, "abcde", screenText(term)[0])
+
+	term.processBytes([]byte("\x1b[?1K"))
+	assert.Equal(t, "  cd", screenText(term)[0])
+
+	term.processBytes([]byte("\x1b[K"))
+	assert.Equal(t, "  cd", screenText(term)[0])
+	term.processBytes([]byte("\x1b[1;1H\x1b[K"))
+	assert.Equal(t, "", screenText(term)[0], "EL erases protected characters")
+}
+
+func TestSelectiveEraseInDisplayAroundTheCursor(t *testing.T) {
+	term := newHeadlessTerminal(4, 3)
+	fillScreen(term, 3, 4)
+	term.processBytes([]byte("\x1b[2;2H\x1b[1\"qB\x1b[2;2H\x1b[?J"))
+	assert.Equal(t, []string{"aaaa", "bB", ""}, screenText(term))
+
+	term.processBytes([]byte("\x1b[?1J"))
+	assert.Equal(t, []string{"", " B", ""}, screenText(term))
+}
+
+func TestIntermediateCharactersArentWritten(t *testing.T) {
+	term := newHeadlessTerminal(10, 1)
+	term.processBytes([]byte("a\x1b[2 qb"))
+
+	assert.Equal(t, "ab", screenText(term)[0])
+}
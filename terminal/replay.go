@@ -0,0 +1,79 @@
+package terminal
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+// replayedModes are the modes Replay carries over, in the order they're set. Mouse modes are only ever set by
+// it, so setting one can't be undone by resetting another. Reverse video isn't among them, as the colours of the
+// lines replayed are already swapped.
+var replayedModes = []string{
+	"4", "8", "20", "?1", "?7", "?12", "?25", "?45", "?69",
+	"?9", "?1000", "?1002", "?1003", "?1004", "?1005", "?1006", "?1015", "?2004",
+}
+
+// Replay returns the sequences which bring a terminal of the same size, which has only just been created with
+// the same options, to the state this one is in: the scrollback and both screens, the cursor and its pen, the
+// scrolling region, modes, title and working directory. Images, character sets, saved cursors, left and right
+// margins and colours changed by the application aren't carried over. It's how a terminal kept by the daemon is
+// shown again in a window which attaches to it.
+func (terminal *Terminal) Replay() []byte {
+	terminal.lock.Lock()
+	defer terminal.lock.Unlock()
+
+	var out bytes.Buffer
+	out.Write(terminal.buffers[MainBuffer].Replay())
+	if !terminal.UsingMainBuffer() {
+		out.WriteString("\x1b[?1047h\x1b[H")
+		out.Write(terminal.buffers[AltBuffer].Replay())
+	}
+
+	b := terminal.ActiveBuffer()
+	if rows := uint(terminal.size.Height); b.TopMargin() > 0 || (rows > 0 && b.BottomMargin() < rows-1) {
+		fmt.Fprintf(&out, "\x1b[%d;%dr", b.TopMargin()+1, b.BottomMargin()+1)
+	}
+
+	fresh := New(terminal.pty, terminal.logger, terminal.options)
+	for _, mode := range replayedModes {
+		state := modeStates[mode]
+		if on := state(terminal); on != state(fresh) {
+			fmt.Fprintf(&out, "\x1b[%s%s", mode, recoverCodeFromEnabled(on))
+		}
+	}
+
+	// setting origin mode homes the cursor, which is then placed within the margins
+	if terminal.terminalState.OriginMode {
+		out.WriteString("\x1b[?6h")
+	}
+	fmt.Fprintf(&out, "\x1b[%d;%dH", b.CursorLine()+1, b.CursorColumnRelative()+1)
+	if terminal.terminalState.CursorAttr.Protected {
+		out.WriteString("\x1b[1\"q")
+	}
+	out.WriteString(terminal.terminalState.CursorAttr.SGR())
+
+	if terminal.title != "" {
+		fmt.Fprintf(&out, "\x1b]2;%s\x07", strings.Map(stripControl, terminal.title))
+	}
+	if terminal.workingDirectory != "" {
+		path := filepath.ToSlash(terminal.workingDirectory)
+		if !strings.HasPrefix(path, "/") {
+			// a Windows drive, which OSC 7 gives as file:///C:/Users
+			path = "/" + path
+		}
+		location := url.URL{Scheme: "file", Path: path}
+		fmt.Fprintf(&out, "\x1b]7;%s\x07", location.String())
+	}
+	return out.Bytes()
+}
+
+// stripControl drops control characters from text sent in an OSC, where they would end it early
+func stripControl(r rune) rune {
+	if r < 0x20 || r == 0x7f {
+		return -1
+	}
+	return r
+}
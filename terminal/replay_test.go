@@ -0,0 +1,72 @@
+package terminal
+
+import (
+	"testing"
+
+	"github.com/liamg/aminal/buffer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func replayed(term *Terminal) *Terminal {
+	cols, rows := term.GetSize()
+	restored := newHeadlessTerminal(uint(cols), uint(rows))
+	restored.processBytes(term.Replay())
+	return restored
+}
+
+func TestReplayDrawsTheScrollbackAndScreen(t *testing.T) {
+	term := newHeadlessTerminal(10, 3)
+	term.processBytes([]byte("one\r\ntwo\r\n\x1b[1;31mthree\x1b[0m\r\nfour is long enough to wrap\r\n\x1b[44mfive\x1b[K"))
+
+	restored := replayed(term)
+	assert.Equal(t, term.ActiveBuffer().Height(), restored.ActiveBuffer().Height())
+	assert.Equal(t, screenText(term), screenText(restored))
+	for line := 0; line < term.ActiveBuffer().Height(); line++ {
+		for col := uint16(0); col < 10; col++ {
+			want, got := term.ActiveBuffer().GetRawCell(col, uint64(line)), restored.ActiveBuffer().GetRawCell(col, uint64(line))
+			if want == nil {
+				continue
+			}
+			require.NotNil(t, got, "line %d column %d", line, col)
+			assert.Equal(t, want.Rune() == 0 || want.Rune() == ' ', got.Rune() == 0 || got.Rune() == ' ', "line %d column %d", line, col)
+			if want.Rune() != 0 && want.Rune() != ' ' {
+				assert.Equal(t, want.Rune(), got.Rune(), "line %d column %d", line, col)
+				assert.Equal(t, want.Attr(), got.Attr(), "line %d column %d", line, col)
+			}
+		}
+	}
+
+	wrapped := func(term *Terminal) []bool {
+		var flags []bool
+		for _, line := range term.GetVisibleLines() {
+			flags = append(flags, line.Wrapped())
+		}
+		return flags
+	}
+	assert.Equal(t, wrapped(term), wrapped(restored))
+	assert.Equal(t, term.GetLogicalCursorX(), restored.GetLogicalCursorX())
+	assert.Equal(t, term.GetLogicalCursorY(), restored.GetLogicalCursorY())
+	assert.Equal(t, buffer.PaletteRef(4), restored.ActiveBuffer().CursorAttr().BgRef, "the pen is carried over")
+}
+
+func TestReplayRestoresTheAlternateScreenAndModes(t *testing.T) {
+	term := newHeadlessTerminal(20, 5)
+	term.processBytes([]byte("shell prompt\r\n\x1b[?1049h\x1b[2;4r\x1b[?1h\x1b[?1002h\x1b[?1006h\x1b[?2004h\x1b[?25l"))
+	term.processBytes([]byte("\x1b[Heditor\x1b[3;6H\x1b]2;vim notes.txt\x07\x1b]7;file:///home/me/notes\x07"))
+
+	restored := replayed(term)
+	assert.False(t, restored.UsingMainBuffer())
+	assert.Equal(t, screenText(term), screenText(restored))
+	assert.Equal(t, "shell prompt", restored.buffers[MainBuffer].GetVisibleLines()[0].String())
+	assert.Equal(t, term.Modes(), restored.Modes())
+	assert.Equal(t, MouseModeButtonEvent, restored.GetMouseMode())
+	assert.Equal(t, MouseExtSGR, restored.GetMouseExtMode())
+	assert.True(t, restored.IsBracketedPasteModeEnabled())
+	assert.Equal(t, uint(1), restored.ActiveBuffer().TopMargin())
+	assert.Equal(t, uint(3), restored.ActiveBuffer().BottomMargin())
+	assert.Equal(t, uint16(5), restored.GetLogicalCursorX())
+	assert.Equal(t, uint16(2), restored.GetLogicalCursorY())
+	assert.Equal(t, "vim notes.txt", restored.GetTitle())
+	assert.Equal(t, term.WorkingDirectory(), restored.WorkingDirectory())
+}
@@ -0,0 +1,55 @@
+package terminal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func newReportingTerminal(version string) (*Terminal, *recordingPty) {
+	pty := &recordingPty{}
+	options := DefaultOptions()
+	options.Version = version
+	term := New(pty, zap.NewNop().Sugar(), options)
+	_ = term.SetSize(20, 5)
+	return term, pty
+}
+
+func TestDeviceAttributes(t *testing.T) {
+	term, pty := newReportingTerminal("v0.9.12")
+
+	term.processBytes([]byte("\x1b[c\x1b[0c"))
+	assert.Equal(t, "\x1b[?62;1;4;6;22c\x1b[?62;1;4;6;22c", pty.written.String())
+
+	pty.written.Reset()
+	term.processBytes([]byte("\x1b[>c"))
+	assert.Equal(t, "\x1b[>1;912;0c", pty.written.String())
+
+	pty.written.Reset()
+	term.processBytes([]byte("\x1b[=c"))
+	assert.Equal(t, "\x1bP!|00000000\x1b\\", pty.written.String())
+}
+
+func TestReportingTheVersion(t *testing.T) {
+	term, pty := newReportingTerminal("v0.9.12-3-gabcdef")
+	term.processBytes([]byte("\x1b[>q"))
+	assert.Equal(t, "\x1bP>|aminal(0.9.12-3-gabcdef)\x1b\\", pty.written.String())
+
+	term, pty = newReportingTerminal("")
+	term.processBytes([]byte("\x1b[>0q\x1b[>c"))
+	assert.Equal(t, "\x1bP>|aminal(development)\x1b\\\x1b[>1;0;0c", pty.written.String())
+}
+
+func TestVersionNumber(t *testing.T) {
+	assert.Equal(t, 10203, versionNumber("v1.2.3"))
+	assert.Equal(t, 912, versionNumber("0.9.12-3-gabcdef"))
+	assert.Equal(t, 0, versionNumber("development"))
+	assert.Equal(t, 0, versionNumber("1.2"))
+}
+
+func TestExtendedCursorPositionReport(t *testing.T) {
+	term, pty := newReportingTerminal("")
+	term.processBytes([]byte("\x1b[3;7H\x1b[6n\x1b[?6n"))
+	assert.Equal(t, "\x1b[3;7R\x1b[?3;7;1R", pty.written.String())
+}
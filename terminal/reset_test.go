@@ -0,0 +1,64 @@
+package terminal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHardReset(t *testing.T) {
+	term := newHeadlessTerminal(20, 5)
+	attr := term.terminalState.CursorAttr
+	term.processBytes([]byte("scrolled\r\n\r\n\r\n\r\n\r\nshown"))
+	term.processBytes([]byte("\x1b[1;31m\x1b[4h\x1b[?7l\x1b[?25l\x1b[?1h\x1b[?1000h\x1b[?1006h\x1b[?2004h\x1b[2;4r\x1b[3g\x1b(0\x1b[?5h"))
+	term.processBytes([]byte("\x1b]4;1;rgb:00/ff/00\x1b\\\x1b[?1049h"))
+
+	term.processBytes([]byte("\x1bc"))
+
+	assert.True(t, term.UsingMainBuffer())
+	assert.Equal(t, attr, term.terminalState.CursorAttr)
+	assert.False(t, term.terminalState.InsertMode)
+	assert.True(t, term.IsAutoWrap())
+	assert.False(t, term.ScreenMode())
+	assert.Equal(t, Modes{ShowCursor: true}, term.Modes())
+	assert.Equal(t, MouseModeNone, term.GetMouseMode())
+	assert.Equal(t, MouseExtNone, term.GetMouseExtMode())
+	assert.False(t, term.IsBracketedPasteModeEnabled())
+	assert.Equal(t, term.configured.Palette[1], term.paletteColour(1))
+	assert.Nil(t, term.terminalState.Charsets[0])
+
+	term.processBytes([]byte("\x1b[5;1Hx\tx"))
+	line := term.ActiveBuffer().GetVisibleLines()[4].String()
+	assert.Equal(t, "x   x", line, "the tab stops and margins are reset")
+
+	assert.Equal(t, "", term.ActiveBuffer().GetVisibleLines()[0].String())
+	assert.Equal(t, 11, term.ActiveBuffer().Height(), "the scrollback is kept, with the old screen pushed into it")
+}
+
+func TestEraseScrollback(t *testing.T) {
+	term := newHeadlessTerminal(20, 3)
+	term.processBytes([]byte("one\r\ntwo\r\nthree\r\nfour\r\nfive"))
+	require.Equal(t, 5, term.ActiveBuffer().Height())
+
+	term.processBytes([]byte("\x1b[3J"))
+
+	assert.Equal(t, 3, term.ActiveBuffer().Height())
+	assert.Equal(t, "three", term.ActiveBuffer().GetVisibleLines()[0].String(), "the screen is left alone")
+}
+
+func TestClearAllLeavesTheAltScreen(t *testing.T) {
+	term := newHeadlessTerminal(20, 3)
+	term.processBytes([]byte("one\r\ntwo\r\nthree\r\nfour\r\n~ $ "))
+	term.processBytes([]byte("\x1b[?1049hvim"))
+
+	term.ClearAll()
+
+	assert.Equal(t, "vim", term.ActiveBuffer().GetVisibleLines()[0].String())
+	term.processBytes([]byte("\x1b[?1049l"))
+	assert.Equal(t, 3, term.ActiveBuffer().Height())
+
+	term.ClearAll()
+	assert.Equal(t, "~ $", term.ActiveBuffer().GetVisibleLines()[0].String())
+	assert.Equal(t, uint16(0), term.GetLogicalCursorY())
+}
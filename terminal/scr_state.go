@@ -3,7 +3,7 @@ package terminal
 import "fmt"
 
 func screenStateHandler(pty chan rune, terminal *Terminal) error {
-	b := <-pty
+	b := readRune(pty)
 	switch b {
 	case '8': // DECALN -- Screen Alignment Pattern
 		// hide cursor?
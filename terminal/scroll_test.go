@@ -0,0 +1,77 @@
+package terminal
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestScrollUpAndDownInTheScrollingRegion(t *testing.T) {
+	term := newHeadlessTerminal(4, 4)
+	fillScreen(term, 4, 4)
+	term.processBytes([]byte("\x1b[2;3r\x1b[4;2H\x1b[S"))
+
+	assert.Equal(t, []string{"aaaa", "cccc", "", "dddd"}, screenText(term))
+	assert.Equal(t, uint16(1), term.GetLogicalCursorX(), "the cursor doesn't move")
+	assert.Equal(t, uint16(3), term.GetLogicalCursorY())
+
+	term.processBytes([]byte("\x1b[2T"))
+	assert.Equal(t, []string{"aaaa", "", "", "dddd"}, screenText(term))
+	assert.Equal(t, 4, term.ActiveBuffer().Height(), "nothing is scrolled into the scrollback")
+}
+
+func TestScrollDownMovesLinesNotYetWritten(t *testing.T) {
+	term := newHeadlessTerminal(4, 4)
+	term.processBytes([]byte("ab\x1b[T"))
+
+	assert.Equal(t, []string{"", "ab", "", ""}, screenText(term))
+}
+
+func TestScrollingFurtherThanTheScreenClearsIt(t *testing.T) {
+	term := newHeadlessTerminal(4, 3)
+	fillScreen(term, 3, 4)
+	term.processBytes([]byte("\x1b[70000S"))
+
+	assert.Equal(t, []string{"", "", ""}, screenText(term))
+}
+
+func TestBackAndForwardIndex(t *testing.T) {
+	term := newHeadlessTerminal(6, 3)
+	fillScreen(term, 3, 6)
+	term.processBytes([]byte("\x1b[?69h\x1b[2;4s\x1b[1;2r\x1b[1;3H\x1b6"))
+	assert.Equal(t, uint16(1), term.GetLogicalCursorX(), "the cursor moves left until it reaches the margin")
+
+	term.processBytes([]byte("\x1b6"))
+	assert.Equal(t, []string{"a aaaa", "b bbbb", "cccccc"}, screenText(term))
+	assert.Equal(t, uint16(1), term.GetLogicalCursorX())
+
+	term.processBytes([]byte("\x1b[1;3H\x1b9\x1b9"))
+	assert.Equal(t, []string{"aaa aa", "bbb bb", "cccccc"}, screenText(term))
+	assert.Equal(t, uint16(3), term.GetLogicalCursorX())
+
+	term.processBytes([]byte("\x1b[3;4H\x1b9"))
+	assert.Equal(t, "cccccc", screenText(term)[2], "nothing scrolls with the cursor outside the scrolling region")
+}
+
+func TestScrollingPastTheTopOfMemoryRestoresSpilledLines(t *testing.T) {
+	options := DefaultOptions()
+	options.MaxLines = 10
+	term := New(&nullPty{}, zap.NewNop().Sugar(), options)
+	_ = term.SetSize(4, 3)
+	require.Nil(t, term.SpillScrollback(t.TempDir()))
+	defer term.CloseSpill()
+	for i := 0; i < 30; i++ {
+		term.processBytes([]byte(fmt.Sprintf("%d\r\n", i)))
+	}
+
+	term.ScreenScrollUp(20)
+	assert.Equal(t, uint(7), term.GetScrollOffset(), "only the lines in memory can be scrolled to until the rest are back")
+
+	term.processBytes(nil) // the parser restores them
+	assert.Equal(t, uint(20), term.GetScrollOffset())
+	b := term.ActiveBuffer()
+	assert.Equal(t, '8', b.GetRawCell(0, uint64(b.Height()-3-20)).Rune(), "the top of the view")
+}
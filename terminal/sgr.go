@@ -6,43 +6,65 @@ import (
 	"strings"
 
 	"github.com/liamg/aminal/buffer"
-	"github.com/liamg/aminal/config"
 )
 
 func sgrSequenceHandler(params []string, terminal *Terminal) error {
 	if len(params) == 0 {
 		params = []string{"0"}
 	}
+	if strings.HasPrefix(params[0], ">") {
+		// XTMODKEYS, which shares SGR's final character
+		return csiModifyKeysHandler(params, terminal)
+	}
+
+	// the colours are looked up once the attributes are set, as bold can change the foreground
+	defer terminal.resolveColours(terminal.ActiveBuffer().CursorAttr())
 
-	for i := range params {
+	for i := 0; i < len(params); i++ {
 
 		p := strings.Replace(strings.Replace(params[i], "[", "", -1), "]", "", -1)
 
+		if strings.Contains(p, ":") {
+			if err := terminal.sgrSubParams(strings.Split(p, ":")); err != nil {
+				return err
+			}
+			continue
+		}
+
 		switch p {
 		case "00", "0", "":
+			// protection isn't a graphic rendition, so it's left as DECSCA set it
 			attr := terminal.ActiveBuffer().CursorAttr()
 			*attr = buffer.CellAttributes{
-				FgColour: terminal.config.ColourScheme.Foreground,
-				BgColour: terminal.config.ColourScheme.Background,
+				FgRef:     buffer.ColourDefaultFg,
+				BgRef:     buffer.ColourDefaultBg,
+				Protected: attr.Protected,
 			}
 		case "1", "01":
 			terminal.ActiveBuffer().CursorAttr().Bold = true
 		case "2", "02":
 			terminal.ActiveBuffer().CursorAttr().Dim = true
+		case "3", "03":
+			terminal.ActiveBuffer().CursorAttr().Italic = true
 		case "4", "04":
 			terminal.ActiveBuffer().CursorAttr().Underline = true
+			terminal.ActiveBuffer().CursorAttr().UnderlineStyle = buffer.UnderlineSingle
 		case "5", "05":
 			terminal.ActiveBuffer().CursorAttr().Blink = true
 		case "7", "07":
 			terminal.ActiveBuffer().CursorAttr().Inverse = true
 		case "8", "08":
 			terminal.ActiveBuffer().CursorAttr().Hidden = true
-		case "21":
+		case "9", "09":
+			terminal.ActiveBuffer().CursorAttr().Strikethrough = true
+		case "21": // doubly underlined
+			terminal.ActiveBuffer().CursorAttr().Underline = true
+			terminal.ActiveBuffer().CursorAttr().UnderlineStyle = buffer.UnderlineDouble
+		case "22": // normal intensity, neither bold nor dim
 			terminal.ActiveBuffer().CursorAttr().Bold = false
-		case "22":
 			terminal.ActiveBuffer().CursorAttr().Dim = false
 		case "23":
-			// not italic
+			terminal.ActiveBuffer().CursorAttr().Italic = false
 		case "24":
 			terminal.ActiveBuffer().CursorAttr().Underline = false
 		case "25":
@@ -52,89 +74,104 @@ func sgrSequenceHandler(params []string, terminal *Terminal) error {
 		case "28":
 			terminal.ActiveBuffer().CursorAttr().Hidden = false
 		case "29":
-			// not strikethrough
+			terminal.ActiveBuffer().CursorAttr().Strikethrough = false
+		case "53":
+			terminal.ActiveBuffer().CursorAttr().Overline = true
+		case "55":
+			terminal.ActiveBuffer().CursorAttr().Overline = false
 		case "39":
-			terminal.ActiveBuffer().CursorAttr().FgColour = terminal.config.ColourScheme.Foreground
+			terminal.ActiveBuffer().CursorAttr().FgRef = buffer.ColourDefaultFg
 		case "30":
-			terminal.ActiveBuffer().CursorAttr().FgColour = terminal.config.ColourScheme.Black
+			terminal.ActiveBuffer().CursorAttr().FgRef = buffer.PaletteRef(0)
 		case "31":
-			terminal.ActiveBuffer().CursorAttr().FgColour = terminal.config.ColourScheme.Red
+			terminal.ActiveBuffer().CursorAttr().FgRef = buffer.PaletteRef(1)
 		case "32":
-			terminal.ActiveBuffer().CursorAttr().FgColour = terminal.config.ColourScheme.Green
+			terminal.ActiveBuffer().CursorAttr().FgRef = buffer.PaletteRef(2)
 		case "33":
-			terminal.ActiveBuffer().CursorAttr().FgColour = terminal.config.ColourScheme.Yellow
+			terminal.ActiveBuffer().CursorAttr().FgRef = buffer.PaletteRef(3)
 		case "34":
-			terminal.ActiveBuffer().CursorAttr().FgColour = terminal.config.ColourScheme.Blue
+			terminal.ActiveBuffer().CursorAttr().FgRef = buffer.PaletteRef(4)
 		case "35":
-			terminal.ActiveBuffer().CursorAttr().FgColour = terminal.config.ColourScheme.Magenta
+			terminal.ActiveBuffer().CursorAttr().FgRef = buffer.PaletteRef(5)
 		case "36":
-			terminal.ActiveBuffer().CursorAttr().FgColour = terminal.config.ColourScheme.Cyan
+			terminal.ActiveBuffer().CursorAttr().FgRef = buffer.PaletteRef(6)
 		case "37":
-			terminal.ActiveBuffer().CursorAttr().FgColour = terminal.config.ColourScheme.White
+			terminal.ActiveBuffer().CursorAttr().FgRef = buffer.PaletteRef(7)
 		case "90":
-			terminal.ActiveBuffer().CursorAttr().FgColour = terminal.config.ColourScheme.DarkGrey
+			terminal.ActiveBuffer().CursorAttr().FgRef = buffer.PaletteRef(8)
 		case "91":
-			terminal.ActiveBuffer().CursorAttr().FgColour = terminal.config.ColourScheme.LightRed
+			terminal.ActiveBuffer().CursorAttr().FgRef = buffer.PaletteRef(9)
 		case "92":
-			terminal.ActiveBuffer().CursorAttr().FgColour = terminal.config.ColourScheme.LightGreen
+			terminal.ActiveBuffer().CursorAttr().FgRef = buffer.PaletteRef(10)
 		case "93":
-			terminal.ActiveBuffer().CursorAttr().FgColour = terminal.config.ColourScheme.LightYellow
+			terminal.ActiveBuffer().CursorAttr().FgRef = buffer.PaletteRef(11)
 		case "94":
-			terminal.ActiveBuffer().CursorAttr().FgColour = terminal.config.ColourScheme.LightBlue
+			terminal.ActiveBuffer().CursorAttr().FgRef = buffer.PaletteRef(12)
 		case "95":
-			terminal.ActiveBuffer().CursorAttr().FgColour = terminal.config.ColourScheme.LightMagenta
+			terminal.ActiveBuffer().CursorAttr().FgRef = buffer.PaletteRef(13)
 		case "96":
-			terminal.ActiveBuffer().CursorAttr().FgColour = terminal.config.ColourScheme.LightCyan
+			terminal.ActiveBuffer().CursorAttr().FgRef = buffer.PaletteRef(14)
 		case "97":
-			terminal.ActiveBuffer().CursorAttr().FgColour = terminal.config.ColourScheme.White
+			terminal.ActiveBuffer().CursorAttr().FgRef = buffer.PaletteRef(15)
 		case "49":
-			terminal.ActiveBuffer().CursorAttr().BgColour = terminal.config.ColourScheme.Background
+			terminal.ActiveBuffer().CursorAttr().BgRef = buffer.ColourDefaultBg
 		case "40":
-			terminal.ActiveBuffer().CursorAttr().BgColour = terminal.config.ColourScheme.Black
+			terminal.ActiveBuffer().CursorAttr().BgRef = buffer.PaletteRef(0)
 		case "41":
-			terminal.ActiveBuffer().CursorAttr().BgColour = terminal.config.ColourScheme.Red
+			terminal.ActiveBuffer().CursorAttr().BgRef = buffer.PaletteRef(1)
 		case "42":
-			terminal.ActiveBuffer().CursorAttr().BgColour = terminal.config.ColourScheme.Green
+			terminal.ActiveBuffer().CursorAttr().BgRef = buffer.PaletteRef(2)
 		case "43":
-			terminal.ActiveBuffer().CursorAttr().BgColour = terminal.config.ColourScheme.Yellow
+			terminal.ActiveBuffer().CursorAttr().BgRef = buffer.PaletteRef(3)
 		case "44":
-			terminal.ActiveBuffer().CursorAttr().BgColour = terminal.config.ColourScheme.Blue
+			terminal.ActiveBuffer().CursorAttr().BgRef = buffer.PaletteRef(4)
 		case "45":
-			terminal.ActiveBuffer().CursorAttr().BgColour = terminal.config.ColourScheme.Magenta
+			terminal.ActiveBuffer().CursorAttr().BgRef = buffer.PaletteRef(5)
 		case "46":
-			terminal.ActiveBuffer().CursorAttr().BgColour = terminal.config.ColourScheme.Cyan
+			terminal.ActiveBuffer().CursorAttr().BgRef = buffer.PaletteRef(6)
 		case "47":
-			terminal.ActiveBuffer().CursorAttr().BgColour = terminal.config.ColourScheme.White
+			terminal.ActiveBuffer().CursorAttr().BgRef = buffer.PaletteRef(7)
 		case "100":
-			terminal.ActiveBuffer().CursorAttr().BgColour = terminal.config.ColourScheme.DarkGrey
+			terminal.ActiveBuffer().CursorAttr().BgRef = buffer.PaletteRef(8)
 		case "101":
-			terminal.ActiveBuffer().CursorAttr().BgColour = terminal.config.ColourScheme.LightRed
+			terminal.ActiveBuffer().CursorAttr().BgRef = buffer.PaletteRef(9)
 		case "102":
-			terminal.ActiveBuffer().CursorAttr().BgColour = terminal.config.ColourScheme.LightGreen
+			terminal.ActiveBuffer().CursorAttr().BgRef = buffer.PaletteRef(10)
 		case "103":
-			terminal.ActiveBuffer().CursorAttr().BgColour = terminal.config.ColourScheme.LightYellow
+			terminal.ActiveBuffer().CursorAttr().BgRef = buffer.PaletteRef(11)
 		case "104":
-			terminal.ActiveBuffer().CursorAttr().BgColour = terminal.config.ColourScheme.LightBlue
+			terminal.ActiveBuffer().CursorAttr().BgRef = buffer.PaletteRef(12)
 		case "105":
-			terminal.ActiveBuffer().CursorAttr().BgColour = terminal.config.ColourScheme.LightMagenta
+			terminal.ActiveBuffer().CursorAttr().BgRef = buffer.PaletteRef(13)
 		case "106":
-			terminal.ActiveBuffer().CursorAttr().BgColour = terminal.config.ColourScheme.LightCyan
+			terminal.ActiveBuffer().CursorAttr().BgRef = buffer.PaletteRef(14)
 		case "107":
-			terminal.ActiveBuffer().CursorAttr().BgColour = terminal.config.ColourScheme.White
+			terminal.ActiveBuffer().CursorAttr().BgRef = buffer.PaletteRef(15)
 		case "38": // set foreground
-			c, err := terminal.getANSIColour(params[i:])
+			ref, c, used, err := terminal.getANSIColour(params[i:], false)
 			if err != nil {
 				return err
 			}
+			terminal.ActiveBuffer().CursorAttr().FgRef = ref
 			terminal.ActiveBuffer().CursorAttr().FgColour = c
-			return nil
+			i += used - 1
 		case "48": // set background
-			c, err := terminal.getANSIColour(params[i:])
+			ref, c, used, err := terminal.getANSIColour(params[i:], false)
 			if err != nil {
 				return err
 			}
+			terminal.ActiveBuffer().CursorAttr().BgRef = ref
 			terminal.ActiveBuffer().CursorAttr().BgColour = c
-			return nil
+			i += used - 1
+		case "58": // set underline colour
+			ref, c, used, err := terminal.getANSIColour(params[i:], false)
+			if err != nil {
+				return err
+			}
+			terminal.setUnderlineColour(ref, c)
+			i += used - 1
+		case "59":
+			terminal.ActiveBuffer().CursorAttr().UnderlineColoured = false
 		default:
 			return fmt.Errorf("Unknown SGR control sequence: (ESC[%sm)", params[i:])
 		}
@@ -145,7 +182,54 @@ func sgrSequenceHandler(params []string, terminal *Terminal) error {
 	return nil
 }
 
-func (terminal *Terminal) getANSIColour(params []string) (config.Colour, error) {
+// sgrSubParams handles an SGR parameter with colon separated parts, like 38:2::r:g:b
+func (terminal *Terminal) sgrSubParams(params []string) error {
+	attr := terminal.ActiveBuffer().CursorAttr()
+	switch params[0] {
+	case "4": // underline style
+		style, err := strconv.Atoi(params[1])
+		if err != nil || style < 0 || style > 5 {
+			return fmt.Errorf("Unknown underline style: (ESC[%sm)", strings.Join(params, ":"))
+		}
+		attr.Underline = style != 0
+		if style > 0 {
+			attr.UnderlineStyle = buffer.UnderlineStyle(style - 1)
+		}
+	case "38":
+		ref, c, _, err := terminal.getANSIColour(params, true)
+		if err != nil {
+			return err
+		}
+		attr.FgRef, attr.FgColour = ref, c
+	case "48":
+		ref, c, _, err := terminal.getANSIColour(params, true)
+		if err != nil {
+			return err
+		}
+		attr.BgRef, attr.BgColour = ref, c
+	case "58":
+		ref, c, _, err := terminal.getANSIColour(params, true)
+		if err != nil {
+			return err
+		}
+		terminal.setUnderlineColour(ref, c)
+	default:
+		return fmt.Errorf("Unknown SGR control sequence: (ESC[%sm)", strings.Join(params, ":"))
+	}
+	return nil
+}
+
+func (terminal *Terminal) setUnderlineColour(ref buffer.ColourRef, colour [3]float32) {
+	attr := terminal.ActiveBuffer().CursorAttr()
+	attr.UnderlineColoured = true
+	attr.UnderlineRef = ref
+	attr.UnderlineColour = colour
+}
+
+// getANSIColour reads the colour following SGR 38, 48 or 58 in params, which is either a reference to the palette or a
+// true colour, and returns the number of params it takes up. subParams says the params were separated by colons,
+// as in 38:2:r:g:b, where a true colour may also have a colour space id before the components.
+func (terminal *Terminal) getANSIColour(params []string, subParams bool) (buffer.ColourRef, [3]float32, int, error) {
 	if len(params) > 2 {
 		switch params[1] {
 		case "5":
@@ -153,97 +237,41 @@ func (terminal *Terminal) getANSIColour(params []string) (config.Colour, error)
 			colNum, err := strconv.Atoi(params[2])
 
 			if err != nil || colNum >= 256 || colNum < 0 {
-				return [3]float32{0, 0, 0}, fmt.Errorf("Invalid 8-bit colour specifier")
+				return buffer.ColourRGB, [3]float32{0, 0, 0}, 0, fmt.Errorf("Invalid 8-bit colour specifier")
 			}
-			return terminal.get8BitSGRColour(uint8(colNum)), nil
+			return buffer.PaletteRef(uint8(colNum)), terminal.paletteColour(uint8(colNum)), 3, nil
 
 		case "2":
-			if len(params) < 4 {
-				return [3]float32{0, 0, 0}, fmt.Errorf("Invalid true colour specifier")
-			}
 			// 24 bit colour
-			if len(params) == 5 { // standard true colour
-
-				r, err := strconv.Atoi(params[2])
-				if err != nil {
-					return [3]float32{0, 0, 0}, fmt.Errorf("Invalid true colour specifier")
-				}
-				g, err := strconv.Atoi(params[3])
-				if err != nil {
-					return [3]float32{0, 0, 0}, fmt.Errorf("Invalid true colour specifier")
-				}
-				b, err := strconv.Atoi(params[4])
-				if err != nil {
-					return [3]float32{0, 0, 0}, fmt.Errorf("Invalid true colour specifier")
-				}
-				return [3]float32{
-					float32(r) / 0xff,
-					float32(g) / 0xff,
-					float32(b) / 0xff,
-				}, nil
-			} else if len(params) > 5 { // ISO/IEC International Standard 8613-6
-				r, err := strconv.Atoi(params[3])
-				if err != nil {
-					return [3]float32{0, 0, 0}, fmt.Errorf("Invalid true colour specifier")
-				}
-				g, err := strconv.Atoi(params[4])
-				if err != nil {
-					return [3]float32{0, 0, 0}, fmt.Errorf("Invalid true colour specifier")
-				}
-				b, err := strconv.Atoi(params[5])
-				if err != nil {
-					return [3]float32{0, 0, 0}, fmt.Errorf("Invalid true colour specifier")
+			components := params[2:]
+			used := 5
+			if subParams && len(components) > 3 {
+				// ISO/IEC International Standard 8613-6, with the colour space id first
+				components = components[1:]
+				used = 6
+			}
+			if len(components) < 3 {
+				return buffer.ColourRGB, [3]float32{0, 0, 0}, 0, fmt.Errorf("Invalid true colour specifier")
+			}
+			var colour [3]float32
+			for j := range colour {
+				v, err := strconv.Atoi(components[j])
+				if err != nil || v < 0 || v > 0xff {
+					return buffer.ColourRGB, [3]float32{0, 0, 0}, 0, fmt.Errorf("Invalid true colour specifier")
 				}
-				return [3]float32{
-					float32(r) / 0xff,
-					float32(g) / 0xff,
-					float32(b) / 0xff,
-				}, nil
+				colour[j] = float32(v) / 0xff
 			}
+			return buffer.ColourRGB, colour, used, nil
 		}
 	}
 
-	return [3]float32{}, fmt.Errorf("Unknown ANSI colour format identifier")
+	return buffer.ColourRGB, [3]float32{}, 0, fmt.Errorf("Unknown ANSI colour format identifier")
 }
 
-func (terminal *Terminal) get8BitSGRColour(colNum uint8) [3]float32 {
+// extendedColour returns one of the colours 16-255 from the standard 256 colour palette
+func extendedColour(colNum uint8) [3]float32 {
 	// https://en.wikipedia.org/wiki/ANSI_escape_code#8-bit
 
-	switch colNum {
-	case 0:
-		return terminal.config.ColourScheme.Black
-	case 1:
-		return terminal.config.ColourScheme.Red
-	case 2:
-		return terminal.config.ColourScheme.Green
-	case 3:
-		return terminal.config.ColourScheme.Yellow
-	case 4:
-		return terminal.config.ColourScheme.Blue
-	case 5:
-		return terminal.config.ColourScheme.Magenta
-	case 6:
-		return terminal.config.ColourScheme.Cyan
-	case 7:
-		return terminal.config.ColourScheme.White
-	case 8:
-		return terminal.config.ColourScheme.DarkGrey
-	case 9:
-		return terminal.config.ColourScheme.LightRed
-	case 10:
-		return terminal.config.ColourScheme.LightGreen
-	case 11:
-		return terminal.config.ColourScheme.LightYellow
-	case 12:
-		return terminal.config.ColourScheme.LightBlue
-	case 13:
-		return terminal.config.ColourScheme.LightMagenta
-	case 14:
-		return terminal.config.ColourScheme.LightCyan
-	case 15:
-		return terminal.config.ColourScheme.White
-	}
-
 	if colNum < 232 {
 
 		r := 0
@@ -0,0 +1,57 @@
+package terminal
+
+import (
+	"testing"
+
+	"github.com/liamg/aminal/buffer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnderlineStyles(t *testing.T) {
+	term := newHeadlessTerminal(20, 5)
+	term.processBytes([]byte("\x1b[4ma\x1b[4:3mb\x1b[21mc\x1b[4:5md\x1b[4:0me\x1b[4:4mf\x1b[24mg"))
+
+	cells := term.ActiveBuffer().GetVisibleLines()[0].Cells()
+	styles := []buffer.UnderlineStyle{
+		buffer.UnderlineSingle, buffer.UnderlineCurly, buffer.UnderlineDouble, buffer.UnderlineDashed,
+	}
+	for i, style := range styles {
+		assert.True(t, cells[i].Attr().Underline, "cell %d", i)
+		assert.Equal(t, style, cells[i].Attr().UnderlineStyle, "cell %d", i)
+	}
+	assert.False(t, cells[4].Attr().Underline, "4:0 turns the underline off")
+	assert.Equal(t, buffer.UnderlineDotted, cells[5].Attr().UnderlineStyle)
+	assert.False(t, cells[6].Attr().Underline)
+}
+
+func TestUnderlineColour(t *testing.T) {
+	term := newHeadlessTerminal(20, 5)
+	term.processBytes([]byte("\x1b[31;4ma\x1b[58;2;0;255;0mb\x1b[58:5:4mc\x1b[59md"))
+
+	cells := term.ActiveBuffer().GetVisibleLines()[0].Cells()
+	palette := DefaultOptions().Palette
+	assert.Equal(t, palette[1], cells[0].UnderlineColour(), "the underline takes the text colour until one is set")
+	assert.Equal(t, [3]float32{0, 1, 0}, cells[1].UnderlineColour())
+	assert.Equal(t, palette[1], cells[1].Fg())
+	assert.Equal(t, palette[4], cells[2].UnderlineColour())
+	assert.Equal(t, palette[1], cells[3].UnderlineColour())
+
+	term.processBytes([]byte("\x1b]4;4;#ffffff\x07"))
+	assert.Equal(t, [3]float32{1, 1, 1}, term.ActiveBuffer().GetVisibleLines()[0].Cells()[2].UnderlineColour(), "palette underline colours follow the palette")
+}
+
+func TestTextDecorationAttributes(t *testing.T) {
+	term := newHeadlessTerminal(20, 5)
+	term.processBytes([]byte("\x1b[9;53;5;8ma\x1b[29mb\x1b[55mc\x1b[25;28md"))
+
+	cells := term.ActiveBuffer().GetVisibleLines()[0].Cells()
+	assert.True(t, cells[0].Attr().Strikethrough)
+	assert.True(t, cells[0].Attr().Overline)
+	assert.True(t, cells[0].Attr().Blink)
+	assert.True(t, cells[0].Attr().Hidden)
+	assert.False(t, cells[1].Attr().Strikethrough)
+	assert.True(t, cells[1].Attr().Overline)
+	assert.False(t, cells[2].Attr().Overline)
+	assert.False(t, cells[3].Attr().Blink)
+	assert.False(t, cells[3].Attr().Hidden)
+}
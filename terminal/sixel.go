@@ -15,7 +15,7 @@ type boolFormRuneFunc func(rune) bool
 
 func swallowByFunction(pty chan rune, isTerminator boolFormRuneFunc) {
 	for {
-		b := <-pty
+		b := readRune(pty)
 		if isTerminator(b) {
 			break
 		}
@@ -46,9 +46,9 @@ func sixelHandler(pty chan rune, terminal *Terminal) error {
 	yStartWithOffset := y + scrollOffset
 	matrix := matrix.NewAutoMatrix() // a simplified version of Buffer
 	for {
-		b := <-pty
+		b := readRune(pty)
 		if b == 0x1b {
-			t := <-pty
+			t := readRune(pty)
 			if t == '[' { // Windows injected a CSI sequence
 				final, param, _ := loadCSI(pty)
 
@@ -136,6 +136,11 @@ func sixelHandler(pty chan rune, terminal *Terminal) error {
 }
 
 func drawSixel(six *sixel.Sixel, terminal *Terminal) {
+	if terminal.charWidth <= 0 || terminal.charHeight <= 0 {
+		// we don't know the cell size (e.g. running headless), so can't map the image onto cells
+		return
+	}
+
 	originalImage := six.RGBA()
 
 	w := originalImage.Bounds().Size().X
@@ -0,0 +1,46 @@
+package terminal
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/liamg/aminal/platform"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestTerminalOnAStream(t *testing.T) {
+	device, console := net.Pipe()
+	pty := platform.NewStreamPty(console)
+	process, err := pty.CreateGuestProcess("", "")
+	require.Nil(t, err)
+
+	term := New(pty, zap.NewNop().Sugar(), DefaultOptions())
+	term.SetCharSize(8, 16)
+	require.Nil(t, term.SetSize(20, 5))
+	read := make(chan error, 1)
+	go func() { read <- term.Read() }()
+
+	_, err = device.Write([]byte("U-Boot 2021.01\r\n=> "))
+	require.Nil(t, err)
+	require.Eventually(t, func() bool {
+		return term.ActiveBuffer().GetVisibleLines()[0].String() == "U-Boot 2021.01"
+	}, 5*time.Second, 10*time.Millisecond)
+
+	go term.Write([]byte("help\r"))
+	typed := make([]byte, 5)
+	n, err := device.Read(typed)
+	require.Nil(t, err)
+	assert.Equal(t, "help\r", string(typed[:n]))
+
+	// the device going away finishes the process, as a shell exiting would
+	device.Close()
+	assert.Nil(t, process.Wait())
+	select {
+	case <-read:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the terminal to stop reading")
+	}
+}
@@ -4,10 +4,14 @@ import (
 	"bufio"
 	"fmt"
 	"io"
+	"math"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/liamg/aminal/buffer"
-	"github.com/liamg/aminal/config"
 	"github.com/liamg/aminal/platform"
 	"go.uber.org/zap"
 )
@@ -33,6 +37,7 @@ const (
 	MouseExtNone MouseExtMode = iota
 	MouseExtUTF
 	MouseExtSGR
+	MouseExtURXVT
 )
 
 type Terminal struct {
@@ -43,20 +48,42 @@ type Terminal struct {
 	pty                       platform.Pty
 	logger                    *zap.SugaredLogger
 	title                     string
+	workingDirectory          string // as last reported by the shell with OSC 7
+	host                      string // the other host the shell last reported being on with OSC 7, e.g. over ssh
 	size                      Winsize
-	config                    *config.Config
+	options                   Options
+	configured                Options              // options as they were given, before the application changed any colours
+	extendedPalette           map[uint8][3]float32 // colours 16-255 changed by the application
 	titleHandlers             []chan bool
 	resizeHandlers            []chan bool
 	reverseHandlers           []chan bool
+	outputHandlers            []chan bool
+	themeHandlers             []chan string
+	fontSizeHandlers          []chan FontSizeChange
+	bellHandlers              []chan bool
+	commandHandlers           []chan CommandFinished
+	colourHandlers            []chan bool
+	hostHandlers              []chan bool
+	lineHandlers              []chan string
+	sequenceHandlers          []chan Sequence
+	triggers                  []Trigger
+	triggerHandlers           []chan TriggerMatch
+	clipboard                 Clipboard
 	modes                     Modes
 	mouseMode                 MouseMode
 	mouseExtMode              MouseExtMode
 	bracketedPasteMode        bool
+	keyboardStacks            [2][]int // kitty keyboard protocol flags pushed by the application, for each screen
+	modifyOtherKeys           int      // xterm's modifyOtherKeys level, set with CSI > 4 m
 	isDirty                   bool
+	bellRung                  bool // since the bell was last checked
 	charWidth                 float32
 	charHeight                float32
 	lastBuffer                uint8
 	terminalState             *buffer.TerminalState
+	spill                     *buffer.Spill // only set when spilling the scrollback to disk
+	input                     chan rune     // what the parser goroutine reads, woken with wakeInput
+	restoreWanted             int32         // lines scrolled to beyond the top of memory, brought back from disk by the parser goroutine
 	platformDependentSettings platform.PlatformDependentSettings
 }
 
@@ -64,6 +91,11 @@ type Modes struct {
 	ShowCursor            bool
 	ApplicationCursorKeys bool
 	BlinkingCursor        bool
+	ReportFocus           bool // send focus in and out events, with DECSET 1004
+	// Bidi lays out lines with right to left text in them with the bidirectional algorithm, unless the
+	// application says it has already done so with BDSM (CSI 8 h)
+	Bidi          bool
+	BidiDirection buffer.BidiDirection // the direction lines run in, chosen with SCP (CSI Ps SP k)
 }
 
 type Winsize struct {
@@ -73,48 +105,135 @@ type Winsize struct {
 	y      uint16 // ignored, but necessary for ioctl calls
 }
 
-func New(pty platform.Pty, logger *zap.SugaredLogger, config *config.Config) *Terminal {
+func New(pty platform.Pty, logger *zap.SugaredLogger, options Options) *Terminal {
 	t := &Terminal{
-		terminalState: buffer.NewTerminalState(1, 1, buffer.CellAttributes{
-			FgColour: config.ColourScheme.Foreground,
-			BgColour: config.ColourScheme.Background,
-		}, config.MaxLines),
-		pty:           pty,
-		logger:        logger,
-		config:        config,
-		titleHandlers: []chan bool{},
+		terminalState:   buffer.NewTerminalState(1, 1, defaultAttributes(options), options.MaxLines),
+		pty:             pty,
+		logger:          logger,
+		options:         options,
+		configured:      options,
+		extendedPalette: map[uint8][3]float32{},
+		titleHandlers:   []chan bool{},
 		modes: Modes{
 			ShowCursor: true,
+			Bidi:       options.Bidi,
 		},
 		platformDependentSettings: pty.GetPlatformDependentSettings(),
 	}
 	t.buffers = []*buffer.Buffer{
 		buffer.NewBuffer(t.terminalState),
-		buffer.NewBuffer(t.terminalState),
+		buffer.NewAltBuffer(t.terminalState),
 		buffer.NewBuffer(t.terminalState),
 	}
 	t.activeBuffer = t.buffers[0]
 	return t
 }
 
+// defaultAttributes returns the attributes text is written with until the application changes them
+func defaultAttributes(options Options) buffer.CellAttributes {
+	return buffer.CellAttributes{
+		FgColour: options.Foreground,
+		BgColour: options.Background,
+		FgRef:    buffer.ColourDefaultFg,
+		BgRef:    buffer.ColourDefaultBg,
+	}
+}
+
 func (terminal *Terminal) SetProgram(program uint32) {
 	terminal.program = program
 }
 
+// Options returns the options the terminal is running with
+func (terminal *Terminal) Options() Options {
+	return terminal.options
+}
+
+// SetColours changes the default colours and palette, replacing any changes made by the application.
+// Text already written in the old colours is changed to the new ones.
+func (terminal *Terminal) SetColours(foreground [3]float32, background [3]float32, cursor [3]float32, palette Palette) {
+	terminal.options.Foreground = foreground
+	terminal.options.Background = background
+	terminal.options.Cursor = cursor
+	terminal.options.Palette = palette
+	terminal.configured.Foreground = foreground
+	terminal.configured.Background = background
+	terminal.configured.Cursor = cursor
+	terminal.configured.Palette = palette
+	terminal.recolour()
+}
+
+// SetBoldIsBright turns showing bold text in the bright palette colours on or off, including text already written
+func (terminal *Terminal) SetBoldIsBright(enabled bool) {
+	if terminal.options.BoldIsBright == enabled {
+		return
+	}
+	terminal.options.BoldIsBright = enabled
+	terminal.configured.BoldIsBright = enabled
+	terminal.recolour()
+}
+
+// SetSlomo enables or disables slow motion processing of pty output
+func (terminal *Terminal) SetSlomo(enabled bool) {
+	terminal.options.Slomo = enabled
+}
+
+// ReportFocus tells the application that the terminal has gained or lost the keyboard focus, if it has asked to know
+func (terminal *Terminal) ReportFocus(focused bool) error {
+	report := terminal.FocusReport(focused)
+	if report == nil {
+		return nil
+	}
+	return terminal.Write(report)
+}
+
+// FocusReport returns what ReportFocus sends, or nil if the application hasn't asked to know about focus changes
+func (terminal *Terminal) FocusReport(focused bool) []byte {
+	if !terminal.modes.ReportFocus {
+		return nil
+	}
+	if focused {
+		return []byte("\x1b[I")
+	}
+	return []byte("\x1b[O")
+}
+
 func (terminal *Terminal) SetBracketedPasteMode(enabled bool) {
 	terminal.bracketedPasteMode = enabled
 }
 
+// IsBracketedPasteModeEnabled returns true if the application has asked for pastes to be marked, so it won't run them as typed
+func (terminal *Terminal) IsBracketedPasteModeEnabled() bool {
+	return terminal.bracketedPasteMode
+}
+
 func (terminal *Terminal) CheckDirty() bool {
 	d := terminal.isDirty
 	terminal.isDirty = false
 	return d || terminal.ActiveBuffer().IsDirty()
 }
 
+// CheckBell returns true if the application has rung the bell since it was last checked
+func (terminal *Terminal) CheckBell() bool {
+	rung := terminal.bellRung
+	terminal.bellRung = false
+	return rung
+}
+
+// SetDirty redraws the whole view, for changes which the buffer doesn't mark row by row
 func (terminal *Terminal) SetDirty() {
 	terminal.isDirty = true
 }
 
+// TakeDamage returns the part of the view which has changed since it was last taken, and forgets it
+func (terminal *Terminal) TakeDamage() buffer.Damage {
+	damage := terminal.ActiveBuffer().TakeDamage()
+	if terminal.isDirty {
+		damage.All = true
+		terminal.isDirty = false
+	}
+	return damage
+}
+
 func (terminal *Terminal) IsApplicationCursorKeysModeEnabled() bool {
 	return terminal.modes.ApplicationCursorKeys
 }
@@ -150,6 +269,19 @@ func (terminal *Terminal) UseAltBuffer() {
 	terminal.SetSize(uint(terminal.size.Width), uint(terminal.size.Height))
 }
 
+// EnterAltScreen saves the cursor and its attributes, then shows a blank alt buffer, as DECSET 1049 does
+func (terminal *Terminal) EnterAltScreen() {
+	terminal.buffers[MainBuffer].SaveCursor()
+	terminal.UseAltBuffer()
+	terminal.ActiveBuffer().Clear()
+}
+
+// ExitAltScreen returns to the main buffer and restores the cursor saved by EnterAltScreen
+func (terminal *Terminal) ExitAltScreen() {
+	terminal.UseMainBuffer()
+	terminal.ActiveBuffer().RestoreCursor()
+}
+
 func (terminal *Terminal) UseInternalBuffer() {
 	terminal.activeBuffer = terminal.buffers[InternalBuffer]
 	terminal.SetSize(uint(terminal.size.Width), uint(terminal.size.Height))
@@ -167,6 +299,64 @@ func (terminal *Terminal) UsingMainBuffer() bool {
 	return terminal.activeBuffer == terminal.buffers[MainBuffer]
 }
 
+// ScrollbackMemory returns an estimate of the memory held by the terminal buffers, in bytes
+func (terminal *Terminal) ScrollbackMemory() uint64 {
+	var total uint64
+	for _, b := range terminal.buffers {
+		total += b.MemoryUsage()
+	}
+	return total
+}
+
+// ScrollbackLines returns the number of lines in the main buffer, which is the one trimmed, including those on screen
+func (terminal *Terminal) ScrollbackLines() int {
+	return terminal.buffers[MainBuffer].Height()
+}
+
+// TrimScrollback discards all but the most recent keep lines of scrollback from the main buffer
+func (terminal *Terminal) TrimScrollback(keep int) {
+	terminal.buffers[MainBuffer].TrimScrollback(keep)
+	terminal.SetDirty()
+}
+
+// WriteTrimmedScrollback writes the text of the lines TrimScrollback(keep) would discard from the main buffer to w
+func (terminal *Terminal) WriteTrimmedScrollback(w io.Writer, keep int) error {
+	return terminal.buffers[MainBuffer].WriteTrimmed(w, keep)
+}
+
+// SpillScrollback writes lines which drop off the top of the main buffer's scrollback to a file in dir instead of
+// discarding them, reading them back as they're scrolled to. An empty dir uses the system temporary directory.
+func (terminal *Terminal) SpillScrollback(dir string) error {
+	spill, err := buffer.NewSpill(dir)
+	if err != nil {
+		return err
+	}
+	terminal.buffers[MainBuffer].SpillTo(spill)
+	terminal.spill = spill
+	return nil
+}
+
+// SpillsScrollback returns true if lines dropped from the scrollback are being kept on disk
+func (terminal *Terminal) SpillsScrollback() bool {
+	return terminal.spill != nil && terminal.buffers[MainBuffer].SpillError() == nil
+}
+
+// SpillError returns the error which stopped the scrollback being spilled to disk, if any
+func (terminal *Terminal) SpillError() error {
+	return terminal.buffers[MainBuffer].SpillError()
+}
+
+// CloseSpill removes the file the scrollback has been spilled to
+func (terminal *Terminal) CloseSpill() error {
+	if terminal.spill == nil {
+		return nil
+	}
+	terminal.buffers[MainBuffer].SpillTo(nil)
+	err := terminal.spill.Close()
+	terminal.spill = nil
+	return err
+}
+
 func (terminal *Terminal) GetScrollOffset() uint {
 	return terminal.terminalState.GetScrollOffset()
 }
@@ -209,6 +399,16 @@ func (terminal *Terminal) ScreenScrollUp(lines uint16) {
 
 	offset := terminal.terminalState.GetScrollOffset()
 
+	// lines scrolled to past the top of what's in memory are brought back from disk by the parser goroutine, as
+	// only it changes the buffer, and the view scrolls on up to them once they're back
+	if top := uint(buffer.Height()) - uint(buffer.ViewHeight()); uint(lines)+offset > top && terminal.spill != nil {
+		atomic.AddInt32(&terminal.restoreWanted, int32(uint(lines)+offset-top))
+		select {
+		case terminal.input <- wakeInput:
+		default: // the parser has output to get through, and looks at restoreWanted as it goes
+		}
+	}
+
 	if uint(lines)+offset >= (uint(buffer.Height()) - uint(buffer.ViewHeight())) {
 		terminal.terminalState.SetScrollOffset(uint(buffer.Height()) - uint(buffer.ViewHeight()))
 	} else {
@@ -216,6 +416,23 @@ func (terminal *Terminal) ScreenScrollUp(lines uint16) {
 	}
 }
 
+// restoreSpilled brings back the lines ScreenScrollUp wanted from disk and scrolls up to them. It's run on the
+// parser goroutine.
+func (terminal *Terminal) restoreSpilled() {
+	wanted := int(atomic.SwapInt32(&terminal.restoreWanted, 0))
+	if wanted <= 0 {
+		return
+	}
+	restored := terminal.ActiveBuffer().RestoreSpilled(wanted)
+	if restored < wanted {
+		wanted = restored
+	}
+	if wanted > 0 {
+		terminal.terminalState.SetScrollOffset(terminal.terminalState.GetScrollOffset() + uint(wanted))
+		terminal.SetDirty()
+	}
+}
+
 func (terminal *Terminal) ScrollPageDown() {
 	terminal.ScreenScrollDown(terminal.terminalState.ViewHeight())
 }
@@ -229,6 +446,35 @@ func (terminal *Terminal) ScrollToEnd() {
 	terminal.terminalState.SetScrollOffset(0)
 }
 
+// ScrollToLine scrolls the view so that the given raw buffer line is roughly in the middle of the screen
+func (terminal *Terminal) ScrollToLine(rawLine int) {
+	terminal.scrollLineToRow(rawLine, int(terminal.ActiveBuffer().ViewHeight())/2)
+}
+
+// ScrollToLineAtTop scrolls the view so that the given raw buffer line is at the top of the screen
+func (terminal *Terminal) ScrollToLineAtTop(rawLine int) {
+	terminal.scrollLineToRow(rawLine, 0)
+}
+
+func (terminal *Terminal) scrollLineToRow(rawLine int, row int) {
+	defer terminal.SetDirty()
+	buffer := terminal.ActiveBuffer()
+
+	maxOffset := buffer.Height() - int(buffer.ViewHeight())
+	if maxOffset <= 0 {
+		terminal.terminalState.SetScrollOffset(0)
+		return
+	}
+
+	offset := maxOffset - rawLine + row
+	if offset < 0 {
+		offset = 0
+	} else if offset > maxOffset {
+		offset = maxOffset
+	}
+	terminal.terminalState.SetScrollOffset(uint(offset))
+}
+
 func (terminal *Terminal) GetVisibleLines() []buffer.Line {
 	return terminal.ActiveBuffer().GetVisibleLines()
 }
@@ -249,6 +495,55 @@ func (terminal *Terminal) AttachReverseHandler(handler chan bool) {
 	terminal.reverseHandlers = append(terminal.reverseHandlers, handler)
 }
 
+// AttachOutputHandler registers a channel to be notified each time the terminal finishes processing
+// a batch of output. Notifications are dropped rather than blocking if the channel is full.
+func (terminal *Terminal) AttachOutputHandler(handler chan bool) {
+	terminal.outputHandlers = append(terminal.outputHandlers, handler)
+}
+
+// AttachThemeHandler registers a channel to receive the names of themes the application asks for
+func (terminal *Terminal) AttachThemeHandler(handler chan string) {
+	terminal.themeHandlers = append(terminal.themeHandlers, handler)
+}
+
+// AttachFontSizeHandler registers a channel to receive the changes to the size of the text the application asks for
+func (terminal *Terminal) AttachFontSizeHandler(handler chan FontSizeChange) {
+	terminal.fontSizeHandlers = append(terminal.fontSizeHandlers, handler)
+}
+
+// AttachBellHandler registers a channel to be notified when the application rings the bell
+func (terminal *Terminal) AttachBellHandler(handler chan bool) {
+	terminal.bellHandlers = append(terminal.bellHandlers, handler)
+}
+
+// AttachCommandFinishedHandler registers a channel to receive the commands shell integration reports finishing
+func (terminal *Terminal) AttachCommandFinishedHandler(handler chan CommandFinished) {
+	terminal.commandHandlers = append(terminal.commandHandlers, handler)
+}
+
+// AttachColourChangeHandler registers a channel to be notified when the application changes the default colours
+func (terminal *Terminal) AttachColourChangeHandler(handler chan bool) {
+	terminal.colourHandlers = append(terminal.colourHandlers, handler)
+}
+
+// AttachHostChangeHandler registers a channel to be notified when the shell reports being on another host, or
+// back on this one
+func (terminal *Terminal) AttachHostChangeHandler(handler chan bool) {
+	terminal.hostHandlers = append(terminal.hostHandlers, handler)
+}
+
+// AttachLineHandler registers a channel to receive each line of output on the main screen as it is finished with a
+// new line. Lines are sent in order, waiting for the channel to take each one, so it must be kept drained.
+func (terminal *Terminal) AttachLineHandler(handler chan string) {
+	terminal.lineHandlers = append(terminal.lineHandlers, handler)
+}
+
+// AttachSequenceHandler registers a channel to receive the OSC sequences the terminal doesn't handle itself, in
+// order. As with lines, the channel must be kept drained.
+func (terminal *Terminal) AttachSequenceHandler(handler chan Sequence) {
+	terminal.sequenceHandlers = append(terminal.sequenceHandlers, handler)
+}
+
 func (terminal *Terminal) Modes() Modes {
 	return terminal.modes
 }
@@ -261,6 +556,14 @@ func (terminal *Terminal) emitTitleChange() {
 	}
 }
 
+func (terminal *Terminal) emitHostChange() {
+	for _, h := range terminal.hostHandlers {
+		go func(c chan bool) {
+			c <- true
+		}(h)
+	}
+}
+
 func (terminal *Terminal) emitResize() {
 	for _, h := range terminal.resizeHandlers {
 		go func(c chan bool) {
@@ -277,6 +580,68 @@ func (terminal *Terminal) emitReverse(reverse bool) {
 	}
 }
 
+func (terminal *Terminal) emitColourChange() {
+	for _, h := range terminal.colourHandlers {
+		go func(c chan bool) {
+			c <- true
+		}(h)
+	}
+}
+
+func (terminal *Terminal) emitThemeChange(name string) {
+	for _, h := range terminal.themeHandlers {
+		go func(c chan string) {
+			c <- name
+		}(h)
+	}
+}
+
+func (terminal *Terminal) emitFontSizeChange(change FontSizeChange) {
+	for _, h := range terminal.fontSizeHandlers {
+		go func(c chan FontSizeChange) {
+			c <- change
+		}(h)
+	}
+}
+
+func (terminal *Terminal) emitCommandFinished(finished CommandFinished) {
+	for _, h := range terminal.commandHandlers {
+		go func(c chan CommandFinished) {
+			c <- finished
+		}(h)
+	}
+}
+
+func (terminal *Terminal) emitLine(line string) {
+	for _, h := range terminal.lineHandlers {
+		h <- line
+	}
+}
+
+func (terminal *Terminal) emitSequence(sequence Sequence) {
+	for _, h := range terminal.sequenceHandlers {
+		h <- sequence
+	}
+}
+
+func (terminal *Terminal) emitBell() {
+	for _, h := range terminal.bellHandlers {
+		select {
+		case h <- true:
+		default: // the bell is already waiting to be answered
+		}
+	}
+}
+
+func (terminal *Terminal) emitOutput() {
+	for _, h := range terminal.outputHandlers {
+		select {
+		case h <- true:
+		default:
+		}
+	}
+}
+
 func (terminal *Terminal) GetLogicalCursorX() uint16 {
 	if terminal.ActiveBuffer().CursorColumn() >= terminal.ActiveBuffer().Width() {
 		return 0
@@ -302,34 +667,80 @@ func (terminal *Terminal) SetTitle(title string) {
 	terminal.emitTitleChange()
 }
 
+// WorkingDirectory returns the directory the shell last reported being in, which is empty if it hasn't reported one
+// with OSC 7 or is running on another host
+func (terminal *Terminal) WorkingDirectory() string {
+	return terminal.workingDirectory
+}
+
+// Host returns the name of the host the shell last reported being on with OSC 7, which is empty if it hasn't
+// reported one or is running on this host
+func (terminal *Terminal) Host() string {
+	return terminal.host
+}
+
 // Write sends data, i.e. locally typed keystrokes to the pty
 func (terminal *Terminal) Write(data []byte) error {
 	_, err := terminal.pty.Write(data)
 	return err
 }
 
+// Close closes the pty, which ends the process running in it
+func (terminal *Terminal) Close() error {
+	return terminal.pty.Close()
+}
+
 func (terminal *Terminal) WriteReturn() error {
+	return terminal.Write(terminal.ReturnSequence())
+}
+
+// ReturnSequence returns what should be sent for the return key, which depends on new line mode
+func (terminal *Terminal) ReturnSequence() []byte {
 	if terminal.terminalState.IsNewLineMode() {
-		return terminal.Write([]byte{0x0d, 0x0a})
-	} else {
-		return terminal.Write([]byte{0x0d})
+		return []byte{0x0d, 0x0a}
 	}
+	return []byte{0x0d}
 }
 
+// Paste sends pasted text to the pty. When the application has enabled bracketed paste mode, the text is
+// wrapped in markers so it isn't run as it arrives, and stripped of control characters so it can't end the paste early.
 func (terminal *Terminal) Paste(data []byte) error {
+	_, err := terminal.pty.Write(terminal.PasteSequence(data))
+	return err
+}
+
+// PasteSequence returns what Paste sends for the pasted text
+func (terminal *Terminal) PasteSequence(data []byte) []byte {
 	if terminal.bracketedPasteMode {
-		data = []byte(fmt.Sprintf("\x1b[200~%s\x1b[201~", string(data)))
+		return []byte(fmt.Sprintf("\x1b[200~%s\x1b[201~", sanitizePaste(data)))
 	}
-	_, err := terminal.pty.Write(data)
-	return err
+	return data
+}
+
+// sanitizePaste removes C0 and C1 control characters other than tab and line breaks, along with invalid UTF-8
+func sanitizePaste(data []byte) string {
+	var builder strings.Builder
+	for len(data) > 0 {
+		r, size := utf8.DecodeRune(data)
+		data = data[size:]
+		if r == utf8.RuneError && size <= 1 {
+			continue
+		}
+		if r == '\t' || r == '\n' || r == '\r' || !unicode.IsControl(r) {
+			builder.WriteRune(r)
+		}
+	}
+	return builder.String()
 }
 
 // Read needs to be run on a goroutine, as it continually reads output to set on the terminal
 func (terminal *Terminal) Read() error {
 	buffer := make(chan rune, 0xffff)
+	defer close(buffer)
 
 	reader := bufio.NewReader(terminal.pty)
 
+	terminal.input = buffer
 	go terminal.processInput(buffer)
 	for {
 		r, _, err := reader.ReadRune()
@@ -350,6 +761,56 @@ func (terminal *Terminal) Clear() {
 	terminal.ActiveBuffer().Clear()
 }
 
+// ClearScrollback discards the lines scrolled off the top of the main screen, leaving the screen itself alone
+func (terminal *Terminal) ClearScrollback() {
+	terminal.buffers[MainBuffer].ClearScrollback()
+	terminal.SetDirty()
+}
+
+// ClearAll discards the scrollback and clears the screen apart from the cursor's line, like cmd + k in iTerm.
+// Only the scrollback is cleared while an application is using the alternate screen, as it would otherwise
+// lose what it has drawn.
+func (terminal *Terminal) ClearAll() {
+	if terminal.UsingMainBuffer() {
+		terminal.buffers[MainBuffer].ClearAll()
+	} else {
+		terminal.buffers[MainBuffer].ClearScrollback()
+	}
+	terminal.SetDirty()
+}
+
+// Reset puts the terminal back as it was when it started (RIS): modes, character sets, tab stops, margins and
+// colours are all reset, the main screen is shown and cleared, and the scrollback is kept.
+func (terminal *Terminal) Reset() {
+	terminal.SetScreenMode(false)
+	recolour := terminal.resetPalette()
+	for code := dynamicForeground; code <= dynamicCursor; code++ {
+		if terminal.resetDynamicColour(code) {
+			recolour = true
+		}
+	}
+	if recolour {
+		terminal.recolour()
+	}
+	terminal.terminalState.Reset(defaultAttributes(terminal.options))
+	terminal.modes = Modes{
+		ShowCursor: true,
+		Bidi:       terminal.options.Bidi,
+	}
+	terminal.mouseMode = MouseModeNone
+	terminal.mouseExtMode = MouseExtNone
+	terminal.bracketedPasteMode = false
+	terminal.keyboardStacks = [2][]int{}
+	terminal.modifyOtherKeys = 0
+
+	terminal.buffers[AltBuffer].Reset()
+	if terminal.activeBuffer == terminal.buffers[AltBuffer] {
+		terminal.UseMainBuffer()
+	}
+	terminal.buffers[MainBuffer].Reset()
+	terminal.SetDirty()
+}
+
 func (terminal *Terminal) GetSize() (int, int) {
 	return int(terminal.size.Width), int(terminal.size.Height)
 }
@@ -358,6 +819,10 @@ func (terminal *Terminal) SetSize(newCols uint, newLines uint) error {
 	terminal.lock.Lock()
 	defer terminal.lock.Unlock()
 
+	if newCols == 0 || newLines == 0 || newCols > math.MaxUint16 || newLines > math.MaxUint16 {
+		return fmt.Errorf("Invalid terminal size %dx%d", newCols, newLines)
+	}
+
 	if terminal.size.Width == uint16(newCols) && terminal.size.Height == uint16(newLines) {
 		return nil
 	}
@@ -390,11 +855,11 @@ func (terminal *Terminal) SetOriginMode(enabled bool) {
 }
 
 func (terminal *Terminal) SetInsertMode() {
-	terminal.terminalState.ReplaceMode = false
+	terminal.terminalState.InsertMode = true
 }
 
 func (terminal *Terminal) SetReplaceMode() {
-	terminal.terminalState.ReplaceMode = true
+	terminal.terminalState.InsertMode = false
 }
 
 func (terminal *Terminal) SetNewLineMode() {
@@ -409,6 +874,19 @@ func (terminal *Terminal) ResetVerticalMargins() {
 	terminal.terminalState.ResetVerticalMargins()
 }
 
+// SetLeftRightMarginMode turns DECLRMM on or off. Turning it off moves the margins back to the edges of the screen.
+func (terminal *Terminal) SetLeftRightMarginMode(enabled bool) {
+	terminal.terminalState.LeftRightMarginMode = enabled
+	if !enabled {
+		terminal.terminalState.ResetHorizontalMargins()
+	}
+}
+
+// ScreenMode returns true if the screen is in reverse video, with DECSCNM
+func (terminal *Terminal) ScreenMode() bool {
+	return terminal.terminalState.ScreenMode
+}
+
 func (terminal *Terminal) SetScreenMode(enabled bool) {
 	if terminal.terminalState.ScreenMode == enabled {
 		return
@@ -0,0 +1,71 @@
+package terminal
+
+import (
+	"regexp"
+	"unicode/utf8"
+)
+
+// Trigger is a pattern matched against each line of output on the main screen as it's finished, like iTerm2's
+// triggers. Matches are highlighted straight away if Highlight is set, and reported to the trigger handlers.
+type Trigger struct {
+	Pattern   *regexp.Regexp
+	Highlight *[3]float32 // the background to give each match, in black text, or nil to leave them as they are
+}
+
+// TriggerMatch is a line matched by a trigger, with the first match in it
+type TriggerMatch struct {
+	Trigger int // which of the triggers given to SetTriggers matched
+	Line    string
+	Match   string
+	Groups  []string // what the pattern's groups matched, from the first
+}
+
+// SetTriggers sets the patterns each line of output is matched against
+func (terminal *Terminal) SetTriggers(triggers []Trigger) {
+	terminal.triggers = triggers
+}
+
+// AttachTriggerHandler registers a channel to receive the lines which match a trigger
+func (terminal *Terminal) AttachTriggerHandler(handler chan TriggerMatch) {
+	terminal.triggerHandlers = append(terminal.triggerHandlers, handler)
+}
+
+// runTriggers matches the line the cursor is on, once it's finished, against the triggers
+func (terminal *Terminal) runTriggers(line string) {
+	for i, trigger := range terminal.triggers {
+		matches := trigger.Pattern.FindAllStringSubmatchIndex(line, -1)
+		if len(matches) == 0 {
+			continue
+		}
+		if trigger.Highlight != nil {
+			for _, match := range matches {
+				start := utf8.RuneCountInString(line[:match[0]])
+				end := start + utf8.RuneCountInString(line[match[0]:match[1]])
+				terminal.ActiveBuffer().HighlightCursorLine(start, end, [3]float32{0, 0, 0}, *trigger.Highlight)
+			}
+		}
+		first := matches[0]
+		groups := []string{}
+		for g := 2; g+1 < len(first); g += 2 {
+			if first[g] < 0 {
+				groups = append(groups, "")
+				continue
+			}
+			groups = append(groups, line[first[g]:first[g+1]])
+		}
+		terminal.emitTriggerMatch(TriggerMatch{
+			Trigger: i,
+			Line:    line,
+			Match:   line[first[0]:first[1]],
+			Groups:  groups,
+		})
+	}
+}
+
+func (terminal *Terminal) emitTriggerMatch(match TriggerMatch) {
+	for _, h := range terminal.triggerHandlers {
+		go func(c chan TriggerMatch) {
+			c <- match
+		}(h)
+	}
+}
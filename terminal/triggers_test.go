@@ -0,0 +1,43 @@
+package terminal
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTriggers(t *testing.T) {
+	term := newHeadlessTerminal(20, 5)
+	red := [3]float32{1, 0, 0}
+	term.SetTriggers([]Trigger{
+		{Pattern: regexp.MustCompile(`error: (\w+)`), Highlight: &red},
+		{Pattern: regexp.MustCompile(`done`)},
+	})
+	matches := make(chan TriggerMatch, 2)
+	term.AttachTriggerHandler(matches)
+
+	term.processBytes([]byte("é error: disk full\r\n"))
+
+	select {
+	case match := <-matches:
+		assert.Equal(t, TriggerMatch{Trigger: 0, Line: "é error: disk full", Match: "error: disk", Groups: []string{"disk"}}, match)
+	case <-time.After(time.Second):
+		t.Fatal("the match wasn't reported")
+	}
+	line := term.GetVisibleLines()[0]
+	require.True(t, len(line.Cells()) > 13)
+	assert.NotEqual(t, red, line.Cells()[1].Bg())
+	assert.Equal(t, red, line.Cells()[2].Bg(), "the match is highlighted")
+	assert.Equal(t, red, line.Cells()[12].Bg())
+	assert.NotEqual(t, red, line.Cells()[13].Bg())
+
+	term.processBytes([]byte("\x1b[?1049hdone\r\n\x1b[?1049l"))
+	select {
+	case match := <-matches:
+		t.Fatalf("%q on the alternate screen matched", match.Line)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
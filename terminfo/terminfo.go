@@ -0,0 +1,148 @@
+// Package terminfo holds aminal's terminfo entry, which describes the terminal to the programs running in it.
+package terminfo
+
+import (
+	_ "embed" // for the entry's source
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Name is the name of the entry, which TERM is set to in order to use it
+const Name = "aminal"
+
+// Source is the entry as tic compiles it
+//
+//go:embed aminal.terminfo
+var Source string
+
+// Install compiles the entry with tic, which puts it in ~/.terminfo, or in the system's terminfo directory when
+// run as root
+func Install() error {
+	f, err := ioutil.TempFile("", "aminal-terminfo")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(Source); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	output, err := exec.Command("tic", "-x", f.Name()).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("tic failed: %s: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+var capabilities = parse(Source)
+
+// Lookup returns the value of one of the capabilities the entry adds to xterm-256color, with any escapes in a
+// string replaced by the characters they stand for. Booleans have an empty value. It returns false for a
+// capability the entry doesn't have, or which it takes from xterm-256color. TN, which isn't a capability, is the
+// name of the entry, as xterm answers it.
+func Lookup(name string) (string, bool) {
+	if name == "TN" {
+		return Name, true
+	}
+	value, ok := capabilities[name]
+	return value, ok
+}
+
+// parse reads the capabilities from the source of an entry
+func parse(source string) map[string]string {
+	caps := map[string]string{}
+	body := []string{}
+	for _, line := range strings.Split(source, "\n") {
+		// the capabilities are on the indented lines after the names
+		if strings.HasPrefix(line, "\t") {
+			body = append(body, strings.TrimSpace(line))
+		}
+	}
+
+	for _, field := range splitFields(strings.Join(body, " ")) {
+		switch {
+		case strings.Contains(field, "="):
+			parts := strings.SplitN(field, "=", 2)
+			caps[parts[0]] = unescape(parts[1])
+		case strings.Contains(field, "#"):
+			parts := strings.SplitN(field, "#", 2)
+			caps[parts[0]] = parts[1]
+		default:
+			caps[field] = ""
+		}
+	}
+	delete(caps, "use")
+	return caps
+}
+
+// splitFields splits the capabilities at the commas between them, leaving escaped commas in the values
+func splitFields(s string) []string {
+	fields := []string{}
+	field := ""
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && i+1 < len(s):
+			field += s[i : i+2]
+			i++
+		case s[i] == ',':
+			if field = strings.TrimSpace(field); field != "" {
+				fields = append(fields, field)
+			}
+			field = ""
+		default:
+			field += string(s[i])
+		}
+	}
+	return fields
+}
+
+// unescape replaces the escapes in a string capability, like \E and ^C, with the characters they stand for
+func unescape(s string) string {
+	var out strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '^' && i+1 < len(s):
+			i++
+			out.WriteByte(s[i] & 0x1f)
+		case s[i] == '\\' && i+1 < len(s):
+			i++
+			switch s[i] {
+			case 'E', 'e':
+				out.WriteByte(0x1b)
+			case 'n':
+				out.WriteByte('\n')
+			case 'r':
+				out.WriteByte('\r')
+			case 't':
+				out.WriteByte('\t')
+			case 'b':
+				out.WriteByte('\b')
+			case 'f':
+				out.WriteByte('\f')
+			case 's':
+				out.WriteByte(' ')
+			case '0', '1', '2', '3':
+				// three octal digits, where \0 on its own is a null
+				n := 0
+				j := i
+				for ; j < len(s) && j < i+3 && s[j] >= '0' && s[j] <= '7'; j++ {
+					n = n*8 + int(s[j]-'0')
+				}
+				i = j - 1
+				out.WriteByte(byte(n))
+			default:
+				out.WriteByte(s[i])
+			}
+		default:
+			out.WriteByte(s[i])
+		}
+	}
+	return out.String()
+}
@@ -0,0 +1,35 @@
+package terminfo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLookup(t *testing.T) {
+	value, ok := Lookup("Smulx")
+	assert.True(t, ok)
+	assert.Equal(t, "\x1b[4:%p1%dm", value)
+
+	value, ok = Lookup("colors")
+	assert.True(t, ok)
+	assert.Equal(t, "256", value)
+
+	value, ok = Lookup("RGB")
+	assert.True(t, ok)
+	assert.Equal(t, "", value, "booleans have no value")
+
+	value, ok = Lookup("TN")
+	assert.True(t, ok)
+	assert.Equal(t, "aminal", value)
+
+	_, ok = Lookup("use")
+	assert.False(t, ok)
+	_, ok = Lookup("kcuu1")
+	assert.False(t, ok, "capabilities taken from xterm-256color aren't known")
+}
+
+func TestUnescape(t *testing.T) {
+	assert.Equal(t, "\x1b]52;%p1%s;%p2%s\a", unescape(`\E]52;%p1%s;%p2%s\007`))
+	assert.Equal(t, "\x03a,b\\ \x00", unescape(`^Ca\,b\\\s\0`))
+}
@@ -0,0 +1,170 @@
+// Package themes bundles named colour schemes, and loads user defined ones from a directory of theme files.
+// A theme file is named after the theme, e.g. mytheme.toml, and holds the same keys as the [colours] section
+// of the config. Anything it leaves out is taken from the default colours.
+package themes
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/liamg/aminal/config"
+)
+
+const fileExtension = ".toml"
+
+// palette is a theme as it's written below, with the 16 ANSI colours in order, normal then bright
+type palette struct {
+	cursor     string
+	foreground string
+	background string
+	selection  string
+	colours    [16]string
+}
+
+var builtin = map[string]palette{
+	"solarized-dark": {
+		cursor:     "#93a1a1",
+		foreground: "#839496",
+		background: "#002b36",
+		selection:  "#073642",
+		colours: [16]string{
+			"#073642", "#dc322f", "#859900", "#b58900", "#268bd2", "#d33682", "#2aa198", "#eee8d5",
+			"#002b36", "#cb4b16", "#586e75", "#657b83", "#839496", "#6c71c4", "#93a1a1", "#fdf6e3",
+		},
+	},
+	"solarized-light": {
+		cursor:     "#586e75",
+		foreground: "#657b83",
+		background: "#fdf6e3",
+		selection:  "#eee8d5",
+		colours: [16]string{
+			"#073642", "#dc322f", "#859900", "#b58900", "#268bd2", "#d33682", "#2aa198", "#eee8d5",
+			"#002b36", "#cb4b16", "#586e75", "#657b83", "#839496", "#6c71c4", "#93a1a1", "#fdf6e3",
+		},
+	},
+	"gruvbox": {
+		cursor:     "#ebdbb2",
+		foreground: "#ebdbb2",
+		background: "#282828",
+		selection:  "#504945",
+		colours: [16]string{
+			"#282828", "#cc241d", "#98971a", "#d79921", "#458588", "#b16286", "#689d6a", "#a89984",
+			"#928374", "#fb4934", "#b8bb26", "#fabd2f", "#83a598", "#d3869b", "#8ec07c", "#ebdbb2",
+		},
+	},
+	"dracula": {
+		cursor:     "#f8f8f2",
+		foreground: "#f8f8f2",
+		background: "#282a36",
+		selection:  "#44475a",
+		colours: [16]string{
+			"#21222c", "#ff5555", "#50fa7b", "#f1fa8c", "#bd93f9", "#ff79c6", "#8be9fd", "#f8f8f2",
+			"#6272a4", "#ff6e6e", "#69ff94", "#ffffa5", "#d6acff", "#ff92df", "#a4ffff", "#ffffff",
+		},
+	},
+	"nord": {
+		cursor:     "#d8dee9",
+		foreground: "#d8dee9",
+		background: "#2e3440",
+		selection:  "#434c5e",
+		colours: [16]string{
+			"#3b4252", "#bf616a", "#a3be8c", "#ebcb8b", "#81a1c1", "#b48ead", "#88c0d0", "#e5e9f0",
+			"#4c566a", "#bf616a", "#a3be8c", "#ebcb8b", "#81a1c1", "#b48ead", "#8fbcbb", "#eceff4",
+		},
+	},
+}
+
+func (p palette) scheme() config.ColourScheme {
+	scheme := config.DefaultConfig.ColourScheme
+	targets := []*config.Colour{
+		&scheme.Cursor, &scheme.Foreground, &scheme.Background, &scheme.Selection,
+		&scheme.Black, &scheme.Red, &scheme.Green, &scheme.Yellow,
+		&scheme.Blue, &scheme.Magenta, &scheme.Cyan, &scheme.LightGrey,
+		&scheme.DarkGrey, &scheme.LightRed, &scheme.LightGreen, &scheme.LightYellow,
+		&scheme.LightBlue, &scheme.LightMagenta, &scheme.LightCyan, &scheme.White,
+	}
+	values := append([]string{p.cursor, p.foreground, p.background, p.selection}, p.colours[:]...)
+	for i, value := range values {
+		// the built in themes are all valid, which the tests check
+		_ = targets[i].UnmarshalText([]byte(value))
+	}
+	return scheme
+}
+
+// Directory returns where the user's theme files are kept: the themes_directory from the config, or a
+// themes directory next to the config file. It's empty if there's neither.
+func Directory(conf *config.Config) string {
+	if conf.ThemesDirectory != "" {
+		return conf.ThemesDirectory
+	}
+	if conf.Path != "" {
+		return filepath.Join(filepath.Dir(conf.Path), "themes")
+	}
+	return ""
+}
+
+// Names returns the names of the built in themes and the themes in dir, sorted
+func Names(dir string) []string {
+	seen := map[string]bool{}
+	for name := range builtin {
+		seen[name] = true
+	}
+	if dir != "" {
+		files, _ := ioutil.ReadDir(dir)
+		for _, file := range files {
+			if !file.IsDir() && strings.HasSuffix(file.Name(), fileExtension) {
+				seen[strings.TrimSuffix(file.Name(), fileExtension)] = true
+			}
+		}
+	}
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Load returns the colours of the named theme. A theme file in dir takes precedence over a built in theme of
+// the same name.
+func Load(name string, dir string) (config.ColourScheme, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) {
+		return config.ColourScheme{}, fmt.Errorf("Invalid theme name %q", name)
+	}
+	if dir != "" {
+		data, err := ioutil.ReadFile(filepath.Join(dir, name+fileExtension))
+		if err == nil {
+			scheme := config.DefaultConfig.ColourScheme
+			if err := toml.Unmarshal(data, &scheme); err != nil {
+				return config.ColourScheme{}, fmt.Errorf("Invalid theme file for %s: %s", name, err)
+			}
+			return scheme, nil
+		}
+		if !os.IsNotExist(err) {
+			return config.ColourScheme{}, err
+		}
+	}
+	if p, ok := builtin[name]; ok {
+		return p.scheme(), nil
+	}
+	return config.ColourScheme{}, fmt.Errorf("Unknown theme %q", name)
+}
+
+// Apply replaces the colours in conf with those of its theme, if it has one. The colours are left alone if
+// the theme can't be loaded.
+func Apply(conf *config.Config) error {
+	if conf.Theme == "" {
+		return nil
+	}
+	scheme, err := Load(conf.Theme, Directory(conf))
+	if err != nil {
+		return err
+	}
+	conf.ColourScheme = scheme
+	return nil
+}
@@ -0,0 +1,67 @@
+package themes
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/liamg/aminal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuiltinThemesAreValid(t *testing.T) {
+	for name, p := range builtin {
+		values := append([]string{p.cursor, p.foreground, p.background, p.selection}, p.colours[:]...)
+		for _, value := range values {
+			var c config.Colour
+			assert.Nil(t, c.UnmarshalText([]byte(value)), "%s: %s", name, value)
+		}
+	}
+}
+
+func TestLoadBuiltinTheme(t *testing.T) {
+	scheme, err := Load("nord", "")
+	require.Nil(t, err)
+	var background config.Colour
+	require.Nil(t, background.UnmarshalText([]byte("#2e3440")))
+	assert.Equal(t, background, scheme.Background)
+	assert.Equal(t, config.DefaultConfig.ColourScheme.Diff, scheme.Diff)
+}
+
+func TestUserThemes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "aminal-themes")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	require.Nil(t, ioutil.WriteFile(filepath.Join(dir, "mine.toml"), []byte("background = \"#102030\"\n"), 0o644))
+	require.Nil(t, ioutil.WriteFile(filepath.Join(dir, "nord.toml"), []byte("background = \"#000000\"\n"), 0o644))
+
+	assert.Equal(t, []string{"dracula", "gruvbox", "mine", "nord", "solarized-dark", "solarized-light"}, Names(dir))
+
+	scheme, err := Load("mine", dir)
+	require.Nil(t, err)
+	assert.Equal(t, config.Colour{0x10 / 255.0, 0x20 / 255.0, 0x30 / 255.0}, scheme.Background)
+	assert.Equal(t, config.DefaultConfig.ColourScheme.Foreground, scheme.Foreground)
+
+	scheme, err = Load("nord", dir)
+	require.Nil(t, err)
+	assert.Equal(t, config.Colour{0, 0, 0}, scheme.Background, "user themes take precedence")
+
+	_, err = Load("missing", dir)
+	assert.NotNil(t, err)
+	_, err = Load("../mine", dir)
+	assert.NotNil(t, err)
+}
+
+func TestApply(t *testing.T) {
+	conf := config.DefaultConfig
+	conf.Theme = "dracula"
+	require.Nil(t, Apply(&conf))
+	scheme, _ := Load("dracula", "")
+	assert.Equal(t, scheme, conf.ColourScheme)
+
+	conf.Theme = "missing"
+	assert.NotNil(t, Apply(&conf))
+	assert.Equal(t, scheme, conf.ColourScheme, "colours are left alone")
+}